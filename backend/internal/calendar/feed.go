@@ -0,0 +1,207 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+const (
+	// tokenBytes is the entropy of a feed URL token.
+	tokenBytes = 24
+	// uidDomain qualifies event UIDs so they stay unique across producers.
+	uidDomain = "@my239"
+	// deadlineAlarm reminds students a day before a series is due.
+	deadlineAlarm = 24 * time.Hour
+)
+
+// NewToken returns a fresh feed token and the hash to store.
+func NewToken() (string, []byte, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	raw := hex.EncodeToString(b)
+	return raw, HashToken(raw), nil
+}
+
+// HashToken returns the storage representation of a feed token.
+func HashToken(raw string) []byte {
+	sum := sha256.Sum256([]byte(raw))
+	return sum[:]
+}
+
+func termLabel(t store.CalendarTerm) string {
+	return mc.TermDisplayName(t.TermKind, t.TermGrade)
+}
+
+func uid(parts ...any) string {
+	s := make([]string, len(parts))
+	for i, p := range parts {
+		s[i] = fmt.Sprint(p)
+	}
+	return strings.Join(s, "-") + uidDomain
+}
+
+// MemberEvents collects the feed of every center the user belongs to:
+// published series deadlines, coffin releases, scheduled разбор
+// publications, published likbez and lessons of the user's groups.
+//
+// A student's deadline is the series due_at; a coffin stays open past it
+// until its разбор is released, which is what the coffin events show. There
+// are no per-student extensions to reflect: the schema has no such deadline.
+func MemberEvents(ctx context.Context, q *store.Queries, userID int64) ([]Event, error) {
+	series, err := q.ListCalendarSeries(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("calendar series: %w", err)
+	}
+	coffins, err := q.ListCalendarCoffinReleases(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("calendar coffins: %w", err)
+	}
+	publications, err := q.ListCalendarPublications(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("calendar publications: %w", err)
+	}
+	likbez, err := q.ListCalendarLikbez(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("calendar likbez: %w", err)
+	}
+	lessons, err := q.ListCalendarLessons(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("calendar lessons: %w", err)
+	}
+
+	events := make([]Event, 0, len(series)+len(coffins)+len(publications)+len(likbez)+len(lessons))
+	for _, s := range series {
+		events = append(events, Event{
+			UID:         uid("series", s.SeriesID, "due"),
+			Summary:     "Дедлайн: " + mc.SeriesDisplayName(int(s.Number), s.Name),
+			Description: termLabel(s.CalendarTerm),
+			Start:       s.DueAt,
+			Alarm:       deadlineAlarm,
+		})
+	}
+	for _, c := range coffins {
+		names := make([]string, len(c.ProblemNumbers))
+		for i := range c.ProblemNumbers {
+			names[i] = mc.SubproblemDisplayName(int(c.ProblemNumbers[i]), c.Labels[i])
+		}
+		events = append(events, Event{
+			UID:         uid("coffins", c.SeriesID, c.ReleasedAt.Unix()),
+			Summary:     "Гробы закрыты: " + mc.SeriesDisplayName(int(c.SeriesNumber), c.SeriesName),
+			Description: termLabel(c.CalendarTerm) + "\n" + strings.Join(names, ", "),
+			Start:       c.ReleasedAt,
+		})
+	}
+	for _, p := range publications {
+		summary := "Разбор: "
+		if p.ReleasesCoffins {
+			summary = "Разбор и закрытие гробов: "
+		}
+		events = append(events, Event{
+			UID:         uid("schedule", p.ScheduleID),
+			Summary:     summary + publicationTitle(p),
+			Description: termLabel(p.CalendarTerm),
+			Start:       p.PublishAt,
+		})
+	}
+	for _, l := range likbez {
+		events = append(events, Event{
+			UID:         uid("likbez", l.LikbezID),
+			Summary:     "Ликбез " + strconv.Itoa(int(l.Number)) + ". " + l.Title,
+			Description: termLabel(l.CalendarTerm),
+			Start:       l.HeldOn.Time,
+			AllDay:      true,
+		})
+	}
+	for _, l := range lessons {
+		summary := "Занятие"
+		if l.Topic != "" {
+			summary += ": " + l.Topic
+		}
+		events = append(events, Event{
+			UID:         uid("lesson", l.LessonID),
+			Summary:     summary,
+			Description: termLabel(l.CalendarTerm) + "\n" + strings.Join(l.GroupNames, ", "),
+			Start:       wallClock(l.HeldOn, l.StartsAt),
+			End:         wallClock(l.HeldOn, l.EndsAt),
+			Floating:    true,
+		})
+	}
+	return events, nil
+}
+
+// TeachingEvents collects the teacher feed for the centers the user
+// teaches: the grading deadline of every series (due_at plus the center's
+// SLA, by which the last submissions are overdue), deadlines of series still
+// in draft, and scheduled series and likbez publications.
+func TeachingEvents(ctx context.Context, q *store.Queries, userID int64) ([]Event, error) {
+	series, err := q.ListTeachingCalendarSeries(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("teaching calendar series: %w", err)
+	}
+	publications, err := q.ListTeachingCalendarPublications(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("teaching calendar publications: %w", err)
+	}
+
+	events := make([]Event, 0, 2*len(series)+len(publications))
+	for _, s := range series {
+		name := mc.SeriesDisplayName(int(s.Number), s.Name)
+		if !s.Published {
+			events = append(events, Event{
+				UID:         uid("series", s.SeriesID, "draft-due"),
+				Summary:     "Дедлайн (черновик): " + name,
+				Description: termLabel(s.CalendarTerm),
+				Start:       s.DueAt,
+			})
+		}
+		hours := homework.DefaultSLAHours
+		if s.SLAHours != nil {
+			hours = int(*s.SLAHours)
+		}
+		events = append(events, Event{
+			UID:         uid("series", s.SeriesID, "grading"),
+			Summary:     "Срок проверки: " + name,
+			Description: termLabel(s.CalendarTerm) + "\nSLA проверки: " + strconv.Itoa(hours) + " ч после дедлайна",
+			Start:       s.DueAt.Add(time.Duration(hours) * time.Hour),
+		})
+	}
+	for _, p := range publications {
+		events = append(events, Event{
+			UID:         uid("schedule", p.ScheduleID),
+			Summary:     "Публикация: " + publicationTitle(p),
+			Description: termLabel(p.CalendarTerm),
+			Start:       p.PublishAt,
+		})
+	}
+	return events, nil
+}
+
+func publicationTitle(p store.CalendarPublicationRow) string {
+	if p.TargetKind == store.PublicationTargetLikbez && p.LikbezNumber != nil && p.LikbezTitle != nil {
+		return "Ликбез " + strconv.Itoa(int(*p.LikbezNumber)) + ". " + *p.LikbezTitle
+	}
+	if p.SeriesNumber != nil && p.SeriesName != nil {
+		return mc.SeriesDisplayName(int(*p.SeriesNumber), *p.SeriesName)
+	}
+	return ""
+}
+
+// wallClock combines a lesson date and time of day into a zone-less time.
+func wallClock(d pgtype.Date, t pgtype.Time) time.Time {
+	y, m, day := d.Time.Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, time.UTC).Add(time.Duration(t.Microseconds) * time.Microsecond)
+}
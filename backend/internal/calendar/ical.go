@@ -0,0 +1,127 @@
+// Package calendar builds the per-user iCalendar feeds: series deadlines,
+// coffin releases, scheduled разбор publications, likbez and lessons for
+// every center the user belongs to, plus a teacher feed of grading dates.
+// Feeds are served at a secret URL so calendar clients can subscribe
+// without a session; the token is stored hashed, like refresh tokens.
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event is one VEVENT. A timed event is written in UTC unless Floating is
+// set, which keeps the center's wall-clock time (lessons have no zone). An
+// AllDay event uses only Start's date. End may be zero for a point in time.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Floating    bool
+	// Alarm, when positive, adds a display reminder that long before Start.
+	Alarm time.Duration
+}
+
+const (
+	utcLayout      = "20060102T150405Z"
+	floatingLayout = "20060102T150405"
+	dateLayout     = "20060102"
+	// maxLineOctets is the RFC 5545 content line limit before folding.
+	maxLineOctets = 75
+)
+
+// Encode writes a VCALENDAR named name. stamp is the DTSTAMP of every event:
+// the time the feed was generated.
+func Encode(w io.Writer, name string, stamp time.Time, events []Event) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) { writeFolded(bw, s) }
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//my239//calendar//RU")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeText(name))
+	line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	line("X-PUBLISHED-TTL:PT1H")
+	dtstamp := stamp.UTC().Format(utcLayout)
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + dtstamp)
+		switch {
+		case e.AllDay:
+			line("DTSTART;VALUE=DATE:" + e.Start.Format(dateLayout))
+			line("DTEND;VALUE=DATE:" + e.Start.AddDate(0, 0, 1).Format(dateLayout))
+		case e.Floating:
+			line("DTSTART:" + e.Start.Format(floatingLayout))
+			if !e.End.IsZero() {
+				line("DTEND:" + e.End.Format(floatingLayout))
+			}
+		default:
+			line("DTSTART:" + e.Start.UTC().Format(utcLayout))
+			if !e.End.IsZero() {
+				line("DTEND:" + e.End.UTC().Format(utcLayout))
+			}
+		}
+		line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.Alarm > 0 {
+			line("BEGIN:VALARM")
+			line("ACTION:DISPLAY")
+			line("DESCRIPTION:" + escapeText(e.Summary))
+			line("TRIGGER:-" + formatDuration(e.Alarm))
+			line("END:VALARM")
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// formatDuration renders d as an RFC 5545 duration in whole minutes, e.g.
+// PT24H or PT1H30M.
+func formatDuration(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	out := "PT"
+	if h := minutes / 60; h > 0 {
+		out += strconv.Itoa(h) + "H"
+	}
+	if m := minutes % 60; m > 0 || minutes < 60 {
+		out += strconv.Itoa(m) + "M"
+	}
+	return out
+}
+
+// escapeText escapes a TEXT value (RFC 5545 §3.3.11).
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// writeFolded writes one content line with CRLF, folding it into
+// continuation lines of at most maxLineOctets without splitting a UTF-8
+// sequence.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		_, _ = w.WriteString(s[:cut])
+		_, _ = w.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space of a continuation line counts toward its length.
+		limit = maxLineOctets - 1
+	}
+	_, _ = w.WriteString(s)
+	_, _ = w.WriteString("\r\n")
+}
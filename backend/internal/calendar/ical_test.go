@@ -0,0 +1,74 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	stamp := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2026, 9, 10, 20, 59, 0, 0, time.UTC)
+	lesson := time.Date(2026, 9, 3, 16, 30, 0, 0, time.UTC)
+	events := []Event{
+		{UID: "series-1-due@my239", Summary: "Серия 1. Графы, циклы; деревья", Start: due, Alarm: 24 * time.Hour},
+		{UID: "likbez-2@my239", Summary: "Ликбез 2. Инварианты", Start: time.Date(2026, 9, 5, 0, 0, 0, 0, time.UTC), AllDay: true},
+		{UID: "lesson-3@my239", Summary: "Занятие", Description: "9 класс\nА, Б", Start: lesson, End: lesson.Add(90 * time.Minute), Floating: true},
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, "my239", stamp, events); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"DTSTAMP:20260901T120000Z\r\n",
+		"DTSTART:20260910T205900Z\r\n",
+		`SUMMARY:Серия 1. Графы\, циклы\; деревья` + "\r\n",
+		"TRIGGER:-PT24H\r\n",
+		"DTSTART;VALUE=DATE:20260905\r\nDTEND;VALUE=DATE:20260906\r\n",
+		"DTSTART:20260903T163000\r\nDTEND:20260903T180000\r\n",
+		`DESCRIPTION:9 класс\nА\, Б` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestEncodeFoldsLongLines(t *testing.T) {
+	var buf bytes.Buffer
+	summary := strings.Repeat("ж", 100)
+	if err := Encode(&buf, "x", time.Now(), []Event{{UID: "u", Summary: summary, Start: time.Now()}}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var unfolded strings.Builder
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line %d has %d octets", i, len(line))
+		}
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+			continue
+		}
+		unfolded.WriteString("\n" + line)
+	}
+	if !strings.Contains(unfolded.String(), "\nSUMMARY:"+summary+"\n") {
+		t.Errorf("unfolded summary mismatch:\n%s", unfolded.String())
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		24 * time.Hour:               "PT24H",
+		90 * time.Minute:             "PT1H30M",
+		15 * time.Minute:             "PT15M",
+		15*time.Minute + time.Second: "PT15M",
+	} {
+		if got := formatDuration(d); got != want {
+			t.Errorf("formatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
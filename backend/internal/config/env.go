@@ -23,6 +23,14 @@ type Config struct {
 	S3             S3Config
 	GoogleSheets   GoogleSheetsConfig
 	TelegramAlerts TelegramAlertsConfig
+	Photos         PhotoPipelineConfig
+}
+
+// PhotoPipelineConfig tunes the background homework-photo processor.
+// HEICConverterPath empty means: look for libheif's heif-dec / heif-convert
+// on PATH, and leave HEIC uploads unconverted when neither is installed.
+type PhotoPipelineConfig struct {
+	HEICConverterPath string
 }
 
 // GoogleSheetsConfig is optional so local development and deployments that do
@@ -184,6 +192,7 @@ func Load() (*Config, error) {
 		},
 		GoogleSheets:   GoogleSheetsConfig{ServiceAccountJSON: googleServiceAccountJSON},
 		TelegramAlerts: telegramAlerts,
+		Photos:         PhotoPipelineConfig{HEICConverterPath: os.Getenv("PHOTO_HEIC_CONVERTER")},
 	}, nil
 }
 
@@ -0,0 +1,216 @@
+package googlesheets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+// attendanceMarker is one filled cell of the «Посещаемость» block. Year is
+// zero when the header gives only day and month.
+type attendanceMarker struct {
+	StudentName string
+	Year        int
+	Month       time.Month
+	Day         int
+	Status      string
+	Cell        string
+}
+
+const attendanceSection = "посещаемость"
+
+var lessonDateHeader = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})(?:\.(\d{2}|\d{4}))?$`)
+
+// attendanceMarks maps the marks teachers put in the conduit to statuses.
+var attendanceMarks = map[string]string{
+	"+":  mc.AttendancePresent,
+	"п":  mc.AttendancePresent,
+	"1":  mc.AttendancePresent,
+	"о":  mc.AttendanceLate,
+	"оп": mc.AttendanceLate,
+	"н":  mc.AttendanceAbsent,
+	"-":  mc.AttendanceAbsent,
+	"0":  mc.AttendanceAbsent,
+	"у":  mc.AttendanceExcused,
+	"б":  mc.AttendanceExcused,
+	"ув": mc.AttendanceExcused,
+}
+
+// parseConduitAttendance reads the «Посещаемость» band section: its columns
+// are headed by lesson dates (dd.mm or dd.mm.yyyy) on the «Фамилия Имя» row.
+// Cells with an unknown mark are ignored.
+func parseConduitAttendance(values [][]string) ([]attendanceMarker, error) {
+	headerRow, nameColumn := findConduitHeader(values)
+	if headerRow < 1 {
+		return nil, errors.New("google sheet conduit header «Фамилия Имя» was not found")
+	}
+	markers := make([]attendanceMarker, 0)
+	inSection := false
+	for columnIndex := nameColumn + 1; columnIndex < len(values[headerRow]); columnIndex++ {
+		if columnIndex < len(values[headerRow-1]) {
+			if section := normalizeCell(values[headerRow-1][columnIndex]); section != "" {
+				inSection = section == attendanceSection
+			}
+		}
+		if !inSection {
+			continue
+		}
+		year, month, day, ok := parseLessonDateHeader(values[headerRow][columnIndex])
+		if !ok {
+			continue
+		}
+		for rowIndex := headerRow + 1; rowIndex < len(values); rowIndex++ {
+			if nameColumn >= len(values[rowIndex]) || columnIndex >= len(values[rowIndex]) {
+				continue
+			}
+			name := normalizeCell(values[rowIndex][nameColumn])
+			status, known := attendanceMarks[normalizeCell(values[rowIndex][columnIndex])]
+			if name == "" || !known {
+				continue
+			}
+			markers = append(markers, attendanceMarker{
+				StudentName: name, Year: year, Month: month, Day: day, Status: status,
+				Cell: columnName(columnIndex) + strconv.Itoa(rowIndex+1),
+			})
+		}
+	}
+	return markers, nil
+}
+
+func parseLessonDateHeader(value string) (int, time.Month, int, bool) {
+	match := lessonDateHeader.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, 0, 0, false
+	}
+	day, _ := strconv.Atoi(match[1])
+	month, _ := strconv.Atoi(match[2])
+	year := 0
+	if match[3] != "" {
+		year, _ = strconv.Atoi(match[3])
+		if year < 100 {
+			year += 2000
+		}
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return 0, 0, 0, false
+	}
+	return year, time.Month(month), day, true
+}
+
+// attendanceTargets holds the group's students by sheet name and its
+// lessons in the term by date. A name or date that matches twice is left
+// out, as is a day with two lessons: such marks are skipped, not guessed.
+type attendanceTargets struct {
+	students map[string]int64
+	// lessons is keyed by full date and by day and month alone.
+	lessons map[string]int64
+}
+
+func lessonDateKey(year int, month time.Month, day int) string {
+	return fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+}
+
+func addUnambiguous(m map[string]int64, ambiguous map[string]struct{}, key string, id int64) {
+	if _, seen := ambiguous[key]; seen {
+		return
+	}
+	if existing, duplicate := m[key]; duplicate && existing != id {
+		delete(m, key)
+		ambiguous[key] = struct{}{}
+		return
+	}
+	m[key] = id
+}
+
+func loadAttendanceTargets(ctx context.Context, tx pgx.Tx, link Link) (attendanceTargets, error) {
+	targets := attendanceTargets{students: map[string]int64{}, lessons: map[string]int64{}}
+	rows, err := tx.Query(ctx, `SELECT u.id, u.first_name, u.middle_name, u.last_name
+        FROM math_center_students mcs
+        JOIN users u ON u.id = mcs.user_id
+        WHERE mcs.group_id = $1 AND mcs.term_id = $2`, *link.GroupID, link.TermID)
+	if err != nil {
+		return attendanceTargets{}, fmt.Errorf("listing attendance students: %w", err)
+	}
+	ambiguous := make(map[string]struct{})
+	for rows.Next() {
+		var user userName
+		if err := rows.Scan(&user.id, &user.firstName, &user.middleName, &user.lastName); err != nil {
+			rows.Close()
+			return attendanceTargets{}, fmt.Errorf("scanning attendance student: %w", err)
+		}
+		addUnambiguous(targets.students, ambiguous, normalizeCell(sheetPersonName(user)), user.id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return attendanceTargets{}, fmt.Errorf("iterating attendance students: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, `SELECT l.id, l.held_on
+        FROM math_center_lessons l
+        JOIN math_center_lesson_groups lg ON lg.lesson_id = l.id
+        WHERE lg.group_id = $1 AND l.term_id = $2`, *link.GroupID, link.TermID)
+	if err != nil {
+		return attendanceTargets{}, fmt.Errorf("listing attendance lessons: %w", err)
+	}
+	defer rows.Close()
+	ambiguous = make(map[string]struct{})
+	for rows.Next() {
+		var id int64
+		var heldOn time.Time
+		if err := rows.Scan(&id, &heldOn); err != nil {
+			return attendanceTargets{}, fmt.Errorf("scanning attendance lesson: %w", err)
+		}
+		addUnambiguous(targets.lessons, ambiguous, lessonDateKey(heldOn.Year(), heldOn.Month(), heldOn.Day()), id)
+		addUnambiguous(targets.lessons, ambiguous, lessonDateKey(0, heldOn.Month(), heldOn.Day()), id)
+	}
+	if err := rows.Err(); err != nil {
+		return attendanceTargets{}, fmt.Errorf("iterating attendance lessons: %w", err)
+	}
+	return targets, nil
+}
+
+// importAttendance records the «Посещаемость» marks inside the conduit
+// import transaction. Like solution markers, a mark only fills a blank: an
+// existing attendance mark is never overwritten from the sheet.
+func importAttendance(ctx context.Context, tx pgx.Tx, link Link, actorID int64, markers []attendanceMarker, summary *syncSummary) error {
+	if len(markers) == 0 {
+		return nil
+	}
+	targets, err := loadAttendanceTargets(ctx, tx, link)
+	if err != nil {
+		return err
+	}
+	q := store.New(tx)
+	for _, marker := range markers {
+		studentID, okStudent := targets.students[marker.StudentName]
+		lessonID, okLesson := targets.lessons[lessonDateKey(marker.Year, marker.Month, marker.Day)]
+		if !okStudent || !okLesson {
+			summary.Skipped++
+			continue
+		}
+		inserted, err := q.InsertImportedAttendance(ctx, store.InsertImportedAttendanceParams{
+			LessonID:          lessonID,
+			StudentUserID:     studentID,
+			Status:            marker.Status,
+			MarkedByUserID:    actorID,
+			GoogleSheetLinkID: link.ID,
+			GoogleSheetCell:   marker.Cell,
+		})
+		if err != nil {
+			return fmt.Errorf("recording imported attendance: %w", err)
+		}
+		if inserted {
+			summary.Attendance++
+		}
+	}
+	return nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/jackc/pgx/v5"
 
 	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/hwchain"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 )
@@ -344,8 +345,9 @@ func (s *Service) syncLink(ctx context.Context, link Link, actorID int64) (SyncR
 }
 
 type syncSummary struct {
-	Imported int `json:"imported"`
-	Skipped  int `json:"skipped"`
+	Imported   int `json:"imported"`
+	Skipped    int `json:"skipped"`
+	Attendance int `json:"attendance"`
 }
 
 type conduitMarker struct {
@@ -476,6 +478,10 @@ func (s *Service) importLink(ctx context.Context, link Link, actorID int64, vers
 	if err != nil {
 		return syncSummary{}, err
 	}
+	attendance, err := parseConduitAttendance(values)
+	if err != nil {
+		return syncSummary{}, err
+	}
 	targets, err := s.conduitTargets(ctx, link)
 	if err != nil {
 		return syncSummary{}, err
@@ -515,6 +521,10 @@ func (s *Service) importLink(ctx context.Context, link Link, actorID int64, vers
 		if err != nil {
 			return syncSummary{}, fmt.Errorf("creating imported solution event id: %w", err)
 		}
+		prev, err := q.LockEventChain(ctx, thread.ID)
+		if err != nil {
+			return syncSummary{}, fmt.Errorf("locking imported solution thread: %w", err)
+		}
 		verdict := homework.VerdictAccepted
 		event, err := q.AppendOfflineEvent(ctx, store.AppendOfflineEventParams{
 			ThreadID:           thread.ID,
@@ -532,6 +542,9 @@ func (s *Service) importLink(ctx context.Context, link Link, actorID int64, vers
             WHERE id = $4`, link.ID, marker.Cell, version, event.ID); err != nil {
 			return syncSummary{}, fmt.Errorf("recording imported solution source: %w", err)
 		}
+		if err := hwchain.Seal(ctx, q, prev, event); err != nil {
+			return syncSummary{}, fmt.Errorf("sealing imported solution event: %w", err)
+		}
 		if err := q.UpdateThreadAfterOfflineAccept(ctx, store.UpdateThreadAfterOfflineAcceptParams{
 			GradeEventID: event.ID, GraderName: marker.Initials, ID: thread.ID,
 		}); err != nil {
@@ -539,6 +552,9 @@ func (s *Service) importLink(ctx context.Context, link Link, actorID int64, vers
 		}
 		summary.Imported++
 	}
+	if err := importAttendance(ctx, tx, link, actorID, attendance, &summary); err != nil {
+		return syncSummary{}, err
+	}
 	if err := tx.Commit(ctx); err != nil {
 		return syncSummary{}, fmt.Errorf("committing google sheet import: %w", err)
 	}
@@ -261,3 +261,31 @@ func TestSplitSheetName(t *testing.T) {
 		t.Fatal("student identity matching must preserve exact spelling")
 	}
 }
+
+func TestParseConduitAttendance(t *testing.T) {
+	values := [][]string{
+		{"", "Серия 1", "Посещаемость", "", "", "КР"},
+		{"Фамилия Имя", "1", "03.09", "10.09.2026", "17.09", "1"},
+		{"Иванов Иван", "АБ", "+", "н", "?", "+"},
+		{"Петров Пётр", "", "ОП", "", "ув", ""},
+	}
+	markers, err := parseConduitAttendance(values)
+	if err != nil {
+		t.Fatalf("parseConduitAttendance() error = %v", err)
+	}
+	if len(markers) != 4 {
+		t.Fatalf("markers = %#v, want 4", markers)
+	}
+	if got := markers[0]; got.StudentName != "иванов иван" || got.Year != 0 || got.Month != 9 || got.Day != 3 || got.Status != "present" || got.Cell != "C3" {
+		t.Fatalf("first marker = %#v", got)
+	}
+	if got := markers[1]; got.StudentName != "петров петр" || got.Status != "late" {
+		t.Fatalf("second marker = %#v", got)
+	}
+	if got := markers[2]; got.Year != 2026 || got.Day != 10 || got.Status != "absent" || got.Cell != "D3" {
+		t.Fatalf("third marker = %#v", got)
+	}
+	if got := markers[3]; got.Day != 17 || got.Status != "excused" || got.Cell != "E4" {
+		t.Fatalf("fourth marker = %#v", got)
+	}
+}
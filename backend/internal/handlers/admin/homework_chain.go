@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/hwchain"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// VerifyThreadChain re-hashes one homework thread's event log and reports
+// every link that does not hold. For "I was accepted and then it vanished":
+// an ok chain proves no event was rewritten or removed since it was sealed.
+func VerifyThreadChain(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		threadID, err := pathInt64(r, "threadID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid thread id")
+			return
+		}
+		var result *hwchain.ThreadResult
+		err = hwchain.Verify(ctx, store.New(database.Pool()), hwchain.Scope{ThreadID: threadID}, func(t hwchain.ThreadResult) {
+			result = &t
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "admin: verify thread chain", err, "thread_id", threadID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to verify chain")
+			return
+		}
+		if result == nil {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "thread not found")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, result)
+	}
+}
+
+type centerChainResponse struct {
+	Threads int `json:"threads"`
+	Events  int `json:"events"`
+	Sealed  int `json:"sealed"`
+	// Broken lists only the threads whose chain failed.
+	Broken []hwchain.ThreadResult `json:"broken"`
+}
+
+// VerifyCenterChain re-hashes every thread of a math center and returns the
+// totals plus the threads whose chain is broken.
+func VerifyCenterChain(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		centerID, err := pathInt64(r, "id")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		resp := centerChainResponse{Broken: []hwchain.ThreadResult{}}
+		err = hwchain.Verify(ctx, store.New(database.Pool()), hwchain.Scope{MathCenterID: centerID}, func(t hwchain.ThreadResult) {
+			resp.Threads++
+			resp.Events += t.Events
+			resp.Sealed += t.Sealed
+			if !t.OK {
+				resp.Broken = append(resp.Broken, t)
+			}
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "admin: verify center chain", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to verify chain")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,103 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/hwchain"
+)
+
+var chainThreadColumns = []string{"id", "math_center_id", "series_id", "event_chain_head"}
+
+var chainEventColumns = []string{
+	"id", "thread_id", "event_uuid", "kind", "actor_user_id", "body", "verdict", "refers_to_event_id",
+	"created_at", "is_offline", "credited_grader_user_id", "credited_grader_name", "prev_hash", "hash",
+}
+
+// expectChain mocks a thread with a sealed submit + accept, where the stored
+// accept row has been edited to verdict after storedVerdict.
+func expectChain(mock pgxmock.PgxPoolIface, storedVerdict string) {
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	accepted := homework.VerdictAccepted
+	submitID := int64(10)
+	submit := homework.ChainEvent{ID: submitID, ThreadID: 1, EventUUID: "a", Kind: homework.KindSubmitted, ActorUserID: 7, Body: "x", CreatedAt: at}
+	grade := homework.ChainEvent{ID: 11, ThreadID: 1, EventUUID: "b", Kind: homework.KindGraded, ActorUserID: 3, Body: "ok",
+		Verdict: &accepted, RefersToEventID: &submitID, CreatedAt: at.Add(time.Hour)}
+	h1 := homework.EventHash([]byte{}, submit)
+	h2 := homework.EventHash(h1, grade)
+
+	mock.ExpectQuery(`SELECT id, math_center_id, series_id, event_chain_head\s+FROM homework_thread`).
+		WithArgs(int64(0), int64(1), int64(0), int32(500)).
+		WillReturnRows(mock.NewRows(chainThreadColumns).AddRow(int64(1), int64(42), int64(100), h2))
+	mock.ExpectQuery(`FROM homework_thread_event\s+WHERE thread_id = ANY`).
+		WithArgs([]int64{1}).
+		WillReturnRows(mock.NewRows(chainEventColumns).
+			AddRow(submit.ID, int64(1), "a", submit.Kind, int64(7), "x", (*string)(nil), (*int64)(nil),
+				at, false, (*int64)(nil), "", []byte{}, h1).
+			AddRow(grade.ID, int64(1), "b", grade.Kind, int64(3), "ok", &storedVerdict, &submitID,
+				grade.CreatedAt, false, (*int64)(nil), "", h1, h2))
+	mock.ExpectQuery(`SELECT id, math_center_id, series_id, event_chain_head\s+FROM homework_thread`).
+		WithArgs(int64(0), int64(1), int64(1), int32(500)).
+		WillReturnRows(mock.NewRows(chainThreadColumns))
+}
+
+func TestVerifyThreadChain(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name    string
+		verdict string
+		wantOK  bool
+	}{
+		{"intact", homework.VerdictAccepted, true},
+		{"verdict rewritten", homework.VerdictRejected, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mock, _ := pgxmock.NewPool()
+			defer mock.Close()
+			r, access := newAdminRouter(t, mock)
+			expectChain(mock, tc.verdict)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, adminRequest(t, access, true, http.MethodGet, "/homework/threads/1/chain", nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status: got %d, want 200, body=%s", rr.Code, rr.Body.String())
+			}
+			var got hwchain.ThreadResult
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.OK != tc.wantOK || got.Sealed != 2 {
+				t.Errorf("result = %+v; want ok=%t sealed=2", got, tc.wantOK)
+			}
+			if !tc.wantOK && (len(got.Breaks) != 1 || got.Breaks[0].EventID != 11) {
+				t.Errorf("breaks = %v; want one on event 11", got.Breaks)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyThreadChain_NotFound(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access := newAdminRouter(t, mock)
+	mock.ExpectQuery(`SELECT id, math_center_id, series_id, event_chain_head\s+FROM homework_thread`).
+		WithArgs(int64(0), int64(9), int64(0), int32(500)).
+		WillReturnRows(mock.NewRows(chainThreadColumns))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, adminRequest(t, access, true, http.MethodGet, "/homework/threads/9/chain", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status: got %d, want 404, body=%s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,256 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+const (
+	defaultStorageTopStudents = 20
+	maxStorageTopStudents     = 500
+)
+
+// storageUsage splits bytes by who uploaded them. Only StudentBytes counts
+// against the per-student quota; grader attachments are reported so the
+// bill adds up.
+type storageUsage struct {
+	StudentBytes int64 `json:"student_bytes"`
+	GraderBytes  int64 `json:"grader_bytes"`
+	Photos       int64 `json:"photos"`
+}
+
+func toStorageUsage(u store.StorageUsage) storageUsage {
+	return storageUsage{StudentBytes: u.StudentBytes, GraderBytes: u.GraderBytes, Photos: u.Photos}
+}
+
+type centerStorageView struct {
+	MathCenterID    int64 `json:"math_center_id"`
+	GraduationYear  int32 `json:"graduation_year"`
+	BytesPerStudent int64 `json:"bytes_per_student"`
+	QuotaIsDefault  bool  `json:"quota_is_default"`
+	storageUsage
+}
+
+type storageOverviewResponse struct {
+	DefaultBytesPerStudent int64               `json:"default_bytes_per_student"`
+	Centers                []centerStorageView `json:"centers"`
+}
+
+// GetStorageOverview lists photo storage per math center, newest cohort
+// first, with each center's per-student quota.
+func GetStorageOverview(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		rows, err := store.New(database.Pool()).ListCenterStorageUsage(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "admin: list storage usage", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to load storage usage")
+			return
+		}
+		resp := storageOverviewResponse{
+			DefaultBytesPerStudent: homework.DefaultStudentQuotaBytes,
+			Centers:                make([]centerStorageView, 0, len(rows)),
+		}
+		for _, c := range rows {
+			v := centerStorageView{
+				MathCenterID:    c.MathCenterID,
+				GraduationYear:  c.GraduationYear,
+				BytesPerStudent: homework.DefaultStudentQuotaBytes,
+				QuotaIsDefault:  c.BytesPerStudent == nil,
+				storageUsage:    toStorageUsage(c.StorageUsage),
+			}
+			if c.BytesPerStudent != nil {
+				v.BytesPerStudent = *c.BytesPerStudent
+			}
+			resp.Centers = append(resp.Centers, v)
+		}
+		httpx.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+type seriesStorageView struct {
+	SeriesID     int64 `json:"series_id"`
+	SeriesNumber int32 `json:"series_number"`
+	storageUsage
+}
+
+type termStorageView struct {
+	TermID int64  `json:"term_id"`
+	Kind   string `json:"kind"`
+	Grade  *int32 `json:"grade,omitempty"`
+	storageUsage
+	Series []seriesStorageView `json:"series"`
+}
+
+type studentStorageView struct {
+	UserID    int64  `json:"user_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Bytes     int64  `json:"bytes"`
+	Photos    int64  `json:"photos"`
+	OverQuota bool   `json:"over_quota"`
+}
+
+type centerStorageResponse struct {
+	MathCenterID      int64 `json:"math_center_id"`
+	BytesPerStudent   int64 `json:"bytes_per_student"`
+	QuotaIsDefault    bool  `json:"quota_is_default"`
+	StudentsOverQuota int64 `json:"students_over_quota"`
+	storageUsage
+	Terms []termStorageView `json:"terms"`
+	// TopStudents is the heaviest ?students=N (default 20) students.
+	TopStudents []studentStorageView `json:"top_students"`
+}
+
+// GetCenterStorage breaks one center's photo storage down by term and
+// series and lists its heaviest students against the quota.
+func GetCenterStorage(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		centerID, err := pathInt64(r, "id")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		limit := defaultStorageTopStudents
+		if raw := r.URL.Query().Get("students"); raw != "" {
+			limit, err = strconv.Atoi(raw)
+			if err != nil || limit < 0 || limit > maxStorageTopStudents {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "students must be between 0 and 500")
+				return
+			}
+		}
+
+		q := store.New(database.Pool())
+		resp := centerStorageResponse{
+			MathCenterID:    centerID,
+			BytesPerStudent: homework.DefaultStudentQuotaBytes,
+			QuotaIsDefault:  true,
+			Terms:           []termStorageView{},
+		}
+		quota, err := q.GetStorageQuota(ctx, centerID)
+		switch {
+		case err == nil:
+			resp.BytesPerStudent = quota.BytesPerStudent
+			resp.QuotaIsDefault = false
+		case !errors.Is(err, pgx.ErrNoRows):
+			logger.LogErrorContext(ctx, "admin: get storage quota", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to load storage usage")
+			return
+		}
+
+		series, err := q.ListSeriesStorageUsage(ctx, centerID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "admin: list series storage usage", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to load storage usage")
+			return
+		}
+		for _, s := range series {
+			// Rows come grouped by term, so a term is always the last one.
+			if n := len(resp.Terms); n == 0 || resp.Terms[n-1].TermID != s.TermID {
+				resp.Terms = append(resp.Terms, termStorageView{
+					TermID: s.TermID,
+					Kind:   s.TermKind,
+					Grade:  s.TermGrade,
+					Series: []seriesStorageView{},
+				})
+			}
+			term := &resp.Terms[len(resp.Terms)-1]
+			term.Series = append(term.Series, seriesStorageView{
+				SeriesID:     s.SeriesID,
+				SeriesNumber: s.SeriesNumber,
+				storageUsage: toStorageUsage(s.StorageUsage),
+			})
+			term.add(s.StorageUsage)
+			resp.add(s.StorageUsage)
+		}
+
+		students, err := q.ListStudentStorageUsage(ctx, centerID, int32(limit))
+		if err != nil {
+			logger.LogErrorContext(ctx, "admin: list student storage usage", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to load storage usage")
+			return
+		}
+		resp.TopStudents = make([]studentStorageView, 0, len(students))
+		for _, s := range students {
+			resp.TopStudents = append(resp.TopStudents, studentStorageView{
+				UserID:    s.UserID,
+				FirstName: s.FirstName,
+				LastName:  s.LastName,
+				Bytes:     s.Bytes,
+				Photos:    s.Photos,
+				OverQuota: homework.QuotaExceeded(s.Bytes, resp.BytesPerStudent),
+			})
+		}
+		resp.StudentsOverQuota, err = q.CountStudentsOverQuota(ctx, centerID, resp.BytesPerStudent)
+		if err != nil {
+			logger.LogErrorContext(ctx, "admin: count students over quota", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to load storage usage")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+func (u *storageUsage) add(s store.StorageUsage) {
+	u.StudentBytes += s.StudentBytes
+	u.GraderBytes += s.GraderBytes
+	u.Photos += s.Photos
+}
+
+type storageQuotaRequest struct {
+	BytesPerStudent int64 `json:"bytes_per_student"`
+}
+
+type storageQuotaResponse struct {
+	MathCenterID    int64     `json:"math_center_id"`
+	BytesPerStudent int64     `json:"bytes_per_student"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// PutStorageQuota sets a center's per-student photo quota. Lowering it
+// below what students already store deletes nothing; they just cannot
+// upload again until it is raised.
+func PutStorageQuota(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		centerID, err := pathInt64(r, "id")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		var req storageQuotaRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if err := homework.ValidateStudentQuotaBytes(req.BytesPerStudent); err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, err.Error())
+			return
+		}
+		row, err := store.New(database.Pool()).UpsertStorageQuota(ctx, centerID, req.BytesPerStudent)
+		if err != nil {
+			if isFKViolation(err) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "center not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "admin: save storage quota", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save storage quota")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, storageQuotaResponse{
+			MathCenterID:    row.MathCenterID,
+			BytesPerStudent: row.BytesPerStudent,
+			UpdatedAt:       row.UpdatedAt,
+		})
+	}
+}
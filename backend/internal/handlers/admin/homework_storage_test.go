@@ -0,0 +1,186 @@
+package admin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+)
+
+func TestGetStorageOverview(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access := newAdminRouter(t, mock)
+
+	custom := int64(1 << 30)
+	mock.ExpectQuery(`WITH usage .* FROM math_centers c`).
+		WillReturnRows(mock.NewRows([]string{"id", "graduation_year", "bytes_per_student", "student_bytes", "grader_bytes", "photos"}).
+			AddRow(int64(42), int32(2028), &custom, int64(3000), int64(500), int64(4)).
+			AddRow(int64(41), int32(2027), (*int64)(nil), int64(0), int64(0), int64(0)))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, adminRequest(t, access, true, http.MethodGet, "/homework/storage", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200, body=%s", rr.Code, rr.Body.String())
+	}
+	var got struct {
+		DefaultBytesPerStudent int64 `json:"default_bytes_per_student"`
+		Centers                []struct {
+			MathCenterID    int64 `json:"math_center_id"`
+			BytesPerStudent int64 `json:"bytes_per_student"`
+			QuotaIsDefault  bool  `json:"quota_is_default"`
+			StudentBytes    int64 `json:"student_bytes"`
+			GraderBytes     int64 `json:"grader_bytes"`
+		} `json:"centers"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.DefaultBytesPerStudent != homework.DefaultStudentQuotaBytes || len(got.Centers) != 2 {
+		t.Fatalf("got %+v", got)
+	}
+	if c := got.Centers[0]; c.BytesPerStudent != custom || c.QuotaIsDefault || c.StudentBytes != 3000 || c.GraderBytes != 500 {
+		t.Errorf("center 42 = %+v", c)
+	}
+	if c := got.Centers[1]; c.BytesPerStudent != homework.DefaultStudentQuotaBytes || !c.QuotaIsDefault {
+		t.Errorf("center 41 = %+v; want the default quota", c)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetCenterStorage_GroupsByTermAndSeries(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access := newAdminRouter(t, mock)
+
+	quota := homework.MinStudentQuotaBytes
+	grade9, grade8 := int32(9), int32(8)
+	mock.ExpectQuery(`FROM homework_storage_quota\s+WHERE math_center_id`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows([]string{"math_center_id", "bytes_per_student", "updated_at"}).
+			AddRow(int64(42), quota, time.Now()))
+	mock.ExpectQuery(`FROM homework_thread_event_photo p.*JOIN math_center_terms`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows([]string{"term_id", "kind", "grade", "series_id", "number", "student_bytes", "grader_bytes", "photos"}).
+			AddRow(int64(2), "academic", &grade9, int64(100), int32(1), int64(1000), int64(0), int64(2)).
+			AddRow(int64(2), "academic", &grade9, int64(101), int32(2), int64(2000), int64(300), int64(3)).
+			AddRow(int64(1), "academic", &grade8, int64(90), int32(1), int64(50), int64(0), int64(1)))
+	mock.ExpectQuery(`SELECT u.id, u.first_name, u.last_name, SUM\(p.size_bytes\)`).
+		WithArgs(int64(42), int32(5)).
+		WillReturnRows(mock.NewRows([]string{"id", "first_name", "last_name", "bytes", "photos"}).
+			AddRow(int64(7), "Ann", "Lee", quota, int64(10)).
+			AddRow(int64(8), "Bob", "Ray", int64(1000), int64(1)))
+	mock.ExpectQuery(`HAVING SUM\(p.size_bytes\) >= \$2`).
+		WithArgs(int64(42), quota).
+		WillReturnRows(mock.NewRows([]string{"count"}).AddRow(int64(1)))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, adminRequest(t, access, true, http.MethodGet, "/mathcenter/42/homework/storage?students=5", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200, body=%s", rr.Code, rr.Body.String())
+	}
+	var got struct {
+		BytesPerStudent   int64 `json:"bytes_per_student"`
+		QuotaIsDefault    bool  `json:"quota_is_default"`
+		StudentsOverQuota int64 `json:"students_over_quota"`
+		StudentBytes      int64 `json:"student_bytes"`
+		GraderBytes       int64 `json:"grader_bytes"`
+		Terms             []struct {
+			TermID       int64 `json:"term_id"`
+			StudentBytes int64 `json:"student_bytes"`
+			Series       []struct {
+				SeriesID int64 `json:"series_id"`
+			} `json:"series"`
+		} `json:"terms"`
+		TopStudents []struct {
+			UserID    int64 `json:"user_id"`
+			OverQuota bool  `json:"over_quota"`
+		} `json:"top_students"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.BytesPerStudent != quota || got.QuotaIsDefault || got.StudentsOverQuota != 1 {
+		t.Errorf("quota fields = %+v", got)
+	}
+	if got.StudentBytes != 3050 || got.GraderBytes != 300 {
+		t.Errorf("totals = %d / %d; want 3050 / 300", got.StudentBytes, got.GraderBytes)
+	}
+	if len(got.Terms) != 2 || got.Terms[0].TermID != 2 || len(got.Terms[0].Series) != 2 ||
+		got.Terms[0].StudentBytes != 3000 || len(got.Terms[1].Series) != 1 {
+		t.Errorf("terms = %+v", got.Terms)
+	}
+	if len(got.TopStudents) != 2 || !got.TopStudents[0].OverQuota || got.TopStudents[1].OverQuota {
+		t.Errorf("top students = %+v", got.TopStudents)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPutStorageQuota(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access := newAdminRouter(t, mock)
+
+	quota := int64(1 << 30)
+	mock.ExpectQuery(`INSERT INTO homework_storage_quota`).
+		WithArgs(int64(42), quota).
+		WillReturnRows(mock.NewRows([]string{"math_center_id", "bytes_per_student", "updated_at"}).
+			AddRow(int64(42), quota, time.Now()))
+
+	body, _ := json.Marshal(map[string]any{"bytes_per_student": quota})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, adminRequest(t, access, true, http.MethodPut, "/mathcenter/42/homework/storage/quota", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200, body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPutStorageQuota_Rejects(t *testing.T) {
+	t.Parallel()
+	t.Run("below one event", func(t *testing.T) {
+		t.Parallel()
+		mock, _ := pgxmock.NewPool()
+		defer mock.Close()
+		r, access := newAdminRouter(t, mock)
+
+		body, _ := json.Marshal(map[string]any{"bytes_per_student": homework.MinStudentQuotaBytes - 1})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, adminRequest(t, access, true, http.MethodPut, "/mathcenter/42/homework/storage/quota", bytes.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("status: got %d, want 400", rr.Code)
+		}
+	})
+	t.Run("unknown center", func(t *testing.T) {
+		t.Parallel()
+		mock, _ := pgxmock.NewPool()
+		defer mock.Close()
+		r, access := newAdminRouter(t, mock)
+
+		mock.ExpectQuery(`INSERT INTO homework_storage_quota`).
+			WithArgs(int64(99), homework.DefaultStudentQuotaBytes).
+			WillReturnError(&pgconn.PgError{Code: "23503"})
+		body, _ := json.Marshal(map[string]any{"bytes_per_student": homework.DefaultStudentQuotaBytes})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, adminRequest(t, access, true, http.MethodPut, "/mathcenter/99/homework/storage/quota", bytes.NewReader(body)))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("status: got %d, want 404", rr.Code)
+		}
+	})
+}
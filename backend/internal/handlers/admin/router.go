@@ -50,7 +50,17 @@ func Router(database *db.DB, tokens *internalAuth.TokenService) chi.Router {
 		// Shared "MathCenter" classroom login, provisioned as a head teacher
 		// of {id}. See CreateMathCenterAccount.
 		r.Post("/{id}/accounts", CreateMathCenterAccount(database))
+
+		// Homework event hash chain: re-hash the log and report broken links.
+		r.Get("/{id}/homework/chain", VerifyCenterChain(database))
+
+		// Photo storage: usage by term and series, per-student quota.
+		r.Get("/{id}/homework/storage", GetCenterStorage(database))
+		r.Put("/{id}/homework/storage/quota", PutStorageQuota(database))
 	})
 
+	r.Get("/homework/threads/{threadID}/chain", VerifyThreadChain(database))
+	r.Get("/homework/storage", GetStorageOverview(database))
+
 	return r
 }
@@ -0,0 +1,173 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/calendar"
+	"github.com/Alarion239/my239/backend/internal/ctxcache"
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+type feedStatusView struct {
+	Enabled   bool       `json:"enabled"`
+	IsTeacher bool       `json:"is_teacher"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// feedURLsView is returned once, when the token is minted. TeacherURL is
+// set for teachers of any center.
+type feedURLsView struct {
+	URL        string    `json:"url"`
+	TeacherURL *string   `json:"teacher_url"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func requireUser(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := ctxcache.UserID(r.Context())
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusUnauthorized, httpx.CodeUnauthenticated, "unauthenticated")
+		return 0, false
+	}
+	return userID, true
+}
+
+func isTeacher(ctx context.Context, q *store.Queries, userID int64) (bool, error) {
+	centers, err := q.ListCentersForTeacher(ctx, userID)
+	return len(centers) > 0, err
+}
+
+// GetFeed reports whether the caller has a feed. The URL itself is not
+// recoverable; rotate to get a new one.
+func GetFeed(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		q := store.New(database.Pool())
+		teacher, err := isTeacher(ctx, q, userID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "calendar: teacher lookup", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		out := feedStatusView{IsTeacher: teacher}
+		feed, err := q.GetCalendarFeed(ctx, userID)
+		switch {
+		case err == nil:
+			out.Enabled, out.CreatedAt = true, &feed.CreatedAt
+		case !errors.Is(err, pgx.ErrNoRows):
+			logger.LogErrorContext(ctx, "calendar: get feed", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// RotateFeed mints a new feed token for the caller, invalidating the old
+// URL, and returns the feed URLs.
+func RotateFeed(database *db.DB, baseURL string) http.HandlerFunc {
+	base := strings.TrimRight(baseURL, "/") + "/api/v1/calendar/feeds/"
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		q := store.New(database.Pool())
+		teacher, err := isTeacher(ctx, q, userID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "calendar: teacher lookup", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		raw, hash, err := calendar.NewToken()
+		if err != nil {
+			logger.LogErrorContext(ctx, "calendar: mint token", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		feed, err := q.UpsertCalendarFeed(ctx, userID, hash)
+		if err != nil {
+			logger.LogErrorContext(ctx, "calendar: save feed", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to create feed")
+			return
+		}
+		out := feedURLsView{URL: base + raw + "/calendar.ics", CreatedAt: feed.CreatedAt}
+		if teacher {
+			teacherURL := base + raw + "/teaching.ics"
+			out.TeacherURL = &teacherURL
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// DeleteFeed turns the caller's feed off; subscribed clients get 404.
+func DeleteFeed(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		if err := store.New(database.Pool()).DeleteCalendarFeed(ctx, userID); err != nil {
+			logger.LogErrorContext(ctx, "calendar: delete feed", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to delete feed")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// MemberFeed serves the deadlines, releases, likbez and lessons of every
+// center the token's owner belongs to.
+func MemberFeed(database *db.DB) http.HandlerFunc {
+	return serveFeed(database, "my239", calendar.MemberEvents)
+}
+
+// TeachingFeed serves the grading dates of the centers the token's owner
+// teaches.
+func TeachingFeed(database *db.DB) http.HandlerFunc {
+	return serveFeed(database, "my239 · проверка", calendar.TeachingEvents)
+}
+
+func serveFeed(database *db.DB, name string, collect func(context.Context, *store.Queries, int64) ([]calendar.Event, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		q := store.New(database.Pool())
+		feed, err := q.GetCalendarFeedByToken(ctx, calendar.HashToken(chi.URLParam(r, "token")))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "feed not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "calendar: resolve feed", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		events, err := collect(ctx, q, feed.UserID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "calendar: collect events", err, "user_id", feed.UserID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to build feed")
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		w.WriteHeader(http.StatusOK)
+		if err := calendar.Encode(w, name, time.Now(), events); err != nil {
+			logger.LogErrorContext(ctx, "calendar: write feed", err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package calendar_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+
+	internalAuth "github.com/Alarion239/my239/backend/internal/auth"
+	"github.com/Alarion239/my239/backend/internal/calendar"
+	calendarHandlers "github.com/Alarion239/my239/backend/internal/handlers/calendar"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/ratelimit"
+)
+
+func newRouter(t *testing.T, mock pgxmock.PgxPoolIface) (http.Handler, *internalAuth.AccessTokenService) {
+	t.Helper()
+	database := db.NewWithPool(mock)
+	access, err := internalAuth.NewAccessTokenService(internalAuth.AccessTokenConfig{
+		Secret: "test-secret", Issuer: "test-issuer", Audience: "test-audience", Expiration: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("access service: %v", err)
+	}
+	refresh, err := internalAuth.NewRefreshTokenService(internalAuth.RefreshTokenConfig{DB: database, Expiration: time.Hour})
+	if err != nil {
+		t.Fatalf("refresh service: %v", err)
+	}
+	tokens, err := internalAuth.NewTokenService(internalAuth.TokenServiceConfig{Access: access, Refresh: refresh})
+	if err != nil {
+		t.Fatalf("token service: %v", err)
+	}
+	return calendarHandlers.Router(database, tokens, ratelimit.NewMemory(), "https://my239.example/"), access
+}
+
+func authedRequest(t *testing.T, access *internalAuth.AccessTokenService, userID int64, method, path string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	tok, err := access.Generate(userID, fmt.Sprintf("user%d", userID), false)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tok)
+	return req
+}
+
+func TestFeed_UnknownTokenIsNotFound(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, _ := newRouter(t, mock)
+
+	mock.ExpectQuery(`FROM calendar_feeds WHERE token_hash`).
+		WithArgs(calendar.HashToken("nope")).
+		WillReturnRows(mock.NewRows([]string{"user_id", "created_at"}))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/feeds/nope/calendar.ics", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("got %d, want 404; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestMemberFeed_ServesDeadlines(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, _ := newRouter(t, mock)
+
+	grade := int32(9)
+	due := time.Date(2026, 9, 10, 20, 59, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM calendar_feeds WHERE token_hash`).
+		WithArgs(calendar.HashToken("secret")).
+		WillReturnRows(mock.NewRows([]string{"user_id", "created_at"}).AddRow(int64(7), time.Now()))
+	mock.ExpectQuery(`FROM math_center_series se`).
+		WithArgs(int64(7)).
+		WillReturnRows(mock.NewRows([]string{"id", "number", "name", "due_at", "published", "hours", "kind", "grade"}).
+			AddRow(int64(100), int32(1), "Графы", due, true, (*int32)(nil), "academic", &grade))
+	mock.ExpectQuery(`FROM math_center_subproblem_solutions ss`).
+		WithArgs(int64(7)).
+		WillReturnRows(mock.NewRows([]string{"id", "number", "name", "released_at", "numbers", "labels", "kind", "grade"}))
+	mock.ExpectQuery(`FROM math_center_publication_schedule ps`).
+		WithArgs(int64(7)).
+		WillReturnRows(mock.NewRows([]string{"id", "target_kind", "publish_at", "series_number", "series_name",
+			"likbez_number", "likbez_title", "releases_coffins", "kind", "grade"}))
+	mock.ExpectQuery(`FROM math_center_likbez l`).
+		WithArgs(int64(7)).
+		WillReturnRows(mock.NewRows([]string{"id", "number", "title", "held_on", "kind", "grade"}))
+	mock.ExpectQuery(`FROM math_center_lessons l`).
+		WithArgs(int64(7)).
+		WillReturnRows(mock.NewRows([]string{"id", "held_on", "starts_at", "ends_at", "topic", "groups", "kind", "grade"}))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/feeds/secret/calendar.ics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("content type = %q", ct)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"UID:series-100-due@my239", "DTSTART:20260910T205900Z", "SUMMARY:Дедлайн: Серия 1. Графы"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("feed lacks %q:\n%s", want, body)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRotateFeed_ReturnsTeacherURL(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access := newRouter(t, mock)
+
+	mock.ExpectQuery(`FROM math_center_teachers`).
+		WithArgs(int64(3)).
+		WillReturnRows(mock.NewRows([]string{"id", "graduation_year", "is_head_teacher"}).AddRow(int64(42), int32(2030), false))
+	mock.ExpectQuery(`INSERT INTO calendar_feeds`).
+		WithArgs(int64(3), pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"user_id", "created_at"}).AddRow(int64(3), time.Now()))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, authedRequest(t, access, 3, http.MethodPost, "/feed"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		URL        string  `json:"url"`
+		TeacherURL *string `json:"teacher_url"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	const prefix = "https://my239.example/api/v1/calendar/feeds/"
+	if !strings.HasPrefix(resp.URL, prefix) || !strings.HasSuffix(resp.URL, "/calendar.ics") {
+		t.Errorf("url = %q", resp.URL)
+	}
+	if resp.TeacherURL == nil || !strings.HasSuffix(*resp.TeacherURL, "/teaching.ics") {
+		t.Errorf("teacher_url = %v", resp.TeacherURL)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
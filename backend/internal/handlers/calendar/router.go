@@ -0,0 +1,36 @@
+// Package calendar exposes the /api/v1/calendar HTTP surface: managing a
+// user's secret feed URL and serving the iCalendar feeds behind it.
+package calendar
+
+import (
+	"github.com/go-chi/chi/v5"
+
+	internalAuth "github.com/Alarion239/my239/backend/internal/auth"
+	"github.com/Alarion239/my239/backend/internal/middleware"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/ratelimit"
+)
+
+// Router mounts the calendar routes. The feeds themselves are public: the
+// token in the path is the credential, because calendar clients subscribe
+// without a session. baseURL is the public origin the feed URLs are built
+// on (the API is served under it, as for the Telegram webhook).
+func Router(database *db.DB, tokens *internalAuth.TokenService, limiter ratelimit.Limiter, baseURL string) chi.Router {
+	r := chi.NewRouter()
+
+	r.With(limiter.Middleware("calendar.feed", 60, 60)).
+		Get("/feeds/{token}/calendar.ics", MemberFeed(database))
+	r.With(limiter.Middleware("calendar.feed", 60, 60)).
+		Get("/feeds/{token}/teaching.ics", TeachingFeed(database))
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(tokens.Access()))
+		r.Use(middleware.ImpersonationMiddleware(database))
+		r.Get("/feed", GetFeed(database))
+		// Creating and rotating are the same call; the URL is shown once.
+		r.Post("/feed", RotateFeed(database, baseURL))
+		r.Delete("/feed", DeleteFeed(database))
+	})
+
+	return r
+}
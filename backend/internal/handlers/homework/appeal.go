@@ -84,6 +84,9 @@ func AppealGrade(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.H
 			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
 			return
 		}
+		if !requireStorageFor(ctx, w, r, q, userID, thread.MathCenterID, photos) {
+			return
+		}
 
 		if err := writeAttempt(ctx, database, thread.ID, req.EventUUID, homework.KindAppealed, userID, body, photos, thread.CurrentGradeEventID); err != nil {
 			logger.LogErrorContext(ctx, "homework: appeal tx", err)
@@ -66,10 +66,12 @@ func TestAppeal_HappyPath(t *testing.T) {
 		}, now)...))
 
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), "u", "appealed", int64(7), "please regrade", (*string)(nil), &gradeID).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(70), int64(1), "u", "appealed", int64(7), "please regrade", (*string)(nil), &gradeID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(70), int64(1))
 	newAttempt := int64(70)
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= 'appealed'`).
 		WithArgs(int64(1), &newAttempt).
@@ -21,6 +21,10 @@ func TestRouter_RequiresAuth(t *testing.T) {
 	}{
 		{http.MethodPost, "/threads/1/upload-urls"},
 		{http.MethodPost, "/threads/1/submit"},
+		{http.MethodGet, "/threads/1/draft"},
+		{http.MethodPut, "/threads/1/draft"},
+		{http.MethodDelete, "/threads/1/draft"},
+		{http.MethodPost, "/threads/1/draft/submit"},
 		{http.MethodPost, "/threads/1/appeal"},
 		{http.MethodGet, "/threads/by-id/1"},
 		{http.MethodPost, "/threads/by-id/1/upload-urls"},
@@ -33,6 +37,9 @@ func TestRouter_RequiresAuth(t *testing.T) {
 		{http.MethodGet, "/series/1/queue"},
 		{http.MethodGet, "/series/1/grid"},
 		{http.MethodGet, "/centers/1/grader-stats"},
+		{http.MethodPost, "/centers/1/bulk-grade"},
+		{http.MethodGet, "/centers/1/reason-codes"},
+		{http.MethodPut, "/centers/1/reason-codes"},
 		{http.MethodGet, "/centers/1/grid"},
 	}
 	for _, c := range cases {
@@ -0,0 +1,296 @@
+package homework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/hwchain"
+	"github.com/Alarion239/my239/backend/internal/live"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/metrics"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// maxBulkGrade caps one bulk request; the queue page selects at most a
+// series' worth of warm-ups at a time.
+const maxBulkGrade = 200
+
+// bulkGradeRequest is the body of /bulk-grade: one verdict and one comment
+// for every listed thread, and optionally one reason code for a rejection.
+// Photos are not supported in bulk.
+type bulkGradeRequest struct {
+	Verdict    string  `json:"verdict"`
+	Body       string  `json:"body"`
+	ReasonCode string  `json:"reason_code"`
+	ThreadIDs  []int64 `json:"thread_ids"`
+}
+
+// Per-thread outcomes of a bulk grade.
+const (
+	bulkGraded    = "graded"
+	bulkConflict  = "conflict"
+	bulkForbidden = "forbidden"
+	bulkNotFound  = "not_found"
+)
+
+type bulkGradeResult struct {
+	ThreadID int64  `json:"thread_id"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+type bulkGradeResponse struct {
+	Graded  int               `json:"graded"`
+	Results []bulkGradeResult `json:"results"`
+}
+
+// bulkGradedThread is what the post-commit bookkeeping needs for one verdict.
+type bulkGradedThread struct {
+	thread store.HomeworkThread
+	event  store.HomeworkThreadEvent
+	claim  *store.AttemptClaim
+}
+
+// BulkGrade — teacher of the center. Claims, grades and releases every
+// listed thread with the same verdict and comment in one transaction.
+// Each thread goes through the checks Claim and Grade make: the status
+// must allow a grade, appeals stay with the original grader (or an admin),
+// and a live claim by another grader is a conflict. Threads that fail a
+// check are reported and skipped; the rest commit together. One live event
+// is published per affected series.
+func BulkGrade(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+
+		var req bulkGradeRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		threadIDs, verdict, body, vErr := validateBulkGradeInput(req)
+		if vErr != "" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
+			return
+		}
+
+		q := store.New(database.Pool())
+		access, ok := teacherAccess(ctx, w, r, q, userID, centerID, 0, mc.CanGrade)
+		if !ok {
+			return
+		}
+		reason, ok := resolveReasonCode(ctx, w, r, q, centerID, req.ReasonCode, homework.KindGraded)
+		if !ok {
+			return
+		}
+
+		results, graded, err := writeBulkGrade(ctx, database, centerID, access.TermID, threadIDs, userID, callerIsAdmin(r), verdict, body, reasonID(reason))
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: bulk grade tx", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to record grades")
+			return
+		}
+
+		published := map[int64]bool{}
+		for _, g := range graded {
+			if g.claim != nil {
+				metrics.HomeworkClaimWaitSeconds.Observe(g.claim.ClaimedAt.Sub(g.claim.SubmittedAt).Seconds())
+			}
+			observeTurnaround(ctx, q, g.thread, g.event)
+			if !published[g.thread.SeriesID] {
+				published[g.thread.SeriesID] = true
+				live.Publish(ctx, database.Pool(), live.Event{CenterID: centerID, Kind: live.KindGrading, SeriesID: g.thread.SeriesID})
+			}
+		}
+		httpx.WriteJSON(w, http.StatusOK, bulkGradeResponse{Graded: len(graded), Results: results})
+	}
+}
+
+// validateBulkGradeInput applies the single-grade rules to verdict and body
+// and returns the thread ids deduplicated in ascending order, which is also
+// the order rows are locked in so two bulk grades cannot deadlock.
+func validateBulkGradeInput(req bulkGradeRequest) (ids []int64, verdict, body, errMsg string) {
+	verdict, body, errMsg = validateVerdictBody(req.Verdict, req.Body, req.ReasonCode)
+	if errMsg != "" {
+		return nil, "", "", errMsg
+	}
+	if len(req.ThreadIDs) == 0 {
+		return nil, "", "", "thread_ids is required"
+	}
+	seen := make(map[int64]bool, len(req.ThreadIDs))
+	for _, id := range req.ThreadIDs {
+		if id <= 0 {
+			return nil, "", "", "invalid thread id"
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > maxBulkGrade {
+		return nil, "", "", fmt.Sprintf("at most %d threads per request", maxBulkGrade)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, verdict, body, ""
+}
+
+// writeBulkGrade runs every thread through claim → graded event → cache
+// update in one transaction. A failed check only skips its thread, and a
+// verdict that loses its claim is rolled back to the thread's savepoint and
+// reported as a conflict; any database error rolls back the whole batch. A non-nil termID keeps a
+// term-scoped grader to that term's series.
+func writeBulkGrade(ctx context.Context, database *db.DB, centerID int64, termID *int64, threadIDs []int64, graderUserID int64, isAdmin bool, verdict, body string, reasonCodeID *int64) ([]bulkGradeResult, []bulkGradedThread, error) {
+	tx, err := database.Pool().Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qx := store.New(tx)
+	results := make([]bulkGradeResult, 0, len(threadIDs))
+	var graded []bulkGradedThread
+	for _, id := range threadIDs {
+		res := bulkGradeResult{ThreadID: id}
+		// The chain lock comes first so the status read below cannot go
+		// stale before the verdict lands.
+		prev, err := qx.LockEventChain(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			res.Status, res.Error = bulkNotFound, "thread not found"
+			results = append(results, res)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("lock event chain %d: %w", id, err)
+		}
+		thread, err := qx.GetThread(ctx, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get thread %d: %w", id, err)
+		}
+		if thread.MathCenterID != centerID {
+			res.Status, res.Error = bulkNotFound, "thread not found"
+			results = append(results, res)
+			continue
+		}
+		if termID != nil {
+			inTerm, err := qx.SeriesInTerm(ctx, thread.SeriesID, *termID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("series term %d: %w", id, err)
+			}
+			if !inTerm {
+				res.Status, res.Error = bulkForbidden, "your teacher role is limited to another term"
+				results = append(results, res)
+				continue
+			}
+		}
+		if err := homework.CanTransition(thread.CurrentStatus, homework.KindGraded); err != nil {
+			res.Status, res.Error = bulkConflict, err.Error()
+			results = append(results, res)
+			continue
+		}
+		if thread.CurrentStatus == homework.StatusAppealed {
+			if !isAdmin && (thread.LastGraderUserID == nil || *thread.LastGraderUserID != graderUserID) {
+				res.Status, res.Error = bulkForbidden, "appeals must be answered by the original grader"
+				results = append(results, res)
+				continue
+			}
+		}
+		if err := qx.Savepoint(ctx); err != nil {
+			return nil, nil, fmt.Errorf("savepoint %d: %w", id, err)
+		}
+		claimed, err := qx.TryClaim(ctx, store.TryClaimParams{ID: id, GraderUserID: graderUserID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			res.Status, res.Error = bulkConflict, "thread is currently claimed by another grader"
+			results = append(results, res)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("try claim %d: %w", id, err)
+		}
+		g := bulkGradedThread{thread: claimed}
+		if claimed.CurrentAttemptEventID != nil {
+			first, err := qx.RecordAttemptClaim(ctx, *claimed.CurrentAttemptEventID, graderUserID)
+			switch {
+			case err == nil:
+				g.claim = &first
+			case !errors.Is(err, pgx.ErrNoRows):
+				return nil, nil, fmt.Errorf("record claim %d: %w", id, err)
+			}
+		}
+
+		eventUUID, err := homework.NewEventUUID()
+		if err != nil {
+			return nil, nil, err
+		}
+		event, err := qx.AppendEvent(ctx, store.AppendEventParams{
+			ThreadID:        id,
+			EventUuid:       eventUUID,
+			Kind:            homework.KindGraded,
+			ActorUserID:     graderUserID,
+			Body:            body,
+			Verdict:         &verdict,
+			RefersToEventID: claimed.CurrentAttemptEventID,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("append grade event %d: %w", id, err)
+		}
+		if err := hwchain.Seal(ctx, qx, prev, event); err != nil {
+			return nil, nil, err
+		}
+		if reasonCodeID != nil {
+			if err := qx.SetEventReason(ctx, event.ID, *reasonCodeID); err != nil {
+				return nil, nil, fmt.Errorf("set grade reason %d: %w", id, err)
+			}
+		}
+		// Same claim re-check as writeGrade; it also releases the claim.
+		affected, err := qx.UpdateThreadAfterGrade(ctx, store.UpdateThreadAfterGradeParams{
+			Verdict:      verdict,
+			GradeEventID: event.ID,
+			GraderUserID: graderUserID,
+			ID:           id,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("update thread after grade %d: %w", id, err)
+		}
+		if affected == 0 {
+			if err := qx.RollbackToSavepoint(ctx); err != nil {
+				return nil, nil, fmt.Errorf("rollback thread %d: %w", id, err)
+			}
+			res.Status, res.Error = bulkConflict, "claim expired or held by another grader"
+			results = append(results, res)
+			continue
+		}
+		if _, err := qx.SampleCalibration(ctx, store.SampleCalibrationParams{
+			ThreadID:          id,
+			GradedEventID:     event.ID,
+			FirstGraderUserID: graderUserID,
+			FirstVerdict:      verdict,
+			MathCenterID:      centerID,
+		}); err != nil {
+			return nil, nil, fmt.Errorf("sample calibration %d: %w", id, err)
+		}
+		g.event = event
+		graded = append(graded, g)
+		res.Status = bulkGraded
+		results = append(results, res)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return results, graded, nil
+}
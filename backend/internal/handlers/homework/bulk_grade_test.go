@@ -0,0 +1,258 @@
+package homework_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type bulkGradeResponse struct {
+	Graded  int `json:"graded"`
+	Results []struct {
+		ThreadID int64  `json:"thread_id"`
+		Status   string `json:"status"`
+		Error    string `json:"error"`
+	} `json:"results"`
+}
+
+// expectBulkThread adds the lock + fresh read every thread in a bulk grade
+// starts with.
+func expectBulkThread(mock pgxmock.PgxPoolIface, threadID, centerID int64, opts threadRowOpts, now time.Time) {
+	expectChainLock(mock, threadID)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(threadID).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(threadID, 7, 900+threadID, 100, centerID, opts, now)...))
+}
+
+func expectSavepoint(mock pgxmock.PgxPoolIface) {
+	mock.ExpectExec(`^SAVEPOINT batch_item$`).WillReturnResult(pgxmock.NewResult("SAVEPOINT", 0))
+}
+
+// expectBulkGraded adds claim → graded event → cache update → calibration
+// for one thread that passes every check.
+func expectBulkGraded(mock pgxmock.PgxPoolIface, threadID, attemptID, eventID int64, now time.Time) {
+	expectSavepoint(mock)
+	mock.ExpectQuery(`UPDATE homework_thread\s+SET claim_holder_user_id`).
+		WithArgs(int64(3), threadID).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(threadID, 7, 900+threadID, 100, 42, threadRowOpts{
+			Status: "submitted", AttemptEventID: &attemptID, ClaimHolderID: ptr64(3), ClaimExpiresAt: ptrTime(now.Add(15 * time.Minute)),
+		}, now)...))
+	mock.ExpectQuery(`INSERT INTO homework_attempt_claim`).
+		WithArgs(attemptID, int64(3)).
+		WillReturnRows(mock.NewRows([]string{"claimed_at", "created_at"}).AddRow(now, now.Add(-time.Hour)))
+	verdict := "accepted"
+	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
+		WithArgs(threadID, pgxmock.AnyArg(), "graded", int64(3), "ok", &verdict, &attemptID).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(eventID, threadID, "u", "graded", int64(3), "ok", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, eventID, threadID)
+	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= CASE`).
+		WithArgs("accepted", eventID, int64(3), threadID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectCalibrationSample(mock, threadID, eventID, 3, "accepted", 42, false)
+}
+
+func postBulkGrade(t *testing.T, r http.Handler, req *http.Request) (*httptest.ResponseRecorder, bulkGradeResponse) {
+	t.Helper()
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	var out bulkGradeResponse
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rr, out
+}
+
+func TestBulkGrade_GradesFreeThreadsAndReportsConflicts(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectBegin()
+	expectBulkThread(mock, 1, 42, threadRowOpts{Status: "submitted", AttemptEventID: ptr64(50)}, now)
+	expectBulkGraded(mock, 1, 50, 80, now)
+	expectBulkThread(mock, 2, 42, threadRowOpts{Status: "submitted", AttemptEventID: ptr64(51)}, now)
+	expectBulkGraded(mock, 2, 51, 81, now)
+	// Thread 3 is held by another grader.
+	expectBulkThread(mock, 3, 42, threadRowOpts{
+		Status: "submitted", AttemptEventID: ptr64(52), ClaimHolderID: ptr64(9), ClaimExpiresAt: ptrTime(now.Add(time.Minute)),
+	}, now)
+	expectSavepoint(mock)
+	mock.ExpectQuery(`UPDATE homework_thread\s+SET claim_holder_user_id`).
+		WithArgs(int64(3), int64(3)).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectCommit()
+	expectAttemptSubmittedAt(mock, 50, now.Add(-time.Hour))
+	// Both verdicts are in series 100: one coalesced live event.
+	mock.ExpectExec(`pg_notify`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	expectAttemptSubmittedAt(mock, 51, now.Add(-time.Hour))
+
+	body, _ := json.Marshal(map[string]any{"verdict": "accepted", "body": "ok", "thread_ids": []int64{3, 1, 2, 1}})
+	rr, out := postBulkGrade(t, r, authedRequest(t, access, 3, false, http.MethodPost, "/centers/42/bulk-grade", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if out.Graded != 2 || len(out.Results) != 3 {
+		t.Fatalf("got %+v, want 2 graded of 3", out)
+	}
+	want := map[int64]string{1: "graded", 2: "graded", 3: "conflict"}
+	for _, res := range out.Results {
+		if res.Status != want[res.ThreadID] {
+			t.Errorf("thread %d: got %q, want %q", res.ThreadID, res.Status, want[res.ThreadID])
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
+
+func TestBulkGrade_LostClaimRollsBackOnlyThatThread(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	attemptID := int64(50)
+	verdict := "accepted"
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectBegin()
+	// Thread 1 is claimed and graded, but the claim is gone by the cache
+	// update: its writes are undone and the batch carries on.
+	expectBulkThread(mock, 1, 42, threadRowOpts{Status: "submitted", AttemptEventID: &attemptID}, now)
+	expectSavepoint(mock)
+	mock.ExpectQuery(`UPDATE homework_thread\s+SET claim_holder_user_id`).
+		WithArgs(int64(3), int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 901, 100, 42, threadRowOpts{
+			Status: "submitted", AttemptEventID: &attemptID, ClaimHolderID: ptr64(3), ClaimExpiresAt: ptrTime(now.Add(15 * time.Minute)),
+		}, now)...))
+	mock.ExpectQuery(`INSERT INTO homework_attempt_claim`).
+		WithArgs(attemptID, int64(3)).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
+		WithArgs(int64(1), pgxmock.AnyArg(), "graded", int64(3), "ok", &verdict, &attemptID).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(80), int64(1), "u", "graded", int64(3), "ok", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, 80, 1)
+	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= CASE`).
+		WithArgs("accepted", int64(80), int64(3), int64(1)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectExec(`^ROLLBACK TO SAVEPOINT batch_item$`).WillReturnResult(pgxmock.NewResult("ROLLBACK", 0))
+	expectBulkThread(mock, 2, 42, threadRowOpts{Status: "submitted", AttemptEventID: ptr64(51)}, now)
+	expectBulkGraded(mock, 2, 51, 81, now)
+	mock.ExpectCommit()
+	expectAttemptSubmittedAt(mock, 51, now.Add(-time.Hour))
+	mock.ExpectExec(`pg_notify`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	body, _ := json.Marshal(map[string]any{"verdict": "accepted", "body": "ok", "thread_ids": []int64{1, 2}})
+	rr, out := postBulkGrade(t, r, authedRequest(t, access, 3, false, http.MethodPost, "/centers/42/bulk-grade", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	want := map[int64]string{1: "conflict", 2: "graded"}
+	if out.Graded != 1 || len(out.Results) != 2 {
+		t.Fatalf("got %+v, want 1 graded of 2", out)
+	}
+	for _, res := range out.Results {
+		if res.Status != want[res.ThreadID] {
+			t.Errorf("thread %d: got %q, want %q", res.ThreadID, res.Status, want[res.ThreadID])
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
+
+func TestBulkGrade_SkipsForeignAppealsAndOtherCenters(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectBegin()
+	// Appeal answered by someone else's verdict: not ours to grade.
+	expectBulkThread(mock, 1, 42, threadRowOpts{Status: "appealed", AttemptEventID: ptr64(50), LastGraderID: ptr64(9)}, now)
+	// Already accepted: no legal grade transition.
+	expectBulkThread(mock, 2, 42, threadRowOpts{Status: "accepted", AttemptEventID: ptr64(51)}, now)
+	// Belongs to another center.
+	expectBulkThread(mock, 3, 43, threadRowOpts{Status: "submitted", AttemptEventID: ptr64(52)}, now)
+	mock.ExpectQuery(`SELECT event_chain_head\s+FROM homework_thread`).
+		WithArgs(int64(4)).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectCommit()
+
+	body, _ := json.Marshal(map[string]any{"verdict": "accepted", "body": "ok", "thread_ids": []int64{1, 2, 3, 4}})
+	rr, out := postBulkGrade(t, r, authedRequest(t, access, 3, false, http.MethodPost, "/centers/42/bulk-grade", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	want := []string{"forbidden", "conflict", "not_found", "not_found"}
+	if out.Graded != 0 || len(out.Results) != len(want) {
+		t.Fatalf("got %+v", out)
+	}
+	for i, res := range out.Results {
+		if res.Status != want[i] {
+			t.Errorf("thread %d: got %q, want %q", res.ThreadID, res.Status, want[i])
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
+
+func TestBulkGrade_ValidatesInput(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	tooMany := make([]int64, 201)
+	for i := range tooMany {
+		tooMany[i] = int64(i + 1)
+	}
+	for name, payload := range map[string]map[string]any{
+		"bad verdict": {"verdict": "maybe", "body": "ok", "thread_ids": []int64{1}},
+		"no body":     {"verdict": "accepted", "body": "  ", "thread_ids": []int64{1}},
+		"no threads":  {"verdict": "accepted", "body": "ok", "thread_ids": []int64{}},
+		"bad id":      {"verdict": "accepted", "body": "ok", "thread_ids": []int64{0}},
+		"too many":    {"verdict": "accepted", "body": "ok", "thread_ids": tooMany},
+	} {
+		body, _ := json.Marshal(payload)
+		rr, _ := postBulkGrade(t, r, authedRequest(t, access, 3, false, http.MethodPost, "/centers/42/bulk-grade", bytes.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", name, rr.Code)
+		}
+	}
+}
+
+func TestBulkGrade_RequiresTeacher(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectTeacherAccess(mock, 3, 42, "")
+	body, _ := json.Marshal(map[string]any{"verdict": "accepted", "body": "ok", "thread_ids": []int64{1}})
+	rr, _ := postBulkGrade(t, r, authedRequest(t, access, 3, false, http.MethodPost, "/centers/42/bulk-grade", bytes.NewReader(body)))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403", rr.Code)
+	}
+}
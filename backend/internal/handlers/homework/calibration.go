@@ -0,0 +1,533 @@
+package homework
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/live"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/objectstore"
+)
+
+// calibrationConfig is the wire shape of GET/PUT /centers/{centerID}/calibration.
+type calibrationConfig struct {
+	Percent int `json:"percent"`
+}
+
+// calibrationItem is one entry of the blind second-grading queue. It carries
+// only thread coordinates — never the first verdict or its author.
+type calibrationItem struct {
+	ID            int64     `json:"id"`
+	ThreadID      int64     `json:"thread_id"`
+	SeriesID      int64     `json:"series_id"`
+	SubproblemID  int64     `json:"subproblem_id"`
+	StudentUserID int64     `json:"student_user_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// calibrationResult is a completed (or reviewed) pair, shown to head teachers
+// and to the second grader right after they commit their verdict.
+type calibrationResult struct {
+	calibrationItem
+	FirstGraderUserID  int64      `json:"first_grader_user_id"`
+	FirstVerdict       string     `json:"first_verdict"`
+	SecondGraderUserID *int64     `json:"second_grader_user_id,omitempty"`
+	SecondVerdict      *string    `json:"second_verdict,omitempty"`
+	SecondBody         string     `json:"second_body"`
+	Agreed             bool       `json:"agreed"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	ResolvedByUserID   *int64     `json:"resolved_by_user_id,omitempty"`
+	ResolvedAt         *time.Time `json:"resolved_at,omitempty"`
+}
+
+// blindCalibrationView is what the second grader works from: the thread as it
+// stood when the first verdict was given, minus that verdict.
+type blindCalibrationView struct {
+	CalibrationID int64      `json:"calibration_id"`
+	Completed     bool       `json:"completed"`
+	Thread        threadView `json:"thread"`
+}
+
+// calibrationGradeRequest is the body of POST /calibration/{calibrationID}/grade.
+type calibrationGradeRequest struct {
+	Verdict string `json:"verdict"`
+	Body    string `json:"body"`
+}
+
+// graderAgreement is one row of the per-term agreement report. Rate is nil
+// for a grader with no completed pairs.
+type graderAgreement struct {
+	GraderUserID    int64    `json:"grader_user_id"`
+	GraderFirstName string   `json:"grader_first_name"`
+	GraderLastName  string   `json:"grader_last_name"`
+	AsFirstTotal    int64    `json:"as_first_total"`
+	AsFirstAgreed   int64    `json:"as_first_agreed"`
+	AsSecondTotal   int64    `json:"as_second_total"`
+	AsSecondAgreed  int64    `json:"as_second_agreed"`
+	AgreementRate   *float64 `json:"agreement_rate"`
+}
+
+type calibrationReport struct {
+	TermID  int64             `json:"term_id"`
+	Graders []graderAgreement `json:"graders"`
+}
+
+// GetCalibrationConfig — teacher of the center. Returns the sampling rate; a
+// center never configured reports 0.
+func GetCalibrationConfig(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanView) {
+			return
+		}
+		cfg := calibrationConfig{}
+		row, err := q.GetCalibrationConfig(ctx, centerID)
+		switch {
+		case err == nil:
+			cfg.Percent = int(row.Percent)
+		case !errors.Is(err, pgx.ErrNoRows):
+			logger.LogErrorContext(ctx, "homework: get calibration config", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, cfg)
+	}
+}
+
+// PutCalibrationConfig — head teacher of the center. Sets the share of graded
+// verdicts queued for a blind second grading. Only verdicts given after the
+// change are sampled.
+func PutCalibrationConfig(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		var req calibrationConfig
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if err := homework.ValidateCalibrationPercent(req.Percent); err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, err.Error())
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireHeadTeacher(ctx, w, r, q, userID, centerID) {
+			return
+		}
+		row, err := q.UpsertCalibrationConfig(ctx, centerID, int32(req.Percent))
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: upsert calibration config", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save calibration config")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, calibrationConfig{Percent: int(row.Percent)})
+	}
+}
+
+// CalibrationQueue — teacher of the center. Lists sampled verdicts still
+// waiting for a second grading, oldest first, excluding the caller's own; a
+// term-scoped assistant sees only their term.
+func CalibrationQueue(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		access, ok := teacherAccess(ctx, w, r, q, userID, centerID, 0, mc.CanView)
+		if !ok {
+			return
+		}
+		rows, err := q.ListPendingCalibrations(ctx, centerID, userID, access.TermID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: list pending calibrations", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		out := make([]calibrationItem, 0, len(rows))
+		for _, c := range rows {
+			out = append(out, toCalibrationItem(c))
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// GetCalibration — teacher of the center other than the first grader. Returns
+// the blind view: the thread timeline up to the sampled verdict, with that
+// verdict, claims, and the last-grader fields removed.
+func GetCalibration(database *db.DB, blobs objectstore.Store, downloadTTL time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		q := store.New(database.Pool())
+		c, ok := loadCalibrationForSecondGrader(ctx, w, r, q, userID)
+		if !ok {
+			return
+		}
+		thread, err := q.GetThread(ctx, c.ThreadID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: get thread for calibration", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		view, err := buildThreadView(ctx, q, blobs, thread, downloadTTL)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: build calibration view", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		blindThreadView(view, c.GradedEventID)
+		httpx.WriteJSON(w, http.StatusOK, blindCalibrationView{
+			CalibrationID: c.ID,
+			Completed:     c.CompletedAt != nil,
+			Thread:        *view,
+		})
+	}
+}
+
+// GradeCalibration — teacher of the center other than the first grader.
+// Records the blind second verdict; the first one wins a race (409 for the
+// loser). The response reveals the first verdict. The live event refreshes
+// the other graders' queues and the head teachers' disagreement list.
+func GradeCalibration(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		var req calibrationGradeRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if req.Verdict != homework.VerdictAccepted && req.Verdict != homework.VerdictRejected {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "verdict must be 'accepted' or 'rejected'")
+			return
+		}
+		body, err := homework.ValidateBody(req.Body)
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, err.Error())
+			return
+		}
+
+		q := store.New(database.Pool())
+		c, ok := loadCalibrationForSecondGrader(ctx, w, r, q, userID)
+		if !ok {
+			return
+		}
+		done, err := q.CompleteCalibration(ctx, store.CompleteCalibrationParams{
+			ID:                 c.ID,
+			SecondGraderUserID: userID,
+			SecondVerdict:      req.Verdict,
+			SecondBody:         body,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "calibration already graded")
+				return
+			}
+			logger.LogErrorContext(ctx, "homework: complete calibration", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to record calibration")
+			return
+		}
+		live.Publish(ctx, database.Pool(), live.Event{CenterID: done.MathCenterID, Kind: live.KindCalibration, SeriesID: done.SeriesID})
+		httpx.WriteJSON(w, http.StatusOK, toCalibrationResult(done))
+	}
+}
+
+// CalibrationDisagreements — head teacher of the center. Lists completed
+// pairs whose verdicts differ, newest first. Reviewed ones are hidden unless
+// ?all=true.
+func CalibrationDisagreements(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+
+		q := store.New(database.Pool())
+		if !requireHeadTeacher(ctx, w, r, q, userID, centerID) {
+			return
+		}
+		rows, err := q.ListCalibrationDisagreements(ctx, centerID, all)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: list calibration disagreements", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		out := make([]calibrationResult, 0, len(rows))
+		for _, c := range rows {
+			out = append(out, toCalibrationResult(c))
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// ResolveCalibration — head teacher of the center. Marks a disagreement as
+// reviewed so it leaves the default disagreements list. Resolving twice is a
+// 409.
+func ResolveCalibration(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		calibrationID, err := pathInt64(r, "calibrationID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid calibration id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireHeadTeacher(ctx, w, r, q, userID, centerID) {
+			return
+		}
+		affected, err := q.ResolveCalibration(ctx, calibrationID, centerID, userID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: resolve calibration", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if affected == 0 {
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "calibration is not awaiting review")
+			return
+		}
+		live.Publish(ctx, database.Pool(), live.Event{CenterID: centerID, Kind: live.KindCalibration})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CalibrationReport — head teacher of the center. Agreement rates per grader
+// over the calibrations of one term (?term_id=, default the active term),
+// counted from both sides of each pair.
+func CalibrationReport(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireHeadTeacher(ctx, w, r, q, userID, centerID) {
+			return
+		}
+		termID, ok := resolveReportTerm(ctx, w, r, q, centerID)
+		if !ok {
+			return
+		}
+		rows, err := q.CalibrationAgreementForTerm(ctx, centerID, termID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: calibration report", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		out := calibrationReport{TermID: termID, Graders: make([]graderAgreement, 0, len(rows))}
+		for _, row := range rows {
+			g := graderAgreement{
+				GraderUserID:    row.GraderUserID,
+				GraderFirstName: row.GraderFirstName,
+				GraderLastName:  row.GraderLastName,
+				AsFirstTotal:    row.AsFirstTotal,
+				AsFirstAgreed:   row.AsFirstAgreed,
+				AsSecondTotal:   row.AsSecondTotal,
+				AsSecondAgreed:  row.AsSecondAgreed,
+			}
+			if total := row.AsFirstTotal + row.AsSecondTotal; total > 0 {
+				rate := float64(row.AsFirstAgreed+row.AsSecondAgreed) / float64(total)
+				g.AgreementRate = &rate
+			}
+			out.Graders = append(out.Graders, g)
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// resolveReportTerm reads ?term_id= (which must belong to the center) or
+// falls back to the center's active term, writing the error envelope on
+// failure.
+func resolveReportTerm(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, centerID int64) (int64, bool) {
+	if raw := r.URL.Query().Get("term_id"); raw != "" {
+		termID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid term_id")
+			return 0, false
+		}
+		term, err := q.GetTerm(ctx, termID)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && term.MathCenterID != centerID) {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "term not found")
+			return 0, false
+		}
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: get term", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return 0, false
+		}
+		return term.ID, true
+	}
+	term, err := q.GetActiveTermForCenter(ctx, centerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "no active term")
+			return 0, false
+		}
+		logger.LogErrorContext(ctx, "homework: get active term", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return 0, false
+	}
+	return term.ID, true
+}
+
+// loadCalibrationForSecondGrader fetches {calibrationID} and enforces
+// "teacher of its center, not its first grader", writing the error envelope
+// on failure. Admins pass the teacher check but are still refused their own
+// first verdicts.
+func loadCalibrationForSecondGrader(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID int64) (store.HomeworkCalibration, bool) {
+	calibrationID, err := pathInt64(r, "calibrationID")
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid calibration id")
+		return store.HomeworkCalibration{}, false
+	}
+	c, err := q.GetCalibration(ctx, calibrationID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "calibration not found")
+			return c, false
+		}
+		logger.LogErrorContext(ctx, "homework: get calibration", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return c, false
+	}
+	if !requireTeacherCan(ctx, w, r, q, userID, c.MathCenterID, mc.CanGrade) {
+		return c, false
+	}
+	if c.FirstGraderUserID == userID {
+		httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "you gave the first verdict")
+		return c, false
+	}
+	return c, true
+}
+
+// blindThreadView rewinds a thread view to just before the sampled verdict:
+// later events and the verdict itself go, as do claim noise and every field
+// naming the last grader. The users map is pruned to the remaining actors.
+func blindThreadView(view *threadView, gradedEventID int64) {
+	kept := make([]eventView, 0, len(view.Events))
+	status := homework.StatusSubmitted
+	for _, e := range view.Events {
+		if e.ID >= gradedEventID {
+			continue
+		}
+		switch e.Kind {
+		case homework.KindClaimed, homework.KindReleased:
+			continue
+		case homework.KindSubmitted:
+			status = homework.StatusSubmitted
+		case homework.KindAppealed:
+			status = homework.StatusAppealed
+		}
+		kept = append(kept, e)
+	}
+	view.Events = kept
+	view.CurrentStatus = status
+	view.LastGraderUserID = nil
+	view.LastGraderName = ""
+	view.ClaimHolderUserID = nil
+	view.ClaimExpiresAt = nil
+
+	users := map[string]string{}
+	keep := func(id int64) {
+		key := strconv.FormatInt(id, 10)
+		if name, ok := view.Users[key]; ok {
+			users[key] = name
+		}
+	}
+	keep(view.StudentUserID)
+	for _, e := range kept {
+		keep(e.ActorUserID)
+	}
+	view.Users = users
+}
+
+func toCalibrationItem(c store.HomeworkCalibration) calibrationItem {
+	return calibrationItem{
+		ID:            c.ID,
+		ThreadID:      c.ThreadID,
+		SeriesID:      c.SeriesID,
+		SubproblemID:  c.SubproblemID,
+		StudentUserID: c.StudentUserID,
+		CreatedAt:     c.CreatedAt,
+	}
+}
+
+func toCalibrationResult(c store.HomeworkCalibration) calibrationResult {
+	return calibrationResult{
+		calibrationItem:    toCalibrationItem(c),
+		FirstGraderUserID:  c.FirstGraderUserID,
+		FirstVerdict:       c.FirstVerdict,
+		SecondGraderUserID: c.SecondGraderUserID,
+		SecondVerdict:      c.SecondVerdict,
+		SecondBody:         c.SecondBody,
+		Agreed:             c.SecondVerdict != nil && *c.SecondVerdict == c.FirstVerdict,
+		CompletedAt:        c.CompletedAt,
+		ResolvedByUserID:   c.ResolvedByUserID,
+		ResolvedAt:         c.ResolvedAt,
+	}
+}
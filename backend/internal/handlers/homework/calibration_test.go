@@ -0,0 +1,292 @@
+package homework_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var calibrationColumns = []string{
+	"id", "thread_id", "graded_event_id", "first_grader_user_id", "first_verdict",
+	"second_grader_user_id", "second_verdict", "second_body", "created_at", "completed_at",
+	"resolved_by_user_id", "resolved_at",
+	"math_center_id", "series_id", "subproblem_id", "student_user_id",
+}
+
+// pendingCalibrationRow is calibration 5: thread 1 (student 7, center 42),
+// graded event 80 rejected by teacher 3, not yet second-graded.
+func pendingCalibrationRow(now time.Time) []any {
+	return []any{
+		int64(5), int64(1), int64(80), int64(3), "rejected",
+		(*int64)(nil), (*string)(nil), "", now, (*time.Time)(nil),
+		(*int64)(nil), (*time.Time)(nil),
+		int64(42), int64(100), int64(900), int64(7),
+	}
+}
+
+func expectHeadTeacherCheck(mock pgxmock.PgxPoolIface, userID, centerID int64, ok bool) {
+	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers .* is_head_teacher = TRUE`).
+		WithArgs(userID, centerID).
+		WillReturnRows(mock.NewRows([]string{"is_head_teacher"}).AddRow(ok))
+}
+
+func TestGetCalibration_BlindViewHidesFirstVerdict(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	attemptID := int64(50)
+	gradeID := int64(80)
+	grader := int64(3)
+	verdict := "rejected"
+	mock.ExpectQuery(`FROM homework_calibration c\s+JOIN homework_thread t ON t.id = c.thread_id\s+WHERE c.id`).
+		WithArgs(int64(5)).
+		WillReturnRows(mock.NewRows(calibrationColumns).AddRow(pendingCalibrationRow(now)...))
+	expectTeacherAccess(mock, 4, 42, "teacher")
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "rejected", AttemptEventID: &attemptID, GradeEventID: &gradeID, LastGraderID: &grader,
+		}, now)...))
+	expectGetSeriesForView(mock, 100, 42, now)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(50), int64(1), "s1", "submitted", int64(7), "my proof", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", "").
+			AddRow(int64(60), int64(1), "c1", "claimed", int64(3), "", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", "").
+			AddRow(int64(80), int64(1), "g1", "graded", int64(3), "gap in step 2", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	mock.ExpectQuery(`SELECT id, first_name, middle_name, last_name\s+FROM users\s+WHERE id = ANY`).
+		WithArgs([]int64{7, 3}).
+		WillReturnRows(mock.NewRows([]string{"id", "first_name", "middle_name", "last_name"}).
+			AddRow(int64(7), "Петя", (*string)(nil), "Иванов").
+			AddRow(int64(3), "Анна", (*string)(nil), "Смирнова"))
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event_photo`).
+		WithArgs([]int64{50, 60, 80}).
+		WillReturnRows(mock.NewRows(photoColumns))
+	expectEventReasons(mock, []int64{80}, nil)
+
+	req := authedRequest(t, access, 4, false, http.MethodGet, "/calibration/5", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Thread struct {
+			CurrentStatus    string                  `json:"current_status"`
+			LastGraderUserID *int64                  `json:"last_grader_user_id"`
+			Events           []struct{ Kind string } `json:"events"`
+			Users            map[string]string       `json:"users"`
+		} `json:"thread"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Thread.CurrentStatus != "submitted" || resp.Thread.LastGraderUserID != nil {
+		t.Fatalf("thread state leaks first verdict: %s", rr.Body.String())
+	}
+	if len(resp.Thread.Events) != 1 || resp.Thread.Events[0].Kind != "submitted" {
+		t.Fatalf("events = %+v; want only the attempt", resp.Thread.Events)
+	}
+	if _, ok := resp.Thread.Users["3"]; ok {
+		t.Fatalf("users map names the first grader: %v", resp.Thread.Users)
+	}
+	if strings.Contains(rr.Body.String(), "gap in step 2") {
+		t.Fatalf("first grader's comment leaked: %s", rr.Body.String())
+	}
+}
+
+func TestGetCalibration_FirstGraderForbidden(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	mock.ExpectQuery(`FROM homework_calibration c\s+JOIN homework_thread t ON t.id = c.thread_id\s+WHERE c.id`).
+		WithArgs(int64(5)).
+		WillReturnRows(mock.NewRows(calibrationColumns).AddRow(pendingCalibrationRow(time.Now())...))
+	expectTeacherAccess(mock, 3, 42, "teacher")
+
+	req := authedRequest(t, access, 3, false, http.MethodGet, "/calibration/5", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGradeCalibration_DisagreementRevealsFirstVerdict(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM homework_calibration c\s+JOIN homework_thread t ON t.id = c.thread_id\s+WHERE c.id`).
+		WithArgs(int64(5)).
+		WillReturnRows(mock.NewRows(calibrationColumns).AddRow(pendingCalibrationRow(now)...))
+	expectTeacherAccess(mock, 4, 42, "teacher")
+	second := int64(4)
+	accepted := "accepted"
+	done := pendingCalibrationRow(now)
+	done[5], done[6], done[7], done[9] = &second, &accepted, "looks complete", &now
+	mock.ExpectQuery(`UPDATE homework_calibration\s+SET second_grader_user_id`).
+		WithArgs(int64(5), int64(4), "accepted", "looks complete").
+		WillReturnRows(mock.NewRows(calibrationColumns).AddRow(done...))
+
+	body, _ := json.Marshal(map[string]any{"verdict": "accepted", "body": "looks complete"})
+	req := authedRequest(t, access, 4, false, http.MethodPost, "/calibration/5/grade", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		FirstVerdict string `json:"first_verdict"`
+		Agreed       bool   `json:"agreed"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.FirstVerdict != "rejected" || resp.Agreed {
+		t.Fatalf("got %+v; want rejected vs accepted disagreement", resp)
+	}
+}
+
+func TestGradeCalibration_AlreadyGradedConflict(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	mock.ExpectQuery(`FROM homework_calibration c\s+JOIN homework_thread t ON t.id = c.thread_id\s+WHERE c.id`).
+		WithArgs(int64(5)).
+		WillReturnRows(mock.NewRows(calibrationColumns).AddRow(pendingCalibrationRow(time.Now())...))
+	expectTeacherAccess(mock, 4, 42, "teacher")
+	mock.ExpectQuery(`UPDATE homework_calibration\s+SET second_grader_user_id`).
+		WithArgs(int64(5), int64(4), "rejected", "").
+		WillReturnRows(mock.NewRows(calibrationColumns))
+
+	body, _ := json.Marshal(map[string]any{"verdict": "rejected"})
+	req := authedRequest(t, access, 4, false, http.MethodPost, "/calibration/5/grade", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("got %d, want 409; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPutCalibrationConfig_RequiresHeadTeacher(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectHeadTeacherCheck(mock, 3, 42, false)
+
+	body, _ := json.Marshal(map[string]any{"percent": 10})
+	req := authedRequest(t, access, 3, false, http.MethodPut, "/centers/42/calibration", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPutCalibrationConfig_RejectsOutOfRange(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	body, _ := json.Marshal(map[string]any{"percent": 150})
+	req := authedRequest(t, access, 3, false, http.MethodPut, "/centers/42/calibration", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestCalibrationReport_AgreementRatePerGrader(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	expectHeadTeacherCheck(mock, 1, 42, true)
+	mock.ExpectQuery(`FROM math_center_terms\s+WHERE math_center_id = \$1\s+AND is_active`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows([]string{"id", "math_center_id", "kind", "grade", "is_active", "created_at", "archived_at"}).
+			AddRow(int64(9), int64(42), "academic", ptrInt32(9), true, now, (*time.Time)(nil)))
+	mock.ExpectQuery(`WITH done AS`).
+		WithArgs(int64(42), int64(9)).
+		WillReturnRows(mock.NewRows([]string{"grader_user_id", "first_name", "last_name", "a", "b", "c", "d"}).
+			AddRow(int64(3), "Анна", "Смирнова", int64(3), int64(2), int64(1), int64(1)).
+			AddRow(int64(4), "Борис", "Петров", int64(0), int64(0), int64(0), int64(0)))
+
+	req := authedRequest(t, access, 1, false, http.MethodGet, "/centers/42/calibration/report", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		TermID  int64 `json:"term_id"`
+		Graders []struct {
+			GraderUserID  int64    `json:"grader_user_id"`
+			AgreementRate *float64 `json:"agreement_rate"`
+		} `json:"graders"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.TermID != 9 || len(resp.Graders) != 2 {
+		t.Fatalf("got %+v", resp)
+	}
+	if rate := resp.Graders[0].AgreementRate; rate == nil || *rate != 0.75 {
+		t.Fatalf("grader 3 rate = %v; want 0.75", rate)
+	}
+	if resp.Graders[1].AgreementRate != nil {
+		t.Fatalf("grader 4 rate = %v; want null", *resp.Graders[1].AgreementRate)
+	}
+}
+
+func ptrInt32(v int32) *int32 { return &v }
+
+func TestCalibrationQueue_TermScopedToOwnTerm(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	termID := int64(5)
+	expectTermGraderAccess(mock, 4, 42, termID)
+	mock.ExpectQuery(`FROM homework_calibration c\s+JOIN homework_thread t ON t.id = c.thread_id\s+WHERE t.math_center_id`).
+		WithArgs(int64(42), int64(4), &termID).
+		WillReturnRows(mock.NewRows(calibrationColumns).AddRow(pendingCalibrationRow(now)...))
+
+	req := authedRequest(t, access, 4, false, http.MethodGet, "/centers/42/calibration/queue", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/Alarion239/my239/backend/internal/homework"
 	"github.com/Alarion239/my239/backend/internal/httpx"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 )
@@ -36,7 +37,7 @@ func CenterTeachers(database *db.DB) http.HandlerFunc {
 		}
 
 		q := store.New(database.Pool())
-		if !requireTeacher(ctx, w, r, q, userID, centerID) {
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanView) {
 			return
 		}
 
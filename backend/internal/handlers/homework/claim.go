@@ -1,6 +1,7 @@
 package homework
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/Alarion239/my239/backend/internal/httpx"
 	"github.com/Alarion239/my239/backend/internal/live"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/metrics"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 	"github.com/Alarion239/my239/backend/pkg/objectstore"
@@ -43,14 +46,15 @@ func Claim(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.Handler
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, thread.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanGrade) {
 			return
 		}
 
-		if _, err := q.TryClaim(ctx, store.TryClaimParams{
+		claimed, err := q.TryClaim(ctx, store.TryClaimParams{
 			ID:           threadID,
 			GraderUserID: userID,
-		}); err != nil {
+		})
+		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "thread is currently claimed by another grader")
 				return
@@ -59,6 +63,7 @@ func Claim(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.Handler
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
+		recordFirstClaim(ctx, q, claimed, userID)
 		live.Publish(ctx, database.Pool(), live.Event{CenterID: thread.MathCenterID, Kind: live.KindGrading, SeriesID: thread.SeriesID})
 		// Same response shape as /grade, /retract, /submit, /appeal — the
 		// frontend's claimThread() is typed as ThreadView and uses
@@ -68,6 +73,23 @@ func Claim(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.Handler
 	}
 }
 
+// recordFirstClaim notes the first claim on the thread's current attempt for
+// the turnaround metrics. The claim itself is already granted, so a failure
+// here is logged rather than returned.
+func recordFirstClaim(ctx context.Context, q *store.Queries, thread store.HomeworkThread, graderUserID int64) {
+	if thread.CurrentAttemptEventID == nil {
+		return
+	}
+	first, err := q.RecordAttemptClaim(ctx, *thread.CurrentAttemptEventID, graderUserID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.LogWarnContext(ctx, "homework: record first claim", "error", err, "thread_id", thread.ID)
+		}
+		return
+	}
+	metrics.HomeworkClaimWaitSeconds.Observe(first.ClaimedAt.Sub(first.SubmittedAt).Seconds())
+}
+
 // Heartbeat — must be the current claim holder. Extends the lease by 15min.
 // 409 if the lock has expired or was stolen.
 func Heartbeat(database *db.DB) http.HandlerFunc {
@@ -94,7 +116,7 @@ func Heartbeat(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, thread.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanGrade) {
 			return
 		}
 
@@ -141,7 +163,7 @@ func Release(database *db.DB, hub *live.Hub) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, thread.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanGrade) {
 			return
 		}
 
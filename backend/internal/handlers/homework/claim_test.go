@@ -17,15 +17,20 @@ func TestClaim_GrantsWhenFree(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
+	attemptID := int64(50)
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
 		WithArgs(int64(1)).
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{Status: "submitted"}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`UPDATE homework_thread\s+SET claim_holder_user_id`).
 		WithArgs(int64(3), int64(1)).
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
-			Status: "submitted", ClaimHolderID: ptr64(3), ClaimExpiresAt: ptrTime(now.Add(15 * time.Minute)),
+			Status: "submitted", AttemptEventID: &attemptID, ClaimHolderID: ptr64(3), ClaimExpiresAt: ptrTime(now.Add(15 * time.Minute)),
 		}, now)...))
+	// First claim on the attempt is recorded for the turnaround metrics.
+	mock.ExpectQuery(`INSERT INTO homework_attempt_claim`).
+		WithArgs(attemptID, int64(3)).
+		WillReturnRows(mock.NewRows([]string{"claimed_at", "created_at"}).AddRow(now, now.Add(-2*time.Hour)))
 	// Claim now returns the full threadView so the client doesn't crash
 	// on .events access; that means a writeThreadView round-trip after
 	// TryClaim succeeds (re-fetches thread, fetches series, lists events).
@@ -46,6 +51,9 @@ func TestClaim_GrantsWhenFree(t *testing.T) {
 	if rr.Code != http.StatusOK {
 		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
 	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
 }
 
 func TestClaim_ConflictWhenHeldByOther(t *testing.T) {
@@ -62,7 +70,7 @@ func TestClaim_ConflictWhenHeldByOther(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "submitted", ClaimHolderID: &heldBy, ClaimExpiresAt: &exp,
 		}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// TryClaim returns no rows (UPDATE didn't match).
 	mock.ExpectQuery(`UPDATE homework_thread\s+SET claim_holder_user_id`).
 		WithArgs(int64(3), int64(1)).
@@ -86,7 +94,27 @@ func TestClaim_NonTeacherForbidden(t *testing.T) {
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
 		WithArgs(int64(1)).
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
-	expectTeacherCheck(mock, 3, 42, false)
+	expectTeacherAccess(mock, 3, 42, "")
+
+	req := authedRequest(t, access, 3, false, http.MethodPost, "/threads/by-id/1/claim", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("got %d, want 403", rr.Code)
+	}
+}
+
+func TestClaim_EditorForbidden(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	expectTeacherAccess(mock, 3, 42, "editor")
 
 	req := authedRequest(t, access, 3, false, http.MethodPost, "/threads/by-id/1/claim", nil)
 	rr := httptest.NewRecorder()
@@ -106,7 +134,7 @@ func TestHeartbeat_RejectsWhenNotHolder(t *testing.T) {
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
 		WithArgs(int64(1)).
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectExec(`UPDATE homework_thread\s+SET claim_expires_at`).
 		WithArgs(int64(1), int64(3)).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
@@ -129,7 +157,7 @@ func TestHeartbeat_HappyPath(t *testing.T) {
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
 		WithArgs(int64(1)).
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectExec(`UPDATE homework_thread\s+SET claim_expires_at`).
 		WithArgs(int64(1), int64(3)).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
@@ -152,7 +180,7 @@ func TestRelease_IsIdempotent(t *testing.T) {
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
 		WithArgs(int64(1)).
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// Even when zero rows are affected (lock already gone), Release
 	// returns 204.
 	mock.ExpectExec(`UPDATE homework_thread\s+SET claim_holder_user_id\s+= NULL`).
@@ -0,0 +1,206 @@
+package homework
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/objectstore"
+)
+
+// draftPhotoView is one photo already uploaded for the draft. URL is a
+// short-TTL presigned GET of the upload itself: draft photos are not run
+// through the photo pipeline until the draft is sent.
+type draftPhotoView struct {
+	Index     int    `json:"index"`
+	ObjectKey string `json:"object_key"`
+	URL       string `json:"url"`
+}
+
+// draftView is the student's unsent attempt. EventUUID is the upload prefix
+// to keep uploading under and, once sent, the submitted event's UUID.
+type draftView struct {
+	ThreadID  int64            `json:"thread_id"`
+	EventUUID string           `json:"event_uuid"`
+	Body      string           `json:"body"`
+	Photos    []draftPhotoView `json:"photos"`
+	UpdatedAt time.Time        `json:"updated_at"`
+	ExpiresAt time.Time        `json:"expires_at"`
+}
+
+func toDraftView(r *http.Request, blobs objectstore.Store, d store.HomeworkAttemptDraft, ttl time.Duration) draftView {
+	out := draftView{
+		ThreadID:  d.ThreadID,
+		EventUUID: d.EventUUID,
+		Body:      d.Body,
+		Photos:    make([]draftPhotoView, 0, len(d.ObjectKeys)),
+		UpdatedAt: d.UpdatedAt,
+		ExpiresAt: d.ExpiresAt,
+	}
+	for i, key := range d.ObjectKeys {
+		out.Photos = append(out.Photos, draftPhotoView{
+			Index:     i,
+			ObjectKey: key,
+			URL:       presignOrEmpty(r.Context(), blobs, key, ttl),
+		})
+	}
+	return out
+}
+
+// GetDraft — student. Returns the caller's unexpired draft for the
+// subproblem, 404 when there is none.
+func GetDraft(database *db.DB, blobs objectstore.Store, downloadTTL time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		subproblemID, err := pathInt64(r, "subproblemID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid subproblem id")
+			return
+		}
+
+		draft, err := store.New(database.Pool()).GetAttemptDraft(ctx, userID, subproblemID, time.Now())
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "no draft")
+				return
+			}
+			logger.LogErrorContext(ctx, "homework: get draft", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, toDraftView(r, blobs, draft, downloadTTL))
+	}
+}
+
+// PutDraft — student of the center. Saves the whole draft (same body as
+// /submit) over the previous one and restarts its expiry. Allowed only
+// while a submission would be: window open, thread not already waiting for
+// or past a verdict. Every listed photo must already be uploaded.
+func PutDraft(database *db.DB, blobs objectstore.Store, downloadTTL time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		subproblemID, err := pathInt64(r, "subproblemID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid subproblem id")
+			return
+		}
+
+		var req submitRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		body, vErr := validateSubmitInput(req)
+		if vErr != "" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
+			return
+		}
+
+		q := store.New(database.Pool())
+		thread, ok := openAttemptThread(w, r, q, userID, subproblemID)
+		if !ok {
+			return
+		}
+		if _, vErr := validateAndStatPhotos(ctx, blobs, thread.ID, req.EventUUID, req.ObjectKeys); vErr != "" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
+			return
+		}
+		keys := req.ObjectKeys
+		if keys == nil {
+			keys = []string{}
+		}
+		draft, err := q.UpsertAttemptDraft(ctx, store.UpsertAttemptDraftParams{
+			ThreadID:   thread.ID,
+			EventUUID:  req.EventUUID,
+			Body:       body,
+			ObjectKeys: keys,
+			ExpiresAt:  time.Now().Add(homework.DraftTTL),
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: upsert draft", err, "thread_id", thread.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save draft")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, toDraftView(r, blobs, draft, downloadTTL))
+	}
+}
+
+// DeleteDraft — student. Discards the caller's draft for the subproblem and
+// its photos. Idempotent.
+func DeleteDraft(database *db.DB, blobs objectstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		subproblemID, err := pathInt64(r, "subproblemID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid subproblem id")
+			return
+		}
+
+		draft, err := store.New(database.Pool()).DeleteAttemptDraft(ctx, userID, subproblemID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			logger.LogErrorContext(ctx, "homework: delete draft", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		// The row is gone, so anything left behind is an orphan the upload
+		// sweep would catch anyway; deleting now just saves the wait.
+		for _, key := range draft.ObjectKeys {
+			if err := blobs.Delete(ctx, key); err != nil {
+				logger.LogWarnContext(ctx, "homework: delete draft photo", "error", err, "object_key", key)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SubmitDraft — student of the center. Sends the stored draft exactly as
+// SubmitAttempt would have sent it; the draft's UUID becomes the event's.
+// The submit transaction removes the draft.
+func SubmitDraft(database *db.DB, blobs objectstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		subproblemID, err := pathInt64(r, "subproblemID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid subproblem id")
+			return
+		}
+
+		draft, err := store.New(database.Pool()).GetAttemptDraft(ctx, userID, subproblemID, time.Now())
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "no draft")
+				return
+			}
+			logger.LogErrorContext(ctx, "homework: get draft for submit", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		submitAttempt(w, r, database, blobs, userID, subproblemID, submitRequest{
+			EventUUID:  draft.EventUUID,
+			Body:       draft.Body,
+			ObjectKeys: draft.ObjectKeys,
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package homework_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var draftColumns = []string{"thread_id", "event_uuid", "body", "object_keys", "updated_at", "expires_at"}
+
+func serveJSON(t *testing.T, r http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPutDraft_SavesUploadedPhotos(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	now := time.Now()
+	pub := now.Add(-time.Hour)
+	key := "homework/thread/1/d1/0.jpg"
+	_ = blobs.Put(context.Background(), key, strings.NewReader("img"), 3, "image/jpeg")
+
+	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "a", now.Add(time.Hour), &pub)
+	expectStudentCheck(mock, 7, 42, true)
+	mock.ExpectQuery(`INSERT INTO homework_thread`).
+		WithArgs(int64(7), int64(900), int64(100), int64(42)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	mock.ExpectQuery(`INSERT INTO homework_attempt_draft`).
+		WithArgs(int64(1), "d1", "half done", []string{key}, pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows(draftColumns).
+			AddRow(int64(1), "d1", "half done", []string{key}, now, now.Add(7*24*time.Hour)))
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "d1", "body": " half done ", "object_keys": []string{key}})
+	rr := serveJSON(t, r, authedRequest(t, access, 7, false, http.MethodPut, "/threads/900/draft", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var v struct {
+		EventUUID string `json:"event_uuid"`
+		Photos    []struct {
+			ObjectKey string `json:"object_key"`
+			URL       string `json:"url"`
+		} `json:"photos"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if v.EventUUID != "d1" || len(v.Photos) != 1 || v.Photos[0].URL == "" {
+		t.Errorf("draft = %+v", v)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
+
+func TestPutDraft_RejectsMissingPhoto(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	pub := now.Add(-time.Hour)
+	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "a", now.Add(time.Hour), &pub)
+	expectStudentCheck(mock, 7, 42, true)
+	mock.ExpectQuery(`INSERT INTO homework_thread`).
+		WithArgs(int64(7), int64(900), int64(100), int64(42)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "d1", "body": "", "object_keys": []string{"homework/thread/1/d1/0.jpg"}})
+	rr := serveJSON(t, r, authedRequest(t, access, 7, false, http.MethodPut, "/threads/900/draft", bytes.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPutDraft_ClosedAfterDue(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	pub := now.Add(-48 * time.Hour)
+	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "a", now.Add(-time.Hour), &pub)
+	expectStudentCheck(mock, 7, 42, true)
+	expectRejectedWithoutAttempt(mock, 7, 900, false)
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "d1", "body": "late", "object_keys": []string{}})
+	rr := serveJSON(t, r, authedRequest(t, access, 7, false, http.MethodPut, "/threads/900/draft", bytes.NewReader(body)))
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("got %d, want 409; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGetDraft_NotFound(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	mock.ExpectQuery(`FROM homework_attempt_draft d`).
+		WithArgs(int64(7), int64(900), pgxmock.AnyArg()).
+		WillReturnError(pgx.ErrNoRows)
+
+	rr := serveJSON(t, r, authedRequest(t, access, 7, false, http.MethodGet, "/threads/900/draft", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("got %d, want 404", rr.Code)
+	}
+}
+
+func TestDeleteDraft_RemovesPhotos(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	key := "homework/thread/1/d1/0.jpg"
+	_ = blobs.Put(context.Background(), key, strings.NewReader("img"), 3, "image/jpeg")
+	mock.ExpectQuery(`DELETE FROM homework_attempt_draft d`).
+		WithArgs(int64(7), int64(900)).
+		WillReturnRows(mock.NewRows([]string{"thread_id", "event_uuid", "object_keys"}).AddRow(int64(1), "d1", []string{key}))
+
+	rr := serveJSON(t, r, authedRequest(t, access, 7, false, http.MethodDelete, "/threads/900/draft", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("got %d, want 204; body=%s", rr.Code, rr.Body.String())
+	}
+	if exists, _ := blobs.Exists(context.Background(), key); exists {
+		t.Error("draft photo survived")
+	}
+}
+
+func TestSubmitDraft_SendsStoredAttempt(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	now := time.Now()
+	pub := now.Add(-time.Hour)
+	key := "homework/thread/1/d1/0.jpg"
+	_ = blobs.Put(context.Background(), key, strings.NewReader("img"), 3, "image/jpeg")
+
+	mock.ExpectQuery(`FROM homework_attempt_draft d`).
+		WithArgs(int64(7), int64(900), pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows(draftColumns).
+			AddRow(int64(1), "d1", "done", []string{key}, now, now.Add(time.Hour)))
+	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "a", now.Add(time.Hour), &pub)
+	expectStudentCheck(mock, 7, 42, true)
+	mock.ExpectQuery(`INSERT INTO homework_thread`).
+		WithArgs(int64(7), int64(900), int64(100), int64(42)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	expectStorageQuota(mock, 7, 42, nil, 0)
+	mock.ExpectBegin()
+	expectChainLock(mock, 1)
+	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
+		WithArgs(int64(1), "d1", "submitted", int64(7), "done", (*string)(nil), (*int64)(nil)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(50), int64(1), "d1", "submitted", int64(7), "done", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, 50, 1)
+	mock.ExpectExec(`INSERT INTO homework_thread_event_photo`).
+		WithArgs(int64(50), int32(0), key, int64(3), "image/jpeg").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	evID := int64(50)
+	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= 'submitted'`).
+		WithArgs(int64(1), &evID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectAssignGrader(mock, 1, nil)
+	mock.ExpectExec(`DELETE FROM homework_attempt_draft WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`pg_notify`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "submitted", AttemptEventID: &evID,
+		}, now)...))
+	expectGetSeriesForView(mock, 100, 42, now)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(eventColumns))
+	expectGetUsersForView(mock)
+
+	rr := serveJSON(t, r, authedRequest(t, access, 7, false, http.MethodPost, "/threads/900/draft/submit", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
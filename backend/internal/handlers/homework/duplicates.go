@@ -0,0 +1,154 @@
+package homework
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// duplicateFlagView is one suspected copy as seen from a thread: EventID and
+// PhotoIndex are on this thread, the Other* fields on the matching attempt
+// of another student. Kind is "photo" (near-identical photo, Distance bits
+// apart) or "text" (identical attempt text). PhotoIndex fields are omitted
+// for text flags.
+type duplicateFlagView struct {
+	Kind             string    `json:"kind"`
+	Distance         int       `json:"distance"`
+	CreatedAt        time.Time `json:"created_at"`
+	EventID          int64     `json:"event_id"`
+	PhotoIndex       *int      `json:"photo_index,omitempty"`
+	OtherThreadID    int64     `json:"other_thread_id"`
+	OtherEventID     int64     `json:"other_event_id"`
+	OtherPhotoIndex  *int      `json:"other_photo_index,omitempty"`
+	OtherStudentID   int64     `json:"other_student_user_id"`
+	OtherStudentName string    `json:"other_student_name"`
+}
+
+// duplicatePairView is one flagged pair in the series report: the later
+// attempt first, then the earlier one it matched.
+type duplicatePairView struct {
+	Kind             string    `json:"kind"`
+	Distance         int       `json:"distance"`
+	CreatedAt        time.Time `json:"created_at"`
+	SubproblemID     int64     `json:"subproblem_id"`
+	Display          string    `json:"display"`
+	ThreadID         int64     `json:"thread_id"`
+	EventID          int64     `json:"event_id"`
+	PhotoIndex       *int      `json:"photo_index,omitempty"`
+	StudentUserID    int64     `json:"student_user_id"`
+	StudentName      string    `json:"student_name"`
+	OtherThreadID    int64     `json:"other_thread_id"`
+	OtherEventID     int64     `json:"other_event_id"`
+	OtherPhotoIndex  *int      `json:"other_photo_index,omitempty"`
+	OtherStudentID   int64     `json:"other_student_user_id"`
+	OtherStudentName string    `json:"other_student_name"`
+}
+
+type duplicatesResponse struct {
+	SeriesID int64               `json:"series_id"`
+	Pairs    []duplicatePairView `json:"pairs"`
+}
+
+// flagPhotoIndex is nil for text flags, whose photo columns are unused.
+func flagPhotoIndex(kind string, idx int32) *int {
+	if kind != "photo" {
+		return nil
+	}
+	i := int(idx)
+	return &i
+}
+
+// loadDuplicateFlags reads the flags touching threadID. Teacher-only: callers
+// must never attach them to a view the student can see.
+func loadDuplicateFlags(ctx context.Context, q *store.Queries, threadID int64) ([]duplicateFlagView, error) {
+	rows, err := q.ListThreadDuplicateFlags(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]duplicateFlagView, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, duplicateFlagView{
+			Kind:             row.Kind,
+			Distance:         int(row.Distance),
+			CreatedAt:        row.CreatedAt,
+			EventID:          row.EventID,
+			PhotoIndex:       flagPhotoIndex(row.Kind, row.PhotoIdx),
+			OtherThreadID:    row.OtherThreadID,
+			OtherEventID:     row.OtherEventID,
+			OtherPhotoIndex:  flagPhotoIndex(row.Kind, row.OtherPhotoIdx),
+			OtherStudentID:   row.OtherStudentUserID,
+			OtherStudentName: mc.StudentDisplayName(row.OtherStudentFirstName, row.OtherStudentLastName),
+		})
+	}
+	return out, nil
+}
+
+// SeriesDuplicates — teacher of the series's center. Every suspected copy in
+// the series, by problem: near-identical photos and identical attempt texts
+// sent by different students for the same subproblem.
+func SeriesDuplicates(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		seriesID, err := pathInt64(r, "seriesID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid series id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		series, err := q.GetSeries(ctx, seriesID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "series not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "homework: get series for duplicates", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanView) {
+			return
+		}
+
+		rows, err := q.ListSeriesDuplicateFlags(ctx, seriesID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: series duplicates", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		out := duplicatesResponse{SeriesID: seriesID, Pairs: make([]duplicatePairView, 0, len(rows))}
+		for _, row := range rows {
+			out.Pairs = append(out.Pairs, duplicatePairView{
+				Kind:             row.Kind,
+				Distance:         int(row.Distance),
+				CreatedAt:        row.CreatedAt,
+				SubproblemID:     row.SubproblemID,
+				Display:          mc.SubproblemDisplayName(int(row.ProblemNumber), row.SubproblemLabel),
+				ThreadID:         row.ThreadID,
+				EventID:          row.EventID,
+				PhotoIndex:       flagPhotoIndex(row.Kind, row.PhotoIdx),
+				StudentUserID:    row.StudentUserID,
+				StudentName:      mc.StudentDisplayName(row.StudentFirstName, row.StudentLastName),
+				OtherThreadID:    row.OtherThreadID,
+				OtherEventID:     row.OtherEventID,
+				OtherPhotoIndex:  flagPhotoIndex(row.Kind, row.OtherPhotoIdx),
+				OtherStudentID:   row.OtherStudentUserID,
+				OtherStudentName: mc.StudentDisplayName(row.OtherStudentFirstName, row.OtherStudentLastName),
+			})
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
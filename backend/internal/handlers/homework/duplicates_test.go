@@ -0,0 +1,83 @@
+package homework_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var seriesDuplicateColumns = []string{
+	"id", "kind", "distance", "created_at",
+	"subproblem_id", "number", "label",
+	"thread_id", "event_id", "photo_idx", "student_user_id", "first_name", "last_name",
+	"other_thread_id", "other_event_id", "other_photo_idx", "other_student_user_id", "other_first_name", "other_last_name",
+}
+
+func TestSeriesDuplicates_ListsPairs(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	expectGetSeriesForView(mock, 100, 42, now)
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectQuery(`FROM homework_duplicate_flag f\s+JOIN math_center_subproblems`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesDuplicateColumns).
+			AddRow(int64(5), "photo", int32(2), now, int64(900), int32(1), "а",
+				int64(2), int64(60), int32(1), int64(8), "Пётр", "Иванов",
+				int64(1), int64(50), int32(0), int64(7), "Анна", "Петрова"))
+
+	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/duplicates", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var out struct {
+		Pairs []struct {
+			Kind            string `json:"kind"`
+			Distance        int    `json:"distance"`
+			ThreadID        int64  `json:"thread_id"`
+			OtherThreadID   int64  `json:"other_thread_id"`
+			OtherPhotoIndex *int   `json:"other_photo_index"`
+			StudentName     string `json:"student_name"`
+		} `json:"pairs"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out.Pairs) != 1 {
+		t.Fatalf("want 1 pair, got %+v", out.Pairs)
+	}
+	p := out.Pairs[0]
+	if p.Kind != "photo" || p.Distance != 2 || p.ThreadID != 2 || p.OtherThreadID != 1 ||
+		p.OtherPhotoIndex == nil || *p.OtherPhotoIndex != 0 || p.StudentName == "" {
+		t.Errorf("pair = %+v", p)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
+
+func TestSeriesDuplicates_StudentForbidden(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectGetSeriesForView(mock, 100, 42, time.Now())
+	expectTeacherAccess(mock, 7, 42, "")
+
+	req := authedRequest(t, access, 7, false, http.MethodGet, "/series/100/duplicates", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403", rr.Code)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 
+	"github.com/Alarion239/my239/backend/internal/homework"
 	"github.com/Alarion239/my239/backend/internal/httpx"
 	"github.com/Alarion239/my239/backend/internal/logger"
 	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
@@ -19,14 +20,20 @@ import (
 )
 
 // photoView is a single photo on an event. URL is a short-TTL presigned
-// GET; ObjectKey is exposed too so the frontend can match user-visible
-// images back to events without parsing URLs.
+// GET of the screen-sized preview and ThumbURL of the list thumbnail; until
+// the photo pipeline has processed the upload both point at the upload
+// itself. The full-resolution image is fetched on demand through
+// GetEventPhotoOriginal rather than signed for every photo on the page.
+// ObjectKey is exposed too so the frontend can match user-visible images
+// back to events without parsing URLs.
 type photoView struct {
-	Index       int    `json:"index"`
-	ObjectKey   string `json:"object_key"`
-	URL         string `json:"url"`
-	ContentType string `json:"content_type"`
-	SizeBytes   int64  `json:"size_bytes"`
+	Index            int    `json:"index"`
+	ObjectKey        string `json:"object_key"`
+	URL              string `json:"url"`
+	ThumbURL         string `json:"thumb_url"`
+	ContentType      string `json:"content_type"`
+	SizeBytes        int64  `json:"size_bytes"`
+	ProcessingStatus string `json:"processing_status"`
 }
 
 // eventView mirrors a homework_thread_event row plus its photos.
@@ -45,6 +52,8 @@ type eventView struct {
 	// may not be a registered user.
 	IsOffline          bool   `json:"is_offline,omitempty"`
 	CreditedGraderName string `json:"credited_grader_name,omitempty"`
+	// Reason is the taxonomy code a grader gave on a rejection or retraction.
+	Reason *eventReasonView `json:"reason,omitempty"`
 }
 
 // threadView is the full timeline + cache state for one thread. Used by
@@ -69,10 +78,15 @@ type threadView struct {
 	UpdatedAt         time.Time         `json:"updated_at"`
 	Events            []eventView       `json:"events"`
 	Users             map[string]string `json:"users"`
+	// DuplicateFlags are suspected copies of this thread's attempts. Only
+	// GetThread fills them, and only for teachers and admins.
+	DuplicateFlags []duplicateFlagView `json:"duplicate_flags,omitempty"`
 }
 
 // GetThread — student owner, teacher of the center, or admin. Returns the
-// thread's full event timeline with short-TTL presigned photo URLs.
+// thread's full event timeline with short-TTL presigned photo URLs; a teacher
+// who could still second-grade a sampled verdict on the thread gets the blind
+// calibration view instead.
 func GetThread(database *db.DB, blobs objectstore.Store, downloadTTL time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -114,6 +128,29 @@ func GetThread(database *db.DB, blobs objectstore.Store, downloadTTL time.Durati
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
+		// Anyone allowed in who is not the owner is a teacher or an admin.
+		if thread.StudentUserID != userID {
+			// While a sampled verdict waits for its blind second grading, a
+			// teacher who may take it sees the thread as the calibration view
+			// does, so opening the thread from the queue gives nothing away.
+			if !callerIsAdmin(r) {
+				gradedEventID, err := q.PendingCalibrationOnThread(ctx, thread.ID, userID)
+				switch {
+				case err == nil:
+					blindThreadView(view, gradedEventID)
+				case !errors.Is(err, pgx.ErrNoRows):
+					logger.LogErrorContext(ctx, "homework: pending calibration", err, "thread_id", thread.ID)
+					httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+					return
+				}
+			}
+			view.DuplicateFlags, err = loadDuplicateFlags(ctx, q, thread.ID)
+			if err != nil {
+				logger.LogErrorContext(ctx, "homework: duplicate flags", err, "thread_id", thread.ID)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+				return
+			}
+		}
 		httpx.WriteJSON(w, http.StatusOK, view)
 	}
 }
@@ -134,7 +171,8 @@ func canViewThread(ctx context.Context, r *http.Request, q *store.Queries, userI
 
 // buildThreadView joins events and their photos and signs each photo's GET
 // URL with the configured download TTL. Photos are bucketed by event_id in
-// Go so we only run two queries against the DB for the timeline. We also
+// Go so we only run two queries against the DB for the timeline (a third
+// for reason codes once a verdict or retraction is on it). We also
 // fetch the series row (for due_at) and the set of users that appear
 // anywhere on the page (for display-name resolution).
 func buildThreadView(ctx context.Context, q *store.Queries, blobs objectstore.Store, thread store.HomeworkThread, downloadTTL time.Duration) (*threadView, error) {
@@ -156,27 +194,37 @@ func buildThreadView(ctx context.Context, q *store.Queries, blobs objectstore.St
 	}
 	photosByEvent := map[int64][]photoView{}
 	if len(eventIDs) > 0 {
-		rows, err := q.ListEventPhotosForEvents(ctx, eventIDs)
+		rows, err := q.ListEventPhotoRenditionsForEvents(ctx, eventIDs)
 		if err != nil {
 			return nil, fmt.Errorf("list event photos: %w", err)
 		}
 		for _, p := range rows {
-			url, err := blobs.PresignGet(ctx, p.ObjectKey, downloadTTL)
-			if err != nil {
-				// If the object is gone (lifecycle expired, manual
-				// delete, etc.) still surface the row — clients can
-				// render a placeholder. Hide URL by leaving it empty.
-				url = ""
+			// If an object is gone (lifecycle expired, manual delete, etc.)
+			// still surface the row — clients can render a placeholder.
+			// Hide the URL by leaving it empty.
+			var url, thumbURL string
+			if p.PreviewObjectKey != nil && p.ThumbObjectKey != nil {
+				url = presignOrEmpty(ctx, blobs, *p.PreviewObjectKey, downloadTTL)
+				thumbURL = presignOrEmpty(ctx, blobs, *p.ThumbObjectKey, downloadTTL)
+			} else {
+				url = presignOrEmpty(ctx, blobs, p.ObjectKey, downloadTTL)
+				thumbURL = url
 			}
 			photosByEvent[p.EventID] = append(photosByEvent[p.EventID], photoView{
-				Index:       int(p.Idx),
-				ObjectKey:   p.ObjectKey,
-				URL:         url,
-				ContentType: p.ContentType,
-				SizeBytes:   p.SizeBytes,
+				Index:            int(p.Idx),
+				ObjectKey:        p.ObjectKey,
+				URL:              url,
+				ThumbURL:         thumbURL,
+				ContentType:      p.ContentType,
+				SizeBytes:        p.SizeBytes,
+				ProcessingStatus: p.ProcessingStatus,
 			})
 		}
 	}
+	reasons, err := loadEventReasons(ctx, q, events)
+	if err != nil {
+		return nil, fmt.Errorf("list event reasons: %w", err)
+	}
 	evViews := make([]eventView, 0, len(events))
 	for _, e := range events {
 		photos := photosByEvent[e.ID]
@@ -195,6 +243,7 @@ func buildThreadView(ctx context.Context, q *store.Queries, blobs objectstore.St
 			Photos:             photos,
 			IsOffline:          e.IsOffline,
 			CreditedGraderName: e.CreditedGraderName,
+			Reason:             reasons[e.ID],
 		})
 	}
 	return &threadView{
@@ -216,6 +265,40 @@ func buildThreadView(ctx context.Context, q *store.Queries, blobs objectstore.St
 	}, nil
 }
 
+// loadEventReasons maps the reasoned events of a timeline to their reason.
+// Only verdicts and retractions can carry one, so a timeline without either
+// costs no query.
+func loadEventReasons(ctx context.Context, q *store.Queries, events []store.HomeworkThreadEvent) (map[int64]*eventReasonView, error) {
+	var ids []int64
+	for _, e := range events {
+		if e.Kind == homework.KindGraded || e.Kind == homework.KindRetracted {
+			ids = append(ids, e.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := q.ListEventReasons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int64]*eventReasonView, len(rows))
+	for _, r := range rows {
+		out[r.EventID] = &eventReasonView{Code: r.Code, Label: r.Label, CountsAsAttempt: r.CountsAsAttempt}
+	}
+	return out, nil
+}
+
+// presignOrEmpty signs a GET for key, returning "" when the object is missing
+// or storage is unavailable so one bad photo never fails the whole view.
+func presignOrEmpty(ctx context.Context, blobs objectstore.Store, key string, ttl time.Duration) string {
+	url, err := blobs.PresignGet(ctx, key, ttl)
+	if err != nil {
+		return ""
+	}
+	return url
+}
+
 // loadUserNames bulk-fetches every user that appears on the thread page
 // and returns a map[stringified-id]display-name. The student gets the
 // "Имя Фамилия" form; everyone else (graders, retracters, etc.) gets
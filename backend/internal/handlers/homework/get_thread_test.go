@@ -32,8 +32,8 @@ func TestGetThread_StudentOwnerAllowed(t *testing.T) {
 	_ = blobs.Put(t.Context(), key, strings.NewReader("img"), 3, "image/jpeg")
 	mock.ExpectQuery(`SELECT .* FROM homework_thread_event_photo`).
 		WithArgs([]int64{50}).
-		WillReturnRows(mock.NewRows([]string{"event_id", "idx", "object_key", "size_bytes", "content_type", "created_at"}).
-			AddRow(int64(50), int32(0), key, int64(3), "image/jpeg", now))
+		WillReturnRows(mock.NewRows(photoColumns).
+			AddRow(int64(50), int32(0), key, int64(3), "image/jpeg", now, "pending", (*string)(nil), (*string)(nil)))
 
 	req := authedRequest(t, access, 7, false, http.MethodGet, "/threads/by-id/1", nil)
 	rr := httptest.NewRecorder()
@@ -53,6 +53,9 @@ func TestGetThread_StudentOwnerAllowed(t *testing.T) {
 	if len(v.Events) != 1 {
 		t.Fatalf("want 1 event, got %d", len(v.Events))
 	}
+	if strings.Contains(rr.Body.String(), "duplicate_flags") {
+		t.Error("duplicate flags leaked to the student")
+	}
 	if len(v.Events[0].Photos) != 1 || v.Events[0].Photos[0].URL == "" {
 		t.Errorf("photo URL missing: %+v", v.Events[0].Photos)
 	}
@@ -74,6 +77,8 @@ func TestGetThread_TeacherOfCenterAllowed(t *testing.T) {
 		WithArgs(int64(1)).
 		WillReturnRows(mock.NewRows(eventColumns))
 	expectGetUsersForView(mock)
+	expectPendingCalibrationOnThread(mock, 1, 3, 0)
+	expectDuplicateFlags(mock, 1, nil)
 
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/threads/by-id/1", nil)
 	rr := httptest.NewRecorder()
@@ -83,6 +88,54 @@ func TestGetThread_TeacherOfCenterAllowed(t *testing.T) {
 	}
 }
 
+func TestGetThread_TeacherSeesDuplicateFlags(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	expectTeacherCheck(mock, 3, 42, true)
+	expectGetSeriesForView(mock, 100, 42, now)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(eventColumns))
+	expectGetUsersForView(mock)
+	expectPendingCalibrationOnThread(mock, 1, 3, 0)
+	expectDuplicateFlags(mock, 1, mock.NewRows(threadDuplicateFlagColumns).
+		AddRow(int64(5), "photo", int32(3), now, int64(50), int32(1), int64(2), int64(60), int32(0), int64(8), "Пётр", "Иванов").
+		AddRow(int64(6), "text", int32(0), now, int64(50), int32(0), int64(3), int64(70), int32(0), int64(9), "Анна", "Петрова"))
+
+	req := authedRequest(t, access, 3, false, http.MethodGet, "/threads/by-id/1", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var v struct {
+		DuplicateFlags []struct {
+			Kind          string `json:"kind"`
+			PhotoIndex    *int   `json:"photo_index"`
+			OtherThreadID int64  `json:"other_thread_id"`
+		} `json:"duplicate_flags"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(v.DuplicateFlags) != 2 {
+		t.Fatalf("want 2 flags, got %+v", v.DuplicateFlags)
+	}
+	if f := v.DuplicateFlags[0]; f.Kind != "photo" || f.PhotoIndex == nil || *f.PhotoIndex != 1 || f.OtherThreadID != 2 {
+		t.Errorf("photo flag = %+v", f)
+	}
+	if f := v.DuplicateFlags[1]; f.Kind != "text" || f.PhotoIndex != nil {
+		t.Errorf("text flag = %+v", f)
+	}
+}
+
 func TestGetThread_AdminAllowed(t *testing.T) {
 	t.Parallel()
 	mock, _ := pgxmock.NewPool()
@@ -98,6 +151,7 @@ func TestGetThread_AdminAllowed(t *testing.T) {
 		WithArgs(int64(1)).
 		WillReturnRows(mock.NewRows(eventColumns))
 	expectGetUsersForView(mock)
+	expectDuplicateFlags(mock, 1, nil)
 
 	req := authedRequest(t, access, 99, true /* admin */, http.MethodGet, "/threads/by-id/1", nil)
 	rr := httptest.NewRecorder()
@@ -126,3 +180,60 @@ func TestGetThread_OutsiderForbidden(t *testing.T) {
 		t.Errorf("got %d, want 403", rr.Code)
 	}
 }
+
+func TestGetThread_PendingCalibrationIsBlinded(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	attemptID := int64(50)
+	gradeID := int64(80)
+	grader := int64(3)
+	verdict := "rejected"
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "rejected", AttemptEventID: &attemptID, GradeEventID: &gradeID, LastGraderID: &grader,
+		}, now)...))
+	expectTeacherCheck(mock, 4, 42, true)
+	expectGetSeriesForView(mock, 100, 42, now)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(50), int64(1), "s1", "submitted", int64(7), "my proof", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", "").
+			AddRow(int64(80), int64(1), "g1", "graded", int64(3), "gap in step 2", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	mock.ExpectQuery(`SELECT id, first_name, middle_name, last_name\s+FROM users\s+WHERE id = ANY`).
+		WithArgs([]int64{7, 3}).
+		WillReturnRows(mock.NewRows([]string{"id", "first_name", "middle_name", "last_name"}).
+			AddRow(int64(7), "Петя", (*string)(nil), "Иванов").
+			AddRow(int64(3), "Анна", (*string)(nil), "Смирнова"))
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event_photo`).
+		WithArgs([]int64{50, 80}).
+		WillReturnRows(mock.NewRows(photoColumns))
+	expectEventReasons(mock, []int64{80}, nil)
+	expectPendingCalibrationOnThread(mock, 1, 4, 80)
+	expectDuplicateFlags(mock, 1, nil)
+
+	req := authedRequest(t, access, 4, false, http.MethodGet, "/threads/by-id/1", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var v struct {
+		CurrentStatus    string                  `json:"current_status"`
+		LastGraderUserID *int64                  `json:"last_grader_user_id"`
+		Events           []struct{ Kind string } `json:"events"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if v.CurrentStatus != "submitted" || v.LastGraderUserID != nil || len(v.Events) != 1 {
+		t.Fatalf("first verdict not blinded: %s", rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "gap in step 2") || strings.Contains(rr.Body.String(), "Смирнова") {
+		t.Fatalf("first grade leaked: %s", rr.Body.String())
+	}
+}
@@ -10,8 +10,11 @@ import (
 
 	"github.com/Alarion239/my239/backend/internal/homework"
 	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/hwchain"
 	"github.com/Alarion239/my239/backend/internal/live"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/metrics"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 	"github.com/Alarion239/my239/backend/pkg/objectstore"
@@ -19,12 +22,14 @@ import (
 
 // gradeRequest is the body of /grade. Verdict is "accepted" or "rejected".
 // Body is the required text comment ("the grade should come with a text
-// comment"). ObjectKeys are optional photo comment(s).
+// comment"). ObjectKeys are optional photo comment(s). ReasonCode is an
+// optional code from the center's taxonomy, only on rejections.
 type gradeRequest struct {
 	Verdict    string   `json:"verdict"`
 	Body       string   `json:"body"`
 	EventUUID  string   `json:"event_uuid"`
 	ObjectKeys []string `json:"object_keys"`
+	ReasonCode string   `json:"reason_code"`
 }
 
 // Grade — teacher of center, must hold the claim. Appends a 'graded' event
@@ -67,7 +72,7 @@ func Grade(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.Handler
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, thread.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanGrade) {
 			return
 		}
 
@@ -87,13 +92,18 @@ func Grade(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.Handler
 			}
 		}
 
+		reason, ok := resolveReasonCode(ctx, w, r, q, thread.MathCenterID, req.ReasonCode, homework.KindGraded)
+		if !ok {
+			return
+		}
 		photos, vErr := validateAndStatPhotos(ctx, blobs, thread.ID, req.EventUUID, req.ObjectKeys)
 		if vErr != "" {
 			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
 			return
 		}
 
-		if err := writeGrade(ctx, database, thread, req.EventUUID, userID, verdict, body, photos); err != nil {
+		event, err := writeGrade(ctx, database, thread, req.EventUUID, userID, verdict, body, reasonID(reason), photos)
+		if err != nil {
 			if errors.Is(err, errClaimContention) {
 				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "claim expired or held by another grader")
 				return
@@ -102,33 +112,60 @@ func Grade(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.Handler
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to record grade")
 			return
 		}
+		observeTurnaround(ctx, q, thread, event)
 		live.Publish(ctx, database.Pool(), live.Event{CenterID: thread.MathCenterID, Kind: live.KindGrading, SeriesID: thread.SeriesID})
 		writeThreadView(ctx, w, r, database, blobs, thread.ID)
 	}
 }
 
+// observeTurnaround feeds the submitted → graded histogram once the verdict
+// is committed. A failed lookup only costs a sample.
+func observeTurnaround(ctx context.Context, q *store.Queries, thread store.HomeworkThread, graded store.HomeworkThreadEvent) {
+	if thread.CurrentAttemptEventID == nil || graded.Verdict == nil {
+		return
+	}
+	submittedAt, err := q.GetAttemptSubmittedAt(ctx, *thread.CurrentAttemptEventID)
+	if err != nil {
+		logger.LogWarnContext(ctx, "homework: attempt time for turnaround", "error", err, "thread_id", thread.ID)
+		return
+	}
+	metrics.HomeworkGradeTurnaroundSeconds.WithLabelValues(*graded.Verdict).Observe(graded.CreatedAt.Sub(submittedAt).Seconds())
+}
+
 // validateGradeInput enforces the contract from the spec: verdict in
 // {accepted, rejected}, body non-empty within MaxBodyChars.
 func validateGradeInput(req gradeRequest) (verdict, body, errMsg string) {
-	switch req.Verdict {
+	verdict, cleaned, errMsg := validateVerdictBody(req.Verdict, req.Body, req.ReasonCode)
+	if errMsg != "" {
+		return "", "", errMsg
+	}
+	if req.EventUUID == "" || len(req.EventUUID) > 64 {
+		return "", "", "event_uuid is required"
+	}
+	if len(req.ObjectKeys) > homework.MaxPhotosPerEvent {
+		return "", "", fmt.Sprintf("at most %d photos per event", homework.MaxPhotosPerEvent)
+	}
+	return verdict, cleaned, ""
+}
+
+// validateVerdictBody is the part of the grade contract BulkGrade shares.
+// A reason code explains a rejection; accepted verdicts carry none.
+func validateVerdictBody(verdict, body, reasonCode string) (string, string, string) {
+	switch verdict {
 	case homework.VerdictAccepted, homework.VerdictRejected:
-		verdict = req.Verdict
 	default:
 		return "", "", "verdict must be 'accepted' or 'rejected'"
 	}
-	cleaned, err := homework.ValidateBody(req.Body)
+	if reasonCode != "" && verdict != homework.VerdictRejected {
+		return "", "", "reason_code is only given on rejections"
+	}
+	cleaned, err := homework.ValidateBody(body)
 	if err != nil {
 		return "", "", err.Error()
 	}
 	if cleaned == "" {
 		return "", "", "body (grader comment) is required"
 	}
-	if req.EventUUID == "" || len(req.EventUUID) > 64 {
-		return "", "", "event_uuid is required"
-	}
-	if len(req.ObjectKeys) > homework.MaxPhotosPerEvent {
-		return "", "", fmt.Sprintf("at most %d photos per event", homework.MaxPhotosPerEvent)
-	}
 	return verdict, cleaned, ""
 }
 
@@ -137,18 +174,22 @@ func validateGradeInput(req gradeRequest) (verdict, body, errMsg string) {
 // 409 in the handler.
 var errClaimContention = errors.New("homework: claim contention")
 
-// writeGrade commits a graded event + photos + cache update in one
-// transaction. UpdateThreadAfterGrade's WHERE clause re-checks claim
+// writeGrade commits a graded event + photos + reason + cache update in one
+// transaction and returns the event. UpdateThreadAfterGrade's WHERE clause re-checks claim
 // ownership, so a slow grader whose lease has expired cannot land a grade
 // on top of someone else's claim.
-func writeGrade(ctx context.Context, database *db.DB, thread store.HomeworkThread, eventUUID string, graderUserID int64, verdict, body string, photos []validatedPhoto) error {
+func writeGrade(ctx context.Context, database *db.DB, thread store.HomeworkThread, eventUUID string, graderUserID int64, verdict, body string, reasonCodeID *int64, photos []validatedPhoto) (store.HomeworkThreadEvent, error) {
 	tx, err := database.Pool().Begin(ctx)
 	if err != nil {
-		return err
+		return store.HomeworkThreadEvent{}, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	qx := store.New(tx)
+	prev, err := qx.LockEventChain(ctx, thread.ID)
+	if err != nil {
+		return store.HomeworkThreadEvent{}, fmt.Errorf("lock event chain: %w", err)
+	}
 	event, err := qx.AppendEvent(ctx, store.AppendEventParams{
 		ThreadID:        thread.ID,
 		EventUuid:       eventUUID,
@@ -159,7 +200,15 @@ func writeGrade(ctx context.Context, database *db.DB, thread store.HomeworkThrea
 		RefersToEventID: thread.CurrentAttemptEventID,
 	})
 	if err != nil {
-		return fmt.Errorf("append grade event: %w", err)
+		return store.HomeworkThreadEvent{}, fmt.Errorf("append grade event: %w", err)
+	}
+	if err := hwchain.Seal(ctx, qx, prev, event); err != nil {
+		return store.HomeworkThreadEvent{}, err
+	}
+	if reasonCodeID != nil {
+		if err := qx.SetEventReason(ctx, event.ID, *reasonCodeID); err != nil {
+			return store.HomeworkThreadEvent{}, fmt.Errorf("set grade reason: %w", err)
+		}
 	}
 	for _, p := range photos {
 		if err := qx.InsertEventPhoto(ctx, store.InsertEventPhotoParams{
@@ -169,7 +218,7 @@ func writeGrade(ctx context.Context, database *db.DB, thread store.HomeworkThrea
 			SizeBytes:   p.Size,
 			ContentType: p.ContentType,
 		}); err != nil {
-			return fmt.Errorf("insert grade photo %d: %w", p.Idx, err)
+			return store.HomeworkThreadEvent{}, fmt.Errorf("insert grade photo %d: %w", p.Idx, err)
 		}
 	}
 	affected, err := qx.UpdateThreadAfterGrade(ctx, store.UpdateThreadAfterGradeParams{
@@ -179,10 +228,24 @@ func writeGrade(ctx context.Context, database *db.DB, thread store.HomeworkThrea
 		ID:           thread.ID,
 	})
 	if err != nil {
-		return fmt.Errorf("update thread after grade: %w", err)
+		return store.HomeworkThreadEvent{}, fmt.Errorf("update thread after grade: %w", err)
 	}
 	if affected == 0 {
-		return errClaimContention
+		return store.HomeworkThreadEvent{}, errClaimContention
+	}
+	// A share of verdicts (per the center's calibration percent) is queued
+	// for an independent blind second grading.
+	if _, err := qx.SampleCalibration(ctx, store.SampleCalibrationParams{
+		ThreadID:          thread.ID,
+		GradedEventID:     event.ID,
+		FirstGraderUserID: graderUserID,
+		FirstVerdict:      verdict,
+		MathCenterID:      thread.MathCenterID,
+	}); err != nil {
+		return store.HomeworkThreadEvent{}, fmt.Errorf("sample calibration: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return store.HomeworkThreadEvent{}, err
 	}
-	return tx.Commit(ctx)
+	return event, nil
 }
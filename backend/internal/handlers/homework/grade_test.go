@@ -26,18 +26,22 @@ func TestGrade_HappyPathAccept(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "submitted", AttemptEventID: &attemptID,
 		}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	verdict := "accepted"
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), "g1", "graded", int64(3), "great work", &verdict, &attemptID).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(80), int64(1), "g1", "graded", int64(3), "great work", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(80), int64(1))
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= CASE`).
 		WithArgs("accepted", int64(80), int64(3), int64(1)).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectCalibrationSample(mock, 1, 80, 3, "accepted", 42, false)
 	mock.ExpectCommit()
+	expectAttemptSubmittedAt(mock, attemptID, now.Add(-time.Hour))
 	// view fetch
 	gradeID := int64(80)
 	graderID := int64(3)
@@ -77,18 +81,22 @@ func TestGrade_RejectVerdictAlsoWorks(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "submitted", AttemptEventID: &attemptID,
 		}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	verdict := "rejected"
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), "g2", "graded", int64(3), "see step 3", &verdict, &attemptID).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(81), int64(1), "g2", "graded", int64(3), "see step 3", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(81), int64(1))
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= CASE`).
 		WithArgs("rejected", int64(81), int64(3), int64(1)).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectCalibrationSample(mock, 1, 81, 3, "rejected", 42, false)
 	mock.ExpectCommit()
+	expectAttemptSubmittedAt(mock, attemptID, now.Add(-time.Hour))
 	gradeID := int64(81)
 	graderID := int64(3)
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
@@ -127,14 +135,16 @@ func TestGrade_ClaimContention(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "submitted", AttemptEventID: &attemptID,
 		}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	verdict := "accepted"
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), "g3", "graded", int64(3), "ok", &verdict, &attemptID).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(82), int64(1), "g3", "graded", int64(3), "ok", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(82), int64(1))
 	// UpdateThreadAfterGrade affects 0 rows — claim was stolen.
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= CASE`).
 		WithArgs("accepted", int64(82), int64(3), int64(1)).
@@ -169,7 +179,7 @@ func TestGrade_AppealedRoutesToOriginalGrader(t *testing.T) {
 		}, now)...))
 	// Different teacher (user 4) tries to grade — they're a teacher of
 	// the center but NOT the original grader.
-	expectTeacherCheck(mock, 4, 42, true)
+	expectTeacherAccess(mock, 4, 42, "teacher")
 
 	body, _ := json.Marshal(map[string]any{
 		"verdict": "accepted", "body": "ok", "event_uuid": "g4", "object_keys": []string{},
@@ -197,19 +207,23 @@ func TestGrade_AdminCanOverrideAppealStickiness(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "appealed", AttemptEventID: &attemptID, LastGraderID: &originalGrader,
 		}, now)...))
-	// Admin is a teacher superset: requireTeacher short-circuits without an
-	// IsTeacherInCenter query, so we deliberately do not expect one here.
+	// Admin is a teacher superset: teacherAccess short-circuits without a
+	// role lookup, so we deliberately do not expect one here.
 
 	verdict := "accepted"
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), "g5", "graded", int64(4), "admin override", &verdict, &attemptID).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(90), int64(1), "g5", "graded", int64(4), "admin override", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(90), int64(1))
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= CASE`).
 		WithArgs("accepted", int64(90), int64(4), int64(1)).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectCalibrationSample(mock, 1, 90, 4, "accepted", 42, false)
 	mock.ExpectCommit()
+	expectAttemptSubmittedAt(mock, attemptID, now.Add(-time.Hour))
 	gradeID := int64(90)
 	graderID := int64(4)
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
@@ -284,7 +298,7 @@ func TestGrade_HappyPathWithPhoto(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "submitted", AttemptEventID: &attemptID,
 		}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	// Seed a photo at the canonical prefix.
 	key := "homework/thread/1/g6/0.png"
@@ -292,17 +306,21 @@ func TestGrade_HappyPathWithPhoto(t *testing.T) {
 
 	verdict := "rejected"
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), "g6", "graded", int64(3), "annotated", &verdict, &attemptID).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(83), int64(1), "g6", "graded", int64(3), "annotated", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(83), int64(1))
 	mock.ExpectExec(`INSERT INTO homework_thread_event_photo`).
 		WithArgs(int64(83), int32(0), key, int64(5), "image/png").
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= CASE`).
 		WithArgs("rejected", int64(83), int64(3), int64(1)).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectCalibrationSample(mock, 1, 83, 3, "rejected", 42, false)
 	mock.ExpectCommit()
+	expectAttemptSubmittedAt(mock, attemptID, now.Add(-time.Hour))
 	gradeID := int64(83)
 	graderID := int64(3)
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
@@ -343,7 +361,7 @@ func TestGrade_RejectsWhenAcceptedAlready(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "accepted",
 		}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	body, _ := json.Marshal(map[string]any{
 		"verdict": "accepted", "body": "x", "event_uuid": "g7", "object_keys": []string{},
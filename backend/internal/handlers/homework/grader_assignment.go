@@ -0,0 +1,343 @@
+package homework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/live"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// gradingConfig is the wire shape of a series' assignment configuration,
+// used for both GET and PUT.
+type gradingConfig struct {
+	Mode              string         `json:"mode"`
+	ReassignOnAbsence bool           `json:"reassign_on_absence"`
+	Pool              []int64        `json:"pool"`
+	ProblemOwners     []problemOwner `json:"problem_owners"`
+}
+
+type problemOwner struct {
+	ProblemID    int64 `json:"problem_id"`
+	GraderUserID int64 `json:"grader_user_id"`
+}
+
+// GetSeriesGrading — teacher of the series's center. Returns the assignment
+// configuration; a series never configured reports mode 'off'.
+func GetSeriesGrading(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		seriesID, err := pathInt64(r, "seriesID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid series id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		series, ok := loadSeriesForTeacher(ctx, w, r, q, userID, seriesID)
+		if !ok {
+			return
+		}
+		cfg, err := readGradingConfig(ctx, q, series.ID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: read grading config", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, cfg)
+	}
+}
+
+// PutSeriesGrading — teacher of the series's center. Replaces the assignment
+// configuration and rebalances every submitted thread of the series under
+// the new rules, all in one transaction. Pool members and problem owners must
+// be teachers of the center; problems must belong to the series.
+func PutSeriesGrading(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		seriesID, err := pathInt64(r, "seriesID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid series id")
+			return
+		}
+		var req gradingConfig
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if err := homework.ValidateAssignmentMode(req.Mode); err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, err.Error())
+			return
+		}
+		if len(req.Pool) > homework.MaxSeriesGraders || len(req.ProblemOwners) > homework.MaxSeriesGraders {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest,
+				fmt.Sprintf("at most %d graders per list", homework.MaxSeriesGraders))
+			return
+		}
+
+		q := store.New(database.Pool())
+		series, ok := loadSeriesForTeacher(ctx, w, r, q, userID, seriesID)
+		if !ok {
+			return
+		}
+		if msg, err := validateGradingTargets(ctx, q, series.MathCenterID, series.ID, req); err != nil {
+			logger.LogErrorContext(ctx, "homework: validate grading config", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		} else if msg != "" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, msg)
+			return
+		}
+
+		if err := writeGradingConfig(ctx, database, series.ID, req); err != nil {
+			logger.LogErrorContext(ctx, "homework: write grading config", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save grading config")
+			return
+		}
+		live.Publish(ctx, database.Pool(), live.Event{CenterID: series.MathCenterID, Kind: live.KindGrading, SeriesID: series.ID})
+
+		cfg, err := readGradingConfig(ctx, q, series.ID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: read grading config", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, cfg)
+	}
+}
+
+// awayRequest is the body of PUT /centers/{centerID}/away. A null or past
+// AwayUntil marks the caller present again.
+type awayRequest struct {
+	AwayUntil *time.Time `json:"away_until"`
+}
+
+// SetAway — teacher of the center, for themselves. Marks the caller away
+// until the given time. In series that opted into reassign-on-absence the
+// caller's pending submissions are handed to other graders right away; new
+// submissions skip them until they are back. Appeals stay with the caller.
+func SetAway(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		var req awayRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		away := req.AwayUntil != nil && req.AwayUntil.After(time.Now())
+		if !away {
+			req.AwayUntil = nil
+		}
+
+		reassigned, err := writeAway(ctx, database, centerID, userID, req.AwayUntil, away)
+		if err != nil {
+			if errors.Is(err, errNotTeacher) {
+				httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "not a teacher of this center")
+				return
+			}
+			logger.LogErrorContext(ctx, "homework: set away", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to update absence")
+			return
+		}
+		if reassigned > 0 {
+			live.Publish(ctx, database.Pool(), live.Event{CenterID: centerID, Kind: live.KindGrading})
+		}
+		httpx.WriteJSON(w, http.StatusOK, map[string]any{
+			"away_until": req.AwayUntil,
+			"reassigned": reassigned,
+		})
+	}
+}
+
+// errNotTeacher signals that SetTeacherAwayUntil matched no membership row.
+var errNotTeacher = errors.New("homework: not a teacher of this center")
+
+func writeAway(ctx context.Context, database *db.DB, centerID, userID int64, awayUntil *time.Time, away bool) (int, error) {
+	tx, err := database.Pool().Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qx := store.New(tx)
+	affected, err := qx.SetTeacherAwayUntil(ctx, centerID, userID, awayUntil)
+	if err != nil {
+		return 0, fmt.Errorf("set away: %w", err)
+	}
+	if affected == 0 {
+		return 0, errNotTeacher
+	}
+	reassigned := 0
+	if away {
+		ids, err := qx.ListAbsenceReassignableThreadIDs(ctx, centerID, userID)
+		if err != nil {
+			return 0, fmt.Errorf("list reassignable threads: %w", err)
+		}
+		for _, id := range ids {
+			if _, err := qx.AssignThreadGrader(ctx, id); err != nil {
+				return 0, fmt.Errorf("reassign thread %d: %w", id, err)
+			}
+		}
+		reassigned = len(ids)
+	}
+	return reassigned, tx.Commit(ctx)
+}
+
+// loadSeriesForTeacher fetches the series and checks the caller may see it;
+// changing the grading setup also needs mc.CanManage. Writes the error
+// envelope on failure.
+func loadSeriesForTeacher(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, seriesID int64) (store.GetSeriesRow, bool) {
+	series, err := q.GetSeries(ctx, seriesID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "series not found")
+			return series, false
+		}
+		logger.LogErrorContext(ctx, "homework: get series", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return series, false
+	}
+	can := mc.CanManage
+	if r.Method == http.MethodGet {
+		can = mc.CanView
+	}
+	ok := requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, can)
+	return series, ok
+}
+
+// seriesAssignmentMode returns the series' mode, treating "never configured"
+// as AssignmentOff.
+func seriesAssignmentMode(ctx context.Context, q *store.Queries, seriesID int64) (string, error) {
+	g, err := q.GetSeriesGrading(ctx, seriesID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return homework.AssignmentOff, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return g.Mode, nil
+}
+
+func readGradingConfig(ctx context.Context, q *store.Queries, seriesID int64) (gradingConfig, error) {
+	cfg := gradingConfig{Mode: homework.AssignmentOff, Pool: []int64{}, ProblemOwners: []problemOwner{}}
+	g, err := q.GetSeriesGrading(ctx, seriesID)
+	switch {
+	case err == nil:
+		cfg.Mode, cfg.ReassignOnAbsence = g.Mode, g.ReassignOnAbsence
+	case !errors.Is(err, pgx.ErrNoRows):
+		return cfg, fmt.Errorf("get grading: %w", err)
+	}
+	if cfg.Pool, err = q.ListSeriesGraders(ctx, seriesID); err != nil {
+		return cfg, fmt.Errorf("list pool: %w", err)
+	}
+	owners, err := q.ListProblemGradersForSeries(ctx, seriesID)
+	if err != nil {
+		return cfg, fmt.Errorf("list problem owners: %w", err)
+	}
+	for _, o := range owners {
+		cfg.ProblemOwners = append(cfg.ProblemOwners, problemOwner{ProblemID: o.ProblemID, GraderUserID: o.GraderUserID})
+	}
+	return cfg, nil
+}
+
+// validateGradingTargets returns a client-facing message when a grader is not
+// a teacher of the center or a problem is not in the series.
+func validateGradingTargets(ctx context.Context, q *store.Queries, centerID, seriesID int64, req gradingConfig) (string, error) {
+	teachers, err := q.ListTeachersForCenter(ctx, centerID)
+	if err != nil {
+		return "", fmt.Errorf("list teachers: %w", err)
+	}
+	isTeacher := make(map[int64]bool, len(teachers))
+	for _, t := range teachers {
+		isTeacher[t.UserID] = true
+	}
+	problems, err := q.ListProblemsForSeries(ctx, seriesID)
+	if err != nil {
+		return "", fmt.Errorf("list problems: %w", err)
+	}
+	inSeries := make(map[int64]bool, len(problems))
+	for _, p := range problems {
+		inSeries[p.ID] = true
+	}
+
+	for _, id := range req.Pool {
+		if !isTeacher[id] {
+			return fmt.Sprintf("user %d is not a teacher of this center", id), nil
+		}
+	}
+	for _, o := range req.ProblemOwners {
+		if !isTeacher[o.GraderUserID] {
+			return fmt.Sprintf("user %d is not a teacher of this center", o.GraderUserID), nil
+		}
+		if !inSeries[o.ProblemID] {
+			return fmt.Sprintf("problem %d is not in this series", o.ProblemID), nil
+		}
+	}
+	return "", nil
+}
+
+func writeGradingConfig(ctx context.Context, database *db.DB, seriesID int64, req gradingConfig) error {
+	tx, err := database.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qx := store.New(tx)
+	if _, err := qx.UpsertSeriesGrading(ctx, store.UpsertSeriesGradingParams{
+		SeriesID:          seriesID,
+		Mode:              req.Mode,
+		ReassignOnAbsence: req.ReassignOnAbsence,
+	}); err != nil {
+		return fmt.Errorf("upsert grading: %w", err)
+	}
+	pool := req.Pool
+	if pool == nil {
+		pool = []int64{}
+	}
+	if err := qx.ReplaceSeriesGraders(ctx, seriesID, pool); err != nil {
+		return fmt.Errorf("replace pool: %w", err)
+	}
+	owners := make([]store.ProblemGrader, 0, len(req.ProblemOwners))
+	for _, o := range req.ProblemOwners {
+		owners = append(owners, store.ProblemGrader{ProblemID: o.ProblemID, GraderUserID: o.GraderUserID})
+	}
+	if err := qx.ReplaceProblemGraders(ctx, seriesID, owners); err != nil {
+		return fmt.Errorf("replace problem owners: %w", err)
+	}
+	ids, err := qx.ListSubmittedThreadIDsForSeries(ctx, seriesID)
+	if err != nil {
+		return fmt.Errorf("list submitted threads: %w", err)
+	}
+	for _, id := range ids {
+		if _, err := qx.AssignThreadGrader(ctx, id); err != nil {
+			return fmt.Errorf("assign thread %d: %w", id, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,264 @@
+package homework_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func expectSeriesRow(mock pgxmock.PgxPoolIface, seriesID, centerID int64, now time.Time) {
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(seriesID).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(seriesID, centerID, int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
+}
+
+func TestGraderQueue_AssignedModeFiltersToCaller(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	expectSeriesRow(mock, 100, 42, now)
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	expectSeriesGrading(mock, 100, "round_robin")
+	mock.ExpectQuery(`t\.assigned_grader_user_id\s+FROM homework_thread t`).
+		WithArgs(int64(100), int64(3), false).
+		WillReturnRows(mock.NewRows(append(append([]string{}, queueRowColumns...), "assigned_grader_user_id")).
+			AddRow(int64(1), int64(7), int64(900), int64(100), int64(42),
+				"submitted", (*int64)(nil), (*int64)(nil), (*time.Time)(nil), now,
+				"Аня", (*string)(nil), "Иванова", "a", int32(1), ptr64(3)))
+	mock.ExpectQuery(`FROM math_center_student_name_color`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows([]string{"student_user_id", "background_hex"}))
+
+	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/queue", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var items []map[string]any
+	_ = json.Unmarshal(rr.Body.Bytes(), &items)
+	if len(items) != 1 || items[0]["assigned_grader_user_id"] != float64(3) {
+		t.Errorf("unexpected items: %s", rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+func TestGraderQueue_AllBypassesAssignment(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectSeriesRow(mock, 100, 42, time.Now())
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	// No assignment-mode lookup: ?all=true goes straight to the plain queue.
+	mock.ExpectQuery(`FROM homework_thread t\s+JOIN users u`).
+		WithArgs(int64(100), int64(3), false).
+		WillReturnRows(mock.NewRows(queueRowColumns))
+	mock.ExpectQuery(`FROM math_center_student_name_color`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows([]string{"student_user_id", "background_hex"}))
+
+	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/queue?all=true", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+var teacherListColumns = []string{"id", "user_id", "math_center_id", "is_head_teacher", "first_name", "middle_name", "last_name"}
+
+func expectGradingTargets(mock pgxmock.PgxPoolIface, centerID, seriesID int64, teacherIDs []int64, problemIDs []int64) {
+	teachers := mock.NewRows(teacherListColumns)
+	for i, id := range teacherIDs {
+		teachers.AddRow(int64(i+1), id, centerID, false, "T", (*string)(nil), "T")
+	}
+	mock.ExpectQuery(`FROM math_center_teachers t\s+JOIN users u`).
+		WithArgs(centerID).
+		WillReturnRows(teachers)
+	problems := mock.NewRows([]string{"id", "series_id", "number", "created_at"})
+	for i, id := range problemIDs {
+		problems.AddRow(id, seriesID, int32(i+1), time.Now())
+	}
+	mock.ExpectQuery(`FROM math_center_problems\s+WHERE series_id`).
+		WithArgs(seriesID).
+		WillReturnRows(problems)
+}
+
+func TestPutSeriesGrading_RejectsNonTeacher(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectSeriesRow(mock, 100, 42, time.Now())
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	expectGradingTargets(mock, 42, 100, []int64{3}, []int64{500})
+
+	body, _ := json.Marshal(map[string]any{"mode": "round_robin", "pool": []int64{3, 77}, "problem_owners": []any{}})
+	req := authedRequest(t, access, 3, false, http.MethodPut, "/series/100/grading", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPutSeriesGrading_RejectsUnknownMode(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	body, _ := json.Marshal(map[string]any{"mode": "lottery"})
+	req := authedRequest(t, access, 3, false, http.MethodPut, "/series/100/grading", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPutSeriesGrading_SavesAndRebalances(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	expectSeriesRow(mock, 100, 42, now)
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	expectGradingTargets(mock, 42, 100, []int64{3, 4}, []int64{500})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO homework_series_grading`).
+		WithArgs(int64(100), "by_problem", true).
+		WillReturnRows(mock.NewRows([]string{"series_id", "mode", "reassign_on_absence", "updated_at"}).
+			AddRow(int64(100), "by_problem", true, now))
+	mock.ExpectExec(`INSERT INTO homework_series_grader`).
+		WithArgs(int64(100), []int64{3, 4}).
+		WillReturnResult(pgxmock.NewResult("INSERT", 2))
+	mock.ExpectExec(`INSERT INTO homework_problem_grader`).
+		WithArgs(int64(100), []int64{500}, []int64{4}).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`SELECT id\s+FROM homework_thread\s+WHERE series_id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(1)).AddRow(int64(2)))
+	expectAssignGrader(mock, 1, ptr64(4))
+	expectAssignGrader(mock, 2, ptr64(3))
+	mock.ExpectCommit()
+	mock.ExpectExec(`pg_notify`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	// Read-back.
+	expectSeriesGrading(mock, 100, "by_problem")
+	mock.ExpectQuery(`FROM homework_series_grader\s+WHERE series_id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows([]string{"grader_user_id"}).AddRow(int64(3)).AddRow(int64(4)))
+	mock.ExpectQuery(`FROM homework_problem_grader pg`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows([]string{"problem_id", "grader_user_id"}).AddRow(int64(500), int64(4)))
+
+	body, _ := json.Marshal(map[string]any{
+		"mode":                "by_problem",
+		"reassign_on_absence": true,
+		"pool":                []int64{3, 4},
+		"problem_owners":      []map[string]int64{{"problem_id": 500, "grader_user_id": 4}},
+	})
+	req := authedRequest(t, access, 3, false, http.MethodPut, "/series/100/grading", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var cfg struct {
+		Mode          string  `json:"mode"`
+		Pool          []int64 `json:"pool"`
+		ProblemOwners []struct {
+			ProblemID    int64 `json:"problem_id"`
+			GraderUserID int64 `json:"grader_user_id"`
+		} `json:"problem_owners"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &cfg)
+	if cfg.Mode != "by_problem" || len(cfg.Pool) != 2 || len(cfg.ProblemOwners) != 1 {
+		t.Errorf("unexpected config: %s", rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+func TestSetAway_ReassignsPendingThreads(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE math_center_teachers\s+SET away_until`).
+		WithArgs(int64(42), int64(3), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(`SELECT t.id\s+FROM homework_thread t`).
+		WithArgs(int64(42), int64(3)).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(1)))
+	expectAssignGrader(mock, 1, ptr64(4))
+	mock.ExpectCommit()
+	mock.ExpectExec(`pg_notify`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	body, _ := json.Marshal(map[string]any{"away_until": time.Now().Add(72 * time.Hour)})
+	req := authedRequest(t, access, 3, false, http.MethodPut, "/centers/42/away", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Reassigned int `json:"reassigned"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Reassigned != 1 {
+		t.Errorf("reassigned = %d, want 1", resp.Reassigned)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+func TestSetAway_NonTeacherForbidden(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE math_center_teachers\s+SET away_until`).
+		WithArgs(int64(42), int64(7), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectRollback()
+
+	body, _ := json.Marshal(map[string]any{"away_until": nil})
+	req := authedRequest(t, access, 7, false, http.MethodPut, "/centers/42/away", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+}
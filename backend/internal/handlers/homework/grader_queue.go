@@ -1,6 +1,7 @@
 package homework
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 
+	"github.com/Alarion239/my239/backend/internal/homework"
 	"github.com/Alarion239/my239/backend/internal/httpx"
 	"github.com/Alarion239/my239/backend/internal/logger"
 	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
@@ -32,12 +34,18 @@ type queueItem struct {
 	ClaimExpiresAt    *time.Time `json:"claim_expires_at,omitempty"`
 	UpdatedAt         time.Time  `json:"updated_at"`
 	BackgroundHex     *string    `json:"background_hex"`
+	// AssignedGraderUserID is set only in series with an assignment mode.
+	AssignedGraderUserID *int64 `json:"assigned_grader_user_id,omitempty"`
 }
 
 // GraderQueue — teacher of the series's center. Returns items that need
 // grading, optionally filtered to only those where the caller was the
 // most recent grader (?mine=true). Items currently locked by someone else
-// are excluded.
+// are excluded. In series with an assignment mode the queue is further
+// narrowed to the caller's assignments (plus unassigned threads unless
+// ?mine=true); ?all=true shows every pending thread regardless.
+// ?reason=<code> keeps only threads whose previous verdict carried that
+// reason code, e.g. resubmissions after an unreadable photo.
 func GraderQueue(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -51,6 +59,8 @@ func GraderQueue(database *db.DB) http.HandlerFunc {
 			return
 		}
 		mine, _ := strconv.ParseBool(r.URL.Query().Get("mine"))
+		all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+		reason := r.URL.Query().Get("reason")
 
 		q := store.New(database.Pool())
 		series, err := q.GetSeries(ctx, seriesID)
@@ -63,20 +73,45 @@ func GraderQueue(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, series.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanView) {
 			return
 		}
 
-		rows, err := q.ListGraderQueueForSeries(ctx, store.ListGraderQueueForSeriesParams{
+		mode := homework.AssignmentOff
+		if !all {
+			if mode, err = seriesAssignmentMode(ctx, q, seriesID); err != nil {
+				logger.LogErrorContext(ctx, "homework: grader queue assignment mode", err)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+				return
+			}
+		}
+		params := store.ListGraderQueueForSeriesParams{
 			SeriesID:     seriesID,
 			CallerUserID: userID,
 			MineOnly:     mine,
-		})
+		}
+		var rows []store.AssignedGraderQueueRow
+		if mode == homework.AssignmentOff {
+			plain, lErr := q.ListGraderQueueForSeries(ctx, params)
+			for _, row := range plain {
+				rows = append(rows, store.AssignedGraderQueueRow{ListGraderQueueForSeriesRow: row})
+			}
+			err = lErr
+		} else {
+			rows, err = q.ListAssignedGraderQueueForSeries(ctx, params)
+		}
 		if err != nil {
 			logger.LogErrorContext(ctx, "homework: list grader queue", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
+		if reason != "" {
+			if rows, err = filterQueueByReason(ctx, q, seriesID, reason, rows); err != nil {
+				logger.LogErrorContext(ctx, "homework: grader queue reason filter", err)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+				return
+			}
+		}
 		colors, err := mc.StudentNameColorsForCenter(ctx, q, series.MathCenterID)
 		if err != nil {
 			logger.LogErrorContext(ctx, "homework: grader queue student name colors", err)
@@ -104,8 +139,30 @@ func GraderQueue(database *db.DB) http.HandlerFunc {
 				ClaimExpiresAt:    row.ClaimExpiresAt,
 				UpdatedAt:         row.UpdatedAt,
 				BackgroundHex:     backgroundHex,
+
+				AssignedGraderUserID: row.AssignedGraderUserID,
 			})
 		}
 		httpx.WriteJSON(w, http.StatusOK, out)
 	}
 }
+
+// filterQueueByReason narrows queue rows to threads whose previous verdict
+// carried the reason code. An unknown code simply matches nothing.
+func filterQueueByReason(ctx context.Context, q *store.Queries, seriesID int64, code string, rows []store.AssignedGraderQueueRow) ([]store.AssignedGraderQueueRow, error) {
+	ids, err := q.ListPendingThreadsByReason(ctx, seriesID, code)
+	if err != nil {
+		return nil, err
+	}
+	match := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		match[id] = true
+	}
+	kept := rows[:0]
+	for _, row := range rows {
+		if match[row.ID] {
+			kept = append(kept, row)
+		}
+	}
+	return kept, nil
+}
@@ -22,7 +22,8 @@ func TestGraderQueue_HappyPath(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	expectSeriesGrading(mock, 100, "")
 
 	mock.ExpectQuery(`FROM homework_thread t\s+JOIN users u`).
 		WithArgs(int64(100), int64(3), false).
@@ -67,7 +68,8 @@ func TestGraderQueue_MineQueryParam(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	expectSeriesGrading(mock, 100, "")
 
 	// ?mine=true must reach the SQL with mine_only=true.
 	mock.ExpectQuery(`FROM homework_thread t\s+JOIN users u`).
@@ -85,10 +87,56 @@ func TestGraderQueue_MineQueryParam(t *testing.T) {
 	}
 }
 
-// TestGraderQueue_AdminNotEnrolledAllowed proves requireTeacher treats an
-// admin as a teacher superset: no IsTeacherInCenter query runs (the admin
-// short-circuits it) yet the request succeeds for a center the admin is not
-// enrolled in.
+// TestGraderQueue_AdminNotEnrolledAllowed proves teacherAccess treats an
+// admin as a teacher superset: no role lookup runs (the admin short-circuits
+// it) yet the request succeeds for a center the admin is not enrolled in.
+func TestGraderQueue_ReasonFilter(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	expectSeriesGrading(mock, 100, "")
+	mock.ExpectQuery(`FROM homework_thread t\s+JOIN users u`).
+		WithArgs(int64(100), int64(3), false).
+		WillReturnRows(mock.NewRows(queueRowColumns).
+			AddRow(int64(1), int64(7), int64(900), int64(100), int64(42),
+				"submitted", ptr64(4), (*int64)(nil), (*time.Time)(nil), now,
+				"Аня", (*string)(nil), "Иванова", "a", int32(1)).
+			AddRow(int64(2), int64(8), int64(901), int64(100), int64(42),
+				"submitted", (*int64)(nil), (*int64)(nil), (*time.Time)(nil), now,
+				"Боря", (*string)(nil), "Петров", "b", int32(2)))
+	mock.ExpectQuery(`JOIN homework_event_reason er ON er.event_id = t.current_grade_event_id`).
+		WithArgs(int64(100), "illegible_photo").
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(1)))
+	mock.ExpectQuery(`FROM math_center_student_name_color`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows([]string{"student_user_id", "background_hex"}))
+
+	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/queue?reason=illegible_photo", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var items []struct {
+		ThreadID int64 `json:"thread_id"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &items)
+	if len(items) != 1 || items[0].ThreadID != 1 {
+		t.Fatalf("items = %+v, want only thread 1", items)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
+
 func TestGraderQueue_AdminNotEnrolledAllowed(t *testing.T) {
 	t.Parallel()
 	mock, _ := pgxmock.NewPool()
@@ -101,6 +149,7 @@ func TestGraderQueue_AdminNotEnrolledAllowed(t *testing.T) {
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
 	// NOTE: deliberately no expectTeacherCheck — an admin must not trigger it.
+	expectSeriesGrading(mock, 100, "")
 	mock.ExpectQuery(`FROM homework_thread t\s+JOIN users u`).
 		WithArgs(int64(100), int64(99), false).
 		WillReturnRows(mock.NewRows(queueRowColumns))
@@ -131,7 +180,7 @@ func TestGraderQueue_NonTeacherForbidden(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	expectTeacherCheck(mock, 3, 42, false)
+	expectTeacherAccess(mock, 3, 42, "")
 
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/queue", nil)
 	rr := httptest.NewRecorder()
@@ -140,3 +189,28 @@ func TestGraderQueue_NonTeacherForbidden(t *testing.T) {
 		t.Errorf("got %d, want 403", rr.Code)
 	}
 }
+
+func TestGraderQueue_OtherTermForbidden(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
+	expectTermGraderAccess(mock, 3, 42, 5)
+	expectSeriesInTerm(mock, 100, 5, false)
+
+	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/queue", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Alarion239/my239/backend/internal/httpx"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 )
@@ -20,6 +21,7 @@ type graderStatsResponse struct {
 
 // GraderStats — teacher of the center. Single-row count summary for the
 // dashboard ("3 to grade, 1 you're already on, 2 appeals waiting for you").
+// A term-scoped assistant counts only their term.
 func GraderStats(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -34,13 +36,15 @@ func GraderStats(database *db.DB) http.HandlerFunc {
 		}
 
 		q := store.New(database.Pool())
-		if !requireTeacher(ctx, w, r, q, userID, centerID) {
+		access, ok := teacherAccess(ctx, w, r, q, userID, centerID, 0, mc.CanView)
+		if !ok {
 			return
 		}
 
 		row, err := q.GraderStatsForCenter(ctx, store.GraderStatsForCenterParams{
 			MathCenterID: centerID,
 			CallerUserID: userID,
+			TermID:       access.TermID,
 		})
 		if err != nil {
 			logger.LogErrorContext(ctx, "homework: grader stats", err)
@@ -15,9 +15,9 @@ func TestGraderStats_HappyPath(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`SELECT\s+COUNT\(\*\)`).
-		WithArgs(int64(42), int64(3)).
+		WithArgs(int64(42), int64(3), (*int64)(nil)).
 		WillReturnRows(mock.NewRows([]string{"pending_count", "my_claimed_count", "my_appeals_count"}).
 			AddRow(int64(5), int64(2), int64(1)))
 
@@ -40,7 +40,7 @@ func TestGraderStats_NonTeacherForbidden(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, false)
+	expectTeacherAccess(mock, 3, 42, "")
 
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/grader-stats", nil)
 	rr := httptest.NewRecorder()
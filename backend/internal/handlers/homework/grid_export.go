@@ -0,0 +1,368 @@
+package homework
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/xlsx"
+)
+
+// gradebookStatusLetters is what a non-empty cell shows; ungraded cells stay
+// blank. Accepted cells also carry the grader's initials and the acceptance
+// time.
+var gradebookStatusLetters = map[string]string{
+	homework.StatusAccepted:  "+",
+	homework.StatusRejected:  "-",
+	homework.StatusSubmitted: "?",
+	homework.StatusAppealed:  "!",
+}
+
+// gradebookTimeLayout renders acceptance times in UTC, to the minute.
+const gradebookTimeLayout = "2006-01-02T15:04Z"
+
+// ExportCenterGrid — teacher of the center. Streams the term's students ×
+// subproblems matrix (the one GetCenterGrid returns) as CSV or XLSX:
+// ?format=csv|xlsx (default csv), ?term=<id> (default: the active term).
+//
+// Two header rows (series, then column labels), one row per student grouped
+// by math_center_groups, a Σ column with the student's accepted count after
+// each series, and a footer row with per-column accepted counts. Roster and
+// columns are read up front; cells stream from the database one student at a
+// time, so memory does not grow with the size of the center.
+func ExportCenterGrid(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "xlsx" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "format must be csv or xlsx")
+			return
+		}
+
+		q := store.New(database.Pool())
+		access, ok := teacherAccess(ctx, w, r, q, userID, centerID, 0, mc.CanView)
+		if !ok {
+			return
+		}
+		termID, err := centerGridTermFor(ctx, q, access, centerID, r.URL.Query().Get("term"))
+		if err != nil {
+			if errors.Is(err, errInvalidCenterGridTerm) {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid term id")
+				return
+			}
+			if errors.Is(err, mc.ErrTeacherOutsideTerm) {
+				httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, err.Error())
+				return
+			}
+			logger.LogErrorContext(ctx, "homework: grid export term", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+
+		// One snapshot for roster, columns and cells, like GetCenterGrid.
+		tx, err := database.Pool().BeginTx(ctx, pgx.TxOptions{
+			IsoLevel:   pgx.RepeatableRead,
+			AccessMode: pgx.ReadOnly,
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: grid export begin", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+		args := store.CenterGridTermParams{MathCenterID: centerID, TermID: termID}
+
+		var (
+			roster  []store.TeacherCenterGridRosterRow
+			columns []store.TeacherCenterGridColumnRow
+		)
+		if termID != 0 {
+			if roster, err = qx.TeacherCenterGridRosterForTerm(ctx, args); err == nil {
+				columns, err = qx.TeacherCenterGridColumnsForTerm(ctx, args)
+			}
+			if err != nil {
+				logger.LogErrorContext(ctx, "homework: grid export axes", err, "center_id", centerID, "term_id", termID)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+				return
+			}
+		}
+
+		filename := fmt.Sprintf("gradebook-center-%d-term-%d.%s", centerID, termID, format)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("Cache-Control", "no-store")
+		var sheet gradebookSheet
+		if format == "xlsx" {
+			w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+			xw, err := xlsx.NewWriter(w, "Кондуит", 2, 2)
+			if err != nil {
+				logger.LogErrorContext(ctx, "homework: grid export xlsx", err)
+				return
+			}
+			sheet = xlsxSheet{xw}
+		} else {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			// A BOM so spreadsheet apps pick UTF-8 for the Cyrillic names.
+			_, _ = w.Write([]byte("\ufeff"))
+			sheet = csvSheet{csv.NewWriter(w)}
+		}
+
+		// From here on the status line is sent: a failure can only cut the
+		// download short, so it is logged and the body left truncated.
+		export := newGradebookExport(sheet, roster, seriesFromColumns(columns))
+		if err := export.run(ctx, qx, args); err != nil {
+			logger.LogErrorContext(ctx, "homework: grid export stream", err, "center_id", centerID, "term_id", termID)
+			return
+		}
+		_ = tx.Commit(ctx)
+	}
+}
+
+func seriesFromColumns(columns []store.TeacherCenterGridColumnRow) []centerGridSeries {
+	b := newSeriesBuilder()
+	for _, c := range columns {
+		b.add(c)
+	}
+	return b.build()
+}
+
+// gradebookSheet is the row sink shared by the CSV and XLSX exports. Values
+// are strings or ints.
+type gradebookSheet interface {
+	header(values ...any) error
+	row(values ...any) error
+	close() error
+}
+
+type csvSheet struct{ w *csv.Writer }
+
+func (s csvSheet) header(values ...any) error { return s.row(values...) }
+
+func (s csvSheet) row(values ...any) error {
+	rec := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case string:
+			rec[i] = csvSafe(v)
+		case int:
+			rec[i] = strconv.Itoa(v)
+		default:
+			return fmt.Errorf("csv: unsupported cell type %T", v)
+		}
+	}
+	return s.w.Write(rec)
+}
+
+// csvSafe keeps spreadsheet apps from evaluating a cell as a formula (an
+// accepted "+ ИП …" cell, or a hostile name) by prefixing an apostrophe,
+// which Excel and LibreOffice hide.
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+func (s csvSheet) close() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+type xlsxSheet struct{ w *xlsx.Writer }
+
+func (s xlsxSheet) header(values ...any) error { return s.w.WriteHeader(values...) }
+func (s xlsxSheet) row(values ...any) error    { return s.w.WriteRow(values...) }
+func (s xlsxSheet) close() error               { return s.w.Close() }
+
+// gradebookExport merges the ordered roster with the cell stream, which
+// comes in the same order, holding only the current student's row.
+type gradebookExport struct {
+	sheet  gradebookSheet
+	roster []store.TeacherCenterGridRosterRow
+	series []centerGridSeries
+	// colIdx maps a subproblem to its position among all columns; seriesOf
+	// maps that position back to its series.
+	colIdx   map[int64]int
+	seriesOf []int
+	// next is the first roster entry not yet written; cur is the one whose
+	// cells are being collected (-1 when none).
+	next, cur int
+	cells     []string
+	acceptedN []int
+	// accepted counts per column, for the footer.
+	colAccepted []int
+}
+
+func newGradebookExport(sheet gradebookSheet, roster []store.TeacherCenterGridRosterRow, series []centerGridSeries) *gradebookExport {
+	e := &gradebookExport{
+		sheet:  sheet,
+		roster: roster,
+		series: series,
+		colIdx: map[int64]int{},
+		cur:    -1,
+	}
+	for si, s := range series {
+		for _, c := range s.Columns {
+			e.colIdx[c.SubproblemID] = len(e.seriesOf)
+			e.seriesOf = append(e.seriesOf, si)
+		}
+	}
+	e.cells = make([]string, len(e.seriesOf))
+	e.acceptedN = make([]int, len(series))
+	e.colAccepted = make([]int, len(e.seriesOf))
+	return e
+}
+
+func (e *gradebookExport) run(ctx context.Context, q *store.Queries, args store.CenterGridTermParams) error {
+	if err := e.writeHeaders(); err != nil {
+		return err
+	}
+	if args.TermID != 0 {
+		if err := q.ForEachGradebookCell(ctx, args, e.addCell); err != nil {
+			return err
+		}
+	}
+	if err := e.flushThrough(len(e.roster)); err != nil {
+		return err
+	}
+	if err := e.writeFooter(); err != nil {
+		return err
+	}
+	return e.sheet.close()
+}
+
+func (e *gradebookExport) writeHeaders() error {
+	top := []any{"Группа", "Ученик"}
+	labels := []any{"", ""}
+	for _, s := range e.series {
+		for i, c := range s.Columns {
+			if i == 0 {
+				top = append(top, s.DisplayName)
+			} else {
+				top = append(top, "")
+			}
+			labels = append(labels, c.ColumnLabel)
+		}
+		top = append(top, "")
+		labels = append(labels, "Σ")
+	}
+	if err := e.sheet.header(top...); err != nil {
+		return err
+	}
+	return e.sheet.header(labels...)
+}
+
+func (e *gradebookExport) addCell(c store.GradebookCellRow) error {
+	col, ok := e.colIdx[c.SubproblemID]
+	if !ok {
+		return nil
+	}
+	if e.cur < 0 || !e.matches(e.cur, c) {
+		j := e.next
+		for j < len(e.roster) && !e.matches(j, c) {
+			j++
+		}
+		if j == len(e.roster) {
+			// Not on the roster (should not happen: both sides share joins
+			// and order); skip rather than emitting the rest as empty.
+			return nil
+		}
+		if err := e.flushThrough(j); err != nil {
+			return err
+		}
+		e.cur = j
+		e.next = j + 1
+	}
+	letter := gradebookStatusLetters[c.CurrentStatus]
+	if c.CurrentStatus == homework.StatusAccepted {
+		e.acceptedN[e.seriesOf[col]]++
+		e.colAccepted[col]++
+		grader := c.LastGraderName
+		if c.LastGraderUserID != nil {
+			grader = initials(c.GraderFirstName, c.GraderLastName)
+		}
+		if grader != "" {
+			letter += " " + grader
+		}
+		if c.AcceptedAt != nil {
+			letter += " " + c.AcceptedAt.UTC().Format(gradebookTimeLayout)
+		}
+	}
+	e.cells[col] = letter
+	return nil
+}
+
+func (e *gradebookExport) matches(i int, c store.GradebookCellRow) bool {
+	return e.roster[i].GroupID == c.GroupID && e.roster[i].StudentUserID == c.StudentUserID
+}
+
+// flushThrough writes the collected row, then every roster entry before
+// index end as an empty row.
+func (e *gradebookExport) flushThrough(end int) error {
+	if e.cur >= 0 {
+		if err := e.writeStudent(e.roster[e.cur]); err != nil {
+			return err
+		}
+		e.cur = -1
+	}
+	for ; e.next < end; e.next++ {
+		if err := e.writeStudent(e.roster[e.next]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *gradebookExport) writeStudent(s store.TeacherCenterGridRosterRow) error {
+	values := []any{s.GroupName, mc.StudentDisplayName(s.StudentFirstName, s.StudentLastName)}
+	col := 0
+	for si, series := range e.series {
+		for range series.Columns {
+			values = append(values, e.cells[col])
+			e.cells[col] = ""
+			col++
+		}
+		values = append(values, e.acceptedN[si])
+		e.acceptedN[si] = 0
+	}
+	return e.sheet.row(values...)
+}
+
+func (e *gradebookExport) writeFooter() error {
+	values := []any{"", "Принято"}
+	col := 0
+	for _, series := range e.series {
+		total := 0
+		for range series.Columns {
+			values = append(values, e.colAccepted[col])
+			total += e.colAccepted[col]
+			col++
+		}
+		values = append(values, total)
+	}
+	return e.sheet.row(values...)
+}
@@ -0,0 +1,166 @@
+package homework_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var gradebookCellColumns = []string{
+	"group_id", "student_user_id", "subproblem_id", "current_status", "last_grader_user_id", "last_grader_name",
+	"grader_first_name", "grader_last_name", "accepted_at",
+}
+
+// expectGradebook mocks one term: groups А (Аня, Борис) and Б (Вера), series
+// 1 with columns У and 1a, series 2 with column 1. Борис has no threads.
+func expectGradebook(mock pgxmock.PgxPoolIface) {
+	due := time.Now()
+	graderID := int64(3)
+	grFirst, grLast := "Пётр", "Сидоров"
+	acceptedAt := time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)
+
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	mock.ExpectQuery(`FROM math_center_groups g`).
+		WithArgs(int64(42), int64(5)).
+		WillReturnRows(mock.NewRows(centerGridRosterColumns).
+			AddRow(int64(10), "А", int64(7), "Аня", "Иванова", false).
+			AddRow(int64(10), "А", int64(8), "Борис", "Петров", false).
+			AddRow(int64(11), "Б", int64(9), "Вера", "Смирнова", false))
+	mock.ExpectQuery(`FROM math_center_series s`).
+		WithArgs(int64(42), int64(5)).
+		WillReturnRows(mock.NewRows(centerGridColumnColumns).
+			AddRow(int64(100), int32(1), "Алгебра", due, int64(900), "", int64(500), int32(0), false, (*time.Time)(nil)).
+			AddRow(int64(100), int32(1), "Алгебра", due, int64(901), "a", int64(501), int32(1), false, (*time.Time)(nil)).
+			AddRow(int64(200), int32(2), "Геометрия", due, int64(910), "", int64(600), int32(1), false, (*time.Time)(nil)))
+	mock.ExpectQuery(`FROM homework_thread t`).
+		WithArgs(int64(42), int64(5)).
+		WillReturnRows(mock.NewRows(gradebookCellColumns).
+			AddRow(int64(10), int64(7), int64(900), "accepted", &graderID, "", &grFirst, &grLast, &acceptedAt).
+			AddRow(int64(10), int64(7), int64(901), "rejected", &graderID, "", &grFirst, &grLast, (*time.Time)(nil)).
+			AddRow(int64(11), int64(9), int64(910), "accepted", (*int64)(nil), "АА", (*string)(nil), (*string)(nil), &acceptedAt).
+			AddRow(int64(11), int64(9), int64(901), "submitted", (*int64)(nil), "", (*string)(nil), (*string)(nil), (*time.Time)(nil)))
+	mock.ExpectCommit()
+}
+
+func TestExportCenterGrid_CSV(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+	expectGradebook(mock)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/grid/export?format=csv&term=5", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("content-type = %q", ct)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(rr.Body.String(), "\ufeff"))).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	want := [][]string{
+		{"Группа", "Ученик", "Серия 1. Алгебра", "", "", "Серия 2. Геометрия", ""},
+		{"", "", "У", "1a", "Σ", "1", "Σ"},
+		{"А", records[2][1], "'+ ПС 2026-03-01T10:15Z", "'-", "1", "", "0"},
+		{"А", records[3][1], "", "", "0", "", "0"},
+		{"Б", records[4][1], "", "?", "0", "'+ АА 2026-03-01T10:15Z", "1"},
+		{"", "Принято", "1", "0", "1", "1", "1"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d rows, want %d: %q", len(records), len(want), records)
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, records[i], want[i])
+		}
+	}
+	if !strings.Contains(records[3][1], "Борис") {
+		t.Errorf("row 3 student = %q, want the student without threads", records[3][1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+func TestExportCenterGrid_XLSX(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+	expectGradebook(mock)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/grid/export?format=xlsx&term=5", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatalf("open xlsx: %v", err)
+	}
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open sheet: %v", err)
+		}
+		b, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		sheet = string(b)
+	}
+	// No formula guard in XLSX: inline strings are never evaluated.
+	for _, s := range []string{"+ ПС 2026-03-01T10:15Z", "+ АА 2026-03-01T10:15Z", "Принято", "Серия 2. Геометрия"} {
+		if !strings.Contains(sheet, s) {
+			t.Errorf("sheet missing %q", s)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+func TestExportCenterGrid_BadFormat(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/grid/export?format=pdf", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400", rr.Code)
+	}
+}
+
+func TestExportCenterGrid_OtherTermForbidden(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+	expectTermGraderAccess(mock, 3, 42, 4)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/grid/export?format=csv&term=5", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
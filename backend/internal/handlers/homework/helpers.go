@@ -2,6 +2,7 @@ package homework
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/Alarion239/my239/backend/internal/ctxcache"
 	"github.com/Alarion239/my239/backend/internal/httpx"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
 )
 
@@ -39,32 +41,67 @@ func callerIsAdmin(r *http.Request) bool {
 	return v
 }
 
-// requireTeacher enforces "caller teaches this center"; returns false and
-// emits an error envelope if not. Used by every grader-facing handler.
-//
-// Admin is a true superset of teacher: an admin grades/retracts/manages ANY
-// center without being enrolled in it. callerIsAdmin reads the EFFECTIVE
-// is_admin from context, so when an admin impersonates a non-admin (see
-// middleware.ImpersonationMiddleware) the bypass correctly does not apply.
-func requireTeacher(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID int64) bool {
+// requireHeadTeacher enforces "caller is a head teacher of this center" (or
+// an admin); returns false and emits an error envelope if not. Used for the
+// center-wide oversight views such as calibration disagreements.
+func requireHeadTeacher(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID int64) bool {
 	if callerIsAdmin(r) {
 		return true
 	}
-	isTeacher, err := q.IsTeacherInCenter(ctx, store.IsTeacherInCenterParams{
+	isHead, err := q.IsHeadTeacherInCenter(ctx, store.IsHeadTeacherInCenterParams{
 		UserID: userID, MathCenterID: centerID,
 	})
 	if err != nil {
-		logger.LogErrorContext(ctx, "homework: teacher check", err)
+		logger.LogErrorContext(ctx, "homework: head teacher check", err)
 		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 		return false
 	}
-	if !isTeacher {
-		httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "not a teacher of this center")
+	if !isHead {
+		httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "not a head teacher of this center")
 		return false
 	}
 	return true
 }
 
+// requireTeacherCan enforces "caller teaches this center" with a role that
+// allows can: reads need mc.CanView, a grading action mc.CanGrade, the
+// grading setup mc.CanManage.
+func requireTeacherCan(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID int64, can mc.TeacherCapability) bool {
+	_, ok := teacherAccess(ctx, w, r, q, userID, centerID, 0, can)
+	return ok
+}
+
+// requireSeriesTeacherCan is requireTeacherCan for an action on one series;
+// a term-scoped assistant is also kept to the series of their term.
+func requireSeriesTeacherCan(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID, seriesID int64, can mc.TeacherCapability) bool {
+	_, ok := teacherAccess(ctx, w, r, q, userID, centerID, seriesID, can)
+	return ok
+}
+
+// teacherAccess runs mc.AuthorizeTeacher for the caller, with seriesID 0
+// for center-wide actions. Every grader-facing handler goes through it.
+//
+// Admin is a true superset of teacher: an admin passes as a head of ANY
+// center without being enrolled in it. callerIsAdmin reads the EFFECTIVE
+// is_admin from context, so when an admin impersonates a non-admin (see
+// middleware.ImpersonationMiddleware) the bypass correctly does not apply.
+func teacherAccess(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID, seriesID int64, can mc.TeacherCapability) (store.TeacherAccess, bool) {
+	if callerIsAdmin(r) {
+		return store.TeacherAccess{IsHeadTeacher: true, InScope: true}, true
+	}
+	access, err := mc.AuthorizeTeacher(ctx, q, userID, centerID, seriesID, can)
+	switch {
+	case err == nil:
+		return access, true
+	case errors.Is(err, mc.ErrNotTeacher), errors.Is(err, mc.ErrTeacherRoleForbids), errors.Is(err, mc.ErrTeacherOutsideTerm):
+		httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, err.Error())
+	default:
+		logger.LogErrorContext(ctx, "homework: teacher access", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+	}
+	return store.TeacherAccess{}, false
+}
+
 // requireStudent enforces "caller is a student of this center"; returns
 // false and emits an error envelope if not.
 func requireStudent(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID int64) bool {
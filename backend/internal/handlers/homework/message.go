@@ -0,0 +1,151 @@
+package homework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/hwchain"
+	"github.com/Alarion239/my239/backend/internal/live"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/objectstore"
+)
+
+// PostMessage — the thread's student, a teacher of the center whose role can
+// grade the series, or an admin. Appends a 'message' event (text and/or photos) for clarifications in either
+// direction. The thread cache is left untouched: no status change, no claim
+// or attempt bookkeeping, so messages never count as attempts anywhere.
+// Photos are uploaded through the usual upload-urls flow for the caller's
+// side (student: /threads/{subproblemID}/upload-urls; grader: by-id).
+func PostMessage(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		threadID, err := pathInt64(r, "threadID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid thread id")
+			return
+		}
+
+		var req submitRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		body, vErr := validateSubmitInput(req)
+		if vErr != "" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
+			return
+		}
+		if body == "" && len(req.ObjectKeys) == 0 {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "message needs text or a photo")
+			return
+		}
+
+		q := store.New(database.Pool())
+		thread, err := q.GetThread(ctx, threadID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "thread not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "homework: get thread for message", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		allowed, err := canViewThread(ctx, r, q, userID, thread)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: thread auth", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if !allowed {
+			httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "no access to this thread")
+			return
+		}
+		// Reading a thread is not enough to write into it: observers and
+		// assistants of another term may look but not reply.
+		if userID != thread.StudentUserID && !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanGrade) {
+			return
+		}
+
+		photos, vErr := validateAndStatPhotos(ctx, blobs, thread.ID, req.EventUUID, req.ObjectKeys)
+		if vErr != "" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
+			return
+		}
+		// Only the student's photos count against their quota.
+		if userID == thread.StudentUserID && !requireStorageFor(ctx, w, r, q, userID, thread.MathCenterID, photos) {
+			return
+		}
+
+		if err := writeMessage(ctx, database, thread, req.EventUUID, userID, body, photos); err != nil {
+			logger.LogErrorContext(ctx, "homework: message tx", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save message")
+			return
+		}
+		live.Publish(ctx, database.Pool(), live.Event{
+			CenterID:      thread.MathCenterID,
+			Kind:          live.KindMessages,
+			SeriesID:      thread.SeriesID,
+			StudentUserID: thread.StudentUserID,
+			ThreadID:      thread.ID,
+		})
+		writeThreadView(ctx, w, r, database, blobs, thread.ID)
+	}
+}
+
+// writeMessage commits a message event and its photos in one transaction.
+// CanTransition is still consulted so a future status rule for messages has
+// one place to live.
+func writeMessage(ctx context.Context, database *db.DB, thread store.HomeworkThread, eventUUID string, actorUserID int64, body string, photos []validatedPhoto) error {
+	if err := homework.CanTransition(thread.CurrentStatus, homework.KindMessage); err != nil {
+		return err
+	}
+	tx, err := database.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qx := store.New(tx)
+	prev, err := qx.LockEventChain(ctx, thread.ID)
+	if err != nil {
+		return fmt.Errorf("lock event chain: %w", err)
+	}
+	event, err := qx.AppendEvent(ctx, store.AppendEventParams{
+		ThreadID:    thread.ID,
+		EventUuid:   eventUUID,
+		Kind:        homework.KindMessage,
+		ActorUserID: actorUserID,
+		Body:        body,
+	})
+	if err != nil {
+		return fmt.Errorf("append event: %w", err)
+	}
+	if err := hwchain.Seal(ctx, qx, prev, event); err != nil {
+		return err
+	}
+	for _, p := range photos {
+		if err := qx.InsertEventPhoto(ctx, store.InsertEventPhotoParams{
+			EventID:     event.ID,
+			Idx:         int32(p.Idx),
+			ObjectKey:   p.ObjectKey,
+			SizeBytes:   p.Size,
+			ContentType: p.ContentType,
+		}); err != nil {
+			return fmt.Errorf("insert photo %d: %w", p.Idx, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
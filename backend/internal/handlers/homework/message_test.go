@@ -0,0 +1,210 @@
+package homework_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+)
+
+func TestPostMessage_StudentWithPhotoKeepsStatus(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	now := time.Now()
+	attemptID := int64(50)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "submitted", AttemptEventID: &attemptID,
+		}, now)...))
+
+	eventUUID := "msg1"
+	key0 := "homework/thread/1/" + eventUUID + "/0.jpg"
+	_ = blobs.Put(context.Background(), key0, strings.NewReader("img-body"), 8, "image/jpeg")
+	expectStorageQuota(mock, 7, 42, nil, 0)
+
+	// Tx: AppendEvent('message') → InsertEventPhoto → Commit. No thread UPDATE.
+	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
+	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
+		WithArgs(int64(1), eventUUID, "message", int64(7), "is n even?", (*string)(nil), (*int64)(nil)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(60), int64(1), eventUUID, "message", int64(7), "is n even?", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(60), int64(1))
+	mock.ExpectExec(`INSERT INTO homework_thread_event_photo`).
+		WithArgs(int64(60), int32(0), key0, int64(8), "image/jpeg").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "submitted", AttemptEventID: &attemptID,
+		}, now)...))
+	expectGetSeriesForView(mock, 100, 42, now)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(eventColumns))
+	expectGetUsersForView(mock)
+
+	body, _ := json.Marshal(map[string]any{
+		"event_uuid":  eventUUID,
+		"body":        "is n even?",
+		"object_keys": []string{key0},
+	})
+	req := authedRequest(t, access, 7, false, http.MethodPost, "/threads/by-id/1/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostMessage_StudentPhotosPastQuotaRejected(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	key0 := "homework/thread/1/msg6/0.jpg"
+	_ = blobs.Put(context.Background(), key0, strings.NewReader("img-body"), 8, "image/jpeg")
+	quota := homework.MinStudentQuotaBytes
+	expectStorageQuota(mock, 7, 42, &quota, quota-4)
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "msg6", "object_keys": []string{key0}})
+	req := authedRequest(t, access, 7, false, http.MethodPost, "/threads/by-id/1/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "quota_exceeded") {
+		t.Fatalf("got %d, want 409 quota_exceeded; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostMessage_TeacherReply(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "rejected",
+		}, now)...))
+	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
+	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
+		WithArgs(int64(1), "msg2", "message", int64(3), "yes, n is even", (*string)(nil), (*int64)(nil)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(61), int64(1), "msg2", "message", int64(3), "yes, n is even", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(61), int64(1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "rejected",
+		}, now)...))
+	expectGetSeriesForView(mock, 100, 42, now)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(eventColumns))
+	expectGetUsersForView(mock)
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "msg2", "body": "yes, n is even"})
+	req := authedRequest(t, access, 3, false, http.MethodPost, "/threads/by-id/1/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPostMessage_ForeignUserForbidden(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	expectTeacherCheck(mock, 8, 42, false)
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "msg3", "body": "hi"})
+	req := authedRequest(t, access, 8, false, http.MethodPost, "/threads/by-id/1/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPostMessage_ObserverForbidden(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "observer")
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "msg5", "body": "hi"})
+	req := authedRequest(t, access, 3, false, http.MethodPost, "/threads/by-id/1/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostMessage_EmptyRejected(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "msg4", "body": "   "})
+	req := authedRequest(t, access, 7, false, http.MethodPost, "/threads/by-id/1/messages", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,45 @@
+package homework
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// MyProgress — student of the center. The whole-history counterpart of
+// MySeriesRollup: accepted / rejected / pending per series and term across
+// every academic year and camp the student took part in, coffin solves, the
+// average wait from attempt to verdict and the series streak. Unpublished
+// series are left out, as everywhere on the student side. Teachers see the
+// same payload on the student's profile (mathcenter.GetStudentProfile).
+func MyProgress(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireStudent(ctx, w, r, q, userID, centerID) {
+			return
+		}
+		progress, err := mc.LoadStudentProgress(ctx, q, centerID, userID, time.Now())
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: student progress", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, progress)
+	}
+}
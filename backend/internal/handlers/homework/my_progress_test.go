@@ -0,0 +1,113 @@
+package homework_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var progressSeriesColumns = []string{
+	"id", "number", "name", "due_at", "term_id", "kind", "grade", "is_active",
+	"subproblems", "accepted", "rejected", "pending", "coffins", "coffins_solved", "coffins_open",
+}
+
+func TestMyProgress_GroupsSeriesByTerm(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	g8, g9 := int32(8), int32(9)
+	expectStudentCheck(mock, 7, 42, true)
+	mock.ExpectQuery(`FROM math_center_series se\s+JOIN math_center_terms`).
+		WithArgs(int64(42), int64(7), pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows(progressSeriesColumns).
+			AddRow(int64(10), int32(1), "Алгебра", now.Add(-60*24*time.Hour), int64(1), "academic", &g8, false,
+				int64(3), int64(2), int64(1), int64(0), int64(1), int64(1), int64(0)).
+			AddRow(int64(20), int32(1), "Графы", now.Add(-7*24*time.Hour), int64(2), "academic", &g9, true,
+				int64(4), int64(1), int64(0), int64(3), int64(2), int64(0), int64(2)).
+			AddRow(int64(21), int32(2), "Инварианты", now.Add(7*24*time.Hour), int64(2), "academic", &g9, true,
+				int64(2), int64(0), int64(0), int64(2), int64(0), int64(0), int64(0)))
+	mock.ExpectQuery(`JOIN homework_thread_event g`).
+		WithArgs(int64(42), int64(7)).
+		WillReturnRows(mock.NewRows([]string{"series_id", "graded", "total_seconds"}).
+			AddRow(int64(10), int64(3), float64(3*3600)).
+			AddRow(int64(20), int64(1), float64(5*3600)))
+
+	req := authedRequest(t, access, 7, false, http.MethodGet, "/centers/42/my/progress", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Counts struct {
+			Accepted int64 `json:"accepted"`
+			Pending  int64 `json:"pending"`
+		} `json:"counts"`
+		Coffins struct {
+			Total  int64 `json:"total"`
+			Solved int64 `json:"solved"`
+			Open   int64 `json:"open"`
+		} `json:"coffins"`
+		Turnaround struct {
+			Graded     int64    `json:"graded"`
+			AvgSeconds *float64 `json:"avg_seconds"`
+		} `json:"turnaround"`
+		Streak struct {
+			Current int `json:"current"`
+			Longest int `json:"longest"`
+		} `json:"streak"`
+		Terms []struct {
+			DisplayName string `json:"display_name"`
+			Series      []struct {
+				DisplayName string `json:"display_name"`
+			} `json:"series"`
+		} `json:"terms"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Counts.Accepted != 3 || resp.Counts.Pending != 5 {
+		t.Errorf("counts = %+v", resp.Counts)
+	}
+	if resp.Coffins.Total != 3 || resp.Coffins.Solved != 1 || resp.Coffins.Open != 2 {
+		t.Errorf("coffins = %+v", resp.Coffins)
+	}
+	if resp.Turnaround.Graded != 4 || resp.Turnaround.AvgSeconds == nil || *resp.Turnaround.AvgSeconds != 2*3600 {
+		t.Errorf("turnaround = %+v", resp.Turnaround)
+	}
+	// The open series 21 has nothing accepted yet and does not break the run.
+	if resp.Streak.Current != 2 || resp.Streak.Longest != 2 {
+		t.Errorf("streak = %+v", resp.Streak)
+	}
+	if len(resp.Terms) != 2 || resp.Terms[0].DisplayName != "8 класс" || len(resp.Terms[1].Series) != 2 {
+		t.Fatalf("terms = %+v", resp.Terms)
+	}
+	if resp.Terms[1].Series[1].DisplayName != "Серия 2. Инварианты" {
+		t.Errorf("series display = %q", resp.Terms[1].Series[1].DisplayName)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMyProgress_RejectsNonStudent(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectStudentCheck(mock, 7, 42, false)
+	req := authedRequest(t, access, 7, false, http.MethodGet, "/centers/42/my/progress", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+}
@@ -10,8 +10,10 @@ import (
 
 	"github.com/Alarion239/my239/backend/internal/homework"
 	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/hwchain"
 	"github.com/Alarion239/my239/backend/internal/live"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 	"github.com/Alarion239/my239/backend/pkg/objectstore"
@@ -59,7 +61,7 @@ func OfflineAccept(database *db.DB, hub *live.Hub, blobs objectstore.Store) http
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, spCtx.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, spCtx.MathCenterID, spCtx.SeriesID, mc.CanGrade) {
 			return
 		}
 
@@ -159,7 +161,7 @@ func OfflineUndo(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.H
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, thread.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanGrade) {
 			return
 		}
 		if err := homework.CanTransition(thread.CurrentStatus, homework.KindOfflineRetracted); err != nil {
@@ -292,6 +294,10 @@ func writeOfflineAccept(ctx context.Context, database *db.DB, thread store.Homew
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	qx := store.New(tx)
+	prev, err := qx.LockEventChain(ctx, thread.ID)
+	if err != nil {
+		return fmt.Errorf("lock event chain: %w", err)
+	}
 	verdict := homework.VerdictAccepted
 	event, err := qx.AppendOfflineEvent(ctx, store.AppendOfflineEventParams{
 		ThreadID:             thread.ID,
@@ -307,6 +313,9 @@ func writeOfflineAccept(ctx context.Context, database *db.DB, thread store.Homew
 	if err != nil {
 		return fmt.Errorf("append accepted_offline event: %w", err)
 	}
+	if err := hwchain.Seal(ctx, qx, prev, event); err != nil {
+		return err
+	}
 	if err := qx.UpdateThreadAfterOfflineAccept(ctx, store.UpdateThreadAfterOfflineAcceptParams{
 		GradeEventID: event.ID,
 		GraderUserID: creditedUserID,
@@ -328,7 +337,11 @@ func writeOfflineUndo(ctx context.Context, database *db.DB, threadID int64, even
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	qx := store.New(tx)
-	if _, err := qx.AppendOfflineEvent(ctx, store.AppendOfflineEventParams{
+	prev, err := qx.LockEventChain(ctx, threadID)
+	if err != nil {
+		return fmt.Errorf("lock event chain: %w", err)
+	}
+	event, err := qx.AppendOfflineEvent(ctx, store.AppendOfflineEventParams{
 		ThreadID:             threadID,
 		EventUuid:            eventUUID,
 		Kind:                 homework.KindOfflineRetracted,
@@ -338,9 +351,13 @@ func writeOfflineUndo(ctx context.Context, database *db.DB, threadID int64, even
 		RefersToEventID:      &gradeEventID,
 		CreditedGraderUserID: nil,
 		CreditedGraderName:   "",
-	}); err != nil {
+	})
+	if err != nil {
 		return fmt.Errorf("append offline_retracted event: %w", err)
 	}
+	if err := hwchain.Seal(ctx, qx, prev, event); err != nil {
+		return err
+	}
 	if err := qx.UpdateThreadAfterOfflineUndo(ctx, store.UpdateThreadAfterOfflineUndoParams{
 		RollbackStatus: rollback,
 		ID:             threadID,
@@ -42,10 +42,12 @@ func expectOfflineAcceptTx(mock pgxmock.PgxPoolIface, threadID, studentID, subID
 
 	verdict := "accepted"
 	mock.ExpectBegin()
+	expectChainLock(mock, threadID)
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(threadID, pgxmock.AnyArg(), "accepted_offline", actorID, "", &verdict, (*int64)(nil), creditedID, creditedName).
 		WillReturnRows(mock.NewRows(eventColumns).AddRow(
 			int64(80), threadID, "uuid", "accepted_offline", actorID, "", &verdict, (*int64)(nil), now, true, creditedID, creditedName, (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(80), threadID)
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= 'accepted'`).
 		WithArgs(int64(80), creditedID, creditedName, threadID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
@@ -74,7 +76,7 @@ func TestOfflineAccept_PhoneFlowCreditsTeacher(t *testing.T) {
 
 	now := time.Now()
 	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "", now.Add(time.Hour), &now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// No grader fields → credit the session user (id 3).
 	expectGetUserByID(mock, 3, "Мария", "Кузнецова", now)
 	creditedID := int64(3)
@@ -98,7 +100,7 @@ func TestOfflineAccept_ConduitResolvedTeacher(t *testing.T) {
 
 	now := time.Now()
 	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "", now.Add(time.Hour), &now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// grader_user_id=9 must be validated as a teacher of the center, then named.
 	expectTeacherCheck(mock, 9, 42, true)
 	expectGetUserByID(mock, 9, "Пётр", "Сидоров", now)
@@ -123,7 +125,7 @@ func TestOfflineAccept_ConduitFreeTextGrader(t *testing.T) {
 
 	now := time.Now()
 	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "", now.Add(time.Hour), &now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// Free-text grader → unregistered, last_grader_user_id stays NULL.
 	expectOfflineAcceptTx(mock, 1, 7, 900, 100, 42, 3, nil, "Иванов", now)
 
@@ -145,7 +147,7 @@ func TestOfflineAccept_GraderNotTeacher(t *testing.T) {
 
 	now := time.Now()
 	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "", now.Add(time.Hour), &now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	expectTeacherCheck(mock, 9, 42, false) // credited grader is NOT a teacher
 
 	body, _ := json.Marshal(map[string]any{"student_user_id": 7, "subproblem_id": 900, "grader_user_id": 9})
@@ -166,7 +168,7 @@ func TestOfflineAccept_AlreadyAccepted(t *testing.T) {
 
 	now := time.Now()
 	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "", now.Add(time.Hour), &now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	expectGetUserByID(mock, 3, "Мария", "Кузнецова", now)
 	// Find-or-create returns a thread accepted via an ONLINE grade → 409.
 	gradeID := int64(80)
@@ -198,7 +200,7 @@ func TestOfflineAccept_RecreditsOfflineAccept(t *testing.T) {
 
 	now := time.Now()
 	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "", now.Add(time.Hour), &now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	gradeID := int64(80)
 	// Find-or-create returns a thread already accepted OFFLINE (credited "АБ").
 	mock.ExpectQuery(`INSERT INTO homework_thread \(`).
@@ -216,10 +218,12 @@ func TestOfflineAccept_RecreditsOfflineAccept(t *testing.T) {
 
 	// Re-credit tx: new accepted_offline event + cache repoint to "МК".
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), pgxmock.AnyArg(), "accepted_offline", int64(3), "", &verdict, (*int64)(nil), (*int64)(nil), "МК").
 		WillReturnRows(mock.NewRows(eventColumns).AddRow(
 			int64(81), int64(1), "uuid2", "accepted_offline", int64(3), "", &verdict, (*int64)(nil), now, true, (*int64)(nil), "МК", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(81), int64(1))
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= 'accepted'`).
 		WithArgs(int64(81), (*int64)(nil), "МК", int64(1)).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
@@ -256,7 +260,7 @@ func TestOfflineAccept_NonTeacherForbidden(t *testing.T) {
 
 	now := time.Now()
 	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "", now.Add(time.Hour), &now)
-	expectTeacherCheck(mock, 3, 42, false)
+	expectTeacherAccess(mock, 3, 42, "")
 
 	body, _ := json.Marshal(map[string]any{"student_user_id": 7, "subproblem_id": 900})
 	req := authedRequest(t, access, 3, false, http.MethodPost, "/offline/accept", bytes.NewReader(body))
@@ -283,7 +287,7 @@ func TestOfflineUndo_RevertsToUngraded(t *testing.T) {
 			threadRow(1, 7, 900, 100, 42, threadRowOpts{
 				Status: "accepted", GradeEventID: &gradeID, LastGraderName: "Иванов",
 			}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// GetEvent(grade) → is_offline = true.
 	verdict := "accepted"
 	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE id`).
@@ -292,10 +296,12 @@ func TestOfflineUndo_RevertsToUngraded(t *testing.T) {
 			gradeID, int64(1), "uuid", "accepted_offline", int64(3), "", &verdict, (*int64)(nil), now, true, (*int64)(nil), "Иванов", (*int64)(nil), "", ""))
 
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), pgxmock.AnyArg(), "offline_retracted", int64(3), "", (*string)(nil), &gradeID, (*int64)(nil), "").
 		WillReturnRows(mock.NewRows(eventColumns).AddRow(
 			int64(81), int64(1), "uuid2", "offline_retracted", int64(3), "", (*string)(nil), &gradeID, now, true, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(81), int64(1))
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= \$1`).
 		WithArgs("ungraded", int64(1)).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
@@ -334,7 +340,7 @@ func TestOfflineUndo_RejectsOnlineGrade(t *testing.T) {
 		WithArgs(int64(7), int64(900)).
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(
 			threadRow(1, 7, 900, 100, 42, threadRowOpts{Status: "accepted", GradeEventID: &gradeID}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// The current grade is an ONLINE graded event (is_offline=false) → 409.
 	verdict := "accepted"
 	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE id`).
@@ -358,7 +364,7 @@ func TestCenterTeachers_HappyPath(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	teacherCols := []string{"id", "user_id", "math_center_id", "is_head_teacher", "first_name", "middle_name", "last_name"}
 	mock.ExpectQuery(`FROM math_center_teachers t\s+JOIN users u`).
 		WithArgs(int64(42)).
@@ -394,7 +400,7 @@ func TestCenterTeachers_NonTeacherForbidden(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, false)
+	expectTeacherAccess(mock, 3, 42, "")
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/teachers", nil)
 	rr := httptest.NewRecorder()
 	r.ServeHTTP(rr, req)
@@ -0,0 +1,104 @@
+package homework
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/objectstore"
+)
+
+// GetEventPhotoOriginal — same audience as GetThread. 302-redirects to a
+// presigned GET of one photo at full resolution. The thread view only signs
+// previews and thumbnails; the full image is fetched when a viewer zooms in.
+// After the photo pipeline has run, "full resolution" is the sanitized JPEG
+// (metadata stripped, HEIC converted) — the raw upload no longer exists.
+func GetEventPhotoOriginal(database *db.DB, blobs objectstore.Store, downloadTTL time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		threadID, err := pathInt64(r, "threadID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid thread id")
+			return
+		}
+		eventID, err := pathInt64(r, "eventID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid event id")
+			return
+		}
+		idx, err := strconv.ParseInt(chi.URLParam(r, "idx"), 10, 32)
+		if err != nil || idx < 0 {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid photo index")
+			return
+		}
+
+		q := store.New(database.Pool())
+		thread, err := q.GetThread(ctx, threadID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "thread not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "homework: get thread for photo", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		allowed, err := canViewThread(ctx, r, q, userID, thread)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: thread auth", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if !allowed {
+			httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "no access to this thread")
+			return
+		}
+
+		// The event must belong to this thread, otherwise access to one
+		// thread would unlock every photo in the system by id.
+		event, err := q.GetEvent(ctx, eventID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			logger.LogErrorContext(ctx, "homework: get event for photo", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if err != nil || event.ThreadID != thread.ID {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "photo not found")
+			return
+		}
+		photo, err := q.GetEventPhotoRendition(ctx, eventID, int32(idx))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "photo not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "homework: get photo", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+
+		url, err := blobs.PresignGet(ctx, photo.ObjectKey, downloadTTL)
+		if err != nil {
+			if errors.Is(err, objectstore.ErrNotFound) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "photo missing in storage")
+				return
+			}
+			logger.LogErrorContext(ctx, "homework: presign photo", err)
+			httpx.WriteAPIError(w, r, http.StatusBadGateway, httpx.CodeUnavailable, "object storage unavailable")
+			return
+		}
+		http.Redirect(w, r, url, http.StatusFound)
+	}
+}
@@ -0,0 +1,117 @@
+package homework_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestGetThread_ProcessedPhotoServesRenditions(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	expectGetSeriesForView(mock, 100, 42, now)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(50), int64(1), "u", "submitted", int64(7), "", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectGetUsersForView(mock)
+	full, preview, thumb := "homework/thread/1/u/0.full.jpg", "homework/thread/1/u/0.preview.jpg", "homework/thread/1/u/0.thumb.jpg"
+	for _, k := range []string{full, preview, thumb} {
+		_ = blobs.Put(t.Context(), k, strings.NewReader("img"), 3, "image/jpeg")
+	}
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event_photo`).
+		WithArgs([]int64{50}).
+		WillReturnRows(mock.NewRows(photoColumns).
+			AddRow(int64(50), int32(0), full, int64(3), "image/jpeg", now, "done", &preview, &thumb))
+
+	req := authedRequest(t, access, 7, false, http.MethodGet, "/threads/by-id/1", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var v struct {
+		Events []struct {
+			Photos []struct {
+				URL      string `json:"url"`
+				ThumbURL string `json:"thumb_url"`
+			} `json:"photos"`
+		} `json:"events"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &v)
+	if len(v.Events) != 1 || len(v.Events[0].Photos) != 1 {
+		t.Fatalf("unexpected body: %s", rr.Body.String())
+	}
+	p := v.Events[0].Photos[0]
+	if !strings.HasSuffix(p.URL, preview) || !strings.HasSuffix(p.ThumbURL, thumb) {
+		t.Errorf("got url=%q thumb=%q; want preview and thumb renditions", p.URL, p.ThumbURL)
+	}
+}
+
+func TestGetEventPhotoOriginal_RedirectsToFullImage(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	now := time.Now()
+	full := "homework/thread/1/u/0.full.jpg"
+	_ = blobs.Put(t.Context(), full, strings.NewReader("img"), 3, "image/jpeg")
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE id`).
+		WithArgs(int64(50)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(50), int64(1), "u", "submitted", int64(7), "", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	mock.ExpectQuery(`FROM homework_thread_event_photo\s+WHERE event_id = \$1`).
+		WithArgs(int64(50), int32(0)).
+		WillReturnRows(mock.NewRows(photoColumns).
+			AddRow(int64(50), int32(0), full, int64(3), "image/jpeg", now, "done", (*string)(nil), (*string)(nil)))
+
+	req := authedRequest(t, access, 7, false, http.MethodGet, "/threads/by-id/1/photos/50/0", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusFound {
+		t.Fatalf("got %d, want 302; body=%s", rr.Code, rr.Body.String())
+	}
+	if loc := rr.Header().Get("Location"); !strings.HasSuffix(loc, full) {
+		t.Errorf("Location = %q, want presigned %s", loc, full)
+	}
+}
+
+func TestGetEventPhotoOriginal_ForeignEventNotFound(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	// Event 60 belongs to thread 2: owning thread 1 must not unlock it.
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE id`).
+		WithArgs(int64(60)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(60), int64(2), "v", "submitted", int64(8), "", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+
+	req := authedRequest(t, access, 7, false, http.MethodGet, "/threads/by-id/1/photos/60/0", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got %d, want 404; body=%s", rr.Code, rr.Body.String())
+	}
+}
@@ -18,6 +18,11 @@ import (
 // subproblem (e.g. 1а, 1б) is reported as its own line — they are never folded
 // into a single problem. The five buckets are mutually exclusive, so
 // accepted+appealed+rejected+submitted+unsolved == total_students for every row.
+//
+// FailedAttempts counts standing rejections, leaving out those whose reason
+// does not count as an attempt (an unreadable photo). The reason maps tally
+// standing rejections and all retractions by reason code; events given
+// without a code are not in them.
 type problemStat struct {
 	ProblemID       int64  `json:"problem_id"`
 	ProblemNumber   int    `json:"problem_number"`
@@ -29,6 +34,10 @@ type problemStat struct {
 	Rejected        int    `json:"rejected"`
 	Submitted       int    `json:"submitted"`
 	Unsolved        int    `json:"unsolved"`
+
+	FailedAttempts    int            `json:"failed_attempts"`
+	RejectionReasons  map[string]int `json:"rejection_reasons"`
+	RetractionReasons map[string]int `json:"retraction_reasons"`
 }
 
 // problemStatsResponse is the series-page teacher summary: the roster size plus
@@ -66,7 +75,7 @@ func ProblemStats(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, series.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanView) {
 			return
 		}
 
@@ -77,7 +86,15 @@ func ProblemStats(database *db.DB) http.HandlerFunc {
 			return
 		}
 
+		reasons, err := q.ListSeriesReasonCounts(ctx, seriesID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: series reason counts", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+
 		total, problems := aggregateProblemStats(rows)
+		addReasonCounts(problems, reasons)
 		httpx.WriteJSON(w, http.StatusOK, problemStatsResponse{
 			TotalStudents: total,
 			Problems:      problems,
@@ -113,6 +130,9 @@ func aggregateProblemStats(rows []store.SeriesProblemStatsRow) (totalStudents in
 				ProblemDisplay:  mc.ProblemDisplayName(int(row.ProblemNumber)),
 				SubproblemID:    row.SubproblemID,
 				SubproblemLabel: row.SubproblemLabel,
+
+				RejectionReasons:  map[string]int{},
+				RetractionReasons: map[string]int{},
 			}
 			bySub[row.SubproblemID] = s
 			order = append(order, row.SubproblemID)
@@ -147,3 +167,34 @@ func aggregateProblemStats(rows []store.SeriesProblemStatsRow) (totalStudents in
 	}
 	return len(students), problems
 }
+
+// addReasonCounts folds the per-(subproblem, kind, code) tallies into the
+// subproblem lines. Counts for a subproblem no longer in the series are
+// dropped.
+func addReasonCounts(problems []problemStat, counts []store.SeriesReasonCount) {
+	idx := make(map[int64]int, len(problems))
+	for i, p := range problems {
+		idx[p.SubproblemID] = i
+	}
+	for _, c := range counts {
+		i, ok := idx[c.SubproblemID]
+		if !ok {
+			continue
+		}
+		p := &problems[i]
+		n := int(c.Count)
+		switch c.Kind {
+		case hw.KindGraded:
+			if c.CountsAsAttempt {
+				p.FailedAttempts += n
+			}
+			if c.Code != "" {
+				p.RejectionReasons[c.Code] += n
+			}
+		case hw.KindRetracted:
+			if c.Code != "" {
+				p.RetractionReasons[c.Code] += n
+			}
+		}
+	}
+}
@@ -28,9 +28,26 @@ type problemStatsResp struct {
 		Rejected        int    `json:"rejected"`
 		Submitted       int    `json:"submitted"`
 		Unsolved        int    `json:"unsolved"`
+
+		FailedAttempts    int            `json:"failed_attempts"`
+		RejectionReasons  map[string]int `json:"rejection_reasons"`
+		RetractionReasons map[string]int `json:"retraction_reasons"`
 	} `json:"problems"`
 }
 
+var reasonCountColumns = []string{"subproblem_id", "kind", "code", "counts_as_attempt", "count"}
+
+// expectReasonCounts adds the per-reason tally ProblemStats reads after the
+// status rows; nil rows means no rejections or retractions yet.
+func expectReasonCounts(mock pgxmock.PgxPoolIface, seriesID int64, rows *pgxmock.Rows) {
+	if rows == nil {
+		rows = mock.NewRows(reasonCountColumns)
+	}
+	mock.ExpectQuery(`FROM homework_thread_event e\s+JOIN homework_thread t`).
+		WithArgs(seriesID).
+		WillReturnRows(rows)
+}
+
 func expectSeriesForStats(mock pgxmock.PgxPoolIface, seriesID, centerID int64, now time.Time) {
 	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
 		WithArgs(seriesID).
@@ -59,7 +76,7 @@ func TestProblemStats_PerSubproblemCounts(t *testing.T) {
 
 	now := time.Now()
 	expectSeriesForStats(mock, 100, 42, now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	// One problem (id 500, number 1) with two subproblems (a=900, b=901),
 	// five students. Counted per subproblem:
@@ -78,6 +95,12 @@ func TestProblemStats_PerSubproblemCounts(t *testing.T) {
 			AddRow(statRow(3, 500, 1, 901, "b", "accepted")...).
 			AddRow(statRow(4, 500, 1, 901, "b", "ungraded")...).
 			AddRow(statRow(5, 500, 1, 901, "b", "ungraded")...))
+	// Student 3's rejection on а was for an unreadable photo; the one on б
+	// and an earlier, since retracted rejection on а had no code.
+	expectReasonCounts(mock, 100, mock.NewRows(reasonCountColumns).
+		AddRow(int64(900), "graded", "illegible_photo", false, int64(1)).
+		AddRow(int64(900), "retracted", "wrong_answer", true, int64(1)).
+		AddRow(int64(901), "graded", "", true, int64(1)))
 
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/problem-stats", nil)
 	rr := httptest.NewRecorder()
@@ -113,6 +136,13 @@ func TestProblemStats_PerSubproblemCounts(t *testing.T) {
 		t.Errorf("b buckets = a%d ap%d r%d s%d u%d, want 2/0/1/0/2", b.Accepted, b.Appealed, b.Rejected, b.Submitted, b.Unsolved)
 	}
 
+	if a.FailedAttempts != 0 || a.RejectionReasons["illegible_photo"] != 1 || a.RetractionReasons["wrong_answer"] != 1 {
+		t.Errorf("a reasons = failed %d rej %v retr %v", a.FailedAttempts, a.RejectionReasons, a.RetractionReasons)
+	}
+	if b.FailedAttempts != 1 || len(b.RejectionReasons) != 0 {
+		t.Errorf("b reasons = failed %d rej %v", b.FailedAttempts, b.RejectionReasons)
+	}
+
 	for _, p := range resp.Problems {
 		if sum := p.Accepted + p.Appealed + p.Rejected + p.Submitted + p.Unsolved; sum != resp.TotalStudents {
 			t.Errorf("subproblem %d buckets sum %d != total_students %d", p.SubproblemID, sum, resp.TotalStudents)
@@ -128,7 +158,7 @@ func TestProblemStats_MultipleProblemsOrdered(t *testing.T) {
 
 	now := time.Now()
 	expectSeriesForStats(mock, 100, 42, now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	// Problem 1 (id 500) has subproblems a=900, b=901; problem 2 (id 501) has a
 	// single sentinel subproblem 950 (label ''). Two students. Rows arrive in
@@ -142,6 +172,7 @@ func TestProblemStats_MultipleProblemsOrdered(t *testing.T) {
 			AddRow(statRow(2, 500, 1, 901, "b", "accepted")...).
 			AddRow(statRow(1, 501, 2, 950, "", "rejected")...).
 			AddRow(statRow(2, 501, 2, 950, "", "ungraded")...))
+	expectReasonCounts(mock, 100, nil)
 
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/problem-stats", nil)
 	rr := httptest.NewRecorder()
@@ -193,7 +224,7 @@ func TestProblemStats_EmptyRosterShowsProblems(t *testing.T) {
 
 	now := time.Now()
 	expectSeriesForStats(mock, 100, 42, now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	// Two subproblems, no roster: one placeholder row each.
 	mock.ExpectQuery(`FROM math_center_subproblems sp\s+JOIN math_center_problems`).
@@ -201,6 +232,7 @@ func TestProblemStats_EmptyRosterShowsProblems(t *testing.T) {
 		WillReturnRows(mock.NewRows(problemStatsRowColumns).
 			AddRow(emptyRosterRow(500, 1, 900, "a")...).
 			AddRow(emptyRosterRow(500, 1, 901, "b")...))
+	expectReasonCounts(mock, 100, nil)
 
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/problem-stats", nil)
 	rr := httptest.NewRecorder()
@@ -234,11 +266,12 @@ func TestProblemStats_AdminAllowed(t *testing.T) {
 
 	now := time.Now()
 	expectSeriesForStats(mock, 100, 42, now)
-	// No teacher check: admin short-circuits requireTeacher via the JWT claim.
+	// No teacher check: admin short-circuits teacherAccess via the JWT claim.
 	mock.ExpectQuery(`FROM math_center_subproblems sp\s+JOIN math_center_problems`).
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(problemStatsRowColumns).
 			AddRow(statRow(1, 500, 1, 900, "a", "accepted")...))
+	expectReasonCounts(mock, 100, nil)
 
 	req := authedRequest(t, access, 9, true, http.MethodGet, "/series/100/problem-stats", nil)
 	rr := httptest.NewRecorder()
@@ -263,7 +296,7 @@ func TestProblemStats_NonTeacherForbidden(t *testing.T) {
 
 	now := time.Now()
 	expectSeriesForStats(mock, 100, 42, now)
-	expectTeacherCheck(mock, 3, 42, false)
+	expectTeacherAccess(mock, 3, 42, "")
 
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/problem-stats", nil)
 	rr := httptest.NewRecorder()
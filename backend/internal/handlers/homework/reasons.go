@@ -0,0 +1,203 @@
+package homework
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// reasonCodeView is one entry of a center's taxonomy. Archived codes are no
+// longer offered to graders but still label the events that carry them.
+type reasonCodeView struct {
+	Code            string     `json:"code"`
+	Label           string     `json:"label"`
+	AppliesTo       string     `json:"applies_to"`
+	CountsAsAttempt bool       `json:"counts_as_attempt"`
+	ArchivedAt      *time.Time `json:"archived_at,omitempty"`
+}
+
+// eventReasonView is the reason shown on a graded or retracted event.
+// CountsAsAttempt false tells the student the rejection was about the
+// submission itself and a fresh attempt is welcome.
+type eventReasonView struct {
+	Code            string `json:"code"`
+	Label           string `json:"label"`
+	CountsAsAttempt bool   `json:"counts_as_attempt"`
+}
+
+// reasonCodesRequest is the body of PUT /centers/{centerID}/reason-codes: the
+// whole taxonomy, in display order.
+type reasonCodesRequest struct {
+	Codes []struct {
+		Code            string `json:"code"`
+		Label           string `json:"label"`
+		AppliesTo       string `json:"applies_to"`
+		CountsAsAttempt bool   `json:"counts_as_attempt"`
+	} `json:"codes"`
+}
+
+func toReasonCodeViews(rows []store.HomeworkReasonCode) []reasonCodeView {
+	out := make([]reasonCodeView, 0, len(rows))
+	for _, c := range rows {
+		out = append(out, reasonCodeView{
+			Code:            c.Code,
+			Label:           c.Label,
+			AppliesTo:       c.AppliesTo,
+			CountsAsAttempt: c.CountsAsAttempt,
+			ArchivedAt:      c.ArchivedAt,
+		})
+	}
+	return out
+}
+
+// GetReasonCodes — teacher of the center. The taxonomy graders pick from,
+// archived codes last.
+func GetReasonCodes(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanView) {
+			return
+		}
+		rows, err := q.ListReasonCodes(ctx, centerID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: list reason codes", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, toReasonCodeViews(rows))
+	}
+}
+
+// PutReasonCodes — head teacher of the center. Replaces the taxonomy: listed
+// codes are created or updated in the given order, codes left out are
+// archived (events keep pointing at them).
+func PutReasonCodes(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		var req reasonCodesRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		specs := make([]homework.ReasonCodeSpec, 0, len(req.Codes))
+		for _, c := range req.Codes {
+			specs = append(specs, homework.ReasonCodeSpec{
+				Code:            c.Code,
+				Label:           c.Label,
+				AppliesTo:       c.AppliesTo,
+				CountsAsAttempt: c.CountsAsAttempt,
+			})
+		}
+		specs, err = homework.ValidateReasonCodes(specs)
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, err.Error())
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireHeadTeacher(ctx, w, r, q, userID, centerID) {
+			return
+		}
+		if err := writeReasonCodes(ctx, database, centerID, specs); err != nil {
+			logger.LogErrorContext(ctx, "homework: save reason codes", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save reason codes")
+			return
+		}
+		rows, err := q.ListReasonCodes(ctx, centerID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: list reason codes", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, toReasonCodeViews(rows))
+	}
+}
+
+func writeReasonCodes(ctx context.Context, database *db.DB, centerID int64, specs []homework.ReasonCodeSpec) error {
+	tx, err := database.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qx := store.New(tx)
+	keep := make([]string, 0, len(specs))
+	for i, s := range specs {
+		if _, err := qx.UpsertReasonCode(ctx, store.UpsertReasonCodeParams{
+			MathCenterID:    centerID,
+			Code:            s.Code,
+			Label:           s.Label,
+			AppliesTo:       s.AppliesTo,
+			CountsAsAttempt: s.CountsAsAttempt,
+			Position:        int32(i + 1),
+		}); err != nil {
+			return err
+		}
+		keep = append(keep, s.Code)
+	}
+	if err := qx.ArchiveReasonCodesExcept(ctx, centerID, keep); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// resolveReasonCode looks up the code a grader picked for an event of kind
+// (KindGraded or KindRetracted). An empty code is fine and yields nil; an
+// unknown or archived one, or one not meant for this kind of event, is a 400.
+func resolveReasonCode(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, centerID int64, code, kind string) (*store.HomeworkReasonCode, bool) {
+	if code == "" {
+		return nil, true
+	}
+	rc, err := q.GetActiveReasonCode(ctx, centerID, code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "unknown reason_code")
+			return nil, false
+		}
+		logger.LogErrorContext(ctx, "homework: get reason code", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return nil, false
+	}
+	if !homework.ReasonApplies(rc.AppliesTo, kind) {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "reason_code does not apply here")
+		return nil, false
+	}
+	return &rc, true
+}
+
+// reasonID is the id to store for an optional resolved code.
+func reasonID(rc *store.HomeworkReasonCode) *int64 {
+	if rc == nil {
+		return nil
+	}
+	return &rc.ID
+}
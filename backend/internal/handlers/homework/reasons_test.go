@@ -0,0 +1,275 @@
+package homework_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var reasonCodeColumns = []string{"id", "math_center_id", "code", "label", "applies_to", "counts_as_attempt", "position", "archived_at"}
+
+func reasonCodeRow(id int64, code, label, appliesTo string, counts bool, position int32) []any {
+	return []any{id, int64(42), code, label, appliesTo, counts, position, (*time.Time)(nil)}
+}
+
+func expectActiveReasonCode(mock pgxmock.PgxPoolIface, code string, row []any) {
+	q := mock.ExpectQuery(`FROM homework_reason_code\s+WHERE math_center_id = \$1\s+AND code = \$2`).
+		WithArgs(int64(42), code)
+	if row == nil {
+		q.WillReturnError(pgx.ErrNoRows)
+		return
+	}
+	q.WillReturnRows(mock.NewRows(reasonCodeColumns).AddRow(row...))
+}
+
+func TestGrade_RejectionCarriesReasonCode(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	attemptID := int64(50)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "submitted", AttemptEventID: &attemptID,
+		}, now)...))
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	expectActiveReasonCode(mock, "illegible_photo", reasonCodeRow(5, "illegible_photo", "Нечитаемое фото", "rejection", false, 3))
+
+	verdict := "rejected"
+	mock.ExpectBegin()
+	expectChainLock(mock, 1)
+	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
+		WithArgs(int64(1), "g1", "graded", int64(3), "retake the photo", &verdict, &attemptID).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(80), int64(1), "g1", "graded", int64(3), "retake the photo", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, 80, 1)
+	mock.ExpectExec(`INSERT INTO homework_event_reason`).
+		WithArgs(int64(80), int64(5)).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= CASE`).
+		WithArgs("rejected", int64(80), int64(3), int64(1)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectCalibrationSample(mock, 1, 80, 3, "rejected", 42, false)
+	mock.ExpectCommit()
+	expectAttemptSubmittedAt(mock, attemptID, now.Add(-time.Hour))
+	gradeID := int64(80)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "rejected", AttemptEventID: &attemptID, GradeEventID: &gradeID, LastGraderID: ptr64(3),
+		}, now)...))
+	expectGetSeriesForView(mock, 100, 42, now)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(80), int64(1), "g1", "graded", int64(3), "retake the photo", &verdict, &attemptID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectGetUsersForView(mock)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event_photo`).
+		WithArgs([]int64{80}).
+		WillReturnRows(mock.NewRows(photoColumns))
+	expectEventReasons(mock, []int64{80}, mock.NewRows(eventReasonColumns).
+		AddRow(int64(80), "illegible_photo", "Нечитаемое фото", false))
+
+	body, _ := json.Marshal(map[string]any{
+		"verdict": "rejected", "body": "retake the photo", "event_uuid": "g1", "object_keys": []string{},
+		"reason_code": "illegible_photo",
+	})
+	rr := serveJSON(t, r, authedRequest(t, access, 3, false, http.MethodPost, "/threads/by-id/1/grade", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var view struct {
+		Events []struct {
+			Reason *struct {
+				Code            string `json:"code"`
+				CountsAsAttempt bool   `json:"counts_as_attempt"`
+			} `json:"reason"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &view); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(view.Events) != 1 || view.Events[0].Reason == nil ||
+		view.Events[0].Reason.Code != "illegible_photo" || view.Events[0].Reason.CountsAsAttempt {
+		t.Errorf("events = %s", rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
+
+func TestGrade_ReasonCodeOnlyOnRejections(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	body, _ := json.Marshal(map[string]any{
+		"verdict": "accepted", "body": "ok", "event_uuid": "g1", "reason_code": "wrong_answer",
+	})
+	rr := serveJSON(t, r, authedRequest(t, access, 3, false, http.MethodPost, "/threads/by-id/1/grade", bytes.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400", rr.Code)
+	}
+}
+
+func TestGrade_UnknownReasonCode(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "submitted", AttemptEventID: ptr64(50),
+		}, now)...))
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	expectActiveReasonCode(mock, "typo", nil)
+
+	body, _ := json.Marshal(map[string]any{
+		"verdict": "rejected", "body": "no", "event_uuid": "g1", "reason_code": "typo",
+	})
+	rr := serveJSON(t, r, authedRequest(t, access, 3, false, http.MethodPost, "/threads/by-id/1/grade", bytes.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestRetract_RejectionOnlyReasonRefused(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "rejected", AttemptEventID: ptr64(50), GradeEventID: ptr64(80), LastGraderID: ptr64(3),
+		}, now)...))
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	expectActiveReasonCode(mock, "illegible_photo", reasonCodeRow(5, "illegible_photo", "Нечитаемое фото", "rejection", false, 3))
+
+	body, _ := json.Marshal(map[string]any{"body": "oops", "reason_code": "illegible_photo"})
+	rr := serveJSON(t, r, authedRequest(t, access, 3, false, http.MethodPost, "/threads/by-id/1/retract", bytes.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGetReasonCodes(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectQuery(`FROM homework_reason_code\s+WHERE math_center_id = \$1\s+ORDER BY`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows(reasonCodeColumns).
+			AddRow(reasonCodeRow(1, "wrong_answer", "Неверный ответ", "both", true, 1)...).
+			AddRow(reasonCodeRow(3, "illegible_photo", "Нечитаемое фото", "rejection", false, 3)...))
+
+	rr := serveJSON(t, r, authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/reason-codes", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var codes []struct {
+		Code            string `json:"code"`
+		CountsAsAttempt bool   `json:"counts_as_attempt"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &codes); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(codes) != 2 || codes[1].Code != "illegible_photo" || codes[1].CountsAsAttempt {
+		t.Errorf("codes = %+v", codes)
+	}
+}
+
+func TestPutReasonCodes_ReplacesTaxonomy(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectHeadTeacherCheck(mock, 3, 42, true)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO homework_reason_code`).
+		WithArgs(int64(42), "illegible_photo", "Нечитаемое фото", "rejection", false, int32(1)).
+		WillReturnRows(mock.NewRows(reasonCodeColumns).AddRow(reasonCodeRow(3, "illegible_photo", "Нечитаемое фото", "rejection", false, 1)...))
+	mock.ExpectQuery(`INSERT INTO homework_reason_code`).
+		WithArgs(int64(42), "no_proof", "Нет доказательства", "both", true, int32(2)).
+		WillReturnRows(mock.NewRows(reasonCodeColumns).AddRow(reasonCodeRow(7, "no_proof", "Нет доказательства", "both", true, 2)...))
+	mock.ExpectExec(`UPDATE homework_reason_code\s+SET archived_at`).
+		WithArgs(int64(42), []string{"illegible_photo", "no_proof"}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mock.ExpectCommit()
+	archived := time.Now()
+	mock.ExpectQuery(`FROM homework_reason_code\s+WHERE math_center_id = \$1\s+ORDER BY`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows(reasonCodeColumns).
+			AddRow(reasonCodeRow(3, "illegible_photo", "Нечитаемое фото", "rejection", false, 1)...).
+			AddRow(reasonCodeRow(7, "no_proof", "Нет доказательства", "both", true, 2)...).
+			AddRow(int64(1), int64(42), "wrong_answer", "Неверный ответ", "both", true, int32(1), &archived))
+
+	body, _ := json.Marshal(map[string]any{"codes": []map[string]any{
+		{"code": "illegible_photo", "label": "Нечитаемое фото", "applies_to": "rejection", "counts_as_attempt": false},
+		{"code": "no_proof", "label": " Нет доказательства ", "applies_to": "both", "counts_as_attempt": true},
+	}})
+	rr := serveJSON(t, r, authedRequest(t, access, 3, false, http.MethodPut, "/centers/42/reason-codes", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var codes []struct {
+		Code       string     `json:"code"`
+		ArchivedAt *time.Time `json:"archived_at"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &codes); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(codes) != 3 || codes[2].ArchivedAt == nil {
+		t.Errorf("codes = %+v", codes)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
+
+func TestPutReasonCodes_Validates(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	body, _ := json.Marshal(map[string]any{"codes": []map[string]any{
+		{"code": "Bad Code", "label": "x", "applies_to": "both"},
+	}})
+	rr := serveJSON(t, r, authedRequest(t, access, 3, false, http.MethodPut, "/centers/42/reason-codes", bytes.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400", rr.Code)
+	}
+}
+
+func TestPutReasonCodes_RequiresHeadTeacher(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectHeadTeacherCheck(mock, 3, 42, false)
+	body, _ := json.Marshal(map[string]any{"codes": []map[string]any{}})
+	rr := serveJSON(t, r, authedRequest(t, access, 3, false, http.MethodPut, "/centers/42/reason-codes", bytes.NewReader(body)))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403", rr.Code)
+	}
+}
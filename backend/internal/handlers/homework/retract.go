@@ -10,17 +10,21 @@ import (
 
 	"github.com/Alarion239/my239/backend/internal/homework"
 	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/hwchain"
 	"github.com/Alarion239/my239/backend/internal/live"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 	"github.com/Alarion239/my239/backend/pkg/objectstore"
 )
 
 // retractRequest is the body of /retract. Body is an optional reason
-// (helpful for the audit trail; the timeline shows it to the student).
+// (helpful for the audit trail; the timeline shows it to the student), and
+// ReasonCode an optional code from the center's taxonomy.
 type retractRequest struct {
-	Body string `json:"body"`
+	Body       string `json:"body"`
+	ReasonCode string `json:"reason_code"`
 }
 
 // Retract — most recent grader OR admin. Undoes the latest 'graded'
@@ -61,7 +65,7 @@ func Retract(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.Handl
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, thread.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanGrade) {
 			return
 		}
 		// Only the grader who issued the verdict (or an admin) may
@@ -79,6 +83,11 @@ func Retract(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.Handl
 			return
 		}
 
+		reason, ok := resolveReasonCode(ctx, w, r, q, thread.MathCenterID, req.ReasonCode, homework.KindRetracted)
+		if !ok {
+			return
+		}
+
 		gradedEvent, err := q.GetMostRecentGradedEvent(ctx, thread.ID)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
@@ -103,7 +112,7 @@ func Retract(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.Handl
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if err := writeRetract(ctx, database, thread.ID, eventUUID, userID, body, gradedEvent.ID, rollback); err != nil {
+		if err := writeRetract(ctx, database, thread.ID, eventUUID, userID, body, reasonID(reason), gradedEvent.ID, rollback); err != nil {
 			logger.LogErrorContext(ctx, "homework: retract tx", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to retract")
 			return
@@ -137,9 +146,9 @@ func rollbackStatus(ctx context.Context, q *store.Queries, thread store.Homework
 }
 
 // writeRetract appends a 'retracted' event referring to the rescinded
-// grade, then flips the thread cache back to its prior attempt status,
+// grade (with its reason, if one was picked), then flips the thread cache back to its prior attempt status,
 // all in one tx so the cache and timeline can't disagree.
-func writeRetract(ctx context.Context, database *db.DB, threadID int64, eventUUID string, actorUserID int64, body string, gradedEventID int64, rollback string) error {
+func writeRetract(ctx context.Context, database *db.DB, threadID int64, eventUUID string, actorUserID int64, body string, reasonCodeID *int64, gradedEventID int64, rollback string) error {
 	tx, err := database.Pool().Begin(ctx)
 	if err != nil {
 		return err
@@ -147,7 +156,11 @@ func writeRetract(ctx context.Context, database *db.DB, threadID int64, eventUUI
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	qx := store.New(tx)
-	if _, err := qx.AppendEvent(ctx, store.AppendEventParams{
+	prev, err := qx.LockEventChain(ctx, threadID)
+	if err != nil {
+		return fmt.Errorf("lock event chain: %w", err)
+	}
+	event, err := qx.AppendEvent(ctx, store.AppendEventParams{
 		ThreadID:        threadID,
 		EventUuid:       eventUUID,
 		Kind:            homework.KindRetracted,
@@ -155,9 +168,18 @@ func writeRetract(ctx context.Context, database *db.DB, threadID int64, eventUUI
 		Body:            body,
 		Verdict:         nil,
 		RefersToEventID: &gradedEventID,
-	}); err != nil {
+	})
+	if err != nil {
 		return fmt.Errorf("append retract event: %w", err)
 	}
+	if err := hwchain.Seal(ctx, qx, prev, event); err != nil {
+		return err
+	}
+	if reasonCodeID != nil {
+		if err := qx.SetEventReason(ctx, event.ID, *reasonCodeID); err != nil {
+			return fmt.Errorf("set retract reason: %w", err)
+		}
+	}
 	if err := qx.UpdateThreadAfterRetract(ctx, store.UpdateThreadAfterRetractParams{
 		ID:            threadID,
 		CurrentStatus: rollback,
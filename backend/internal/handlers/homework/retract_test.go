@@ -26,7 +26,7 @@ func TestRetract_OwnGraderRollsBackToSubmitted(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "rejected", AttemptEventID: &attemptID, GradeEventID: &gradeID, LastGraderID: &grader,
 		}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// Most-recent graded event.
 	verdict := "rejected"
 	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id = \$1\s+AND kind\s+= 'graded'`).
@@ -40,10 +40,12 @@ func TestRetract_OwnGraderRollsBackToSubmitted(t *testing.T) {
 
 	// Tx: AppendEvent('retracted', refers_to=80) → UpdateThreadAfterRetract.
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), pgxmock.AnyArg(), "retracted", int64(3), "my mistake", (*string)(nil), &gradeID).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(85), int64(1), "rev1", "retracted", int64(3), "my mistake", (*string)(nil), &gradeID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(85), int64(1))
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= \$2`).
 		WithArgs(int64(1), "submitted").
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
@@ -86,7 +88,7 @@ func TestRetract_RollbackAfterAppealGoesToAppealed(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "accepted", AttemptEventID: &appealEv, GradeEventID: &gradeID, LastGraderID: &grader,
 		}, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	verdict := "accepted"
 	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id = \$1\s+AND kind\s+= 'graded'`).
 		WithArgs(int64(1)).
@@ -97,10 +99,12 @@ func TestRetract_RollbackAfterAppealGoesToAppealed(t *testing.T) {
 		WillReturnRows(mock.NewRows([]string{"kind"}).AddRow("appealed"))
 
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), pgxmock.AnyArg(), "retracted", int64(3), "", (*string)(nil), &gradeID).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(95), int64(1), "rev2", "retracted", int64(3), "", (*string)(nil), &gradeID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(95), int64(1))
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= \$2`).
 		WithArgs(int64(1), "appealed").
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
@@ -138,7 +142,7 @@ func TestRetract_OtherGraderForbidden(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "accepted", LastGraderID: &originalGrader,
 		}, now)...))
-	expectTeacherCheck(mock, 4, 42, true) // user 4 is a teacher, but not the original grader
+	expectTeacherAccess(mock, 4, 42, "teacher") // user 4 is a teacher, but not the original grader
 
 	req := authedRequest(t, access, 4, false, http.MethodPost, "/threads/by-id/1/retract", bytes.NewReader([]byte(`{}`)))
 	req.Header.Set("Content-Type", "application/json")
@@ -164,8 +168,8 @@ func TestRetract_AdminCanRetract(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
 			Status: "rejected", AttemptEventID: &attemptID, GradeEventID: &gradeID, LastGraderID: &originalGrader,
 		}, now)...))
-	// Admin is a teacher superset: requireTeacher short-circuits without an
-	// IsTeacherInCenter query, so we deliberately do not expect one here.
+	// Admin is a teacher superset: teacherAccess short-circuits without a
+	// role lookup, so we deliberately do not expect one here.
 	verdict := "rejected"
 	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id = \$1\s+AND kind\s+= 'graded'`).
 		WithArgs(int64(1)).
@@ -176,10 +180,12 @@ func TestRetract_AdminCanRetract(t *testing.T) {
 		WillReturnRows(mock.NewRows([]string{"kind"}).AddRow("submitted"))
 
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), pgxmock.AnyArg(), "retracted", int64(4), "policy override", (*string)(nil), &gradeID).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(95), int64(1), "rev", "retracted", int64(4), "policy override", (*string)(nil), &gradeID, now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(95), int64(1))
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= \$2`).
 		WithArgs(int64(1), "submitted").
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
@@ -35,18 +35,25 @@ func Router(database *db.DB, hub *live.Hub, tokens *internalAuth.TokenService, b
 		r.Post("/upload-urls", IssueStudentUploadURLs(database, blobs, uploadTTL))
 		r.Post("/submit", SubmitAttempt(database, hub, blobs))
 		r.Post("/appeal", AppealGrade(database, hub, blobs))
+		// Server-side draft of the next attempt, kept until sent or expired.
+		r.Get("/draft", GetDraft(database, blobs, downloadTTL))
+		r.Put("/draft", PutDraft(database, blobs, downloadTTL))
+		r.Delete("/draft", DeleteDraft(database, blobs))
+		r.Post("/draft/submit", SubmitDraft(database, blobs))
 	})
 
 	// Grader-target routes operate on an existing thread by id. The
 	// /by-id/ segment disambiguates the chi param from /threads/{subproblemID}.
 	r.Route("/threads/by-id/{threadID}", func(r chi.Router) {
 		r.Get("/", GetThread(database, blobs, downloadTTL))
+		r.Get("/photos/{eventID}/{idx}", GetEventPhotoOriginal(database, blobs, downloadTTL))
 		r.Post("/upload-urls", IssueGraderUploadURLs(database, blobs, uploadTTL))
 		r.Post("/claim", Claim(database, hub, blobs))
 		r.Post("/claim/heartbeat", Heartbeat(database))
 		r.Post("/claim/release", Release(database, hub))
 		r.Post("/grade", Grade(database, hub, blobs))
 		r.Post("/retract", Retract(database, hub, blobs))
+		r.Post("/messages", PostMessage(database, hub, blobs))
 
 		// Internal teacher-only notes on the solution thread (never shown to
 		// the student). Author-or-admin may edit/delete.
@@ -72,12 +79,41 @@ func Router(database *db.DB, hub *live.Hub, tokens *internalAuth.TokenService, b
 	r.Get("/series/{seriesID}/queue", GraderQueue(database))
 	r.Get("/series/{seriesID}/grid", TeacherGrid(database))
 	r.Get("/series/{seriesID}/problem-stats", ProblemStats(database))
+	r.Get("/series/{seriesID}/duplicates", SeriesDuplicates(database))
+	r.Get("/series/{seriesID}/grading", GetSeriesGrading(database))
+	r.Put("/series/{seriesID}/grading", PutSeriesGrading(database))
 
 	// Center-scoped dashboards.
 	r.Get("/centers/{centerID}/grader-stats", GraderStats(database))
+	r.Post("/centers/{centerID}/bulk-grade", BulkGrade(database))
+	r.Get("/centers/{centerID}/my/progress", MyProgress(database))
 	r.Get("/centers/{centerID}/grid", GetCenterGrid(database))
+	r.Get("/centers/{centerID}/grid/export", ExportCenterGrid(database))
 	r.Get("/centers/{centerID}/grid/series/{seriesID}/cells", GetCenterGridSeriesCells(database))
 	r.Get("/centers/{centerID}/teachers", CenterTeachers(database))
+	r.Put("/centers/{centerID}/away", SetAway(database))
+
+	// Double-blind calibration: any teacher grades the queue blind; head
+	// teachers configure the rate and review disagreements and agreement.
+	r.Get("/centers/{centerID}/calibration", GetCalibrationConfig(database))
+	r.Put("/centers/{centerID}/calibration", PutCalibrationConfig(database))
+	r.Get("/centers/{centerID}/calibration/queue", CalibrationQueue(database))
+	r.Get("/centers/{centerID}/calibration/disagreements", CalibrationDisagreements(database))
+	r.Post("/centers/{centerID}/calibration/{calibrationID}/resolve", ResolveCalibration(database))
+	r.Get("/centers/{centerID}/calibration/report", CalibrationReport(database))
+	r.Get("/calibration/{calibrationID}", GetCalibration(database, blobs, downloadTTL))
+	r.Post("/calibration/{calibrationID}/grade", GradeCalibration(database))
+
+	// Reason codes graders attach to rejections and retractions.
+	r.Get("/centers/{centerID}/reason-codes", GetReasonCodes(database))
+	r.Put("/centers/{centerID}/reason-codes", PutReasonCodes(database))
+
+	// Grading turnaround: the center's SLA, what is overdue against it, and
+	// per-series / per-grader waits for a term.
+	r.Get("/centers/{centerID}/sla", GetSLAConfig(database))
+	r.Put("/centers/{centerID}/sla", PutSLAConfig(database))
+	r.Get("/centers/{centerID}/sla/overdue", ListOverdueThreads(database))
+	r.Get("/centers/{centerID}/turnaround", TurnaroundReport(database))
 
 	return r
 }
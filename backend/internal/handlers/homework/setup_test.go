@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 
 	internalAuth "github.com/Alarion239/my239/backend/internal/auth"
@@ -33,10 +34,15 @@ var eventColumns = []string{
 	"google_sheet_link_id", "google_sheet_cell", "google_sheet_version",
 }
 
+var photoColumns = []string{
+	"event_id", "idx", "object_key", "size_bytes", "content_type", "created_at",
+	"processing_status", "preview_object_key", "thumb_object_key",
+}
+
 var subproblemCtxColumns = []string{
 	"subproblem_id", "subproblem_label", "problem_id", "problem_number",
 	"series_id", "math_center_id", "series_due_at", "series_published_at",
-	"is_coffin", "coffin_released_at",
+	"is_coffin", "coffin_released_at", "solution_published_at",
 }
 
 var seriesColumns = []string{
@@ -113,6 +119,122 @@ func expectTeacherCheck(mock pgxmock.PgxPoolIface, userID, centerID int64, ok bo
 		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(ok))
 }
 
+var teacherAccessColumns = []string{"id", "is_head_teacher", "role", "term_id", "in_scope"}
+
+// expectTeacherAccess adds the role lookup grading actions make. Role ""
+// means the user does not teach the center; "head" sets the head flag.
+func expectTeacherAccess(mock pgxmock.PgxPoolIface, userID, centerID int64, role string) {
+	q := mock.ExpectQuery(`FROM math_center_teachers t\s+LEFT JOIN math_center_terms`).
+		WithArgs(userID, centerID)
+	if role == "" {
+		q.WillReturnError(pgx.ErrNoRows)
+		return
+	}
+	isHead := role == "head"
+	if isHead {
+		role = "teacher"
+	}
+	q.WillReturnRows(mock.NewRows(teacherAccessColumns).
+		AddRow(int64(1), isHead, role, (*int64)(nil), true))
+}
+
+// expectTermGraderAccess is expectTeacherAccess for a grader whose role is
+// limited to termID.
+func expectTermGraderAccess(mock pgxmock.PgxPoolIface, userID, centerID, termID int64) {
+	mock.ExpectQuery(`FROM math_center_teachers t\s+LEFT JOIN math_center_terms`).
+		WithArgs(userID, centerID).
+		WillReturnRows(mock.NewRows(teacherAccessColumns).
+			AddRow(int64(1), false, "grader", &termID, true))
+}
+
+// expectSeriesInTerm adds the term-scope check made for a term-limited role.
+func expectSeriesInTerm(mock pgxmock.PgxPoolIface, seriesID, termID int64, ok bool) {
+	mock.ExpectQuery(`FROM math_center_series WHERE id = \$1 AND term_id = \$2`).
+		WithArgs(seriesID, termID).
+		WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(ok))
+}
+
+// expectSeriesGrading adds the assignment-mode lookup GraderQueue makes.
+// mode "" means the series was never configured (no row).
+func expectSeriesGrading(mock pgxmock.PgxPoolIface, seriesID int64, mode string) {
+	rows := mock.NewRows([]string{"series_id", "mode", "reassign_on_absence", "updated_at"})
+	if mode != "" {
+		rows.AddRow(seriesID, mode, false, time.Now())
+	}
+	mock.ExpectQuery(`FROM homework_series_grading\s+WHERE series_id`).
+		WithArgs(seriesID).
+		WillReturnRows(rows)
+}
+
+// expectAssignGrader adds the AssignThreadGrader call every submit makes
+// inside its transaction. assignee nil = left unassigned (mode 'off').
+func expectAssignGrader(mock pgxmock.PgxPoolIface, threadID int64, assignee *int64) {
+	mock.ExpectQuery(`UPDATE homework_thread th\s+SET assigned_grader_user_id`).
+		WithArgs(threadID).
+		WillReturnRows(mock.NewRows([]string{"assigned_grader_user_id"}).AddRow(assignee))
+}
+
+// expectDraftCleared adds the draft cleanup every submit makes inside its
+// transaction, after grader assignment.
+func expectDraftCleared(mock pgxmock.PgxPoolIface, threadID int64) {
+	mock.ExpectExec(`DELETE FROM homework_attempt_draft WHERE thread_id`).
+		WithArgs(threadID).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+}
+
+// expectStorageQuota adds the quota check of IssueStudentUploadURLs, and of
+// a submission, appeal or student message that attaches photos: the center's
+// configured quota (nil for no row, i.e. the default) and the bytes the
+// student's own photos already take.
+func expectStorageQuota(mock pgxmock.PgxPoolIface, studentID, centerID int64, quota *int64, used int64) {
+	rows := mock.NewRows([]string{"math_center_id", "bytes_per_student", "updated_at"})
+	if quota != nil {
+		rows.AddRow(centerID, *quota, time.Now())
+	}
+	mock.ExpectQuery(`FROM homework_storage_quota\s+WHERE math_center_id`).
+		WithArgs(centerID).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(p.size_bytes\), 0\)`).
+		WithArgs(studentID, centerID).
+		WillReturnRows(mock.NewRows([]string{"sum"}).AddRow(used))
+}
+
+// expectChainLock adds the LockEventChain read every append makes before its
+// insert. The thread has no sealed events yet, so the head is NULL.
+func expectChainLock(mock pgxmock.PgxPoolIface, threadID int64) {
+	mock.ExpectQuery(`SELECT event_chain_head\s+FROM homework_thread\s+WHERE id = \$1\s+FOR UPDATE`).
+		WithArgs(threadID).
+		WillReturnRows(mock.NewRows([]string{"event_chain_head"}).AddRow([]byte(nil)))
+}
+
+// expectChainSeal adds the SealEvent update that follows every append. The
+// event is the first link, so prev_hash is empty.
+func expectChainSeal(mock pgxmock.PgxPoolIface, eventID, threadID int64) {
+	mock.ExpectExec(`UPDATE homework_thread_event\s+SET prev_hash`).
+		WithArgs(eventID, threadID, []byte{}, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+}
+
+// expectAttemptSubmittedAt adds the post-commit lookup Grade makes to
+// observe the submitted → graded histogram.
+func expectAttemptSubmittedAt(mock pgxmock.PgxPoolIface, attemptID int64, at time.Time) {
+	mock.ExpectQuery(`SELECT created_at\s+FROM homework_thread_event\s+WHERE id = \$1`).
+		WithArgs(attemptID).
+		WillReturnRows(mock.NewRows([]string{"created_at"}).AddRow(at))
+}
+
+// expectCalibrationSample adds the SampleCalibration insert every grade makes
+// inside its transaction. sampled reports whether the draw queued the verdict.
+func expectCalibrationSample(mock pgxmock.PgxPoolIface, threadID, eventID, graderID int64, verdict string, centerID int64, sampled bool) {
+	var n int64
+	if sampled {
+		n = 1
+	}
+	mock.ExpectExec(`INSERT INTO homework_calibration \(`).
+		WithArgs(threadID, eventID, graderID, verdict, centerID).
+		WillReturnResult(pgxmock.NewResult("INSERT", n))
+}
+
 // expectStudentCheck adds the standard "is this user a student of this
 // center?" expectation.
 func expectStudentCheck(mock pgxmock.PgxPoolIface, userID, centerID int64, ok bool) {
@@ -128,7 +250,7 @@ func expectSubproblemContext(mock pgxmock.PgxPoolIface, subproblemID, problemID,
 	mock.ExpectQuery(`FROM math_center_subproblems sp\s+JOIN math_center_problems`).
 		WithArgs(subproblemID).
 		WillReturnRows(mock.NewRows(subproblemCtxColumns).
-			AddRow(subproblemID, label, problemID, problemNumber, seriesID, centerID, due, publishedAt, false, (*time.Time)(nil)))
+			AddRow(subproblemID, label, problemID, problemNumber, seriesID, centerID, due, publishedAt, false, (*time.Time)(nil), (*time.Time)(nil)))
 }
 
 // expectSubproblemContextCoffin is the coffin variant: isCoffin=true and an
@@ -137,7 +259,7 @@ func expectSubproblemContextCoffin(mock pgxmock.PgxPoolIface, subproblemID, prob
 	mock.ExpectQuery(`FROM math_center_subproblems sp\s+JOIN math_center_problems`).
 		WithArgs(subproblemID).
 		WillReturnRows(mock.NewRows(subproblemCtxColumns).
-			AddRow(subproblemID, label, problemID, problemNumber, seriesID, centerID, due, publishedAt, true, coffinReleasedAt))
+			AddRow(subproblemID, label, problemID, problemNumber, seriesID, centerID, due, publishedAt, true, coffinReleasedAt, (*time.Time)(nil)))
 }
 
 // expectGetSeriesForView adds the GetSeries expectation that buildThreadView
@@ -167,6 +289,28 @@ func expectGetUsersForView(mock pgxmock.PgxPoolIface) {
 		WillReturnRows(mock.NewRows([]string{"id", "first_name", "middle_name", "last_name"}))
 }
 
+// eventReasonColumns is the ListEventReasons row shape.
+var eventReasonColumns = []string{"event_id", "code", "label", "counts_as_attempt"}
+
+// expectEventReasons adds the reason lookup buildThreadView makes after the
+// photos when the timeline holds verdicts or retractions (eventIDs).
+func expectEventReasons(mock pgxmock.PgxPoolIface, eventIDs []int64, rows *pgxmock.Rows) {
+	if rows == nil {
+		rows = mock.NewRows(eventReasonColumns)
+	}
+	mock.ExpectQuery(`FROM homework_event_reason er`).
+		WithArgs(eventIDs).
+		WillReturnRows(rows)
+}
+
+// expectRejectedWithoutAttempt adds the check a closed submission window
+// makes for a standing rejection that did not count as an attempt.
+func expectRejectedWithoutAttempt(mock pgxmock.PgxPoolIface, studentID, subproblemID int64, exempt bool) {
+	mock.ExpectQuery(`AND NOT rc.counts_as_attempt`).
+		WithArgs(studentID, subproblemID).
+		WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(exempt))
+}
+
 // emptyThreadRow returns a brand-new thread row matching FindOrCreateThread's
 // RETURNING list, suitable for AddRow.
 func emptyThreadRow(threadID, studentID, subID, seriesID, centerID int64, now time.Time) []any {
@@ -212,3 +356,32 @@ func threadRow(threadID, studentID, subID, seriesID, centerID int64, opts thread
 		opts.LastGraderName,
 	}
 }
+
+var threadDuplicateFlagColumns = []string{
+	"id", "kind", "distance", "created_at", "event_id", "photo_idx",
+	"other_thread_id", "other_event_id", "other_photo_idx",
+	"other_student_user_id", "first_name", "last_name",
+}
+
+// expectPendingCalibrationOnThread mocks the blinding check GetThread runs for
+// teachers; gradedEventID 0 means nothing is waiting for the caller.
+func expectPendingCalibrationOnThread(mock pgxmock.PgxPoolIface, threadID, userID, gradedEventID int64) {
+	rows := mock.NewRows([]string{"graded_event_id"})
+	if gradedEventID != 0 {
+		rows.AddRow(gradedEventID)
+	}
+	mock.ExpectQuery(`SELECT c.graded_event_id\s+FROM homework_calibration c`).
+		WithArgs(threadID, userID).
+		WillReturnRows(rows)
+}
+
+// expectDuplicateFlags adds the teacher-only flag lookup GetThread makes
+// for viewers other than the owning student. rows may be nil.
+func expectDuplicateFlags(mock pgxmock.PgxPoolIface, threadID int64, rows *pgxmock.Rows) {
+	if rows == nil {
+		rows = mock.NewRows(threadDuplicateFlagColumns)
+	}
+	mock.ExpectQuery(`FROM homework_duplicate_flag f`).
+		WithArgs(threadID).
+		WillReturnRows(rows)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/Alarion239/my239/backend/internal/homework"
 	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/hwchain"
 	"github.com/Alarion239/my239/backend/internal/live"
 	"github.com/Alarion239/my239/backend/internal/logger"
 	"github.com/Alarion239/my239/backend/internal/store"
@@ -34,7 +35,6 @@ type submitRequest struct {
 // Blocked after series.due_at.
 func SubmitAttempt(database *db.DB, hub *live.Hub, blobs objectstore.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
 		userID, ok := requireUser(w, r)
 		if !ok {
 			return
@@ -49,71 +49,110 @@ func SubmitAttempt(database *db.DB, hub *live.Hub, blobs objectstore.Store) http
 		if !httpx.DecodeJSONBody(w, r, &req) {
 			return
 		}
-		body, vErr := validateSubmitInput(req)
-		if vErr != "" {
-			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
-			return
-		}
+		submitAttempt(w, r, database, blobs, userID, subproblemID, req)
+	}
+}
 
-		q := store.New(database.Pool())
-		spCtx, err := q.GetSubproblemContext(ctx, subproblemID)
-		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "subproblem not found")
-				return
-			}
-			logger.LogErrorContext(ctx, "homework: subproblem ctx", err)
-			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
-			return
-		}
-		if !requireStudent(ctx, w, r, q, userID, spCtx.MathCenterID) {
-			return
+// submitAttempt is SubmitAttempt after the request is decoded; SubmitDraft
+// feeds it the stored draft instead.
+func submitAttempt(w http.ResponseWriter, r *http.Request, database *db.DB, blobs objectstore.Store, userID, subproblemID int64, req submitRequest) {
+	ctx := r.Context()
+	body, vErr := validateSubmitInput(req)
+	if vErr != "" {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
+		return
+	}
+
+	q := store.New(database.Pool())
+	thread, ok := openAttemptThread(w, r, q, userID, subproblemID)
+	if !ok {
+		return
+	}
+
+	// Verify every claimed object exists, matches policy, and lives
+	// under the prefix this server would have signed.
+	photos, vErr := validateAndStatPhotos(ctx, blobs, thread.ID, req.EventUUID, req.ObjectKeys)
+	if vErr != "" {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
+		return
+	}
+	if !requireStorageFor(ctx, w, r, q, userID, thread.MathCenterID, photos) {
+		return
+	}
+
+	if err := writeAttempt(ctx, database, thread.ID, req.EventUUID, homework.KindSubmitted, userID, body, photos, nil); err != nil {
+		logger.LogErrorContext(ctx, "homework: submit tx", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save submission")
+		return
+	}
+	live.Publish(ctx, database.Pool(), live.Event{CenterID: thread.MathCenterID, Kind: live.KindGrading, SeriesID: thread.SeriesID})
+	writeThreadView(ctx, w, r, database, blobs, thread.ID)
+}
+
+// openAttemptThread runs the checks every new attempt (and every draft of
+// one) goes through — student of the center, submission window open, thread
+// in a state that accepts an attempt — and returns the find-or-created
+// thread. On failure the response is already written.
+func openAttemptThread(w http.ResponseWriter, r *http.Request, q *store.Queries, userID, subproblemID int64) (store.HomeworkThread, bool) {
+	ctx := r.Context()
+	spCtx, err := q.GetSubproblemContext(ctx, subproblemID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "subproblem not found")
+			return store.HomeworkThread{}, false
 		}
-		// Submission window. Normal problems close at the series deadline; a
-		// coffin (гроб) stays open past it until its own solution is released.
-		// Appeals are NOT blocked by this (a rejection might land post-due and
-		// the student still deserves a regrade path) — see AppealGrade.
-		if homework.SubmissionClosed(spCtx.IsCoffin, spCtx.CoffinReleasedAt, spCtx.SeriesDueAt, time.Now()) {
+		logger.LogErrorContext(ctx, "homework: subproblem ctx", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return store.HomeworkThread{}, false
+	}
+	if !requireStudent(ctx, w, r, q, userID, spCtx.MathCenterID) {
+		return store.HomeworkThread{}, false
+	}
+	// Submission window. Normal problems close at the series deadline; a
+	// coffin (гроб) stays open past it until its own solution is released.
+	// Appeals are NOT blocked by this (a rejection might land post-due and
+	// the student still deserves a regrade path) — see AppealGrade. Neither
+	// is a resubmission after a rejection that did not count as an attempt
+	// (an unreadable photo): the student never got a real verdict. That
+	// exemption ends once the solution is released.
+	now := time.Now()
+	if homework.SubmissionClosed(spCtx.IsCoffin, spCtx.CoffinReleasedAt, spCtx.SeriesDueAt, now) {
+		if homework.SolutionReleased(spCtx.SolutionPublishedAt, spCtx.CoffinReleasedAt, now) {
 			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "submissions closed for this series")
-			return
+			return store.HomeworkThread{}, false
 		}
-
-		thread, err := q.FindOrCreateThread(ctx, store.FindOrCreateThreadParams{
-			StudentUserID: userID,
-			SubproblemID:  spCtx.SubproblemID,
-			SeriesID:      spCtx.SeriesID,
-			MathCenterID:  spCtx.MathCenterID,
-		})
+		exempt, err := q.RejectedWithoutAttempt(ctx, userID, spCtx.SubproblemID)
 		if err != nil {
-			logger.LogErrorContext(ctx, "homework: find-or-create thread", err)
+			logger.LogErrorContext(ctx, "homework: rejection reason for late resubmit", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
-			return
+			return store.HomeworkThread{}, false
 		}
-
-		// Submission is legal from 'ungraded' (first attempt) and from
-		// 'rejected' (resubmission). Other states (submitted, appealed,
-		// accepted) are deliberately blocked.
-		if err := homework.CanTransition(thread.CurrentStatus, homework.KindSubmitted); err != nil {
-			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, err.Error())
-			return
+		if !exempt {
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "submissions closed for this series")
+			return store.HomeworkThread{}, false
 		}
+	}
 
-		// Verify every claimed object exists, matches policy, and lives
-		// under the prefix this server would have signed.
-		photos, vErr := validateAndStatPhotos(ctx, blobs, thread.ID, req.EventUUID, req.ObjectKeys)
-		if vErr != "" {
-			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
-			return
-		}
+	thread, err := q.FindOrCreateThread(ctx, store.FindOrCreateThreadParams{
+		StudentUserID: userID,
+		SubproblemID:  spCtx.SubproblemID,
+		SeriesID:      spCtx.SeriesID,
+		MathCenterID:  spCtx.MathCenterID,
+	})
+	if err != nil {
+		logger.LogErrorContext(ctx, "homework: find-or-create thread", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return store.HomeworkThread{}, false
+	}
 
-		if err := writeAttempt(ctx, database, thread.ID, req.EventUUID, homework.KindSubmitted, userID, body, photos, nil); err != nil {
-			logger.LogErrorContext(ctx, "homework: submit tx", err)
-			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save submission")
-			return
-		}
-		live.Publish(ctx, database.Pool(), live.Event{CenterID: thread.MathCenterID, Kind: live.KindGrading, SeriesID: thread.SeriesID})
-		writeThreadView(ctx, w, r, database, blobs, thread.ID)
+	// Submission is legal from 'ungraded' (first attempt) and from
+	// 'rejected' (resubmission). Other states (submitted, appealed,
+	// accepted) are deliberately blocked.
+	if err := homework.CanTransition(thread.CurrentStatus, homework.KindSubmitted); err != nil {
+		httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, err.Error())
+		return store.HomeworkThread{}, false
 	}
+	return thread, true
 }
 
 // validateSubmitInput trims/checks the request fields shared by submit and
@@ -173,7 +212,9 @@ func validateAndStatPhotos(ctx context.Context, blobs objectstore.Store, threadI
 }
 
 // writeAttempt commits a submit-or-appeal in a single transaction:
-// AppendEvent → InsertEventPhoto × N → UpdateThreadAfter{Submit,Appeal}.
+// LockEventChain → AppendEvent → Seal → InsertEventPhoto × N → RecordBodyDigest
+// → UpdateThreadAfter{Submit,Appeal}, plus grader assignment and draft
+// cleanup for submits.
 // kind is "submitted" or "appealed"; refersTo is nil for submit, the
 // graded-event id for appeal.
 func writeAttempt(ctx context.Context, database *db.DB, threadID int64, eventUUID, kind string, actorUserID int64, body string, photos []validatedPhoto, refersTo *int64) error {
@@ -186,6 +227,10 @@ func writeAttempt(ctx context.Context, database *db.DB, threadID int64, eventUUI
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	qx := store.New(tx)
+	prev, err := qx.LockEventChain(ctx, threadID)
+	if err != nil {
+		return fmt.Errorf("lock event chain: %w", err)
+	}
 	event, err := qx.AppendEvent(ctx, store.AppendEventParams{
 		ThreadID:        threadID,
 		EventUuid:       eventUUID,
@@ -198,6 +243,9 @@ func writeAttempt(ctx context.Context, database *db.DB, threadID int64, eventUUI
 	if err != nil {
 		return fmt.Errorf("append event: %w", err)
 	}
+	if err := hwchain.Seal(ctx, qx, prev, event); err != nil {
+		return err
+	}
 	for _, p := range photos {
 		if err := qx.InsertEventPhoto(ctx, store.InsertEventPhotoParams{
 			EventID:     event.ID,
@@ -209,6 +257,13 @@ func writeAttempt(ctx context.Context, database *db.DB, threadID int64, eventUUI
 			return fmt.Errorf("insert photo %d: %w", p.Idx, err)
 		}
 	}
+	// Identical texts from other students on this subproblem are flagged for
+	// teachers; photos are compared later, once the pipeline has decoded them.
+	if digest, ok := homework.BodyDigest(body); ok {
+		if _, err := qx.RecordBodyDigest(ctx, event.ID, digest); err != nil {
+			return fmt.Errorf("record body digest: %w", err)
+		}
+	}
 	switch kind {
 	case homework.KindSubmitted:
 		if err := qx.UpdateThreadAfterSubmit(ctx, store.UpdateThreadAfterSubmitParams{
@@ -217,6 +272,17 @@ func writeAttempt(ctx context.Context, database *db.DB, threadID int64, eventUUI
 		}); err != nil {
 			return fmt.Errorf("update thread after submit: %w", err)
 		}
+		// No-op (assignee stays NULL) unless the series has an assignment
+		// mode. Appeals skip this: they stick to last_grader_user_id.
+		if _, err := qx.AssignThreadGrader(ctx, threadID); err != nil {
+			return fmt.Errorf("assign grader: %w", err)
+		}
+		// Whether or not it came from the draft, the sent attempt replaces
+		// it. Photos of a draft under another UUID are left to the orphan
+		// upload sweep.
+		if err := qx.ClearAttemptDraft(ctx, threadID); err != nil {
+			return fmt.Errorf("clear draft: %w", err)
+		}
 	case homework.KindAppealed:
 		if err := qx.UpdateThreadAfterAppeal(ctx, store.UpdateThreadAfterAppealParams{
 			ID:                    threadID,
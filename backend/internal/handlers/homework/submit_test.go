@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
 )
 
 func TestSubmit_HappyPath(t *testing.T) {
@@ -33,13 +35,16 @@ func TestSubmit_HappyPath(t *testing.T) {
 	eventUUID := "abc123"
 	key0 := "homework/thread/1/" + eventUUID + "/0.jpg"
 	_ = blobs.Put(context.Background(), key0, strings.NewReader("img-body"), 8, "image/jpeg")
+	expectStorageQuota(mock, 7, 42, nil, 0)
 
 	// Tx: AppendEvent → InsertEventPhoto → UpdateThreadAfterSubmit → Commit
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), eventUUID, "submitted", int64(7), "my solution", (*string)(nil), (*int64)(nil)).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(50), int64(1), eventUUID, "submitted", int64(7), "my solution", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(50), int64(1))
 	mock.ExpectExec(`INSERT INTO homework_thread_event_photo`).
 		WithArgs(int64(50), int32(0), key0, int64(8), "image/jpeg").
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
@@ -47,6 +52,8 @@ func TestSubmit_HappyPath(t *testing.T) {
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= 'submitted'`).
 		WithArgs(int64(1), &evID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectAssignGrader(mock, 1, nil)
+	expectDraftCleared(mock, 1)
 	mock.ExpectCommit()
 
 	// Post-mutation view fetch: GetThread → ListThreadEvents → ListEventPhotosForEvents
@@ -63,8 +70,8 @@ func TestSubmit_HappyPath(t *testing.T) {
 	expectGetUsersForView(mock)
 	mock.ExpectQuery(`SELECT .* FROM homework_thread_event_photo`).
 		WithArgs([]int64{50}).
-		WillReturnRows(mock.NewRows([]string{"event_id", "idx", "object_key", "size_bytes", "content_type", "created_at"}).
-			AddRow(int64(50), int32(0), key0, int64(8), "image/jpeg", now))
+		WillReturnRows(mock.NewRows(photoColumns).
+			AddRow(int64(50), int32(0), key0, int64(8), "image/jpeg", now, "pending", (*string)(nil), (*string)(nil)))
 
 	body, _ := json.Marshal(map[string]any{
 		"event_uuid":  eventUUID,
@@ -83,6 +90,106 @@ func TestSubmit_HappyPath(t *testing.T) {
 	}
 }
 
+// Minting checks only the bytes already stored, so the quota is enforced
+// again against what actually landed when the attempt is sent.
+func TestSubmit_PhotosPastQuotaRejected(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	now := time.Now()
+	pub := now.Add(-time.Hour)
+	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "a", now.Add(time.Hour), &pub)
+	expectStudentCheck(mock, 7, 42, true)
+	mock.ExpectQuery(`INSERT INTO homework_thread`).
+		WithArgs(int64(7), int64(900), int64(100), int64(42)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+	key0 := "homework/thread/1/abc/0.jpg"
+	_ = blobs.Put(context.Background(), key0, strings.NewReader("img-body"), 8, "image/jpeg")
+	quota := homework.MinStudentQuotaBytes
+	expectStorageQuota(mock, 7, 42, &quota, quota-4)
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "abc", "body": "mine", "object_keys": []string{key0}})
+	req := authedRequest(t, access, 7, false, http.MethodPost, "/threads/900/submit", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "quota_exceeded") {
+		t.Fatalf("got %d, want 409 quota_exceeded; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestSubmit_LongTextIsDigested(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	due := now.Add(time.Hour)
+	pub := now.Add(-time.Hour)
+	text := "Пусть n чётно, тогда n = 2k и n² = 4k², что делится на 4."
+
+	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "a", due, &pub)
+	expectStudentCheck(mock, 7, 42, true)
+	mock.ExpectQuery(`INSERT INTO homework_thread`).
+		WithArgs(int64(7), int64(900), int64(100), int64(42)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(1, 7, 900, 100, 42, now)...))
+
+	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
+	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
+		WithArgs(int64(1), "txt1", "submitted", int64(7), text, (*string)(nil), (*int64)(nil)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(50), int64(1), "txt1", "submitted", int64(7), text, (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(50), int64(1))
+	// Same text from another student on this subproblem: one flag.
+	mock.ExpectExec(`INSERT INTO homework_body_digest`).
+		WithArgs(int64(50), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	evID := int64(50)
+	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= 'submitted'`).
+		WithArgs(int64(1), &evID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectAssignGrader(mock, 1, nil)
+	expectDraftCleared(mock, 1)
+	mock.ExpectCommit()
+
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "submitted", AttemptEventID: &evID,
+		}, now)...))
+	expectGetSeriesForView(mock, 100, 42, now)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(50), int64(1), "txt1", "submitted", int64(7), text, (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectGetUsersForView(mock)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event_photo`).
+		WithArgs([]int64{50}).
+		WillReturnRows(mock.NewRows(photoColumns))
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "txt1", "body": text, "object_keys": []string{}})
+	req := authedRequest(t, access, 7, false, http.MethodPost, "/threads/900/submit", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "duplicate_flags") {
+		t.Error("duplicate flags leaked to the student")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
 func TestSubmit_AfterDueBlocks(t *testing.T) {
 	t.Parallel()
 	mock, _ := pgxmock.NewPool()
@@ -94,6 +201,7 @@ func TestSubmit_AfterDueBlocks(t *testing.T) {
 	pub := now.Add(-2 * time.Hour)
 	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "a", due, &pub)
 	expectStudentCheck(mock, 7, 42, true)
+	expectRejectedWithoutAttempt(mock, 7, 900, false)
 
 	body, _ := json.Marshal(map[string]any{
 		"event_uuid":  "abc",
@@ -109,6 +217,96 @@ func TestSubmit_AfterDueBlocks(t *testing.T) {
 	}
 }
 
+// A rejection for an unreadable photo does not count as an attempt, so the
+// student may send a readable one after the deadline.
+func TestSubmit_AfterDueAllowedAfterUncountedRejection(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	pub := now.Add(-48 * time.Hour)
+	attemptID, gradeID := int64(50), int64(60)
+	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "a", now.Add(-time.Hour), &pub)
+	expectStudentCheck(mock, 7, 42, true)
+	expectRejectedWithoutAttempt(mock, 7, 900, true)
+	mock.ExpectQuery(`INSERT INTO homework_thread`).
+		WithArgs(int64(7), int64(900), int64(100), int64(42)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "rejected", AttemptEventID: &attemptID, GradeEventID: &gradeID, LastGraderID: ptr64(3),
+		}, now)...))
+	mock.ExpectBegin()
+	expectChainLock(mock, 1)
+	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
+		WithArgs(int64(1), "abc", "submitted", int64(7), "retaken", (*string)(nil), (*int64)(nil)).
+		WillReturnRows(mock.NewRows(eventColumns).
+			AddRow(int64(70), int64(1), "abc", "submitted", int64(7), "retaken", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, 70, 1)
+	evID := int64(70)
+	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= 'submitted'`).
+		WithArgs(int64(1), &evID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectAssignGrader(mock, 1, nil)
+	expectDraftCleared(mock, 1)
+	mock.ExpectCommit()
+	mock.ExpectExec(`pg_notify`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(threadColumns).AddRow(threadRow(1, 7, 900, 100, 42, threadRowOpts{
+			Status: "submitted", AttemptEventID: &evID, GradeEventID: &gradeID, LastGraderID: ptr64(3),
+		}, now)...))
+	expectGetSeriesForView(mock, 100, 42, now)
+	mock.ExpectQuery(`SELECT .* FROM homework_thread_event\s+WHERE thread_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(mock.NewRows(eventColumns))
+	expectGetUsersForView(mock)
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "abc", "body": "retaken", "object_keys": []string{}})
+	req := authedRequest(t, access, 7, false, http.MethodPost, "/threads/900/submit", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
+
+// Once the разбор is published an uncounted rejection no longer reopens the
+// problem: the rejection reason is not even consulted.
+func TestSubmit_AfterSolutionPublishedBlocksUncountedRejection(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	pub := now.Add(-48 * time.Hour)
+	razbor := now.Add(-time.Hour)
+	mock.ExpectQuery(`FROM math_center_subproblems sp\s+JOIN math_center_problems`).
+		WithArgs(int64(900)).
+		WillReturnRows(mock.NewRows(subproblemCtxColumns).
+			AddRow(int64(900), "a", int64(500), int32(1), int64(100), int64(42), now.Add(-2*time.Hour), &pub, false, (*time.Time)(nil), &razbor))
+	expectStudentCheck(mock, 7, 42, true)
+
+	body, _ := json.Marshal(map[string]any{"event_uuid": "abc", "body": "copied", "object_keys": []string{}})
+	req := authedRequest(t, access, 7, false, http.MethodPost, "/threads/900/submit", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("got %d, want 409 (solution published)", rr.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet: %v", err)
+	}
+}
+
 // TestSubmit_ReleasedCoffinBlocks: a coffin whose solution has been released is
 // closed for submission even though it's a coffin.
 func TestSubmit_ReleasedCoffinBlocks(t *testing.T) {
@@ -153,14 +351,18 @@ func TestSubmit_OpenCoffinAllowsLateSubmit(t *testing.T) {
 
 	eventUUID := "coffin-late"
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), eventUUID, "submitted", int64(7), "late coffin try", (*string)(nil), (*int64)(nil)).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(50), int64(1), eventUUID, "submitted", int64(7), "late coffin try", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(50), int64(1))
 	evID := int64(50)
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= 'submitted'`).
 		WithArgs(int64(1), &evID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectAssignGrader(mock, 1, nil)
+	expectDraftCleared(mock, 1)
 	mock.ExpectCommit()
 
 	// Post-mutation view fetch.
@@ -177,7 +379,7 @@ func TestSubmit_OpenCoffinAllowsLateSubmit(t *testing.T) {
 	expectGetUsersForView(mock)
 	mock.ExpectQuery(`SELECT .* FROM homework_thread_event_photo`).
 		WithArgs([]int64{50}).
-		WillReturnRows(mock.NewRows([]string{"event_id", "idx", "object_key", "size_bytes", "content_type", "created_at"}))
+		WillReturnRows(mock.NewRows(photoColumns))
 
 	body, _ := json.Marshal(map[string]any{"event_uuid": eventUUID, "body": "late coffin try", "object_keys": []string{}})
 	req := authedRequest(t, access, 7, false, http.MethodPost, "/threads/900/submit", bytes.NewReader(body))
@@ -341,14 +543,18 @@ func TestSubmit_AllowedAfterRejection(t *testing.T) {
 
 	// Empty photos OK — resubmission with text-only body.
 	mock.ExpectBegin()
+	expectChainLock(mock, int64(1))
 	mock.ExpectQuery(`INSERT INTO homework_thread_event`).
 		WithArgs(int64(1), "uuid2", "submitted", int64(7), "fixed it", (*string)(nil), (*int64)(nil)).
 		WillReturnRows(mock.NewRows(eventColumns).
 			AddRow(int64(60), int64(1), "uuid2", "submitted", int64(7), "fixed it", (*string)(nil), (*int64)(nil), now, false, (*int64)(nil), "", (*int64)(nil), "", ""))
+	expectChainSeal(mock, int64(60), int64(1))
 	newAttempt := int64(60)
 	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= 'submitted'`).
 		WithArgs(int64(1), &newAttempt).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectAssignGrader(mock, 1, nil)
+	expectDraftCleared(mock, 1)
 	mock.ExpectCommit()
 	// view fetch
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
@@ -89,7 +89,7 @@ func SubproblemContext(database *db.DB) http.HandlerFunc {
 }
 
 // membership is a tiny helper used by the subproblem-context handler. The
-// existing handler helpers (`requireTeacher`, `requireStudent`) each only
+// existing handler helpers (`requireTeacherCan`, `requireStudent`) each only
 // gate one role; this returns both flags in one call.
 func membership(ctx context.Context, q *store.Queries, userID, centerID int64) (teacher, student bool, err error) {
 	teacher, err = q.IsTeacherInCenter(ctx, store.IsTeacherInCenterParams{UserID: userID, MathCenterID: centerID})
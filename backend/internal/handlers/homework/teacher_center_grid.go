@@ -47,6 +47,9 @@ type centerGridStudentEntry struct {
 	// HasStudentComment marks the student when at least one internal teacher
 	// note is attached to them.
 	HasStudentComment bool `json:"has_student_comment"`
+	// Attendance summarizes the term's lessons held so far; absent when the
+	// student had none.
+	Attendance *mc.AttendanceSummary `json:"attendance,omitempty"`
 }
 
 type centerGridSeries struct {
@@ -105,16 +108,21 @@ func GetCenterGrid(database *db.DB) http.HandlerFunc {
 		}
 
 		q := store.New(database.Pool())
-		if !requireTeacher(ctx, w, r, q, userID, centerID) {
+		access, ok := teacherAccess(ctx, w, r, q, userID, centerID, 0, mc.CanView)
+		if !ok {
 			return
 		}
 
-		termID, err := resolveCenterGridTerm(ctx, q, centerID, r.URL.Query().Get("term_id"))
+		termID, err := centerGridTermFor(ctx, q, access, centerID, r.URL.Query().Get("term_id"))
 		if err != nil {
 			if errors.Is(err, errInvalidCenterGridTerm) {
 				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid term id")
 				return
 			}
+			if errors.Is(err, mc.ErrTeacherOutsideTerm) {
+				httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, err.Error())
+				return
+			}
 			logger.LogErrorContext(ctx, "homework: center grid term", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
@@ -175,7 +183,7 @@ func GetCenterGridSeriesCells(database *db.DB) http.HandlerFunc {
 		}
 
 		q := store.New(database.Pool())
-		if !requireTeacher(ctx, w, r, q, userID, centerID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, centerID, seriesID, mc.CanView) {
 			return
 		}
 		series, err := q.GetSeries(ctx, seriesID)
@@ -259,6 +267,26 @@ func resolveCenterGridTerm(ctx context.Context, q *store.Queries, centerID int64
 	return 0, fmt.Errorf("resolve legacy term: %w", err)
 }
 
+// centerGridTermFor is resolveCenterGridTerm for a caller with access: a
+// term-scoped assistant defaults to their own term and may not ask for
+// another one.
+func centerGridTermFor(ctx context.Context, q *store.Queries, access store.TeacherAccess, centerID int64, termParam string) (int64, error) {
+	if access.TermID == nil {
+		return resolveCenterGridTerm(ctx, q, centerID, termParam)
+	}
+	if termParam == "" {
+		return *access.TermID, nil
+	}
+	termID, err := resolveCenterGridTerm(ctx, q, centerID, termParam)
+	if err != nil {
+		return 0, err
+	}
+	if termID != *access.TermID {
+		return 0, mc.ErrTeacherOutsideTerm
+	}
+	return termID, nil
+}
+
 func loadCenterGridSnapshot(ctx context.Context, pool db.Pool, centerID, termID int64) (centerGridResponse, centerGridSnapshotTimings, error) {
 	var timings centerGridSnapshotTimings
 	tx, err := pool.BeginTx(ctx, pgx.TxOptions{
@@ -302,8 +330,14 @@ func loadCenterGridSnapshot(ctx context.Context, pool db.Pool, centerID, termID
 		return centerGridResponse{}, timings, fmt.Errorf("query center grid student name colors: %w", err)
 	}
 
+	attendance, err := q.ListTermAttendance(ctx, centerID, termID, time.Now())
+	if err != nil {
+		return centerGridResponse{}, timings, fmt.Errorf("query center grid attendance: %w", err)
+	}
+
 	started = time.Now()
 	response := buildCenterGridResponse(roster, columns, cells, colors)
+	attachCenterGridAttendance(response.Groups, attendance)
 	timings.assembly = time.Since(started)
 
 	if err := tx.Commit(ctx); err != nil {
@@ -345,6 +379,24 @@ func buildCenterGridResponse(roster []store.TeacherCenterGridRosterRow, columns
 	}
 }
 
+// attachCenterGridAttendance sets each student's attendance summary.
+func attachCenterGridAttendance(groups []centerGridGroup, rows []store.TermAttendanceRow) {
+	byStudent := make(map[int64]store.AttendanceCounts, len(rows))
+	for _, row := range rows {
+		byStudent[row.StudentUserID] = row.AttendanceCounts
+	}
+	for gi := range groups {
+		for si := range groups[gi].Students {
+			counts, ok := byStudent[groups[gi].Students[si].UserID]
+			if !ok || counts.Lessons == 0 {
+				continue
+			}
+			summary := mc.SummarizeAttendance(counts)
+			groups[gi].Students[si].Attendance = &summary
+		}
+	}
+}
+
 func buildCenterGridCells(rows []store.TeacherCenterGridCellRow) (map[string]centerGridCell, map[int64]string) {
 	cells := make(map[string]centerGridCell, len(rows))
 	graders := make(map[int64]string)
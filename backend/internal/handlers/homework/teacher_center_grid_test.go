@@ -33,7 +33,7 @@ func TestGetCenterGrid_HappyPath(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	now := time.Now()
 	due := now.Add(time.Hour)
@@ -68,6 +68,10 @@ func TestGetCenterGrid_HappyPath(t *testing.T) {
 	mock.ExpectQuery(`FROM math_center_student_name_color`).
 		WithArgs(int64(42)).
 		WillReturnRows(mock.NewRows([]string{"student_user_id", "background_hex"}))
+	mock.ExpectQuery(`FROM math_center_students s\s+JOIN math_center_lessons l`).
+		WithArgs(int64(42), int64(5), pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"user_id", "lessons", "present", "late", "absent", "excused"}).
+			AddRow(int64(7), int64(4), int64(2), int64(1), int64(1), int64(0)))
 	mock.ExpectCommit()
 
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/grid?term_id=5", nil)
@@ -81,8 +85,12 @@ func TestGetCenterGrid_HappyPath(t *testing.T) {
 			GroupID  int64  `json:"group_id"`
 			Name     string `json:"name"`
 			Students []struct {
-				UserID int64  `json:"user_id"`
-				Name   string `json:"name"`
+				UserID     int64  `json:"user_id"`
+				Name       string `json:"name"`
+				Attendance *struct {
+					Lessons int64    `json:"lessons"`
+					Rate    *float64 `json:"rate"`
+				} `json:"attendance"`
 			} `json:"students"`
 		} `json:"groups"`
 		Series []struct {
@@ -109,6 +117,9 @@ func TestGetCenterGrid_HappyPath(t *testing.T) {
 	if len(resp.Groups[0].Students) != 1 || resp.Groups[0].Students[0].Name != "Иванова Аня" {
 		t.Fatalf("students: %+v", resp.Groups[0].Students)
 	}
+	if a := resp.Groups[0].Students[0].Attendance; a == nil || a.Lessons != 4 || a.Rate == nil || *a.Rate != 0.75 {
+		t.Errorf("attendance: %+v", a)
+	}
 	if len(resp.Series) != 2 {
 		t.Fatalf("series count: got %d, want 2", len(resp.Series))
 	}
@@ -151,7 +162,7 @@ func TestGetCenterGrid_NonTeacherForbidden(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, false)
+	expectTeacherAccess(mock, 3, 42, "")
 
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/grid", nil)
 	rr := httptest.NewRecorder()
@@ -177,7 +188,7 @@ func TestGetCenterGrid_RollsBackAfterSnapshotStageFailure(t *testing.T) {
 			mock, _ := pgxmock.NewPool()
 			defer mock.Close()
 			r, access, _ := newRouter(t, mock)
-			expectTeacherCheck(mock, 3, 42, true)
+			expectTeacherAccess(mock, 3, 42, "teacher")
 			mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
 			if stage.name == "columns" {
 				mock.ExpectQuery(`FROM math_center_groups g`).
@@ -215,7 +226,7 @@ func TestGetCenterGridSeriesCells_Empty(t *testing.T) {
 	mock, _ := pgxmock.NewPool()
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	now := time.Now()
 	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
 		WithArgs(int64(100)).
@@ -252,7 +263,7 @@ func TestGetCenterGridSeriesCells_WrongCenterNotFound(t *testing.T) {
 	mock, _ := pgxmock.NewPool()
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	now := time.Now()
 	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
 		WithArgs(int64(100)).
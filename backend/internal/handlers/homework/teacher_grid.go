@@ -101,7 +101,7 @@ func TeacherGrid(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, series.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanView) {
 			return
 		}
 
@@ -31,7 +31,7 @@ func TestTeacherGrid_HappyPath(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	// 2 students × 2 subproblems = 4 rows. Student A has submitted task 1a;
 	// everything else is ungraded.
@@ -134,7 +134,7 @@ func TestTeacherGrid_NonTeacherForbidden(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	expectTeacherCheck(mock, 3, 42, false)
+	expectTeacherAccess(mock, 3, 42, "")
 
 	req := authedRequest(t, access, 3, false, http.MethodGet, "/series/100/grid", nil)
 	rr := httptest.NewRecorder()
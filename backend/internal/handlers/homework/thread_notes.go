@@ -19,7 +19,7 @@ import (
 
 // threadNoteView is the wire shape for one internal note on a solution thread.
 // The body is teacher-only and is NEVER included in the student-visible
-// ThreadView; it surfaces only through these requireTeacher endpoints.
+// ThreadView; it surfaces only through these teacher endpoints.
 type threadNoteView struct {
 	ID           int64     `json:"id"`
 	AuthorUserID int64     `json:"author_user_id"`
@@ -65,7 +65,7 @@ func ListThreadNotes(database *db.DB) http.HandlerFunc {
 		if !ok {
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, thread.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanView) {
 			return
 		}
 		rows, err := q.ListThreadNotesAuthored(ctx, threadID)
@@ -116,7 +116,7 @@ func CreateThreadNote(database *db.DB) http.HandlerFunc {
 		if !ok {
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, thread.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanGrade) {
 			return
 		}
 		note, err := q.CreateThreadNote(ctx, store.CreateThreadNoteParams{
@@ -259,7 +259,7 @@ func authorizeThreadNoteWrite(ctx context.Context, w http.ResponseWriter, r *htt
 	if !ok {
 		return store.HomeworkThread{}, false
 	}
-	if !requireTeacher(ctx, w, r, q, userID, thread.MathCenterID) {
+	if !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanGrade) {
 		return store.HomeworkThread{}, false
 	}
 	if !callerIsAdmin(r) && note.AuthorUserID != userID {
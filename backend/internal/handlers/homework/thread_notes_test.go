@@ -36,7 +36,7 @@ func TestCreateThreadNote_TeacherSucceeds(t *testing.T) {
 	now := time.Now()
 
 	expectGetThread(mock, 1, 42, now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`INSERT INTO homework_thread_note`).
 		WithArgs(int64(1), int64(3), "suspicious — identical to neighbour").
 		WillReturnRows(mock.NewRows(threadNoteColumns).
@@ -91,7 +91,7 @@ func TestCreateThreadNote_RejectsNonTeacher(t *testing.T) {
 	now := time.Now()
 
 	expectGetThread(mock, 1, 42, now)
-	expectTeacherCheck(mock, 9, 42, false)
+	expectTeacherAccess(mock, 9, 42, "")
 
 	body, _ := json.Marshal(map[string]any{"body": "note"})
 	req := authedRequest(t, access, 9, false, http.MethodPost, "/threads/by-id/1/notes", bytes.NewReader(body))
@@ -112,7 +112,7 @@ func TestListThreadNotes_TeacherSucceeds(t *testing.T) {
 	now := time.Now()
 
 	expectGetThread(mock, 1, 42, now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`FROM homework_thread_note n\s+JOIN users u .* WHERE n.thread_id`).
 		WithArgs(int64(1)).
 		WillReturnRows(mock.NewRows(threadNoteAuthoredColumns).
@@ -147,7 +147,7 @@ func TestUpdateThreadNote_RejectsNonAuthor(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadNoteColumns).
 			AddRow(int64(500), int64(1), int64(4), "original", now, now))
 	expectGetThread(mock, 1, 42, now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	body, _ := json.Marshal(map[string]any{"body": "rewrite"})
 	req := authedRequest(t, access, 3, false, http.MethodPatch, "/threads/by-id/1/notes/500", bytes.NewReader(body))
@@ -172,7 +172,7 @@ func TestDeleteThreadNote_AuthorSucceeds(t *testing.T) {
 		WillReturnRows(mock.NewRows(threadNoteColumns).
 			AddRow(int64(500), int64(1), int64(3), "mine", now, now))
 	expectGetThread(mock, 1, 42, now)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectExec(`DELETE FROM homework_thread_note WHERE id`).
 		WithArgs(int64(500)).
 		WillReturnResult(pgxmock.NewResult("DELETE", 1))
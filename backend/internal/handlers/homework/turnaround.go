@@ -0,0 +1,291 @@
+package homework
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// overdueLimit caps the overdue listing; a center that far behind needs the
+// count more than the tail of the list.
+const overdueLimit = 500
+
+// slaConfig is the wire shape of GET/PUT /centers/{centerID}/sla.
+type slaConfig struct {
+	Hours int `json:"hours"`
+}
+
+// durationStats is one duration (in seconds) over a set of verdicts. The
+// figures are nil when no verdict has it.
+type durationStats struct {
+	Count int64    `json:"count"`
+	Avg   *float64 `json:"avg_seconds"`
+	P50   *float64 `json:"p50_seconds"`
+	P90   *float64 `json:"p90_seconds"`
+}
+
+func toDurationStats(s store.DurationStats) durationStats {
+	return durationStats{Count: s.Count, Avg: s.Avg, P50: s.P50, P90: s.P90}
+}
+
+// seriesTurnaround and graderTurnaround split the term's verdicts two ways.
+// ClaimWait is submitted → first claim; Turnaround is submitted → verdict.
+type seriesTurnaround struct {
+	SeriesID    int64         `json:"series_id"`
+	DisplayName string        `json:"display_name"`
+	ClaimWait   durationStats `json:"claim_wait"`
+	Turnaround  durationStats `json:"turnaround"`
+}
+
+type graderTurnaround struct {
+	GraderUserID    int64         `json:"grader_user_id"`
+	GraderFirstName string        `json:"grader_first_name"`
+	GraderLastName  string        `json:"grader_last_name"`
+	ClaimWait       durationStats `json:"claim_wait"`
+	Turnaround      durationStats `json:"turnaround"`
+}
+
+type turnaroundReport struct {
+	TermID   int64              `json:"term_id"`
+	SLAHours int                `json:"sla_hours"`
+	Series   []seriesTurnaround `json:"series"`
+	Graders  []graderTurnaround `json:"graders"`
+}
+
+// overdueThread is one submission or appeal waiting past the SLA.
+type overdueThread struct {
+	ThreadID          int64      `json:"thread_id"`
+	SeriesID          int64      `json:"series_id"`
+	SeriesDisplay     string     `json:"series_display"`
+	Display           string     `json:"display"`
+	StudentUserID     int64      `json:"student_user_id"`
+	StudentName       string     `json:"student_name"`
+	CurrentStatus     string     `json:"current_status"`
+	SubmittedAt       time.Time  `json:"submitted_at"`
+	WaitingSeconds    float64    `json:"waiting_seconds"`
+	ClaimedAt         *time.Time `json:"claimed_at,omitempty"`
+	ClaimHolderUserID *int64     `json:"claim_holder_user_id,omitempty"`
+	ClaimExpiresAt    *time.Time `json:"claim_expires_at,omitempty"`
+}
+
+type overdueResponse struct {
+	SLAHours int             `json:"sla_hours"`
+	Cutoff   time.Time       `json:"cutoff"`
+	Threads  []overdueThread `json:"threads"`
+}
+
+// centerSLAHours returns the center's SLA, or the default when unset.
+func centerSLAHours(ctx context.Context, q *store.Queries, centerID int64) (int, error) {
+	row, err := q.GetSLAConfig(ctx, centerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return homework.DefaultSLAHours, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(row.Hours), nil
+}
+
+// GetSLAConfig — teacher of the center. Returns the grading SLA in hours.
+func GetSLAConfig(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanView) {
+			return
+		}
+		hours, err := centerSLAHours(ctx, q, centerID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: get sla config", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, slaConfig{Hours: hours})
+	}
+}
+
+// PutSLAConfig — head teacher of the center. Sets how long a submission may
+// wait for a verdict before it is listed as overdue.
+func PutSLAConfig(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		var req slaConfig
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if err := homework.ValidateSLAHours(req.Hours); err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, err.Error())
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireHeadTeacher(ctx, w, r, q, userID, centerID) {
+			return
+		}
+		row, err := q.UpsertSLAConfig(ctx, centerID, int32(req.Hours))
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: upsert sla config", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save sla config")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, slaConfig{Hours: int(row.Hours)})
+	}
+}
+
+// ListOverdueThreads — head teacher of the center. Submissions and appeals
+// that have waited for a verdict longer than the center's SLA, oldest first,
+// at most overdueLimit of them.
+func ListOverdueThreads(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireHeadTeacher(ctx, w, r, q, userID, centerID) {
+			return
+		}
+		hours, err := centerSLAHours(ctx, q, centerID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: sla for overdue", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		now := time.Now()
+		cutoff := now.Add(-time.Duration(hours) * time.Hour)
+		rows, err := q.ListOverdueThreads(ctx, store.ListOverdueThreadsParams{
+			MathCenterID:    centerID,
+			SubmittedBefore: cutoff,
+			Limit:           overdueLimit,
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: list overdue threads", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		out := overdueResponse{SLAHours: hours, Cutoff: cutoff, Threads: make([]overdueThread, 0, len(rows))}
+		for _, row := range rows {
+			out.Threads = append(out.Threads, overdueThread{
+				ThreadID:          row.ThreadID,
+				SeriesID:          row.SeriesID,
+				SeriesDisplay:     mc.SeriesDisplayName(int(row.SeriesNumber), row.SeriesName),
+				Display:           mc.SubproblemDisplayName(int(row.ProblemNumber), row.SubproblemLabel),
+				StudentUserID:     row.StudentUserID,
+				StudentName:       mc.StudentDisplayName(row.StudentFirstName, row.StudentLastName),
+				CurrentStatus:     row.CurrentStatus,
+				SubmittedAt:       row.SubmittedAt,
+				WaitingSeconds:    now.Sub(row.SubmittedAt).Seconds(),
+				ClaimedAt:         row.ClaimedAt,
+				ClaimHolderUserID: row.ClaimHolderUserID,
+				ClaimExpiresAt:    row.ClaimExpiresAt,
+			})
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// TurnaroundReport — head teacher of the center. Per-series and per-grader
+// waits for one term (?term_id=, default the active one): submitted → first
+// claim and submitted → verdict, as count / average / median / p90.
+func TurnaroundReport(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireHeadTeacher(ctx, w, r, q, userID, centerID) {
+			return
+		}
+		termID, ok := resolveReportTerm(ctx, w, r, q, centerID)
+		if !ok {
+			return
+		}
+		hours, err := centerSLAHours(ctx, q, centerID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: sla for turnaround", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		args := store.TurnaroundParams{MathCenterID: centerID, TermID: termID}
+		seriesRows, err := q.SeriesTurnaround(ctx, args)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: series turnaround", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		graderRows, err := q.GraderTurnaround(ctx, args)
+		if err != nil {
+			logger.LogErrorContext(ctx, "homework: grader turnaround", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		out := turnaroundReport{
+			TermID:   termID,
+			SLAHours: hours,
+			Series:   make([]seriesTurnaround, 0, len(seriesRows)),
+			Graders:  make([]graderTurnaround, 0, len(graderRows)),
+		}
+		for _, row := range seriesRows {
+			out.Series = append(out.Series, seriesTurnaround{
+				SeriesID:    row.SeriesID,
+				DisplayName: mc.SeriesDisplayName(int(row.SeriesNumber), row.SeriesName),
+				ClaimWait:   toDurationStats(row.ClaimWait),
+				Turnaround:  toDurationStats(row.Turnaround),
+			})
+		}
+		for _, row := range graderRows {
+			out.Graders = append(out.Graders, graderTurnaround{
+				GraderUserID:    row.GraderUserID,
+				GraderFirstName: row.GraderFirstName,
+				GraderLastName:  row.GraderLastName,
+				ClaimWait:       toDurationStats(row.ClaimWait),
+				Turnaround:      toDurationStats(row.Turnaround),
+			})
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
@@ -0,0 +1,197 @@
+package homework_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var durationStatsColumns = []string{
+	"claim_count", "claim_avg", "claim_p50", "claim_p90",
+	"turnaround_count", "turnaround_avg", "turnaround_p50", "turnaround_p90",
+}
+
+func ptrFloat(f float64) *float64 { return &f }
+
+func TestGetSLAConfig_DefaultsWhenUnset(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectQuery(`FROM homework_sla_config`).
+		WithArgs(int64(42)).
+		WillReturnError(pgx.ErrNoRows)
+
+	req := authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/sla", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Hours int `json:"hours"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Hours != 72 {
+		t.Errorf("hours = %d; want the 72h default", resp.Hours)
+	}
+}
+
+func TestPutSLAConfig_RejectsOutOfRange(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	body, _ := json.Marshal(map[string]any{"hours": 0})
+	req := authedRequest(t, access, 3, false, http.MethodPut, "/centers/42/sla", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestListOverdueThreads_UsesCenterSLA(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	expectHeadTeacherCheck(mock, 1, 42, true)
+	mock.ExpectQuery(`FROM homework_sla_config`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows([]string{"math_center_id", "hours", "updated_at"}).
+			AddRow(int64(42), int32(24), now))
+	mock.ExpectQuery(`AND th.current_status IN \('submitted', 'appealed'\)\s+AND a.created_at < \$2`).
+		WithArgs(int64(42), pgxmock.AnyArg(), int32(500)).
+		WillReturnRows(mock.NewRows([]string{
+			"id", "series_id", "number", "name", "problem_number", "label", "user_id",
+			"first_name", "last_name", "current_status", "submitted_at", "claimed_at",
+			"claim_holder_user_id", "claim_expires_at",
+		}).AddRow(int64(1), int64(100), int32(3), "Графы", int32(2), "а", int64(7),
+			"Иван", "Иванов", "submitted", now.Add(-50*time.Hour), (*time.Time)(nil),
+			(*int64)(nil), (*time.Time)(nil)))
+
+	req := authedRequest(t, access, 1, false, http.MethodGet, "/centers/42/sla/overdue", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		SLAHours int       `json:"sla_hours"`
+		Cutoff   time.Time `json:"cutoff"`
+		Threads  []struct {
+			Display        string  `json:"display"`
+			StudentName    string  `json:"student_name"`
+			WaitingSeconds float64 `json:"waiting_seconds"`
+		} `json:"threads"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.SLAHours != 24 || len(resp.Threads) != 1 {
+		t.Fatalf("got %+v", resp)
+	}
+	if d := now.Sub(resp.Cutoff); d < 23*time.Hour || d > 25*time.Hour {
+		t.Errorf("cutoff %v is not 24h before now", resp.Cutoff)
+	}
+	if resp.Threads[0].StudentName != "Иванов Иван" || resp.Threads[0].WaitingSeconds < 50*3600 {
+		t.Errorf("thread = %+v", resp.Threads[0])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestListOverdueThreads_RequiresHeadTeacher(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectHeadTeacherCheck(mock, 3, 42, false)
+	req := authedRequest(t, access, 3, false, http.MethodGet, "/centers/42/sla/overdue", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestTurnaroundReport_PerSeriesAndGrader(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	expectHeadTeacherCheck(mock, 1, 42, true)
+	mock.ExpectQuery(`FROM math_center_terms\s+WHERE math_center_id = \$1\s+AND is_active`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows([]string{"id", "math_center_id", "kind", "grade", "is_active", "created_at", "archived_at"}).
+			AddRow(int64(9), int64(42), "academic", ptrInt32(9), true, now, (*time.Time)(nil)))
+	mock.ExpectQuery(`FROM homework_sla_config`).
+		WithArgs(int64(42)).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectQuery(`WITH verdicts AS .* JOIN math_center_series se ON se.id = v.series_id`).
+		WithArgs(int64(42), int64(9)).
+		WillReturnRows(mock.NewRows(append([]string{"id", "number", "name"}, durationStatsColumns...)).
+			AddRow(int64(100), int32(1), "Алгебра",
+				int64(0), (*float64)(nil), (*float64)(nil), (*float64)(nil),
+				int64(4), ptrFloat(7200), ptrFloat(3600), ptrFloat(18000)))
+	mock.ExpectQuery(`WITH verdicts AS .* JOIN users u ON u.id = v.grader_user_id`).
+		WithArgs(int64(42), int64(9)).
+		WillReturnRows(mock.NewRows(append([]string{"id", "first_name", "last_name"}, durationStatsColumns...)).
+			AddRow(int64(3), "Анна", "Смирнова",
+				int64(2), ptrFloat(600), ptrFloat(600), ptrFloat(900),
+				int64(4), ptrFloat(7200), ptrFloat(3600), ptrFloat(18000)))
+
+	req := authedRequest(t, access, 1, false, http.MethodGet, "/centers/42/turnaround", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		TermID   int64 `json:"term_id"`
+		SLAHours int   `json:"sla_hours"`
+		Series   []struct {
+			DisplayName string `json:"display_name"`
+			ClaimWait   struct {
+				Count int64    `json:"count"`
+				Avg   *float64 `json:"avg_seconds"`
+			} `json:"claim_wait"`
+			Turnaround struct {
+				P90 *float64 `json:"p90_seconds"`
+			} `json:"turnaround"`
+		} `json:"series"`
+		Graders []struct {
+			GraderUserID int64 `json:"grader_user_id"`
+		} `json:"graders"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.TermID != 9 || resp.SLAHours != 72 || len(resp.Series) != 1 || len(resp.Graders) != 1 {
+		t.Fatalf("got %+v", resp)
+	}
+	s := resp.Series[0]
+	if s.DisplayName != "Серия 1. Алгебра" || s.ClaimWait.Count != 0 || s.ClaimWait.Avg != nil {
+		t.Errorf("series = %+v", s)
+	}
+	if s.Turnaround.P90 == nil || *s.Turnaround.P90 != 18000 {
+		t.Errorf("p90 = %v; want 18000", s.Turnaround.P90)
+	}
+}
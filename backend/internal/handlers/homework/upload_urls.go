@@ -3,6 +3,7 @@ package homework
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/Alarion239/my239/backend/internal/homework"
 	"github.com/Alarion239/my239/backend/internal/httpx"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 	"github.com/Alarion239/my239/backend/pkg/objectstore"
@@ -42,11 +44,12 @@ type uploadURLsResponse struct {
 }
 
 // IssueStudentUploadURLs — student of the subproblem's center. Used before
-// /submit (first attempt or resubmission) and /appeal. Mints one presigned
-// PUT URL per requested content_type. We don't reveal the bucket name to
-// the client; the URL host is the bucket's (browser-reachable) host and the
-// URL is signed for the exact ContentType the client commits to, so the
-// browser PUT must include that header verbatim.
+// /submit (first attempt or resubmission) and /appeal. Refused with 409
+// quota_exceeded once the student's photos fill the center's quota. Mints
+// one presigned PUT URL per requested content_type. We don't reveal the
+// bucket name to the client; the URL host is the bucket's (browser-
+// reachable) host and the URL is signed for the exact ContentType the
+// client commits to, so the browser PUT must include that header verbatim.
 func IssueStudentUploadURLs(database *db.DB, blobs objectstore.Store, uploadTTL time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -83,6 +86,9 @@ func IssueStudentUploadURLs(database *db.DB, blobs objectstore.Store, uploadTTL
 		if !requireStudent(ctx, w, r, q, userID, spCtx.MathCenterID) {
 			return
 		}
+		if !requireStorageLeft(ctx, w, r, q, userID, spCtx.MathCenterID) {
+			return
+		}
 
 		thread, err := q.FindOrCreateThread(ctx, store.FindOrCreateThreadParams{
 			StudentUserID: userID,
@@ -108,7 +114,8 @@ func IssueStudentUploadURLs(database *db.DB, blobs objectstore.Store, uploadTTL
 
 // IssueGraderUploadURLs — teacher of the thread's center. Used before
 // /grade when the grader wants to attach a comment photo (e.g. annotated
-// geometry diagram). Same minting logic; different auth.
+// geometry diagram). Same minting logic; different auth, and no storage
+// quota: a full student quota must never stop a grader from answering.
 func IssueGraderUploadURLs(database *db.DB, blobs objectstore.Store, uploadTTL time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -142,7 +149,7 @@ func IssueGraderUploadURLs(database *db.DB, blobs objectstore.Store, uploadTTL t
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, thread.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, thread.MathCenterID, thread.SeriesID, mc.CanGrade) {
 			return
 		}
 
@@ -156,6 +163,77 @@ func IssueGraderUploadURLs(database *db.DB, blobs objectstore.Store, uploadTTL t
 	}
 }
 
+// centerStudentQuota returns the center's per-student storage quota, or the
+// default when unset.
+func centerStudentQuota(ctx context.Context, q *store.Queries, centerID int64) (int64, error) {
+	row, err := q.GetStorageQuota(ctx, centerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return homework.DefaultStudentQuotaBytes, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return row.BytesPerStudent, nil
+}
+
+// requireStorageLeft writes 409 quota_exceeded and returns false when the
+// student's own photos in the center already reach the quota.
+func requireStorageLeft(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID int64) bool {
+	used, quota, ok := studentStorage(ctx, w, r, q, userID, centerID)
+	if !ok {
+		return false
+	}
+	if homework.QuotaExceeded(used, quota) {
+		httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeQuotaExceeded,
+			fmt.Sprintf("photo storage quota used up (%d of %d bytes)", used, quota))
+		return false
+	}
+	return true
+}
+
+// requireStorageFor writes 409 quota_exceeded and returns false when
+// attaching the landed photos would take the student past the quota. The
+// minting check cannot stop a student from asking for many batches while
+// under it; this one runs against the sizes Stat reported. Rejected uploads
+// are left to the housekeeping sweeper like any other unfinalized upload.
+func requireStorageFor(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID int64, photos []validatedPhoto) bool {
+	if len(photos) == 0 {
+		return true
+	}
+	var incoming int64
+	for _, p := range photos {
+		incoming += p.Size
+	}
+	used, quota, ok := studentStorage(ctx, w, r, q, userID, centerID)
+	if !ok {
+		return false
+	}
+	if homework.UploadExceedsQuota(used, incoming, quota) {
+		httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeQuotaExceeded,
+			fmt.Sprintf("photos do not fit the storage quota (%d + %d of %d bytes)", used, incoming, quota))
+		return false
+	}
+	return true
+}
+
+// studentStorage returns the bytes the student's own photos in the center
+// take and the center's quota. On failure the response is already written.
+func studentStorage(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID int64) (int64, int64, bool) {
+	quota, err := centerStudentQuota(ctx, q, centerID)
+	if err != nil {
+		logger.LogErrorContext(ctx, "homework: get storage quota", err, "center_id", centerID)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return 0, 0, false
+	}
+	used, err := q.StudentStorageBytes(ctx, userID, centerID)
+	if err != nil {
+		logger.LogErrorContext(ctx, "homework: student storage bytes", err, "center_id", centerID)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return 0, 0, false
+	}
+	return used, quota, true
+}
+
 // mintSlots is the shared body of both upload-url handlers: allocate one
 // event UUID, derive a key for each content type via the canonical layout
 // (so finalize handlers can recompute and verify the prefix), and presign
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
 )
 
 func TestIssueStudentUploadURLs_HappyPath(t *testing.T) {
@@ -22,6 +24,7 @@ func TestIssueStudentUploadURLs_HappyPath(t *testing.T) {
 	pub := now.Add(-time.Hour)
 	expectSubproblemContext(mock, 900, 500, 100, 42, 1, "a", now.Add(time.Hour), &pub)
 	expectStudentCheck(mock, 7, 42, true)
+	expectStorageQuota(mock, 7, 42, nil, 0)
 	// FindOrCreateThread upsert returns the row.
 	mock.ExpectQuery(`INSERT INTO homework_thread`).
 		WithArgs(int64(7), int64(900), int64(100), int64(42)).
@@ -123,6 +126,43 @@ func TestIssueStudentUploadURLs_NotStudentForbidden(t *testing.T) {
 	}
 }
 
+func TestIssueStudentUploadURLs_QuotaExceeded(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name  string
+		quota *int64
+		used  int64
+	}{
+		{"default quota", nil, homework.DefaultStudentQuotaBytes},
+		{"center quota", ptr64(homework.MinStudentQuotaBytes), homework.MinStudentQuotaBytes + 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mock, _ := pgxmock.NewPool()
+			defer mock.Close()
+			r, access, _ := newRouter(t, mock)
+
+			now := time.Now()
+			pub := now.Add(-time.Hour)
+			expectSubproblemContext(mock, 900, 500, 100, 42, 1, "a", now.Add(time.Hour), &pub)
+			expectStudentCheck(mock, 7, 42, true)
+			expectStorageQuota(mock, 7, 42, tc.quota, tc.used)
+
+			body, _ := json.Marshal(map[string]any{"content_types": []string{"image/jpeg"}})
+			req := authedRequest(t, access, 7, false, http.MethodPost, "/threads/900/upload-urls", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "quota_exceeded") {
+				t.Fatalf("got %d %s, want 409 quota_exceeded", rr.Code, rr.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
 func TestIssueGraderUploadURLs_HappyPath(t *testing.T) {
 	t.Parallel()
 	mock, _ := pgxmock.NewPool()
@@ -133,7 +173,9 @@ func TestIssueGraderUploadURLs_HappyPath(t *testing.T) {
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
 		WithArgs(int64(5)).
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(5, 7, 900, 100, 42, now)...))
-	expectTeacherCheck(mock, 3, 42, true)
+	// No storage quota lookups: graders attach photos whatever the
+	// student's usage; pgxmock fails on any unexpected query.
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	body, _ := json.Marshal(map[string]any{"content_types": []string{"image/png"}})
 	req := authedRequest(t, access, 3, false, http.MethodPost, "/threads/by-id/5/upload-urls", bytes.NewReader(body))
@@ -155,7 +197,7 @@ func TestIssueGraderUploadURLs_NonTeacherForbidden(t *testing.T) {
 	mock.ExpectQuery(`SELECT .* FROM homework_thread\s+WHERE id`).
 		WithArgs(int64(5)).
 		WillReturnRows(mock.NewRows(threadColumns).AddRow(emptyThreadRow(5, 7, 900, 100, 42, now)...))
-	expectTeacherCheck(mock, 3, 42, false)
+	expectTeacherAccess(mock, 3, 42, "")
 
 	body, _ := json.Marshal(map[string]any{"content_types": []string{"image/png"}})
 	req := authedRequest(t, access, 3, false, http.MethodPost, "/threads/by-id/5/upload-urls", bytes.NewReader(body))
@@ -93,6 +93,8 @@ type coffinActionView struct {
 	HasSolutionTex      bool       `json:"has_solution_tex"`
 	HasSolutionPDF      bool       `json:"has_solution_pdf"`
 	SolutionLink        *string    `json:"solution_link,omitempty"`
+	// TexDiagnostics is set only in the response to a TeX save.
+	TexDiagnostics []mc.TexDiagnostic `json:"tex_diagnostics,omitempty"`
 }
 
 func toCoffinActionView(s store.MathCenterSubproblemSolution) coffinActionView {
@@ -304,7 +306,8 @@ type coffinQueueItem struct {
 }
 
 // ListCoffinQueue — teacher of the center. The center-wide coffin grading queue:
-// submissions/appeals on coffin subproblems available to grade.
+// submissions/appeals on coffin subproblems available to grade, limited to
+// the term of a term-scoped assistant.
 func ListCoffinQueue(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -318,11 +321,12 @@ func ListCoffinQueue(database *db.DB) http.HandlerFunc {
 			return
 		}
 		q := store.New(database.Pool())
-		if !requireTeacher(ctx, w, r, q, userID, centerID) {
+		access, ok := teacherAccess(ctx, w, r, q, userID, centerID, 0, mc.CanView)
+		if !ok {
 			return
 		}
 		rows, err := q.ListCoffinQueueForCenter(ctx, store.ListCoffinQueueForCenterParams{
-			MathCenterID: centerID, CallerUserID: userID,
+			MathCenterID: centerID, CallerUserID: userID, TermID: access.TermID,
 		})
 		if err != nil {
 			logger.LogErrorContext(ctx, "coffins: queue", err)
@@ -376,7 +380,7 @@ func loadSubproblemForWrite(ctx context.Context, w http.ResponseWriter, r *http.
 		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 		return store.GetSubproblemSolutionCenterRow{}, false
 	}
-	if !requireTeacher(ctx, w, r, q, userID, sc.MathCenterID) {
+	if !requireTeacherCan(ctx, w, r, q, userID, sc.MathCenterID, mc.CanEditContent) {
 		return store.GetSubproblemSolutionCenterRow{}, false
 	}
 	return sc, true
@@ -384,27 +388,28 @@ func loadSubproblemForWrite(ctx context.Context, w http.ResponseWriter, r *http.
 
 // loadSubproblemForRead resolves a subproblem + its solution row and authorizes
 // any center member, also reporting whether the caller is a teacher (so reads
-// can gate students on release) and the series deadline. Writes 404/403/500.
-func loadSubproblemForRead(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, subproblemID int64) (store.MathCenterSubproblemSolution, time.Time, bool, razborAccess, bool) {
+// can gate students on release) and its center row, which carries the series
+// deadline. Writes 404/403/500.
+func loadSubproblemForRead(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, subproblemID int64) (store.MathCenterSubproblemSolution, store.GetSubproblemSolutionCenterRow, bool, razborAccess, bool) {
 	sc, err := q.GetSubproblemSolutionCenter(ctx, subproblemID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "subproblem not found")
-			return store.MathCenterSubproblemSolution{}, time.Time{}, false, razborAccess{}, false
+			return store.MathCenterSubproblemSolution{}, store.GetSubproblemSolutionCenterRow{}, false, razborAccess{}, false
 		}
 		logger.LogErrorContext(ctx, "coffins: subproblem center", err)
 		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
-		return store.MathCenterSubproblemSolution{}, time.Time{}, false, razborAccess{}, false
+		return store.MathCenterSubproblemSolution{}, store.GetSubproblemSolutionCenterRow{}, false, razborAccess{}, false
 	}
 	isTeacher, isStudent, err := membership(ctx, r, q, userID, sc.MathCenterID)
 	if err != nil {
 		logger.LogErrorContext(ctx, "coffins: membership", err)
 		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
-		return store.MathCenterSubproblemSolution{}, time.Time{}, false, razborAccess{}, false
+		return store.MathCenterSubproblemSolution{}, store.GetSubproblemSolutionCenterRow{}, false, razborAccess{}, false
 	}
 	if !isTeacher && !isStudent {
 		httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "no access to this subproblem")
-		return store.MathCenterSubproblemSolution{}, time.Time{}, false, razborAccess{}, false
+		return store.MathCenterSubproblemSolution{}, store.GetSubproblemSolutionCenterRow{}, false, razborAccess{}, false
 	}
 	access := razborAccess{Video: true, PDFTex: true}
 	if isStudent && !isTeacher {
@@ -415,7 +420,7 @@ func loadSubproblemForRead(ctx context.Context, w http.ResponseWriter, r *http.R
 		if err != nil {
 			logger.LogErrorContext(ctx, "coffins: razbor access", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
-			return store.MathCenterSubproblemSolution{}, time.Time{}, false, razborAccess{}, false
+			return store.MathCenterSubproblemSolution{}, store.GetSubproblemSolutionCenterRow{}, false, razborAccess{}, false
 		}
 		access = razborAccess{Video: accessRow.CanViewVideo, PDFTex: accessRow.CanViewPdfTex}
 	}
@@ -423,16 +428,16 @@ func loadSubproblemForRead(ctx context.Context, w http.ResponseWriter, r *http.R
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "no разбор uploaded yet")
-			return store.MathCenterSubproblemSolution{}, time.Time{}, false, razborAccess{}, false
+			return store.MathCenterSubproblemSolution{}, store.GetSubproblemSolutionCenterRow{}, false, razborAccess{}, false
 		}
 		logger.LogErrorContext(ctx, "coffins: get solution", err)
 		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
-		return store.MathCenterSubproblemSolution{}, time.Time{}, false, razborAccess{}, false
+		return store.MathCenterSubproblemSolution{}, store.GetSubproblemSolutionCenterRow{}, false, razborAccess{}, false
 	}
 	if s.IsCoffin {
 		access = razborAccess{Video: true, PDFTex: true}
 	}
-	return s, sc.SeriesDueAt, isTeacher, access, true
+	return s, sc, isTeacher, access, true
 }
 
 // MarkCoffin — teacher-only. Marks a subproblem as a coffin (idempotent),
@@ -552,18 +557,51 @@ func PutSubproblemSolutionTex(database *db.DB, hub *live.Hub) http.HandlerFunc {
 			return
 		}
 		tex := normalizeTexSource(req.Tex)
-		s, err := q.SetSubproblemSolutionTexWithPublication(ctx, subproblemID, &tex)
+		diags, ok := lintTexSource(ctx, w, r, database, sc.MathCenterID, tex, nil)
+		if !ok {
+			return
+		}
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "coffins: begin solution tex tx", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+
+		s, err := qx.SetSubproblemSolutionTexWithPublication(ctx, subproblemID, &tex)
 		if err != nil {
 			logger.LogErrorContext(ctx, "coffins: set solution tex", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save разбор")
 			return
 		}
+		// A published разбор is live; the deferred rollback discards a save
+		// with errors.
+		if s.PublishedAt != nil && writeTexPublishError(w, r, mc.CheckTexDiagnostics(diags)) {
+			return
+		}
+		if !recordTexRevision(ctx, w, r, qx, store.RecordTexRevisionParams{
+			Target:       solutionTexTarget(sc.MathCenterID, subproblemID),
+			Body:         &tex,
+			AuthorUserID: userID,
+			Published:    s.PublishedAt != nil,
+		}) {
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			logger.LogErrorContext(ctx, "coffins: commit solution tex", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
 		live.Publish(ctx, database.Pool(), live.Event{CenterID: sc.MathCenterID, Kind: live.KindCoffins})
-		httpx.WriteJSON(w, http.StatusOK, toCoffinActionView(s))
+		view := toCoffinActionView(s)
+		view.TexDiagnostics = diags
+		httpx.WriteJSON(w, http.StatusOK, view)
 	}
 }
 
-func GetSubproblemSolutionTex(database *db.DB) http.HandlerFunc {
+func GetSubproblemSolutionTex(database *db.DB, blobs objectstore.Store, ttl time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		userID, ok := requireUser(w, r)
@@ -576,7 +614,7 @@ func GetSubproblemSolutionTex(database *db.DB) http.HandlerFunc {
 			return
 		}
 		q := store.New(database.Pool())
-		s, dueAt, isTeacher, access, ok := loadSubproblemForRead(ctx, w, r, q, userID, subproblemID)
+		s, sc, isTeacher, access, ok := loadSubproblemForRead(ctx, w, r, q, userID, subproblemID)
 		if !ok {
 			return
 		}
@@ -584,7 +622,7 @@ func GetSubproblemSolutionTex(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "PDF and LaTeX razbor access is disabled")
 			return
 		}
-		if !isTeacher && !solutionReleasedToStudent(s, dueAt, time.Now()) {
+		if !isTeacher && !solutionReleasedToStudent(s, sc.SeriesDueAt, time.Now()) {
 			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "разбор not available yet")
 			return
 		}
@@ -592,7 +630,7 @@ func GetSubproblemSolutionTex(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "no разбор tex uploaded yet")
 			return
 		}
-		httpx.WriteJSON(w, http.StatusOK, texPayload{Tex: *s.SolutionTexSource})
+		writeTexSource(ctx, w, r, q, blobs, ttl, solutionTexAssetOwner(sc.MathCenterID, subproblemID), *s.SolutionTexSource)
 	}
 }
 
@@ -671,7 +709,7 @@ func DownloadSubproblemSolutionPDF(database *db.DB, blobs objectstore.Store, ttl
 			return
 		}
 		q := store.New(database.Pool())
-		s, dueAt, isTeacher, access, ok := loadSubproblemForRead(ctx, w, r, q, userID, subproblemID)
+		s, sc, isTeacher, access, ok := loadSubproblemForRead(ctx, w, r, q, userID, subproblemID)
 		if !ok {
 			return
 		}
@@ -679,7 +717,7 @@ func DownloadSubproblemSolutionPDF(database *db.DB, blobs objectstore.Store, ttl
 			httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "PDF and LaTeX razbor access is disabled")
 			return
 		}
-		if !isTeacher && !solutionReleasedToStudent(s, dueAt, time.Now()) {
+		if !isTeacher && !solutionReleasedToStudent(s, sc.SeriesDueAt, time.Now()) {
 			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "разбор not available yet")
 			return
 		}
@@ -748,7 +786,7 @@ func AssignSolutionGroup(database *db.DB) http.HandlerFunc {
 				return
 			}
 		}
-		if !requireTeacher(ctx, w, r, q, userID, first.MathCenterID) {
+		if !requireTeacherCan(ctx, w, r, q, userID, first.MathCenterID, mc.CanEditContent) {
 			return
 		}
 		groupID, err := q.CreateSolutionGroup(ctx)
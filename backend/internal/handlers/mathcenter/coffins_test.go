@@ -69,9 +69,7 @@ func TestMarkCoffin_NonTeacherForbidden(t *testing.T) {
 
 	now := time.Now()
 	expectSubproblemCenter(mock, 900, 42, "b", 5, now)
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(false))
+	expectTeacherAccess(mock, int64(7), int64(42), "")
 
 	req := authedRequest(t, access, 7, http.MethodPost, "/subproblems/900/coffin", nil)
 	rr := httptest.NewRecorder()
@@ -90,12 +88,16 @@ func TestPutSubproblemSolutionTex_AdminClosesCoffin(t *testing.T) {
 	now := time.Now()
 	tex := `\documentclass{article}\begin{document}Разбор\end{document}`
 	expectSubproblemCenter(mock, 900, 42, "b", 5, now)
+	expectCenterPreamble(mock, 42, nil)
+	mock.ExpectBegin()
 	mock.ExpectQuery(
 		`DO UPDATE SET solution_tex_source = EXCLUDED.solution_tex_source,[\s\S]*updated_at = NOW`,
 	).
 		WithArgs(int64(900), pgxmock.AnyArg()).
 		WillReturnRows(mock.NewRows(subproblemSolutionColumns).
 			AddRow(int64(9), int64(900), true, (*time.Time)(nil), &tex, (*string)(nil), (*string)(nil), now, now, (*int64)(nil), (*time.Time)(nil)))
+	expectTexRevisionRecorded(mock, solutionTexArgs(42, 900, pgxmock.AnyArg(), 1, false), 1)
+	mock.ExpectCommit()
 
 	body, _ := json.Marshal(map[string]string{"tex": tex})
 	req := authedAdminRequest(t, access, 1, http.MethodPut, "/subproblems/900/solution/tex", bytes.NewReader(body))
@@ -166,7 +168,7 @@ func TestListCoffinQueue_AdminReturnsRows(t *testing.T) {
 
 	now := time.Now()
 	mock.ExpectQuery(`FROM homework_thread t\s+JOIN math_center_subproblem_solutions ss`).
-		WithArgs(int64(42), int64(1)).
+		WithArgs(int64(42), int64(1), (*int64)(nil)).
 		WillReturnRows(mock.NewRows([]string{
 			"thread_id", "student_user_id", "subproblem_id", "series_id",
 			"current_status", "last_grader_user_id", "claim_holder_user_id",
@@ -255,10 +257,10 @@ func TestPublishSubproblemSolutions_PublishesAtomicallyAndReleasesCoffins(t *tes
 	mock.ExpectQuery(`FOR UPDATE OF ss`).
 		WithArgs([]int64{900, 901}).
 		WillReturnRows(mock.NewRows([]string{
-			"subproblem_id", "math_center_id", "series_id", "is_coffin", "has_material", "solution_group_id",
+			"subproblem_id", "math_center_id", "series_id", "is_coffin", "has_material", "solution_group_id", "solution_tex_source",
 		}).
-			AddRow(int64(900), int64(42), int64(100), true, true, (*int64)(nil)).
-			AddRow(int64(901), int64(42), int64(100), false, true, (*int64)(nil)))
+			AddRow(int64(900), int64(42), int64(100), true, true, (*int64)(nil), (*string)(nil)).
+			AddRow(int64(901), int64(42), int64(100), false, true, (*int64)(nil), (*string)(nil)))
 	mock.ExpectQuery(`INSERT INTO math_center_solution_groups`).
 		WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(77)))
 	mock.ExpectExec(`UPDATE math_center_subproblem_solutions\s+SET solution_group_id`).
@@ -269,6 +271,9 @@ func TestPublishSubproblemSolutions_PublishesAtomicallyAndReleasesCoffins(t *tes
 		WillReturnRows(mock.NewRows([]string{"subproblem_id", "is_coffin", "published_at"}).
 			AddRow(int64(900), true, time.Now()).
 			AddRow(int64(901), false, time.Now()))
+	mock.ExpectExec(`UPDATE math_center_tex_revisions\s+SET published_at`).
+		WithArgs([]int64{900, 901}, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
 	mock.ExpectCommit()
 
 	body, _ := json.Marshal(map[string]any{"subproblem_ids": []int64{901, 900, 900}})
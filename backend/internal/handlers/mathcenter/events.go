@@ -72,8 +72,10 @@ func Events(hub *live.Hub, database *db.DB) http.HandlerFunc {
 				}
 				flusher.Flush()
 			case ev := <-sub.C:
-				if _, err := fmt.Fprintf(w, "event: %s\ndata: {\"center_id\":%d,\"kind\":%q,\"series_id\":%d}\n\n",
-					ev.Kind, ev.CenterID, ev.Kind, ev.SeriesID); err != nil {
+				if !eventVisibleTo(ev, userID, isTeacher) {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Kind, ssePayload(ev)); err != nil {
 					return
 				}
 				flusher.Flush()
@@ -81,3 +83,25 @@ func Events(hub *live.Hub, database *db.DB) http.HandlerFunc {
 		}
 	}
 }
+
+// eventVisibleTo drops thread-scoped events a student has no business seeing:
+// a message on another student's thread must not even nudge their client.
+func eventVisibleTo(ev live.Event, userID int64, isTeacher bool) bool {
+	if ev.Kind == live.KindMessages && !isTeacher {
+		return ev.StudentUserID == userID
+	}
+	if ev.Kind == live.KindCalibration {
+		return isTeacher
+	}
+	return true
+}
+
+// ssePayload renders the coarse JSON body of one SSE event. thread_id is
+// only emitted for thread-scoped kinds.
+func ssePayload(ev live.Event) string {
+	payload := fmt.Sprintf("{\"center_id\":%d,\"kind\":%q,\"series_id\":%d", ev.CenterID, ev.Kind, ev.SeriesID)
+	if ev.ThreadID != 0 {
+		payload += fmt.Sprintf(",\"thread_id\":%d", ev.ThreadID)
+	}
+	return payload + "}"
+}
@@ -143,3 +143,72 @@ func TestEvents_MemberStreamsConnectedThenEvent(t *testing.T) {
 		}
 	}
 }
+
+// TestEvents_StudentOnlySeesOwnThreadMessages proves a message on another
+// student's thread is filtered out of the stream while the student's own
+// message (with its thread_id) comes through.
+func TestEvents_StudentOnlySeesOwnThreadMessages(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	srv, access, hub := newEventsServer(t, mock)
+
+	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
+		WithArgs(int64(7), int64(42)).
+		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(false))
+	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_students`).
+		WithArgs(int64(7), int64(42)).
+		WillReturnRows(mock.NewRows([]string{"is_student"}).AddRow(true))
+
+	tok, err := access.Generate(7, "user7", false)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/centers/42/events", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got %d, want 200", resp.StatusCode)
+	}
+
+	got := make(chan string, 1)
+	go func() {
+		reader := bufio.NewReader(resp.Body)
+		for {
+			l, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(l, "data: ") {
+				got <- strings.TrimSpace(strings.TrimPrefix(l, "data: "))
+				return
+			}
+		}
+	}()
+
+	deadline := time.After(2 * time.Second)
+	tick := time.NewTicker(20 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case data := <-got:
+			if !strings.Contains(data, `"thread_id":5`) {
+				t.Fatalf("got data %s, want the student's own thread 5", data)
+			}
+			return
+		case <-deadline:
+			t.Fatal("did not receive own message event")
+		case <-tick.C:
+			// The foreign message is published first every round; it must
+			// never be the one that arrives.
+			hub.Publish(live.Event{CenterID: 42, Kind: live.KindMessages, SeriesID: 7, StudentUserID: 8, ThreadID: 6})
+			hub.Publish(live.Event{CenterID: 42, Kind: live.KindMessages, SeriesID: 7, StudentUserID: 7, ThreadID: 5})
+		}
+	}
+}
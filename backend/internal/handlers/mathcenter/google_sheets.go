@@ -14,6 +14,7 @@ import (
 	"github.com/Alarion239/my239/backend/internal/httpx"
 	"github.com/Alarion239/my239/backend/internal/live"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 )
@@ -54,7 +55,7 @@ func GoogleSheetConfig(database *db.DB, sheets *googlesheets.Service) http.Handl
 			httpx.WriteAPIError(w, r, http.StatusUnauthorized, httpx.CodeUnauthenticated, "unauthenticated")
 			return
 		}
-		if !requireTeacher(r.Context(), w, r, store.New(database.Pool()), userID, centerID) {
+		if !requireTeacherCan(r.Context(), w, r, store.New(database.Pool()), userID, centerID, mc.CanView) {
 			return
 		}
 		httpx.WriteJSON(w, http.StatusOK, googleSheetConfigView{
@@ -251,7 +252,7 @@ func SyncGoogleSheets(database *db.DB, sheets *googlesheets.Service) http.Handle
 			return
 		}
 		q := store.New(database.Pool())
-		if !requireTeacher(r.Context(), w, r, q, userID, centerID) {
+		if !requireTeacherCan(r.Context(), w, r, q, userID, centerID, mc.CanManage) {
 			return
 		}
 		var request googleSheetSyncRequest
@@ -12,6 +12,7 @@ import (
 
 	"github.com/Alarion239/my239/backend/internal/httpx"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 )
@@ -118,8 +119,9 @@ func GetLatexPreamble(database *db.DB) http.HandlerFunc {
 
 func UpdateLatexPreamble(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		q := store.New(database.Pool())
-		centerID, _, ok := manageGate(w, r, q)
+		centerID, userID, ok := manageGateCan(w, r, q, mc.CanEditContent)
 		if !ok {
 			return
 		}
@@ -132,17 +134,36 @@ func UpdateLatexPreamble(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, message)
 			return
 		}
-		_, err := database.Pool().Exec(r.Context(), `
-			INSERT INTO math_center_latex_settings (math_center_id, preamble)
-			VALUES ($1, $2)
-			ON CONFLICT (math_center_id) DO UPDATE
-			SET preamble = EXCLUDED.preamble, updated_at = NOW()
-		`, centerID, req.Preamble)
+		tx, err := database.Pool().Begin(ctx)
 		if err != nil {
-			logger.LogErrorContext(r.Context(), `latex preamble: update`, err)
+			logger.LogErrorContext(ctx, `latex preamble: begin tx`, err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, `internal error`)
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+
+		if err := qx.UpsertLatexPreamble(ctx, centerID, req.Preamble); err != nil {
+			logger.LogErrorContext(ctx, `latex preamble: update`, err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, `failed to save latex preamble`)
 			return
 		}
+		// The preamble applies to every rendered source at once, so each
+		// revision is shown to students as soon as it is saved.
+		preamble := req.Preamble
+		if !recordTexRevision(ctx, w, r, qx, store.RecordTexRevisionParams{
+			Target:       preambleTexTarget(centerID),
+			Body:         &preamble,
+			AuthorUserID: userID,
+			Published:    true,
+		}) {
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			logger.LogErrorContext(ctx, `latex preamble: commit`, err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, `internal error`)
+			return
+		}
 		httpx.WriteJSON(w, http.StatusOK, latexPreambleView(req))
 	}
 }
@@ -0,0 +1,573 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/live"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// Lesson schedule and attendance. A lesson belongs to one term and is held
+// for one or more of its groups; teachers of the center manage lessons and
+// mark attendance for the students of those groups. Marks can also come from
+// the «Посещаемость» block of a group's conduit tab (see googlesheets).
+
+type lessonGroupView struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type lessonView struct {
+	ID        int64             `json:"id"`
+	TermID    int64             `json:"term_id"`
+	HeldOn    string            `json:"held_on"`
+	StartsAt  string            `json:"starts_at"`
+	EndsAt    string            `json:"ends_at"`
+	Topic     string            `json:"topic"`
+	SeriesID  *int64            `json:"series_id"`
+	LikbezID  *int64            `json:"likbez_id"`
+	Groups    []lessonGroupView `json:"groups"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+func lessonClock(t pgtype.Time) time.Duration {
+	return time.Duration(t.Microseconds) * time.Microsecond
+}
+
+func toLessonView(l store.Lesson) lessonView {
+	groups := make([]lessonGroupView, 0, len(l.GroupIDs))
+	for i, id := range l.GroupIDs {
+		groups = append(groups, lessonGroupView{ID: id, Name: l.GroupNames[i]})
+	}
+	return lessonView{
+		ID:        l.ID,
+		TermID:    l.TermID,
+		HeldOn:    l.HeldOn.Time.Format(mc.LessonDateLayout),
+		StartsAt:  mc.FormatLessonClock(lessonClock(l.StartsAt)),
+		EndsAt:    mc.FormatLessonClock(lessonClock(l.EndsAt)),
+		Topic:     l.Topic,
+		SeriesID:  l.SeriesID,
+		LikbezID:  l.LikbezID,
+		Groups:    groups,
+		CreatedAt: l.CreatedAt,
+		UpdatedAt: l.UpdatedAt,
+	}
+}
+
+// lessonRequest is the body of create and update. TermID is read on create
+// only; a lesson stays in its term.
+type lessonRequest struct {
+	TermID   int64   `json:"term_id"`
+	GroupIDs []int64 `json:"group_ids"`
+	HeldOn   string  `json:"held_on"`
+	StartsAt string  `json:"starts_at"`
+	EndsAt   string  `json:"ends_at"`
+	Topic    string  `json:"topic"`
+	SeriesID *int64  `json:"series_id"`
+	LikbezID *int64  `json:"likbez_id"`
+}
+
+// lessonParams validates the request against the lesson's center and term:
+// the groups must be given, and a linked series must be of the same term, a
+// linked likbez of the same center. Writes 400/500 on failure.
+func lessonParams(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, centerID, termID int64, req lessonRequest) (store.LessonParams, []int64, bool) {
+	schedule, err := mc.ParseLessonSchedule(req.HeldOn, req.StartsAt, req.EndsAt, req.Topic)
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, err.Error())
+		return store.LessonParams{}, nil, false
+	}
+	groupIDs := slices.Clone(req.GroupIDs)
+	slices.Sort(groupIDs)
+	groupIDs = slices.Compact(groupIDs)
+	if len(groupIDs) == 0 || len(groupIDs) > mc.MaxLessonGroups {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "group_ids must name 1 to "+strconv.Itoa(mc.MaxLessonGroups)+" groups")
+		return store.LessonParams{}, nil, false
+	}
+	if req.SeriesID != nil {
+		inTerm, err := q.SeriesInTerm(ctx, *req.SeriesID, termID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "lessons: series term", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return store.LessonParams{}, nil, false
+		}
+		if !inTerm {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "series_id must be a series of the lesson's term")
+			return store.LessonParams{}, nil, false
+		}
+	}
+	if req.LikbezID != nil {
+		likbez, err := q.GetLikbez(ctx, *req.LikbezID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			logger.LogErrorContext(ctx, "lessons: get likbez", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return store.LessonParams{}, nil, false
+		}
+		if err != nil || likbez.MathCenterID != centerID {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "likbez_id must be a likbez of this center")
+			return store.LessonParams{}, nil, false
+		}
+	}
+	return store.LessonParams{
+		HeldOn:   pgtype.Date{Time: schedule.HeldOn, Valid: true},
+		StartsAt: pgtype.Time{Microseconds: schedule.StartsAt.Microseconds(), Valid: true},
+		EndsAt:   pgtype.Time{Microseconds: schedule.EndsAt.Microseconds(), Valid: true},
+		Topic:    schedule.Topic,
+		SeriesID: req.SeriesID,
+		LikbezID: req.LikbezID,
+	}, groupIDs, true
+}
+
+// saveLessonGroups links the groups inside the caller's transaction and
+// writes 400 when one of them is not a group of the term.
+func saveLessonGroups(ctx context.Context, w http.ResponseWriter, r *http.Request, qx *store.Queries, lessonID, termID int64, groupIDs []int64) bool {
+	linked, err := qx.SetLessonGroups(ctx, lessonID, termID, groupIDs)
+	if err != nil {
+		logger.LogErrorContext(ctx, "lessons: set groups", err, "lesson_id", lessonID)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save lesson")
+		return false
+	}
+	if linked != int64(len(groupIDs)) {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "group_ids must be groups of the lesson's term")
+		return false
+	}
+	return true
+}
+
+// loadLessonForTeacher fetches the lesson named by {lessonID} and checks the
+// caller's teacher role allows can on it. On !ok the response is already
+// written.
+func loadLessonForTeacher(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID int64, can mc.TeacherCapability) (store.Lesson, bool) {
+	lessonID, err := pathInt64(r, "lessonID")
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid lesson id")
+		return store.Lesson{}, false
+	}
+	lesson, err := q.GetLesson(ctx, lessonID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "lesson not found")
+			return store.Lesson{}, false
+		}
+		logger.LogErrorContext(ctx, "lessons: get", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return store.Lesson{}, false
+	}
+	if !requireTeacherCan(ctx, w, r, q, userID, lesson.MathCenterID, methodCapability(r, can)) {
+		return store.Lesson{}, false
+	}
+	return lesson, true
+}
+
+// parseLessonDateParam reads an optional YYYY-MM-DD query parameter.
+func parseLessonDateParam(value string) (pgtype.Date, bool) {
+	if value == "" {
+		return pgtype.Date{}, true
+	}
+	t, err := time.Parse(mc.LessonDateLayout, value)
+	if err != nil {
+		return pgtype.Date{}, false
+	}
+	return pgtype.Date{Time: t, Valid: true}, true
+}
+
+// ListLessons — teacher of the center. Returns the term's lessons in
+// timetable order: ?term_id= (default: the active term, or a term-scoped
+// assistant's own), optionally ?group_id= and a ?from= / ?to= date range.
+func ListLessons(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		q := store.New(database.Pool())
+		access, ok := teacherAccess(ctx, w, r, q, userID, centerID, 0, mc.CanView)
+		if !ok {
+			return
+		}
+		query := r.URL.Query()
+		params := store.ListLessonsParams{MathCenterID: centerID}
+		if v := query.Get("term_id"); v != "" {
+			params.TermID, err = strconv.ParseInt(v, 10, 64)
+			if err != nil || params.TermID <= 0 {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid term id")
+				return
+			}
+			if access.TermID != nil && params.TermID != *access.TermID {
+				httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, mc.ErrTeacherOutsideTerm.Error())
+				return
+			}
+		} else if access.TermID != nil {
+			params.TermID = *access.TermID
+		} else {
+			active, err := q.GetActiveTermForCenter(ctx, centerID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteJSON(w, http.StatusOK, []lessonView{})
+				return
+			}
+			if err != nil {
+				logger.LogErrorContext(ctx, "lessons: active term", err)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+				return
+			}
+			params.TermID = active.ID
+		}
+		if v := query.Get("group_id"); v != "" {
+			groupID, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || groupID <= 0 {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid group id")
+				return
+			}
+			params.GroupID = &groupID
+		}
+		var okFrom, okTo bool
+		params.From, okFrom = parseLessonDateParam(query.Get("from"))
+		params.To, okTo = parseLessonDateParam(query.Get("to"))
+		if !okFrom || !okTo {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "from and to must be calendar dates")
+			return
+		}
+		lessons, err := q.ListLessons(ctx, params)
+		if err != nil {
+			logger.LogErrorContext(ctx, "lessons: list", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to list lessons")
+			return
+		}
+		out := make([]lessonView, 0, len(lessons))
+		for _, l := range lessons {
+			out = append(out, toLessonView(l))
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// CreateLesson — teacher of the center. Schedules a lesson for groups of
+// one term.
+func CreateLesson(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		var req lessonRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		q := store.New(database.Pool())
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanManage) {
+			return
+		}
+		if req.TermID <= 0 {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "term_id is required")
+			return
+		}
+		term, err := q.GetTerm(ctx, req.TermID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			logger.LogErrorContext(ctx, "lessons: get term", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if err != nil || term.MathCenterID != centerID {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "term does not belong to this math center")
+			return
+		}
+		params, groupIDs, ok := lessonParams(ctx, w, r, q, centerID, term.ID, req)
+		if !ok {
+			return
+		}
+
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "lessons: begin create", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+		lessonID, err := qx.CreateLesson(ctx, store.CreateLessonParams{
+			MathCenterID:    centerID,
+			TermID:          term.ID,
+			CreatedByUserID: userID,
+			LessonParams:    params,
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "lessons: create", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to create lesson")
+			return
+		}
+		if !saveLessonGroups(ctx, w, r, qx, lessonID, term.ID, groupIDs) {
+			return
+		}
+		lesson, err := qx.GetLesson(ctx, lessonID)
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			logger.LogErrorContext(ctx, "lessons: commit create", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to create lesson")
+			return
+		}
+		live.Publish(ctx, database.Pool(), live.Event{CenterID: centerID, Kind: live.KindLessons})
+		httpx.WriteJSON(w, http.StatusCreated, toLessonView(lesson))
+	}
+}
+
+// GetLesson — teacher of the center.
+func GetLesson(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		lesson, ok := loadLessonForTeacher(r.Context(), w, r, store.New(database.Pool()), userID, mc.CanView)
+		if !ok {
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, toLessonView(lesson))
+	}
+}
+
+// UpdateLesson — teacher of the center. Replaces the lesson's date, times,
+// topic, links and groups. Marks of students no longer in its groups stay
+// recorded.
+func UpdateLesson(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		var req lessonRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		q := store.New(database.Pool())
+		lesson, ok := loadLessonForTeacher(ctx, w, r, q, userID, mc.CanManage)
+		if !ok {
+			return
+		}
+		params, groupIDs, ok := lessonParams(ctx, w, r, q, lesson.MathCenterID, lesson.TermID, req)
+		if !ok {
+			return
+		}
+
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "lessons: begin update", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+		if err := qx.UpdateLesson(ctx, lesson.ID, params); err != nil {
+			logger.LogErrorContext(ctx, "lessons: update", err, "lesson_id", lesson.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to update lesson")
+			return
+		}
+		if !saveLessonGroups(ctx, w, r, qx, lesson.ID, lesson.TermID, groupIDs) {
+			return
+		}
+		updated, err := qx.GetLesson(ctx, lesson.ID)
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			logger.LogErrorContext(ctx, "lessons: commit update", err, "lesson_id", lesson.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to update lesson")
+			return
+		}
+		live.Publish(ctx, database.Pool(), live.Event{CenterID: lesson.MathCenterID, Kind: live.KindLessons})
+		httpx.WriteJSON(w, http.StatusOK, toLessonView(updated))
+	}
+}
+
+// DeleteLesson — teacher of the center. Its attendance marks go with it.
+func DeleteLesson(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		q := store.New(database.Pool())
+		lesson, ok := loadLessonForTeacher(ctx, w, r, q, userID, mc.CanManage)
+		if !ok {
+			return
+		}
+		if err := q.DeleteLesson(ctx, lesson.ID); err != nil {
+			logger.LogErrorContext(ctx, "lessons: delete", err, "lesson_id", lesson.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to delete lesson")
+			return
+		}
+		live.Publish(ctx, database.Pool(), live.Event{CenterID: lesson.MathCenterID, Kind: live.KindLessons})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type attendanceEntryView struct {
+	StudentUserID int64      `json:"student_user_id"`
+	DisplayName   string     `json:"display_name"`
+	GroupID       int64      `json:"group_id"`
+	GroupName     string     `json:"group_name"`
+	Status        *string    `json:"status"`
+	MarkedBy      *int64     `json:"marked_by_user_id"`
+	FromSheet     bool       `json:"from_google_sheet"`
+	MarkedAt      *time.Time `json:"marked_at"`
+}
+
+type lessonAttendanceView struct {
+	Lesson   lessonView            `json:"lesson"`
+	Students []attendanceEntryView `json:"students"`
+}
+
+type attendanceMarkRequest struct {
+	StudentUserID int64 `json:"student_user_id"`
+	// Status is one of present, late, absent, excused; empty clears the
+	// mark.
+	Status string `json:"status"`
+}
+
+type attendanceRequest struct {
+	Marks []attendanceMarkRequest `json:"marks"`
+}
+
+func writeLessonAttendance(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, lesson store.Lesson) {
+	rows, err := q.ListLessonAttendance(ctx, lesson.ID)
+	if err != nil {
+		logger.LogErrorContext(ctx, "lessons: list attendance", err, "lesson_id", lesson.ID)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to load attendance")
+		return
+	}
+	out := lessonAttendanceView{Lesson: toLessonView(lesson), Students: make([]attendanceEntryView, 0, len(rows))}
+	for _, a := range rows {
+		out.Students = append(out.Students, attendanceEntryView{
+			StudentUserID: a.StudentUserID,
+			DisplayName:   mc.StudentDisplayName(a.FirstName, a.LastName),
+			GroupID:       a.GroupID,
+			GroupName:     a.GroupName,
+			Status:        a.Status,
+			MarkedBy:      a.MarkedByUserID,
+			FromSheet:     a.GoogleSheetCell != "",
+			MarkedAt:      a.MarkedAt,
+		})
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+// GetLessonAttendance — teacher of the center. Returns the students of the
+// lesson's groups with their marks.
+func GetLessonAttendance(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		q := store.New(database.Pool())
+		lesson, ok := loadLessonForTeacher(ctx, w, r, q, userID, mc.CanView)
+		if !ok {
+			return
+		}
+		writeLessonAttendance(ctx, w, r, q, lesson)
+	}
+}
+
+// PutLessonAttendance — teacher of the center. Sets or clears the marks of
+// the listed students, who must be in the lesson's groups; students not
+// listed keep theirs. A teacher's mark replaces an imported one.
+func PutLessonAttendance(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		var req attendanceRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if len(req.Marks) == 0 {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "marks is required")
+			return
+		}
+		for _, m := range req.Marks {
+			if m.Status != "" && !mc.ValidAttendanceStatus(m.Status) {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "status must be present, late, absent, excused or empty")
+				return
+			}
+		}
+		q := store.New(database.Pool())
+		lesson, ok := loadLessonForTeacher(ctx, w, r, q, userID, mc.CanGrade)
+		if !ok {
+			return
+		}
+		roster, err := q.ListLessonAttendance(ctx, lesson.ID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "lessons: roster for marks", err, "lesson_id", lesson.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		enrolled := make(map[int64]bool, len(roster))
+		for _, a := range roster {
+			enrolled[a.StudentUserID] = true
+		}
+		for _, m := range req.Marks {
+			if !enrolled[m.StudentUserID] {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "student "+strconv.FormatInt(m.StudentUserID, 10)+" is not in the lesson's groups")
+				return
+			}
+		}
+
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "lessons: begin marks", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+		for _, m := range req.Marks {
+			if m.Status == "" {
+				err = qx.DeleteAttendance(ctx, lesson.ID, m.StudentUserID)
+			} else {
+				err = qx.UpsertAttendance(ctx, lesson.ID, m.StudentUserID, m.Status, userID)
+			}
+			if err != nil {
+				logger.LogErrorContext(ctx, "lessons: save mark", err, "lesson_id", lesson.ID)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save attendance")
+				return
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			logger.LogErrorContext(ctx, "lessons: commit marks", err, "lesson_id", lesson.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save attendance")
+			return
+		}
+		live.Publish(ctx, database.Pool(), live.Event{CenterID: lesson.MathCenterID, Kind: live.KindLessons})
+		writeLessonAttendance(ctx, w, r, q, lesson)
+	}
+}
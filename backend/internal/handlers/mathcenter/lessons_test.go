@@ -0,0 +1,216 @@
+package mathcenter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var lessonColumns = []string{
+	"id", "math_center_id", "term_id", "held_on", "starts_at", "ends_at", "topic", "series_id", "likbez_id",
+	"created_by_user_id", "created_at", "updated_at", "group_ids", "group_names",
+}
+
+var lessonAttendanceColumns = []string{
+	"user_id", "group_id", "group_name", "first_name", "last_name", "status", "marked_by_user_id",
+	"google_sheet_cell", "marked_at",
+}
+
+// expectLesson answers GetLesson with lesson 300 of center 42, term 70,
+// held for group 5 on 2026-09-03 16:30–18:00.
+func expectLesson(mock pgxmock.PgxPoolIface) {
+	now := time.Now()
+	heldOn := pgtype.Date{Time: time.Date(2026, 9, 3, 0, 0, 0, 0, time.UTC), Valid: true}
+	startsAt := pgtype.Time{Microseconds: (16*time.Hour + 30*time.Minute).Microseconds(), Valid: true}
+	endsAt := pgtype.Time{Microseconds: (18 * time.Hour).Microseconds(), Valid: true}
+	mock.ExpectQuery(`FROM math_center_lessons l\s+WHERE l.id`).
+		WithArgs(int64(300)).
+		WillReturnRows(mock.NewRows(lessonColumns).
+			AddRow(int64(300), int64(42), int64(70), heldOn, startsAt, endsAt, "Графы", (*int64)(nil), (*int64)(nil),
+				(*int64)(nil), now, now, []int64{5}, []string{"А"}))
+}
+
+func TestCreateLesson_RejectsGroupOfAnotherTerm(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectQuery(`FROM math_center_terms\s+WHERE id = \$1`).
+		WithArgs(int64(70)).
+		WillReturnRows(mock.NewRows([]string{
+			"id", "math_center_id", "kind", "grade", "is_active", "created_at", "archived_at",
+		}).AddRow(int64(70), int64(42), "academic", (*int32)(nil), true, time.Now(), (*time.Time)(nil)))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO math_center_lessons`).
+		WithArgs(int64(42), int64(70), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "Графы",
+			(*int64)(nil), (*int64)(nil), int64(3)).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(300)))
+	mock.ExpectExec(`DELETE FROM math_center_lesson_groups`).
+		WithArgs(int64(300)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectExec(`INSERT INTO math_center_lesson_groups`).
+		WithArgs(int64(300), int64(70), []int64{5, 9}).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectRollback()
+
+	body, _ := json.Marshal(map[string]any{
+		"term_id": 70, "group_ids": []int64{9, 5, 5}, "held_on": "2026-09-03",
+		"starts_at": "16:30", "ends_at": "18:00", "topic": "Графы",
+	})
+	req := authedRequest(t, access, 3, http.MethodPost, "/centers/42/lessons/", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateLesson_RejectsBadSchedule(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectQuery(`FROM math_center_terms\s+WHERE id = \$1`).
+		WithArgs(int64(70)).
+		WillReturnRows(mock.NewRows([]string{
+			"id", "math_center_id", "kind", "grade", "is_active", "created_at", "archived_at",
+		}).AddRow(int64(70), int64(42), "academic", (*int32)(nil), true, time.Now(), (*time.Time)(nil)))
+
+	body, _ := json.Marshal(map[string]any{
+		"term_id": 70, "group_ids": []int64{5}, "held_on": "2026-09-03",
+		"starts_at": "18:00", "ends_at": "16:30",
+	})
+	req := authedRequest(t, access, 3, http.MethodPost, "/centers/42/lessons/", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGetLesson_RejectsNonTeacher(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectLesson(mock)
+	expectTeacherAccess(mock, 9, 42, "")
+
+	req := authedRequest(t, access, 9, http.MethodGet, "/lessons/300/", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestListLessons_RejectsAnotherTermForScopedTeacher(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	termID := int64(4)
+	mock.ExpectQuery(`FROM math_center_teachers t\s+LEFT JOIN math_center_terms`).
+		WithArgs(int64(3), int64(42)).
+		WillReturnRows(mock.NewRows(teacherAccessColumns).
+			AddRow(int64(1), false, "grader", &termID, true))
+
+	req := authedRequest(t, access, 3, http.MethodGet, "/centers/42/lessons/?term_id=5", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPutLessonAttendance_MarksRosterStudent(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	present, now := "present", time.Now()
+	expectLesson(mock)
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectQuery(`FROM math_center_lessons l\s+JOIN math_center_lesson_groups`).
+		WithArgs(int64(300)).
+		WillReturnRows(mock.NewRows(lessonAttendanceColumns).
+			AddRow(int64(99), int64(5), "А", "Иван", "Иванов", (*string)(nil), (*int64)(nil), "", (*time.Time)(nil)))
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO math_center_lesson_attendance`).
+		WithArgs(int64(300), int64(99), "present", int64(3)).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`FROM math_center_lessons l\s+JOIN math_center_lesson_groups`).
+		WithArgs(int64(300)).
+		WillReturnRows(mock.NewRows(lessonAttendanceColumns).
+			AddRow(int64(99), int64(5), "А", "Иван", "Иванов", &present, ptrInt64(3), "", &now))
+
+	body, _ := json.Marshal(map[string]any{"marks": []map[string]any{{"student_user_id": 99, "status": "present"}}})
+	req := authedRequest(t, access, 3, http.MethodPut, "/lessons/300/attendance", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Lesson struct {
+			StartsAt string `json:"starts_at"`
+		} `json:"lesson"`
+		Students []struct {
+			DisplayName string  `json:"display_name"`
+			Status      *string `json:"status"`
+		} `json:"students"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Lesson.StartsAt != "16:30" || len(resp.Students) != 1 || resp.Students[0].Status == nil ||
+		*resp.Students[0].Status != "present" || resp.Students[0].DisplayName != "Иванов Иван" {
+		t.Fatalf("resp = %+v", resp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPutLessonAttendance_RejectsStudentOutsideGroups(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectLesson(mock)
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectQuery(`FROM math_center_lessons l\s+JOIN math_center_lesson_groups`).
+		WithArgs(int64(300)).
+		WillReturnRows(mock.NewRows(lessonAttendanceColumns).
+			AddRow(int64(99), int64(5), "А", "Иван", "Иванов", (*string)(nil), (*int64)(nil), "", (*time.Time)(nil)))
+
+	body, _ := json.Marshal(map[string]any{"marks": []map[string]any{{"student_user_id": 77, "status": "absent"}}})
+	req := authedRequest(t, access, 3, http.MethodPut, "/lessons/300/attendance", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -51,6 +51,8 @@ type likbezView struct {
 	HasPDF          bool       `json:"has_pdf"`
 	HasTex          bool       `json:"has_tex"`
 	VideoURL        *string    `json:"video_url,omitempty"`
+	// TexDiagnostics is set only in the response to a TeX save.
+	TexDiagnostics []mc.TexDiagnostic `json:"tex_diagnostics,omitempty"`
 }
 
 func likbezObjectKey(likbezID int64) string {
@@ -192,7 +194,7 @@ func CreateLikbez(database *db.DB) http.HandlerFunc {
 		}
 
 		q := store.New(database.Pool())
-		if !requireTeacher(ctx, w, r, q, userID, centerID) {
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanEditContent) {
 			return
 		}
 		if !validLikbezTerm(ctx, q, req.TermID, centerID) {
@@ -354,6 +356,12 @@ func DeleteLikbez(database *db.DB, blobs objectstore.Store) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "not a teacher of this center")
 			return
 		}
+		assets, err := q.ListTexAssets(r.Context(), likbezTexAssetOwner(row.MathCenterID, row.ID))
+		if err != nil {
+			logger.LogErrorContext(r.Context(), "likbez: list tex assets for delete", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
 		if _, err := q.DeleteLikbez(r.Context(), row.ID); err != nil {
 			logger.LogErrorContext(r.Context(), "likbez: delete", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to delete likbez")
@@ -364,6 +372,11 @@ func DeleteLikbez(database *db.DB, blobs objectstore.Store) http.HandlerFunc {
 				logger.LogErrorContext(r.Context(), "likbez: delete pdf", err)
 			}
 		}
+		assetKeys := make([]string, len(assets))
+		for i, a := range assets {
+			assetKeys[i] = a.ObjectKey
+		}
+		deleteTexAssetObjects(r.Context(), blobs, assetKeys)
 		publishLikbezChanged(r.Context(), database, row.MathCenterID)
 		w.WriteHeader(http.StatusNoContent)
 	})
@@ -442,6 +455,13 @@ func PutLikbezTex(database *db.DB) http.HandlerFunc {
 			return
 		}
 		tex := normalizeTexSource(req.Tex)
+		diags, ok := lintTexSource(r.Context(), w, r, database, row.MathCenterID, tex, nil)
+		if !ok {
+			return
+		}
+		if row.PublishedAt != nil && writeTexPublishError(w, r, mc.CheckTexDiagnostics(diags)) {
+			return
+		}
 		if _, err := q.SetLikbezTex(r.Context(), store.SetLikbezTexParams{ID: row.ID, TexSource: &tex}); err != nil {
 			logger.LogErrorContext(r.Context(), "likbez: set tex", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save tex")
@@ -453,13 +473,14 @@ func PutLikbezTex(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
+		view.TexDiagnostics = diags
 		publishLikbezChanged(r.Context(), database, row.MathCenterID)
 		httpx.WriteJSON(w, http.StatusOK, view)
 	})
 }
 
-func GetLikbezTex(database *db.DB) http.HandlerFunc {
-	return withLikbezAccess(database, func(w http.ResponseWriter, r *http.Request, _ *store.Queries, row store.GetLikbezRow, _ likbezView, teacher bool) {
+func GetLikbezTex(database *db.DB, blobs objectstore.Store, ttl time.Duration) http.HandlerFunc {
+	return withLikbezAccess(database, func(w http.ResponseWriter, r *http.Request, q *store.Queries, row store.GetLikbezRow, _ likbezView, teacher bool) {
 		if !teacher && row.PublishedAt == nil {
 			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "likbez not found")
 			return
@@ -468,7 +489,7 @@ func GetLikbezTex(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "no tex source uploaded yet")
 			return
 		}
-		httpx.WriteJSON(w, http.StatusOK, texPayload{Tex: *row.TexSource})
+		writeTexSource(r.Context(), w, r, q, blobs, ttl, likbezTexAssetOwner(row.MathCenterID, row.ID), *row.TexSource)
 	})
 }
 
@@ -513,6 +534,9 @@ func PublishLikbez(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "not a teacher of this center")
 			return
 		}
+		if row.TexSource != nil && writeTexPublishError(w, r, mc.CheckTexPublishable(*row.TexSource, nil)) {
+			return
+		}
 		if _, err := q.PublishLikbez(r.Context(), row.ID); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "attach a material before publishing")
@@ -592,6 +616,9 @@ func withLikbezAccess(database *db.DB, next func(http.ResponseWriter, *http.Requ
 			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "likbez not found")
 			return
 		}
+		if teacher && !requireTeacherCan(ctx, w, r, q, userID, row.MathCenterID, methodCapability(r, mc.CanEditContent)) {
+			return
+		}
 		next(w, r, q, row, view, teacher)
 	}
 }
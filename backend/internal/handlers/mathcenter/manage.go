@@ -42,6 +42,8 @@ func ManageRouter(database *db.DB, hub *live.Hub, sheetServices ...*googlesheets
 	r.Get("/teachers", manageListTeachers(database))
 	r.Post("/teachers", manageAddTeacher(database, hub))
 	r.Patch("/teachers/{teacherID}/head", manageSetTeacherHead(database, hub))
+	r.Get("/teacher-roles", manageListTeacherRoles(database))
+	r.Put("/teachers/{teacherID}/role", manageSetTeacherRole(database))
 	r.Delete("/teachers/{teacherID}", manageRemoveTeacher(database, hub))
 
 	r.Get("/students", manageListStudents(database))
@@ -74,13 +76,24 @@ func ManageRouter(database *db.DB, hub *live.Hub, sheetServices ...*googlesheets
 	r.Post("/google-sheets/sync-students", manageGoogleSheetSyncStudents(database, sheets))
 	r.Post("/google-sheets/sync-series", manageGoogleSheetSyncSeries(database, sheets))
 	r.Patch("/latex-preamble", UpdateLatexPreamble(database))
+	r.Get("/latex-preamble/revisions", ListTexRevisions(database, resolvePreambleTexTarget))
+	r.Get("/latex-preamble/revisions/{revision}", GetTexRevision(database, resolvePreambleTexTarget))
+	r.Post("/latex-preamble/revisions/{revision}/restore", RestoreTexRevision(database, resolvePreambleTexTarget))
+	r.Get("/latex-preamble/diff", DiffTexRevisions(database, resolvePreambleTexTarget))
 
 	return r
 }
 
 // manageGate resolves the {centerID} path param + the caller and runs the
-// teacher check. On !ok the response is already written.
+// teacher check: any teacher may read the manage views, changes need a role
+// with mc.CanManage. On !ok the response is already written.
 func manageGate(w http.ResponseWriter, r *http.Request, q *store.Queries) (centerID, userID int64, ok bool) {
+	return manageGateCan(w, r, q, methodCapability(r, mcdomain.CanManage))
+}
+
+// manageGateCan is manageGate with an explicit capability, for center-wide
+// actions outside CanManage (head-only role changes, the shared preamble).
+func manageGateCan(w http.ResponseWriter, r *http.Request, q *store.Queries, can mcdomain.TeacherCapability) (centerID, userID int64, ok bool) {
 	centerID, err := strconv.ParseInt(chi.URLParam(r, "centerID"), 10, 64)
 	if err != nil {
 		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
@@ -91,7 +104,7 @@ func manageGate(w http.ResponseWriter, r *http.Request, q *store.Queries) (cente
 		httpx.WriteAPIError(w, r, http.StatusUnauthorized, httpx.CodeUnauthenticated, "unauthenticated")
 		return 0, 0, false
 	}
-	if !requireTeacher(r.Context(), w, r, q, userID, centerID) {
+	if !requireTeacherCan(r.Context(), w, r, q, userID, centerID, can) {
 		return 0, 0, false
 	}
 	return centerID, userID, true
@@ -255,7 +268,7 @@ func manageListTeachers(database *db.DB) http.HandlerFunc {
 func manageAddTeacher(database *db.DB, hub *live.Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		centerID, _, ok := manageGate(w, r, store.New(database.Pool()))
+		centerID, userID, ok := manageGate(w, r, store.New(database.Pool()))
 		if !ok {
 			return
 		}
@@ -263,6 +276,10 @@ func manageAddTeacher(database *db.DB, hub *live.Hub) http.HandlerFunc {
 		if !httpx.DecodeJSONBody(w, r, &req) {
 			return
 		}
+		// Only a head teacher may appoint another.
+		if req.IsHeadTeacher && !requireTeacherCan(ctx, w, r, store.New(database.Pool()), userID, centerID, mcdomain.CanLead) {
+			return
+		}
 		if req.UserID == 0 {
 			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "user_id required")
 			return
@@ -319,7 +336,7 @@ func manageAddTeacher(database *db.DB, hub *live.Hub) http.HandlerFunc {
 func manageSetTeacherHead(database *db.DB, hub *live.Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		q := store.New(database.Pool())
-		centerID, _, ok := manageGate(w, r, q)
+		centerID, _, ok := manageGateCan(w, r, q, mcdomain.CanLead)
 		if !ok {
 			return
 		}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 )
 
@@ -25,6 +26,26 @@ var (
 	}
 )
 
+var teacherAccessColumns = []string{"id", "is_head_teacher", "role", "term_id", "in_scope"}
+
+// expectTeacherAccess mocks GetTeacherAccess for a non-admin caller taking a
+// role-checked action. Role "" means the caller does not teach the center;
+// "head" sets the head flag.
+func expectTeacherAccess(mock pgxmock.PgxPoolIface, userID, centerID int64, role string) {
+	q := mock.ExpectQuery(`FROM math_center_teachers t\s+LEFT JOIN math_center_terms`).
+		WithArgs(userID, centerID)
+	if role == "" {
+		q.WillReturnError(pgx.ErrNoRows)
+		return
+	}
+	isHead := role == "head"
+	if isHead {
+		role = "teacher"
+	}
+	q.WillReturnRows(mock.NewRows(teacherAccessColumns).
+		AddRow(int64(1), isHead, role, (*int64)(nil), true))
+}
+
 // expectTeacherCheck mocks IsTeacherInCenter for a non-admin caller.
 func expectTeacherCheck(mock pgxmock.PgxPoolIface, userID, centerID int64, isTeacher bool) {
 	mock.ExpectQuery(`FROM math_center_teachers\s+WHERE user_id = \$1\s+AND math_center_id = \$2`).
@@ -38,7 +59,7 @@ func TestManage_TeacherAllowedRegardlessOfTitle(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`FROM math_center_groups g\s+WHERE g.math_center_id = \$1`).
 		WithArgs(int64(42)).
 		WillReturnRows(mock.NewRows(manageGroupColumns))
@@ -57,7 +78,7 @@ func TestManage_NonTeacherForbidden(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, false)
+	expectTeacherAccess(mock, 3, 42, "")
 
 	req := authedRequest(t, access, 3, http.MethodGet, "/centers/42/manage/groups", nil)
 	rr := httptest.NewRecorder()
@@ -282,7 +303,7 @@ func TestManage_CreateGroup(t *testing.T) {
 
 	now := time.Now()
 	grade := int32(5)
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectBegin()
 	mock.ExpectQuery(`SELECT graduation_year FROM math_centers`).
 		WithArgs(int64(42)).
@@ -314,7 +335,7 @@ func TestManage_DeleteGroupForeignCenter(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// The group belongs to a DIFFERENT center → treated as not found.
 	mock.ExpectQuery(`FROM math_center_groups\s+WHERE id = \$1`).
 		WithArgs(int64(5)).
@@ -335,7 +356,7 @@ func TestManage_AddTeacherHappy(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectBegin()
 	mock.ExpectQuery(`FROM math_center_students s`).
 		WithArgs(int64(55), int64(42)).
@@ -362,7 +383,7 @@ func TestManage_RemoveSoleSeniorTeacherAllowed(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`FROM math_center_teachers\s+WHERE id = \$1`).
 		WithArgs(int64(8)).
 		WillReturnRows(mock.NewRows(manageTeacherColumns).AddRow(int64(8), int64(3), int64(42), true, now))
@@ -385,7 +406,7 @@ func TestManage_AddStudentForeignGroup(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectBegin()
 	mock.ExpectQuery(`FROM math_center_groups\s+WHERE id = \$1`).
 		WithArgs(int64(5)).
@@ -409,7 +430,7 @@ func TestManage_MoveStudent(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// Resolve the student, then its current group (in center), then the target.
 	mock.ExpectQuery(`FROM math_center_students\s+WHERE id = \$1`).
 		WithArgs(int64(11)).
@@ -441,7 +462,7 @@ func TestManage_SetStudentRazborAccess(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`FROM math_center_students\s+WHERE id = \$1`).
 		WithArgs(int64(11)).
 		WillReturnRows(mock.NewRows(manageStudentColumns).
@@ -471,7 +492,7 @@ func TestManage_ListStudentSeriesRazborAccess(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`FROM math_center_students\s+WHERE id = \$1`).
 		WithArgs(int64(11)).
 		WillReturnRows(mock.NewRows(manageStudentColumns).
@@ -508,7 +529,7 @@ func TestManage_SetStudentSeriesRazborAccess(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`FROM math_center_students\s+WHERE id = \$1`).
 		WithArgs(int64(11)).
 		WillReturnRows(mock.NewRows(manageStudentColumns).
@@ -538,7 +559,7 @@ func TestManage_SetStudentSeriesRazborAccessRejectsForeignSeries(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`FROM math_center_students\s+WHERE id = \$1`).
 		WithArgs(int64(11)).
 		WillReturnRows(mock.NewRows(manageStudentColumns).
@@ -567,7 +588,7 @@ func TestManage_UserSearch(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`FROM users\s+WHERE username ILIKE`).
 		WithArgs("an").
 		WillReturnRows(mock.NewRows([]string{"id", "username", "first_name", "middle_name", "last_name"}).
@@ -587,7 +608,7 @@ func TestManage_UserSearchShortQuery(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// q too short → empty result, no SearchUsers query issued.
 
 	req := authedRequest(t, access, 3, http.MethodGet, "/centers/42/manage/user-search?q=a", nil)
@@ -608,7 +629,7 @@ func TestManage_CreateTeacherInvite(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// Validate the teacher preset → the center must exist.
 	mock.ExpectQuery(`FROM math_centers\s+WHERE id = \$1`).
 		WithArgs(int64(42)).
@@ -646,7 +667,7 @@ func TestManage_CreatePersonalStudentInvite(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`FROM users user_row`).
 		WithArgs(int64(77), int64(42)).
 		WillReturnRows(mock.NewRows([]string{"first_name", "middle_name", "last_name"}).
@@ -684,7 +705,7 @@ func TestManage_ListPersonalInviteStudents(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	mock.ExpectQuery(`WITH selected_term AS`).
 		WithArgs(int64(42)).
 		WillReturnRows(mock.NewRows([]string{
@@ -715,7 +736,7 @@ func TestManage_CreateInviteBadRole(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 
 	body := strings.NewReader(`{"role":"admin","description":"x","max_uses":1,"expires_in_hours":1}`)
 	req := authedRequest(t, access, 3, http.MethodPost, "/centers/42/manage/invites", body)
@@ -734,7 +755,7 @@ func TestManage_RevokeInviteForeignCenter(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 
 	now := time.Now()
-	expectTeacherCheck(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	// Token belongs to another center → not found.
 	mock.ExpectQuery(`FROM invitation_tokens\s+WHERE id = \$1`).
 		WithArgs(int64(20)).
@@ -751,3 +772,122 @@ func TestManage_RevokeInviteForeignCenter(t *testing.T) {
 
 // ptrInt64 returns a pointer to v, for nullable *int64 mock args/rows.
 func ptrInt64(v int64) *int64 { return &v }
+
+func TestRolloverTerm_RejectsStudentOfAnotherTerm(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	grade := int32(9)
+	expectTeacherAccess(mock, 3, 42, "teacher")
+	mock.ExpectBegin()
+	mock.ExpectExec(`FROM math_centers WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(42)).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery(`FROM math_center_terms\s+WHERE math_center_id = \$1\s+ORDER BY`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows(manageTermColumns).
+			AddRow(int64(70), int64(42), "academic", &grade, true, now, (*time.Time)(nil)))
+	mock.ExpectQuery(`FROM math_center_groups\s+WHERE term_id = \$1`).
+		WithArgs(int64(70)).
+		WillReturnRows(mock.NewRows(append(manageGroupColumns, "term_id")).
+			AddRow(int64(1), int64(42), "9-1", now, int64(70)))
+	mock.ExpectQuery(`FROM math_center_students s`).
+		WithArgs(int64(70)).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "group_id", "term_id", "group_name", "first_name", "middle_name", "last_name"}).
+			AddRow(int64(500), int64(10), int64(1), int64(70), "9-1", "Аня", (*string)(nil), "Иванова"))
+	mock.ExpectRollback()
+
+	body := strings.NewReader(`{"dry_run":true,"students":[{"user_id":99}]}`)
+	req := authedRequest(t, access, 3, http.MethodPost, "/centers/42/terms/rollover", body)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestManage_SetTeacherRoleRequiresHead(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectTeacherAccess(mock, 3, 42, "teacher")
+
+	body := strings.NewReader(`{"role":"teacher"}`)
+	req := authedRequest(t, access, 3, http.MethodPut, "/centers/42/manage/teachers/3/role", body)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestManage_SetTeacherRoleScopesGraderToTerm(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	expectTeacherAccess(mock, 3, 42, "head")
+	mock.ExpectQuery(`FROM math_center_terms\s+WHERE id = \$1`).
+		WithArgs(int64(70)).
+		WillReturnRows(mock.NewRows(manageTermColumns).
+			AddRow(int64(70), int64(42), "camp", (*int32)(nil), true, now, (*time.Time)(nil)))
+	mock.ExpectQuery(`FROM math_center_teachers\s+WHERE id = \$1`).
+		WithArgs(int64(8)).
+		WillReturnRows(mock.NewRows(manageTeacherColumns).AddRow(int64(8), int64(55), int64(42), false, now))
+	termID := int64(70)
+	mock.ExpectExec(`UPDATE math_center_teachers\s+SET is_head_teacher = \$2`).
+		WithArgs(int64(8), false, "grader", &termID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	body := strings.NewReader(`{"role":"grader","term_id":70}`)
+	req := authedRequest(t, access, 3, http.MethodPut, "/centers/42/manage/teachers/8/role", body)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var got struct {
+		Role   string `json:"role"`
+		TermID *int64 `json:"term_id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Role != "grader" || got.TermID == nil || *got.TermID != 70 {
+		t.Errorf("role = %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestManage_SetTeacherRoleRejectsScopedHead(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectTeacherAccess(mock, 3, 42, "head")
+
+	body := strings.NewReader(`{"role":"head","term_id":70}`)
+	req := authedRequest(t, access, 3, http.MethodPut, "/centers/42/manage/teachers/8/role", body)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
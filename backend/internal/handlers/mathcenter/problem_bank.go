@@ -0,0 +1,581 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// Problem bank. Series problems are numbered rows with no text of their own;
+// the bank holds reusable problems with statement, solution, tags,
+// difficulty and source, and a series problem placed from the bank links
+// back to it so the bank can show how the problem actually went.
+//
+// A bank problem belongs to one center (every teacher there may edit it) or
+// is school-wide (visible from every center; only its author or an admin
+// edits it).
+
+const (
+	defaultBankListLimit = 100
+	maxBankListLimit     = 500
+)
+
+type bankProblemStatsView struct {
+	Uses         int64    `json:"uses"`
+	FinishedUses int64    `json:"finished_uses"`
+	Assigned     int64    `json:"assigned"`
+	Attempted    int64    `json:"attempted"`
+	Accepted     int64    `json:"accepted"`
+	SolveRate    *float64 `json:"solve_rate"`
+}
+
+type bankProblemView struct {
+	ID              int64                `json:"id"`
+	MathCenterID    *int64               `json:"math_center_id"`
+	SchoolWide      bool                 `json:"school_wide"`
+	Title           string               `json:"title"`
+	StatementTex    string               `json:"statement_tex"`
+	SolutionTex     *string              `json:"solution_tex"`
+	Tags            []string             `json:"tags"`
+	Difficulty      *int16               `json:"difficulty"`
+	Source          string               `json:"source"`
+	SubproblemCount int                  `json:"subproblem_count"`
+	CreatedByUserID *int64               `json:"created_by_user_id"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+	Stats           bankProblemStatsView `json:"stats"`
+}
+
+type bankProblemRequest struct {
+	Title           string   `json:"title"`
+	StatementTex    string   `json:"statement_tex"`
+	SolutionTex     *string  `json:"solution_tex"`
+	Tags            []string `json:"tags"`
+	Difficulty      *int     `json:"difficulty"`
+	Source          string   `json:"source"`
+	SubproblemCount int      `json:"subproblem_count"`
+	// SchoolWide is read on create only; a problem keeps its scope.
+	SchoolWide bool `json:"school_wide"`
+}
+
+func (req bankProblemRequest) normalized() (mc.BankProblemInput, *int16, string) {
+	in := mc.BankProblemInput{
+		Title: req.Title, StatementTex: req.StatementTex, SolutionTex: req.SolutionTex,
+		Tags: req.Tags, Difficulty: req.Difficulty, Source: req.Source, SubproblemCount: req.SubproblemCount,
+	}
+	if err := mc.NormalizeBankProblem(&in); err != nil {
+		return in, nil, err.Error()
+	}
+	var difficulty *int16
+	if in.Difficulty != nil {
+		d := int16(*in.Difficulty)
+		difficulty = &d
+	}
+	return in, difficulty, ""
+}
+
+func toBankProblemView(b store.BankProblem, stat store.BankProblemStat) bankProblemView {
+	return bankProblemView{
+		ID:              b.ID,
+		MathCenterID:    b.MathCenterID,
+		SchoolWide:      b.MathCenterID == nil,
+		Title:           b.Title,
+		StatementTex:    b.StatementTex,
+		SolutionTex:     b.SolutionTex,
+		Tags:            b.Tags,
+		Difficulty:      b.Difficulty,
+		Source:          b.Source,
+		SubproblemCount: int(b.SubproblemCount),
+		CreatedByUserID: b.CreatedByUserID,
+		CreatedAt:       b.CreatedAt,
+		UpdatedAt:       b.UpdatedAt,
+		Stats: bankProblemStatsView{
+			Uses:         stat.Uses,
+			FinishedUses: stat.FinishedUses,
+			Assigned:     stat.Assigned,
+			Attempted:    stat.Attempted,
+			Accepted:     stat.Accepted,
+			SolveRate:    mc.BankSolveRate(stat.Accepted, stat.Assigned),
+		},
+	}
+}
+
+// bankStatsByID loads the history of the given bank problems keyed by id.
+func bankStatsByID(ctx context.Context, q *store.Queries, ids []int64) (map[int64]store.BankProblemStat, error) {
+	out := make(map[int64]store.BankProblemStat, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	stats, err := q.BankProblemStats(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stats {
+		out[s.BankProblemID] = s
+	}
+	return out, nil
+}
+
+// loadVisibleBankProblem fetches a bank problem the center can see (its own
+// or a school-wide one) and writes 404 otherwise, so one center cannot probe
+// another's bank by id.
+func loadVisibleBankProblem(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, centerID, bankProblemID int64) (store.BankProblem, bool) {
+	b, err := q.GetBankProblem(ctx, bankProblemID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.LogErrorContext(ctx, "problem bank: get", err, "bank_problem_id", bankProblemID)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return store.BankProblem{}, false
+	}
+	if err != nil || b.ArchivedAt != nil || (b.MathCenterID != nil && *b.MathCenterID != centerID) {
+		httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "bank problem not found")
+		return store.BankProblem{}, false
+	}
+	return b, true
+}
+
+// requireBankEditor allows any teacher of the owning center (checked by the
+// caller) to edit a center problem; a school-wide one only by its author or
+// an admin.
+func requireBankEditor(w http.ResponseWriter, r *http.Request, userID int64, b store.BankProblem) bool {
+	if b.MathCenterID != nil || callerIsAdmin(r) || (b.CreatedByUserID != nil && *b.CreatedByUserID == userID) {
+		return true
+	}
+	httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "only the author edits a school-wide problem")
+	return false
+}
+
+// bankPath parses the {centerID} and {bankProblemID} path parameters.
+func bankPath(w http.ResponseWriter, r *http.Request) (centerID, bankProblemID int64, ok bool) {
+	centerID, err := pathInt64(r, "centerID")
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+		return 0, 0, false
+	}
+	bankProblemID, err = pathInt64(r, "bankProblemID")
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid bank problem id")
+		return 0, 0, false
+	}
+	return centerID, bankProblemID, true
+}
+
+// ListBankProblems — teacher of the center. Returns the center's and the
+// school-wide bank problems with their history. Filters: ?tag=, ?difficulty=,
+// ?q= (title or source). ?sort=solve_rate orders hardest first by the real
+// solve rate (never-used problems last) within the ?limit= most recently
+// edited matches; the default keeps that recency order.
+func ListBankProblems(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		query := r.URL.Query()
+		params := store.ListBankProblemsParams{
+			MathCenterID: centerID,
+			Search:       query.Get("q"),
+			Limit:        defaultBankListLimit,
+		}
+		if tag := query.Get("tag"); tag != "" {
+			tags, err := mc.NormalizeBankTags([]string{tag})
+			if err != nil {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, err.Error())
+				return
+			}
+			if len(tags) == 1 {
+				params.Tag = tags[0]
+			}
+		}
+		if raw := query.Get("difficulty"); raw != "" {
+			d, err := strconv.Atoi(raw)
+			if err != nil || d < mc.MinBankDifficulty || d > mc.MaxBankDifficulty {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid difficulty")
+				return
+			}
+			d16 := int16(d)
+			params.Difficulty = &d16
+		}
+		if raw := query.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 || n > maxBankListLimit {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid limit")
+				return
+			}
+			params.Limit = int32(n)
+		}
+		sortBy := query.Get("sort")
+		if sortBy != "" && sortBy != "solve_rate" && sortBy != "recent" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "sort must be recent or solve_rate")
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanView) {
+			return
+		}
+		rows, err := q.ListBankProblems(ctx, params)
+		if err != nil {
+			logger.LogErrorContext(ctx, "problem bank: list", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		ids := make([]int64, len(rows))
+		for i, b := range rows {
+			ids[i] = b.ID
+		}
+		stats, err := bankStatsByID(ctx, q, ids)
+		if err != nil {
+			logger.LogErrorContext(ctx, "problem bank: stats", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		out := make([]bankProblemView, len(rows))
+		for i, b := range rows {
+			out[i] = toBankProblemView(b, stats[b.ID])
+		}
+		if sortBy == "solve_rate" {
+			sort.SliceStable(out, func(i, j int) bool {
+				a, b := out[i].Stats.SolveRate, out[j].Stats.SolveRate
+				if a == nil || b == nil {
+					return a != nil
+				}
+				return *a < *b
+			})
+		}
+		httpx.WriteJSON(w, http.StatusOK, map[string]any{"problems": out})
+	}
+}
+
+// CreateBankProblem — teacher of the center. school_wide=true files the
+// problem in the school-wide bank instead of the center's.
+func CreateBankProblem(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		var req bankProblemRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		in, difficulty, vErr := req.normalized()
+		if vErr != "" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanEditContent) {
+			return
+		}
+		owner := &centerID
+		if req.SchoolWide {
+			owner = nil
+		}
+		created, err := q.CreateBankProblem(ctx, store.CreateBankProblemParams{
+			MathCenterID: owner, Title: in.Title, StatementTex: in.StatementTex, SolutionTex: in.SolutionTex,
+			Tags: in.Tags, Difficulty: difficulty, Source: in.Source, SubproblemCount: int32(in.SubproblemCount),
+			CreatedByUserID: userID,
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "problem bank: create", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to create bank problem")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusCreated, toBankProblemView(created, store.BankProblemStat{}))
+	}
+}
+
+// GetBankProblem — teacher of the center; the problem must be the center's
+// own or school-wide.
+func GetBankProblem(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, bankProblemID, ok := bankPath(w, r)
+		if !ok {
+			return
+		}
+		q := store.New(database.Pool())
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanView) {
+			return
+		}
+		b, ok := loadVisibleBankProblem(ctx, w, r, q, centerID, bankProblemID)
+		if !ok {
+			return
+		}
+		stats, err := bankStatsByID(ctx, q, []int64{b.ID})
+		if err != nil {
+			logger.LogErrorContext(ctx, "problem bank: stats", err, "bank_problem_id", b.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, toBankProblemView(b, stats[b.ID]))
+	}
+}
+
+// UpdateBankProblem — editor of the problem (see requireBankEditor). Series
+// that already placed the problem keep their own problem rows; only the bank
+// entry changes.
+func UpdateBankProblem(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, bankProblemID, ok := bankPath(w, r)
+		if !ok {
+			return
+		}
+		var req bankProblemRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		in, difficulty, vErr := req.normalized()
+		if vErr != "" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
+			return
+		}
+
+		q := store.New(database.Pool())
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanEditContent) {
+			return
+		}
+		b, ok := loadVisibleBankProblem(ctx, w, r, q, centerID, bankProblemID)
+		if !ok || !requireBankEditor(w, r, userID, b) {
+			return
+		}
+		updated, err := q.UpdateBankProblem(ctx, store.UpdateBankProblemParams{
+			ID: b.ID, Title: in.Title, StatementTex: in.StatementTex, SolutionTex: in.SolutionTex,
+			Tags: in.Tags, Difficulty: difficulty, Source: in.Source, SubproblemCount: int32(in.SubproblemCount),
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "bank problem not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "problem bank: update", err, "bank_problem_id", b.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to update bank problem")
+			return
+		}
+		stats, err := bankStatsByID(ctx, q, []int64{b.ID})
+		if err != nil {
+			logger.LogErrorContext(ctx, "problem bank: stats", err, "bank_problem_id", b.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, toBankProblemView(updated, stats[b.ID]))
+	}
+}
+
+// ArchiveBankProblem — editor of the problem. Archiving hides the problem
+// from the bank; series that placed it keep the link and its history.
+func ArchiveBankProblem(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, bankProblemID, ok := bankPath(w, r)
+		if !ok {
+			return
+		}
+		q := store.New(database.Pool())
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanEditContent) {
+			return
+		}
+		b, ok := loadVisibleBankProblem(ctx, w, r, q, centerID, bankProblemID)
+		if !ok || !requireBankEditor(w, r, userID, b) {
+			return
+		}
+		if _, err := q.ArchiveBankProblem(ctx, b.ID); err != nil {
+			logger.LogErrorContext(ctx, "problem bank: archive", err, "bank_problem_id", b.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to archive bank problem")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type addBankProblemRequest struct {
+	BankProblemID int64 `json:"bank_problem_id"`
+	Number        int   `json:"number"`
+	// SubproblemCount overrides the bank's default part count when set.
+	SubproblemCount *int `json:"subproblem_count"`
+}
+
+// AddBankProblemToSeries — teacher of the series' center. Places a bank
+// problem the center can see into the series as problem `number`, with the
+// bank's subproblem structure unless overridden, and returns the series.
+func AddBankProblemToSeries(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		seriesID, err := pathInt64(r, "seriesID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid series id")
+			return
+		}
+		var req addBankProblemRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if req.BankProblemID <= 0 {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "bank_problem_id is required")
+			return
+		}
+		if req.Number < 0 || req.Number > maxOrdinal {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, fmt.Sprintf("problem number must be 0..%d", maxOrdinal))
+			return
+		}
+		if req.SubproblemCount != nil && (*req.SubproblemCount < 0 || *req.SubproblemCount > mc.MaxSubproblemsPerProblem) {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, fmt.Sprintf("subproblem_count must be 0..%d", mc.MaxSubproblemsPerProblem))
+			return
+		}
+
+		q := store.New(database.Pool())
+		series, err := q.GetSeries(ctx, seriesID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "series not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "problem bank: get series", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanEditContent) {
+			return
+		}
+		b, ok := loadVisibleBankProblem(ctx, w, r, q, series.MathCenterID, req.BankProblemID)
+		if !ok {
+			return
+		}
+		count := int(b.SubproblemCount)
+		if req.SubproblemCount != nil {
+			count = *req.SubproblemCount
+		}
+
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "problem bank: begin tx", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+		problem, err := qx.CreateProblemFromBank(ctx, series.ID, int32(req.Number), b.ID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "problem number already exists in this series")
+				return
+			}
+			logger.LogErrorContext(ctx, "problem bank: place problem", err, "series_id", series.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to add problem")
+			return
+		}
+		if err := writeSubproblems(ctx, qx, problem.ID, count); err != nil {
+			logger.LogErrorContext(ctx, "problem bank: write subproblems", err, "series_id", series.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to add problem")
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			logger.LogErrorContext(ctx, "problem bank: commit", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+
+		view, err := buildSeriesView(ctx, q, seriesFromGetRow(series))
+		if err != nil {
+			logger.LogErrorContext(ctx, "problem bank: build series view", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusCreated, view)
+	}
+}
+
+type seriesBankLinkView struct {
+	ProblemID     int64  `json:"problem_id"`
+	ProblemNumber int    `json:"problem_number"`
+	Display       string `json:"display"`
+	BankProblemID int64  `json:"bank_problem_id"`
+	Title         string `json:"title"`
+}
+
+// ListSeriesBankLinks — teacher of the series' center. Lists the series
+// problems that were placed from the bank.
+func ListSeriesBankLinks(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		seriesID, err := pathInt64(r, "seriesID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid series id")
+			return
+		}
+		q := store.New(database.Pool())
+		series, err := q.GetSeries(ctx, seriesID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "series not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "problem bank: get series", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanView) {
+			return
+		}
+		links, err := q.ListSeriesBankLinks(ctx, series.ID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "problem bank: list series links", err, "series_id", series.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		out := make([]seriesBankLinkView, len(links))
+		for i, l := range links {
+			out[i] = seriesBankLinkView{
+				ProblemID: l.ProblemID, ProblemNumber: int(l.ProblemNumber), Display: mc.ProblemDisplayName(int(l.ProblemNumber)),
+				BankProblemID: l.BankProblemID, Title: l.Title,
+			}
+		}
+		httpx.WriteJSON(w, http.StatusOK, map[string]any{"problems": out})
+	}
+}
@@ -0,0 +1,201 @@
+package mathcenter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var bankProblemColumns = []string{
+	"id", "math_center_id", "title", "statement_tex", "solution_tex", "tags", "difficulty", "source",
+	"subproblem_count", "created_by_user_id", "created_at", "updated_at", "archived_at",
+}
+
+var bankStatColumns = []string{"bank_problem_id", "uses", "finished_uses", "assigned", "attempted", "accepted"}
+
+func bankProblemRow(rows *pgxmock.Rows, id int64, centerID *int64, author int64, subproblems int32) *pgxmock.Rows {
+	now := time.Now()
+	difficulty := int16(3)
+	return rows.AddRow(id, centerID, "Шахматная доска", `\item Доска`, (*string)(nil), []string{"раскраски"}, &difficulty,
+		"Турнир городов", subproblems, &author, now, now, (*time.Time)(nil))
+}
+
+func TestCreateBankProblem_SchoolWide(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	difficulty := int16(4)
+	mock.ExpectQuery(`INSERT INTO math_center_bank_problems`).
+		WithArgs((*int64)(nil), "Шахматная доска", `\item Доска`, (*string)(nil), []string{"инварианты", "раскраски"},
+			&difficulty, "Турнир городов", int32(2), int64(7)).
+		WillReturnRows(bankProblemRow(mock.NewRows(bankProblemColumns), 5, nil, 7, 2))
+
+	body, _ := json.Marshal(map[string]any{
+		"title": " Шахматная доска ", "statement_tex": `\item Доска`, "tags": []string{"Раскраски", "инварианты", "раскраски"},
+		"difficulty": 4, "source": "Турнир городов", "subproblem_count": 2, "school_wide": true,
+	})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, authedRequest(t, access, 7, http.MethodPost, "/centers/42/problem-bank", bytes.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got %d, want 201; body=%s", rr.Code, rr.Body.String())
+	}
+	var got struct {
+		SchoolWide bool `json:"school_wide"`
+		Stats      struct {
+			SolveRate *float64 `json:"solve_rate"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !got.SchoolWide || got.Stats.SolveRate != nil {
+		t.Errorf("got %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestListBankProblems_SortsBySolveRate(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	center := int64(42)
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	rows := mock.NewRows(bankProblemColumns)
+	bankProblemRow(rows, 1, &center, 7, 0)
+	bankProblemRow(rows, 2, nil, 8, 0)
+	bankProblemRow(rows, 3, &center, 7, 0)
+	mock.ExpectQuery(`FROM math_center_bank_problems\s+WHERE \(math_center_id = \$1 OR math_center_id IS NULL\)`).
+		WithArgs(int64(42), "графы", (*int16)(nil), "", int32(100)).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`WITH links AS`).
+		WithArgs([]int64{1, 2, 3}).
+		WillReturnRows(mock.NewRows(bankStatColumns).
+			AddRow(int64(1), int64(2), int64(2), int64(40), int64(30), int64(30)).
+			AddRow(int64(2), int64(1), int64(1), int64(20), int64(10), int64(5)))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, authedRequest(t, access, 7, http.MethodGet, "/centers/42/problem-bank?tag=Графы&sort=solve_rate", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var got struct {
+		Problems []struct {
+			ID    int64 `json:"id"`
+			Stats struct {
+				SolveRate *float64 `json:"solve_rate"`
+			} `json:"stats"`
+		} `json:"problems"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got.Problems) != 3 || got.Problems[0].ID != 2 || got.Problems[1].ID != 1 || got.Problems[2].ID != 3 {
+		t.Fatalf("order = %+v; want hardest (2) first, unused (3) last", got.Problems)
+	}
+	if rate := got.Problems[0].Stats.SolveRate; rate == nil || *rate != 0.25 {
+		t.Errorf("solve rate of 2 = %v, want 0.25", rate)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestBankProblem_Visibility(t *testing.T) {
+	t.Parallel()
+
+	t.Run("another center's problem is not found", func(t *testing.T) {
+		t.Parallel()
+		mock, _ := pgxmock.NewPool()
+		defer mock.Close()
+		r, access, _ := newRouter(t, mock)
+		other := int64(43)
+		expectTeacherAccess(mock, 7, 42, "teacher")
+		mock.ExpectQuery(`FROM math_center_bank_problems\s+WHERE id = \$1`).
+			WithArgs(int64(5)).
+			WillReturnRows(bankProblemRow(mock.NewRows(bankProblemColumns), 5, &other, 7, 0))
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authedRequest(t, access, 7, http.MethodGet, "/centers/42/problem-bank/5", nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("got %d, want 404", rr.Code)
+		}
+	})
+
+	t.Run("school-wide problem is edited by its author only", func(t *testing.T) {
+		t.Parallel()
+		mock, _ := pgxmock.NewPool()
+		defer mock.Close()
+		r, access, _ := newRouter(t, mock)
+		expectTeacherAccess(mock, 9, 42, "teacher")
+		mock.ExpectQuery(`FROM math_center_bank_problems\s+WHERE id = \$1`).
+			WithArgs(int64(5)).
+			WillReturnRows(bankProblemRow(mock.NewRows(bankProblemColumns), 5, nil, 7, 0))
+
+		body, _ := json.Marshal(map[string]any{"title": "t", "statement_tex": "x"})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authedRequest(t, access, 9, http.MethodPut, "/centers/42/problem-bank/5", bytes.NewReader(body)))
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("got %d, want 403", rr.Code)
+		}
+	})
+}
+
+func TestAddBankProblemToSeries(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now, (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	mock.ExpectQuery(`FROM math_center_bank_problems\s+WHERE id = \$1`).
+		WithArgs(int64(5)).
+		WillReturnRows(bankProblemRow(mock.NewRows(bankProblemColumns), 5, nil, 8, 2))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO math_center_problems \(series_id, number, bank_problem_id\)`).
+		WithArgs(int64(100), int32(3), int64(5)).
+		WillReturnRows(mock.NewRows(problemColumns).AddRow(int64(500), int64(100), int32(3), now))
+	for i, label := range []string{"a", "b"} {
+		mock.ExpectQuery(`INSERT INTO math_center_subproblems`).
+			WithArgs(int64(500), label).
+			WillReturnRows(mock.NewRows([]string{"id", "problem_id", "label", "created_at"}).AddRow(int64(900+i), int64(500), label, now))
+	}
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(problemColumns).AddRow(int64(500), int64(100), int32(3), now))
+	mock.ExpectQuery(`FROM math_center_subproblems s\s+JOIN math_center_problems`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(subproblemRowColumns).
+			AddRow(int64(900), int64(500), "a").
+			AddRow(int64(901), int64(500), "b"))
+	mock.ExpectQuery(`FROM math_center_subproblem_solutions ss`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(subproblemSolutionMetaColumns))
+
+	body, _ := json.Marshal(map[string]any{"bank_problem_id": 5, "number": 3})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, authedRequest(t, access, 7, http.MethodPost, "/series/100/bank-problems", bytes.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got %d, want 201; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,395 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// publicationScheduleRequest is the body of every publish-schedule endpoint.
+// Exactly one of PublishAt and AtSeriesDue is set; AtSeriesDue is only
+// offered for разборы. SubproblemIDs is the shared разбор to publish.
+type publicationScheduleRequest struct {
+	PublishAt     *time.Time `json:"publish_at"`
+	AtSeriesDue   bool       `json:"at_series_due"`
+	SubproblemIDs []int64    `json:"subproblem_ids"`
+}
+
+// publicationScheduleView is one schedule. RunAt is when it runs (or ran):
+// publish_at, or the series due_at for at_series_due schedules.
+type publicationScheduleView struct {
+	ID              int64      `json:"id"`
+	TargetKind      string     `json:"target_kind"`
+	SeriesID        *int64     `json:"series_id,omitempty"`
+	LikbezID        *int64     `json:"likbez_id,omitempty"`
+	SubproblemIDs   []int64    `json:"subproblem_ids,omitempty"`
+	PublishAt       *time.Time `json:"publish_at,omitempty"`
+	AtSeriesDue     bool       `json:"at_series_due"`
+	RunAt           time.Time  `json:"run_at"`
+	Status          string     `json:"status"`
+	LastError       string     `json:"last_error,omitempty"`
+	CreatedByUserID *int64     `json:"created_by_user_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+}
+
+func toPublicationScheduleView(p store.PublicationSchedule) publicationScheduleView {
+	return publicationScheduleView{
+		ID:              p.ID,
+		TargetKind:      p.TargetKind,
+		SeriesID:        p.SeriesID,
+		LikbezID:        p.LikbezID,
+		SubproblemIDs:   p.SubproblemIDs,
+		PublishAt:       p.PublishAt,
+		AtSeriesDue:     p.AtSeriesDue,
+		RunAt:           p.RunAt,
+		Status:          p.Status,
+		LastError:       p.LastError,
+		CreatedByUserID: p.CreatedByUserID,
+		CreatedAt:       p.CreatedAt,
+		FinishedAt:      p.FinishedAt,
+	}
+}
+
+// validatePublishAt checks a fixed publication time: present and in the
+// future. A past time would publish at once; the publish button does that.
+func validatePublishAt(w http.ResponseWriter, r *http.Request, req publicationScheduleRequest, now time.Time) bool {
+	if req.AtSeriesDue {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "at_series_due is only available for разборы")
+		return false
+	}
+	if req.PublishAt == nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "publish_at required")
+		return false
+	}
+	if !req.PublishAt.After(now) {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "publish_at must be in the future")
+		return false
+	}
+	return true
+}
+
+// ScheduleSeriesPublication — teacher-only. Schedules the series statement
+// to be published at publish_at, replacing any pending schedule for it. The
+// statement and problems only need to exist by then; if they don't, the
+// schedule fails and shows why in the schedule list.
+func ScheduleSeriesPublication(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		seriesID, err := pathInt64(r, "seriesID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid series id")
+			return
+		}
+		var req publicationScheduleRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if !validatePublishAt(w, r, req, time.Now()) {
+			return
+		}
+
+		q := store.New(database.Pool())
+		series, err := q.GetSeries(ctx, seriesID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "series not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "series schedule: get series", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanEditContent) {
+			return
+		}
+		if series.PublishedAt != nil {
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "series is already published")
+			return
+		}
+
+		sched, err := replacePublicationSchedule(ctx, database, store.CreatePublicationScheduleParams{
+			MathCenterID:    series.MathCenterID,
+			TargetKind:      store.PublicationTargetSeries,
+			SeriesID:        &series.ID,
+			PublishAt:       req.PublishAt,
+			CreatedByUserID: userID,
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "series schedule: save", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to schedule publication")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusCreated, toPublicationScheduleView(sched))
+	}
+}
+
+// ScheduleLikbezPublication — teacher-only. Same as the series variant for a
+// likbez entry.
+func ScheduleLikbezPublication(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		likbezID, err := pathInt64(r, "likbezID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid likbez id")
+			return
+		}
+		var req publicationScheduleRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if !validatePublishAt(w, r, req, time.Now()) {
+			return
+		}
+
+		q := store.New(database.Pool())
+		row, err := q.GetLikbez(ctx, likbezID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "likbez not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "likbez schedule: get likbez", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if !requireTeacherCan(ctx, w, r, q, userID, row.MathCenterID, mc.CanEditContent) {
+			return
+		}
+		if row.PublishedAt != nil {
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "likbez is already published")
+			return
+		}
+
+		sched, err := replacePublicationSchedule(ctx, database, store.CreatePublicationScheduleParams{
+			MathCenterID:    row.MathCenterID,
+			TargetKind:      store.PublicationTargetLikbez,
+			LikbezID:        &row.ID,
+			PublishAt:       req.PublishAt,
+			CreatedByUserID: userID,
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "likbez schedule: save", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to schedule publication")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusCreated, toPublicationScheduleView(sched))
+	}
+}
+
+// replacePublicationSchedule cancels the target's pending schedule and
+// creates the new one in one transaction.
+func replacePublicationSchedule(ctx context.Context, database *db.DB, arg store.CreatePublicationScheduleParams) (store.PublicationSchedule, error) {
+	tx, err := database.Pool().Begin(ctx)
+	if err != nil {
+		return store.PublicationSchedule{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qx := store.New(tx)
+	if arg.LikbezID != nil {
+		err = qx.CancelPendingLikbezSchedule(ctx, *arg.LikbezID)
+	} else {
+		err = qx.CancelPendingSeriesSchedule(ctx, *arg.SeriesID)
+	}
+	if err != nil {
+		return store.PublicationSchedule{}, err
+	}
+	sched, err := qx.CreatePublicationSchedule(ctx, arg)
+	if err != nil {
+		return store.PublicationSchedule{}, err
+	}
+	return sched, tx.Commit(ctx)
+}
+
+// ScheduleSolutionPublication — teacher-only. Schedules one shared разбор
+// (the same set PublishSubproblemSolutions takes) for publish_at or for the
+// moment the series is due. Every subproblem needs a saved разбор row;
+// its material may still be written before the schedule runs.
+func ScheduleSolutionPublication(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		var req publicationScheduleRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		ids := uniquePositiveIDs(req.SubproblemIDs)
+		if len(ids) == 0 {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "subproblem_ids required")
+			return
+		}
+		now := time.Now()
+		if req.AtSeriesDue == (req.PublishAt != nil) {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "give either publish_at or at_series_due")
+			return
+		}
+		if req.PublishAt != nil && !req.PublishAt.After(now) {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "publish_at must be in the future")
+			return
+		}
+
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "razbor schedule: begin", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		q := store.New(tx)
+		targets, err := q.LockSolutionPublicationTargets(ctx, ids)
+		if err != nil {
+			logger.LogErrorContext(ctx, "razbor schedule: lock targets", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if err := mc.CheckSolutionTargets(targets, len(ids)); err != nil && !errors.Is(err, mc.ErrSolutionNoMaterial) {
+			writeSolutionTargetsError(w, r, err)
+			return
+		}
+		centerID, seriesID := targets[0].MathCenterID, targets[0].SeriesID
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanEditContent) {
+			return
+		}
+		if req.AtSeriesDue {
+			series, err := q.GetSeries(ctx, seriesID)
+			if err != nil {
+				logger.LogErrorContext(ctx, "razbor schedule: get series", err)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+				return
+			}
+			if !series.DueAt.After(now) {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "the series is already due; publish now instead")
+				return
+			}
+		}
+		overlaps, err := q.PendingSolutionScheduleOverlaps(ctx, ids)
+		if err != nil {
+			logger.LogErrorContext(ctx, "razbor schedule: overlap check", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if overlaps {
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "a subproblem is already scheduled; cancel that schedule first")
+			return
+		}
+
+		sched, err := q.CreatePublicationSchedule(ctx, store.CreatePublicationScheduleParams{
+			MathCenterID:    centerID,
+			TargetKind:      store.PublicationTargetSolutions,
+			SeriesID:        &seriesID,
+			SubproblemIDs:   ids,
+			PublishAt:       req.PublishAt,
+			AtSeriesDue:     req.AtSeriesDue,
+			CreatedByUserID: userID,
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "razbor schedule: save", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to schedule publication")
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			logger.LogErrorContext(ctx, "razbor schedule: commit", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusCreated, toPublicationScheduleView(sched))
+	}
+}
+
+// ListPublicationSchedules — teacher of the center. Pending schedules in the
+// order they will run, then the last two weeks of finished ones so a failed
+// schedule and its reason stay visible. A term-scoped assistant sees only
+// their term's.
+func ListPublicationSchedules(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		centerID, err := pathInt64(r, "centerID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid center id")
+			return
+		}
+		q := store.New(database.Pool())
+		access, ok := teacherAccess(ctx, w, r, q, userID, centerID, 0, mc.CanView)
+		if !ok {
+			return
+		}
+		rows, err := q.ListPublicationSchedules(ctx, centerID, access.TermID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "publication schedules: list", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		out := make([]publicationScheduleView, 0, len(rows))
+		for _, p := range rows {
+			out = append(out, toPublicationScheduleView(p))
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// CancelPublicationSchedule — teacher of the schedule's center. Only a
+// pending schedule can be cancelled; one that already ran is a 409.
+func CancelPublicationSchedule(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		scheduleID, err := pathInt64(r, "scheduleID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid schedule id")
+			return
+		}
+		q := store.New(database.Pool())
+		sched, err := q.GetPublicationSchedule(ctx, scheduleID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "schedule not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "publication schedule: get", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if !requireTeacherCan(ctx, w, r, q, userID, sched.MathCenterID, mc.CanEditContent) {
+			return
+		}
+		n, err := q.CancelPublicationSchedule(ctx, sched.ID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "publication schedule: cancel", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to cancel schedule")
+			return
+		}
+		if n == 0 {
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "schedule is no longer pending")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
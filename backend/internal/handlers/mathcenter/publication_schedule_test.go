@@ -0,0 +1,310 @@
+package mathcenter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var publicationScheduleColumns = []string{
+	"id", "math_center_id", "target_kind", "series_id", "likbez_id", "subproblem_ids",
+	"publish_at", "at_series_due", "run_at",
+	"status", "attempts", "last_error", "created_by_user_id", "created_at", "finished_at",
+}
+
+func publicationScheduleRow(id int64, kind string, seriesID, likbezID *int64, ids []int64, publishAt *time.Time, atDue bool, runAt time.Time, status string) []any {
+	if ids == nil {
+		ids = []int64{}
+	}
+	creator := int64(7)
+	return []any{id, int64(42), kind, seriesID, likbezID, ids, publishAt, atDue, runAt,
+		status, int32(0), "", &creator, time.Now(), (*time.Time)(nil)}
+}
+
+func expectTeacher(mock pgxmock.PgxPoolIface, userID, centerID int64) {
+	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
+		WithArgs(userID, centerID).
+		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+}
+
+func TestScheduleSeriesPublication_ReplacesPending(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	at := now.Add(3 * time.Hour).UTC().Truncate(time.Second)
+	seriesID := int64(100)
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(seriesID).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(seriesID, int64(42), int32(1), "S", now.Add(48*time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE math_center_publication_schedule\s+SET status\s+= 'cancelled'.*target_kind = 'series'`).
+		WithArgs(seriesID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(`INSERT INTO math_center_publication_schedule`).
+		WithArgs(int64(42), "series", &seriesID, (*int64)(nil), []int64{}, &at, false, int64(7)).
+		WillReturnRows(mock.NewRows(publicationScheduleColumns).
+			AddRow(publicationScheduleRow(5, "series", &seriesID, nil, nil, &at, false, at, "pending")...))
+	mock.ExpectCommit()
+
+	body, _ := json.Marshal(map[string]any{"publish_at": at})
+	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/publish-schedule", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got %d, want 201; body=%s", rr.Code, rr.Body.String())
+	}
+	var view struct {
+		ID     int64     `json:"id"`
+		Status string    `json:"status"`
+		RunAt  time.Time `json:"run_at"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &view); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if view.ID != 5 || view.Status != "pending" || !view.RunAt.Equal(at) {
+		t.Errorf("view = %+v", view)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestScheduleSeriesPublication_Rejects(t *testing.T) {
+	t.Parallel()
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	for _, tc := range []struct {
+		name string
+		body map[string]any
+	}{
+		{"missing time", map[string]any{}},
+		{"past time", map[string]any{"publish_at": past}},
+		{"at series due", map[string]any{"publish_at": future, "at_series_due": true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mock, _ := pgxmock.NewPool()
+			defer mock.Close()
+			r, access, _ := newRouter(t, mock)
+
+			body, _ := json.Marshal(tc.body)
+			req := authedRequest(t, access, 7, http.MethodPost, "/series/100/publish-schedule", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestScheduleLikbezPublication_AlreadyPublished(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	published := time.Now().Add(-time.Hour)
+	expectLikbez(mock, 9, 42, &published)
+	expectTeacherAccess(mock, 7, 42, "teacher")
+
+	body, _ := json.Marshal(map[string]any{"publish_at": time.Now().Add(time.Hour)})
+	req := authedRequest(t, access, 7, http.MethodPost, "/likbez/9/publish-schedule", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("got %d, want 409; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func expectSolutionTargets(mock pgxmock.PgxPoolIface, ids []int64, hasMaterial bool) {
+	rows := mock.NewRows([]string{"subproblem_id", "math_center_id", "series_id", "is_coffin", "has_material", "solution_group_id", "solution_tex_source"})
+	for _, id := range ids {
+		rows.AddRow(id, int64(42), int64(100), false, hasMaterial, (*int64)(nil), (*string)(nil))
+	}
+	mock.ExpectQuery(`FOR UPDATE OF ss`).WithArgs(ids).WillReturnRows(rows)
+}
+
+func TestScheduleSolutionPublication_AtSeriesDue(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	due := now.Add(24 * time.Hour)
+	seriesID := int64(100)
+	ids := []int64{900, 901}
+	mock.ExpectBegin()
+	// Material may still be written before the deadline.
+	expectSolutionTargets(mock, ids, false)
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(seriesID).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(seriesID, int64(42), int32(1), "S", due, (*string)(nil), &now, now, (*string)(nil)))
+	mock.ExpectQuery(`subproblem_ids && \$1`).
+		WithArgs(ids).
+		WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`INSERT INTO math_center_publication_schedule`).
+		WithArgs(int64(42), "solutions", &seriesID, (*int64)(nil), ids, (*time.Time)(nil), true, int64(7)).
+		WillReturnRows(mock.NewRows(publicationScheduleColumns).
+			AddRow(publicationScheduleRow(6, "solutions", &seriesID, nil, ids, nil, true, due, "pending")...))
+	mock.ExpectCommit()
+
+	body, _ := json.Marshal(map[string]any{"subproblem_ids": []int64{901, 900}, "at_series_due": true})
+	req := authedRequest(t, access, 7, http.MethodPost, "/subproblem-solutions/publish-schedule", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got %d, want 201; body=%s", rr.Code, rr.Body.String())
+	}
+	var view struct {
+		AtSeriesDue bool      `json:"at_series_due"`
+		RunAt       time.Time `json:"run_at"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &view); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !view.AtSeriesDue || !view.RunAt.Equal(due) {
+		t.Errorf("view = %+v; want at_series_due running at %v", view, due)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestScheduleSolutionPublication_OverlapConflict(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	ids := []int64{900}
+	mock.ExpectBegin()
+	expectSolutionTargets(mock, ids, true)
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	mock.ExpectQuery(`subproblem_ids && \$1`).
+		WithArgs(ids).
+		WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	body, _ := json.Marshal(map[string]any{"subproblem_ids": ids, "publish_at": time.Now().Add(time.Hour)})
+	req := authedRequest(t, access, 7, http.MethodPost, "/subproblem-solutions/publish-schedule", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("got %d, want 409; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestScheduleSolutionPublication_NeedsExactlyOneTime(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	body, _ := json.Marshal(map[string]any{
+		"subproblem_ids": []int64{900}, "publish_at": time.Now().Add(time.Hour), "at_series_due": true,
+	})
+	req := authedRequest(t, access, 7, http.MethodPost, "/subproblem-solutions/publish-schedule", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestListPublicationSchedules(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	at := time.Now().Add(time.Hour)
+	likbezID := int64(9)
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	mock.ExpectQuery(`FROM math_center_publication_schedule ps.*WHERE ps.math_center_id = \$1`).
+		WithArgs(int64(42), (*int64)(nil)).
+		WillReturnRows(mock.NewRows(publicationScheduleColumns).
+			AddRow(publicationScheduleRow(3, "likbez", nil, &likbezID, nil, &at, false, at, "pending")...))
+
+	req := authedRequest(t, access, 7, http.MethodGet, "/centers/42/publication-schedules", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var got []struct {
+		ID       int64  `json:"id"`
+		LikbezID *int64 `json:"likbez_id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got) != 1 || got[0].LikbezID == nil || *got[0].LikbezID != 9 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestCancelPublicationSchedule(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name     string
+		affected int64
+		want     int
+	}{
+		{"pending", 1, http.StatusNoContent},
+		{"already ran", 0, http.StatusConflict},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mock, _ := pgxmock.NewPool()
+			defer mock.Close()
+			r, access, _ := newRouter(t, mock)
+
+			at := time.Now().Add(time.Hour)
+			seriesID := int64(100)
+			mock.ExpectQuery(`FROM math_center_publication_schedule ps.*WHERE ps.id = \$1`).
+				WithArgs(int64(5)).
+				WillReturnRows(mock.NewRows(publicationScheduleColumns).
+					AddRow(publicationScheduleRow(5, "series", &seriesID, nil, nil, &at, false, at, "pending")...))
+			expectTeacherAccess(mock, 7, 42, "teacher")
+			mock.ExpectExec(`SET status\s+= 'cancelled'.*WHERE id = \$1`).
+				WithArgs(int64(5)).
+				WillReturnResult(pgxmock.NewResult("UPDATE", tc.affected))
+
+			req := authedRequest(t, access, 7, http.MethodDelete, "/publication-schedules/5", nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			if rr.Code != tc.want {
+				t.Fatalf("got %d, want %d; body=%s", rr.Code, tc.want, rr.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatalf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/live"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// methodCapability is can for writes and mc.CanView for reads, for helpers
+// shared by a resource's GET and its mutations.
+func methodCapability(r *http.Request, can mc.TeacherCapability) mc.TeacherCapability {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return mc.CanView
+	}
+	return can
+}
+
+// teacherAccess runs mc.AuthorizeTeacher for the caller, with seriesID 0
+// for center-wide actions. Admins pass as heads. On !ok the response is
+// already written.
+func teacherAccess(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID, seriesID int64, can mc.TeacherCapability) (store.TeacherAccess, bool) {
+	if callerIsAdmin(r) {
+		return store.TeacherAccess{IsHeadTeacher: true, InScope: true}, true
+	}
+	access, err := mc.AuthorizeTeacher(ctx, q, userID, centerID, seriesID, can)
+	switch {
+	case err == nil:
+		return access, true
+	case errors.Is(err, mc.ErrNotTeacher), errors.Is(err, mc.ErrTeacherRoleForbids), errors.Is(err, mc.ErrTeacherOutsideTerm):
+		httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, err.Error())
+	default:
+		logger.LogErrorContext(ctx, "mathcenter: teacher access", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+	}
+	return store.TeacherAccess{}, false
+}
+
+// requireTeacherCan enforces "caller teaches this center" with a role that
+// allows can; with mc.CanView it still keeps out an assistant whose term is
+// over.
+func requireTeacherCan(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID int64, can mc.TeacherCapability) bool {
+	_, ok := teacherAccess(ctx, w, r, q, userID, centerID, 0, can)
+	return ok
+}
+
+// requireSeriesTeacherCan is requireTeacherCan for an action on one series,
+// reads included; it also keeps a term-scoped assistant to the series of
+// their term.
+func requireSeriesTeacherCan(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID, seriesID int64, can mc.TeacherCapability) bool {
+	_, ok := teacherAccess(ctx, w, r, q, userID, centerID, seriesID, can)
+	return ok
+}
+
+type teacherRoleView struct {
+	TeacherID int64  `json:"teacher_id"`
+	UserID    int64  `json:"user_id"`
+	Role      string `json:"role"`
+	TermID    *int64 `json:"term_id"`
+}
+
+type setTeacherRoleRequest struct {
+	Role string `json:"role"`
+	// TermID limits the teacher to one term of the center; null lifts it.
+	TermID *int64 `json:"term_id"`
+}
+
+// manageListTeacherRoles returns the role of every teacher of the center,
+// alongside the teacher list.
+func manageListTeacherRoles(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := store.New(database.Pool())
+		centerID, _, ok := manageGate(w, r, q)
+		if !ok {
+			return
+		}
+		rows, err := q.ListTeacherRolesForCenter(r.Context(), centerID)
+		if err != nil {
+			logger.LogErrorContext(r.Context(), "manage: list teacher roles", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to list teacher roles")
+			return
+		}
+		teachers, err := q.ListTeachersForCenter(r.Context(), centerID)
+		if err != nil {
+			logger.LogErrorContext(r.Context(), "manage: list teachers for roles", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to list teacher roles")
+			return
+		}
+		head := make(map[int64]bool, len(teachers))
+		for _, t := range teachers {
+			head[t.ID] = t.IsHeadTeacher
+		}
+		out := make([]teacherRoleView, 0, len(rows))
+		for _, row := range rows {
+			out = append(out, teacherRoleView{
+				TeacherID: row.TeacherID,
+				UserID:    row.UserID,
+				Role:      mc.EffectiveTeacherRole(head[row.TeacherID], row.Role),
+				TermID:    row.TermID,
+			})
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// manageSetTeacherRole assigns a teacher's role. Only a head teacher (or an
+// admin) may, so a teacher cannot widen their own access. "head" sets the
+// head flag; any other role clears it.
+func manageSetTeacherRole(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		q := store.New(database.Pool())
+		centerID, _, ok := manageGateCan(w, r, q, mc.CanLead)
+		if !ok {
+			return
+		}
+		teacherID, err := strconv.ParseInt(chi.URLParam(r, "teacherID"), 10, 64)
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid teacher id")
+			return
+		}
+		var req setTeacherRoleRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if !mc.ValidTeacherRole(req.Role) {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "role must be head, teacher, grader, editor or observer")
+			return
+		}
+		isHead := req.Role == mc.TeacherRoleHead
+		if isHead && req.TermID != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "a head teacher cannot be limited to one term")
+			return
+		}
+		if req.TermID != nil {
+			term, err := q.GetTerm(ctx, *req.TermID)
+			if errors.Is(err, pgx.ErrNoRows) || (err == nil && term.MathCenterID != centerID) {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid term id")
+				return
+			}
+			if err != nil {
+				logger.LogErrorContext(ctx, "manage: role term", err)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to update teacher")
+				return
+			}
+		}
+		target, ok := teacherInCenter(w, r, q, teacherID, centerID)
+		if !ok {
+			return
+		}
+		if target.IsHeadTeacher && !isHead {
+			heads, err := q.CountHeadTeachersForCenter(ctx, centerID)
+			if err != nil {
+				logger.LogErrorContext(ctx, "manage: count heads", err)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to update teacher")
+				return
+			}
+			if heads <= 1 {
+				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "the center needs at least one head teacher")
+				return
+			}
+		}
+
+		role := req.Role
+		if isHead {
+			role = mc.TeacherRoleTeacher
+		}
+		if _, err := q.SetTeacherRole(ctx, teacherID, isHead, role, req.TermID); err != nil {
+			logger.LogErrorContext(ctx, "manage: set role", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to update teacher")
+			return
+		}
+		live.Publish(ctx, database.Pool(), live.Event{CenterID: centerID, Kind: live.KindMembership})
+		httpx.WriteJSON(w, http.StatusOK, teacherRoleView{
+			TeacherID: teacherID,
+			UserID:    target.UserID,
+			Role:      req.Role,
+			TermID:    req.TermID,
+		})
+	}
+}
@@ -37,17 +37,34 @@ func Router(database *db.DB, hub *live.Hub, tokens *internalAuth.TokenService, b
 	r.Get("/centers/{centerID}/terms", ListTermsForCenter(database))
 	r.Get("/centers/{centerID}/latex-preamble", GetLatexPreamble(database))
 	r.Post("/centers/{centerID}/terms", CreateTerm(database))
+	// Rollover wizard: pick groups and students for the next term, with a
+	// dry_run preview.
+	r.Post("/centers/{centerID}/terms/rollover", RolloverTerm(database))
 
 	r.Route("/centers/{centerID}/series", func(r chi.Router) {
 		r.Get("/", ListSeriesForCenter(database))
 		r.Post("/", CreateSeries(database))
 	})
+	// Problem bank: the center's own problems plus the school-wide ones,
+	// reusable across series and terms.
+	r.Route("/centers/{centerID}/problem-bank", func(r chi.Router) {
+		r.Get("/", ListBankProblems(database))
+		r.Post("/", CreateBankProblem(database))
+		r.Get("/{bankProblemID}", GetBankProblem(database))
+		r.Put("/{bankProblemID}", UpdateBankProblem(database))
+		r.Delete("/{bankProblemID}", ArchiveBankProblem(database))
+	})
 	// Center-wide lecture catalog. Unlike series, the collection spans every
 	// term; each likbez carries its period only as a historical label.
 	r.Route("/centers/{centerID}/likbez", func(r chi.Router) {
 		r.Get("/", ListLikbezForCenter(database))
 		r.Post("/", CreateLikbez(database))
 	})
+	// Lesson schedule per group and attendance marks (teachers only).
+	r.Route("/centers/{centerID}/lessons", func(r chi.Router) {
+		r.Get("/", ListLessons(database))
+		r.Post("/", CreateLesson(database))
+	})
 	// Center-wide coffins ("Гробы") tab.
 	r.Get("/centers/{centerID}/coffins", ListCenterCoffins(database))
 	r.Get("/centers/{centerID}/coffin-queue", ListCoffinQueue(database))
@@ -73,41 +90,79 @@ func Router(database *db.DB, hub *live.Hub, tokens *internalAuth.TokenService, b
 	// Publish saved разбор drafts atomically; this is also what releases coffin
 	// submissions.
 	r.Post("/subproblem-solutions/publish", PublishSubproblemSolutions(database, hub))
+	// Scheduled publication: the worker in internal/publishing runs these.
+	r.Post("/subproblem-solutions/publish-schedule", ScheduleSolutionPublication(database))
+	r.Get("/centers/{centerID}/publication-schedules", ListPublicationSchedules(database))
+	r.Delete("/publication-schedules/{scheduleID}", CancelPublicationSchedule(database))
 
 	r.Route("/series/{seriesID}", func(r chi.Router) {
 		r.Get("/", GetSeries(database))
 		r.Put("/", UpdateSeries(database))
 		r.Delete("/", DeleteSeries(database, blobs))
 		r.Post("/publish", PublishSeries(database))
+		r.Post("/publish-schedule", ScheduleSeriesPublication(database))
+		// Copy into another term with a new number and due date.
+		r.Post("/clone", CloneSeries(database, blobs))
+		r.Get("/bank-problems", ListSeriesBankLinks(database))
+		r.Post("/bank-problems", AddBankProblemToSeries(database))
 		r.Post("/pdf/upload-url", IssuePDFUploadURL(database, blobs, uploadTTL))
 		r.Post("/pdf/publish", FinalizePDFPublish(database, blobs))
 		r.Get("/pdf", DownloadSeriesPDF(database, blobs, downloadTTL))
-		r.Get("/tex", GetSeriesTex(database))
+		r.Get("/tex", GetSeriesTex(database, blobs, downloadTTL))
 		r.Put("/tex", PutSeriesTex(database))
 		r.Delete("/tex", DeleteSeriesTex(database))
+		r.Get("/tex/revisions", ListTexRevisions(database, resolveSeriesTexTarget))
+		r.Get("/tex/revisions/{revision}", GetTexRevision(database, resolveSeriesTexTarget))
+		r.Post("/tex/revisions/{revision}/restore", RestoreTexRevision(database, resolveSeriesTexTarget))
+		r.Get("/tex/diff", DiffTexRevisions(database, resolveSeriesTexTarget))
+		r.Get("/tex/assets", ListTexAssets(database, resolveSeriesTexAssetOwner))
+		r.Post("/tex/assets/upload-url", IssueTexAssetUploadURL(database, blobs, uploadTTL, resolveSeriesTexAssetOwner))
+		r.Post("/tex/assets", FinalizeTexAsset(database, blobs, resolveSeriesTexAssetOwner))
+		r.Delete("/tex/assets/{name}", DeleteTexAsset(database, blobs, resolveSeriesTexAssetOwner))
 	})
 	r.Route("/likbez/{likbezID}", func(r chi.Router) {
 		r.Get("/", GetLikbez(database))
 		r.Put("/", UpdateLikbez(database))
 		r.Delete("/", DeleteLikbez(database, blobs))
 		r.Post("/publish", PublishLikbez(database))
+		r.Post("/publish-schedule", ScheduleLikbezPublication(database))
 		r.Post("/unpublish", UnpublishLikbez(database))
 		r.Post("/pdf/upload-url", IssueLikbezPDFUploadURL(database, blobs, uploadTTL))
 		r.Post("/pdf/publish", FinalizeLikbezPDF(database, blobs))
 		r.Get("/pdf", DownloadLikbezPDF(database, blobs, downloadTTL))
-		r.Get("/tex", GetLikbezTex(database))
+		r.Get("/tex", GetLikbezTex(database, blobs, downloadTTL))
 		r.Put("/tex", PutLikbezTex(database))
+		r.Get("/tex/assets", ListTexAssets(database, resolveLikbezTexAssetOwner))
+		r.Post("/tex/assets/upload-url", IssueTexAssetUploadURL(database, blobs, uploadTTL, resolveLikbezTexAssetOwner))
+		r.Post("/tex/assets", FinalizeTexAsset(database, blobs, resolveLikbezTexAssetOwner))
+		r.Delete("/tex/assets/{name}", DeleteTexAsset(database, blobs, resolveLikbezTexAssetOwner))
 		r.Put("/video", SetLikbezVideoURL(database))
 	})
 
+	r.Route("/lessons/{lessonID}", func(r chi.Router) {
+		r.Get("/", GetLesson(database))
+		r.Put("/", UpdateLesson(database))
+		r.Delete("/", DeleteLesson(database))
+		r.Get("/attendance", GetLessonAttendance(database))
+		r.Put("/attendance", PutLessonAttendance(database))
+	})
+
 	// Per-subproblem coffins ("гробы") + официальный «Разбор». The subproblem is
 	// the unit: mark/unmark + разбор (TeX/PDF/link) all key on it. Publishing
 	// any разбор format releases an open coffin automatically.
 	r.Route("/subproblems/{subproblemID}", func(r chi.Router) {
 		r.Post("/coffin", MarkCoffin(database, hub))
 		r.Delete("/coffin", UnmarkCoffin(database, hub, blobs))
-		r.Get("/solution/tex", GetSubproblemSolutionTex(database))
+		r.Get("/solution/tex", GetSubproblemSolutionTex(database, blobs, downloadTTL))
 		r.Put("/solution/tex", PutSubproblemSolutionTex(database, hub))
+		r.Get("/solution/tex/revisions", ListTexRevisions(database, resolveSolutionTexTarget))
+		r.Get("/solution/tex/revisions/{revision}", GetTexRevision(database, resolveSolutionTexTarget))
+		r.Post("/solution/tex/revisions/{revision}/restore", RestoreTexRevision(database, resolveSolutionTexTarget))
+		r.Get("/solution/tex/diff", DiffTexRevisions(database, resolveSolutionTexTarget))
+		r.Get("/solution/tex/assets", ListTexAssets(database, resolveSolutionTexAssetOwner))
+		r.Post("/solution/tex/assets/upload-url", IssueTexAssetUploadURL(database, blobs, uploadTTL, resolveSolutionTexAssetOwner))
+		r.Post("/solution/tex/assets", FinalizeTexAsset(database, blobs, resolveSolutionTexAssetOwner))
+		r.Delete("/solution/tex/assets/{name}", DeleteTexAsset(database, blobs, resolveSolutionTexAssetOwner))
 		r.Post("/solution/pdf/upload-url", IssueSubproblemSolutionPDFUploadURL(database, blobs, uploadTTL))
 		r.Post("/solution/pdf/publish", FinalizeSubproblemSolutionPDFPublish(database, hub, blobs))
 		r.Get("/solution/pdf", DownloadSubproblemSolutionPDF(database, blobs, downloadTTL))
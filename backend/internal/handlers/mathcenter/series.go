@@ -15,6 +15,7 @@ import (
 	"github.com/Alarion239/my239/backend/internal/config"
 	"github.com/Alarion239/my239/backend/internal/ctxcache"
 	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/live"
 	"github.com/Alarion239/my239/backend/internal/logger"
 	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
@@ -108,6 +109,8 @@ type seriesView struct {
 	RazborVideoAccess  bool          `json:"razbor_video_access"`
 	RazborPDFTexAccess bool          `json:"razbor_pdf_tex_access"`
 	Problems           []problemView `json:"problems"`
+	// TexDiagnostics is set only in the response to a TeX save.
+	TexDiagnostics []mc.TexDiagnostic `json:"tex_diagnostics,omitempty"`
 }
 
 type razborAccess struct {
@@ -210,7 +213,7 @@ func CreateSeries(database *db.DB) http.HandlerFunc {
 		}
 
 		q := store.New(database.Pool())
-		if !requireTeacher(ctx, w, r, q, userID, centerID) {
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanEditContent) {
 			return
 		}
 		// Create the series and its problems atomically: a partial write
@@ -472,7 +475,7 @@ func UpdateSeries(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, series.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanEditContent) {
 			return
 		}
 
@@ -559,10 +562,18 @@ func DeleteSeries(database *db.DB, blobs objectstore.Store) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, series.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanEditContent) {
 			return
 		}
 
+		// The asset rows go with the series by cascade, so their keys are
+		// read first.
+		assetKeys, err := q.ListSeriesTexAssetKeys(ctx, series.ID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "series: list tex assets for delete", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
 		if _, err := q.DeleteSeries(ctx, series.ID); err != nil {
 			logger.LogErrorContext(ctx, "series: delete", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to delete series")
@@ -573,6 +584,7 @@ func DeleteSeries(database *db.DB, blobs objectstore.Store) http.HandlerFunc {
 				logger.LogErrorContext(ctx, "series: delete blob", err)
 			}
 		}
+		deleteTexAssetObjects(ctx, blobs, assetKeys)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -620,7 +632,7 @@ func IssuePDFUploadURL(database *db.DB, blobs objectstore.Store, uploadTTL time.
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, series.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanEditContent) {
 			return
 		}
 
@@ -668,7 +680,7 @@ func FinalizePDFPublish(database *db.DB, blobs objectstore.Store) http.HandlerFu
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, series.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanEditContent) {
 			return
 		}
 
@@ -721,7 +733,8 @@ func FinalizePDFPublish(database *db.DB, blobs objectstore.Store) http.HandlerFu
 // PublishSeries — teacher-only. A series remains a teacher-visible draft while
 // its metadata, statement and problem cards are assembled. Publication is the
 // single explicit transition that exposes it to students, and the SQL guard
-// requires both a statement (TeX or PDF) and at least one problem.
+// requires both a statement (TeX or PDF) and at least one problem. A TeX
+// statement with errors is refused with its diagnostics.
 func PublishSeries(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -746,11 +759,28 @@ func PublishSeries(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, series.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanEditContent) {
+			return
+		}
+		if err := mc.CheckSeriesTex(ctx, q, series.ID, series.TexSource); err != nil {
+			if writeTexPublishError(w, r, err) {
+				return
+			}
+			logger.LogErrorContext(ctx, "series: check tex for publication", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
 
-		updated, err := q.PublishSeries(ctx, series.ID)
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "series: begin publish tx", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+
+		updated, err := qx.PublishSeries(ctx, series.ID)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "add a statement and at least one problem before publishing")
@@ -760,6 +790,21 @@ func PublishSeries(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to publish series")
 			return
 		}
+		// Record which statement revision students are shown.
+		if updated.PublishedAt != nil {
+			if err := qx.MarkSeriesTexRevisionPublished(ctx, series.ID, *updated.PublishedAt); err != nil {
+				logger.LogErrorContext(ctx, "series: stamp published tex revision", err)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to publish series")
+				return
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			logger.LogErrorContext(ctx, "series: commit publish", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+
+		live.Publish(ctx, database.Pool(), live.Event{CenterID: series.MathCenterID, Kind: live.KindSeries, SeriesID: series.ID})
 
 		view, err := buildSeriesView(ctx, q, seriesFromPublishRow(updated))
 		if err != nil {
@@ -841,7 +886,9 @@ type texPayload struct {
 // PutSeriesTex — teacher-only. Stores the raw LaTeX source on the series
 // row. Validates UTF-8, size cap, and the presence of \begin{document}
 // so we reject obviously-malformed input early. Saving source does not publish
-// a draft; visibility changes only through PublishSeries.
+// a draft; visibility changes only through PublishSeries. Every save that
+// changes the source appends a revision to its history. The source is
+// linted; a published series refuses a save with errors.
 func PutSeriesTex(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -875,12 +922,35 @@ func PutSeriesTex(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, series.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanEditContent) {
 			return
 		}
 
 		tex := normalizeTexSource(req.Tex)
-		updated, err := q.SetSeriesTex(ctx, store.SetSeriesTexParams{
+		numbers, err := mc.SeriesProblemNumbers(ctx, q, series.ID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "series: problems for tex lint", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		diags, ok := lintTexSource(ctx, w, r, database, series.MathCenterID, tex, numbers)
+		if !ok {
+			return
+		}
+		if series.PublishedAt != nil && writeTexPublishError(w, r, mc.CheckTexDiagnostics(diags)) {
+			return
+		}
+
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "series: begin tex tx", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+
+		updated, err := qx.SetSeriesTex(ctx, store.SetSeriesTexParams{
 			ID:        series.ID,
 			TexSource: &tex,
 		})
@@ -889,12 +959,26 @@ func PutSeriesTex(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save tex")
 			return
 		}
+		if !recordTexRevision(ctx, w, r, qx, store.RecordTexRevisionParams{
+			Target:       seriesTexTarget(series.MathCenterID, series.ID),
+			Body:         &tex,
+			AuthorUserID: userID,
+			Published:    series.PublishedAt != nil,
+		}) {
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			logger.LogErrorContext(ctx, "series: commit tex", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
 		view, err := buildSeriesView(ctx, q, seriesFromSetTexRow(updated))
 		if err != nil {
 			logger.LogErrorContext(ctx, "series: build view after tex", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
+		view.TexDiagnostics = diags
 		httpx.WriteJSON(w, http.StatusOK, view)
 	}
 }
@@ -925,16 +1009,39 @@ func DeleteSeriesTex(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if !requireTeacher(ctx, w, r, q, userID, series.MathCenterID) {
+		if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, mc.CanEditContent) {
+			return
+		}
+
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "series: begin tex clear tx", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
 
-		updated, err := q.ClearSeriesTex(ctx, series.ID)
+		updated, err := qx.ClearSeriesTex(ctx, series.ID)
 		if err != nil {
 			logger.LogErrorContext(ctx, "series: clear tex", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clear tex")
 			return
 		}
+		// A nil body records the clear, so the history shows the gap and
+		// the cleared source can be restored.
+		if !recordTexRevision(ctx, w, r, qx, store.RecordTexRevisionParams{
+			Target:       seriesTexTarget(series.MathCenterID, series.ID),
+			AuthorUserID: userID,
+			Published:    series.PublishedAt != nil,
+		}) {
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			logger.LogErrorContext(ctx, "series: commit tex clear", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
 		view, err := buildSeriesView(ctx, q, seriesFromClearTexRow(updated))
 		if err != nil {
 			logger.LogErrorContext(ctx, "series: build view after tex clear", err)
@@ -946,9 +1053,9 @@ func DeleteSeriesTex(database *db.DB) http.HandlerFunc {
 }
 
 // GetSeriesTex returns the raw LaTeX source as JSON so the frontend can
-// feed it to LaTeX.js. Teachers always have access; students only if the
-// series is published.
-func GetSeriesTex(database *db.DB) http.HandlerFunc {
+// feed it to LaTeX.js, with the statement's assets resolved to signed URLs.
+// Teachers always have access; students only if the series is published.
+func GetSeriesTex(database *db.DB, blobs objectstore.Store, ttl time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		userID, ok := requireUser(w, r)
@@ -991,7 +1098,7 @@ func GetSeriesTex(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "no tex source uploaded yet")
 			return
 		}
-		httpx.WriteJSON(w, http.StatusOK, texPayload{Tex: *series.TexSource})
+		writeTexSource(ctx, w, r, q, blobs, ttl, seriesTexAssetOwner(series.MathCenterID, series.ID), *series.TexSource)
 	}
 }
 
@@ -1078,34 +1185,6 @@ func studentRazborAccessForCenter(ctx context.Context, q *store.Queries, userID,
 	return accessBySeries, nil
 }
 
-// requireTeacher gates teacher-only routes. Returns true if the request can
-// continue; otherwise it has already written a 403/500 and the caller should
-// just return.
-//
-// Admin is a true superset of teacher: an admin creates/edits/deletes series
-// and uploads PDF/TeX for ANY center without being enrolled as a teacher of it.
-// callerIsAdmin reads the EFFECTIVE is_admin from context, so when an admin
-// impersonates a non-admin (see middleware.ImpersonationMiddleware) the bypass
-// correctly does not apply. Mirrors homework/helpers.go requireTeacher.
-func requireTeacher(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID, centerID int64) bool {
-	if callerIsAdmin(r) {
-		return true
-	}
-	isTeacher, err := q.IsTeacherInCenter(ctx, store.IsTeacherInCenterParams{
-		UserID: userID, MathCenterID: centerID,
-	})
-	if err != nil {
-		logger.LogErrorContext(ctx, "series: teacher check", err)
-		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
-		return false
-	}
-	if !isTeacher {
-		httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, "not a teacher of this center")
-		return false
-	}
-	return true
-}
-
 // maxOrdinal caps series/problem numbers well below math.MaxInt32 so the
 // int32 cast at the DB boundary can never silently wrap a large client value.
 const maxOrdinal = 100_000
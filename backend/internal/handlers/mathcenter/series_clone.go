@@ -0,0 +1,266 @@
+package mathcenter
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/objectstore"
+)
+
+// Cloning reuses a series in a later term (or in another center the teacher
+// also teaches): the statement, problem structure, разбор material and
+// coffin flags come along; everything that belongs to the source term's
+// students (homework threads, razbor access, publication) does not.
+
+type cloneSeriesRequest struct {
+	TermID int64     `json:"term_id"`
+	Number int       `json:"number"`
+	DueAt  time.Time `json:"due_at"`
+	// Name defaults to the source series' name.
+	Name string `json:"name"`
+}
+
+// CloneSeries — teacher of both the source series' center and the target
+// term's center; a term-scoped editor stays within their term on both ends.
+// Creates an unpublished copy in the target term with a new number and due
+// date. Object-storage PDFs and TeX assets are copied under
+// the clone's own keys so deleting either series never breaks the other.
+// Razbor access for the clone is initialized from the target term's
+// students' defaults, like a freshly created series.
+func CloneSeries(database *db.DB, blobs objectstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		seriesID, err := pathInt64(r, "seriesID")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid series id")
+			return
+		}
+
+		var req cloneSeriesRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		if req.TermID <= 0 {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "term_id is required")
+			return
+		}
+		if req.DueAt.IsZero() {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "due_at is required")
+			return
+		}
+
+		q := store.New(database.Pool())
+		src, err := q.GetSeries(ctx, seriesID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "series not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "series clone: get source", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		access, ok := teacherAccess(ctx, w, r, q, userID, src.MathCenterID, src.ID, mc.CanEditContent)
+		if !ok {
+			return
+		}
+		if req.Name == "" {
+			req.Name = src.Name
+		}
+		if vErr := validateSeriesPayload(req.Number, req.Name, nil); vErr != "" {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, vErr)
+			return
+		}
+
+		term, err := q.GetTerm(ctx, req.TermID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid term")
+				return
+			}
+			logger.LogErrorContext(ctx, "series clone: get term", err, "term_id", req.TermID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if term.MathCenterID != src.MathCenterID {
+			if access, ok = teacherAccess(ctx, w, r, q, userID, term.MathCenterID, 0, mc.CanEditContent); !ok {
+				return
+			}
+		}
+		if access.TermID != nil && *access.TermID != term.ID {
+			httpx.WriteAPIError(w, r, http.StatusForbidden, httpx.CodeForbidden, mc.ErrTeacherOutsideTerm.Error())
+			return
+		}
+		if !term.IsActive {
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "archived terms are read-only")
+			return
+		}
+
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "series clone: begin tx", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+
+		created, err := qx.CreateSeriesInTerm(ctx, store.CreateSeriesInTermParams{
+			MathCenterID: term.MathCenterID, TermID: term.ID, Number: int32(req.Number), Name: req.Name, DueAt: req.DueAt,
+		})
+		if err != nil {
+			if isUniqueViolation(err) {
+				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "series number already exists in this term")
+				return
+			}
+			logger.LogErrorContext(ctx, "series clone: create", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clone series")
+			return
+		}
+		clone := seriesFromCreateTermRow(created)
+		if err := qx.InitializeSeriesRazborAccess(ctx, clone.ID); err != nil {
+			logger.LogErrorContext(ctx, "series clone: initialize razbor access", err, "series_id", clone.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to initialize razbor access")
+			return
+		}
+		if src.TexSource != nil {
+			if _, err := qx.SetSeriesTex(ctx, store.SetSeriesTexParams{ID: clone.ID, TexSource: src.TexSource}); err != nil {
+				logger.LogErrorContext(ctx, "series clone: copy tex", err, "series_id", clone.ID)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clone series")
+				return
+			}
+			clone.TexSource = src.TexSource
+			if !recordTexRevision(ctx, w, r, qx, store.RecordTexRevisionParams{
+				Target:       seriesTexTarget(clone.MathCenterID, clone.ID),
+				Body:         src.TexSource,
+				AuthorUserID: userID,
+			}) {
+				return
+			}
+		}
+		if err := qx.CloneSeriesProblems(ctx, src.ID, clone.ID); err != nil {
+			logger.LogErrorContext(ctx, "series clone: copy problems", err, "series_id", clone.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clone problems")
+			return
+		}
+		if err := qx.CloneSeriesSubproblems(ctx, src.ID, clone.ID); err != nil {
+			logger.LogErrorContext(ctx, "series clone: copy subproblems", err, "series_id", clone.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clone problems")
+			return
+		}
+		solutionPDFs, err := qx.CloneSeriesSolutions(ctx, src.ID, clone.ID)
+		if err != nil {
+			logger.LogErrorContext(ctx, "series clone: copy solutions", err, "series_id", clone.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clone разборы")
+			return
+		}
+		if err := qx.RecordClonedSolutionRevisions(ctx, clone.ID, userID); err != nil {
+			logger.LogErrorContext(ctx, "series clone: record разбор revisions", err, "series_id", clone.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clone разборы")
+			return
+		}
+
+		// Objects are copied before commit so the clone never points at a
+		// missing PDF or picture. The keys derive from the clone's ids; if
+		// the commit fails they are deleted again.
+		var copied []string
+		cleanup := func() {
+			for _, key := range copied {
+				if err := blobs.Delete(ctx, key); err != nil {
+					logger.LogErrorContext(ctx, "series clone: delete copied object", err, "key", key)
+				}
+			}
+		}
+		if src.PdfObjectKey != nil {
+			key := pdfObjectKey(clone.ID)
+			if err := objectstore.Copy(ctx, blobs, *src.PdfObjectKey, key); err != nil {
+				cleanup()
+				logger.LogErrorContext(ctx, "series clone: copy pdf", err, "series_id", clone.ID)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to copy series PDF")
+				return
+			}
+			copied = append(copied, key)
+			if _, err := qx.SetSeriesPDF(ctx, store.SetSeriesPDFParams{ID: clone.ID, PdfObjectKey: &key}); err != nil {
+				cleanup()
+				logger.LogErrorContext(ctx, "series clone: set pdf", err, "series_id", clone.ID)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clone series")
+				return
+			}
+			clone.PdfObjectKey = &key
+		}
+		for _, sol := range solutionPDFs {
+			key := subproblemSolutionPDFKey(sol.SubproblemID)
+			if err := objectstore.Copy(ctx, blobs, sol.SourceKey, key); err != nil {
+				cleanup()
+				logger.LogErrorContext(ctx, "series clone: copy solution pdf", err, "subproblem_id", sol.SubproblemID)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to copy разбор PDF")
+				return
+			}
+			copied = append(copied, key)
+			if err := qx.SetClonedSolutionPDF(ctx, sol.SubproblemID, key); err != nil {
+				cleanup()
+				logger.LogErrorContext(ctx, "series clone: set solution pdf", err, "subproblem_id", sol.SubproblemID)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clone разборы")
+				return
+			}
+		}
+
+		assets, err := qx.ListTexAssetsForClone(ctx, src.ID, clone.ID)
+		if err != nil {
+			cleanup()
+			logger.LogErrorContext(ctx, "series clone: list tex assets", err, "series_id", clone.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clone series")
+			return
+		}
+		for _, asset := range assets {
+			key := texAssetObjectKey(asset.Owner, asset.Name)
+			if err := objectstore.Copy(ctx, blobs, asset.SourceKey, key); err != nil {
+				cleanup()
+				logger.LogErrorContext(ctx, "series clone: copy tex asset", err, "key", asset.SourceKey)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to copy TeX assets")
+				return
+			}
+			copied = append(copied, key)
+			if _, err := qx.UpsertTexAsset(ctx, store.UpsertTexAssetParams{
+				Owner:            asset.Owner,
+				Name:             asset.Name,
+				ObjectKey:        key,
+				ContentType:      asset.ContentType,
+				SizeBytes:        asset.SizeBytes,
+				UploadedByUserID: &userID,
+			}); err != nil {
+				cleanup()
+				logger.LogErrorContext(ctx, "series clone: record tex asset", err, "key", key)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to clone series")
+				return
+			}
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			cleanup()
+			logger.LogErrorContext(ctx, "series clone: commit", err, "series_id", clone.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+
+		view, err := buildSeriesView(ctx, q, clone)
+		if err != nil {
+			logger.LogErrorContext(ctx, "series clone: build view", err, "series_id", clone.ID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusCreated, view)
+	}
+}
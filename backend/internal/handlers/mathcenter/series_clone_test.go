@@ -0,0 +1,256 @@
+package mathcenter_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var termRowColumns = []string{"id", "math_center_id", "kind", "grade", "is_active", "created_at", "archived_at"}
+
+func expectCloneSource(mock pgxmock.PgxPoolIface, now time.Time, pdfKey, tex *string) {
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(3), "Алгебра", now, pdfKey, &now, now, tex))
+}
+
+func expectCloneTerm(mock pgxmock.PgxPoolIface, termID, centerID int64, active bool) {
+	grade := int32(9)
+	mock.ExpectQuery(`FROM math_center_terms\s+WHERE id = \$1`).
+		WithArgs(termID).
+		WillReturnRows(mock.NewRows(termRowColumns).
+			AddRow(termID, centerID, "academic", &grade, active, time.Now(), (*time.Time)(nil)))
+}
+
+func TestCloneSeries_CopiesContentIntoTargetTerm(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+	ctx := context.Background()
+
+	now := time.Now()
+	due := now.Add(7 * 24 * time.Hour).UTC().Truncate(time.Second)
+	srcPDF, tex := "mathcenter/series/100.pdf", `\section{Алгебра}`
+	_ = blobs.Put(ctx, srcPDF, strings.NewReader("%PDF-series"), 11, "application/pdf")
+	_ = blobs.Put(ctx, "mathcenter/subproblem/900.solution.pdf", strings.NewReader("%PDF-razbor"), 11, "application/pdf")
+	_ = blobs.Put(ctx, "mathcenter/tex-assets/series/100/fig.png", strings.NewReader("PNG"), 3, "image/png")
+
+	expectCloneSource(mock, now, &srcPDF, &tex)
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	expectCloneTerm(mock, 80, 43, true)
+	expectTeacherAccess(mock, 7, 43, "teacher")
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO math_center_series \(math_center_id, term_id`).
+		WithArgs(int64(43), int64(80), int32(1), "Алгебра", due).
+		WillReturnRows(mock.NewRows(append([]string{"id", "math_center_id", "term_id"}, seriesColumns[2:]...)).
+			AddRow(int64(200), int64(43), int64(80), int32(1), "Алгебра", due, (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
+	mock.ExpectExec(`INSERT INTO math_center_student_series_razbor_access`).
+		WithArgs(int64(200)).WillReturnResult(pgxmock.NewResult("INSERT", 12))
+	mock.ExpectQuery(`UPDATE math_center_series\s+SET tex_source`).
+		WithArgs(int64(200), &tex).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(200), int64(43), int32(1), "Алгебра", due, (*string)(nil), (*time.Time)(nil), now, &tex))
+	expectTexRevisionRecorded(mock, seriesTexArgs(43, 200, &tex, 7, false), 1)
+	mock.ExpectExec(`INSERT INTO math_center_problems \(series_id, number, bank_problem_id\)\s+SELECT`).
+		WithArgs(int64(100), int64(200)).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO math_center_subproblems \(problem_id, label\)\s+SELECT`).
+		WithArgs(int64(100), int64(200)).WillReturnResult(pgxmock.NewResult("INSERT", 2))
+	mock.ExpectQuery(`INSERT INTO math_center_subproblem_solutions \(subproblem_id, is_coffin`).
+		WithArgs(int64(100), int64(200)).
+		WillReturnRows(mock.NewRows([]string{"dst_subproblem_id", "solution_pdf_object_key"}).
+			AddRow(int64(1900), "mathcenter/subproblem/900.solution.pdf"))
+	mock.ExpectExec(`INSERT INTO math_center_tex_revisions[\s\S]*'solution'`).
+		WithArgs(int64(200), int64(7)).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	newPDF := "mathcenter/series/200.pdf"
+	mock.ExpectQuery(`UPDATE math_center_series\s+SET pdf_object_key`).
+		WithArgs(int64(200), &newPDF).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(200), int64(43), int32(1), "Алгебра", due, &newPDF, (*time.Time)(nil), now, &tex))
+	mock.ExpectExec(`UPDATE math_center_subproblem_solutions\s+SET solution_pdf_object_key`).
+		WithArgs(int64(1900), "mathcenter/subproblem/1900.solution.pdf").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(`FROM math_center_tex_assets a\s+JOIN math_center_series d`).
+		WithArgs(int64(100), int64(200)).
+		WillReturnRows(mock.NewRows([]string{"math_center_id", "target_kind", "id", "name", "object_key", "content_type", "size_bytes"}).
+			AddRow(int64(43), "series", (*int64)(nil), "fig.png", "mathcenter/tex-assets/series/100/fig.png", "image/png", int64(3)))
+	mock.ExpectQuery(`INSERT INTO math_center_tex_assets`).
+		WithArgs(append(seriesTexAssetArgs(43, 200), "fig.png", "mathcenter/tex-assets/series/200/fig.png", "image/png", int64(3), pgxmock.AnyArg())...).
+		WillReturnRows(mock.NewRows(texAssetColumns).
+			AddRow(int64(1), "fig.png", "mathcenter/tex-assets/series/200/fig.png", "image/png", int64(3), (*int64)(nil), now, now))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
+		WithArgs(int64(200)).
+		WillReturnRows(mock.NewRows(problemColumns).AddRow(int64(600), int64(200), int32(1), now))
+	mock.ExpectQuery(`FROM math_center_subproblems s\s+JOIN math_center_problems`).
+		WithArgs(int64(200)).
+		WillReturnRows(mock.NewRows(subproblemRowColumns).
+			AddRow(int64(1900), int64(600), "a").
+			AddRow(int64(1901), int64(600), "b"))
+	mock.ExpectQuery(`FROM math_center_subproblem_solutions ss`).
+		WithArgs(int64(200)).
+		WillReturnRows(mock.NewRows(subproblemSolutionMetaColumns).
+			AddRow(int64(1900), int64(600), true, (*time.Time)(nil), (*time.Time)(nil), false, true, (*string)(nil), (*int64)(nil)))
+
+	body, _ := json.Marshal(map[string]any{"term_id": 80, "number": 1, "due_at": due})
+	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/clone", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got %d, want 201; body=%s", rr.Code, rr.Body.String())
+	}
+	var got struct {
+		ID        int64 `json:"id"`
+		TermID    int64 `json:"term_id"`
+		Published bool  `json:"published"`
+		HasPDF    bool  `json:"has_pdf"`
+		HasTex    bool  `json:"has_tex"`
+		Problems  []struct {
+			Subproblems []struct {
+				IsCoffin       bool `json:"is_coffin"`
+				HasSolutionPDF bool `json:"has_solution_pdf"`
+			} `json:"subproblems"`
+		} `json:"problems"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.ID != 200 || got.TermID != 80 || got.Published || !got.HasPDF || !got.HasTex {
+		t.Errorf("clone = %+v", got)
+	}
+	if len(got.Problems) != 1 || len(got.Problems[0].Subproblems) != 2 ||
+		!got.Problems[0].Subproblems[0].IsCoffin || !got.Problems[0].Subproblems[0].HasSolutionPDF {
+		t.Errorf("problems = %+v", got.Problems)
+	}
+	for key, want := range map[string]string{
+		newPDF: "%PDF-series",
+		"mathcenter/subproblem/1900.solution.pdf":  "%PDF-razbor",
+		"mathcenter/tex-assets/series/200/fig.png": "PNG",
+	} {
+		rc, _, ok := blobs.Get(key)
+		if !ok {
+			t.Errorf("object %s was not copied", key)
+			continue
+		}
+		if b, _ := io.ReadAll(rc); string(b) != want {
+			t.Errorf("object %s = %q, want %q", key, b, want)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCloneSeries_Rejects(t *testing.T) {
+	t.Parallel()
+	due := time.Now().Add(24 * time.Hour)
+
+	t.Run("archived term", func(t *testing.T) {
+		t.Parallel()
+		mock, _ := pgxmock.NewPool()
+		defer mock.Close()
+		r, access, _ := newRouter(t, mock)
+		expectCloneSource(mock, time.Now(), nil, nil)
+		expectTeacherAccess(mock, 7, 42, "teacher")
+		expectCloneTerm(mock, 70, 42, false)
+
+		body, _ := json.Marshal(map[string]any{"term_id": 70, "number": 1, "due_at": due})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authedRequest(t, access, 7, http.MethodPost, "/series/100/clone", bytes.NewReader(body)))
+		if rr.Code != http.StatusConflict {
+			t.Errorf("got %d, want 409; body=%s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("not a teacher of the target center", func(t *testing.T) {
+		t.Parallel()
+		mock, _ := pgxmock.NewPool()
+		defer mock.Close()
+		r, access, _ := newRouter(t, mock)
+		expectCloneSource(mock, time.Now(), nil, nil)
+		expectTeacherAccess(mock, 7, 42, "teacher")
+		expectCloneTerm(mock, 80, 43, true)
+		expectTeacherAccess(mock, 7, 43, "")
+
+		body, _ := json.Marshal(map[string]any{"term_id": 80, "number": 1, "due_at": due})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authedRequest(t, access, 7, http.MethodPost, "/series/100/clone", bytes.NewReader(body)))
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("source series of another term", func(t *testing.T) {
+		t.Parallel()
+		mock, _ := pgxmock.NewPool()
+		defer mock.Close()
+		r, access, _ := newRouter(t, mock)
+		termID := int64(70)
+		expectCloneSource(mock, time.Now(), nil, nil)
+		mock.ExpectQuery(`FROM math_center_teachers t\s+LEFT JOIN math_center_terms`).
+			WithArgs(int64(7), int64(42)).
+			WillReturnRows(mock.NewRows(teacherAccessColumns).AddRow(int64(1), false, "editor", &termID, true))
+		mock.ExpectQuery(`FROM math_center_series WHERE id = \$1 AND term_id = \$2`).
+			WithArgs(int64(100), termID).
+			WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(false))
+
+		body, _ := json.Marshal(map[string]any{"term_id": 70, "number": 1, "due_at": due})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authedRequest(t, access, 7, http.MethodPost, "/series/100/clone", bytes.NewReader(body)))
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("target term of another term's editor", func(t *testing.T) {
+		t.Parallel()
+		mock, _ := pgxmock.NewPool()
+		defer mock.Close()
+		r, access, _ := newRouter(t, mock)
+		termID := int64(60)
+		expectCloneSource(mock, time.Now(), nil, nil)
+		mock.ExpectQuery(`FROM math_center_teachers t\s+LEFT JOIN math_center_terms`).
+			WithArgs(int64(7), int64(42)).
+			WillReturnRows(mock.NewRows(teacherAccessColumns).AddRow(int64(1), false, "editor", &termID, true))
+		mock.ExpectQuery(`FROM math_center_series WHERE id = \$1 AND term_id = \$2`).
+			WithArgs(int64(100), termID).
+			WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(true))
+		expectCloneTerm(mock, 70, 42, true)
+
+		body, _ := json.Marshal(map[string]any{"term_id": 70, "number": 1, "due_at": due})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authedRequest(t, access, 7, http.MethodPost, "/series/100/clone", bytes.NewReader(body)))
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("missing due date", func(t *testing.T) {
+		t.Parallel()
+		mock, _ := pgxmock.NewPool()
+		defer mock.Close()
+		r, access, _ := newRouter(t, mock)
+
+		body, _ := json.Marshal(map[string]any{"term_id": 80, "number": 1})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authedRequest(t, access, 7, http.MethodPost, "/series/100/clone", bytes.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+		}
+	})
+}
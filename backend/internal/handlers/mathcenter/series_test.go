@@ -121,9 +121,30 @@ func TestRouter_RequiresAuth(t *testing.T) {
 		{http.MethodPut, "/series/1"},
 		{http.MethodDelete, "/series/1"},
 		{http.MethodPost, "/series/1/publish"},
+		{http.MethodPost, "/series/1/publish-schedule"},
+		{http.MethodPost, "/series/1/clone"},
+		{http.MethodGet, "/series/1/bank-problems"},
+		{http.MethodPost, "/series/1/bank-problems"},
+		{http.MethodGet, "/centers/1/problem-bank"},
+		{http.MethodPost, "/centers/1/problem-bank"},
+		{http.MethodGet, "/centers/1/problem-bank/1"},
+		{http.MethodPut, "/centers/1/problem-bank/1"},
+		{http.MethodDelete, "/centers/1/problem-bank/1"},
+		{http.MethodPost, "/subproblem-solutions/publish-schedule"},
+		{http.MethodGet, "/centers/1/publication-schedules"},
+		{http.MethodDelete, "/publication-schedules/1"},
 		{http.MethodPost, "/series/1/pdf/upload-url"},
 		{http.MethodPost, "/series/1/pdf/publish"},
 		{http.MethodGet, "/series/1/pdf"},
+		{http.MethodGet, "/series/1/tex/revisions"},
+		{http.MethodGet, "/series/1/tex/revisions/1"},
+		{http.MethodPost, "/series/1/tex/revisions/1/restore"},
+		{http.MethodGet, "/series/1/tex/diff"},
+		{http.MethodGet, "/subproblems/1/solution/tex/revisions"},
+		{http.MethodPost, "/subproblems/1/solution/tex/revisions/1/restore"},
+		{http.MethodGet, "/subproblems/1/solution/tex/diff"},
+		{http.MethodGet, "/centers/1/manage/latex-preamble/revisions"},
+		{http.MethodGet, "/centers/1/manage/latex-preamble/diff"},
 		{http.MethodGet, "/centers/1/likbez"},
 		{http.MethodPost, "/centers/1/likbez"},
 		{http.MethodGet, "/centers/1/latex-preamble"},
@@ -134,6 +155,7 @@ func TestRouter_RequiresAuth(t *testing.T) {
 		{http.MethodPut, "/likbez/1"},
 		{http.MethodDelete, "/likbez/1"},
 		{http.MethodPost, "/likbez/1/publish"},
+		{http.MethodPost, "/likbez/1/publish-schedule"},
 		{http.MethodPost, "/likbez/1/unpublish"},
 		{http.MethodPost, "/likbez/1/pdf/upload-url"},
 		{http.MethodPost, "/likbez/1/pdf/publish"},
@@ -160,9 +182,7 @@ func TestCreateSeries_RejectsNonTeacher(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(false))
+	expectTeacherAccess(mock, int64(7), int64(42), "")
 
 	body, _ := json.Marshal(map[string]any{
 		"number": 1, "name": "S", "due_at": time.Now().Add(time.Hour),
@@ -188,9 +208,7 @@ func TestCreateSeries_TeacherSucceeds(t *testing.T) {
 	due := now.Add(48 * time.Hour)
 
 	// 1. teacher check
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 	// 2. CreateSeries + its problems run in one transaction.
 	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO math_center_series`).
@@ -290,9 +308,7 @@ func TestCreateSeries_NoProblems(t *testing.T) {
 	now := time.Now()
 	due := now.Add(48 * time.Hour)
 
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO math_center_series`).
 		WithArgs(int64(42), int32(3), "Алгебра", pgxmock.AnyArg()).
@@ -337,7 +353,7 @@ func TestCreateSeries_NoProblems(t *testing.T) {
 // TestCreateSeries_AdminBypassesEnrollment proves the admin teacher-superset:
 // an admin (is_admin via JWT) who is NOT enrolled as a teacher of the center
 // can still create a series. The proof is in the expectations — NO
-// math_center_teachers lookup is set up, so requireTeacher MUST short-circuit
+// math_center_teachers lookup is set up, so teacherAccess MUST short-circuit
 // on callerIsAdmin or the test fails on an unexpected query.
 func TestCreateSeries_AdminBypassesEnrollment(t *testing.T) {
 	t.Parallel()
@@ -456,9 +472,7 @@ func TestUpdateSeries_AddsProblemPreservingExisting(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(3), "Алгебра", oldDue, (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 	// 2. Reconcile runs in one transaction.
 	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE math_center_series`).
@@ -553,9 +567,7 @@ func TestUpdateSeries_RemovesProblem(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(3), "Алгебра", due, (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE math_center_series`).
 		WithArgs(int64(100), int32(3), "Алгебра", pgxmock.AnyArg()).
@@ -625,9 +637,7 @@ func TestUpdateSeries_RollsBackOnProblemFailure(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(3), "Алгебра", now, (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE math_center_series`).
 		WithArgs(int64(100), int32(5), "Геометрия", pgxmock.AnyArg()).
@@ -906,9 +916,7 @@ func TestPDFUploadURLAndPublish(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 
 	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/pdf/upload-url", nil)
 	rr := httptest.NewRecorder()
@@ -938,9 +946,7 @@ func TestPDFUploadURLAndPublish(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 	mock.ExpectQuery(`UPDATE math_center_series\s+SET pdf_object_key`).
 		WithArgs(int64(100), &key).
 		WillReturnRows(mock.NewRows(seriesColumns).
@@ -979,9 +985,7 @@ func TestPDFUploadURLAndPublish(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", due, &key, (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherCheck(mock, 7, 42, true)
 	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_students`).
 		WithArgs(int64(7), int64(42)).
 		WillReturnRows(mock.NewRows([]string{"is_student"}).AddRow(false))
@@ -1011,9 +1015,7 @@ func TestPDFPublish_RejectsMissingObject(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 
 	body, _ := json.Marshal(map[string]string{"object_key": "mathcenter/series/100.pdf"})
 	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/pdf/publish", bytes.NewReader(body))
@@ -1043,9 +1045,7 @@ func TestPDFPublish_RejectsWrongContentType(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 
 	body, _ := json.Marshal(map[string]string{"object_key": key})
 	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/pdf/publish", bytes.NewReader(body))
@@ -1074,9 +1074,7 @@ func TestPDFPublish_RejectsOversize(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 
 	body, _ := json.Marshal(map[string]string{"object_key": key})
 	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/pdf/publish", bytes.NewReader(body))
@@ -1102,9 +1100,7 @@ func TestPDFPublish_RejectsForeignKey(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
 
 	body, _ := json.Marshal(map[string]string{"object_key": "mathcenter/series/999.pdf"})
 	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/pdf/publish", bytes.NewReader(body))
@@ -1127,14 +1123,17 @@ func TestDelete_TeacherRemovesObject(t *testing.T) {
 	key := "mathcenter/series/100.pdf"
 	// Pre-seed the blob so we can confirm Delete removes it.
 	_ = blobs.Put(t.Context(), key, strings.NewReader("PDF"), 3, "application/pdf")
+	assetKey := "mathcenter/tex-assets/series/100/fig.png"
+	_ = blobs.Put(t.Context(), assetKey, strings.NewReader("PNG"), 3, "image/png")
 
 	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), &key, &pubAt, now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
+	mock.ExpectQuery(`SELECT a.object_key\s+FROM math_center_tex_assets`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows([]string{"object_key"}).AddRow(assetKey))
 	mock.ExpectExec(`DELETE\s+FROM math_center_series WHERE id`).
 		WithArgs(int64(100)).
 		WillReturnResult(pgxmock.NewResult("DELETE", 1))
@@ -1148,6 +1147,9 @@ func TestDelete_TeacherRemovesObject(t *testing.T) {
 	if ok, _ := blobs.Exists(req.Context(), key); ok {
 		t.Error("delete should have removed the object")
 	}
+	if ok, _ := blobs.Exists(req.Context(), assetKey); ok {
+		t.Error("delete should have removed the TeX asset")
+	}
 }
 
 func TestPutSeriesTex_TeacherSucceeds(t *testing.T) {
@@ -1163,13 +1165,18 @@ func TestPutSeriesTex_TeacherSucceeds(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
+	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(problemColumns))
+	expectCenterPreamble(mock, 42, nil)
+	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE math_center_series\s+SET tex_source`).
 		WithArgs(int64(100), &tex).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), (*time.Time)(nil), now, &tex))
+	expectTexRevisionRecorded(mock, seriesTexArgs(42, 100, &tex, 7, false), 2)
+	mock.ExpectCommit()
 	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(problemColumns))
@@ -1216,13 +1223,20 @@ func TestPublishSeries_TeacherSucceeds(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), (*time.Time)(nil), now, &tex))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
+	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(problemColumns).
+			AddRow(int64(500), int64(100), int32(1), now))
+	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE math_center_series AS series\s+SET published_at`).
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), &pubAt, now, &tex))
+	mock.ExpectExec(`UPDATE math_center_tex_revisions\s+SET published_at`).
+		WithArgs(int64(100), pubAt).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
 	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(problemColumns).
@@ -1263,12 +1277,12 @@ func TestPublishSeries_RejectsIncompleteDraft(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
+	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE math_center_series AS series\s+SET published_at`).
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns))
+	mock.ExpectRollback()
 
 	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/publish", nil)
 	rr := httptest.NewRecorder()
@@ -1316,9 +1330,7 @@ func TestPutSeriesTex_RejectsNonTeacher(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(false))
+	expectTeacherAccess(mock, int64(7), int64(42), "")
 
 	tex := "\\documentclass{article}\n\\begin{document}\nhi\n\\end{document}\n"
 	body, _ := json.Marshal(map[string]string{"tex": tex})
@@ -1331,6 +1343,62 @@ func TestPutSeriesTex_RejectsNonTeacher(t *testing.T) {
 	}
 }
 
+func TestPutSeriesTex_RejectsGrader(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
+	expectTeacherAccess(mock, 7, 42, "grader")
+
+	body, _ := json.Marshal(map[string]string{"tex": "\\begin{document}\n\\end{document}\n"})
+	req := authedRequest(t, access, 7, http.MethodPut, "/series/100/tex", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+// A term-scoped assistant may edit only the series of their own term.
+func TestPutSeriesTex_RejectsAssistantOfAnotherTerm(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	termID := int64(71)
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
+	mock.ExpectQuery(`FROM math_center_teachers t\s+LEFT JOIN math_center_terms`).
+		WithArgs(int64(7), int64(42)).
+		WillReturnRows(mock.NewRows(teacherAccessColumns).AddRow(int64(1), false, "editor", &termID, true))
+	mock.ExpectQuery(`FROM math_center_series WHERE id = \$1 AND term_id = \$2`).
+		WithArgs(int64(100), termID).
+		WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(false))
+
+	body, _ := json.Marshal(map[string]string{"tex": "\\begin{document}\n\\end{document}\n"})
+	req := authedRequest(t, access, 7, http.MethodPut, "/series/100/tex", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestGetSeriesTex_StudentReadsPublished(t *testing.T) {
 	t.Parallel()
 	mock, _ := pgxmock.NewPool()
@@ -1352,6 +1420,7 @@ func TestGetSeriesTex_StudentReadsPublished(t *testing.T) {
 	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_students`).
 		WithArgs(int64(7), int64(42)).
 		WillReturnRows(mock.NewRows([]string{"is_student"}).AddRow(true))
+	expectTexAssets(mock, seriesTexAssetArgs(42, 100))
 
 	req := authedRequest(t, access, 7, http.MethodGet, "/series/100/tex", nil)
 	rr := httptest.NewRecorder()
@@ -1413,13 +1482,14 @@ func TestDeleteSeriesTex_TeacherClears(t *testing.T) {
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), &pubAt, now, &tex))
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(int64(7), int64(42)).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(true))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
+	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE math_center_series\s+SET tex_source = NULL`).
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(seriesColumns).
 			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), &pubAt, now, (*string)(nil)))
+	expectTexRevisionRecorded(mock, seriesTexArgs(42, 100, nil, 7, true), 3)
+	mock.ExpectCommit()
 	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
 		WithArgs(int64(100)).
 		WillReturnRows(mock.NewRows(problemColumns))
@@ -1,6 +1,7 @@
 package mathcenter
 
 import (
+	"errors"
 	"net/http"
 	"sort"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/Alarion239/my239/backend/internal/httpx"
 	"github.com/Alarion239/my239/backend/internal/live"
 	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
 	"github.com/Alarion239/my239/backend/pkg/db"
 )
@@ -56,77 +58,34 @@ func PublishSubproblemSolutions(database *db.DB, hub *live.Hub) http.HandlerFunc
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if len(targets) != len(ids) {
-			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "one or more subproblems not found")
+		if err := mc.CheckSolutionTargets(targets, len(ids)); err != nil {
+			writeSolutionTargetsError(w, r, err)
 			return
 		}
-		centerID, seriesID := targets[0].MathCenterID, targets[0].SeriesID
-		for _, target := range targets {
-			if target.MathCenterID != centerID || target.SeriesID != seriesID {
-				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "subproblems must belong to one center and series")
-				return
-			}
-			if !target.HasMaterial {
-				httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "every subproblem needs at least one saved разбор format")
-				return
-			}
-		}
-		if !requireTeacher(ctx, w, r, q, userID, centerID) {
+		centerID := targets[0].MathCenterID
+		if !requireTeacherCan(ctx, w, r, q, userID, centerID, mc.CanEditContent) {
 			return
 		}
-
-		// Keep an existing shared group when all targets already point at the
-		// same group. Otherwise mint one group and apply it to the whole set.
-		groupID, sameGroup := int64(0), true
-		for i, target := range targets {
-			if target.GroupID == nil {
-				sameGroup = false
-				break
-			}
-			if i == 0 {
-				groupID = *target.GroupID
-			} else if *target.GroupID != groupID {
-				sameGroup = false
-				break
-			}
-		}
-		if !sameGroup {
-			groupID, err = q.CreateSolutionGroup(ctx)
-			if err != nil {
-				logger.LogErrorContext(ctx, "razbor publish: create group", err)
-				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
-				return
-			}
-			if err := q.SetSubproblemSolutionGroup(ctx, store.SetSubproblemSolutionGroupParams{GroupID: groupID, SubproblemIds: ids}); err != nil {
-				logger.LogErrorContext(ctx, "razbor publish: set group", err)
-				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
-				return
-			}
+		if writeTexPublishError(w, r, mc.CheckSolutionTex(targets)) {
+			return
 		}
 
 		publishedAt := time.Now().UTC()
-		results, err := q.PublishSolutions(ctx, ids, publishedAt)
+		released, err := mc.PublishSolutionGroup(ctx, q, targets, ids, publishedAt)
 		if err != nil {
+			if errors.Is(err, mc.ErrSolutionTargetsChanged) {
+				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, err.Error())
+				return
+			}
 			logger.LogErrorContext(ctx, "razbor publish: update", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		if len(results) != len(ids) {
-			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "publication targets changed; retry")
-			return
-		}
 		if err := tx.Commit(ctx); err != nil {
 			logger.LogErrorContext(ctx, "razbor publish: commit", err)
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
-		released := make([]int64, 0, len(results))
-		for _, result := range results {
-			if result.IsCoffin {
-				released = append(released, result.SubproblemID)
-			}
-		}
-		sort.Slice(released, func(i, j int) bool { return released[i] < released[j] })
 		live.Publish(ctx, database.Pool(), live.Event{CenterID: centerID, Kind: live.KindCoffins})
 		httpx.WriteJSON(w, http.StatusOK, publishSolutionsResponse{
 			SubproblemIDs: ids, PublishedAt: publishedAt.Format(time.RFC3339Nano), ReleasedCoffinIDs: released,
@@ -134,6 +93,16 @@ func PublishSubproblemSolutions(database *db.DB, hub *live.Hub) http.HandlerFunc
 	}
 }
 
+// writeSolutionTargetsError maps a CheckSolutionTargets failure to its
+// response: missing subproblems are a 404, the rest bad requests.
+func writeSolutionTargetsError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, mc.ErrSolutionTargetsMissing) {
+		httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, err.Error())
+		return
+	}
+	httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, err.Error())
+}
+
 func uniquePositiveIDs(ids []int64) []int64 {
 	seen := make(map[int64]struct{}, len(ids))
 	out := make([]int64, 0, len(ids))
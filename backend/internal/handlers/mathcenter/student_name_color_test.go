@@ -16,7 +16,7 @@ func TestUpdateStudentNameColor_NormalizesAndStores(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherInCenter(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	expectStudentInCenter(mock, 99, 42, true)
 	mock.ExpectQuery(`INSERT INTO math_center_student_name_color`).
 		WithArgs(int64(42), int64(99), "#FFD09A").
@@ -46,7 +46,7 @@ func TestUpdateStudentNameColor_RejectsMalformedHex(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherInCenter(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	expectStudentInCenter(mock, 99, 42, true)
 	body, _ := json.Marshal(map[string]any{"background_hex": "red"})
 	req := authedRequest(t, access, 3, http.MethodPut, "/centers/42/students/99/name-color", bytes.NewReader(body))
@@ -65,7 +65,7 @@ func TestUpdateStudentNameColor_ClearsWithNull(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherInCenter(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	expectStudentInCenter(mock, 99, 42, true)
 	mock.ExpectExec(`DELETE FROM math_center_student_name_color`).
 		WithArgs(int64(42), int64(99)).
@@ -93,7 +93,7 @@ func TestUpdateStudentNameColor_RejectsNonTeacher(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherInCenter(mock, 9, 42, false)
+	expectTeacherAccess(mock, 9, 42, "")
 	req := authedRequest(t, access, 9, http.MethodPut, "/centers/42/students/99/name-color", bytes.NewReader([]byte(`{"background_hex":"#FFD09A"}`)))
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
@@ -110,7 +110,7 @@ func TestUpdateStudentNameColor_RejectsNonEnrolledTarget(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherInCenter(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	expectStudentInCenter(mock, 99, 42, false)
 	req := authedRequest(t, access, 3, http.MethodPut, "/centers/42/students/99/name-color", bytes.NewReader([]byte(`{"background_hex":"#FFD09A"}`)))
 	req.Header.Set("Content-Type", "application/json")
@@ -128,7 +128,7 @@ func TestGetStudentProfile_RejectsStudentCaller(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherInCenter(mock, 99, 42, false)
+	expectTeacherAccess(mock, 99, 42, "")
 	req := authedRequest(t, access, 99, http.MethodGet, "/centers/42/students/99/", nil)
 	rr := httptest.NewRecorder()
 	r.ServeHTTP(rr, req)
@@ -18,17 +18,22 @@ import (
 )
 
 // studentProfileView anchors the teacher-facing student page: identity + the
-// student's group, enough for the profile header that hosts the notes panel.
+// student's group, enough for the profile header that hosts the notes panel,
+// plus the progress dashboard the student sees for themselves.
 type studentProfileView struct {
-	UserID         int64   `json:"user_id"`
-	FirstName      string  `json:"first_name"`
-	MiddleName     *string `json:"middle_name"`
-	LastName       string  `json:"last_name"`
-	DisplayName    string  `json:"display_name"`
-	GroupID        int64   `json:"group_id"`
-	GroupName      string  `json:"group_name"`
-	GraduationYear int     `json:"graduation_year"`
-	BackgroundHex  *string `json:"background_hex"`
+	UserID         int64              `json:"user_id"`
+	FirstName      string             `json:"first_name"`
+	MiddleName     *string            `json:"middle_name"`
+	LastName       string             `json:"last_name"`
+	DisplayName    string             `json:"display_name"`
+	GroupID        int64              `json:"group_id"`
+	GroupName      string             `json:"group_name"`
+	GraduationYear int                `json:"graduation_year"`
+	BackgroundHex  *string            `json:"background_hex"`
+	Progress       mc.StudentProgress `json:"progress"`
+	// Attendance is per term in the order the terms began, counting lessons
+	// held so far.
+	Attendance []mc.AttendanceTerm `json:"attendance"`
 }
 
 // studentNoteView is the wire shape for one internal note on a student.
@@ -57,8 +62,9 @@ func validateStudentNoteBody(raw string) (string, string) {
 	return body, ""
 }
 
-// GetStudentProfile — teacher of the center. Returns a student's identity and
-// group; 404 if the user isn't a student of this center.
+// GetStudentProfile — teacher of the center. Returns a student's identity,
+// group and cross-term progress; 404 if the user isn't a student of this
+// center.
 func GetStudentProfile(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -94,6 +100,19 @@ func GetStudentProfile(database *db.DB) http.HandlerFunc {
 			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
 			return
 		}
+		now := time.Now()
+		progress, err := mc.LoadStudentProgress(ctx, q, centerID, studentUserID, now)
+		if err != nil {
+			logger.LogErrorContext(ctx, "mathcenter: get student progress", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		attendance, err := q.ListStudentAttendanceByTerm(ctx, centerID, studentUserID, now)
+		if err != nil {
+			logger.LogErrorContext(ctx, "mathcenter: get student attendance", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
 		httpx.WriteJSON(w, http.StatusOK, studentProfileView{
 			UserID:         u.ID,
 			FirstName:      u.FirstName,
@@ -104,6 +123,8 @@ func GetStudentProfile(database *db.DB) http.HandlerFunc {
 			GroupName:      student.GroupName,
 			GraduationYear: int(student.GraduationYear),
 			BackgroundHex:  backgroundHex,
+			Progress:       progress,
+			Attendance:     mc.BuildAttendanceTerms(attendance),
 		})
 	}
 }
@@ -268,7 +289,7 @@ func studentNotePathAuthz(ctx context.Context, w http.ResponseWriter, r *http.Re
 		return 0, 0, 0, false
 	}
 	q := store.New(database.Pool())
-	if !requireTeacher(ctx, w, r, q, userID, centerID) {
+	if !requireTeacherCan(ctx, w, r, q, userID, centerID, methodCapability(r, mc.CanGrade)) {
 		return 0, 0, 0, false
 	}
 	isStudent, err := q.IsStudentInCenter(ctx, store.IsStudentInCenterParams{
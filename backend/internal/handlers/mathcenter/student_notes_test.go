@@ -30,12 +30,6 @@ var studentByUserColumns = []string{
 	"id", "user_id", "group_id", "can_view_razbors", "group_name", "math_center_id", "graduation_year",
 }
 
-func expectTeacherInCenter(mock pgxmock.PgxPoolIface, userID, centerID int64, ok bool) {
-	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
-		WithArgs(userID, centerID).
-		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(ok))
-}
-
 func expectStudentInCenter(mock pgxmock.PgxPoolIface, userID, centerID int64, ok bool) {
 	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_students`).
 		WithArgs(userID, centerID).
@@ -49,7 +43,7 @@ func TestCreateStudentNote_TeacherSucceeds(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 	now := time.Now()
 
-	expectTeacherInCenter(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	expectStudentInCenter(mock, 99, 42, true)
 	mock.ExpectQuery(`INSERT INTO math_center_student_note`).
 		WithArgs(int64(99), int64(42), int64(3), "consistently strong on geometry").
@@ -82,7 +76,7 @@ func TestCreateStudentNote_RejectsNonStudentTarget(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherInCenter(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	expectStudentInCenter(mock, 99, 42, false)
 
 	body, _ := json.Marshal(map[string]any{"body": "note"})
@@ -102,7 +96,7 @@ func TestCreateStudentNote_RejectsNonTeacher(t *testing.T) {
 	defer mock.Close()
 	r, access, _ := newRouter(t, mock)
 
-	expectTeacherInCenter(mock, 9, 42, false)
+	expectTeacherAccess(mock, 9, 42, "")
 
 	body, _ := json.Marshal(map[string]any{"body": "note"})
 	req := authedRequest(t, access, 9, http.MethodPost, "/centers/42/students/99/notes", bytes.NewReader(body))
@@ -122,7 +116,7 @@ func TestGetStudentProfile_TeacherSucceeds(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 	now := time.Now()
 
-	expectTeacherInCenter(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	expectStudentInCenter(mock, 99, 42, true)
 	mock.ExpectQuery(`FROM math_center_students s\s+JOIN math_center_groups`).
 		WithArgs(int64(99)).
@@ -135,6 +129,20 @@ func TestGetStudentProfile_TeacherSucceeds(t *testing.T) {
 	mock.ExpectQuery(`FROM math_center_student_name_color`).
 		WithArgs(int64(42), int64(99)).
 		WillReturnRows(mock.NewRows([]string{"background_hex"}).AddRow("#FFD09A"))
+	grade := int32(9)
+	mock.ExpectQuery(`FROM math_center_series se\s+JOIN math_center_terms`).
+		WithArgs(int64(42), int64(99), pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows(progressSeriesColumns).
+			AddRow(int64(10), int32(1), "Алгебра", now.Add(-48*time.Hour), int64(7), "academic", &grade, true,
+				int64(4), int64(3), int64(1), int64(0), int64(1), int64(1), int64(0)))
+	mock.ExpectQuery(`JOIN homework_thread_event g`).
+		WithArgs(int64(42), int64(99)).
+		WillReturnRows(mock.NewRows([]string{"series_id", "graded", "total_seconds"}).
+			AddRow(int64(10), int64(2), float64(7200)))
+	mock.ExpectQuery(`FROM math_center_students s\s+JOIN math_center_terms t`).
+		WithArgs(int64(42), int64(99), pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id", "kind", "grade", "is_active", "lessons", "present", "late", "absent", "excused"}).
+			AddRow(int64(7), "academic", &grade, true, int64(5), int64(3), int64(1), int64(1), int64(0)))
 
 	req := authedRequest(t, access, 3, http.MethodGet, "/centers/42/students/99/", nil)
 	rr := httptest.NewRecorder()
@@ -151,6 +159,28 @@ func TestGetStudentProfile_TeacherSucceeds(t *testing.T) {
 	if got["group_name"] != "Группа А" {
 		t.Errorf("group_name: got %v", got["group_name"])
 	}
+	progress, _ := got["progress"].(map[string]any)
+	counts, _ := progress["counts"].(map[string]any)
+	if counts["accepted"] != float64(3) {
+		t.Errorf("progress.counts: got %v", progress["counts"])
+	}
+	turnaround, _ := progress["turnaround"].(map[string]any)
+	if turnaround["avg_seconds"] != float64(3600) {
+		t.Errorf("progress.turnaround: got %v", progress["turnaround"])
+	}
+	attendance, _ := got["attendance"].([]any)
+	if len(attendance) != 1 {
+		t.Fatalf("attendance: got %v", got["attendance"])
+	}
+	term, _ := attendance[0].(map[string]any)
+	if term["rate"] != float64(0.8) || term["unmarked"] != float64(0) {
+		t.Errorf("attendance[0]: got %v", term)
+	}
+}
+
+var progressSeriesColumns = []string{
+	"id", "number", "name", "due_at", "term_id", "kind", "grade", "is_active",
+	"subproblems", "accepted", "rejected", "pending", "coffins", "coffins_solved", "coffins_open",
 }
 
 func TestUpdateStudentNote_RejectsNonAuthor(t *testing.T) {
@@ -160,7 +190,7 @@ func TestUpdateStudentNote_RejectsNonAuthor(t *testing.T) {
 	r, access, _ := newRouter(t, mock)
 	now := time.Now()
 
-	expectTeacherInCenter(mock, 3, 42, true)
+	expectTeacherAccess(mock, 3, 42, "teacher")
 	expectStudentInCenter(mock, 99, 42, true)
 	// Note authored by user 4; user 3 (a teacher, not the author) tries to edit.
 	mock.ExpectQuery(`SELECT .* FROM math_center_student_note\s+WHERE id`).
@@ -11,6 +11,7 @@ import (
 
 	"github.com/Alarion239/my239/backend/internal/ctxcache"
 	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/live"
 	"github.com/Alarion239/my239/backend/internal/logger"
 	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
@@ -196,21 +197,137 @@ func CreateTerm(database *db.DB) http.HandlerFunc {
 	}
 }
 
+type rolloverGroupRequest struct {
+	SourceGroupID int64  `json:"source_group_id"`
+	Name          string `json:"name"`
+}
+
+type rolloverStudentRequest struct {
+	UserID int64 `json:"user_id"`
+	// GroupID is a group of the current term; the student follows its copy.
+	GroupID *int64 `json:"group_id"`
+}
+
+type rolloverTermRequest struct {
+	Kind       string                   `json:"kind"`
+	Grade      int32                    `json:"grade"`
+	Groups     []rolloverGroupRequest   `json:"groups"`
+	Students   []rolloverStudentRequest `json:"students"`
+	KeepGroups bool                     `json:"keep_groups"`
+	DryRun     bool                     `json:"dry_run"`
+}
+
+type rolloverTermResponse struct {
+	DryRun          bool                       `json:"dry_run"`
+	Term            termView                   `json:"term"`
+	ArchivedTerm    *termView                  `json:"archived_term"`
+	Groups          []mc.RolloverGroupReport   `json:"groups"`
+	Carried         []mc.RolloverStudentReport `json:"carried"`
+	LeftBehind      []mc.RolloverStudentReport `json:"left_behind"`
+	Teachers        []mc.RolloverTeacherReport `json:"teachers"`
+	TeachersLeaving []mc.RolloverTeacherReport `json:"teachers_leaving"`
+}
+
+// RolloverTerm moves the cohort into its next term: unlike CreateTerm, the
+// head teacher picks which groups follow (and under what names) and which
+// students are carried, into a group or the unassigned bucket. Omitted
+// kind/grade mean the next stage of the sequence. With dry_run the same
+// report is returned and nothing is saved.
+func RolloverTerm(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		q := store.New(database.Pool())
+		centerID, _, ok := manageGate(w, r, q)
+		if !ok {
+			return
+		}
+		var req rolloverTermRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		opts := mc.RolloverOptions{
+			Kind:       req.Kind,
+			Grade:      req.Grade,
+			KeepGroups: req.KeepGroups,
+			DryRun:     req.DryRun,
+		}
+		if req.Groups != nil {
+			opts.Groups = make([]mc.RolloverGroup, 0, len(req.Groups))
+			for _, g := range req.Groups {
+				opts.Groups = append(opts.Groups, mc.RolloverGroup{SourceGroupID: g.SourceGroupID, Name: g.Name})
+			}
+		}
+		if req.Students != nil {
+			opts.Students = make([]mc.RolloverStudent, 0, len(req.Students))
+			for _, s := range req.Students {
+				opts.Students = append(opts.Students, mc.RolloverStudent{UserID: s.UserID, SourceGroupID: s.GroupID})
+			}
+		}
+
+		report, err := mc.RolloverTerm(ctx, database.Pool(), centerID, opts)
+		switch {
+		case err == nil:
+		case errors.Is(err, mc.ErrInvalidInitialTerm):
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid term kind or grade")
+			return
+		case errors.Is(err, mc.ErrNextTermUnknown):
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "kind and grade are required for the first term")
+			return
+		case errors.Is(err, mc.ErrNoNextTerm):
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "the cohort has finished its last term")
+			return
+		case errors.Is(err, mc.ErrTermOutOfSequence):
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "term does not follow the cohort sequence")
+			return
+		case errors.Is(err, mc.ErrReservedGroupName):
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "this group name is reserved")
+			return
+		case errors.Is(err, mc.ErrRolloverGroupName):
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "name must be 1–50 chars")
+			return
+		case errors.Is(err, mc.ErrRolloverUnknownGroup),
+			errors.Is(err, mc.ErrRolloverUnknownStudent),
+			errors.Is(err, mc.ErrRolloverDuplicateGroup):
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, err.Error())
+			return
+		case isUniqueViolation(err):
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "term already exists")
+			return
+		default:
+			logger.LogErrorContext(ctx, "terms: rollover", err, "center_id", centerID)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to roll over term")
+			return
+		}
+
+		out := rolloverTermResponse{
+			DryRun:          report.DryRun,
+			Term:            toTermView(report.Term),
+			Groups:          report.Groups,
+			Carried:         report.Carried,
+			LeftBehind:      report.LeftBehind,
+			Teachers:        report.Teachers,
+			TeachersLeaving: report.TeachersLeaving,
+		}
+		if report.ArchivedTerm != nil {
+			archived := toTermView(*report.ArchivedTerm)
+			out.ArchivedTerm = &archived
+		}
+		status := http.StatusCreated
+		if report.DryRun {
+			status = http.StatusOK
+		} else {
+			live.Publish(ctx, database.Pool(), live.Event{CenterID: centerID, Kind: live.KindMembership})
+		}
+		httpx.WriteJSON(w, status, out)
+	}
+}
+
 func isNextTerm(terms []store.MathCenterTerm, kind string, grade int32) bool {
 	requested, valid := mc.TermStage(kind, grade)
 	if !valid {
 		return false
 	}
-	last := 0
-	for _, term := range terms {
-		if term.Kind == mc.TermKindLegacy || term.Grade == nil {
-			continue
-		}
-		stage, ok := mc.TermStage(term.Kind, *term.Grade)
-		if ok && stage > last {
-			last = stage
-		}
-	}
+	last := mc.LatestTermStage(terms)
 	// A legacy-only center is allowed to begin its new live history at the
 	// grade it is actually entering. Subsequent terms are strictly sequential.
 	return last == 0 || requested == last+1
@@ -0,0 +1,388 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/live"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/objectstore"
+)
+
+// TeX document assets. A series statement, a likbez and each subproblem's
+// разбор can carry pictures (PNG, SVG, JPEG) that the source references by
+// name with \includegraphics{name}. Uploads use the same presigned-PUT
+// dance as the PDFs: upload-url, PUT to storage, then a finalize call that
+// Stat-validates the object and records it. The endpoints are teacher-only;
+// the TeX GET endpoints resolve the names to signed URLs for everyone who
+// may read the document.
+
+// texAssetOwnerRef is a resolved, authorized asset owner.
+type texAssetOwnerRef struct {
+	owner store.TexAssetOwner
+	// event, when set, is emitted after the owner's assets change.
+	event *live.Event
+}
+
+// texAssetResolver loads the document named by the request path and checks
+// the caller may edit it. On !ok the response is already written.
+type texAssetResolver func(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID int64) (texAssetOwnerRef, bool)
+
+func seriesTexAssetOwner(centerID, seriesID int64) store.TexAssetOwner {
+	return store.TexAssetOwner{MathCenterID: centerID, Kind: store.TexAssetSeries, SeriesID: &seriesID}
+}
+
+func likbezTexAssetOwner(centerID, likbezID int64) store.TexAssetOwner {
+	return store.TexAssetOwner{MathCenterID: centerID, Kind: store.TexAssetLikbez, LikbezID: &likbezID}
+}
+
+func solutionTexAssetOwner(centerID, subproblemID int64) store.TexAssetOwner {
+	return store.TexAssetOwner{MathCenterID: centerID, Kind: store.TexAssetSolution, SubproblemID: &subproblemID}
+}
+
+// texAssetObjectKey is the canonical bucket key of an asset. Like
+// pdfObjectKey it derives from ids, so re-uploading a name is a Put-over.
+func texAssetObjectKey(owner store.TexAssetOwner, name string) string {
+	var id int64
+	switch owner.Kind {
+	case store.TexAssetSeries:
+		id = *owner.SeriesID
+	case store.TexAssetLikbez:
+		id = *owner.LikbezID
+	case store.TexAssetSolution:
+		id = *owner.SubproblemID
+	}
+	return fmt.Sprintf("mathcenter/tex-assets/%s/%d/%s", owner.Kind, id, name)
+}
+
+func resolveSeriesTexAssetOwner(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID int64) (texAssetOwnerRef, bool) {
+	seriesID, err := pathInt64(r, "seriesID")
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid series id")
+		return texAssetOwnerRef{}, false
+	}
+	series, err := q.GetSeries(ctx, seriesID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "series not found")
+			return texAssetOwnerRef{}, false
+		}
+		logger.LogErrorContext(ctx, "tex assets: get series", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return texAssetOwnerRef{}, false
+	}
+	if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, methodCapability(r, mc.CanEditContent)) {
+		return texAssetOwnerRef{}, false
+	}
+	return texAssetOwnerRef{
+		owner: seriesTexAssetOwner(series.MathCenterID, series.ID),
+		event: &live.Event{CenterID: series.MathCenterID, Kind: live.KindSeries, SeriesID: series.ID},
+	}, true
+}
+
+func resolveLikbezTexAssetOwner(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID int64) (texAssetOwnerRef, bool) {
+	likbezID, err := pathInt64(r, "likbezID")
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid likbez id")
+		return texAssetOwnerRef{}, false
+	}
+	row, err := q.GetLikbez(ctx, likbezID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "likbez not found")
+			return texAssetOwnerRef{}, false
+		}
+		logger.LogErrorContext(ctx, "tex assets: get likbez", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return texAssetOwnerRef{}, false
+	}
+	if !requireTeacherCan(ctx, w, r, q, userID, row.MathCenterID, methodCapability(r, mc.CanEditContent)) {
+		return texAssetOwnerRef{}, false
+	}
+	return texAssetOwnerRef{
+		owner: likbezTexAssetOwner(row.MathCenterID, row.ID),
+		event: &live.Event{CenterID: row.MathCenterID, Kind: live.KindLikbez},
+	}, true
+}
+
+func resolveSolutionTexAssetOwner(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID int64) (texAssetOwnerRef, bool) {
+	subproblemID, err := pathInt64(r, "subproblemID")
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid subproblem id")
+		return texAssetOwnerRef{}, false
+	}
+	sc, ok := loadSubproblemForWrite(ctx, w, r, q, userID, subproblemID)
+	if !ok {
+		return texAssetOwnerRef{}, false
+	}
+	return texAssetOwnerRef{
+		owner: solutionTexAssetOwner(sc.MathCenterID, subproblemID),
+		event: &live.Event{CenterID: sc.MathCenterID, Kind: live.KindCoffins},
+	}, true
+}
+
+type texAssetView struct {
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func toTexAssetView(a store.TexAsset) texAssetView {
+	return texAssetView{Name: a.Name, ContentType: a.ContentType, SizeBytes: a.SizeBytes, UpdatedAt: a.UpdatedAt}
+}
+
+type texAssetRequest struct {
+	Name string `json:"name"`
+}
+
+// texAssetUploadURLResponse is the body of assets/upload-url. The client
+// PUTs the bytes to UploadURL with Content-Type set to ContentType, then
+// POSTs Name to assets to commit.
+type texAssetUploadURLResponse struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	ObjectKey   string `json:"object_key"`
+	UploadURL   string `json:"upload_url"`
+}
+
+// ListTexAssets returns the document's assets by name.
+func ListTexAssets(database *db.DB, resolve texAssetResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		q := store.New(database.Pool())
+		ref, ok := resolve(ctx, w, r, q, userID)
+		if !ok {
+			return
+		}
+		assets, err := q.ListTexAssets(ctx, ref.owner)
+		if err != nil {
+			logger.LogErrorContext(ctx, "tex assets: list", err, "kind", ref.owner.Kind)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to list assets")
+			return
+		}
+		out := make([]texAssetView, 0, len(assets))
+		for _, a := range assets {
+			out = append(out, toTexAssetView(a))
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// IssueTexAssetUploadURL validates the name and mints a presigned PUT URL
+// for it. Nothing is recorded until FinalizeTexAsset; a new name is refused
+// once the document holds MaxTexAssetsPerDocument assets.
+func IssueTexAssetUploadURL(database *db.DB, blobs objectstore.Store, uploadTTL time.Duration, resolve texAssetResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		var req texAssetRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		contentType, ok := mc.TexAssetContentType(req.Name)
+		if !ok {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "asset name must be latin letters, digits, '.', '_' or '-' ending in .png, .svg, .jpg or .jpeg")
+			return
+		}
+		q := store.New(database.Pool())
+		ref, ok := resolve(ctx, w, r, q, userID)
+		if !ok {
+			return
+		}
+		assets, err := q.ListTexAssets(ctx, ref.owner)
+		if err != nil {
+			logger.LogErrorContext(ctx, "tex assets: list for upload", err, "kind", ref.owner.Kind)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if len(assets) >= mc.MaxTexAssetsPerDocument && !hasTexAsset(assets, req.Name) {
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, fmt.Sprintf("a document holds at most %d assets", mc.MaxTexAssetsPerDocument))
+			return
+		}
+
+		key := texAssetObjectKey(ref.owner, req.Name)
+		url, err := blobs.PresignPut(ctx, key, contentType, uploadTTL)
+		if err != nil {
+			logger.LogErrorContext(ctx, "tex assets: presign put", err)
+			httpx.WriteAPIError(w, r, http.StatusBadGateway, httpx.CodeUnavailable, "object storage unavailable")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, texAssetUploadURLResponse{Name: req.Name, ContentType: contentType, ObjectKey: key, UploadURL: url})
+	}
+}
+
+// FinalizeTexAsset Stat-validates the uploaded object (existence, the
+// content type its name implies, size <= MaxTexAssetBytes) and records it.
+// Re-finalizing a name replaces the asset.
+func FinalizeTexAsset(database *db.DB, blobs objectstore.Store, resolve texAssetResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		var req texAssetRequest
+		if !httpx.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		contentType, ok := mc.TexAssetContentType(req.Name)
+		if !ok {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid asset name")
+			return
+		}
+		q := store.New(database.Pool())
+		ref, ok := resolve(ctx, w, r, q, userID)
+		if !ok {
+			return
+		}
+
+		key := texAssetObjectKey(ref.owner, req.Name)
+		size, ct, err := blobs.Stat(ctx, key)
+		if err != nil {
+			if errors.Is(err, objectstore.ErrNotFound) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "no asset uploaded yet")
+				return
+			}
+			logger.LogErrorContext(ctx, "tex assets: stat blob", err)
+			httpx.WriteAPIError(w, r, http.StatusBadGateway, httpx.CodeUnavailable, "object storage unavailable")
+			return
+		}
+		if ct != contentType {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "uploaded object is not "+contentType)
+			return
+		}
+		if size <= 0 || size > mc.MaxTexAssetBytes {
+			httpx.WriteAPIError(w, r, http.StatusRequestEntityTooLarge, httpx.CodeBadRequest, "asset exceeds size limit")
+			return
+		}
+
+		asset, err := q.UpsertTexAsset(ctx, store.UpsertTexAssetParams{
+			Owner:            ref.owner,
+			Name:             req.Name,
+			ObjectKey:        key,
+			ContentType:      contentType,
+			SizeBytes:        size,
+			UploadedByUserID: &userID,
+		})
+		if err != nil {
+			logger.LogErrorContext(ctx, "tex assets: record", err, "kind", ref.owner.Kind)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to save asset")
+			return
+		}
+		if ref.event != nil {
+			live.Publish(ctx, database.Pool(), *ref.event)
+		}
+		httpx.WriteJSON(w, http.StatusOK, toTexAssetView(asset))
+	}
+}
+
+// DeleteTexAsset removes the asset and its object. The source is not
+// touched; a leftover \includegraphics of the name no longer resolves.
+func DeleteTexAsset(database *db.DB, blobs objectstore.Store, resolve texAssetResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		name := chi.URLParam(r, "name")
+		q := store.New(database.Pool())
+		ref, ok := resolve(ctx, w, r, q, userID)
+		if !ok {
+			return
+		}
+		asset, err := q.DeleteTexAsset(ctx, ref.owner, name)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "asset not found")
+				return
+			}
+			logger.LogErrorContext(ctx, "tex assets: delete", err, "kind", ref.owner.Kind)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to delete asset")
+			return
+		}
+		deleteTexAssetObjects(ctx, blobs, []string{asset.ObjectKey})
+		if ref.event != nil {
+			live.Publish(ctx, database.Pool(), *ref.event)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func hasTexAsset(assets []store.TexAsset, name string) bool {
+	for _, a := range assets {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteTexAssetObjects deletes objects whose rows are gone. A failure
+// only strands an object, so it is logged, not returned.
+func deleteTexAssetObjects(ctx context.Context, blobs objectstore.Store, keys []string) {
+	for _, key := range keys {
+		if err := blobs.Delete(ctx, key); err != nil {
+			logger.LogErrorContext(ctx, "tex assets: delete object", err, "key", key)
+		}
+	}
+}
+
+// texSourceView is what the TeX GET endpoints serve. RenderedTex is the
+// source with each \includegraphics of an asset pointed at a signed URL,
+// and Assets maps every asset name to its URL; both are omitted when the
+// document has no assets.
+type texSourceView struct {
+	Tex         string            `json:"tex"`
+	RenderedTex string            `json:"rendered_tex,omitempty"`
+	Assets      map[string]string `json:"assets,omitempty"`
+}
+
+// writeTexSource serves tex with the owner's assets resolved. The caller
+// has already authorized the read.
+func writeTexSource(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, blobs objectstore.Store, ttl time.Duration, owner store.TexAssetOwner, tex string) {
+	assets, err := q.ListTexAssets(ctx, owner)
+	if err != nil {
+		logger.LogErrorContext(ctx, "tex assets: list for serving", err, "kind", owner.Kind)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return
+	}
+	view := texSourceView{Tex: tex}
+	if len(assets) > 0 {
+		view.Assets = make(map[string]string, len(assets))
+		for _, a := range assets {
+			url, err := blobs.PresignGet(ctx, a.ObjectKey, ttl)
+			if err != nil {
+				// A missing object leaves its name unresolved rather than
+				// failing the whole document.
+				if errors.Is(err, objectstore.ErrNotFound) {
+					continue
+				}
+				logger.LogErrorContext(ctx, "tex assets: presign get", err)
+				httpx.WriteAPIError(w, r, http.StatusBadGateway, httpx.CodeUnavailable, "object storage unavailable")
+				return
+			}
+			view.Assets[a.Name] = url
+		}
+		view.RenderedTex = mc.ResolveTexAssets(tex, view.Assets)
+	}
+	httpx.WriteJSON(w, http.StatusOK, view)
+}
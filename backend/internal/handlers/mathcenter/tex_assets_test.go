@@ -0,0 +1,201 @@
+package mathcenter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var texAssetColumns = []string{"id", "name", "object_key", "content_type", "size_bytes", "uploaded_by_user_id", "created_at", "updated_at"}
+
+// seriesTexAssetArgs is the owner predicate ($1..$5) of a series statement.
+func seriesTexAssetArgs(centerID, seriesID int64) []any {
+	return []any{centerID, "series", &seriesID, (*int64)(nil), (*int64)(nil)}
+}
+
+// expectTexAssets answers ListTexAssets with the given asset names, each
+// stored under its canonical series key.
+func expectTexAssets(mock pgxmock.PgxPoolIface, owner []any, names ...string) {
+	rows := mock.NewRows(texAssetColumns)
+	now := time.Now()
+	for i, name := range names {
+		key := "mathcenter/tex-assets/series/" + strconv.FormatInt(*owner[2].(*int64), 10) + "/" + name
+		rows.AddRow(int64(i+1), name, key, "image/png", int64(3), (*int64)(nil), now, now)
+	}
+	mock.ExpectQuery(`SELECT .* FROM math_center_tex_assets\s+WHERE math_center_id`).
+		WithArgs(owner...).
+		WillReturnRows(rows)
+}
+
+// expectSeriesForTeacher answers the asset resolver for draft series 100
+// of center 42 and teacher 7.
+func expectSeriesForTeacher(mock pgxmock.PgxPoolIface) {
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
+	expectTeacherAccess(mock, 7, 42, "teacher")
+}
+
+func TestTexAsset_UploadAndFinalize(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	expectSeriesForTeacher(mock)
+	expectTexAssets(mock, seriesTexAssetArgs(42, 100))
+
+	body, _ := json.Marshal(map[string]string{"name": "graph.png"})
+	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/tex/assets/upload-url", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload-url: got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var issued struct {
+		ContentType string `json:"content_type"`
+		ObjectKey   string `json:"object_key"`
+		UploadURL   string `json:"upload_url"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &issued)
+	const key = "mathcenter/tex-assets/series/100/graph.png"
+	if issued.ObjectKey != key || issued.ContentType != "image/png" || issued.UploadURL == "" {
+		t.Fatalf("upload-url = %+v", issued)
+	}
+
+	_ = blobs.Put(t.Context(), key, strings.NewReader("PNG"), 3, "image/png")
+	now := time.Now()
+	expectSeriesForTeacher(mock)
+	mock.ExpectQuery(`INSERT INTO math_center_tex_assets`).
+		WithArgs(append(seriesTexAssetArgs(42, 100), "graph.png", key, "image/png", int64(3), pgxmock.AnyArg())...).
+		WillReturnRows(mock.NewRows(texAssetColumns).
+			AddRow(int64(1), "graph.png", key, "image/png", int64(3), (*int64)(nil), now, now))
+
+	req = authedRequest(t, access, 7, http.MethodPost, "/series/100/tex/assets", bytes.NewReader(body))
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("finalize: got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTexAsset_RejectsBadName(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	for _, name := range []string{"../x.png", "doc.pdf", "graph"} {
+		body, _ := json.Marshal(map[string]string{"name": name})
+		req := authedRequest(t, access, 7, http.MethodPost, "/series/100/tex/assets/upload-url", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%q: got %d, want 400", name, rr.Code)
+		}
+	}
+}
+
+func TestTexAsset_FinalizeRejectsWrongContentType(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	_ = blobs.Put(t.Context(), "mathcenter/tex-assets/series/100/graph.png", strings.NewReader("<svg/>"), 6, "image/svg+xml")
+	expectSeriesForTeacher(mock)
+
+	body, _ := json.Marshal(map[string]string{"name": "graph.png"})
+	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/tex/assets", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetSeriesTex_StudentSeesResolvedAssets(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	_ = blobs.Put(t.Context(), "mathcenter/tex-assets/series/100/graph.png", strings.NewReader("PNG"), 3, "image/png")
+	now := time.Now()
+	tex := `\begin{document}\includegraphics[width=5cm]{graph}\end{document}`
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), &now, now, &tex))
+	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_teachers`).
+		WithArgs(int64(7), int64(42)).
+		WillReturnRows(mock.NewRows([]string{"is_teacher"}).AddRow(false))
+	mock.ExpectQuery(`SELECT EXISTS .* FROM math_center_students`).
+		WithArgs(int64(7), int64(42)).
+		WillReturnRows(mock.NewRows([]string{"is_student"}).AddRow(true))
+	expectTexAssets(mock, seriesTexAssetArgs(42, 100), "graph.png")
+
+	req := authedRequest(t, access, 7, http.MethodGet, "/series/100/tex", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Tex         string            `json:"tex"`
+		RenderedTex string            `json:"rendered_tex"`
+		Assets      map[string]string `json:"assets"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	url := resp.Assets["graph.png"]
+	if resp.Tex != tex || url == "" {
+		t.Fatalf("resp = %+v", resp)
+	}
+	if want := `\includegraphics[width=5cm]{` + url + `}`; !strings.Contains(resp.RenderedTex, want) {
+		t.Errorf("rendered_tex = %q, want it to contain %q", resp.RenderedTex, want)
+	}
+}
+
+func TestTexAsset_DeleteRemovesObject(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, blobs := newRouter(t, mock)
+
+	const key = "mathcenter/tex-assets/series/100/graph.png"
+	_ = blobs.Put(t.Context(), key, strings.NewReader("PNG"), 3, "image/png")
+	now := time.Now()
+	expectSeriesForTeacher(mock)
+	mock.ExpectQuery(`DELETE\s+FROM math_center_tex_assets`).
+		WithArgs(append(seriesTexAssetArgs(42, 100), "graph.png")...).
+		WillReturnRows(mock.NewRows(texAssetColumns).
+			AddRow(int64(1), "graph.png", key, "image/png", int64(3), (*int64)(nil), now, now))
+
+	req := authedRequest(t, access, 7, http.MethodDelete, "/series/100/tex/assets/graph.png", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("got %d, want 204; body=%s", rr.Code, rr.Body.String())
+	}
+	if ok, _ := blobs.Exists(req.Context(), key); ok {
+		t.Error("delete should have removed the object")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
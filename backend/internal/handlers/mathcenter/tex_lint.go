@@ -0,0 +1,41 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// TeX validation. Every TeX save (series statement, likbez, разбор) is
+// linted against the center preamble and the diagnostics come back in the
+// response as tex_diagnostics; a draft is saved regardless. Sources students
+// can see must be free of errors: publication refuses them, and so does a
+// save to a source that is already published.
+
+// lintTexSource lints the stored form of a source. problemNumbers is nil
+// for sources without problem headings. Writes 500 on failure.
+func lintTexSource(ctx context.Context, w http.ResponseWriter, r *http.Request, database *db.DB, centerID int64, tex string, problemNumbers []int32) ([]mc.TexDiagnostic, bool) {
+	preamble, err := centerLatexPreamble(ctx, database, centerID)
+	if err != nil {
+		logger.LogErrorContext(ctx, "tex lint: load preamble", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return nil, false
+	}
+	return mc.LintTex(tex, mc.TexLintOptions{Preamble: preamble, ProblemNumbers: problemNumbers}), true
+}
+
+// writeTexPublishError writes the 409 for a source the publication gate
+// refused, with its errors as details, and reports whether err was one.
+func writeTexPublishError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var perr *mc.TexPublishError
+	if !errors.As(err, &perr) {
+		return false
+	}
+	httpx.WriteAPIErrorDetails(w, r, http.StatusConflict, httpx.CodeConflict, err.Error(), perr.Diagnostics)
+	return true
+}
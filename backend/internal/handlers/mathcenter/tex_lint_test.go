@@ -0,0 +1,190 @@
+package mathcenter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+// expectCenterPreamble answers centerLatexPreamble; nil means the center
+// has not saved one and the default applies.
+func expectCenterPreamble(mock pgxmock.PgxPoolIface, centerID int64, preamble *string) {
+	q := mock.ExpectQuery(`SELECT preamble FROM math_center_latex_settings`).WithArgs(centerID)
+	if preamble == nil {
+		q.WillReturnError(pgx.ErrNoRows)
+		return
+	}
+	q.WillReturnRows(mock.NewRows([]string{"preamble"}).AddRow(*preamble))
+}
+
+type texLintErrorBody struct {
+	Code    string `json:"code"`
+	Details []struct {
+		Line int    `json:"line"`
+		Code string `json:"code"`
+	} `json:"details"`
+}
+
+func TestPutSeriesTex_DraftSavesWithDiagnostics(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	due := now.Add(time.Hour)
+	tex := "\\begin{document}\nЗадача 1. $x^2\n\n\\foo\n\\end{document}"
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), (*time.Time)(nil), now, (*string)(nil)))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
+	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(problemColumns).AddRow(int64(500), int64(100), int32(1), now))
+	expectCenterPreamble(mock, 42, nil)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE math_center_series\s+SET tex_source`).
+		WithArgs(int64(100), &tex).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", due, (*string)(nil), (*time.Time)(nil), now, &tex))
+	expectTexRevisionRecorded(mock, seriesTexArgs(42, 100, &tex, 7, false), 2)
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(problemColumns))
+	mock.ExpectQuery(`FROM math_center_subproblems s\s+JOIN math_center_problems`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(subproblemRowColumns))
+	mock.ExpectQuery(`FROM math_center_subproblem_solutions ss`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(subproblemSolutionMetaColumns))
+
+	body, _ := json.Marshal(map[string]string{"tex": tex})
+	req := authedRequest(t, access, 7, http.MethodPut, "/series/100/tex", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var view struct {
+		TexDiagnostics []struct {
+			Line     int    `json:"line"`
+			Severity string `json:"severity"`
+			Code     string `json:"code"`
+		} `json:"tex_diagnostics"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &view); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(view.TexDiagnostics) != 2 ||
+		view.TexDiagnostics[0].Code != "math-unclosed" || view.TexDiagnostics[0].Line != 2 ||
+		view.TexDiagnostics[1].Code != "unknown-command" || view.TexDiagnostics[1].Severity != "warning" {
+		t.Errorf("unexpected diagnostics: %+v", view.TexDiagnostics)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPutSeriesTex_PublishedRefusesErrors(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	tex := "\\begin{document}\n\\begin{itemize}\n\\end{document}"
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now, (*string)(nil), &now, now, (*string)(nil)))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
+	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(problemColumns))
+	expectCenterPreamble(mock, 42, nil)
+
+	body, _ := json.Marshal(map[string]string{"tex": tex})
+	req := authedRequest(t, access, 7, http.MethodPut, "/series/100/tex", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("got %d, want 409; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp texLintErrorBody
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Code != "conflict" || len(resp.Details) != 1 || resp.Details[0].Code != "env-unclosed" || resp.Details[0].Line != 2 {
+		t.Errorf("unexpected error body: %s", rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPublishSeries_RefusesTexWithErrors(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	tex := "\\begin{document}\nЗадача 1.\nЗадача 2.\n\\end{document}"
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now, (*string)(nil), (*time.Time)(nil), now, &tex))
+	expectTeacherAccess(mock, int64(7), int64(42), "teacher")
+	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(problemColumns).AddRow(int64(500), int64(100), int32(1), now))
+
+	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/publish", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("got %d, want 409; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp texLintErrorBody
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Details) != 1 || resp.Details[0].Code != "problem-unknown" || resp.Details[0].Line != 3 {
+		t.Errorf("unexpected error body: %s", rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPublishLikbez_RefusesTexWithErrors(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	tex := "\\begin{document}\n$$ x\n\\end{document}"
+	mock.ExpectQuery(`FROM math_center_likbez l\s+JOIN math_center_terms`).
+		WithArgs(int64(9)).
+		WillReturnRows(mock.NewRows(likbezColumns).AddRow(
+			int64(9), int64(42), int64(7), int32(4), "Инварианты", now, "",
+			(*string)(nil), &tex, (*string)(nil), (*time.Time)(nil), now, now, "academic", (*int32)(nil),
+		))
+
+	req := authedAdminRequest(t, access, 1, http.MethodPost, "/likbez/9/publish", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("got %d, want 409; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
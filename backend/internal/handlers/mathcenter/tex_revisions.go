@@ -0,0 +1,419 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/httpx"
+	"github.com/Alarion239/my239/backend/internal/live"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mcdomain "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// TeX revision history. The series statement, each subproblem's разбор and
+// the center preamble keep every saved version; the writers append one in
+// the transaction that updates the live column (recordTexRevision). The
+// endpoints below are shared by the three kinds through texTargetResolver
+// and are teacher-only: students only ever see the live source.
+
+// texTargetRef is a resolved, authorized revision target.
+type texTargetRef struct {
+	target store.TexTarget
+	// label names the source in diff headers.
+	label string
+	// apply writes body to the live column inside the restore transaction
+	// and reports whether the target is visible to students.
+	apply func(ctx context.Context, qx *store.Queries, body *string) (published bool, err error)
+	// event, when set, is emitted after a restore commits.
+	event *live.Event
+}
+
+// texTargetResolver loads the target named by the request path and checks
+// the caller may edit it. On !ok the response is already written.
+type texTargetResolver func(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID int64) (texTargetRef, bool)
+
+func seriesTexTarget(centerID, seriesID int64) store.TexTarget {
+	return store.TexTarget{MathCenterID: centerID, Kind: store.TexTargetSeries, SeriesID: &seriesID}
+}
+
+func solutionTexTarget(centerID, subproblemID int64) store.TexTarget {
+	return store.TexTarget{MathCenterID: centerID, Kind: store.TexTargetSolution, SubproblemID: &subproblemID}
+}
+
+func preambleTexTarget(centerID int64) store.TexTarget {
+	return store.TexTarget{MathCenterID: centerID, Kind: store.TexTargetPreamble}
+}
+
+// recordTexRevision appends a revision for a save that changed the source;
+// an unchanged save records nothing. Writes 409/500 on failure.
+func recordTexRevision(ctx context.Context, w http.ResponseWriter, r *http.Request, qx *store.Queries, arg store.RecordTexRevisionParams) bool {
+	_, err := qx.RecordTexRevision(ctx, arg)
+	if err == nil || errors.Is(err, pgx.ErrNoRows) {
+		return true
+	}
+	if isUniqueViolation(err) {
+		httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "the source was saved concurrently; reload and retry")
+		return false
+	}
+	logger.LogErrorContext(ctx, "tex revisions: record", err, "kind", arg.Target.Kind)
+	httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to record revision")
+	return false
+}
+
+func resolveSeriesTexTarget(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID int64) (texTargetRef, bool) {
+	seriesID, err := pathInt64(r, "seriesID")
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid series id")
+		return texTargetRef{}, false
+	}
+	series, err := q.GetSeries(ctx, seriesID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "series not found")
+			return texTargetRef{}, false
+		}
+		logger.LogErrorContext(ctx, "tex revisions: get series", err)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return texTargetRef{}, false
+	}
+	if !requireSeriesTeacherCan(ctx, w, r, q, userID, series.MathCenterID, series.ID, methodCapability(r, mcdomain.CanEditContent)) {
+		return texTargetRef{}, false
+	}
+	return texTargetRef{
+		target: seriesTexTarget(series.MathCenterID, series.ID),
+		label:  fmt.Sprintf("series-%d.tex", series.ID),
+		apply: func(ctx context.Context, qx *store.Queries, body *string) (bool, error) {
+			if body == nil {
+				_, err := qx.ClearSeriesTex(ctx, series.ID)
+				return series.PublishedAt != nil, err
+			}
+			_, err := qx.SetSeriesTex(ctx, store.SetSeriesTexParams{ID: series.ID, TexSource: body})
+			return series.PublishedAt != nil, err
+		},
+	}, true
+}
+
+func resolveSolutionTexTarget(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, userID int64) (texTargetRef, bool) {
+	subproblemID, err := pathInt64(r, "subproblemID")
+	if err != nil {
+		httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid subproblem id")
+		return texTargetRef{}, false
+	}
+	sc, ok := loadSubproblemForWrite(ctx, w, r, q, userID, subproblemID)
+	if !ok {
+		return texTargetRef{}, false
+	}
+	return texTargetRef{
+		target: solutionTexTarget(sc.MathCenterID, subproblemID),
+		label:  fmt.Sprintf("razbor-%d.tex", subproblemID),
+		apply: func(ctx context.Context, qx *store.Queries, body *string) (bool, error) {
+			s, err := qx.SetSubproblemSolutionTexWithPublication(ctx, subproblemID, body)
+			return s.PublishedAt != nil, err
+		},
+		event: &live.Event{CenterID: sc.MathCenterID, Kind: live.KindCoffins},
+	}, true
+}
+
+func resolvePreambleTexTarget(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, _ int64) (texTargetRef, bool) {
+	centerID, _, ok := manageGateCan(w, r, q, methodCapability(r, mcdomain.CanEditContent))
+	if !ok {
+		return texTargetRef{}, false
+	}
+	return texTargetRef{
+		target: preambleTexTarget(centerID),
+		label:  "preamble.tex",
+		apply: func(ctx context.Context, qx *store.Queries, body *string) (bool, error) {
+			// The preamble is never cleared, so every revision has a body.
+			return true, qx.UpsertLatexPreamble(ctx, centerID, *body)
+		},
+	}, true
+}
+
+type texRevisionView struct {
+	Revision             int32      `json:"revision"`
+	AuthorUserID         *int64     `json:"author_user_id"`
+	RestoredFromRevision *int32     `json:"restored_from_revision"`
+	CreatedAt            time.Time  `json:"created_at"`
+	PublishedAt          *time.Time `json:"published_at"`
+	// Cleared marks a revision that removed the source.
+	Cleared bool   `json:"cleared"`
+	Bytes   int    `json:"bytes"`
+	Tex     string `json:"tex,omitempty"`
+}
+
+func toTexRevisionView(rev store.TexRevision, withBody bool) texRevisionView {
+	v := texRevisionView{
+		Revision:             rev.Revision,
+		AuthorUserID:         rev.AuthorUserID,
+		RestoredFromRevision: rev.RestoredFromRevision,
+		CreatedAt:            rev.CreatedAt,
+		PublishedAt:          rev.PublishedAt,
+		Cleared:              rev.Body == nil,
+	}
+	if rev.Body != nil {
+		v.Bytes = len(*rev.Body)
+		if withBody {
+			v.Tex = *rev.Body
+		}
+	}
+	return v
+}
+
+type texHistoryView struct {
+	Revisions []texRevisionView `json:"revisions"`
+	// ShownRevision is the newest revision students have been shown, or
+	// null while the target was never published.
+	ShownRevision *int32 `json:"shown_revision"`
+}
+
+// ListTexRevisions returns the target's history, newest first, without the
+// bodies.
+func ListTexRevisions(database *db.DB, resolve texTargetResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		q := store.New(database.Pool())
+		ref, ok := resolve(ctx, w, r, q, userID)
+		if !ok {
+			return
+		}
+		revs, err := q.ListTexRevisions(ctx, ref.target)
+		if err != nil {
+			logger.LogErrorContext(ctx, "tex revisions: list", err, "kind", ref.target.Kind)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to list revisions")
+			return
+		}
+		out := texHistoryView{Revisions: make([]texRevisionView, 0, len(revs))}
+		for _, rev := range revs {
+			out.Revisions = append(out.Revisions, toTexRevisionView(rev, false))
+			if out.ShownRevision == nil && rev.PublishedAt != nil {
+				n := rev.Revision
+				out.ShownRevision = &n
+			}
+		}
+		httpx.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// GetTexRevision returns one revision with its body.
+func GetTexRevision(database *db.DB, resolve texTargetResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		revision, err := pathRevision(r)
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid revision")
+			return
+		}
+		q := store.New(database.Pool())
+		ref, ok := resolve(ctx, w, r, q, userID)
+		if !ok {
+			return
+		}
+		rev, ok := loadTexRevision(ctx, w, r, q, ref.target, revision)
+		if !ok {
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, toTexRevisionView(rev, true))
+	}
+}
+
+type texDiffView struct {
+	From int32  `json:"from"`
+	To   int32  `json:"to"`
+	Diff string `json:"diff"`
+}
+
+// DiffTexRevisions renders a unified diff between ?from= and ?to=. "to"
+// defaults to the latest revision and "from" to the one before it.
+func DiffTexRevisions(database *db.DB, resolve texTargetResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		from, err := revisionQueryParam(r, "from")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid from revision")
+			return
+		}
+		to, err := revisionQueryParam(r, "to")
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid to revision")
+			return
+		}
+		q := store.New(database.Pool())
+		ref, ok := resolve(ctx, w, r, q, userID)
+		if !ok {
+			return
+		}
+		if to == 0 {
+			revs, err := q.ListTexRevisions(ctx, ref.target)
+			if err != nil {
+				logger.LogErrorContext(ctx, "tex revisions: list for diff", err, "kind", ref.target.Kind)
+				httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+				return
+			}
+			if len(revs) == 0 {
+				httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, "no revisions yet")
+				return
+			}
+			to = revs[0].Revision
+		}
+		if from == 0 {
+			from = to - 1
+		}
+		if from <= 0 {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "nothing to compare the first revision with")
+			return
+		}
+		fromRev, ok := loadTexRevision(ctx, w, r, q, ref.target, from)
+		if !ok {
+			return
+		}
+		toRev, ok := loadTexRevision(ctx, w, r, q, ref.target, to)
+		if !ok {
+			return
+		}
+		diff := mcdomain.UnifiedDiff(
+			fmt.Sprintf("%s@%d", ref.label, from), fmt.Sprintf("%s@%d", ref.label, to),
+			derefString(fromRev.Body), derefString(toRev.Body))
+		httpx.WriteJSON(w, http.StatusOK, texDiffView{From: from, To: to, Diff: diff})
+	}
+}
+
+// RestoreTexRevision makes an old revision current again. History is never
+// rewritten: the restore appends a new revision that copies the old body
+// and records where it came from.
+func RestoreTexRevision(database *db.DB, resolve texTargetResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		revision, err := pathRevision(r)
+		if err != nil {
+			httpx.WriteAPIError(w, r, http.StatusBadRequest, httpx.CodeBadRequest, "invalid revision")
+			return
+		}
+		q := store.New(database.Pool())
+		ref, ok := resolve(ctx, w, r, q, userID)
+		if !ok {
+			return
+		}
+		old, ok := loadTexRevision(ctx, w, r, q, ref.target, revision)
+		if !ok {
+			return
+		}
+		if old.Body == nil && ref.target.Kind != store.TexTargetSeries {
+			httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "this source cannot be cleared")
+			return
+		}
+
+		tx, err := database.Pool().Begin(ctx)
+		if err != nil {
+			logger.LogErrorContext(ctx, "tex revisions: begin restore tx", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		qx := store.New(tx)
+
+		published, err := ref.apply(ctx, qx, old.Body)
+		if err != nil {
+			logger.LogErrorContext(ctx, "tex revisions: apply restore", err, "kind", ref.target.Kind)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to restore revision")
+			return
+		}
+		restoredFrom := old.Revision
+		created, err := qx.RecordTexRevision(ctx, store.RecordTexRevisionParams{
+			Target:       ref.target,
+			Body:         old.Body,
+			AuthorUserID: userID,
+			RestoredFrom: &restoredFrom,
+			Published:    published,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "this revision is already current")
+				return
+			}
+			if isUniqueViolation(err) {
+				httpx.WriteAPIError(w, r, http.StatusConflict, httpx.CodeConflict, "the source was saved concurrently; reload and retry")
+				return
+			}
+			logger.LogErrorContext(ctx, "tex revisions: record restore", err, "kind", ref.target.Kind)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "failed to restore revision")
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			logger.LogErrorContext(ctx, "tex revisions: commit restore", err)
+			httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+			return
+		}
+		if ref.event != nil {
+			live.Publish(ctx, database.Pool(), *ref.event)
+		}
+		httpx.WriteJSON(w, http.StatusCreated, toTexRevisionView(created, true))
+	}
+}
+
+func loadTexRevision(ctx context.Context, w http.ResponseWriter, r *http.Request, q *store.Queries, target store.TexTarget, revision int32) (store.TexRevision, bool) {
+	rev, err := q.GetTexRevision(ctx, target, revision)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.WriteAPIError(w, r, http.StatusNotFound, httpx.CodeNotFound, fmt.Sprintf("revision %d not found", revision))
+			return store.TexRevision{}, false
+		}
+		logger.LogErrorContext(ctx, "tex revisions: get", err, "kind", target.Kind)
+		httpx.WriteAPIError(w, r, http.StatusInternalServerError, httpx.CodeInternal, "internal error")
+		return store.TexRevision{}, false
+	}
+	return rev, true
+}
+
+func pathRevision(r *http.Request) (int32, error) {
+	n, err := strconv.ParseInt(chi.URLParam(r, "revision"), 10, 32)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid revision")
+	}
+	return int32(n), nil
+}
+
+// revisionQueryParam parses an optional positive revision number; 0 when
+// absent.
+func revisionQueryParam(r *http.Request, name string) (int32, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid revision")
+	}
+	return int32(n), nil
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
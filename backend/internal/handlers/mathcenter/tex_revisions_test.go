@@ -0,0 +1,312 @@
+package mathcenter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var texRevisionColumns = []string{"id", "revision", "body", "author_user_id", "restored_from_revision", "created_at", "published_at"}
+
+// seriesTexArgs are the RecordTexRevision arguments for a series statement
+// save.
+func seriesTexArgs(centerID, seriesID int64, body *string, authorID int64, published bool) []any {
+	return []any{centerID, "series", &seriesID, (*int64)(nil), body, authorID, (*int32)(nil), published}
+}
+
+func solutionTexArgs(centerID, subproblemID int64, body any, authorID int64, published bool) []any {
+	return []any{centerID, "solution", (*int64)(nil), &subproblemID, body, authorID, (*int32)(nil), published}
+}
+
+func expectTexRevisionRecorded(mock pgxmock.PgxPoolIface, args []any, revision int32) {
+	author := args[5].(int64)
+	mock.ExpectQuery(`INSERT INTO math_center_tex_revisions`).
+		WithArgs(args...).
+		WillReturnRows(mock.NewRows(texRevisionColumns).
+			AddRow(int64(revision)+1000, revision, (*string)(nil), &author, (*int32)(nil), time.Now(), (*time.Time)(nil)))
+}
+
+func expectSeriesForTex(mock pgxmock.PgxPoolIface, published bool) {
+	now := time.Now()
+	var pubAt *time.Time
+	if published {
+		pubAt = &now
+	}
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(int64(100)).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now.Add(time.Hour), (*string)(nil), pubAt, now, (*string)(nil)))
+}
+
+func TestListSeriesTexRevisions_ReportsShownRevision(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	author := int64(7)
+	v1, v2 := "one", "two"
+	expectSeriesForTex(mock, true)
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	mock.ExpectQuery(`FROM math_center_tex_revisions\s+WHERE math_center_id = \$1`).
+		WithArgs(int64(42), "series", pgxmock.AnyArg(), (*int64)(nil)).
+		WillReturnRows(mock.NewRows(texRevisionColumns).
+			AddRow(int64(12), int32(3), (*string)(nil), &author, (*int32)(nil), now, (*time.Time)(nil)).
+			AddRow(int64(11), int32(2), &v2, &author, (*int32)(nil), now, &now).
+			AddRow(int64(10), int32(1), &v1, (*int64)(nil), (*int32)(nil), now, (*time.Time)(nil)))
+
+	req := authedRequest(t, access, 7, http.MethodGet, "/series/100/tex/revisions", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Revisions []struct {
+			Revision int32  `json:"revision"`
+			Cleared  bool   `json:"cleared"`
+			Bytes    int    `json:"bytes"`
+			Tex      string `json:"tex"`
+		} `json:"revisions"`
+		ShownRevision *int32 `json:"shown_revision"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Revisions) != 3 || !resp.Revisions[0].Cleared || resp.Revisions[1].Bytes != 3 || resp.Revisions[1].Tex != "" {
+		t.Errorf("unexpected revisions: %+v", resp.Revisions)
+	}
+	if resp.ShownRevision == nil || *resp.ShownRevision != 2 {
+		t.Errorf("shown_revision = %v, want 2", resp.ShownRevision)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestListSeriesTexRevisions_RejectsStudent(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	expectSeriesForTex(mock, true)
+	expectTeacherAccess(mock, 7, 42, "")
+
+	req := authedRequest(t, access, 7, http.MethodGet, "/series/100/tex/revisions", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestListSeriesTexRevisions_RejectsAssistantOfAnotherTerm(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	termID := int64(5)
+	expectSeriesForTex(mock, true)
+	mock.ExpectQuery(`FROM math_center_teachers t\s+LEFT JOIN math_center_terms`).
+		WithArgs(int64(7), int64(42)).
+		WillReturnRows(mock.NewRows(teacherAccessColumns).
+			AddRow(int64(1), false, "grader", &termID, true))
+	mock.ExpectQuery(`FROM math_center_series WHERE id = \$1 AND term_id = \$2`).
+		WithArgs(int64(100), termID).
+		WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(false))
+
+	req := authedRequest(t, access, 7, http.MethodGet, "/series/100/tex/revisions", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("got %d, want 403; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDiffSeriesTex_DefaultsToLatestPair(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	v1, v2 := "a\nb\nc\n", "a\nB\nc\n"
+	expectSeriesForTex(mock, false)
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	mock.ExpectQuery(`FROM math_center_tex_revisions\s+WHERE math_center_id = \$1[\s\S]*ORDER BY revision DESC`).
+		WithArgs(int64(42), "series", pgxmock.AnyArg(), (*int64)(nil)).
+		WillReturnRows(mock.NewRows(texRevisionColumns).
+			AddRow(int64(11), int32(2), &v2, (*int64)(nil), (*int32)(nil), now, (*time.Time)(nil)).
+			AddRow(int64(10), int32(1), &v1, (*int64)(nil), (*int32)(nil), now, (*time.Time)(nil)))
+	for _, rev := range []struct {
+		n    int32
+		body *string
+	}{{1, &v1}, {2, &v2}} {
+		mock.ExpectQuery(`FROM math_center_tex_revisions[\s\S]*AND revision = \$5`).
+			WithArgs(int64(42), "series", pgxmock.AnyArg(), (*int64)(nil), rev.n).
+			WillReturnRows(mock.NewRows(texRevisionColumns).
+				AddRow(int64(9+rev.n), rev.n, rev.body, (*int64)(nil), (*int32)(nil), now, (*time.Time)(nil)))
+	}
+
+	req := authedRequest(t, access, 7, http.MethodGet, "/series/100/tex/diff", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		From int32  `json:"from"`
+		To   int32  `json:"to"`
+		Diff string `json:"diff"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.From != 1 || resp.To != 2 {
+		t.Errorf("compared %d..%d, want 1..2", resp.From, resp.To)
+	}
+	if !strings.Contains(resp.Diff, "--- series-100.tex@1\n+++ series-100.tex@2\n") || !strings.Contains(resp.Diff, "-b\n+B\n") {
+		t.Errorf("unexpected diff:\n%s", resp.Diff)
+	}
+}
+
+func TestDiffSeriesTex_RejectsBadRevision(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	req := authedRequest(t, access, 7, http.MethodGet, "/series/100/tex/diff?from=0", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestRestoreSeriesTex_AppendsCopy(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	old := "\\documentclass{article}\\begin{document}old\\end{document}"
+	seriesID := int64(100)
+	restoredFrom := int32(1)
+	author := int64(7)
+	expectSeriesForTex(mock, true)
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	mock.ExpectQuery(`FROM math_center_tex_revisions[\s\S]*AND revision = \$5`).
+		WithArgs(int64(42), "series", &seriesID, (*int64)(nil), int32(1)).
+		WillReturnRows(mock.NewRows(texRevisionColumns).
+			AddRow(int64(10), int32(1), &old, (*int64)(nil), (*int32)(nil), now, &now))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE math_center_series\s+SET tex_source`).
+		WithArgs(int64(100), &old).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(int64(100), int64(42), int32(1), "S", now, (*string)(nil), &now, now, &old))
+	mock.ExpectQuery(`INSERT INTO math_center_tex_revisions`).
+		WithArgs(int64(42), "series", &seriesID, (*int64)(nil), &old, int64(7), &restoredFrom, true).
+		WillReturnRows(mock.NewRows(texRevisionColumns).
+			AddRow(int64(13), int32(4), &old, &author, &restoredFrom, now, &now))
+	mock.ExpectCommit()
+
+	req := authedRequest(t, access, 7, http.MethodPost, "/series/100/tex/revisions/1/restore", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got %d, want 201; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Revision             int32  `json:"revision"`
+		RestoredFromRevision *int32 `json:"restored_from_revision"`
+		Tex                  string `json:"tex"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Revision != 4 || resp.RestoredFromRevision == nil || *resp.RestoredFromRevision != 1 || resp.Tex != old {
+		t.Errorf("unexpected restore response: %+v", resp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRestoreSubproblemSolutionTex_RejectsCurrentRevision(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	now := time.Now()
+	body := "\\documentclass{article}\\begin{document}Разбор\\end{document}"
+	expectSubproblemCenter(mock, 900, 42, "b", 5, now)
+	mock.ExpectQuery(`FROM math_center_tex_revisions[\s\S]*AND revision = \$5`).
+		WithArgs(int64(42), "solution", (*int64)(nil), pgxmock.AnyArg(), int32(2)).
+		WillReturnRows(mock.NewRows(texRevisionColumns).
+			AddRow(int64(10), int32(2), &body, (*int64)(nil), (*int32)(nil), now, (*time.Time)(nil)))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DO UPDATE SET solution_tex_source = EXCLUDED.solution_tex_source`).
+		WithArgs(int64(900), &body).
+		WillReturnRows(mock.NewRows(subproblemSolutionColumns).
+			AddRow(int64(9), int64(900), false, (*time.Time)(nil), &body, (*string)(nil), (*string)(nil), now, now, (*int64)(nil), (*time.Time)(nil)))
+	restoredFrom := int32(2)
+	subproblemID := int64(900)
+	mock.ExpectQuery(`INSERT INTO math_center_tex_revisions`).
+		WithArgs(int64(42), "solution", (*int64)(nil), &subproblemID, &body, int64(1), &restoredFrom, false).
+		WillReturnRows(mock.NewRows(texRevisionColumns))
+	mock.ExpectRollback()
+
+	req := authedAdminRequest(t, access, 1, http.MethodPost, "/subproblems/900/solution/tex/revisions/2/restore", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("got %d, want 409; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateLatexPreamble_RecordsPublishedRevision(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	r, access, _ := newRouter(t, mock)
+
+	preamble := "\\documentclass{article}\n\\usepackage{amsmath}"
+	expectTeacherAccess(mock, 7, 42, "teacher")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO math_center_latex_settings`).
+		WithArgs(int64(42), preamble).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectTexRevisionRecorded(mock,
+		[]any{int64(42), "preamble", (*int64)(nil), (*int64)(nil), &preamble, int64(7), (*int32)(nil), true}, 2)
+	mock.ExpectCommit()
+
+	body, _ := json.Marshal(map[string]string{"preamble": preamble})
+	req := authedRequest(t, access, 7, http.MethodPatch, "/centers/42/manage/latex-preamble", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package homework
+
+import "fmt"
+
+// Grader assignment modes persisted in homework_series_grading.mode.
+const (
+	// AssignmentOff is the default: every teacher sees every pending thread
+	// and graders race to claim.
+	AssignmentOff = "off"
+	// AssignmentRoundRobin hands each submission to the least-loaded grader
+	// of the series pool.
+	AssignmentRoundRobin = "round_robin"
+	// AssignmentByProblem hands each submission to an owner of its problem,
+	// falling back to the pool for problems without owners.
+	AssignmentByProblem = "by_problem"
+)
+
+// MaxSeriesGraders bounds the pool and the per-series owner list; a center
+// has a dozen teachers, not hundreds.
+const MaxSeriesGraders = 100
+
+// ValidateAssignmentMode rejects unknown modes before they hit the CHECK
+// constraint.
+func ValidateAssignmentMode(mode string) error {
+	switch mode {
+	case AssignmentOff, AssignmentRoundRobin, AssignmentByProblem:
+		return nil
+	}
+	return fmt.Errorf("mode must be one of %q, %q, %q", AssignmentOff, AssignmentRoundRobin, AssignmentByProblem)
+}
@@ -0,0 +1,16 @@
+package homework
+
+import "fmt"
+
+// MaxCalibrationPercent is the upper bound of a center's sampling rate;
+// 100 means every graded verdict is graded twice.
+const MaxCalibrationPercent = 100
+
+// ValidateCalibrationPercent rejects rates outside 0–100 before they hit the
+// CHECK constraint.
+func ValidateCalibrationPercent(percent int) error {
+	if percent < 0 || percent > MaxCalibrationPercent {
+		return fmt.Errorf("percent must be between 0 and %d", MaxCalibrationPercent)
+	}
+	return nil
+}
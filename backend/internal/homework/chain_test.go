@@ -0,0 +1,95 @@
+package homework_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+)
+
+// sealedChain builds a well-formed three-event log: submit, accept, retract.
+func sealedChain(t *testing.T) ([]homework.ChainLink, []byte) {
+	t.Helper()
+	at := time.Date(2026, 3, 1, 10, 0, 0, 123456000, time.UTC)
+	accepted := homework.VerdictAccepted
+	submitID := int64(10)
+	gradeID := int64(11)
+	events := []homework.ChainEvent{
+		{ID: submitID, ThreadID: 1, EventUUID: "a", Kind: homework.KindSubmitted, ActorUserID: 7, Body: "solution", CreatedAt: at},
+		{ID: gradeID, ThreadID: 1, EventUUID: "b", Kind: homework.KindGraded, ActorUserID: 3, Body: "ok",
+			Verdict: &accepted, RefersToEventID: &submitID, CreatedAt: at.Add(time.Hour)},
+		{ID: 12, ThreadID: 1, EventUUID: "c", Kind: homework.KindRetracted, ActorUserID: 3,
+			RefersToEventID: &gradeID, CreatedAt: at.Add(2 * time.Hour)},
+	}
+	var links []homework.ChainLink
+	prev := []byte{}
+	for _, e := range events {
+		h := homework.EventHash(prev, e)
+		links = append(links, homework.ChainLink{Event: e, PrevHash: prev, Hash: h})
+		prev = h
+	}
+	return links, prev
+}
+
+func TestVerifyChain_Intact(t *testing.T) {
+	t.Parallel()
+	links, head := sealedChain(t)
+	sealed, breaks := homework.VerifyChain(links, head)
+	if sealed != 3 || len(breaks) != 0 {
+		t.Fatalf("sealed=%d breaks=%v; want 3, none", sealed, breaks)
+	}
+}
+
+func TestVerifyChain_DetectsTampering(t *testing.T) {
+	t.Parallel()
+
+	t.Run("rewritten verdict", func(t *testing.T) {
+		links, head := sealedChain(t)
+		rejected := homework.VerdictRejected
+		links[1].Event.Verdict = &rejected
+		_, breaks := homework.VerifyChain(links, head)
+		if len(breaks) != 1 || breaks[0].EventID != 11 {
+			t.Errorf("breaks = %v; want one on event 11", breaks)
+		}
+	})
+	t.Run("deleted middle event", func(t *testing.T) {
+		links, head := sealedChain(t)
+		links = append(links[:1], links[2:]...)
+		_, breaks := homework.VerifyChain(links, head)
+		if len(breaks) != 1 || breaks[0].EventID != 12 {
+			t.Errorf("breaks = %v; want one on event 12", breaks)
+		}
+	})
+	t.Run("deleted tail", func(t *testing.T) {
+		links, head := sealedChain(t)
+		_, breaks := homework.VerifyChain(links[:2], head)
+		if len(breaks) != 1 {
+			t.Errorf("breaks = %v; want a head mismatch", breaks)
+		}
+	})
+	t.Run("timestamp moved", func(t *testing.T) {
+		links, head := sealedChain(t)
+		links[0].Event.CreatedAt = links[0].Event.CreatedAt.Add(-time.Microsecond)
+		_, breaks := homework.VerifyChain(links, head)
+		if len(breaks) != 1 || breaks[0].EventID != 10 {
+			t.Errorf("breaks = %v; want one on event 10", breaks)
+		}
+	})
+}
+
+func TestVerifyChain_LegacyPrefix(t *testing.T) {
+	t.Parallel()
+	links, head := sealedChain(t)
+	legacy := homework.ChainLink{Event: homework.ChainEvent{ID: 5, ThreadID: 1, Kind: homework.KindSubmitted}}
+
+	sealed, breaks := homework.VerifyChain(append([]homework.ChainLink{legacy}, links...), head)
+	if sealed != 3 || len(breaks) != 0 {
+		t.Errorf("legacy prefix: sealed=%d breaks=%v; want 3, none", sealed, breaks)
+	}
+
+	legacy.Event.ID = 13
+	_, breaks = homework.VerifyChain(append(links, legacy), head)
+	if len(breaks) == 0 {
+		t.Error("unsealed event after the chain started was not reported")
+	}
+}
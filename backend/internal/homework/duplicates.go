@@ -0,0 +1,43 @@
+package homework
+
+import (
+	"crypto/sha256"
+	"math/bits"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MinDuplicateBodyRunes is the shortest normalized attempt text compared
+	// across students. Shorter bodies ("см. фото", "ответ 5") match by
+	// accident far more often than by copying.
+	MinDuplicateBodyRunes = 40
+	// MaxPhotoHashDistance is the largest Hamming distance between two
+	// 64-bit photo hashes still flagged as the same picture. It tolerates
+	// re-compression and small crops, not a different page.
+	MaxPhotoHashDistance = 8
+	// minPhotoHashBits and maxPhotoHashBits bound the set bits of a hash
+	// worth comparing. Blank or evenly lit pages hash to (almost) all zeros
+	// or all ones and would match each other.
+	minPhotoHashBits = 8
+	maxPhotoHashBits = 56
+)
+
+// BodyDigest returns the digest used to match attempt texts: case and
+// whitespace are normalized away. ok is false when the text is too short to
+// be compared.
+func BodyDigest(body string) (digest []byte, ok bool) {
+	normalized := strings.Join(strings.Fields(strings.ToLower(body)), " ")
+	if utf8.RuneCountInString(normalized) < MinDuplicateBodyRunes {
+		return nil, false
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return sum[:], true
+}
+
+// PhotoHashInformative reports whether a photo hash carries enough structure
+// to be compared with others.
+func PhotoHashInformative(hash uint64) bool {
+	n := bits.OnesCount64(hash)
+	return n >= minPhotoHashBits && n <= maxPhotoHashBits
+}
@@ -1,6 +1,7 @@
 package homework_test
 
 import (
+	"bytes"
 	"strings"
 	"testing"
 
@@ -119,6 +120,9 @@ func TestCanTransition(t *testing.T) {
 		{homework.StatusRejected, homework.KindRetracted, true},
 		{homework.StatusAppealed, homework.KindGraded, true},
 		{homework.StatusAccepted, homework.KindRetracted, true},
+		{homework.StatusUngraded, homework.KindMessage, true},
+		{homework.StatusSubmitted, homework.KindMessage, true},
+		{homework.StatusAccepted, homework.KindMessage, true},
 		// Illegal
 		{homework.StatusUngraded, homework.KindGraded, false},
 		{homework.StatusSubmitted, homework.KindAppealed, false},
@@ -134,3 +138,157 @@ func TestCanTransition(t *testing.T) {
 		}
 	}
 }
+
+func TestRenditionObjectKey(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		key, rendition, want string
+	}{
+		{"homework/thread/1/abc/0.jpg", homework.RenditionThumb, "homework/thread/1/abc/0.thumb.jpg"},
+		{"homework/thread/1/abc/3.heic", homework.RenditionFull, "homework/thread/1/abc/3.full.jpg"},
+		{"homework/thread/1/a.b/7", homework.RenditionPreview, "homework/thread/1/a.b/7.preview.jpg"},
+	}
+	for _, c := range cases {
+		if got := homework.RenditionObjectKey(c.key, c.rendition); got != c.want {
+			t.Errorf("RenditionObjectKey(%q, %q) = %q; want %q", c.key, c.rendition, got, c.want)
+		}
+	}
+}
+
+func TestParseObjectKey(t *testing.T) {
+	t.Parallel()
+	key := homework.ObjectKey(42, "abc123", 2, "jpg")
+	id, uuid, ok := homework.ParseObjectKey(key)
+	if !ok || id != 42 || uuid != "abc123" {
+		t.Errorf("ParseObjectKey(%q) = (%d, %q, %v); want (42, abc123, true)", key, id, uuid, ok)
+	}
+	rendition := homework.RenditionObjectKey(key, homework.RenditionThumb)
+	if id, uuid, ok := homework.ParseObjectKey(rendition); !ok || id != 42 || uuid != "abc123" {
+		t.Errorf("ParseObjectKey(%q) = (%d, %q, %v); want (42, abc123, true)", rendition, id, uuid, ok)
+	}
+	for _, bad := range []string{
+		"mathcenter/series/1/x.pdf",
+		"homework/thread/x/abc/0.jpg",
+		"homework/thread/0/abc/0.jpg",
+		"homework/thread/1/abc/",
+		"homework/thread/1//0.jpg",
+		"homework/thread/1",
+	} {
+		if _, _, ok := homework.ParseObjectKey(bad); ok {
+			t.Errorf("ParseObjectKey(%q) ok; want rejected", bad)
+		}
+	}
+}
+
+func TestValidateSLAHours(t *testing.T) {
+	t.Parallel()
+	for _, ok := range []int{1, homework.DefaultSLAHours, homework.MaxSLAHours} {
+		if err := homework.ValidateSLAHours(ok); err != nil {
+			t.Errorf("ValidateSLAHours(%d) = %v; want nil", ok, err)
+		}
+	}
+	for _, bad := range []int{0, -1, homework.MaxSLAHours + 1} {
+		if err := homework.ValidateSLAHours(bad); err == nil {
+			t.Errorf("ValidateSLAHours(%d) = nil; want error", bad)
+		}
+	}
+}
+
+func TestBodyDigest(t *testing.T) {
+	t.Parallel()
+	long := "Пусть n чётно, тогда n = 2k и n² = 4k², что делится на 4."
+	a, ok := homework.BodyDigest(long)
+	if !ok {
+		t.Fatal("long body should be compared")
+	}
+	b, ok := homework.BodyDigest("  ПУСТЬ n чётно,   тогда n = 2k\nи n² = 4k², что делится на 4.  ")
+	if !ok || !bytes.Equal(a, b) {
+		t.Error("case and whitespace should not change the digest")
+	}
+	c, _ := homework.BodyDigest(long + " Ч.т.д.")
+	if bytes.Equal(a, c) {
+		t.Error("different texts share a digest")
+	}
+	if _, ok := homework.BodyDigest("см. фото"); ok {
+		t.Error("short body should not be compared")
+	}
+}
+
+func TestPhotoHashInformative(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		hash uint64
+		want bool
+	}{
+		{0, false},
+		{^uint64(0), false},
+		{0xFF, true},
+		{0x0F0F0F0F0F0F0F0F, true},
+		{0x7F, false},
+	} {
+		if got := homework.PhotoHashInformative(tc.hash); got != tc.want {
+			t.Errorf("PhotoHashInformative(%#x) = %v, want %v", tc.hash, got, tc.want)
+		}
+	}
+}
+
+func TestValidateReasonCodes(t *testing.T) {
+	t.Parallel()
+	got, err := homework.ValidateReasonCodes([]homework.ReasonCodeSpec{
+		{Code: "illegible_photo", Label: " Нечитаемое фото ", AppliesTo: homework.ReasonForRejection},
+		{Code: "misread", Label: "Не так понял", AppliesTo: homework.ReasonForRetraction},
+	})
+	if err != nil {
+		t.Fatalf("valid set rejected: %v", err)
+	}
+	if got[0].Label != "Нечитаемое фото" || got[0].CountsAsAttempt {
+		t.Errorf("first = %+v", got[0])
+	}
+	if !got[1].CountsAsAttempt {
+		t.Error("retraction-only code must count as an attempt")
+	}
+
+	for name, specs := range map[string][]homework.ReasonCodeSpec{
+		"bad code":   {{Code: "Wrong Answer", Label: "x", AppliesTo: homework.ReasonForBoth}},
+		"duplicate":  {{Code: "a", Label: "x", AppliesTo: homework.ReasonForBoth}, {Code: "a", Label: "y", AppliesTo: homework.ReasonForBoth}},
+		"no label":   {{Code: "a", Label: "  ", AppliesTo: homework.ReasonForBoth}},
+		"applies to": {{Code: "a", Label: "x", AppliesTo: "grade"}},
+	} {
+		if _, err := homework.ValidateReasonCodes(specs); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestReasonApplies(t *testing.T) {
+	t.Parallel()
+	if !homework.ReasonApplies(homework.ReasonForBoth, homework.KindRetracted) ||
+		!homework.ReasonApplies(homework.ReasonForRejection, homework.KindGraded) {
+		t.Error("matching kinds refused")
+	}
+	if homework.ReasonApplies(homework.ReasonForRejection, homework.KindRetracted) ||
+		homework.ReasonApplies(homework.ReasonForRetraction, homework.KindGraded) ||
+		homework.ReasonApplies(homework.ReasonForBoth, homework.KindSubmitted) {
+		t.Error("mismatched kinds allowed")
+	}
+}
+
+func TestValidateStudentQuotaBytes(t *testing.T) {
+	t.Parallel()
+	for _, ok := range []int64{homework.MinStudentQuotaBytes, homework.DefaultStudentQuotaBytes, homework.MaxStudentQuotaBytes} {
+		if err := homework.ValidateStudentQuotaBytes(ok); err != nil {
+			t.Errorf("ValidateStudentQuotaBytes(%d) = %v; want nil", ok, err)
+		}
+	}
+	for _, bad := range []int64{0, homework.MinStudentQuotaBytes - 1, homework.MaxStudentQuotaBytes + 1} {
+		if err := homework.ValidateStudentQuotaBytes(bad); err == nil {
+			t.Errorf("ValidateStudentQuotaBytes(%d) = nil; want error", bad)
+		}
+	}
+	if homework.QuotaExceeded(99, 100) || !homework.QuotaExceeded(100, 100) {
+		t.Error("QuotaExceeded boundary wrong")
+	}
+	if homework.UploadExceedsQuota(90, 10, 100) || !homework.UploadExceedsQuota(90, 11, 100) {
+		t.Error("UploadExceedsQuota boundary wrong")
+	}
+}
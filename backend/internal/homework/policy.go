@@ -7,7 +7,9 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Photo and body limits enforced by every event-creating endpoint. Mirrored
@@ -19,6 +21,10 @@ const (
 	MaxBodyChars      = 4000
 )
 
+// DraftTTL is how long an untouched submission draft is kept. Every save
+// restarts it.
+const DraftTTL = 7 * 24 * time.Hour
+
 // allowedContentTypes maps each accepted image MIME type to the file
 // extension we store in the object key. Lowercase for case-insensitive
 // matching.
@@ -83,7 +89,50 @@ func ObjectKeyPrefix(threadID int64, eventUUID string) string {
 	return fmt.Sprintf("homework/thread/%d/%s/", threadID, eventUUID)
 }
 
+// ObjectKeyRoot is the bucket prefix under which every homework photo lives.
+// Maintenance sweeps list it to find uploads no event ever claimed.
+const ObjectKeyRoot = "homework/thread/"
+
+// ParseObjectKey is the inverse of ObjectKeyPrefix: it extracts the thread id
+// and event UUID from any key under ObjectKeyRoot (uploads and renditions
+// alike). ok is false for keys that don't follow the layout.
+func ParseObjectKey(key string) (threadID int64, eventUUID string, ok bool) {
+	rest, found := strings.CutPrefix(key, ObjectKeyRoot)
+	if !found {
+		return 0, "", false
+	}
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return 0, "", false
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", false
+	}
+	return id, parts[1], true
+}
+
 // ObjectKey is the full key for a single photo in the event's batch.
 func ObjectKey(threadID int64, eventUUID string, idx int, ext string) string {
 	return fmt.Sprintf("%s%d.%s", ObjectKeyPrefix(threadID, eventUUID), idx, ext)
 }
+
+// Renditions produced by the background photo pipeline. "full" replaces the
+// upload itself (re-encoded, metadata stripped, long edge capped); "preview"
+// and "thumb" are the smaller copies served in timelines and lists.
+const (
+	RenditionFull    = "full"
+	RenditionPreview = "preview"
+	RenditionThumb   = "thumb"
+)
+
+// RenditionObjectKey derives a rendition's key from the uploaded photo's key:
+// ".../0.heic" → ".../0.thumb.jpg". Renditions are always JPEG and stay under
+// the event prefix, so anything that cleans up by prefix covers them too.
+func RenditionObjectKey(objectKey, rendition string) string {
+	base := objectKey
+	if i := strings.LastIndexByte(base, '.'); i > strings.LastIndexByte(base, '/') {
+		base = base[:i]
+	}
+	return base + "." + rendition + ".jpg"
+}
@@ -0,0 +1,76 @@
+package homework
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Which events a reason code may be given on.
+const (
+	ReasonForRejection  = "rejection"
+	ReasonForRetraction = "retraction"
+	ReasonForBoth       = "both"
+)
+
+// MaxReasonCodes caps a center's taxonomy; it is a pick-list, not a catalogue.
+const MaxReasonCodes = 30
+
+// maxReasonLabelChars mirrors the CHECK on homework_reason_code.label.
+const maxReasonLabelChars = 100
+
+var reasonCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// ReasonCodeSpec is one entry of a center's taxonomy as a head teacher sets it.
+type ReasonCodeSpec struct {
+	Code            string
+	Label           string
+	AppliesTo       string
+	CountsAsAttempt bool
+}
+
+// ValidateReasonCodes checks a whole taxonomy before it replaces the
+// center's: well-formed unique codes, non-empty labels, a known applies_to.
+// Labels come back trimmed. Counting only makes sense on rejections, so a
+// retraction-only code always counts.
+func ValidateReasonCodes(specs []ReasonCodeSpec) ([]ReasonCodeSpec, error) {
+	if len(specs) > MaxReasonCodes {
+		return nil, fmt.Errorf("at most %d reason codes", MaxReasonCodes)
+	}
+	seen := make(map[string]bool, len(specs))
+	out := make([]ReasonCodeSpec, 0, len(specs))
+	for _, s := range specs {
+		if !reasonCodePattern.MatchString(s.Code) {
+			return nil, fmt.Errorf("reason code %q must be lowercase latin letters, digits or '_' (at most 32)", s.Code)
+		}
+		if seen[s.Code] {
+			return nil, fmt.Errorf("reason code %q listed twice", s.Code)
+		}
+		seen[s.Code] = true
+		s.Label = strings.TrimSpace(s.Label)
+		if s.Label == "" || len([]rune(s.Label)) > maxReasonLabelChars {
+			return nil, fmt.Errorf("reason code %q needs a label of 1 to %d characters", s.Code, maxReasonLabelChars)
+		}
+		switch s.AppliesTo {
+		case ReasonForRejection, ReasonForBoth:
+		case ReasonForRetraction:
+			s.CountsAsAttempt = true
+		default:
+			return nil, fmt.Errorf("reason code %q: applies_to must be 'rejection', 'retraction' or 'both'", s.Code)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// ReasonApplies reports whether a code configured with appliesTo may be given
+// on an event of kind (KindGraded for a rejection, KindRetracted).
+func ReasonApplies(appliesTo, kind string) bool {
+	switch kind {
+	case KindGraded:
+		return appliesTo == ReasonForRejection || appliesTo == ReasonForBoth
+	case KindRetracted:
+		return appliesTo == ReasonForRetraction || appliesTo == ReasonForBoth
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package homework
+
+import "fmt"
+
+// ReplayEvent is the part of a homework_thread_event row that drives the
+// thread cache.
+type ReplayEvent struct {
+	ID                   int64
+	Kind                 string
+	ActorUserID          int64
+	Verdict              *string
+	CreditedGraderUserID *int64
+	CreditedGraderName   string
+}
+
+// ThreadCache is the denormalized state homework_thread carries next to its
+// event log. Claims are not part of it: they are leases, not history.
+type ThreadCache struct {
+	Status           string
+	AttemptEventID   *int64
+	GradeEventID     *int64
+	LastGraderUserID *int64
+	LastGraderName   string
+}
+
+// ReplayIssue is an event the transition rules would have refused.
+type ReplayIssue struct {
+	EventID int64
+	Message string
+}
+
+// Replay folds an event log (ascending id) into the cache the handlers would
+// have written, mirroring each UpdateThreadAfter* statement. Illegal steps
+// are reported and then applied anyway, so one bad event does not hide the
+// state the rest of the log leads to.
+func Replay(events []ReplayEvent) (ThreadCache, []ReplayIssue) {
+	c := ThreadCache{Status: StatusUngraded}
+	var issues []ReplayIssue
+	// kindByID resolves the current attempt's kind for retract rollbacks,
+	// the way GetEventKind does in the handlers.
+	kindByID := make(map[int64]string, len(events))
+	for _, e := range events {
+		kindByID[e.ID] = e.Kind
+		if err := CanTransition(c.Status, e.Kind); err != nil {
+			issues = append(issues, ReplayIssue{EventID: e.ID, Message: err.Error()})
+		}
+		id := e.ID
+		switch e.Kind {
+		case KindSubmitted:
+			c.Status, c.AttemptEventID = StatusSubmitted, &id
+		case KindAppealed:
+			c.Status, c.AttemptEventID = StatusAppealed, &id
+		case KindGraded:
+			switch {
+			case e.Verdict == nil:
+				issues = append(issues, ReplayIssue{EventID: e.ID, Message: "graded event without a verdict"})
+			case *e.Verdict == VerdictAccepted:
+				c.Status = StatusAccepted
+			default:
+				c.Status = StatusRejected
+			}
+			actor := e.ActorUserID
+			c.GradeEventID, c.LastGraderUserID = &id, &actor
+		case KindRetracted:
+			c.Status = StatusSubmitted
+			if c.AttemptEventID != nil && kindByID[*c.AttemptEventID] == KindAppealed {
+				c.Status = StatusAppealed
+			}
+			c.GradeEventID = nil
+		case KindAcceptedOffline:
+			c.Status = StatusAccepted
+			c.GradeEventID = &id
+			c.LastGraderUserID = e.CreditedGraderUserID
+			c.LastGraderName = e.CreditedGraderName
+		case KindOfflineRetracted:
+			switch {
+			case c.AttemptEventID == nil:
+				c.Status = StatusUngraded
+			case kindByID[*c.AttemptEventID] == KindAppealed:
+				c.Status = StatusAppealed
+			default:
+				c.Status = StatusSubmitted
+			}
+			c.GradeEventID = nil
+			c.LastGraderName = ""
+		case KindClaimed, KindReleased, KindMessage:
+			// Status-neutral.
+		default:
+			issues = append(issues, ReplayIssue{EventID: e.ID, Message: fmt.Sprintf("unknown event kind %q", e.Kind)})
+		}
+	}
+	return c, issues
+}
+
+// Diff lists the fields where got disagrees with want, as "field: got → want"
+// lines; nil when they match.
+func (want ThreadCache) Diff(got ThreadCache) []string {
+	var out []string
+	if got.Status != want.Status {
+		out = append(out, fmt.Sprintf("current_status: %s → %s", got.Status, want.Status))
+	}
+	if !sameID(got.AttemptEventID, want.AttemptEventID) {
+		out = append(out, fmt.Sprintf("current_attempt_event_id: %s → %s", fmtID(got.AttemptEventID), fmtID(want.AttemptEventID)))
+	}
+	if !sameID(got.GradeEventID, want.GradeEventID) {
+		out = append(out, fmt.Sprintf("current_grade_event_id: %s → %s", fmtID(got.GradeEventID), fmtID(want.GradeEventID)))
+	}
+	if !sameID(got.LastGraderUserID, want.LastGraderUserID) {
+		out = append(out, fmt.Sprintf("last_grader_user_id: %s → %s", fmtID(got.LastGraderUserID), fmtID(want.LastGraderUserID)))
+	}
+	if got.LastGraderName != want.LastGraderName {
+		out = append(out, fmt.Sprintf("last_grader_name: %q → %q", got.LastGraderName, want.LastGraderName))
+	}
+	return out
+}
+
+func sameID(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func fmtID(id *int64) string {
+	if id == nil {
+		return "NULL"
+	}
+	return fmt.Sprint(*id)
+}
@@ -0,0 +1,78 @@
+package homework_test
+
+import (
+	"testing"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+)
+
+func ev(id int64, kind string, actor int64, verdict string) homework.ReplayEvent {
+	e := homework.ReplayEvent{ID: id, Kind: kind, ActorUserID: actor}
+	if verdict != "" {
+		e.Verdict = &verdict
+	}
+	return e
+}
+
+func TestReplay_AppealAfterRejectionThenRetract(t *testing.T) {
+	t.Parallel()
+	got, issues := homework.Replay([]homework.ReplayEvent{
+		ev(1, homework.KindSubmitted, 7, ""),
+		ev(2, homework.KindGraded, 3, homework.VerdictRejected),
+		ev(3, homework.KindMessage, 7, ""),
+		ev(4, homework.KindAppealed, 7, ""),
+		ev(5, homework.KindGraded, 3, homework.VerdictAccepted),
+		ev(6, homework.KindRetracted, 3, ""),
+	})
+	if len(issues) != 0 {
+		t.Fatalf("issues = %+v; want none", issues)
+	}
+	if got.Status != homework.StatusAppealed || *got.AttemptEventID != 4 || got.GradeEventID != nil || *got.LastGraderUserID != 3 {
+		t.Errorf("cache = %+v; want appealed, attempt 4, no grade, last grader 3", got)
+	}
+}
+
+func TestReplay_OfflineAcceptAndUndo(t *testing.T) {
+	t.Parallel()
+	credited := int64(9)
+	accept := homework.ReplayEvent{ID: 1, Kind: homework.KindAcceptedOffline, ActorUserID: 3,
+		CreditedGraderUserID: &credited, CreditedGraderName: "Мария Кузнецова"}
+
+	got, _ := homework.Replay([]homework.ReplayEvent{accept})
+	if got.Status != homework.StatusAccepted || *got.GradeEventID != 1 || *got.LastGraderUserID != 9 || got.LastGraderName != "Мария Кузнецова" {
+		t.Errorf("after accept: %+v", got)
+	}
+	got, issues := homework.Replay([]homework.ReplayEvent{accept, ev(2, homework.KindOfflineRetracted, 3, "")})
+	if len(issues) != 0 {
+		t.Fatalf("issues = %+v; want none", issues)
+	}
+	if got.Status != homework.StatusUngraded || got.GradeEventID != nil || got.LastGraderName != "" {
+		t.Errorf("after undo: %+v; want ungraded with the grade cleared", got)
+	}
+}
+
+func TestReplay_ReportsIllegalSequence(t *testing.T) {
+	t.Parallel()
+	_, issues := homework.Replay([]homework.ReplayEvent{
+		ev(1, homework.KindSubmitted, 7, ""),
+		ev(2, homework.KindGraded, 3, homework.VerdictAccepted),
+		ev(3, homework.KindSubmitted, 7, ""),
+		ev(4, homework.KindGraded, 3, ""),
+	})
+	if len(issues) != 2 || issues[0].EventID != 3 || issues[1].EventID != 4 {
+		t.Fatalf("issues = %+v; want resubmit-after-accept (3) and verdict-less grade (4)", issues)
+	}
+}
+
+func TestThreadCacheDiff(t *testing.T) {
+	t.Parallel()
+	a, b := int64(1), int64(2)
+	want := homework.ThreadCache{Status: homework.StatusSubmitted, AttemptEventID: &a}
+	if d := want.Diff(want); d != nil {
+		t.Errorf("Diff(self) = %v; want nil", d)
+	}
+	got := homework.ThreadCache{Status: homework.StatusAccepted, AttemptEventID: &b, GradeEventID: &b}
+	if d := want.Diff(got); len(d) != 3 {
+		t.Errorf("Diff = %v; want status, attempt and grade", d)
+	}
+}
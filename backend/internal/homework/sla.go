@@ -0,0 +1,20 @@
+package homework
+
+import "fmt"
+
+const (
+	// DefaultSLAHours applies to centers that never set their own: a
+	// submission should be graded within three days.
+	DefaultSLAHours = 72
+	// MaxSLAHours is thirty days; anything longer is not a deadline.
+	MaxSLAHours = 720
+)
+
+// ValidateSLAHours rejects values outside 1–MaxSLAHours before they hit the
+// CHECK constraint.
+func ValidateSLAHours(hours int) error {
+	if hours < 1 || hours > MaxSLAHours {
+		return fmt.Errorf("hours must be between 1 and %d", MaxSLAHours)
+	}
+	return nil
+}
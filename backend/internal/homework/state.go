@@ -27,6 +27,9 @@ const (
 	KindAcceptedOffline = "accepted_offline"
 	// KindOfflineRetracted undoes a prior offline accept.
 	KindOfflineRetracted = "offline_retracted"
+	// KindMessage is a clarification exchanged between the student and the
+	// graders. It never changes the thread status and is not an attempt.
+	KindMessage = "message"
 )
 
 // Verdict values stored on graded events.
@@ -51,6 +54,10 @@ func CanTransition(currentStatus, kind string) error {
 		StatusAppealed:  {KindClaimed: true, KindReleased: true, KindGraded: true, KindAcceptedOffline: true},
 		StatusAccepted:  {KindRetracted: true, KindOfflineRetracted: true},
 	}
+	// Messages are status-neutral: legal whatever the thread is doing.
+	if kind == KindMessage {
+		return nil
+	}
 	// Retract is also legal from rejected (a grader can change their mind
 	// either way). Add it here rather than duplicate the map entry above.
 	if currentStatus == StatusRejected && kind == KindRetracted {
@@ -0,0 +1,39 @@
+package homework
+
+import "fmt"
+
+const (
+	// DefaultStudentQuotaBytes applies to centers that never set their own:
+	// 2 GiB of photos per student, a few hundred ordinary attempts.
+	DefaultStudentQuotaBytes int64 = 2 << 30
+	// MinStudentQuotaBytes is one full event; a smaller quota would block a
+	// student before their first submission.
+	MinStudentQuotaBytes int64 = MaxPhotosPerEvent * MaxPhotoBytes
+	// MaxStudentQuotaBytes is 100 GiB; beyond that there is no quota.
+	MaxStudentQuotaBytes int64 = 100 << 30
+)
+
+// ValidateStudentQuotaBytes rejects values outside the CHECK on
+// homework_storage_quota.bytes_per_student.
+func ValidateStudentQuotaBytes(n int64) error {
+	if n < MinStudentQuotaBytes || n > MaxStudentQuotaBytes {
+		return fmt.Errorf("bytes_per_student must be between %d and %d", MinStudentQuotaBytes, MaxStudentQuotaBytes)
+	}
+	return nil
+}
+
+// QuotaExceeded reports whether a student who already stores used bytes may
+// not start another upload. The check runs before the photos exist, so it
+// cannot see how much will land; UploadExceedsQuota settles that when the
+// photos are attached.
+func QuotaExceeded(used, quota int64) bool {
+	return used >= quota
+}
+
+// UploadExceedsQuota reports whether attaching incoming bytes of landed
+// photos would take a student who already stores used bytes past quota.
+// Upload URLs are minted per batch with no reservation, so this is the check
+// that actually holds the line.
+func UploadExceedsQuota(used, incoming, quota int64) bool {
+	return used+incoming > quota
+}
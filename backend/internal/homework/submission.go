@@ -14,3 +14,16 @@ func SubmissionClosed(isCoffin bool, coffinReleasedAt *time.Time, seriesDueAt, n
 	}
 	return !now.Before(seriesDueAt)
 }
+
+// SolutionReleased reports whether the subproblem's solution is out — its
+// разбор published or, for a coffin, its release time reached. Once it is,
+// no resubmission is accepted after the deadline, whatever the last verdict
+// was: the student could copy the published write-up.
+func SolutionReleased(solutionPublishedAt, coffinReleasedAt *time.Time, now time.Time) bool {
+	for _, at := range []*time.Time{solutionPublishedAt, coffinReleasedAt} {
+		if at != nil && !now.Before(*at) {
+			return true
+		}
+	}
+	return false
+}
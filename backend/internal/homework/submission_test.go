@@ -32,3 +32,29 @@ func TestSubmissionClosed(t *testing.T) {
 		})
 	}
 }
+
+func TestSolutionReleased(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	cases := []struct {
+		name      string
+		published *time.Time
+		released  *time.Time
+		want      bool
+	}{
+		{"nothing out", nil, nil, false},
+		{"разбор published", &past, nil, true},
+		{"разбор scheduled", &future, nil, false},
+		{"coffin released", nil, &past, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := SolutionReleased(c.published, c.released, now); got != c.want {
+				t.Errorf("SolutionReleased = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+// Package housekeeping runs periodic homework maintenance: it clears grading
+// claims whose soft TTL ran out, deletes expired submission drafts with
+// their photos, garbage-collects photo uploads that were never finalized
+// into an event or a draft (the student closed the tab after the PUT, or the
+// submit request failed), and, when an alert sink is attached, sends a
+// daily digest of threads waiting past their center's grading SLA.
+package housekeeping
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/live"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	"github.com/Alarion239/my239/backend/internal/metrics"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/objectstore"
+)
+
+const (
+	// claimSweepInterval is how often expired claims are cleared. Claims
+	// last 15 minutes, so a minute of extra staleness is invisible.
+	claimSweepInterval = time.Minute
+	// uploadSweepInterval is how often the bucket is listed for orphans.
+	// Listing is the expensive part, and orphans cost only storage.
+	uploadSweepInterval = time.Hour
+	// orphanGrace is how old an unreferenced upload must be before it is
+	// deleted. It has to comfortably exceed the presigned PUT lifetime plus
+	// the time a slow client takes to call finalize; a student who finalizes
+	// a day later gets "photo missing" and re-uploads.
+	orphanGrace = 24 * time.Hour
+	// uuidBatchSize bounds the event_uuid = ANY(...) lookup per round trip.
+	uuidBatchSize = 500
+	// uploadSweepLockKey is the pg advisory lock that makes one replica at a
+	// time list the bucket. Arbitrary, but must not collide with other users
+	// of advisory locks ("hwgc" in ASCII).
+	uploadSweepLockKey int64 = 0x68776763
+	// digestCheckInterval is how often the sweeper looks whether today's
+	// overdue digest is due; digestHourUTC is the hour from which it is
+	// (09:00 in Moscow).
+	digestCheckInterval = time.Hour
+	digestHourUTC       = 6
+)
+
+// Sweeper is the maintenance worker. Safe to run on every replica: claim
+// expiry is a single guarded UPDATE, and the upload sweep takes an advisory
+// lock so concurrent replicas skip the round instead of listing twice.
+type Sweeper struct {
+	pool   db.Pool
+	blobs  objectstore.Store
+	alerts logger.AlertSink
+}
+
+// NewSweeper builds a Sweeper.
+func NewSweeper(pool db.Pool, blobs objectstore.Store) *Sweeper {
+	return &Sweeper{pool: pool, blobs: blobs}
+}
+
+// WithDigest attaches the sink the daily overdue digest goes to (the
+// Telegram alert service). Without one, no digest is sent.
+func (s *Sweeper) WithDigest(alerts logger.AlertSink) *Sweeper {
+	s.alerts = alerts
+	return s
+}
+
+// Start launches the sweeper goroutine. It returns immediately; the worker
+// stops when ctx is cancelled.
+func (s *Sweeper) Start(ctx context.Context) {
+	go func() {
+		if err := logger.Guard("homework housekeeping", func() error {
+			s.run(ctx)
+			return nil
+		}); err != nil {
+			logger.LogWarn("homework housekeeping stopped after panic", "error", err)
+		}
+	}()
+}
+
+func (s *Sweeper) run(ctx context.Context) {
+	claims := time.NewTicker(claimSweepInterval)
+	defer claims.Stop()
+	uploads := time.NewTicker(uploadSweepInterval)
+	defer uploads.Stop()
+	digest := time.NewTicker(digestCheckInterval)
+	defer digest.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-claims.C:
+			if _, err := s.SweepClaims(ctx); err != nil && ctx.Err() == nil {
+				logger.LogErrorContext(ctx, "housekeeping: sweep claims", err)
+			}
+		case <-uploads.C:
+			if _, err := s.SweepDrafts(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				logger.LogErrorContext(ctx, "housekeeping: sweep drafts", err)
+			}
+			if _, err := s.SweepUploads(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				logger.LogErrorContext(ctx, "housekeeping: sweep uploads", err)
+			}
+		case <-digest.C:
+			if s.alerts == nil {
+				continue
+			}
+			if _, err := s.SendSLADigest(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				logger.LogErrorContext(ctx, "housekeeping: sla digest", err)
+			}
+		}
+	}
+}
+
+// SweepClaims clears expired claims and publishes one grading event per
+// affected (center, series) so open grader queues refetch. It returns how many
+// claims were cleared.
+func (s *Sweeper) SweepClaims(ctx context.Context) (int, error) {
+	expired, err := store.New(s.pool).ExpireStaleClaims(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("expire claims: %w", err)
+	}
+	type scope struct{ center, series int64 }
+	published := make(map[scope]bool, len(expired))
+	for _, c := range expired {
+		k := scope{c.MathCenterID, c.SeriesID}
+		if published[k] {
+			continue
+		}
+		published[k] = true
+		live.Publish(ctx, s.pool, live.Event{CenterID: c.MathCenterID, Kind: live.KindGrading, SeriesID: c.SeriesID})
+	}
+	metrics.HomeworkClaimsExpired.Add(float64(len(expired)))
+	return len(expired), nil
+}
+
+// SweepDrafts deletes submission drafts that expired by now, then their
+// photos. It returns how many drafts were removed. A photo that fails to
+// delete is only logged: with its draft gone it is an orphan, and
+// SweepUploads picks it up later.
+func (s *Sweeper) SweepDrafts(ctx context.Context, now time.Time) (int, error) {
+	expired, err := store.New(s.pool).DeleteExpiredAttemptDrafts(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired drafts: %w", err)
+	}
+	for _, d := range expired {
+		for _, key := range d.ObjectKeys {
+			if err := s.blobs.Delete(ctx, key); err != nil {
+				logger.LogWarnContext(ctx, "housekeeping: delete draft photo", "error", err, "object_key", key)
+			}
+		}
+	}
+	return len(expired), nil
+}
+
+// SweepUploads deletes homework objects older than orphanGrace that no event
+// photo and no live draft references by key. Comparing keys rather than
+// event UUIDs also catches strays under a finalized event, such as an
+// abandoned retry of one of its upload slots. It returns how many objects
+// were deleted, or 0 when another replica holds the sweep lock.
+//
+// The advisory lock lives in a transaction that stays open for the whole
+// listing. That pins one pool connection for the duration, which is the
+// price of not having two replicas page through the same bucket.
+func (s *Sweeper) SweepUploads(ctx context.Context, now time.Time) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	q := store.New(tx)
+	locked, err := q.TryAdvisoryXactLock(ctx, uploadSweepLockKey)
+	if err != nil {
+		return 0, fmt.Errorf("advisory lock: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	cutoff := now.Add(-orphanGrace)
+	// Keys come back in order, so every object of one event is contiguous;
+	// batching by UUID keeps the lookup to one query per uuidBatchSize events.
+	pending := make(map[string][]string)
+	var order []string
+	deleted := 0
+	flush := func() error {
+		if len(order) == 0 {
+			return nil
+		}
+		referenced, err := q.ListReferencedObjectKeys(ctx, order)
+		if err != nil {
+			return fmt.Errorf("lookup object keys: %w", err)
+		}
+		keep := make(map[string]bool, len(referenced))
+		for _, key := range referenced {
+			keep[key] = true
+		}
+		for _, uuid := range order {
+			for _, key := range pending[uuid] {
+				if keep[key] {
+					continue
+				}
+				if err := s.blobs.Delete(ctx, key); err != nil {
+					return fmt.Errorf("delete %s: %w", key, err)
+				}
+				deleted++
+			}
+		}
+		clear(pending)
+		order = order[:0]
+		return nil
+	}
+
+	err = s.blobs.List(ctx, homework.ObjectKeyRoot, func(obj objectstore.ObjectInfo) error {
+		if !obj.LastModified.Before(cutoff) {
+			return nil
+		}
+		_, uuid, ok := homework.ParseObjectKey(obj.Key)
+		if !ok {
+			// Not ours to judge; leave stray keys for a human.
+			return nil
+		}
+		if _, seen := pending[uuid]; !seen {
+			if len(order) == uuidBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			order = append(order, uuid)
+		}
+		pending[uuid] = append(pending[uuid], obj.Key)
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	metrics.HomeworkOrphanUploadsDeleted.Add(float64(deleted))
+	if err != nil {
+		return deleted, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return deleted, fmt.Errorf("commit: %w", err)
+	}
+	return deleted, nil
+}
+
+// SendSLADigest sends the day's overdue digest once the digest hour has come.
+// Each UTC day is claimed in homework_sla_digest inside the transaction that
+// counts, so only one replica sends it, and a failed count is retried on the
+// next check. It reports whether this call sent (or, with nothing overdue,
+// settled) the day's digest.
+func (s *Sweeper) SendSLADigest(ctx context.Context, now time.Time) (bool, error) {
+	now = now.UTC()
+	if now.Hour() < digestHourUTC {
+		return false, nil
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	q := store.New(tx)
+	claimed, err := q.ClaimSLADigest(ctx, now)
+	if err != nil {
+		return false, fmt.Errorf("claim digest: %w", err)
+	}
+	if !claimed {
+		return false, nil
+	}
+	centers, err := q.CountOverdueByCenter(ctx, now, homework.DefaultSLAHours)
+	if err != nil {
+		return false, fmt.Errorf("count overdue: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	if len(centers) > 0 && s.alerts != nil {
+		s.alerts.Enqueue(slaDigest(centers, now))
+	}
+	return true, nil
+}
+
+// slaDigest renders the overdue counts as one alert, a line per center keyed
+// by its graduation year.
+func slaDigest(centers []store.OverdueCenterRow, now time.Time) logger.AlertEvent {
+	var total int64
+	attrs := make(map[string]string, len(centers))
+	for _, c := range centers {
+		total += c.Overdue
+		attrs[fmt.Sprintf("center_%d", c.GraduationYear)] = fmt.Sprintf(
+			"%d overdue (SLA %dh), oldest waiting since %s",
+			c.Overdue, c.SLAHours, c.OldestAt.UTC().Format("2006-01-02 15:04"))
+	}
+	return logger.AlertEvent{
+		Time:    now,
+		Level:   slog.LevelWarn,
+		Message: fmt.Sprintf("homework sla digest: %d threads waiting past the grading SLA", total),
+		Attrs:   attrs,
+	}
+}
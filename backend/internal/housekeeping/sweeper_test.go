@@ -0,0 +1,218 @@
+package housekeeping_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/Alarion239/my239/backend/internal/housekeeping"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	"github.com/Alarion239/my239/backend/pkg/objectstore"
+)
+
+func TestSweepClaims_PublishesOncePerSeries(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+
+	mock.ExpectQuery(`UPDATE homework_thread\s+SET claim_holder_user_id = NULL`).
+		WillReturnRows(mock.NewRows([]string{"id", "math_center_id", "series_id"}).
+			AddRow(int64(1), int64(42), int64(100)).
+			AddRow(int64(2), int64(42), int64(100)).
+			AddRow(int64(3), int64(42), int64(101)))
+	mock.ExpectExec(`pg_notify`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectExec(`pg_notify`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	n, err := housekeeping.NewSweeper(mock, objectstore.NewMemory()).SweepClaims(context.Background())
+	if err != nil {
+		t.Fatalf("SweepClaims: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("cleared %d claims, want 3", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+func TestSweepUploads_DeletesOnlyOldUnreferencedObjects(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	ctx := context.Background()
+	blobs := objectstore.NewMemory()
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+
+	keys := map[string]time.Time{
+		"homework/thread/1/known/0.jpg":       old, // finalized event
+		"homework/thread/1/known/0.thumb.jpg": old,
+		"homework/thread/1/known/1.jpg":       old, // abandoned retry of a slot
+		"homework/thread/1/orphan/0.jpg":      old, // never finalized
+		"homework/thread/1/orphan/1.png":      old,
+		"homework/thread/2/fresh/0.jpg":       now, // upload may be in flight
+		"homework/thread/stray":               old, // not our layout
+	}
+	for k, at := range keys {
+		if err := blobs.Put(ctx, k, strings.NewReader("x"), 1, "image/jpeg"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		blobs.SetModTime(k, at)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`pg_try_advisory_xact_lock`).
+		WithArgs(pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+	mock.ExpectQuery(`SELECT k.object_key\s+FROM homework_thread_event e`).
+		WithArgs([]string{"known", "orphan"}).
+		WillReturnRows(mock.NewRows([]string{"object_key"}).
+			AddRow("homework/thread/1/known/0.jpg").
+			AddRow("homework/thread/1/known/0.thumb.jpg"))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	n, err := housekeeping.NewSweeper(mock, blobs).SweepUploads(ctx, now)
+	if err != nil {
+		t.Fatalf("SweepUploads: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("deleted %d objects, want 3", n)
+	}
+	for k := range keys {
+		exists, _ := blobs.Exists(ctx, k)
+		want := !strings.Contains(k, "/orphan/") && k != "homework/thread/1/known/1.jpg"
+		if exists != want {
+			t.Errorf("%s exists = %v, want %v", k, exists, want)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+func TestSweepUploads_SkipsWhenAnotherReplicaHoldsTheLock(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	ctx := context.Background()
+	blobs := objectstore.NewMemory()
+	key := "homework/thread/1/orphan/0.jpg"
+	_ = blobs.Put(ctx, key, strings.NewReader("x"), 1, "image/jpeg")
+	blobs.SetModTime(key, time.Now().Add(-48*time.Hour))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`pg_try_advisory_xact_lock`).
+		WithArgs(pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+	mock.ExpectRollback()
+
+	n, err := housekeeping.NewSweeper(mock, blobs).SweepUploads(ctx, time.Now())
+	if err != nil || n != 0 {
+		t.Fatalf("SweepUploads = (%d, %v), want (0, nil)", n, err)
+	}
+	if exists, _ := blobs.Exists(ctx, key); !exists {
+		t.Error("object deleted without holding the sweep lock")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+type recordingSink struct{ events []logger.AlertEvent }
+
+func (s *recordingSink) Enqueue(e logger.AlertEvent) { s.events = append(s.events, e) }
+
+func TestSendSLADigest_OncePerDayAfterDigestHour(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	ctx := context.Background()
+	sink := &recordingSink{}
+	s := housekeeping.NewSweeper(mock, objectstore.NewMemory()).WithDigest(sink)
+
+	// Before the digest hour nothing touches the database.
+	early := time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)
+	if sent, err := s.SendSLADigest(ctx, early); err != nil || sent {
+		t.Fatalf("early: sent=%v err=%v", sent, err)
+	}
+
+	now := time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO homework_sla_digest`).
+		WithArgs(now).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`FROM homework_thread th`).
+		WithArgs(now, int32(72)).
+		WillReturnRows(mock.NewRows([]string{"id", "graduation_year", "hours", "overdue", "oldest"}).
+			AddRow(int64(42), int32(2027), int32(48), int64(5), now.Add(-100*time.Hour)))
+	mock.ExpectCommit()
+	// A second replica finds the day taken.
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO homework_sla_digest`).
+		WithArgs(now).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectRollback()
+
+	if sent, err := s.SendSLADigest(ctx, now); err != nil || !sent {
+		t.Fatalf("first: sent=%v err=%v", sent, err)
+	}
+	if sent, err := s.SendSLADigest(ctx, now); err != nil || sent {
+		t.Fatalf("second: sent=%v err=%v", sent, err)
+	}
+	if len(sink.events) != 1 {
+		t.Fatalf("enqueued %d events, want 1", len(sink.events))
+	}
+	e := sink.events[0]
+	if !strings.Contains(e.Message, "5 threads") || !strings.Contains(e.Attrs["center_2027"], "SLA 48h") {
+		t.Errorf("digest = %+v", e)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+func TestSweepDrafts_DeletesExpiredDraftsWithPhotos(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	ctx := context.Background()
+	blobs := objectstore.NewMemory()
+	kept := "homework/thread/2/live/0.jpg"
+	gone := []string{"homework/thread/1/stale/0.jpg", "homework/thread/1/stale/1.jpg"}
+	for _, k := range append([]string{kept}, gone...) {
+		_ = blobs.Put(ctx, k, strings.NewReader("x"), 1, "image/jpeg")
+	}
+
+	now := time.Now()
+	mock.ExpectQuery(`DELETE FROM homework_attempt_draft\s+WHERE expires_at <= \$1`).
+		WithArgs(now).
+		WillReturnRows(mock.NewRows([]string{"thread_id", "event_uuid", "object_keys"}).
+			AddRow(int64(1), "stale", gone))
+
+	n, err := housekeeping.NewSweeper(mock, blobs).SweepDrafts(ctx, now)
+	if err != nil {
+		t.Fatalf("SweepDrafts: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("removed %d drafts, want 1", n)
+	}
+	for _, k := range gone {
+		if exists, _ := blobs.Exists(ctx, k); exists {
+			t.Errorf("%s survived", k)
+		}
+	}
+	if exists, _ := blobs.Exists(ctx, kept); !exists {
+		t.Errorf("%s of a live draft was deleted", kept)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
@@ -54,4 +54,8 @@ const (
 	// CodeUnavailable — a downstream dependency (DB, Redis) is unreachable;
 	// retrying the request later may succeed.
 	CodeUnavailable ErrorCode = "service_unavailable"
+
+	// CodeQuotaExceeded — the caller used up a storage quota; the request
+	// will keep failing until space is freed or the quota raised.
+	CodeQuotaExceeded ErrorCode = "quota_exceeded"
 )
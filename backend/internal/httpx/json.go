@@ -26,12 +26,15 @@ const MaxBodyBytes = 1 << 20
 // Code is a stable machine-readable identifier — see ErrorCode constants.
 // Error is human-readable and may change between releases.
 // Fields, when present, breaks down per-field validation errors.
+// Details, when present, carries structured context for the failure, such
+// as the diagnostics behind a rejected TeX source.
 // TraceID is the chi request ID, if available, so users can include it in
 // bug reports and operators can grep logs.
 type ErrorResponse struct {
 	Code    ErrorCode         `json:"code"`
 	Error   string            `json:"error"`
 	Fields  map[string]string `json:"fields,omitempty"`
+	Details any               `json:"details,omitempty"`
 	TraceID string            `json:"trace_id,omitempty"`
 }
 
@@ -56,7 +59,12 @@ func WriteJSON(w http.ResponseWriter, status int, v any) {
 // The chi request ID is attached as TraceID when one is in the request
 // context — pass r to populate it.
 func WriteAPIError(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, msg string) {
-	resp := ErrorResponse{Code: code, Error: msg}
+	WriteAPIErrorDetails(w, r, status, code, msg, nil)
+}
+
+// WriteAPIErrorDetails is WriteAPIError with a Details payload.
+func WriteAPIErrorDetails(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, msg string, details any) {
+	resp := ErrorResponse{Code: code, Error: msg, Details: details}
 	if r != nil {
 		resp.TraceID = chiMiddleware.GetReqID(r.Context())
 	}
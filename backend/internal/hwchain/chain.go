@@ -0,0 +1,130 @@
+// Package hwchain keeps the per-thread hash chain over homework_thread_event.
+// Every code path that appends an event — the homework handlers, the Google
+// Sheets import and the demo seeder — locks the chain before the insert and
+// seals the new row right after it, inside the same transaction:
+//
+//	prev, err := qx.LockEventChain(ctx, threadID)
+//	event, err := qx.AppendEvent(ctx, ...)
+//	err = hwchain.Seal(ctx, qx, prev, event)
+//
+// Retractions are ordinary events, so the chain proves the history is
+// append-only: rewriting or deleting a sealed row breaks every later link or
+// the thread's recorded head.
+package hwchain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+// pageSize bounds the threads (and thus the event ANY(...) lookup) per round
+// trip when verifying a whole center.
+const pageSize = 500
+
+// Event projects a stored row onto the fields the hash covers.
+func Event(e store.HomeworkThreadEvent) homework.ChainEvent {
+	return homework.ChainEvent{
+		ID:                   e.ID,
+		ThreadID:             e.ThreadID,
+		EventUUID:            e.EventUuid,
+		Kind:                 e.Kind,
+		ActorUserID:          e.ActorUserID,
+		Body:                 e.Body,
+		Verdict:              e.Verdict,
+		RefersToEventID:      e.RefersToEventID,
+		CreatedAt:            e.CreatedAt,
+		IsOffline:            e.IsOffline,
+		CreditedGraderUserID: e.CreditedGraderUserID,
+		CreditedGraderName:   e.CreditedGraderName,
+	}
+}
+
+// Seal links a freshly appended event to prev (the head LockEventChain
+// returned) and advances the thread's head.
+func Seal(ctx context.Context, q *store.Queries, prev []byte, e store.HomeworkThreadEvent) error {
+	affected, err := q.SealEvent(ctx, store.SealEventParams{
+		EventID:  e.ID,
+		ThreadID: e.ThreadID,
+		PrevHash: prev,
+		Hash:     homework.EventHash(prev, Event(e)),
+	})
+	if err != nil {
+		return fmt.Errorf("seal event %d: %w", e.ID, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("seal event %d: already sealed", e.ID)
+	}
+	return nil
+}
+
+// ThreadResult is the verdict on one thread's chain.
+type ThreadResult struct {
+	ThreadID     int64                 `json:"thread_id"`
+	MathCenterID int64                 `json:"math_center_id"`
+	SeriesID     int64                 `json:"series_id"`
+	Events       int                   `json:"events"`
+	Sealed       int                   `json:"sealed"`
+	OK           bool                  `json:"ok"`
+	Breaks       []homework.ChainBreak `json:"breaks"`
+}
+
+// Scope picks the threads to verify; zero values mean all.
+type Scope struct {
+	MathCenterID int64
+	ThreadID     int64
+}
+
+// Verify re-hashes every thread in scope and calls fn with each result, in
+// thread id order.
+func Verify(ctx context.Context, q *store.Queries, scope Scope, fn func(ThreadResult)) error {
+	afterID := int64(0)
+	for {
+		threads, err := q.ListChainThreads(ctx, store.ListChainThreadsParams{
+			MathCenterID: scope.MathCenterID,
+			ThreadID:     scope.ThreadID,
+			AfterID:      afterID,
+			Limit:        pageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("list threads: %w", err)
+		}
+		if len(threads) == 0 {
+			return nil
+		}
+		ids := make([]int64, len(threads))
+		for i, t := range threads {
+			ids[i] = t.ID
+		}
+		rows, err := q.ListChainEventsForThreads(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("list events: %w", err)
+		}
+		links := map[int64][]homework.ChainLink{}
+		for _, r := range rows {
+			links[r.ThreadID] = append(links[r.ThreadID], homework.ChainLink{
+				Event:    Event(r.HomeworkThreadEvent),
+				PrevHash: r.PrevHash,
+				Hash:     r.Hash,
+			})
+		}
+		for _, t := range threads {
+			sealed, breaks := homework.VerifyChain(links[t.ID], t.EventChainHead)
+			if breaks == nil {
+				breaks = []homework.ChainBreak{}
+			}
+			fn(ThreadResult{
+				ThreadID:     t.ID,
+				MathCenterID: t.MathCenterID,
+				SeriesID:     t.SeriesID,
+				Events:       len(links[t.ID]),
+				Sealed:       sealed,
+				OK:           len(breaks) == 0,
+				Breaks:       breaks,
+			})
+		}
+		afterID = threads[len(threads)-1].ID
+	}
+}
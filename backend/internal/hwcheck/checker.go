@@ -0,0 +1,187 @@
+// Package hwcheck verifies the homework_thread cache against the event log.
+// Handlers and the Google Sheets import keep current_status and the
+// current_*/last_grader_* columns in step with homework_thread_event inside
+// one transaction; the checker replays each log through homework.Replay and
+// reports threads whose log holds an illegal sequence or whose cache drifted,
+// optionally rebuilding the cache from the log.
+package hwcheck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+// pageSize bounds the threads (and thus the event ANY(...) lookup) per round
+// trip.
+const pageSize = 500
+
+// Scope narrows a run to one center and/or one series; zero values mean all.
+type Scope struct {
+	MathCenterID int64
+	SeriesID     int64
+}
+
+// Finding is one thread that failed the check.
+type Finding struct {
+	ThreadID     int64
+	MathCenterID int64
+	SeriesID     int64
+	// Illegal lists events the transition rules would have refused. The log
+	// is history, so these are reported, never rewritten.
+	Illegal []homework.ReplayIssue
+	// Drift lists cache columns that disagree with the replayed log.
+	Drift []string
+	// StrayClaim is a claim left on a thread that is not gradable.
+	StrayClaim bool
+	// Repaired is set when the cache was rebuilt from the log.
+	Repaired bool
+}
+
+// NeedsRepair reports whether rebuilding the cache would change the row.
+func (f Finding) NeedsRepair() bool {
+	return len(f.Drift) > 0 || f.StrayClaim
+}
+
+// Report is the outcome of one run.
+type Report struct {
+	Threads  int
+	Findings []Finding
+}
+
+// Checker runs the replay against the database.
+type Checker struct {
+	pool db.Pool
+}
+
+// New builds a Checker.
+func New(pool db.Pool) *Checker {
+	return &Checker{pool: pool}
+}
+
+// Check replays every thread in scope. With repair, threads whose cache
+// drifted are rebuilt, each under its own row lock so a concurrent handler
+// either lands before the rebuild (and is replayed) or waits for it.
+func (c *Checker) Check(ctx context.Context, scope Scope, repair bool) (Report, error) {
+	var report Report
+	q := store.New(c.pool)
+	afterID := int64(0)
+	for {
+		threads, err := q.ListThreadsForCheck(ctx, store.ListThreadsForCheckParams{
+			MathCenterID: scope.MathCenterID,
+			SeriesID:     scope.SeriesID,
+			AfterID:      afterID,
+			Limit:        pageSize,
+		})
+		if err != nil {
+			return report, fmt.Errorf("list threads: %w", err)
+		}
+		if len(threads) == 0 {
+			return report, nil
+		}
+		ids := make([]int64, len(threads))
+		for i, t := range threads {
+			ids[i] = t.ID
+		}
+		rows, err := q.ListReplayEventsForThreads(ctx, ids)
+		if err != nil {
+			return report, fmt.Errorf("list events: %w", err)
+		}
+		byThread := groupEvents(rows)
+		for _, t := range threads {
+			f := checkThread(t, byThread[t.ID])
+			if f == nil {
+				continue
+			}
+			if repair && f.NeedsRepair() {
+				if err := c.repair(ctx, t.ID); err != nil {
+					return report, fmt.Errorf("repair thread %d: %w", t.ID, err)
+				}
+				f.Repaired = true
+			}
+			report.Findings = append(report.Findings, *f)
+		}
+		report.Threads += len(threads)
+		afterID = threads[len(threads)-1].ID
+	}
+}
+
+// repair rebuilds one thread's cache from its log inside a transaction that
+// holds the thread row, re-reading the log under the lock.
+func (c *Checker) repair(ctx context.Context, threadID int64) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	qx := store.New(tx)
+	t, err := qx.LockThread(ctx, threadID)
+	if err != nil {
+		return fmt.Errorf("lock thread: %w", err)
+	}
+	rows, err := qx.ListReplayEventsForThreads(ctx, []int64{threadID})
+	if err != nil {
+		return fmt.Errorf("list events: %w", err)
+	}
+	events := groupEvents(rows)[threadID]
+	if f := checkThread(t, events); f == nil || !f.NeedsRepair() {
+		// Fixed by someone else in the meantime.
+		return tx.Commit(ctx)
+	}
+	want, _ := homework.Replay(events)
+	if err := qx.RebuildThreadCache(ctx, store.RebuildThreadCacheParams{
+		ID:                    threadID,
+		CurrentStatus:         want.Status,
+		CurrentAttemptEventID: want.AttemptEventID,
+		CurrentGradeEventID:   want.GradeEventID,
+		LastGraderUserID:      want.LastGraderUserID,
+		LastGraderName:        want.LastGraderName,
+	}); err != nil {
+		return fmt.Errorf("rebuild cache: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// checkThread returns nil for a consistent thread.
+func checkThread(t store.HomeworkThread, events []homework.ReplayEvent) *Finding {
+	want, issues := homework.Replay(events)
+	got := homework.ThreadCache{
+		Status:           t.CurrentStatus,
+		AttemptEventID:   t.CurrentAttemptEventID,
+		GradeEventID:     t.CurrentGradeEventID,
+		LastGraderUserID: t.LastGraderUserID,
+		LastGraderName:   t.LastGraderName,
+	}
+	f := Finding{
+		ThreadID:     t.ID,
+		MathCenterID: t.MathCenterID,
+		SeriesID:     t.SeriesID,
+		Illegal:      issues,
+		Drift:        want.Diff(got),
+		StrayClaim: t.ClaimHolderUserID != nil &&
+			want.Status != homework.StatusSubmitted && want.Status != homework.StatusAppealed,
+	}
+	if len(f.Illegal) == 0 && !f.NeedsRepair() {
+		return nil
+	}
+	return &f
+}
+
+func groupEvents(rows []store.ReplayEventRow) map[int64][]homework.ReplayEvent {
+	out := map[int64][]homework.ReplayEvent{}
+	for _, r := range rows {
+		out[r.ThreadID] = append(out[r.ThreadID], homework.ReplayEvent{
+			ID:                   r.ID,
+			Kind:                 r.Kind,
+			ActorUserID:          r.ActorUserID,
+			Verdict:              r.Verdict,
+			CreditedGraderUserID: r.CreditedGraderUserID,
+			CreditedGraderName:   r.CreditedGraderName,
+		})
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package hwcheck_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/Alarion239/my239/backend/internal/hwcheck"
+)
+
+var threadColumns = []string{
+	"id", "student_user_id", "subproblem_id", "series_id", "math_center_id", "current_status",
+	"current_attempt_event_id", "current_grade_event_id", "last_grader_user_id", "claim_holder_user_id",
+	"claim_expires_at", "created_at", "updated_at", "last_grader_name",
+}
+
+var replayColumns = []string{
+	"thread_id", "id", "kind", "actor_user_id", "verdict", "credited_grader_user_id", "credited_grader_name",
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// thread 1 is consistent (submitted → accepted by 3). thread 2's log says
+// rejected but the cache still reads submitted with no grade.
+func expectPage(mock pgxmock.PgxPoolIface, now time.Time) {
+	mock.ExpectQuery(`FROM homework_thread\s+WHERE \(\$1::bigint = 0`).
+		WithArgs(int64(42), int64(0), int64(0), int32(500)).
+		WillReturnRows(mock.NewRows(threadColumns).
+			AddRow(int64(1), int64(7), int64(900), int64(100), int64(42), "accepted",
+				ptr(int64(10)), ptr(int64(11)), ptr(int64(3)), (*int64)(nil), (*time.Time)(nil), now, now, "").
+			AddRow(int64(2), int64(8), int64(900), int64(100), int64(42), "submitted",
+				ptr(int64(20)), (*int64)(nil), (*int64)(nil), (*int64)(nil), (*time.Time)(nil), now, now, ""))
+	mock.ExpectQuery(`FROM homework_thread_event\s+WHERE thread_id = ANY`).
+		WithArgs([]int64{1, 2}).
+		WillReturnRows(mock.NewRows(replayColumns).
+			AddRow(int64(1), int64(10), "submitted", int64(7), (*string)(nil), (*int64)(nil), "").
+			AddRow(int64(1), int64(11), "graded", int64(3), ptr("accepted"), (*int64)(nil), "").
+			AddRow(int64(2), int64(20), "submitted", int64(8), (*string)(nil), (*int64)(nil), "").
+			AddRow(int64(2), int64(21), "graded", int64(4), ptr("rejected"), (*int64)(nil), ""))
+}
+
+func expectLastPage(mock pgxmock.PgxPoolIface) {
+	mock.ExpectQuery(`FROM homework_thread\s+WHERE \(\$1::bigint = 0`).
+		WithArgs(int64(42), int64(0), int64(2), int32(500)).
+		WillReturnRows(mock.NewRows(threadColumns))
+}
+
+func TestCheck_ReportsDrift(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	now := time.Now()
+	expectPage(mock, now)
+	expectLastPage(mock)
+
+	report, err := hwcheck.New(mock).Check(context.Background(), hwcheck.Scope{MathCenterID: 42}, false)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if report.Threads != 2 || len(report.Findings) != 1 {
+		t.Fatalf("report = %+v; want 2 threads, 1 finding", report)
+	}
+	f := report.Findings[0]
+	if f.ThreadID != 2 || len(f.Drift) != 3 || f.Repaired || len(f.Illegal) != 0 {
+		t.Errorf("finding = %+v; want thread 2 with status, grade and grader drift", f)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
+
+func TestCheck_RepairRebuildsUnderLock(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	now := time.Now()
+	expectPage(mock, now)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM homework_thread\s+WHERE id = \$1\s+FOR UPDATE`).
+		WithArgs(int64(2)).
+		WillReturnRows(mock.NewRows(threadColumns).
+			AddRow(int64(2), int64(8), int64(900), int64(100), int64(42), "submitted",
+				ptr(int64(20)), (*int64)(nil), (*int64)(nil), (*int64)(nil), (*time.Time)(nil), now, now, ""))
+	mock.ExpectQuery(`FROM homework_thread_event\s+WHERE thread_id = ANY`).
+		WithArgs([]int64{2}).
+		WillReturnRows(mock.NewRows(replayColumns).
+			AddRow(int64(2), int64(20), "submitted", int64(8), (*string)(nil), (*int64)(nil), "").
+			AddRow(int64(2), int64(21), "graded", int64(4), ptr("rejected"), (*int64)(nil), ""))
+	mock.ExpectExec(`UPDATE homework_thread\s+SET current_status\s+= \$2::text`).
+		WithArgs(int64(2), "rejected", ptr(int64(20)), ptr(int64(21)), ptr(int64(4)), "").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	expectLastPage(mock)
+
+	report, err := hwcheck.New(mock).Check(context.Background(), hwcheck.Scope{MathCenterID: 42}, true)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(report.Findings) != 1 || !report.Findings[0].Repaired {
+		t.Fatalf("report = %+v; want thread 2 repaired", report)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations: %v", err)
+	}
+}
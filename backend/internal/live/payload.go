@@ -19,13 +19,20 @@ const (
 	KindMembership       Kind = "membership"         // groups/teachers/students changes
 	KindComments         Kind = "comments"           // internal teacher notes on threads/students
 	KindStudentNameColor Kind = "student_name_color" // teacher-only student name colors
+	KindMessages         Kind = "messages"           // clarification messages on a homework thread
+	KindCalibration      Kind = "calibration"        // teacher-only blind second-grading queue/disagreements
+	KindSeries           Kind = "series"             // series statement publication (manual or scheduled)
+	KindLessons          Kind = "lessons"            // lesson schedule and attendance marks
 )
 
 // Event is the JSON payload carried by pg_notify and pushed to SSE clients.
 // SeriesID is 0 for non-series kinds (coffins/membership are center-wide).
+// ThreadID is set only for thread-scoped kinds (messages), so an open thread
+// page can refetch without invalidating every queue.
 type Event struct {
 	CenterID      int64 `json:"center_id"`
 	Kind          Kind  `json:"kind"`
 	SeriesID      int64 `json:"series_id,omitempty"`
 	StudentUserID int64 `json:"student_user_id,omitempty"`
+	ThreadID      int64 `json:"thread_id,omitempty"`
 }
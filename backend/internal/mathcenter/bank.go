@@ -0,0 +1,110 @@
+package mathcenter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Problem bank limits. The TeX caps match the series statement cap: a bank
+// entry is one problem, so these are generous.
+const (
+	MaxBankTitleRunes  = 200
+	MaxBankSourceRunes = 300
+	MaxBankTexBytes    = 512 * 1024
+	MaxBankTags        = 20
+	MaxBankTagRunes    = 40
+	MinBankDifficulty  = 1
+	MaxBankDifficulty  = 5
+)
+
+// BankProblemInput is the editable part of a bank problem, as a teacher
+// submits it.
+type BankProblemInput struct {
+	Title           string
+	StatementTex    string
+	SolutionTex     *string
+	Tags            []string
+	Difficulty      *int
+	Source          string
+	SubproblemCount int
+}
+
+// NormalizeBankProblem trims and validates a bank problem in place. Tags are
+// lower-cased, de-duplicated and sorted so that filtering by tag is an exact
+// match. A blank solution is stored as none.
+func NormalizeBankProblem(in *BankProblemInput) error {
+	in.Title = strings.TrimSpace(in.Title)
+	if in.Title == "" {
+		return errors.New("title is required")
+	}
+	if utf8.RuneCountInString(in.Title) > MaxBankTitleRunes {
+		return errors.New("title too long")
+	}
+	if strings.TrimSpace(in.StatementTex) == "" {
+		return errors.New("statement_tex is required")
+	}
+	if len(in.StatementTex) > MaxBankTexBytes {
+		return errors.New("statement_tex too long")
+	}
+	if in.SolutionTex != nil {
+		if strings.TrimSpace(*in.SolutionTex) == "" {
+			in.SolutionTex = nil
+		} else if len(*in.SolutionTex) > MaxBankTexBytes {
+			return errors.New("solution_tex too long")
+		}
+	}
+	in.Source = strings.TrimSpace(in.Source)
+	if utf8.RuneCountInString(in.Source) > MaxBankSourceRunes {
+		return errors.New("source too long")
+	}
+	if in.Difficulty != nil && (*in.Difficulty < MinBankDifficulty || *in.Difficulty > MaxBankDifficulty) {
+		return fmt.Errorf("difficulty must be %d..%d", MinBankDifficulty, MaxBankDifficulty)
+	}
+	if in.SubproblemCount < 0 || in.SubproblemCount > MaxSubproblemsPerProblem {
+		return fmt.Errorf("subproblem_count must be 0..%d", MaxSubproblemsPerProblem)
+	}
+	tags, err := NormalizeBankTags(in.Tags)
+	if err != nil {
+		return err
+	}
+	in.Tags = tags
+	return nil
+}
+
+// NormalizeBankTags lower-cases, trims, de-duplicates and sorts topic tags,
+// dropping blanks. Never returns nil.
+func NormalizeBankTags(raw []string) ([]string, error) {
+	seen := make(map[string]bool, len(raw))
+	out := make([]string, 0, len(raw))
+	for _, t := range raw {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		if utf8.RuneCountInString(t) > MaxBankTagRunes {
+			return nil, fmt.Errorf("tag %q too long", t)
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	if len(out) > MaxBankTags {
+		return nil, fmt.Errorf("at most %d tags", MaxBankTags)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// BankSolveRate is the historical share of accepted (subproblem, student)
+// pairs over every series the problem was placed in, or nil before any such
+// series was due. It is the same tally the series problem stats report,
+// summed across series.
+func BankSolveRate(accepted, assigned int64) *float64 {
+	if assigned <= 0 {
+		return nil
+	}
+	rate := float64(accepted) / float64(assigned)
+	return &rate
+}
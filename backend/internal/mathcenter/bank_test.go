@@ -0,0 +1,64 @@
+package mathcenter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeBankTags(t *testing.T) {
+	got, err := NormalizeBankTags([]string{" Графы ", "инварианты", "графы", "", "ЧЁТНОСТЬ"})
+	if err != nil {
+		t.Fatalf("NormalizeBankTags: %v", err)
+	}
+	want := []string{"графы", "инварианты", "чётность"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got, _ := NormalizeBankTags(nil); got == nil || len(got) != 0 {
+		t.Errorf("nil tags: got %#v, want empty non-nil", got)
+	}
+	if _, err := NormalizeBankTags([]string{strings.Repeat("я", MaxBankTagRunes+1)}); err == nil {
+		t.Error("overlong tag: want error")
+	}
+	many := make([]string, MaxBankTags+1)
+	for i := range many {
+		many[i] = strings.Repeat("x", i+1)
+	}
+	if _, err := NormalizeBankTags(many); err == nil {
+		t.Error("too many tags: want error")
+	}
+}
+
+func TestNormalizeBankProblem(t *testing.T) {
+	blank, three, six := "  ", 3, 6
+	in := BankProblemInput{Title: "  Шахматная доска ", StatementTex: `\item`, SolutionTex: &blank, Difficulty: &three, Source: " Турнир городов "}
+	if err := NormalizeBankProblem(&in); err != nil {
+		t.Fatalf("NormalizeBankProblem: %v", err)
+	}
+	if in.Title != "Шахматная доска" || in.Source != "Турнир городов" || in.SolutionTex != nil || in.Tags == nil {
+		t.Errorf("normalized = %+v", in)
+	}
+
+	cases := map[string]BankProblemInput{
+		"no title":       {StatementTex: "x"},
+		"no statement":   {Title: "t", StatementTex: " "},
+		"bad difficulty": {Title: "t", StatementTex: "x", Difficulty: &six},
+		"too many parts": {Title: "t", StatementTex: "x", SubproblemCount: MaxSubproblemsPerProblem + 1},
+	}
+	for name, c := range cases {
+		if err := NormalizeBankProblem(&c); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestBankSolveRate(t *testing.T) {
+	if BankSolveRate(3, 0) != nil {
+		t.Error("no assignments: want nil rate")
+	}
+	if got := BankSolveRate(3, 12); got == nil || *got != 0.25 {
+		t.Errorf("got %v, want 0.25", got)
+	}
+}
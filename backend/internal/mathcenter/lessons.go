@@ -0,0 +1,143 @@
+package mathcenter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+// Lesson times are center-local wall-clock values: a calendar date like
+// likbez held_on plus start and end times of day.
+const (
+	LessonDateLayout    = "2006-01-02"
+	LessonTimeLayout    = "15:04"
+	MaxLessonTopicRunes = 300
+	MaxLessonGroups     = 20
+)
+
+// Attendance statuses. Late counts as attended; excused counts neither way.
+const (
+	AttendancePresent = "present"
+	AttendanceLate    = "late"
+	AttendanceAbsent  = "absent"
+	AttendanceExcused = "excused"
+)
+
+// ValidAttendanceStatus reports whether s is one of the attendance statuses.
+func ValidAttendanceStatus(s string) bool {
+	switch s {
+	case AttendancePresent, AttendanceLate, AttendanceAbsent, AttendanceExcused:
+		return true
+	}
+	return false
+}
+
+// LessonSchedule is a validated lesson date, time span and topic. StartsAt
+// and EndsAt are offsets from midnight.
+type LessonSchedule struct {
+	HeldOn   time.Time
+	StartsAt time.Duration
+	EndsAt   time.Duration
+	Topic    string
+}
+
+// ParseLessonSchedule validates a lesson as a teacher submits it: heldOn as
+// YYYY-MM-DD, startsAt and endsAt as HH:MM with the end after the start.
+func ParseLessonSchedule(heldOn, startsAt, endsAt, topic string) (LessonSchedule, error) {
+	date, err := time.Parse(LessonDateLayout, strings.TrimSpace(heldOn))
+	if err != nil {
+		return LessonSchedule{}, errors.New("held_on must be a calendar date")
+	}
+	start, err := parseLessonClock(startsAt)
+	if err != nil {
+		return LessonSchedule{}, fmt.Errorf("starts_at %w", err)
+	}
+	end, err := parseLessonClock(endsAt)
+	if err != nil {
+		return LessonSchedule{}, fmt.Errorf("ends_at %w", err)
+	}
+	if end <= start {
+		return LessonSchedule{}, errors.New("ends_at must be after starts_at")
+	}
+	topic = strings.TrimSpace(topic)
+	if utf8.RuneCountInString(topic) > MaxLessonTopicRunes {
+		return LessonSchedule{}, fmt.Errorf("topic must be at most %d characters", MaxLessonTopicRunes)
+	}
+	return LessonSchedule{HeldOn: date, StartsAt: start, EndsAt: end, Topic: topic}, nil
+}
+
+func parseLessonClock(value string) (time.Duration, error) {
+	t, err := time.Parse(LessonTimeLayout, strings.TrimSpace(value))
+	if err != nil {
+		return 0, errors.New("must be a time of day as HH:MM")
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// FormatLessonClock renders an offset from midnight as HH:MM.
+func FormatLessonClock(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// AttendanceSummary counts a student's lessons so far and their marks.
+// Unmarked lessons are ones nobody took attendance for; Rate is attended
+// (present or late) over marked lessons that were not excused, nil until
+// there is one.
+type AttendanceSummary struct {
+	Lessons  int64    `json:"lessons"`
+	Present  int64    `json:"present"`
+	Late     int64    `json:"late"`
+	Absent   int64    `json:"absent"`
+	Excused  int64    `json:"excused"`
+	Unmarked int64    `json:"unmarked"`
+	Rate     *float64 `json:"rate"`
+}
+
+// SummarizeAttendance derives the unmarked count and the rate.
+func SummarizeAttendance(c store.AttendanceCounts) AttendanceSummary {
+	out := AttendanceSummary{
+		Lessons: c.Lessons,
+		Present: c.Present,
+		Late:    c.Late,
+		Absent:  c.Absent,
+		Excused: c.Excused,
+	}
+	out.Unmarked = c.Lessons - c.Present - c.Late - c.Absent - c.Excused
+	if counted := c.Present + c.Late + c.Absent; counted > 0 {
+		rate := float64(c.Present+c.Late) / float64(counted)
+		out.Rate = &rate
+	}
+	return out
+}
+
+// AttendanceTerm is one term of a student's attendance history.
+type AttendanceTerm struct {
+	TermID      int64  `json:"term_id"`
+	Kind        string `json:"kind"`
+	Grade       *int32 `json:"grade"`
+	DisplayName string `json:"display_name"`
+	IsActive    bool   `json:"is_active"`
+	AttendanceSummary
+}
+
+// BuildAttendanceTerms labels and summarizes per-term counts, keeping their
+// order.
+func BuildAttendanceTerms(rows []store.StudentAttendanceTermRow) []AttendanceTerm {
+	out := make([]AttendanceTerm, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, AttendanceTerm{
+			TermID:            r.TermID,
+			Kind:              r.TermKind,
+			Grade:             r.TermGrade,
+			DisplayName:       TermDisplayName(r.TermKind, r.TermGrade),
+			IsActive:          r.TermIsActive,
+			AttendanceSummary: SummarizeAttendance(r.AttendanceCounts),
+		})
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package mathcenter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+func TestParseLessonSchedule(t *testing.T) {
+	got, err := ParseLessonSchedule("2026-09-03", "16:30", " 18:00 ", "  Графы ")
+	if err != nil {
+		t.Fatalf("ParseLessonSchedule: %v", err)
+	}
+	if got.HeldOn.Format(LessonDateLayout) != "2026-09-03" || got.StartsAt != 16*time.Hour+30*time.Minute ||
+		got.EndsAt != 18*time.Hour || got.Topic != "Графы" {
+		t.Errorf("got %+v", got)
+	}
+	if FormatLessonClock(got.StartsAt) != "16:30" {
+		t.Errorf("FormatLessonClock = %q", FormatLessonClock(got.StartsAt))
+	}
+
+	for _, tc := range []struct{ heldOn, startsAt, endsAt, topic string }{
+		{"03.09.2026", "16:30", "18:00", ""},
+		{"2026-09-03", "4pm", "18:00", ""},
+		{"2026-09-03", "18:00", "18:00", ""},
+		{"2026-09-03", "16:30", "18:00", strings.Repeat("я", MaxLessonTopicRunes+1)},
+	} {
+		if _, err := ParseLessonSchedule(tc.heldOn, tc.startsAt, tc.endsAt, tc.topic); err == nil {
+			t.Errorf("%+v: want error", tc)
+		}
+	}
+}
+
+func TestSummarizeAttendance(t *testing.T) {
+	got := SummarizeAttendance(store.AttendanceCounts{Lessons: 6, Present: 2, Late: 1, Absent: 1, Excused: 1})
+	if got.Unmarked != 1 || got.Rate == nil || *got.Rate != 0.75 {
+		t.Errorf("got %+v", got)
+	}
+	if got := SummarizeAttendance(store.AttendanceCounts{Lessons: 2, Excused: 1}); got.Rate != nil {
+		t.Errorf("only excused: rate = %v, want nil", *got.Rate)
+	}
+}
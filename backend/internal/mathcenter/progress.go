@@ -0,0 +1,187 @@
+package mathcenter
+
+import (
+	"context"
+	"time"
+
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+// ProgressCounts is the accepted / rejected / pending summary, the same
+// split MySeriesRollup uses for one series.
+type ProgressCounts struct {
+	Accepted int64 `json:"accepted"`
+	Rejected int64 `json:"rejected"`
+	Pending  int64 `json:"pending"`
+}
+
+// ProgressCoffins counts coffin subproblems. Open ones can still be solved:
+// their разбор is not released to students yet.
+type ProgressCoffins struct {
+	Total  int64 `json:"total"`
+	Solved int64 `json:"solved"`
+	Open   int64 `json:"open"`
+}
+
+// ProgressTurnaround is how long the student waited from an attempt to its
+// verdict. AvgSeconds is nil until something was graded.
+type ProgressTurnaround struct {
+	Graded     int64    `json:"graded"`
+	AvgSeconds *float64 `json:"avg_seconds"`
+}
+
+// ProgressStreak counts consecutive series with at least one accepted
+// subproblem. A series still before its deadline with nothing accepted yet
+// does not break the current streak.
+type ProgressStreak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+type ProgressSeries struct {
+	SeriesID    int64              `json:"series_id"`
+	Number      int                `json:"number"`
+	DisplayName string             `json:"display_name"`
+	DueAt       time.Time          `json:"due_at"`
+	Subproblems int64              `json:"subproblems"`
+	Counts      ProgressCounts     `json:"counts"`
+	Coffins     ProgressCoffins    `json:"coffins"`
+	Turnaround  ProgressTurnaround `json:"turnaround"`
+}
+
+type ProgressTerm struct {
+	TermID      int64              `json:"term_id"`
+	Kind        string             `json:"kind"`
+	Grade       *int32             `json:"grade"`
+	DisplayName string             `json:"display_name"`
+	IsActive    bool               `json:"is_active"`
+	Counts      ProgressCounts     `json:"counts"`
+	Coffins     ProgressCoffins    `json:"coffins"`
+	Turnaround  ProgressTurnaround `json:"turnaround"`
+	Series      []ProgressSeries   `json:"series"`
+}
+
+// StudentProgress is a student's whole history in one center: totals, then
+// terms in the order their first series was due, each with its series.
+type StudentProgress struct {
+	Counts     ProgressCounts     `json:"counts"`
+	Coffins    ProgressCoffins    `json:"coffins"`
+	Turnaround ProgressTurnaround `json:"turnaround"`
+	Streak     ProgressStreak     `json:"streak"`
+	Terms      []ProgressTerm     `json:"terms"`
+}
+
+// LoadStudentProgress reads the student's published series across every term
+// of the center and summarizes them. Callers enforce who may see it.
+func LoadStudentProgress(ctx context.Context, q *store.Queries, centerID, studentUserID int64, now time.Time) (StudentProgress, error) {
+	args := store.StudentProgressParams{MathCenterID: centerID, StudentUserID: studentUserID, Now: now}
+	series, err := q.ListStudentProgressSeries(ctx, args)
+	if err != nil {
+		return StudentProgress{}, err
+	}
+	turnaround, err := q.ListStudentTurnaround(ctx, args)
+	if err != nil {
+		return StudentProgress{}, err
+	}
+	return BuildStudentProgress(series, turnaround, now), nil
+}
+
+// BuildStudentProgress folds per-series rows (ordered by deadline) into the
+// term and overall summaries.
+func BuildStudentProgress(series []store.StudentProgressSeriesRow, turnaround []store.StudentTurnaroundRow, now time.Time) StudentProgress {
+	waits := make(map[int64]store.StudentTurnaroundRow, len(turnaround))
+	for _, t := range turnaround {
+		waits[t.SeriesID] = t
+	}
+
+	out := StudentProgress{Terms: []ProgressTerm{}}
+	termIdx := map[int64]int{}
+	// Turnaround is averaged over verdicts, not over series averages.
+	termSeconds := map[int64]float64{}
+	termGraded := map[int64]int64{}
+	var allSeconds float64
+	for _, s := range series {
+		i, ok := termIdx[s.TermID]
+		if !ok {
+			i = len(out.Terms)
+			termIdx[s.TermID] = i
+			out.Terms = append(out.Terms, ProgressTerm{
+				TermID:      s.TermID,
+				Kind:        s.TermKind,
+				Grade:       s.TermGrade,
+				DisplayName: TermDisplayName(s.TermKind, s.TermGrade),
+				IsActive:    s.TermIsActive,
+				Series:      []ProgressSeries{},
+			})
+		}
+		w := waits[s.SeriesID]
+		ps := ProgressSeries{
+			SeriesID:    s.SeriesID,
+			Number:      int(s.SeriesNumber),
+			DisplayName: SeriesDisplayName(int(s.SeriesNumber), s.SeriesName),
+			DueAt:       s.SeriesDueAt,
+			Subproblems: s.Subproblems,
+			Counts:      ProgressCounts{Accepted: s.Accepted, Rejected: s.Rejected, Pending: s.Pending},
+			Coffins:     ProgressCoffins{Total: s.Coffins, Solved: s.CoffinsSolved, Open: s.CoffinsOpen},
+			Turnaround:  turnaroundOf(w.Graded, w.TotalSeconds),
+		}
+		term := &out.Terms[i]
+		term.Series = append(term.Series, ps)
+		addProgress(&term.Counts, &term.Coffins, ps)
+		addProgress(&out.Counts, &out.Coffins, ps)
+		termSeconds[s.TermID] += w.TotalSeconds
+		termGraded[s.TermID] += w.Graded
+		allSeconds += w.TotalSeconds
+	}
+	var allGraded int64
+	for i := range out.Terms {
+		id := out.Terms[i].TermID
+		out.Terms[i].Turnaround = turnaroundOf(termGraded[id], termSeconds[id])
+		allGraded += termGraded[id]
+	}
+	out.Turnaround = turnaroundOf(allGraded, allSeconds)
+	out.Streak = progressStreak(series, now)
+	return out
+}
+
+func addProgress(c *ProgressCounts, k *ProgressCoffins, s ProgressSeries) {
+	c.Accepted += s.Counts.Accepted
+	c.Rejected += s.Counts.Rejected
+	c.Pending += s.Counts.Pending
+	k.Total += s.Coffins.Total
+	k.Solved += s.Coffins.Solved
+	k.Open += s.Coffins.Open
+}
+
+func turnaroundOf(graded int64, seconds float64) ProgressTurnaround {
+	if graded == 0 {
+		return ProgressTurnaround{}
+	}
+	avg := seconds / float64(graded)
+	return ProgressTurnaround{Graded: graded, AvgSeconds: &avg}
+}
+
+// progressStreak walks the series in deadline order. Series without
+// subproblems are skipped, as are ones still open with nothing accepted.
+func progressStreak(series []store.StudentProgressSeriesRow, now time.Time) ProgressStreak {
+	var st ProgressStreak
+	run := 0
+	for _, s := range series {
+		if s.Subproblems == 0 {
+			continue
+		}
+		switch {
+		case s.Accepted > 0:
+			run++
+		case now.Before(s.SeriesDueAt):
+			continue
+		default:
+			run = 0
+		}
+		if run > st.Longest {
+			st.Longest = run
+		}
+	}
+	st.Current = run
+	return st
+}
@@ -0,0 +1,51 @@
+package mathcenter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+func TestProgressStreak(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := func(days int) time.Time { return now.Add(-time.Duration(days) * 24 * time.Hour) }
+	row := func(due time.Time, subproblems, accepted int64) store.StudentProgressSeriesRow {
+		return store.StudentProgressSeriesRow{SeriesDueAt: due, Subproblems: subproblems, Accepted: accepted}
+	}
+	cases := []struct {
+		name   string
+		series []store.StudentProgressSeriesRow
+		want   ProgressStreak
+	}{
+		{"empty", nil, ProgressStreak{}},
+		{"broken then resumed", []store.StudentProgressSeriesRow{
+			row(past(50), 3, 1), row(past(40), 3, 2), row(past(30), 3, 1),
+			row(past(20), 3, 0), row(past(10), 3, 1),
+		}, ProgressStreak{Current: 1, Longest: 3}},
+		{"open series does not break", []store.StudentProgressSeriesRow{
+			row(past(20), 3, 1), row(past(10), 3, 1), row(now.Add(time.Hour), 3, 0),
+		}, ProgressStreak{Current: 2, Longest: 2}},
+		{"series without subproblems skipped", []store.StudentProgressSeriesRow{
+			row(past(20), 3, 1), row(past(15), 0, 0), row(past(10), 3, 1),
+		}, ProgressStreak{Current: 2, Longest: 2}},
+		{"missed last series", []store.StudentProgressSeriesRow{
+			row(past(20), 3, 1), row(past(10), 3, 0),
+		}, ProgressStreak{Current: 0, Longest: 1}},
+	}
+	for _, c := range cases {
+		if got := progressStreak(c.series, now); got != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBuildStudentProgress_EmptyHistory(t *testing.T) {
+	got := BuildStudentProgress(nil, nil, time.Now())
+	if got.Terms == nil || len(got.Terms) != 0 {
+		t.Errorf("terms = %#v, want empty slice", got.Terms)
+	}
+	if got.Turnaround.AvgSeconds != nil || got.Turnaround.Graded != 0 {
+		t.Errorf("turnaround = %+v, want zero", got.Turnaround)
+	}
+}
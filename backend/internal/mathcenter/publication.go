@@ -0,0 +1,135 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+// Reasons a разбор group cannot be published. The manual endpoint maps them
+// to HTTP errors; the publication scheduler records them on the schedule.
+var (
+	ErrSolutionTargetsMissing = errors.New("one or more subproblems not found")
+	ErrSolutionTargetsMixed   = errors.New("subproblems must belong to one center and series")
+	ErrSolutionNoMaterial     = errors.New("every subproblem needs at least one saved разбор format")
+	ErrSolutionTargetsChanged = errors.New("publication targets changed; retry")
+)
+
+// CheckSolutionTargets validates the rows LockSolutionPublicationTargets
+// returned for want distinct subproblems: all found, one center and series,
+// each with some saved material.
+func CheckSolutionTargets(targets []store.SolutionPublicationTarget, want int) error {
+	if len(targets) != want || want == 0 {
+		return ErrSolutionTargetsMissing
+	}
+	centerID, seriesID := targets[0].MathCenterID, targets[0].SeriesID
+	for _, target := range targets {
+		if target.MathCenterID != centerID || target.SeriesID != seriesID {
+			return ErrSolutionTargetsMixed
+		}
+	}
+	// Checked last so that a schedule, which only needs the material by the
+	// time it runs, can tell this case from the others.
+	for _, target := range targets {
+		if !target.HasMaterial {
+			return ErrSolutionNoMaterial
+		}
+	}
+	return nil
+}
+
+// CheckSolutionTex is the TeX publication gate for a разбор group: the
+// first target whose source has errors blocks the whole group.
+func CheckSolutionTex(targets []store.SolutionPublicationTarget) error {
+	for _, target := range targets {
+		if target.TexSource == nil {
+			continue
+		}
+		if err := CheckTexPublishable(*target.TexSource, nil); err != nil {
+			return fmt.Errorf("разбор of subproblem %d: %w", target.SubproblemID, err)
+		}
+	}
+	return nil
+}
+
+// CheckSeriesTex is the TeX publication gate for a series statement. The
+// problem headings are checked against the series' problems; a series
+// without a TeX statement passes.
+func CheckSeriesTex(ctx context.Context, q *store.Queries, seriesID int64, tex *string) error {
+	if tex == nil {
+		return nil
+	}
+	numbers, err := SeriesProblemNumbers(ctx, q, seriesID)
+	if err != nil {
+		return err
+	}
+	return CheckTexPublishable(*tex, numbers)
+}
+
+// SeriesProblemNumbers lists the numbers of the series' problems, never nil.
+func SeriesProblemNumbers(ctx context.Context, q *store.Queries, seriesID int64) ([]int32, error) {
+	problems, err := q.ListProblemsForSeries(ctx, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("listing problems: %w", err)
+	}
+	numbers := make([]int32, len(problems))
+	for i, p := range problems {
+		numbers[i] = p.Number
+	}
+	return numbers, nil
+}
+
+// PublishSolutionGroup publishes locked, checked targets as one shared
+// разбор at publishedAt and returns the coffins it released, sorted. It
+// keeps an existing shared group when all targets already point at the same
+// group; otherwise it mints one group and applies it to the whole set. The
+// latest TeX revision of each разбор is stamped as the one students were
+// shown. Run it in the transaction that locked the targets.
+func PublishSolutionGroup(ctx context.Context, q *store.Queries, targets []store.SolutionPublicationTarget, ids []int64, publishedAt time.Time) ([]int64, error) {
+	groupID, sameGroup := int64(0), true
+	for i, target := range targets {
+		if target.GroupID == nil {
+			sameGroup = false
+			break
+		}
+		if i == 0 {
+			groupID = *target.GroupID
+		} else if *target.GroupID != groupID {
+			sameGroup = false
+			break
+		}
+	}
+	if !sameGroup {
+		var err error
+		groupID, err = q.CreateSolutionGroup(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("creating solution group: %w", err)
+		}
+		if err := q.SetSubproblemSolutionGroup(ctx, store.SetSubproblemSolutionGroupParams{GroupID: groupID, SubproblemIds: ids}); err != nil {
+			return nil, fmt.Errorf("setting solution group: %w", err)
+		}
+	}
+
+	results, err := q.PublishSolutions(ctx, ids, publishedAt)
+	if err != nil {
+		return nil, fmt.Errorf("publishing solutions: %w", err)
+	}
+	if len(results) != len(ids) {
+		return nil, ErrSolutionTargetsChanged
+	}
+	if err := q.MarkSolutionTexRevisionsPublished(ctx, ids, publishedAt); err != nil {
+		return nil, fmt.Errorf("stamping published revisions: %w", err)
+	}
+	released := make([]int64, 0, len(results))
+	for _, result := range results {
+		if result.IsCoffin {
+			released = append(released, result.SubproblemID)
+		}
+	}
+	sort.Slice(released, func(i, j int) bool { return released[i] < released[j] })
+	return released, nil
+}
@@ -0,0 +1,114 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+// Teacher roles. Head is stored as math_center_teachers.is_head_teacher and
+// the rest in role; the API presents them as one field.
+const (
+	TeacherRoleHead     = "head"
+	TeacherRoleTeacher  = "teacher"
+	TeacherRoleGrader   = "grader"
+	TeacherRoleEditor   = "editor"
+	TeacherRoleObserver = "observer"
+)
+
+// ValidTeacherRole reports whether role can be assigned to a teacher.
+func ValidTeacherRole(role string) bool {
+	switch role {
+	case TeacherRoleHead, TeacherRoleTeacher, TeacherRoleGrader, TeacherRoleEditor, TeacherRoleObserver:
+		return true
+	}
+	return false
+}
+
+// EffectiveTeacherRole folds the head flag into the stored role.
+func EffectiveTeacherRole(isHeadTeacher bool, role string) string {
+	if isHeadTeacher {
+		return TeacherRoleHead
+	}
+	return role
+}
+
+// TeacherCapability is a class of teacher action checked by the handlers.
+type TeacherCapability int
+
+const (
+	// CanView covers every teacher-facing read: grids, queues, stats.
+	CanView TeacherCapability = iota
+	// CanGrade covers claiming, grading and retracting threads, offline
+	// grading, thread and student notes, and attendance marks.
+	CanGrade
+	// CanEditContent covers series, likbez, solutions, the problem bank and
+	// publication schedules.
+	CanEditContent
+	// CanManage covers rosters, groups, terms, invitations, the timetable
+	// and Google Sheets links.
+	CanManage
+	// CanLead is head-only: role assignment and center-wide oversight.
+	CanLead
+)
+
+// TeacherCan reports whether a teacher with access may perform an action of
+// class c. A term-scoped assistant outside their term may do nothing.
+func TeacherCan(access store.TeacherAccess, c TeacherCapability) bool {
+	if !access.InScope {
+		return false
+	}
+	if access.IsHeadTeacher {
+		return true
+	}
+	switch c {
+	case CanView:
+		return true
+	case CanGrade:
+		return access.Role == TeacherRoleTeacher || access.Role == TeacherRoleGrader
+	case CanEditContent:
+		return access.Role == TeacherRoleTeacher || access.Role == TeacherRoleEditor
+	case CanManage:
+		return access.Role == TeacherRoleTeacher
+	}
+	return false
+}
+
+// AuthorizeTeacher refusals; their text is shown to the caller as is.
+var (
+	ErrNotTeacher         = errors.New("not a teacher of this center")
+	ErrTeacherRoleForbids = errors.New("your teacher role does not allow this")
+	ErrTeacherOutsideTerm = errors.New("your teacher role is limited to another term")
+)
+
+// AuthorizeTeacher loads userID's role in the center and checks it allows c.
+// A non-zero seriesID also keeps a term-scoped assistant to the series of
+// their term. It is the one teacher check behind every handler package;
+// admins are let through by the handlers before it is called.
+func AuthorizeTeacher(ctx context.Context, q *store.Queries, userID, centerID, seriesID int64, c TeacherCapability) (store.TeacherAccess, error) {
+	access, err := q.GetTeacherAccess(ctx, userID, centerID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !access.InScope) {
+		return store.TeacherAccess{}, ErrNotTeacher
+	}
+	if err != nil {
+		return store.TeacherAccess{}, fmt.Errorf("teacher access: %w", err)
+	}
+	if !TeacherCan(access, c) {
+		return store.TeacherAccess{}, ErrTeacherRoleForbids
+	}
+	if seriesID == 0 || access.TermID == nil {
+		return access, nil
+	}
+	inTerm, err := q.SeriesInTerm(ctx, seriesID, *access.TermID)
+	if err != nil {
+		return store.TeacherAccess{}, fmt.Errorf("teacher term scope: %w", err)
+	}
+	if !inTerm {
+		return store.TeacherAccess{}, ErrTeacherOutsideTerm
+	}
+	return access, nil
+}
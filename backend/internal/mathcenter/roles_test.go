@@ -0,0 +1,101 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+func TestTeacherCan(t *testing.T) {
+	t.Parallel()
+	all := []TeacherCapability{CanView, CanGrade, CanEditContent, CanManage, CanLead}
+	cases := []struct {
+		name   string
+		access store.TeacherAccess
+		want   []bool
+	}{
+		{"head", store.TeacherAccess{IsHeadTeacher: true, Role: TeacherRoleTeacher, InScope: true}, []bool{true, true, true, true, true}},
+		{"teacher", store.TeacherAccess{Role: TeacherRoleTeacher, InScope: true}, []bool{true, true, true, true, false}},
+		{"grader", store.TeacherAccess{Role: TeacherRoleGrader, InScope: true}, []bool{true, true, false, false, false}},
+		{"editor", store.TeacherAccess{Role: TeacherRoleEditor, InScope: true}, []bool{true, false, true, false, false}},
+		{"observer", store.TeacherAccess{Role: TeacherRoleObserver, InScope: true}, []bool{true, false, false, false, false}},
+		{"out of term", store.TeacherAccess{Role: TeacherRoleTeacher}, []bool{false, false, false, false, false}},
+	}
+	for _, tc := range cases {
+		for i, c := range all {
+			if got := TeacherCan(tc.access, c); got != tc.want[i] {
+				t.Errorf("%s: TeacherCan(%d) = %v, want %v", tc.name, c, got, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestValidTeacherRole(t *testing.T) {
+	t.Parallel()
+	for _, role := range []string{TeacherRoleHead, TeacherRoleTeacher, TeacherRoleGrader, TeacherRoleEditor, TeacherRoleObserver} {
+		if !ValidTeacherRole(role) {
+			t.Errorf("ValidTeacherRole(%q) = false", role)
+		}
+	}
+	for _, role := range []string{"", "admin", "Head"} {
+		if ValidTeacherRole(role) {
+			t.Errorf("ValidTeacherRole(%q) = true", role)
+		}
+	}
+	if got := EffectiveTeacherRole(true, TeacherRoleGrader); got != TeacherRoleHead {
+		t.Errorf("EffectiveTeacherRole(head) = %q", got)
+	}
+}
+
+func TestAuthorizeTeacher(t *testing.T) {
+	t.Parallel()
+	columns := []string{"id", "is_head_teacher", "role", "term_id", "in_scope"}
+	term := int64(70)
+	yes, no := true, false
+	cases := []struct {
+		name     string
+		row      []any // nil: not a teacher
+		seriesID int64
+		// inTerm, when set, is SeriesInTerm's answer.
+		inTerm *bool
+		c      TeacherCapability
+		want   error
+	}{
+		{"not a teacher", nil, 0, nil, CanView, ErrNotTeacher},
+		{"term over", []any{int64(1), false, TeacherRoleGrader, &term, false}, 0, nil, CanView, ErrNotTeacher},
+		{"role forbids", []any{int64(1), false, TeacherRoleObserver, (*int64)(nil), true}, 0, nil, CanGrade, ErrTeacherRoleForbids},
+		{"view in own term", []any{int64(1), false, TeacherRoleGrader, &term, true}, 100, &yes, CanView, nil},
+		{"view of another term", []any{int64(1), false, TeacherRoleGrader, &term, true}, 100, &no, CanView, ErrTeacherOutsideTerm},
+		{"unscoped teacher", []any{int64(1), false, TeacherRoleTeacher, (*int64)(nil), true}, 100, nil, CanEditContent, nil},
+	}
+	for _, tc := range cases {
+		mock, err := pgxmock.NewPool()
+		if err != nil {
+			t.Fatalf("new pool: %v", err)
+		}
+		eq := mock.ExpectQuery(`FROM math_center_teachers t`).WithArgs(int64(3), int64(42))
+		if tc.row == nil {
+			eq.WillReturnError(pgx.ErrNoRows)
+		} else {
+			eq.WillReturnRows(mock.NewRows(columns).AddRow(tc.row...))
+		}
+		if tc.inTerm != nil {
+			mock.ExpectQuery(`FROM math_center_series WHERE id = \$1 AND term_id = \$2`).
+				WithArgs(tc.seriesID, term).
+				WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(*tc.inTerm))
+		}
+		_, err = AuthorizeTeacher(context.Background(), store.New(mock), 3, 42, tc.seriesID, tc.c)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		mock.Close()
+	}
+}
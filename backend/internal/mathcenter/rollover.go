@@ -0,0 +1,407 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+var (
+	ErrNoNextTerm             = errors.New("the cohort has no further math center term")
+	ErrNextTermUnknown        = errors.New("a center without term history needs an explicit next term")
+	ErrTermOutOfSequence      = errors.New("term does not follow the cohort sequence")
+	ErrRolloverUnknownGroup   = errors.New("group does not belong to the current term")
+	ErrRolloverUnknownStudent = errors.New("student is not enrolled in the current term")
+	ErrRolloverGroupName      = errors.New("invalid group name")
+	ErrRolloverDuplicateGroup = errors.New("duplicate group name in the next term")
+)
+
+// maxGroupNameLen matches the limit on groups created from the manage view.
+const maxGroupNameLen = 50
+
+// TermAtStage is the inverse of TermStage.
+func TermAtStage(stage int) (kind string, grade int32, ok bool) {
+	if stage < 1 {
+		return "", 0, false
+	}
+	grade = int32(5 + (stage-1)/2)
+	kind = TermKindAcademic
+	if stage%2 == 0 {
+		kind = TermKindCamp
+	}
+	if s, valid := TermStage(kind, grade); !valid || s != stage {
+		return "", 0, false
+	}
+	return kind, grade, true
+}
+
+// LatestTermStage returns the highest stage among a center's normal terms,
+// or zero for a legacy-only center.
+func LatestTermStage(terms []store.MathCenterTerm) int {
+	last := 0
+	for _, term := range terms {
+		if term.Kind == TermKindLegacy || term.Grade == nil {
+			continue
+		}
+		if stage, ok := TermStage(term.Kind, *term.Grade); ok && stage > last {
+			last = stage
+		}
+	}
+	return last
+}
+
+// RolloverGroup copies a group of the current term into the next one,
+// optionally under a new name ("9-1" becoming "10-1").
+type RolloverGroup struct {
+	SourceGroupID int64
+	Name          string
+}
+
+// RolloverStudent carries one student into the next term. A nil
+// SourceGroupID, or one whose group is not carried, places the student in
+// the unassigned group.
+type RolloverStudent struct {
+	UserID        int64
+	SourceGroupID *int64
+}
+
+// RolloverOptions selects what the next term inherits. An empty Kind asks
+// for the stage after the cohort's latest term. Nil Groups copies every
+// group under its own name; nil Students carries every enrolled student,
+// into the copy of their own group when KeepGroups is set and into the
+// unassigned group otherwise.
+type RolloverOptions struct {
+	Kind       string
+	Grade      int32
+	Groups     []RolloverGroup
+	Students   []RolloverStudent
+	KeepGroups bool
+	DryRun     bool
+}
+
+// RolloverPlacement is where a carried student lands. GroupName is
+// UnassignedGroupName when the student was not placed.
+type RolloverPlacement struct {
+	UserID        int64
+	SourceGroupID *int64
+	GroupName     string
+}
+
+// RolloverPlan is the validated outcome of RolloverOptions against the
+// current term's roster. LeftBehind students stay in the archived term only.
+type RolloverPlan struct {
+	Groups     []RolloverGroup
+	Placements []RolloverPlacement
+	LeftBehind []int64
+}
+
+// PlanRollover resolves opts against the groups and students of the term
+// being archived.
+func PlanRollover(groups []store.ListGroupsForTermRow, students []store.ListStudentsForTermRow, opts RolloverOptions) (RolloverPlan, error) {
+	sourceGroups := make(map[int64]string, len(groups))
+	for _, g := range groups {
+		sourceGroups[g.ID] = g.Name
+	}
+
+	var plan RolloverPlan
+	selected := opts.Groups
+	if selected == nil {
+		for _, g := range groups {
+			if !IsUnassignedGroupName(g.Name) {
+				selected = append(selected, RolloverGroup{SourceGroupID: g.ID, Name: g.Name})
+			}
+		}
+	}
+	targetName := make(map[int64]string, len(selected))
+	taken := make(map[string]bool, len(selected))
+	for _, g := range selected {
+		sourceName, ok := sourceGroups[g.SourceGroupID]
+		if !ok || IsUnassignedGroupName(sourceName) {
+			return RolloverPlan{}, fmt.Errorf("%w: %d", ErrRolloverUnknownGroup, g.SourceGroupID)
+		}
+		if _, dup := targetName[g.SourceGroupID]; dup {
+			return RolloverPlan{}, fmt.Errorf("%w: group %d listed twice", ErrRolloverDuplicateGroup, g.SourceGroupID)
+		}
+		name := strings.TrimSpace(g.Name)
+		if name == "" {
+			name = sourceName
+		}
+		if len(name) > maxGroupNameLen {
+			return RolloverPlan{}, fmt.Errorf("%w: %q", ErrRolloverGroupName, name)
+		}
+		if IsUnassignedGroupName(name) {
+			return RolloverPlan{}, ErrReservedGroupName
+		}
+		if taken[name] {
+			return RolloverPlan{}, fmt.Errorf("%w: %q", ErrRolloverDuplicateGroup, name)
+		}
+		taken[name] = true
+		targetName[g.SourceGroupID] = name
+		plan.Groups = append(plan.Groups, RolloverGroup{SourceGroupID: g.SourceGroupID, Name: name})
+	}
+
+	place := func(userID int64, sourceGroupID *int64) {
+		p := RolloverPlacement{UserID: userID, GroupName: UnassignedGroupName}
+		if sourceGroupID != nil {
+			if name, ok := targetName[*sourceGroupID]; ok {
+				p.SourceGroupID, p.GroupName = sourceGroupID, name
+			}
+		}
+		plan.Placements = append(plan.Placements, p)
+	}
+
+	enrolled := make(map[int64]bool, len(students))
+	for _, s := range students {
+		enrolled[s.UserID] = true
+	}
+	if opts.Students == nil {
+		for _, s := range students {
+			var source *int64
+			if opts.KeepGroups {
+				id := s.GroupID
+				source = &id
+			}
+			place(s.UserID, source)
+		}
+		return plan, nil
+	}
+
+	carried := make(map[int64]bool, len(opts.Students))
+	for _, s := range opts.Students {
+		if !enrolled[s.UserID] {
+			return RolloverPlan{}, fmt.Errorf("%w: %d", ErrRolloverUnknownStudent, s.UserID)
+		}
+		if carried[s.UserID] {
+			continue
+		}
+		if s.SourceGroupID != nil {
+			if _, ok := targetName[*s.SourceGroupID]; !ok {
+				return RolloverPlan{}, fmt.Errorf("%w: %d", ErrRolloverUnknownGroup, *s.SourceGroupID)
+			}
+		}
+		carried[s.UserID] = true
+		place(s.UserID, s.SourceGroupID)
+	}
+	for _, s := range students {
+		if !carried[s.UserID] {
+			plan.LeftBehind = append(plan.LeftBehind, s.UserID)
+		}
+	}
+	return plan, nil
+}
+
+// RolloverGroupReport is one group of the new term.
+type RolloverGroupReport struct {
+	GroupID       int64  `json:"group_id"`
+	SourceGroupID *int64 `json:"source_group_id"`
+	Name          string `json:"name"`
+	Students      int    `json:"students"`
+}
+
+// RolloverStudentReport is one student of the archived term.
+type RolloverStudentReport struct {
+	UserID    int64   `json:"user_id"`
+	Name      string  `json:"name"`
+	FromGroup string  `json:"from_group"`
+	ToGroup   *string `json:"to_group"`
+}
+
+// RolloverTeacherReport is one teacher membership. Teachers belong to the
+// center, so they continue into the new term, except assistants scoped to
+// the archived term: they lose access with it.
+type RolloverTeacherReport struct {
+	UserID        int64  `json:"user_id"`
+	Name          string `json:"name"`
+	IsHeadTeacher bool   `json:"is_head_teacher"`
+}
+
+// RolloverReport describes a finished or previewed rollover.
+type RolloverReport struct {
+	DryRun       bool
+	Term         store.MathCenterTerm
+	ArchivedTerm *store.MathCenterTerm
+	Groups       []RolloverGroupReport
+	Carried      []RolloverStudentReport
+	LeftBehind   []RolloverStudentReport
+	Teachers     []RolloverTeacherReport
+	// TeachersLeaving are the assistants scoped to the archived term. A head
+	// teacher re-scopes them from the roles panel to keep them.
+	TeachersLeaving []RolloverTeacherReport
+}
+
+// RolloverTerm archives the current term and creates the next one in one
+// transaction: the new term gets the selected groups with their razbor
+// defaults, the selected students with their own razbor defaults, and the
+// protected unassigned group. A dry run performs the same writes and rolls
+// them back, so the preview reports exactly what a real run would do.
+func RolloverTerm(ctx context.Context, pool db.Pool, centerID int64, opts RolloverOptions) (RolloverReport, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return RolloverReport{}, fmt.Errorf("beginning term rollover: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Serialize rollovers of one center so two head teachers cannot both
+	// archive the same term.
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM math_centers WHERE id = $1 FOR UPDATE`, centerID); err != nil {
+		return RolloverReport{}, fmt.Errorf("locking math center: %w", err)
+	}
+	q := store.New(tx)
+	terms, err := q.ListTermsForCenter(ctx, centerID)
+	if err != nil {
+		return RolloverReport{}, fmt.Errorf("listing terms: %w", err)
+	}
+	kind, grade := opts.Kind, opts.Grade
+	last := LatestTermStage(terms)
+	if kind == "" {
+		if last == 0 {
+			return RolloverReport{}, ErrNextTermUnknown
+		}
+		var ok bool
+		if kind, grade, ok = TermAtStage(last + 1); !ok {
+			return RolloverReport{}, ErrNoNextTerm
+		}
+	}
+	requested, valid := TermStage(kind, grade)
+	if !valid {
+		return RolloverReport{}, ErrInvalidInitialTerm
+	}
+	// A legacy-only center begins its live history at any grade, as in
+	// CreateTerm; afterwards terms are strictly sequential.
+	if last != 0 && requested != last+1 {
+		return RolloverReport{}, ErrTermOutOfSequence
+	}
+
+	var source *store.MathCenterTerm
+	for i := range terms {
+		if terms[i].IsActive {
+			source = &terms[i]
+			break
+		}
+	}
+	if source == nil {
+		for i := range terms {
+			if terms[i].Kind == TermKindLegacy {
+				source = &terms[i]
+				break
+			}
+		}
+	}
+	var (
+		groups   []store.ListGroupsForTermRow
+		students []store.ListStudentsForTermRow
+	)
+	if source != nil {
+		if groups, err = q.ListGroupsForTerm(ctx, source.ID); err != nil {
+			return RolloverReport{}, fmt.Errorf("listing groups: %w", err)
+		}
+		if students, err = q.ListStudentsForTerm(ctx, source.ID); err != nil {
+			return RolloverReport{}, fmt.Errorf("listing students: %w", err)
+		}
+	}
+	plan, err := PlanRollover(groups, students, opts)
+	if err != nil {
+		return RolloverReport{}, err
+	}
+
+	if err := q.ArchiveActiveTermsForCenter(ctx, centerID); err != nil {
+		return RolloverReport{}, fmt.Errorf("archiving active term: %w", err)
+	}
+	term, err := q.CreateMathCenterTerm(ctx, store.CreateMathCenterTermParams{
+		MathCenterID: centerID,
+		Kind:         kind,
+		Grade:        &grade,
+	})
+	if err != nil {
+		return RolloverReport{}, fmt.Errorf("creating term: %w", err)
+	}
+	report := RolloverReport{DryRun: opts.DryRun, Term: term}
+	if source != nil && source.IsActive {
+		archived := *source
+		archived.IsActive = false
+		report.ArchivedTerm = &archived
+	}
+
+	unassigned, err := q.CreateMathCenterGroupForTerm(ctx, store.CreateMathCenterGroupForTermParams{
+		ID: term.ID, Name: UnassignedGroupName,
+	})
+	if err != nil {
+		return RolloverReport{}, fmt.Errorf("creating unassigned group: %w", err)
+	}
+	groupIDs := map[string]int64{UnassignedGroupName: unassigned.ID}
+	groupIndex := map[string]int{UnassignedGroupName: 0}
+	report.Groups = append(report.Groups, RolloverGroupReport{GroupID: unassigned.ID, Name: UnassignedGroupName})
+	for _, g := range plan.Groups {
+		id, err := q.CopyGroupToTerm(ctx, g.SourceGroupID, term.ID, g.Name)
+		if err != nil {
+			return RolloverReport{}, fmt.Errorf("copying group %d: %w", g.SourceGroupID, err)
+		}
+		sourceID := g.SourceGroupID
+		groupIDs[g.Name] = id
+		groupIndex[g.Name] = len(report.Groups)
+		report.Groups = append(report.Groups, RolloverGroupReport{GroupID: id, SourceGroupID: &sourceID, Name: g.Name})
+	}
+
+	if source != nil {
+		byUser := make(map[int64]store.ListStudentsForTermRow, len(students))
+		for _, s := range students {
+			byUser[s.UserID] = s
+		}
+		studentReport := func(userID int64) RolloverStudentReport {
+			s := byUser[userID]
+			return RolloverStudentReport{
+				UserID:    userID,
+				Name:      StudentDisplayName(s.FirstName, s.LastName),
+				FromGroup: s.GroupName,
+			}
+		}
+		for _, p := range plan.Placements {
+			inserted, err := q.CarryStudentToTerm(ctx, source.ID, p.UserID, groupIDs[p.GroupName])
+			if err != nil {
+				return RolloverReport{}, fmt.Errorf("carrying student %d: %w", p.UserID, err)
+			}
+			if !inserted {
+				continue
+			}
+			entry := studentReport(p.UserID)
+			to := p.GroupName
+			entry.ToGroup = &to
+			report.Carried = append(report.Carried, entry)
+			report.Groups[groupIndex[p.GroupName]].Students++
+		}
+		for _, userID := range plan.LeftBehind {
+			report.LeftBehind = append(report.LeftBehind, studentReport(userID))
+		}
+	}
+
+	teachers, err := q.ListRolloverTeachers(ctx, centerID)
+	if err != nil {
+		return RolloverReport{}, fmt.Errorf("listing teachers: %w", err)
+	}
+	for _, t := range teachers {
+		entry := RolloverTeacherReport{
+			UserID:        t.UserID,
+			Name:          DisplayName(t.FirstName, t.LastName),
+			IsHeadTeacher: t.IsHeadTeacher,
+		}
+		switch {
+		case t.TermID == nil:
+			report.Teachers = append(report.Teachers, entry)
+		case report.ArchivedTerm != nil && *t.TermID == report.ArchivedTerm.ID:
+			report.TeachersLeaving = append(report.TeachersLeaving, entry)
+		}
+		// Assistants of an older term lost access when it was archived.
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return RolloverReport{}, fmt.Errorf("committing term rollover: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,221 @@
+package mathcenter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/Alarion239/my239/backend/internal/store"
+)
+
+func TestTermAtStageInvertsTermStage(t *testing.T) {
+	t.Parallel()
+	for grade := int32(5); grade <= 11; grade++ {
+		for _, kind := range []string{TermKindAcademic, TermKindCamp} {
+			stage, ok := TermStage(kind, grade)
+			if !ok {
+				continue
+			}
+			gotKind, gotGrade, ok := TermAtStage(stage)
+			if !ok || gotKind != kind || gotGrade != grade {
+				t.Errorf("TermAtStage(%d) = %q %d %v, want %q %d", stage, gotKind, gotGrade, ok, kind, grade)
+			}
+		}
+	}
+	for _, stage := range []int{0, 14, 15} {
+		if _, _, ok := TermAtStage(stage); ok {
+			t.Errorf("TermAtStage(%d) should be invalid", stage)
+		}
+	}
+}
+
+func TestLatestTermStage(t *testing.T) {
+	t.Parallel()
+	nine, ten := int32(9), int32(10)
+	terms := []store.MathCenterTerm{
+		{Kind: TermKindLegacy},
+		{Kind: TermKindAcademic, Grade: &ten},
+		{Kind: TermKindCamp, Grade: &nine},
+	}
+	if got := LatestTermStage(terms); got != 11 {
+		t.Errorf("LatestTermStage = %d, want 11", got)
+	}
+	if got := LatestTermStage(terms[:1]); got != 0 {
+		t.Errorf("legacy-only LatestTermStage = %d, want 0", got)
+	}
+}
+
+func rolloverFixture() ([]store.ListGroupsForTermRow, []store.ListStudentsForTermRow) {
+	groups := []store.ListGroupsForTermRow{
+		{ID: 1, Name: "9-1"},
+		{ID: 2, Name: "9-2"},
+		{ID: 3, Name: UnassignedGroupName},
+	}
+	students := []store.ListStudentsForTermRow{
+		{UserID: 10, GroupID: 1, GroupName: "9-1"},
+		{UserID: 11, GroupID: 2, GroupName: "9-2"},
+		{UserID: 12, GroupID: 3, GroupName: UnassignedGroupName},
+	}
+	return groups, students
+}
+
+func TestPlanRolloverDefaults(t *testing.T) {
+	t.Parallel()
+	groups, students := rolloverFixture()
+
+	plan, err := PlanRollover(groups, students, RolloverOptions{})
+	if err != nil {
+		t.Fatalf("PlanRollover: %v", err)
+	}
+	if len(plan.Groups) != 2 || plan.Groups[0].Name != "9-1" || plan.Groups[1].Name != "9-2" {
+		t.Errorf("groups = %+v, want both named groups", plan.Groups)
+	}
+	for _, p := range plan.Placements {
+		if p.GroupName != UnassignedGroupName {
+			t.Errorf("student %d placed in %q without keep_groups", p.UserID, p.GroupName)
+		}
+	}
+
+	plan, err = PlanRollover(groups, students, RolloverOptions{KeepGroups: true})
+	if err != nil {
+		t.Fatalf("PlanRollover keep: %v", err)
+	}
+	want := map[int64]string{10: "9-1", 11: "9-2", 12: UnassignedGroupName}
+	for _, p := range plan.Placements {
+		if want[p.UserID] != p.GroupName {
+			t.Errorf("student %d placed in %q, want %q", p.UserID, p.GroupName, want[p.UserID])
+		}
+	}
+	if len(plan.LeftBehind) != 0 {
+		t.Errorf("left behind = %v, want none", plan.LeftBehind)
+	}
+}
+
+func TestPlanRolloverSelection(t *testing.T) {
+	t.Parallel()
+	groups, students := rolloverFixture()
+	one := int64(1)
+
+	plan, err := PlanRollover(groups, students, RolloverOptions{
+		Groups:   []RolloverGroup{{SourceGroupID: 1, Name: " 10-1 "}},
+		Students: []RolloverStudent{{UserID: 10, SourceGroupID: &one}, {UserID: 11}},
+	})
+	if err != nil {
+		t.Fatalf("PlanRollover: %v", err)
+	}
+	if len(plan.Groups) != 1 || plan.Groups[0].Name != "10-1" {
+		t.Errorf("groups = %+v, want renamed 10-1 only", plan.Groups)
+	}
+	if len(plan.Placements) != 2 || plan.Placements[0].GroupName != "10-1" || plan.Placements[1].GroupName != UnassignedGroupName {
+		t.Errorf("placements = %+v", plan.Placements)
+	}
+	if len(plan.LeftBehind) != 1 || plan.LeftBehind[0] != 12 {
+		t.Errorf("left behind = %v, want [12]", plan.LeftBehind)
+	}
+}
+
+func TestPlanRolloverRejects(t *testing.T) {
+	t.Parallel()
+	groups, students := rolloverFixture()
+	two := int64(2)
+	cases := []struct {
+		name string
+		opts RolloverOptions
+		want error
+	}{
+		{"foreign group", RolloverOptions{Groups: []RolloverGroup{{SourceGroupID: 99}}}, ErrRolloverUnknownGroup},
+		{"unassigned group", RolloverOptions{Groups: []RolloverGroup{{SourceGroupID: 3}}}, ErrRolloverUnknownGroup},
+		{"reserved name", RolloverOptions{Groups: []RolloverGroup{{SourceGroupID: 1, Name: UnassignedGroupName}}}, ErrReservedGroupName},
+		{"duplicate name", RolloverOptions{Groups: []RolloverGroup{{SourceGroupID: 1, Name: "А"}, {SourceGroupID: 2, Name: "А"}}}, ErrRolloverDuplicateGroup},
+		{"foreign student", RolloverOptions{Students: []RolloverStudent{{UserID: 99}}}, ErrRolloverUnknownStudent},
+		{"uncarried group", RolloverOptions{
+			Groups:   []RolloverGroup{{SourceGroupID: 1}},
+			Students: []RolloverStudent{{UserID: 11, SourceGroupID: &two}},
+		}, ErrRolloverUnknownGroup},
+	}
+	for _, tc := range cases {
+		if _, err := PlanRollover(groups, students, tc.opts); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestRolloverTermDryRunRollsBack(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	defer mock.Close()
+	now := time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)
+	nine := int32(9)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`FROM math_centers WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(42)).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery(`FROM math_center_terms\s+WHERE math_center_id = \$1\s+ORDER BY`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows(bootstrapTermColumns).
+			AddRow(int64(70), int64(42), TermKindAcademic, &nine, true, now, (*time.Time)(nil)))
+	mock.ExpectQuery(`FROM math_center_groups\s+WHERE term_id = \$1`).
+		WithArgs(int64(70)).
+		WillReturnRows(mock.NewRows(bootstrapGroupColumns).
+			AddRow(int64(1), int64(42), "9-1", now, int64(70)).
+			AddRow(int64(3), int64(42), UnassignedGroupName, now, int64(70)))
+	mock.ExpectQuery(`FROM math_center_students s`).
+		WithArgs(int64(70)).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "group_id", "term_id", "group_name", "first_name", "middle_name", "last_name"}).
+			AddRow(int64(500), int64(10), int64(1), int64(70), "9-1", "Аня", (*string)(nil), "Иванова"))
+	mock.ExpectExec(`UPDATE math_center_terms`).
+		WithArgs(int64(42)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(`INSERT INTO math_center_terms`).
+		WithArgs(int64(42), TermKindCamp, pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows(bootstrapTermColumns).
+			AddRow(int64(71), int64(42), TermKindCamp, &nine, true, now, (*time.Time)(nil)))
+	mock.ExpectQuery(`INSERT INTO math_center_groups`).
+		WithArgs(int64(71), UnassignedGroupName).
+		WillReturnRows(mock.NewRows(bootstrapGroupColumns).
+			AddRow(int64(80), int64(42), UnassignedGroupName, now, int64(71)))
+	mock.ExpectQuery(`INSERT INTO math_center_groups`).
+		WithArgs(int64(1), int64(71), "9-1").
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(81)))
+	mock.ExpectExec(`INSERT INTO math_center_students`).
+		WithArgs(int64(70), int64(10), int64(81)).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	oldTerm, archivedTerm := int64(60), int64(70)
+	mock.ExpectQuery(`FROM math_center_teachers t`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows([]string{"user_id", "is_head_teacher", "term_id", "first_name", "last_name"}).
+			AddRow(int64(3), true, (*int64)(nil), "Пётр", "Сидоров").
+			AddRow(int64(4), false, &archivedTerm, "Ольга", "Лагерная").
+			AddRow(int64(5), false, &oldTerm, "Иван", "Прошлогодний"))
+	mock.ExpectRollback()
+
+	report, err := RolloverTerm(context.Background(), mock, 42, RolloverOptions{KeepGroups: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("RolloverTerm: %v", err)
+	}
+	if !report.DryRun || report.Term.Kind != TermKindCamp || report.ArchivedTerm == nil || report.ArchivedTerm.ID != 70 {
+		t.Errorf("report terms = %+v / %+v", report.Term, report.ArchivedTerm)
+	}
+	if len(report.Carried) != 1 || report.Carried[0].Name != "Иванова Аня" || *report.Carried[0].ToGroup != "9-1" {
+		t.Errorf("carried = %+v", report.Carried)
+	}
+	if len(report.Groups) != 2 || report.Groups[1].Students != 1 {
+		t.Errorf("groups = %+v", report.Groups)
+	}
+	if len(report.Teachers) != 1 || !report.Teachers[0].IsHeadTeacher {
+		t.Errorf("teachers = %+v", report.Teachers)
+	}
+	if len(report.TeachersLeaving) != 1 || report.TeachersLeaving[0].UserID != 4 {
+		t.Errorf("assistant of the archived term not reported as leaving: %+v", report.TeachersLeaving)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package mathcenter
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// TeX documents reference their uploaded pictures by name, as in
+// \includegraphics[width=5cm]{graph.png}. The source keeps the name; when a
+// document is served, ResolveTexAssets swaps each known name for a signed
+// URL the browser can load.
+
+const (
+	// MaxTexAssetBytes caps one uploaded picture.
+	MaxTexAssetBytes int64 = 2 << 20
+	// MaxTexAssetsPerDocument caps the pictures of one document.
+	MaxTexAssetsPerDocument = 50
+)
+
+var texAssetNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// texAssetTypes maps the accepted extensions to their content types, in
+// the order an extensionless reference tries them.
+var texAssetTypes = []struct{ ext, contentType string }{
+	{".png", "image/png"},
+	{".svg", "image/svg+xml"},
+	{".jpg", "image/jpeg"},
+	{".jpeg", "image/jpeg"},
+}
+
+// TexAssetContentType validates an asset name and returns the content type
+// its extension implies; ok is false for a bad name or another extension.
+func TexAssetContentType(name string) (contentType string, ok bool) {
+	if !texAssetNamePattern.MatchString(name) {
+		return "", false
+	}
+	ext := strings.ToLower(path.Ext(name))
+	for _, t := range texAssetTypes {
+		if t.ext == ext {
+			return t.contentType, true
+		}
+	}
+	return "", false
+}
+
+var includeGraphicsPattern = regexp.MustCompile(`(\\includegraphics\*?\s*(?:\[[^\]]*\]\s*)?\{)([^{}]*)(\})`)
+
+// ResolveTexAssets replaces the file argument of every \includegraphics
+// that names an asset with that asset's URL. urls is keyed by asset name;
+// a reference without an extension matches name.png, .svg, .jpg or .jpeg.
+// Unknown names are left alone.
+func ResolveTexAssets(src string, urls map[string]string) string {
+	if len(urls) == 0 {
+		return src
+	}
+	return includeGraphicsPattern.ReplaceAllStringFunc(src, func(m string) string {
+		parts := includeGraphicsPattern.FindStringSubmatch(m)
+		name := strings.TrimSpace(parts[2])
+		url, ok := urls[name]
+		if !ok && path.Ext(name) == "" {
+			for _, t := range texAssetTypes {
+				if url, ok = urls[name+t.ext]; ok {
+					break
+				}
+			}
+		}
+		if !ok {
+			return m
+		}
+		return parts[1] + url + parts[3]
+	})
+}
@@ -0,0 +1,47 @@
+package mathcenter
+
+import "testing"
+
+func TestTexAssetContentType(t *testing.T) {
+	cases := []struct {
+		name, want string
+		ok         bool
+	}{
+		{"graph.png", "image/png", true},
+		{"Fig_2-a.JPG", "image/jpeg", true},
+		{"tree.svg", "image/svg+xml", true},
+		{"photo.jpeg", "image/jpeg", true},
+		{"notes.pdf", "", false},
+		{"graph", "", false},
+		{".hidden.png", "", false},
+		{"../up.png", "", false},
+		{"с кириллицей.png", "", false},
+	}
+	for _, c := range cases {
+		got, ok := TexAssetContentType(c.name)
+		if got != c.want || ok != c.ok {
+			t.Errorf("TexAssetContentType(%q) = %q, %v; want %q, %v", c.name, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestResolveTexAssets(t *testing.T) {
+	urls := map[string]string{
+		"graph.png": "https://s3/graph?sig=1",
+		"tree.svg":  "https://s3/tree?sig=2",
+	}
+	src := `\includegraphics[width=5cm]{graph.png}
+\includegraphics{ tree }
+\includegraphics*{missing.png}
+\includegraphics {graph.png}`
+	want := `\includegraphics[width=5cm]{https://s3/graph?sig=1}
+\includegraphics{https://s3/tree?sig=2}
+\includegraphics*{missing.png}
+\includegraphics {https://s3/graph?sig=1}`
+	if got := ResolveTexAssets(src, urls); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := ResolveTexAssets(src, nil); got != src {
+		t.Errorf("no assets must leave the source alone, got %s", got)
+	}
+}
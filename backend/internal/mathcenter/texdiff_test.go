@@ -0,0 +1,88 @@
+package mathcenter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff_Identical(t *testing.T) {
+	if got := UnifiedDiff("a", "b", "x\ny\n", "x\ny\n"); got != "" {
+		t.Errorf("identical inputs: got %q, want empty", got)
+	}
+}
+
+func TestUnifiedDiff_SingleChange(t *testing.T) {
+	a := "\\documentclass{article}\n\\begin{document}\nЗадача 1.\n$x^2$\n\\end{document}\n"
+	b := "\\documentclass{article}\n\\begin{document}\nЗадача 1.\n$x^3$\n\\end{document}\n"
+	want := "--- r1\n+++ r2\n" +
+		"@@ -1,5 +1,5 @@\n" +
+		" \\documentclass{article}\n" +
+		" \\begin{document}\n" +
+		" Задача 1.\n" +
+		"-$x^2$\n" +
+		"+$x^3$\n" +
+		" \\end{document}\n"
+	if got := UnifiedDiff("r1", "r2", a, b); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiff_SeparateHunks(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	a := strings.Join(lines, "\n")
+	edited := append([]string(nil), lines...)
+	edited[1] = "changed 2"
+	edited = append(edited[:15], edited[16:]...) // drop line 16
+	b := strings.Join(edited, "\n")
+
+	got := UnifiedDiff("a", "b", a, b)
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Fatalf("want 2 hunks, got %d:\n%s", n, got)
+	}
+	for _, want := range []string{
+		"@@ -1,5 +1,5 @@\n line 1\n-line 2\n+changed 2\n line 3\n",
+		"@@ -13,7 +13,6 @@\n line 13\n line 14\n line 15\n-line 16\n line 17\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestUnifiedDiff_FromEmpty(t *testing.T) {
+	want := "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if got := UnifiedDiff("a", "b", "", "x\ny"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUnifiedDiff_AppliesBack(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng"
+	b := "a\nc\nd\nx\ne\ng\nh"
+	ops := diffLines(splitLines(a), splitLines(b))
+	var from, to []string
+	for _, op := range ops {
+		if op.kind != '+' {
+			from = append(from, op.text)
+		}
+		if op.kind != '-' {
+			to = append(to, op.text)
+		}
+	}
+	if strings.Join(from, "\n") != a || strings.Join(to, "\n") != b {
+		t.Errorf("edit script does not reproduce inputs: %v", ops)
+	}
+	changes := 0
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changes++
+		}
+	}
+	if changes != 4 {
+		t.Errorf("want a minimal script of 4 edits, got %d: %v", changes, ops)
+	}
+}
@@ -0,0 +1,441 @@
+package mathcenter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TeX is rendered in the browser by LaTeX.js, so a broken source used to
+// surface only when a student opened the page. LintTex is a structural
+// check run on save: it does not typeset anything, it only finds what would
+// certainly break rendering (errors) and what probably will (warnings).
+// Publication refuses sources with errors.
+
+const (
+	TexSeverityError   = "error"
+	TexSeverityWarning = "warning"
+)
+
+// TexDiagnostic is one finding, anchored at a 1-based line and rune column.
+type TexDiagnostic struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// TexLintOptions carries what the source is checked against.
+type TexLintOptions struct {
+	// Preamble is the center LaTeX preamble; commands it defines are known.
+	Preamble string
+	// ProblemNumbers are the series' problems. nil skips the numbering
+	// check, as for likbez and разбор sources.
+	ProblemNumbers []int32
+}
+
+// LintTex checks brace, environment and math-mode balance, flags commands
+// that neither LaTeX.js nor the preamble or source defines, and, for a
+// series statement, compares "Задача N" headings with the series' problems.
+// The result is sorted by position and never nil.
+func LintTex(src string, opts TexLintOptions) []TexDiagnostic {
+	l := texLinter{known: definedTexCommands(opts.Preamble, src), reported: map[string]bool{}, diags: []TexDiagnostic{}}
+	l.scan(src)
+	if opts.ProblemNumbers != nil {
+		l.checkProblemNumbers(src, opts.ProblemNumbers)
+	}
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		if l.diags[i].Line != l.diags[j].Line {
+			return l.diags[i].Line < l.diags[j].Line
+		}
+		return l.diags[i].Column < l.diags[j].Column
+	})
+	return l.diags
+}
+
+// TexErrorCount is the number of error-level diagnostics.
+func TexErrorCount(diags []TexDiagnostic) int {
+	n := 0
+	for _, d := range diags {
+		if d.Severity == TexSeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// TexPublishError blocks publication of a source with errors. It carries
+// the error-level diagnostics so a handler can return them.
+type TexPublishError struct {
+	Diagnostics []TexDiagnostic
+}
+
+func (e *TexPublishError) Error() string {
+	d := e.Diagnostics[0]
+	return fmt.Sprintf("TeX has %d error(s); line %d: %s", len(e.Diagnostics), d.Line, d.Message)
+}
+
+// CheckTexDiagnostics returns a *TexPublishError when diags hold errors.
+func CheckTexDiagnostics(diags []TexDiagnostic) error {
+	var errs []TexDiagnostic
+	for _, d := range diags {
+		if d.Severity == TexSeverityError {
+			errs = append(errs, d)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &TexPublishError{Diagnostics: errs}
+}
+
+// CheckTexPublishable is the publication gate: nil when the source has no
+// errors, otherwise a *TexPublishError. Unknown commands are only warnings,
+// so the preamble does not matter here.
+func CheckTexPublishable(src string, problemNumbers []int32) error {
+	return CheckTexDiagnostics(LintTex(src, TexLintOptions{ProblemNumbers: problemNumbers}))
+}
+
+type texPos struct{ line, col int }
+
+type texOpen struct {
+	name string
+	pos  texPos
+}
+
+type texLinter struct {
+	known    map[string]bool
+	reported map[string]bool
+	diags    []TexDiagnostic
+}
+
+func (l *texLinter) add(pos texPos, severity, code, format string, args ...any) {
+	l.diags = append(l.diags, TexDiagnostic{
+		Line: pos.line, Column: pos.col, Severity: severity, Code: code, Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Environments whose body is not TeX.
+var verbatimEnvironments = map[string]bool{"verbatim": true, "verbatim*": true, "lstlisting": true, "comment": true}
+
+func (l *texLinter) scan(src string) {
+	rs := []rune(src)
+	var (
+		braces []texPos
+		envs   []texOpen
+		math   *texOpen // open math mode: "$", "$$", `\(` or `\[`
+	)
+	pos := texPos{1, 1}
+	i := 0
+	advance := func(n int) {
+		for ; n > 0 && i < len(rs); n-- {
+			if rs[i] == '\n' {
+				pos.line++
+				pos.col = 1
+			} else {
+				pos.col++
+			}
+			i++
+		}
+	}
+	openMath := func(kind string, at texPos) {
+		if math != nil {
+			l.add(at, TexSeverityError, "math-nested", "%s inside math mode opened with %s on line %d", kind, math.name, math.pos.line)
+			return
+		}
+		math = &texOpen{kind, at}
+	}
+	closeMath := func(kind, want string, at texPos) {
+		if math == nil {
+			l.add(at, TexSeverityError, "math-unopened", "%s closes math mode that is not open", kind)
+			return
+		}
+		if math.name != want {
+			l.add(at, TexSeverityError, "math-mismatch", "%s does not close %s opened on line %d", kind, math.name, math.pos.line)
+		}
+		math = nil
+	}
+
+	for i < len(rs) {
+		c := rs[i]
+		at := pos
+		switch {
+		case c == '\n':
+			// A blank line ends the paragraph, which inline math cannot span.
+			j := i + 1
+			for j < len(rs) && (rs[j] == ' ' || rs[j] == '\t' || rs[j] == '\r') {
+				j++
+			}
+			if j < len(rs) && rs[j] == '\n' && math != nil && (math.name == "$" || math.name == `\(`) {
+				l.add(math.pos, TexSeverityError, "math-unclosed", "inline math opened with %s is not closed before the paragraph ends", math.name)
+				math = nil
+			}
+		case c == '%':
+			for i < len(rs) && rs[i] != '\n' {
+				advance(1)
+			}
+			continue
+		case c == '{':
+			braces = append(braces, at)
+		case c == '}':
+			if len(braces) == 0 {
+				l.add(at, TexSeverityError, "brace-unopened", "unmatched }")
+			} else {
+				braces = braces[:len(braces)-1]
+			}
+		case c == '$':
+			// Inside $...$ a doubled $ closes and reopens inline math.
+			if i+1 < len(rs) && rs[i+1] == '$' && (math == nil || math.name != "$") {
+				if math != nil && math.name == "$$" {
+					math = nil
+				} else {
+					openMath("$$", at)
+				}
+				advance(2)
+				continue
+			}
+			if math != nil && math.name == "$" {
+				math = nil
+			} else {
+				openMath("$", at)
+			}
+		case c == '\\':
+			if i+1 >= len(rs) {
+				break
+			}
+			next := rs[i+1]
+			if !isTexLetter(next) {
+				// Control symbol: \\, \{, \$, \% ... and the math delimiters.
+				switch next {
+				case '(':
+					openMath(`\(`, at)
+				case '[':
+					openMath(`\[`, at)
+				case ')':
+					closeMath(`\)`, `\(`, at)
+				case ']':
+					closeMath(`\]`, `\[`, at)
+				}
+				advance(2)
+				continue
+			}
+			j := i + 1
+			for j < len(rs) && isTexLetter(rs[j]) {
+				j++
+			}
+			name := string(rs[i+1 : j])
+			advance(j - i)
+			if i < len(rs) && rs[i] == '*' {
+				advance(1)
+			}
+			switch name {
+			case "begin", "end":
+				env, ok := l.readGroupName(rs, &i, advance)
+				if !ok {
+					l.add(at, TexSeverityError, "env-name", `\%s without an environment name`, name)
+					continue
+				}
+				if name == "begin" {
+					if verbatimEnvironments[env] {
+						l.skipVerbatim(rs, &i, advance, env, at)
+						continue
+					}
+					envs = append(envs, texOpen{env, at})
+					continue
+				}
+				envs = l.closeEnvironment(envs, env, at)
+			case "verb":
+				if i < len(rs) {
+					delim := rs[i]
+					advance(1)
+					for i < len(rs) && rs[i] != delim && rs[i] != '\n' {
+						advance(1)
+					}
+					if i >= len(rs) || rs[i] == '\n' {
+						l.add(at, TexSeverityError, "verb-unclosed", `\verb is not closed on the same line`)
+						continue
+					}
+					advance(1)
+				}
+			default:
+				if !l.known[name] && !builtinTexCommands[name] && !l.reported[name] {
+					l.reported[name] = true
+					l.add(at, TexSeverityWarning, "unknown-command", `unknown command \%s: not defined by LaTeX.js, the center preamble or this source`, name)
+				}
+			}
+			continue
+		}
+		advance(1)
+	}
+
+	for _, b := range braces {
+		l.add(b, TexSeverityError, "brace-unclosed", "unclosed {")
+	}
+	for _, e := range envs {
+		l.add(e.pos, TexSeverityError, "env-unclosed", `\begin{%s} is never closed`, e.name)
+	}
+	if math != nil {
+		l.add(math.pos, TexSeverityError, "math-unclosed", "math mode opened with %s is never closed", math.name)
+	}
+}
+
+// readGroupName reads the {name} argument of \begin or \end.
+func (l *texLinter) readGroupName(rs []rune, i *int, advance func(int)) (string, bool) {
+	for *i < len(rs) && (rs[*i] == ' ' || rs[*i] == '\t') {
+		advance(1)
+	}
+	if *i >= len(rs) || rs[*i] != '{' {
+		return "", false
+	}
+	end := *i + 1
+	for end < len(rs) && rs[end] != '}' && rs[end] != '\n' {
+		end++
+	}
+	if end >= len(rs) || rs[end] != '}' {
+		return "", false
+	}
+	name := strings.TrimSpace(string(rs[*i+1 : end]))
+	advance(end + 1 - *i)
+	return name, name != ""
+}
+
+func (l *texLinter) skipVerbatim(rs []rune, i *int, advance func(int), env string, at texPos) {
+	closer := []rune(`\end{` + env + `}`)
+	for *i < len(rs) {
+		if rs[*i] == '\\' && *i+len(closer) <= len(rs) && string(rs[*i:*i+len(closer)]) == string(closer) {
+			advance(len(closer))
+			return
+		}
+		advance(1)
+	}
+	l.add(at, TexSeverityError, "env-unclosed", `\begin{%s} is never closed`, env)
+}
+
+// closeEnvironment pops env off the stack. When it is not on top, the
+// environments above it were left open; when it is not open at all, the
+// \end is stray.
+func (l *texLinter) closeEnvironment(envs []texOpen, env string, at texPos) []texOpen {
+	for k := len(envs) - 1; k >= 0; k-- {
+		if envs[k].name != env {
+			continue
+		}
+		for _, open := range envs[k+1:] {
+			l.add(open.pos, TexSeverityError, "env-unclosed", `\begin{%s} is not closed before \end{%s} on line %d`, open.name, env, at.line)
+		}
+		return envs[:k]
+	}
+	if len(envs) > 0 {
+		top := envs[len(envs)-1]
+		l.add(at, TexSeverityError, "env-mismatch", `\end{%s} does not match \begin{%s} on line %d`, env, top.name, top.pos.line)
+		return envs[:len(envs)-1]
+	}
+	l.add(at, TexSeverityError, "env-unopened", `\end{%s} without a matching \begin`, env)
+	return envs
+}
+
+func isTexLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+var texDefinitionPattern = regexp.MustCompile(
+	`\\(?:(?:re|provide)?newcommand|DeclareRobustCommand|DeclareMathOperator)\*?\s*\{?\s*\\([A-Za-z]+)` +
+		`|\\(?:g|e|x)?def\s*\\([A-Za-z]+)` +
+		`|\\let\s*\\([A-Za-z]+)`)
+
+// definedTexCommands collects the macros the sources define.
+func definedTexCommands(sources ...string) map[string]bool {
+	known := map[string]bool{}
+	for _, src := range sources {
+		for _, m := range texDefinitionPattern.FindAllStringSubmatch(src, -1) {
+			for _, name := range m[1:] {
+				if name != "" {
+					known[name] = true
+				}
+			}
+		}
+	}
+	return known
+}
+
+// A problem heading as teachers write it: "Задача 3", "Задача №3",
+// "\textbf{Задача 3.}".
+var problemHeadingPattern = regexp.MustCompile(`(?:Задача|ЗАДАЧА)\s*№?\s*(\d+)`)
+
+func (l *texLinter) checkProblemNumbers(src string, numbers []int32) {
+	type heading struct {
+		number int32
+		pos    texPos
+	}
+	var headings []heading
+	for n, line := range strings.Split(src, "\n") {
+		line = stripTexComment(line)
+		for _, m := range problemHeadingPattern.FindAllStringSubmatchIndex(line, -1) {
+			num, err := strconv.ParseInt(line[m[2]:m[3]], 10, 32)
+			if err != nil {
+				continue
+			}
+			col := len([]rune(line[:m[0]])) + 1
+			headings = append(headings, heading{int32(num), texPos{n + 1, col}})
+		}
+	}
+	// A statement that does not use headings is not held to them.
+	if len(headings) == 0 {
+		return
+	}
+
+	inSeries := make(map[int32]bool, len(numbers))
+	for _, n := range numbers {
+		inSeries[n] = true
+	}
+	seen := map[int32]texPos{}
+	var last int32
+	for _, h := range headings {
+		if !inSeries[h.number] {
+			l.add(h.pos, TexSeverityError, "problem-unknown", "Задача %d is not a problem of this series", h.number)
+		}
+		if first, dup := seen[h.number]; dup {
+			l.add(h.pos, TexSeverityWarning, "problem-duplicate", "Задача %d is already headed on line %d", h.number, first.line)
+			continue
+		}
+		if h.number < last {
+			l.add(h.pos, TexSeverityWarning, "problem-order", "Задача %d comes after Задача %d", h.number, last)
+		}
+		seen[h.number] = h.pos
+		last = max(last, h.number)
+	}
+	sorted := append([]int32(nil), numbers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for _, n := range sorted {
+		// Problem 0 holds the exercises, which carry no heading.
+		if n == 0 {
+			continue
+		}
+		if _, ok := seen[n]; ok {
+			continue
+		}
+		// Anchor at the heading the missing one should precede.
+		anchor := headings[len(headings)-1].pos
+		for _, h := range headings {
+			if h.number > n {
+				anchor = h.pos
+				break
+			}
+		}
+		l.add(anchor, TexSeverityWarning, "problem-missing", "problem %d of this series has no Задача heading", n)
+	}
+}
+
+func stripTexComment(line string) string {
+	for k := 0; k < len(line); k++ {
+		switch line[k] {
+		case '\\':
+			k++
+		case '%':
+			return line[:k]
+		}
+	}
+	return line
+}
@@ -0,0 +1,83 @@
+package mathcenter
+
+import "strings"
+
+// builtinTexCommands are the control words LaTeX.js renders without a
+// definition: the LaTeX core and the packages the default center preamble loads
+// (amsmath, amssymb, amsthm, mathtools, graphicx, xcolor, enumitem, hyperref,
+// TikZ basics). It only decides between "known" and an unknown-command
+// warning, so it errs on the side of listing too much.
+var builtinTexCommands = texCommandSet(`
+documentclass usepackage RequirePackage begin end input include
+newcommand renewcommand providecommand DeclareRobustCommand DeclareMathOperator
+newenvironment renewenvironment newtheorem theoremstyle def gdef edef xdef let
+newcounter setcounter addtocounter stepcounter refstepcounter value arabic roman Roman alph Alph fnsymbol
+newlength setlength addtolength settowidth
+maketitle title author date today thanks
+part chapter section subsection subsubsection paragraph subparagraph
+tableofcontents appendix label ref eqref pageref cite footnote footnotemark footnotetext
+item newline linebreak nolinebreak newpage clearpage cleardoublepage pagebreak nopagebreak
+par noindent indent smallskip medskip bigskip vspace hspace vfill hfill hss vss
+quad qquad enspace enskip thinspace negthinspace space nobreakspace
+centering raggedright raggedleft centerline
+textbf textit textsl textsc textup textmd textrm textsf texttt textnormal emph underline
+bfseries itshape slshape scshape upshape mdseries rmfamily sffamily ttfamily normalfont
+bf it sl sc rm sf tt em
+tiny scriptsize footnotesize small normalsize large Large LARGE huge Huge
+mbox makebox fbox framebox parbox raisebox rule strut phantom hphantom vphantom smash
+textcolor color colorbox fcolorbox definecolor pagecolor
+includegraphics graphicspath scalebox resizebox rotatebox reflectbox
+url href hyperref hypersetup nolinkurl
+ldots dots cdots vdots ddots dotsc dotsb dotsm dotsi dotso
+LaTeX TeX LaTeXe textbackslash textasciitilde textasciicircum textbar textless textgreater
+textendash textemdash textquoteleft textquoteright textquotedblleft textquotedblright
+guillemotleft guillemotright textellipsis textbullet textperiodcentered textdegree
+copyright textcopyright S P dag ddag pounds
+geometry pagestyle thispagestyle pagenumbering
+hline cline toprule midrule bottomrule cmidrule multicolumn multirow tabularnewline arraystretch
+caption
+frac dfrac tfrac cfrac binom dbinom tbinom sqrt root
+sum prod coprod int iint iiint oint bigcup bigcap bigsqcup bigvee bigwedge bigoplus bigotimes bigodot biguplus
+lim limsup liminf sup inf max min arg det dim exp gcd hom ker lg ln log Pr deg
+sin cos tan cot sec csc arcsin arccos arctan sinh cosh tanh coth
+mod bmod pmod pod
+left right middle big Big bigg Bigg bigl bigr Bigl Bigr biggl biggr Biggl Biggr
+langle rangle lceil rceil lfloor rfloor lvert rvert lVert rVert vert Vert
+lbrace rbrace lbrack rbrack backslash
+alpha beta gamma delta epsilon varepsilon zeta eta theta vartheta iota kappa varkappa lambda mu nu xi pi varpi
+rho varrho sigma varsigma tau upsilon phi varphi chi psi omega
+Gamma Delta Theta Lambda Xi Pi Sigma Upsilon Phi Psi Omega
+varGamma varDelta varTheta varLambda varXi varPi varSigma varUpsilon varPhi varPsi varOmega
+aleph beth gimel hbar hslash imath jmath ell wp Re Im partial infty nabla emptyset varnothing
+forall exists nexists neg lnot top bot angle measuredangle sphericalangle triangle square blacksquare
+Box Diamond lozenge blacklozenge bigstar clubsuit diamondsuit heartsuit spadesuit flat natural sharp
+prime backprime complement eth mho surd
+pm mp times div cdot ast star circ bullet oplus ominus otimes oslash odot bigcirc setminus smallsetminus
+cap cup sqcap sqcup vee wedge land lor uplus amalg wr dagger ddagger
+leq le geq ge neq ne equiv approx cong sim simeq propto asymp doteq models perp parallel mid nmid
+ll gg lll ggg leqslant geqslant leqq geqq lesssim gtrsim nless ngtr nleq ngeq
+subset supset subseteq supseteq subsetneq supsetneq nsubseteq nsupseteq sqsubset sqsupset sqsubseteq sqsupseteq
+in ni notin owns prec succ preceq succeq vdash dashv Vdash vDash therefore because
+to gets leftarrow rightarrow Leftarrow Rightarrow leftrightarrow Leftrightarrow
+longleftarrow longrightarrow Longleftarrow Longrightarrow longleftrightarrow Longleftrightarrow
+mapsto longmapsto hookleftarrow hookrightarrow uparrow downarrow Uparrow Downarrow updownarrow Updownarrow
+nearrow searrow swarrow nwarrow iff implies impliedby leadsto rightleftharpoons
+xrightarrow xleftarrow overset underset stackrel atop choose over
+hat widehat tilde widetilde bar overline underline vec dot ddot dddot acute grave breve check mathring
+overbrace underbrace overrightarrow overleftarrow overleftrightarrow
+mathbb mathbf mathit mathrm mathsf mathtt mathcal mathfrak mathscr mathnormal boldsymbol bm pmb
+text textstyle displaystyle scriptstyle scriptscriptstyle operatorname limits nolimits
+tag notag nonumber intertext shortintertext substack
+not colon coloneqq eqqcolon
+qedhere qed qedsymbol proofname
+usetikzlibrary tikz draw fill filldraw path node coordinate clip shade foreach pgfmathsetmacro tikzset
+setlist
+`)
+
+func texCommandSet(names string) map[string]bool {
+	set := map[string]bool{}
+	for _, name := range strings.Fields(names) {
+		set[name] = true
+	}
+	return set
+}
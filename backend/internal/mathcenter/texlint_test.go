@@ -0,0 +1,140 @@
+package mathcenter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func lintCodes(diags []TexDiagnostic) []string {
+	codes := make([]string, len(diags))
+	for i, d := range diags {
+		codes[i] = d.Code
+	}
+	return codes
+}
+
+func TestLintTex_CleanDocument(t *testing.T) {
+	src := `\documentclass{article}
+\newcommand{\R}{\mathbb{R}}
+\begin{document}
+% a comment with { and $ is ignored
+\textbf{Задача 1.} Докажите, что $x^2 \geq 0$ для $x \in \R$.
+\[ \sum_{k=1}^{n} k = \frac{n(n+1)}{2} \]
+\begin{enumerate}
+  \item $\{1, 2\}$ \\[2pt]
+  \item 50\% и \verb|{|
+\end{enumerate}
+\begin{verbatim}
+\begin{itemize} {{ $
+\end{verbatim}
+\end{document}
+`
+	if diags := LintTex(src, TexLintOptions{ProblemNumbers: []int32{1}}); len(diags) != 0 {
+		t.Errorf("clean source: got %+v", diags)
+	}
+}
+
+func TestLintTex_Balance(t *testing.T) {
+	cases := []struct {
+		name, src string
+		code      string
+		line, col int
+	}{
+		{"unclosed brace", "a\n\\textbf{b", "brace-unclosed", 2, 8},
+		{"stray brace", "a}", "brace-unopened", 1, 2},
+		{"unclosed env", "\\begin{itemize}\n\\item x", "env-unclosed", 1, 1},
+		{"stray end", "x\n\\end{itemize}", "env-unopened", 2, 1},
+		{"mismatched env", "\\begin{a}\n\\end{b}", "env-mismatch", 2, 1},
+		{"unclosed inline math", "Пусть $x + y.", "math-unclosed", 1, 7},
+		{"inline math across paragraph", "$x\n\ny$", "math-unclosed", 1, 1},
+		{"unclosed display math", "\\[ x", "math-unclosed", 1, 1},
+		{"mismatched display math", "\\[ x \\)", "math-mismatch", 1, 6},
+		{"unclosed verb", "\\verb|x\n|", "verb-unclosed", 1, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			diags := LintTex(c.src, TexLintOptions{})
+			for _, d := range diags {
+				if d.Code == c.code {
+					if d.Line != c.line || d.Column != c.col || d.Severity != TexSeverityError {
+						t.Errorf("got %+v, want %s at %d:%d", d, c.code, c.line, c.col)
+					}
+					return
+				}
+			}
+			t.Errorf("want %s, got %v", c.code, lintCodes(diags))
+		})
+	}
+}
+
+func TestLintTex_ClosingOuterEnvReportsInnerOnce(t *testing.T) {
+	src := "\\begin{center}\n\\begin{tabular}{cc}\n\\end{center}"
+	diags := LintTex(src, TexLintOptions{})
+	if len(diags) != 1 || diags[0].Code != "env-unclosed" || diags[0].Line != 2 {
+		t.Errorf("got %+v", diags)
+	}
+}
+
+func TestLintTex_UnknownCommands(t *testing.T) {
+	preamble := "\\newcommand{\\N}{\\mathbb{N}}\n\\DeclareMathOperator{\\rank}{rank}"
+	src := "$n \\in \\N$, $\\rank A$, \\foo{x} \\foo{y} \\def\\bar{1}"
+	diags := LintTex(src, TexLintOptions{Preamble: preamble})
+	if len(diags) != 1 {
+		t.Fatalf("got %+v", diags)
+	}
+	d := diags[0]
+	if d.Code != "unknown-command" || d.Severity != TexSeverityWarning || d.Column != 24 || !strings.Contains(d.Message, `\foo`) {
+		t.Errorf("got %+v", d)
+	}
+}
+
+func TestLintTex_ProblemNumbers(t *testing.T) {
+	src := "Задача 1. a\nЗадача 3. b\nЗадача №2. c\nЗадача 3. d\nЗадача 7. e\n% Задача 9 in a comment\n"
+	diags := LintTex(src, TexLintOptions{ProblemNumbers: []int32{0, 1, 2, 3, 4}})
+	want := []struct {
+		code string
+		line int
+	}{
+		{"problem-order", 3},
+		{"problem-duplicate", 4},
+		{"problem-unknown", 5},
+		{"problem-missing", 5},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %v", lintCodes(diags))
+	}
+	for i, w := range want {
+		if diags[i].Code != w.code || diags[i].Line != w.line {
+			t.Errorf("diag %d = %+v, want %s on line %d", i, diags[i], w.code, w.line)
+		}
+	}
+	if TexErrorCount(diags) != 1 {
+		t.Errorf("only the unknown problem is an error, got %d", TexErrorCount(diags))
+	}
+
+	if diags := LintTex("Без заголовков.", TexLintOptions{ProblemNumbers: []int32{1, 2}}); len(diags) != 0 {
+		t.Errorf("headingless statement: got %+v", diags)
+	}
+}
+
+func TestCheckTexPublishable(t *testing.T) {
+	if err := CheckTexPublishable("\\foo{x}", nil); err != nil {
+		t.Errorf("warnings must not block: %v", err)
+	}
+	err := CheckTexPublishable("ok\n\\begin{proof}", nil)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("got %v, want an error on line 2", err)
+	}
+}
+
+func TestCheckTexPublishable_CarriesErrors(t *testing.T) {
+	err := CheckTexPublishable("\\begin{a}\n}\n\\foo", nil)
+	var perr *TexPublishError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got %v, want *TexPublishError", err)
+	}
+	if len(perr.Diagnostics) != 2 || perr.Diagnostics[0].Line != 1 || perr.Diagnostics[1].Line != 2 {
+		t.Errorf("want the two errors only, got %+v", perr.Diagnostics)
+	}
+}
@@ -54,8 +54,41 @@ var (
 		Name: "telegram_alert_dropped_total",
 		Help: "Number of Telegram alert events dropped after queue or retry exhaustion.",
 	})
+
+	HomeworkClaimsExpired = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "homework_claims_expired_total",
+		Help: "Number of stale grading claims cleared by the housekeeping sweeper.",
+	})
+
+	HomeworkOrphanUploadsDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "homework_orphan_uploads_deleted_total",
+		Help: "Number of homework photo objects deleted because no event references their upload.",
+	})
+
+	// Grading turnaround, observed when an attempt is first claimed and when
+	// it gets its verdict. Series and graders are deliberately not labels
+	// (both are unbounded); the per-series and per-grader breakdown comes
+	// from the head-teacher turnaround endpoint instead.
+	HomeworkClaimWaitSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "homework_claim_wait_seconds",
+		Help:    "Time from a homework submission or appeal to its first grading claim.",
+		Buckets: turnaroundBuckets,
+	})
+
+	HomeworkGradeTurnaroundSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "homework_grade_turnaround_seconds",
+		Help:    "Time from a homework submission or appeal to its verdict.",
+		Buckets: turnaroundBuckets,
+	}, []string{"verdict"})
 )
 
+// turnaroundBuckets span five minutes to two weeks: homework is graded in
+// hours to days, not milliseconds.
+var turnaroundBuckets = []float64{
+	300, 900, 3600, 3 * 3600, 6 * 3600, 12 * 3600,
+	24 * 3600, 48 * 3600, 72 * 3600, 7 * 24 * 3600, 14 * 24 * 3600,
+}
+
 // Handler serves the registered collectors in the Prometheus text format.
 func Handler() http.Handler {
 	return promhttp.Handler()
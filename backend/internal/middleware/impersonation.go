@@ -27,7 +27,7 @@ const actAsHeader = "X-Act-As-User-Id"
 // the target user and OVERWRITES the effective identity on the context with the
 // target's ID and admin flag, while preserving the real identity under
 // CtxKeyRealUserID / CtxKeyRealIsAdmin. Every downstream ownership and role
-// check (requireTeacher, requireStudent, /me) then acts faithfully as the
+// check (teacherAccess, requireStudent, /me) then acts faithfully as the
 // impersonated user — so impersonating a non-admin deliberately does NOT carry
 // the admin teacher-superset. The header is ignored for non-admin callers and
 // when absent. Each applied impersonation emits one structured audit line,
@@ -0,0 +1,54 @@
+package photopipeline
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// jpegWithOrientation builds the smallest byte stream jpegOrientation accepts:
+// SOI, one APP1 Exif segment with a single IFD0 entry, then SOS.
+func jpegWithOrientation(order binary.ByteOrder, v uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], v)
+
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(out[4:], uint16(len(seg)+2))
+	out = append(out, seg...)
+	return append(out, 0xFF, 0xDA, 0, 2)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"little endian rotate 90", jpegWithOrientation(binary.LittleEndian, 6), 6},
+		{"big endian rotate 180", jpegWithOrientation(binary.BigEndian, 3), 3},
+		{"out of range value", jpegWithOrientation(binary.BigEndian, 9), 1},
+		{"no exif", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0, 2}, 1},
+		{"not a jpeg", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"truncated segment", jpegWithOrientation(binary.LittleEndian, 6)[:12], 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := jpegOrientation(tc.data); got != tc.want {
+				t.Errorf("jpegOrientation = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
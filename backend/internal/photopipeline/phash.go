@@ -0,0 +1,48 @@
+package photopipeline
+
+import "image"
+
+// Difference hash grid: 9 columns give 8 left/right comparisons per row.
+const (
+	hashCols = 9
+	hashRows = 8
+)
+
+// perceptualHash is a 64-bit difference hash: src is averaged down to a 9x8
+// grey grid and each bit records whether a cell is brighter than its right
+// neighbour. Re-encoding, rescaling and mild exposure changes leave most bits
+// alone, so two photos of the same page land within a few bits of each
+// other. src is expected upright and opaque, as render produces it.
+func perceptualHash(src *image.RGBA) uint64 {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w == 0 || h == 0 {
+		return 0
+	}
+	var grid [hashRows][hashCols]uint32
+	for gy := range hashRows {
+		y0, y1 := gy*h/hashRows, max((gy+1)*h/hashRows, gy*h/hashRows+1)
+		for gx := range hashCols {
+			x0, x1 := gx*w/hashCols, max((gx+1)*w/hashCols, gx*w/hashCols+1)
+			var sum, n uint32
+			for y := y0; y < min(y1, h); y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < min(x1, w); x++ {
+					// ITU-R BT.601 luma in integer arithmetic.
+					sum += (299*uint32(row[x*4]) + 587*uint32(row[x*4+1]) + 114*uint32(row[x*4+2])) / 1000
+					n++
+				}
+			}
+			grid[gy][gx] = sum / n
+		}
+	}
+	var hash uint64
+	for gy := range hashRows {
+		for gx := range hashCols - 1 {
+			hash <<= 1
+			if grid[gy][gx] > grid[gy][gx+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
@@ -0,0 +1,66 @@
+package photopipeline
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/bits"
+	"testing"
+)
+
+// page draws dark strokes on white; shift moves them right by that many
+// pixels.
+func page(w, h, shift int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+			if ((x-shift)/(w/12)+y/(h/10))%3 == 0 {
+				c = color.RGBA{R: 0x20, G: 0x20, B: 0x40, A: 0xFF}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestPerceptualHash_SurvivesRescaleAndRecompression(t *testing.T) {
+	t.Parallel()
+	src := page(900, 1200, 0)
+	a := perceptualHash(fit(src, thumbMaxEdge))
+
+	// Same page through a JPEG round trip at a different size.
+	out, err := render(src, 1)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if d := bits.OnesCount64(a ^ perceptualHash(decodeJPEG(t, out.Preview.Body))); d > 4 {
+		t.Errorf("same page differs by %d bits", d)
+	}
+
+	other := perceptualHash(fit(page(900, 1200, 40), thumbMaxEdge))
+	if d := bits.OnesCount64(a ^ other); d <= 8 {
+		t.Errorf("different page only %d bits away", d)
+	}
+}
+
+func TestPerceptualHash_BlankPageIsZero(t *testing.T) {
+	t.Parallel()
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	if h := perceptualHash(img); h != 0 {
+		t.Errorf("blank page hash = %#x, want 0", h)
+	}
+}
+
+func decodeJPEG(t *testing.T, body []byte) *image.RGBA {
+	t.Helper()
+	img, err := jpeg.Decode(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return flatten(img)
+}
@@ -0,0 +1,311 @@
+// Package photopipeline re-encodes homework photos after upload. Students PUT
+// their photos straight into object storage, so the bucket holds whatever the
+// phone produced: GPS coordinates in EXIF, HEIC that most browsers cannot
+// show, 12 MP originals where a thumbnail would do. The Processor leases
+// pending homework_thread_event_photo rows, replaces each upload with a
+// metadata-free JPEG and writes preview/thumb renditions next to it.
+package photopipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+	"github.com/Alarion239/my239/backend/pkg/objectstore"
+)
+
+// Processing statuses persisted in homework_thread_event_photo.processing_status.
+const (
+	StatusPending     = "pending"
+	StatusDone        = "done"
+	StatusFailed      = "failed"
+	StatusUnsupported = "unsupported"
+)
+
+const (
+	// pollInterval is how often an idle worker looks for new photos. Photos
+	// are served from the original until processed, so latency here only
+	// delays thumbnails, never correctness.
+	pollInterval = 5 * time.Second
+	// batchSize bounds the photos one worker leases per round.
+	batchSize = 8
+	// staleLease is how long a lease may be held before another worker
+	// assumes the holder died and retakes the photo.
+	staleLease = 5 * time.Minute
+	// maxAttempts is the retry budget for transient failures (storage
+	// hiccups) before a photo is parked as 'failed'.
+	maxAttempts = 5
+	// maxDecodePixels caps width×height before anything is decoded. A few
+	// hundred KB of PNG can declare a 50 000×50 000 canvas; decoding that
+	// would allocate gigabytes. 48 MP phone sensors fit comfortably.
+	maxDecodePixels = 64_000_000
+)
+
+// Processor is the background worker. Safe to run on every replica: leases
+// are taken with SKIP LOCKED and completion is guarded on the old object key.
+type Processor struct {
+	pool  db.Pool
+	blobs objectstore.Store
+	heic  HEICDecoder
+}
+
+// NewProcessor builds a Processor. heic may be nil, in which case HEIC
+// uploads are marked unsupported and kept as uploaded, minus their metadata.
+func NewProcessor(pool db.Pool, blobs objectstore.Store, heic HEICDecoder) *Processor {
+	return &Processor{pool: pool, blobs: blobs, heic: heic}
+}
+
+// Start launches the polling worker. It returns immediately; the worker stops
+// when ctx is cancelled.
+func (p *Processor) Start(ctx context.Context) {
+	go func() {
+		if err := logger.Guard("photo pipeline worker", func() error {
+			p.run(ctx)
+			return nil
+		}); err != nil {
+			logger.LogWarn("photo pipeline worker stopped after panic", "error", err)
+		}
+	}()
+}
+
+func (p *Processor) run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		// Drain: keep leasing while batches come back full so a burst of
+		// submissions doesn't wait a poll interval per batch.
+		for {
+			n, err := p.ProcessBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.LogErrorContext(ctx, "photo pipeline: process batch", err)
+				}
+				break
+			}
+			if n < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch leases up to batchSize pending photos and processes them one
+// by one. It returns how many photos were leased. Per-photo failures are
+// recorded on the row and do not fail the batch.
+func (p *Processor) ProcessBatch(ctx context.Context) (int, error) {
+	q := store.New(p.pool)
+	leased, err := q.LeasePendingEventPhotos(ctx, batchSize, staleLease)
+	if err != nil {
+		return 0, fmt.Errorf("lease photos: %w", err)
+	}
+	for _, photo := range leased {
+		if ctx.Err() != nil {
+			return len(leased), ctx.Err()
+		}
+		p.processOne(ctx, q, photo)
+	}
+	return len(leased), nil
+}
+
+func (p *Processor) processOne(ctx context.Context, q *store.Queries, photo store.PendingEventPhoto) {
+	err := p.convert(ctx, q, photo)
+	if err == nil {
+		return
+	}
+	status := StatusPending
+	switch {
+	case errors.Is(err, errUnsupported):
+		status = StatusUnsupported
+	case photo.Attempts >= maxAttempts:
+		status = StatusFailed
+		logger.LogErrorContext(ctx, "photo pipeline: giving up on photo", err,
+			"event_id", photo.EventID, "idx", photo.Idx)
+	}
+	if err := q.SetEventPhotoProcessingStatus(ctx, photo.EventID, photo.Idx, status, truncate(err.Error(), 500)); err != nil {
+		logger.LogErrorContext(ctx, "photo pipeline: record failure", err)
+	}
+}
+
+// convert does the actual work for one photo: read, decode, render, upload the
+// renditions, repoint the row, and only then delete the raw upload.
+func (p *Processor) convert(ctx context.Context, q *store.Queries, photo store.PendingEventPhoto) error {
+	data, err := p.read(ctx, photo.ObjectKey)
+	if err != nil {
+		return err
+	}
+	img, orientation, err := p.decode(ctx, photo.ContentType, data)
+	if errors.Is(err, errUnsupported) {
+		return p.keepStripped(ctx, photo, data, err)
+	}
+	if err != nil {
+		return err
+	}
+	out, err := render(img, orientation)
+	if err != nil {
+		return err
+	}
+
+	fullKey := homework.RenditionObjectKey(photo.ObjectKey, homework.RenditionFull)
+	previewKey := homework.RenditionObjectKey(photo.ObjectKey, homework.RenditionPreview)
+	thumbKey := homework.RenditionObjectKey(photo.ObjectKey, homework.RenditionThumb)
+	for _, put := range []struct {
+		key string
+		r   rendition
+	}{{fullKey, out.Full}, {previewKey, out.Preview}, {thumbKey, out.Thumb}} {
+		if err := p.blobs.Put(ctx, put.key, bytes.NewReader(put.r.Body), int64(len(put.r.Body)), "image/jpeg"); err != nil {
+			return fmt.Errorf("put %s: %w", put.key, err)
+		}
+	}
+
+	affected, err := q.CompleteEventPhotoProcessing(ctx, store.CompleteEventPhotoProcessingParams{
+		EventID:          photo.EventID,
+		Idx:              photo.Idx,
+		PreviousKey:      photo.ObjectKey,
+		ObjectKey:        fullKey,
+		SizeBytes:        int64(len(out.Full.Body)),
+		ContentType:      "image/jpeg",
+		PreviewObjectKey: previewKey,
+		ThumbObjectKey:   thumbKey,
+	})
+	if err != nil {
+		return fmt.Errorf("complete photo: %w", err)
+	}
+	if affected == 0 {
+		// Someone else finished this photo (or the event was deleted) while
+		// we held a stale lease. Their renditions have the same keys, so
+		// there is nothing of ours to clean up — and the raw upload is
+		// theirs to delete.
+		return nil
+	}
+	// The raw upload is what carries the GPS metadata; once the row points at
+	// the sanitized copy it must not linger in the bucket.
+	if fullKey != photo.ObjectKey {
+		if err := p.blobs.Delete(ctx, photo.ObjectKey); err != nil {
+			logger.LogErrorContext(ctx, "photo pipeline: delete raw upload", err, "object_key", photo.ObjectKey)
+		}
+	}
+	p.recordHash(ctx, q, photo, out.Hash)
+	return nil
+}
+
+// recordHash stores the photo's perceptual hash and flags near-identical
+// photos other students sent for the same subproblem. The renditions are
+// already committed, so a failure here is only logged.
+func (p *Processor) recordHash(ctx context.Context, q *store.Queries, photo store.PendingEventPhoto, hash uint64) {
+	if !homework.PhotoHashInformative(hash) {
+		return
+	}
+	flagged, err := q.RecordPhotoHash(ctx, store.RecordPhotoHashParams{
+		EventID:     photo.EventID,
+		Idx:         photo.Idx,
+		Hash:        int64(hash),
+		MaxDistance: homework.MaxPhotoHashDistance,
+	})
+	if err != nil {
+		logger.LogErrorContext(ctx, "photo pipeline: record photo hash", err, "event_id", photo.EventID, "idx", photo.Idx)
+		return
+	}
+	if flagged > 0 {
+		logger.LogInfoContext(ctx, "photo pipeline: duplicate photo flagged", "event_id", photo.EventID, "idx", photo.Idx, "matches", flagged)
+	}
+}
+
+// keepStripped handles an upload the pipeline cannot re-encode: it is served
+// as uploaded, so its metadata is stripped in place first. When that is not
+// possible either the upload is deleted rather than served with GPS data.
+// cause (an errUnsupported) is returned so the photo is parked as
+// unsupported. size_bytes keeps the uploaded size, a slight overcount.
+func (p *Processor) keepStripped(ctx context.Context, photo store.PendingEventPhoto, data []byte, cause error) error {
+	clean, err := stripMetadata(photo.ContentType, data)
+	if err != nil {
+		if err := p.blobs.Delete(ctx, photo.ObjectKey); err != nil {
+			return fmt.Errorf("delete unstrippable upload: %w", err)
+		}
+		return fmt.Errorf("%w; upload discarded: %w", cause, err)
+	}
+	if !bytes.Equal(clean, data) {
+		if err := p.blobs.Put(ctx, photo.ObjectKey, bytes.NewReader(clean), int64(len(clean)), photo.ContentType); err != nil {
+			return fmt.Errorf("put stripped %s: %w", photo.ObjectKey, err)
+		}
+	}
+	return cause
+}
+
+func (p *Processor) read(ctx context.Context, key string) ([]byte, error) {
+	rc, _, err := p.blobs.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, objectstore.ErrNotFound) {
+			return nil, fmt.Errorf("%w: object %s is gone", errUnsupported, key)
+		}
+		return nil, fmt.Errorf("open %s: %w", key, err)
+	}
+	defer func() { _ = rc.Close() }()
+	// The finalize handlers already enforced MaxPhotoBytes; the +1 guards
+	// against a bucket object swapped out after finalize.
+	data, err := io.ReadAll(io.LimitReader(rc, homework.MaxPhotoBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", key, err)
+	}
+	if len(data) > homework.MaxPhotoBytes {
+		return nil, fmt.Errorf("%w: object %s exceeds size limit", errUnsupported, key)
+	}
+	return data, nil
+}
+
+// decode returns the image and the EXIF orientation still to be applied.
+func (p *Processor) decode(ctx context.Context, contentType string, data []byte) (image.Image, int, error) {
+	switch strings.ToLower(contentType) {
+	case "image/heic":
+		if p.heic == nil {
+			return nil, 0, fmt.Errorf("%w: no HEIC converter configured", errUnsupported)
+		}
+		img, err := p.heic.DecodeHEIC(ctx, data)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", errUnsupported, err)
+		}
+		return img, 1, nil
+	case "image/jpeg", "image/png":
+		if err := checkDimensions(data); err != nil {
+			return nil, 0, err
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", errUnsupported, err)
+		}
+		orientation := 1
+		if strings.EqualFold(contentType, "image/jpeg") {
+			orientation = jpegOrientation(data)
+		}
+		return img, orientation, nil
+	default:
+		// WebP has no decoder in the standard library.
+		return nil, 0, fmt.Errorf("%w: %s", errUnsupported, contentType)
+	}
+}
+
+// checkDimensions reads only the image header and refuses canvases above
+// maxDecodePixels, so a decompression bomb is rejected before decoding.
+func checkDimensions(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUnsupported, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > maxDecodePixels {
+		return fmt.Errorf("%w: %dx%d image exceeds the pixel limit", errUnsupported, cfg.Width, cfg.Height)
+	}
+	return nil
+}
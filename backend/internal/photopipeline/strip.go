@@ -0,0 +1,324 @@
+package photopipeline
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// errNoStrip means an upload's metadata could not be located, so it cannot
+// be kept: it may still carry the GPS position of the student's phone.
+var errNoStrip = errors.New("photopipeline: cannot strip metadata")
+
+// stripMetadata removes EXIF/XMP (and with them GPS coordinates) from an
+// upload the pipeline cannot re-encode, so it can still be served as
+// uploaded. It works on the container only and never decodes pixels.
+func stripMetadata(contentType string, data []byte) ([]byte, error) {
+	switch strings.ToLower(contentType) {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	case "image/heic":
+		return stripHEIC(data)
+	}
+	return nil, errNoStrip
+}
+
+// stripJPEG drops every APPn segment but JFIF (APP0), ICC profiles (APP2) and
+// Adobe colour info (APP14), plus comments. Everything from start of scan on
+// is copied unchanged.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errNoStrip
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, errNoStrip
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return append(out, data[pos:]...), nil
+		}
+		segLen := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if segLen < 2 || pos+2+segLen > len(data) {
+			return nil, errNoStrip
+		}
+		keep := true
+		switch {
+		case marker >= 0xE0 && marker <= 0xEF:
+			keep = marker == 0xE0 || marker == 0xE2 || marker == 0xEE
+		case marker == 0xFE:
+			keep = false
+		}
+		if keep {
+			out = append(out, data[pos:pos+2+segLen]...)
+		}
+		pos += 2 + segLen
+	}
+	return nil, errNoStrip
+}
+
+// pngDroppedChunks are the PNG chunks that can hold EXIF or free text.
+var pngDroppedChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+func stripPNG(data []byte) ([]byte, error) {
+	const sig = "\x89PNG\r\n\x1a\n"
+	if len(data) < len(sig) || string(data[:len(sig)]) != sig {
+		return nil, errNoStrip
+	}
+	out := append(make([]byte, 0, len(data)), sig...)
+	pos := len(sig)
+	for pos+12 <= len(data) {
+		n := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		end := pos + 12 + n
+		if end > len(data) {
+			return nil, errNoStrip
+		}
+		typ := string(data[pos+4 : pos+8])
+		if !pngDroppedChunks[typ] {
+			out = append(out, data[pos:end]...)
+		}
+		if typ == "IEND" {
+			return out, nil
+		}
+		pos = end
+	}
+	return nil, errNoStrip
+}
+
+// stripWebP drops the EXIF and XMP chunks of a RIFF WebP and clears their
+// flags in the VP8X header.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errNoStrip
+	}
+	out := append(make([]byte, 0, len(data)), data[:12]...)
+	pos := 12
+	for pos+8 <= len(data) {
+		n := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + n + n%2
+		if end > len(data) {
+			return nil, errNoStrip
+		}
+		switch string(data[pos : pos+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[pos:end]...)
+			if n > 0 {
+				out[start+8] &^= 0x08 | 0x04 // EXIF and XMP present
+			}
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	if pos != len(data) {
+		return nil, errNoStrip
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
+
+// stripHEIC zeroes the payload of the Exif and XMP items of a HEIF file in
+// place. Removing them would shift every offset in the iloc box; zeroed
+// payloads keep the file valid and readers simply find no metadata.
+func stripHEIC(data []byte) ([]byte, error) {
+	meta, ok := findBox(data, "meta")
+	if !ok || len(meta) < 4 {
+		return nil, errNoStrip
+	}
+	children := meta[4:] // FullBox header
+	iinf, ok := findBox(children, "iinf")
+	if !ok {
+		return nil, errNoStrip
+	}
+	iloc, ok := findBox(children, "iloc")
+	if !ok {
+		return nil, errNoStrip
+	}
+	items, err := metadataItems(iinf)
+	if err != nil {
+		return nil, err
+	}
+	out := bytes.Clone(data)
+	if len(items) == 0 {
+		return out, nil
+	}
+	extents, err := itemExtents(iloc, items)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range extents {
+		if e.offset > uint64(len(out)) || e.length > uint64(len(out))-e.offset {
+			return nil, errNoStrip
+		}
+		clear(out[e.offset : e.offset+e.length])
+	}
+	return out, nil
+}
+
+// findBox returns the payload of the first ISO-BMFF box of type typ in data.
+func findBox(data []byte, typ string) ([]byte, bool) {
+	for pos := 0; pos+8 <= len(data); {
+		size := uint64(binary.BigEndian.Uint32(data[pos : pos+4]))
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data) - pos)
+		case 1:
+			if pos+16 > len(data) {
+				return nil, false
+			}
+			size = binary.BigEndian.Uint64(data[pos+8 : pos+16])
+			header = 16
+		}
+		if size < header || uint64(pos)+size > uint64(len(data)) {
+			return nil, false
+		}
+		if string(data[pos+4:pos+8]) == typ {
+			return data[uint64(pos)+header : uint64(pos)+size], true
+		}
+		pos += int(size)
+	}
+	return nil, false
+}
+
+// metadataItems returns the ids of the Exif and XMP items listed in iinf.
+func metadataItems(iinf []byte) (map[uint32]bool, error) {
+	if len(iinf) < 6 {
+		return nil, errNoStrip
+	}
+	pos := 6
+	if iinf[0] != 0 {
+		pos = 8
+	}
+	items := map[uint32]bool{}
+	for pos+8 <= len(iinf) {
+		size := int(binary.BigEndian.Uint32(iinf[pos : pos+4]))
+		if size < 8 || pos+size > len(iinf) {
+			return nil, errNoStrip
+		}
+		if string(iinf[pos+4:pos+8]) == "infe" {
+			infe := iinf[pos+8 : pos+size]
+			if len(infe) >= 4 && infe[0] >= 2 {
+				id, rest := uint32(0), infe[4:]
+				if infe[0] == 2 && len(rest) >= 2 {
+					id, rest = uint32(binary.BigEndian.Uint16(rest)), rest[2:]
+				} else if infe[0] > 2 && len(rest) >= 4 {
+					id, rest = binary.BigEndian.Uint32(rest), rest[4:]
+				} else {
+					return nil, errNoStrip
+				}
+				if len(rest) < 6 {
+					return nil, errNoStrip
+				}
+				switch itemType := string(rest[2:6]); itemType {
+				case "Exif":
+					items[id] = true
+				case "mime":
+					contentType, _, _ := bytes.Cut(rest[6:], []byte{0})
+					if string(contentType) == "application/rdf+xml" {
+						items[id] = true
+					}
+				}
+			}
+		}
+		pos += size
+	}
+	return items, nil
+}
+
+type extent struct{ offset, length uint64 }
+
+// itemExtents resolves the file extents of items from the iloc box. Only
+// file-offset construction is supported; anything else is refused.
+func itemExtents(iloc []byte, items map[uint32]bool) ([]extent, error) {
+	r := boxReader{b: iloc}
+	version := r.u8()
+	r.skip(3)
+	sizes := r.u16()
+	offsetSize, lengthSize := int(sizes>>12), int(sizes>>8&0xF)
+	baseOffsetSize, indexSize := int(sizes>>4&0xF), int(sizes&0xF)
+	if version == 0 {
+		indexSize = 0
+	}
+	var count uint32
+	if version < 2 {
+		count = uint32(r.u16())
+	} else {
+		count = r.u32()
+	}
+	var out []extent
+	for range count {
+		var id uint32
+		if version < 2 {
+			id = uint32(r.u16())
+		} else {
+			id = r.u32()
+		}
+		method := uint16(0)
+		if version > 0 {
+			method = r.u16() & 0xF
+		}
+		r.skip(2) // data_reference_index
+		base := r.uint(baseOffsetSize)
+		n := r.u16()
+		for range n {
+			r.uint(indexSize)
+			off := r.uint(offsetSize)
+			length := r.uint(lengthSize)
+			if items[id] {
+				if method != 0 || length == 0 {
+					return nil, errNoStrip
+				}
+				out = append(out, extent{offset: base + off, length: length})
+			}
+		}
+		if r.err {
+			return nil, errNoStrip
+		}
+	}
+	return out, nil
+}
+
+// boxReader reads big-endian fields and records running off the end.
+type boxReader struct {
+	b   []byte
+	pos int
+	err bool
+}
+
+func (r *boxReader) take(n int) []byte {
+	if r.err || r.pos+n > len(r.b) {
+		r.err = true
+		return make([]byte, n)
+	}
+	v := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return v
+}
+
+func (r *boxReader) skip(n int)  { r.take(n) }
+func (r *boxReader) u8() uint8   { return r.take(1)[0] }
+func (r *boxReader) u16() uint16 { return binary.BigEndian.Uint16(r.take(2)) }
+func (r *boxReader) u32() uint32 { return binary.BigEndian.Uint32(r.take(4)) }
+func (r *boxReader) uint(size int) uint64 {
+	switch size {
+	case 0:
+		return 0
+	case 4:
+		return uint64(r.u32())
+	case 8:
+		return binary.BigEndian.Uint64(r.take(8))
+	}
+	r.err = true
+	return 0
+}
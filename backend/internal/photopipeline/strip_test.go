@@ -0,0 +1,130 @@
+package photopipeline
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestStripJPEG_DropsExif(t *testing.T) {
+	t.Parallel()
+	src := jpegWithOrientation(binary.BigEndian, 6)
+	got, err := stripMetadata("image/jpeg", src)
+	if err != nil {
+		t.Fatalf("stripMetadata: %v", err)
+	}
+	if bytes.Contains(got, []byte("Exif")) || jpegOrientation(got) != 1 {
+		t.Errorf("Exif segment survived: %x", got)
+	}
+	if !bytes.HasSuffix(got, []byte{0xFF, 0xDA, 0, 2}) {
+		t.Errorf("scan data not kept: %x", got)
+	}
+}
+
+func TestStripPNG_DropsTextChunks(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	src := buf.Bytes()
+	// Insert a tEXt chunk right after IHDR (8-byte signature + 25-byte IHDR).
+	text := pngChunk("tEXt", []byte("GPS\x0055.75,37.61"))
+	withText := append(append(append([]byte{}, src[:33]...), text...), src[33:]...)
+
+	got, err := stripMetadata("image/png", withText)
+	if err != nil {
+		t.Fatalf("stripMetadata: %v", err)
+	}
+	if !bytes.Equal(got, src) {
+		t.Errorf("stripped PNG differs from the original without tEXt")
+	}
+}
+
+func TestStripWebP_DropsExifChunk(t *testing.T) {
+	t.Parallel()
+	vp8x := append([]byte("VP8X"), 10, 0, 0, 0, 0x08, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	exif := append([]byte("EXIF"), 3, 0, 0, 0, 'g', 'p', 's', 0)
+	body := append(append([]byte("WEBP"), vp8x...), exif...)
+	src := append([]byte("RIFF"), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(src[4:], uint32(len(body)))
+	src = append(src, body...)
+
+	got, err := stripMetadata("image/webp", src)
+	if err != nil {
+		t.Fatalf("stripMetadata: %v", err)
+	}
+	if bytes.Contains(got, []byte("EXIF")) || got[20]&0x08 != 0 {
+		t.Errorf("EXIF survived: %q", got)
+	}
+	if int(binary.LittleEndian.Uint32(got[4:8])) != len(got)-8 {
+		t.Errorf("RIFF size not updated")
+	}
+}
+
+func TestStripHEIC_ZeroesExifItem(t *testing.T) {
+	t.Parallel()
+	payload := []byte("Exif\x00\x00GPS-COORDINATES")
+	// infe v2: item 1, protection 0, type "Exif".
+	infe := box("infe", append([]byte{2, 0, 0, 0, 0, 1, 0, 0}, "Exif"...))
+	iinf := box("iinf", append([]byte{0, 0, 0, 0, 0, 1}, infe...))
+	ilocFor := func(offset uint32) []byte {
+		b := []byte{0, 0, 0, 0, 0x44, 0x00, 0, 1, 0, 1, 0, 0, 0, 1}
+		b = binary.BigEndian.AppendUint32(b, offset)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+		return box("iloc", b)
+	}
+	metaFor := func(offset uint32) []byte {
+		return box("meta", append(append([]byte{0, 0, 0, 0}, iinf...), ilocFor(offset)...))
+	}
+	ftyp := box("ftyp", []byte("heic\x00\x00\x00\x00"))
+	head := append(append([]byte{}, ftyp...), metaFor(0)...)
+	mdatHeader := 8
+	offset := uint32(len(head) + mdatHeader)
+	src := append(append(append([]byte{}, ftyp...), metaFor(offset)...), box("mdat", payload)...)
+
+	got, err := stripMetadata("image/heic", src)
+	if err != nil {
+		t.Fatalf("stripMetadata: %v", err)
+	}
+	if len(got) != len(src) || bytes.Contains(got, []byte("GPS-COORDINATES")) {
+		t.Errorf("Exif payload survived")
+	}
+	if _, err := stripMetadata("image/heic", []byte("not a heif file")); !errors.Is(err, errNoStrip) {
+		t.Errorf("garbage: err = %v, want errNoStrip", err)
+	}
+}
+
+func TestCheckDimensions_RejectsBomb(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := checkDimensions(buf.Bytes()); err != nil {
+		t.Fatalf("small image: %v", err)
+	}
+	// Rewrite IHDR to declare a 60 000 × 60 000 canvas.
+	bomb := bytes.Clone(buf.Bytes())
+	binary.BigEndian.PutUint32(bomb[16:], 60000)
+	binary.BigEndian.PutUint32(bomb[20:], 60000)
+	binary.BigEndian.PutUint32(bomb[29:], crc32.ChecksumIEEE(bomb[12:29]))
+	if err := checkDimensions(bomb); !errors.Is(err, errUnsupported) {
+		t.Errorf("bomb: err = %v, want errUnsupported", err)
+	}
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	out = append(append(out, typ...), data...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[4:]))
+}
+
+func box(typ string, payload []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	return append(append(out, typ...), payload...)
+}
@@ -0,0 +1,156 @@
+package photopipeline
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	// PNG screenshots are the other decodable upload type; registering the
+	// decoder is all image.Decode needs.
+	_ "image/png"
+)
+
+// Rendition sizes, as the maximum long edge in pixels. Full keeps handwriting
+// legible when zoomed; preview fills a phone screen in the thread timeline;
+// thumb is for lists and strips.
+const (
+	fullMaxEdge    = 2560
+	previewMaxEdge = 1280
+	thumbMaxEdge   = 320
+	jpegQuality    = 85
+)
+
+// rendition is one encoded output of the pipeline.
+type rendition struct {
+	Body   []byte
+	Width  int
+	Height int
+}
+
+// renditions is the full set produced for one upload, plus the perceptual
+// hash of the upright image used for copied-solution detection.
+type renditions struct {
+	Full    rendition
+	Preview rendition
+	Thumb   rendition
+	Hash    uint64
+}
+
+// render orients src per its EXIF orientation, flattens it onto white (PNG
+// alpha has no JPEG equivalent) and encodes the three renditions. Each smaller
+// size is derived from the previous one, which keeps the box filter cheap.
+func render(src image.Image, orientation int) (renditions, error) {
+	base := flatten(orient(src, orientation))
+	full := fit(base, fullMaxEdge)
+	preview := fit(full, previewMaxEdge)
+	thumb := fit(preview, thumbMaxEdge)
+
+	var out renditions
+	for _, step := range []struct {
+		img image.Image
+		dst *rendition
+	}{{full, &out.Full}, {preview, &out.Preview}, {thumb, &out.Thumb}} {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, step.img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return renditions{}, fmt.Errorf("encode jpeg: %w", err)
+		}
+		b := step.img.Bounds()
+		*step.dst = rendition{Body: buf.Bytes(), Width: b.Dx(), Height: b.Dy()}
+	}
+	out.Hash = perceptualHash(thumb)
+	return out, nil
+}
+
+// orient applies an EXIF orientation (1..8) so the returned image is upright.
+// Orientation 1 (and anything unknown) returns src unchanged.
+func orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// flatten composites src over an opaque white background into an RGBA image
+// anchored at the origin.
+func flatten(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := range b.Dy() {
+		for x := range b.Dx() {
+			r, g, bl, a := src.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			// Premultiplied over white: c + (1 - a) * white.
+			inv := 0xFFFF - a
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r + inv) >> 8),
+				G: uint8((g + inv) >> 8),
+				B: uint8((bl + inv) >> 8),
+				A: 0xFF,
+			})
+		}
+	}
+	return dst
+}
+
+// fit downscales src so its long edge is at most maxEdge, using an area
+// average (box filter) which keeps thin pen strokes visible in thumbnails.
+// Images already within bounds are returned as-is; nothing is upscaled.
+func fit(src *image.RGBA, maxEdge int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= maxEdge && h <= maxEdge {
+		return src
+	}
+	dw, dh := maxEdge, h*maxEdge/w
+	if h > w {
+		dw, dh = w*maxEdge/h, maxEdge
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := range dh {
+		y0, y1 := dy*h/dh, max((dy+1)*h/dh, dy*h/dh+1)
+		for dx := range dw {
+			x0, x1 := dx*w/dw, max((dx+1)*w/dw, dx*w/dw+1)
+			var r, g, b, n uint32
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					r += uint32(row[x*4])
+					g += uint32(row[x*4+1])
+					b += uint32(row[x*4+2])
+					n++
+				}
+			}
+			dst.SetRGBA(dx, dy, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 0xFF})
+		}
+	}
+	return dst
+}
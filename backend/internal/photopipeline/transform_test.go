@@ -0,0 +1,95 @@
+package photopipeline
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestOrient_SwapsDimensionsAndMovesPixels(t *testing.T) {
+	t.Parallel()
+	// 3x2 image with a single red pixel in the top-left corner.
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.Set(0, 0, color.RGBA{R: 0xFF, A: 0xFF})
+
+	cases := []struct {
+		orientation int
+		w, h        int
+		redX, redY  int
+	}{
+		{1, 3, 2, 0, 0},
+		{3, 3, 2, 2, 1},
+		{6, 2, 3, 1, 0},
+		{8, 2, 3, 0, 2},
+	}
+	for _, tc := range cases {
+		got := orient(src, tc.orientation)
+		b := got.Bounds()
+		if b.Dx() != tc.w || b.Dy() != tc.h {
+			t.Errorf("orientation %d: size %dx%d, want %dx%d", tc.orientation, b.Dx(), b.Dy(), tc.w, tc.h)
+			continue
+		}
+		if r, _, _, _ := got.At(tc.redX, tc.redY).RGBA(); r != 0xFFFF {
+			t.Errorf("orientation %d: red pixel not at (%d,%d)", tc.orientation, tc.redX, tc.redY)
+		}
+	}
+}
+
+func TestFlatten_TransparentBecomesWhite(t *testing.T) {
+	t.Parallel()
+	src := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	got := flatten(src).RGBAAt(0, 0)
+	if got != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Errorf("flatten = %v, want opaque white", got)
+	}
+}
+
+func TestFit(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		w, h, edge   int
+		wantW, wantH int
+	}{
+		{100, 50, 200, 100, 50}, // never upscaled
+		{400, 200, 100, 100, 50},
+		{200, 400, 100, 50, 100},
+		{1000, 1, 10, 10, 1},
+	}
+	for _, tc := range cases {
+		got := fit(image.NewRGBA(image.Rect(0, 0, tc.w, tc.h)), tc.edge).Bounds()
+		if got.Dx() != tc.wantW || got.Dy() != tc.wantH {
+			t.Errorf("fit(%dx%d, %d) = %dx%d, want %dx%d", tc.w, tc.h, tc.edge, got.Dx(), got.Dy(), tc.wantW, tc.wantH)
+		}
+	}
+}
+
+func TestRender_ProducesDecodableRenditions(t *testing.T) {
+	t.Parallel()
+	out, err := render(image.NewRGBA(image.Rect(0, 0, 3000, 1500)), 6)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	for name, r := range map[string]struct {
+		rendition
+		edge int
+	}{
+		"full":    {out.Full, fullMaxEdge},
+		"preview": {out.Preview, previewMaxEdge},
+		"thumb":   {out.Thumb, thumbMaxEdge},
+	} {
+		// Orientation 6 turns the landscape source into portrait.
+		if r.Height != r.edge || r.Width != r.edge/2 {
+			t.Errorf("%s: %dx%d, want %dx%d", name, r.Width, r.Height, r.edge/2, r.edge)
+		}
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(r.Body))
+		if err != nil {
+			t.Errorf("%s: decode: %v", name, err)
+			continue
+		}
+		if cfg.Width != r.Width || cfg.Height != r.Height {
+			t.Errorf("%s: encoded %dx%d, recorded %dx%d", name, cfg.Width, cfg.Height, r.Width, r.Height)
+		}
+	}
+}
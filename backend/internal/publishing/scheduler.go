@@ -0,0 +1,224 @@
+// Package publishing runs scheduled publication. Teachers schedule a series
+// statement, a shared разбор or a likbez entry for a moment (or, for a
+// разбор, for "when the series is due") instead of pressing the publish
+// button at that moment. The Scheduler picks due rows from
+// math_center_publication_schedule and performs the same transition the
+// manual endpoint does, then emits the matching live event.
+package publishing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Alarion239/my239/backend/internal/live"
+	"github.com/Alarion239/my239/backend/internal/logger"
+	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
+	"github.com/Alarion239/my239/backend/internal/store"
+	"github.com/Alarion239/my239/backend/pkg/db"
+)
+
+const (
+	// pollInterval is how often an idle worker looks for due schedules.
+	// Teachers pick minutes, so half a minute of lateness is invisible.
+	pollInterval = 30 * time.Second
+	// maxAttempts is the retry budget for transient failures (database
+	// hiccups) before a schedule is parked as failed.
+	maxAttempts = 5
+)
+
+// errNotReady marks a schedule whose target cannot be published as it
+// stands (no statement yet, no разбор material). Retrying will not help, so
+// the schedule fails at once and the teacher sees why.
+var errNotReady = errors.New("not ready to publish")
+
+// Scheduler is the background worker. Safe to run on every replica: each
+// schedule is claimed with SKIP LOCKED in the transaction that publishes it
+// and marks it done, so two replicas never publish the same row and a crash
+// mid-way leaves it pending.
+type Scheduler struct {
+	pool db.Pool
+}
+
+// NewScheduler builds a Scheduler.
+func NewScheduler(pool db.Pool) *Scheduler {
+	return &Scheduler{pool: pool}
+}
+
+// Start launches the polling worker. It returns immediately; the worker stops
+// when ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		if err := logger.Guard("publication scheduler", func() error {
+			s.run(ctx)
+			return nil
+		}); err != nil {
+			logger.LogWarn("publication scheduler stopped after panic", "error", err)
+		}
+	}()
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		// Drain: a Friday 18:00 usually has several schedules due at once.
+		for {
+			ran, err := s.RunDue(ctx, time.Now())
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.LogErrorContext(ctx, "publication scheduler: run due", err)
+				}
+				break
+			}
+			if !ran {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue claims one schedule due at now and publishes its target. It
+// reports whether a schedule was claimed; false means nothing is due.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := store.New(tx)
+	sched, err := q.ClaimDuePublication(ctx, now)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claim: %w", err)
+	}
+
+	ev, err := publish(ctx, q, sched, now)
+	if errors.Is(err, errNotReady) {
+		if err := q.FinishPublicationSchedule(ctx, sched.ID, store.PublicationFailed, err.Error()); err != nil {
+			return true, fmt.Errorf("record failure: %w", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return true, fmt.Errorf("commit failure: %w", err)
+		}
+		logger.LogWarn("publication scheduler: target not ready",
+			"schedule_id", sched.ID, "kind", sched.TargetKind, "error", err)
+		return true, nil
+	}
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		if rerr := store.New(s.pool).RecordPublicationError(ctx, sched.ID, err.Error(), maxAttempts); rerr != nil {
+			logger.LogErrorContext(ctx, "publication scheduler: record error", rerr, "schedule_id", sched.ID)
+		}
+		return true, fmt.Errorf("publish schedule %d: %w", sched.ID, err)
+	}
+	if err := q.FinishPublicationSchedule(ctx, sched.ID, store.PublicationDone, ""); err != nil {
+		return true, fmt.Errorf("mark done: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return true, fmt.Errorf("commit: %w", err)
+	}
+	live.Publish(ctx, s.pool, ev)
+	return true, nil
+}
+
+// publish performs the schedule's transition inside the claiming transaction
+// and returns the live event to emit once it commits.
+func publish(ctx context.Context, q *store.Queries, sched store.PublicationSchedule, now time.Time) (live.Event, error) {
+	switch sched.TargetKind {
+	case store.PublicationTargetSeries:
+		if sched.SeriesID == nil {
+			return live.Event{}, fmt.Errorf("%w: schedule has no series", errNotReady)
+		}
+		series, err := q.GetSeries(ctx, *sched.SeriesID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return live.Event{}, fmt.Errorf("%w: series not found", errNotReady)
+			}
+			return live.Event{}, err
+		}
+		if err := mc.CheckSeriesTex(ctx, q, series.ID, series.TexSource); err != nil {
+			return live.Event{}, texNotReady(err)
+		}
+		published, err := q.PublishSeries(ctx, *sched.SeriesID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return live.Event{}, fmt.Errorf("%w: add a statement and at least one problem", errNotReady)
+			}
+			return live.Event{}, err
+		}
+		if published.PublishedAt != nil {
+			if err := q.MarkSeriesTexRevisionPublished(ctx, published.ID, *published.PublishedAt); err != nil {
+				return live.Event{}, err
+			}
+		}
+		return live.Event{CenterID: sched.MathCenterID, Kind: live.KindSeries, SeriesID: *sched.SeriesID}, nil
+
+	case store.PublicationTargetLikbez:
+		if sched.LikbezID == nil {
+			return live.Event{}, fmt.Errorf("%w: schedule has no likbez", errNotReady)
+		}
+		likbez, err := q.GetLikbez(ctx, *sched.LikbezID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return live.Event{}, fmt.Errorf("%w: likbez not found", errNotReady)
+			}
+			return live.Event{}, err
+		}
+		if likbez.TexSource != nil {
+			if err := mc.CheckTexPublishable(*likbez.TexSource, nil); err != nil {
+				return live.Event{}, texNotReady(err)
+			}
+		}
+		if _, err := q.PublishLikbez(ctx, *sched.LikbezID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return live.Event{}, fmt.Errorf("%w: attach a material", errNotReady)
+			}
+			return live.Event{}, err
+		}
+		return live.Event{CenterID: sched.MathCenterID, Kind: live.KindLikbez}, nil
+
+	case store.PublicationTargetSolutions:
+		ids := sched.SubproblemIDs
+		targets, err := q.LockSolutionPublicationTargets(ctx, ids)
+		if err != nil {
+			return live.Event{}, err
+		}
+		if err := mc.CheckSolutionTargets(targets, len(ids)); err != nil {
+			return live.Event{}, fmt.Errorf("%w: %s", errNotReady, err.Error())
+		}
+		if err := mc.CheckSolutionTex(targets); err != nil {
+			return live.Event{}, texNotReady(err)
+		}
+		if _, err := mc.PublishSolutionGroup(ctx, q, targets, ids, now.UTC()); err != nil {
+			if errors.Is(err, mc.ErrSolutionTargetsChanged) {
+				return live.Event{}, fmt.Errorf("%w: %s", errNotReady, err.Error())
+			}
+			return live.Event{}, err
+		}
+		return live.Event{CenterID: sched.MathCenterID, Kind: live.KindCoffins}, nil
+	}
+	return live.Event{}, fmt.Errorf("%w: unknown target kind %q", errNotReady, sched.TargetKind)
+}
+
+// texNotReady turns a TeX publication gate failure into errNotReady, so the
+// schedule fails with the first error as its reason; other errors (loading
+// the series' problems) are returned as they are and retried.
+func texNotReady(err error) error {
+	var perr *mc.TexPublishError
+	if errors.As(err, &perr) {
+		return fmt.Errorf("%w: %s", errNotReady, err.Error())
+	}
+	return err
+}
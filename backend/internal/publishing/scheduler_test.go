@@ -0,0 +1,234 @@
+package publishing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/Alarion239/my239/backend/internal/publishing"
+)
+
+var scheduleColumns = []string{
+	"id", "math_center_id", "target_kind", "series_id", "likbez_id", "subproblem_ids",
+	"publish_at", "at_series_due", "run_at",
+	"status", "attempts", "last_error", "created_by_user_id", "created_at", "finished_at",
+}
+
+var seriesColumns = []string{
+	"id", "math_center_id", "number", "name", "due_at",
+	"pdf_object_key", "published_at", "created_at", "tex_source",
+}
+
+var likbezColumns = []string{
+	"id", "math_center_id", "term_id", "number", "title", "held_on",
+	"description", "pdf_object_key", "tex_source", "video_url", "published_at",
+	"created_at", "updated_at", "term_kind", "term_grade",
+}
+
+func expectSeries(mock pgxmock.PgxPoolIface, now time.Time, seriesID int64, tex *string) {
+	mock.ExpectQuery(`SELECT .* FROM math_center_series WHERE id`).
+		WithArgs(seriesID).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(seriesID, int64(42), int32(1), "S", now, (*string)(nil), (*time.Time)(nil), now, tex))
+}
+
+func expectClaim(mock pgxmock.PgxPoolIface, now time.Time, kind string, seriesID, likbezID *int64, ids []int64, atDue bool) {
+	if ids == nil {
+		ids = []int64{}
+	}
+	var publishAt *time.Time
+	if !atDue {
+		publishAt = &now
+	}
+	mock.ExpectQuery(`FOR UPDATE OF ps SKIP LOCKED`).
+		WithArgs(now).
+		WillReturnRows(mock.NewRows(scheduleColumns).AddRow(
+			int64(5), int64(42), kind, seriesID, likbezID, ids, publishAt, atDue, now,
+			"pending", int32(0), "", (*int64)(nil), now, (*time.Time)(nil)))
+}
+
+func TestRunDue_NothingDue(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE OF ps SKIP LOCKED`).WithArgs(now).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	ran, err := publishing.NewScheduler(mock).RunDue(context.Background(), now)
+	if err != nil || ran {
+		t.Fatalf("RunDue = %v, %v; want false, nil", ran, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRunDue_PublishesSeriesAndNotifies(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	now := time.Now()
+	seriesID := int64(100)
+
+	mock.ExpectBegin()
+	expectClaim(mock, now, "series", &seriesID, nil, nil, false)
+	expectSeries(mock, now, seriesID, nil)
+	mock.ExpectQuery(`UPDATE math_center_series AS series\s+SET published_at`).
+		WithArgs(seriesID).
+		WillReturnRows(mock.NewRows(seriesColumns).
+			AddRow(seriesID, int64(42), int32(1), "S", now, (*string)(nil), &now, now, (*string)(nil)))
+	mock.ExpectExec(`UPDATE math_center_tex_revisions\s+SET published_at`).
+		WithArgs(seriesID, now).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`UPDATE math_center_publication_schedule\s+SET status\s+= \$2`).
+		WithArgs(int64(5), "done", "").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`pg_notify`).
+		WithArgs(pgxmock.AnyArg(), `{"center_id":42,"kind":"series","series_id":100}`).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	ran, err := publishing.NewScheduler(mock).RunDue(context.Background(), now)
+	if err != nil || !ran {
+		t.Fatalf("RunDue = %v, %v; want true, nil", ran, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRunDue_NotReadyFailsWithoutRetry(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	now := time.Now()
+	likbezID := int64(9)
+
+	mock.ExpectBegin()
+	expectClaim(mock, now, "likbez", nil, &likbezID, nil, false)
+	mock.ExpectQuery(`FROM math_center_likbez l\s+JOIN math_center_terms`).
+		WithArgs(likbezID).
+		WillReturnRows(mock.NewRows(likbezColumns).AddRow(
+			likbezID, int64(42), int64(7), int32(4), "Инварианты", now, "", (*string)(nil),
+			(*string)(nil), (*string)(nil), (*time.Time)(nil), now, now, "academic", (*int32)(nil)))
+	mock.ExpectQuery(`UPDATE math_center_likbez\s+SET published_at`).
+		WithArgs(likbezID).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectExec(`UPDATE math_center_publication_schedule\s+SET status\s+= \$2`).
+		WithArgs(int64(5), "failed", "not ready to publish: attach a material").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	ran, err := publishing.NewScheduler(mock).RunDue(context.Background(), now)
+	if err != nil || !ran {
+		t.Fatalf("RunDue = %v, %v; want true, nil", ran, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRunDue_TexWithErrorsFailsWithoutRetry(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	now := time.Now()
+	seriesID := int64(100)
+	tex := "\\begin{document}\nЗадача 1. $x\n\\end{document}"
+
+	mock.ExpectBegin()
+	expectClaim(mock, now, "series", &seriesID, nil, nil, false)
+	expectSeries(mock, now, seriesID, &tex)
+	mock.ExpectQuery(`SELECT .* FROM math_center_problems WHERE series_id`).
+		WithArgs(seriesID).
+		WillReturnRows(mock.NewRows([]string{"id", "series_id", "number", "created_at"}).
+			AddRow(int64(500), seriesID, int32(1), now))
+	mock.ExpectExec(`UPDATE math_center_publication_schedule\s+SET status\s+= \$2`).
+		WithArgs(int64(5), "failed", "not ready to publish: TeX has 1 error(s); line 2: math mode opened with $ is never closed").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	ran, err := publishing.NewScheduler(mock).RunDue(context.Background(), now)
+	if err != nil || !ran {
+		t.Fatalf("RunDue = %v, %v; want true, nil", ran, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRunDue_PublishesSolutionGroupAtSeriesDue(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	now := time.Now()
+	seriesID := int64(100)
+	ids := []int64{900, 901}
+
+	mock.ExpectBegin()
+	expectClaim(mock, now, "solutions", &seriesID, nil, ids, true)
+	group := int64(77)
+	mock.ExpectQuery(`FOR UPDATE OF ss`).
+		WithArgs(ids).
+		WillReturnRows(mock.NewRows([]string{"subproblem_id", "math_center_id", "series_id", "is_coffin", "has_material", "solution_group_id", "solution_tex_source"}).
+			AddRow(int64(900), int64(42), seriesID, true, true, &group, (*string)(nil)).
+			AddRow(int64(901), int64(42), seriesID, false, true, &group, (*string)(nil)))
+	mock.ExpectQuery(`UPDATE math_center_subproblem_solutions\s+SET published_at`).
+		WithArgs(ids, now.UTC()).
+		WillReturnRows(mock.NewRows([]string{"subproblem_id", "is_coffin", "published_at"}).
+			AddRow(int64(900), true, now).
+			AddRow(int64(901), false, now))
+	mock.ExpectExec(`UPDATE math_center_tex_revisions\s+SET published_at`).
+		WithArgs(ids, now.UTC()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mock.ExpectExec(`UPDATE math_center_publication_schedule\s+SET status\s+= \$2`).
+		WithArgs(int64(5), "done", "").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`pg_notify`).
+		WithArgs(pgxmock.AnyArg(), `{"center_id":42,"kind":"coffins"}`).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	ran, err := publishing.NewScheduler(mock).RunDue(context.Background(), now)
+	if err != nil || !ran {
+		t.Fatalf("RunDue = %v, %v; want true, nil", ran, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRunDue_TransientErrorIsRecordedOutsideTheTransaction(t *testing.T) {
+	t.Parallel()
+	mock, _ := pgxmock.NewPool()
+	defer mock.Close()
+	now := time.Now()
+	seriesID := int64(100)
+	boom := errors.New("connection reset")
+
+	mock.ExpectBegin()
+	expectClaim(mock, now, "series", &seriesID, nil, nil, false)
+	expectSeries(mock, now, seriesID, nil)
+	mock.ExpectQuery(`UPDATE math_center_series AS series\s+SET published_at`).
+		WithArgs(seriesID).
+		WillReturnError(boom)
+	mock.ExpectRollback()
+	mock.ExpectExec(`SET attempts\s+= attempts \+ 1`).
+		WithArgs(int64(5), "connection reset", int32(5)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	ran, err := publishing.NewScheduler(mock).RunDue(context.Background(), now)
+	if !errors.Is(err, boom) || !ran {
+		t.Fatalf("RunDue = %v, %v; want true, %v", ran, err, boom)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -31,6 +31,7 @@ import (
 
 	"github.com/Alarion239/my239/backend/internal/auth"
 	hw "github.com/Alarion239/my239/backend/internal/homework"
+	"github.com/Alarion239/my239/backend/internal/hwchain"
 	mc "github.com/Alarion239/my239/backend/internal/mathcenter"
 	"github.com/Alarion239/my239/backend/internal/store"
 )
@@ -641,22 +642,34 @@ func (s *seeder) appendGrade(ctx context.Context, threadID, graderID int64, verd
 
 // appendAt inserts a thread event with an explicit created_at and returns its
 // id. (The store's AppendEvent always stamps NOW(), which would make every demo
-// event share a timestamp.)
+// event share a timestamp.) The event is sealed into the thread's hash chain
+// like any handler-appended one.
 func (s *seeder) appendAt(ctx context.Context, threadID int64, kind string, actorID int64, body string, verdict *string, at time.Time) (int64, error) {
 	uuid, err := newEventUUID()
 	if err != nil {
 		return 0, err
 	}
-	var id int64
+	prev, err := s.q.LockEventChain(ctx, threadID)
+	if err != nil {
+		return 0, fmt.Errorf("seed: lock event chain: %w", err)
+	}
+	event := store.HomeworkThreadEvent{
+		ThreadID: threadID, EventUuid: uuid, Kind: kind, ActorUserID: actorID, Body: body, Verdict: verdict,
+	}
+	// created_at is read back: Postgres rounds to microseconds and the hash
+	// must cover the stored value.
 	err = s.db.QueryRow(ctx,
 		`INSERT INTO homework_thread_event
 		     (thread_id, event_uuid, kind, actor_user_id, body, verdict, created_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
-		threadID, uuid, kind, actorID, body, verdict, at).Scan(&id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		threadID, uuid, kind, actorID, body, verdict, at).Scan(&event.ID, &event.CreatedAt)
 	if err != nil {
 		return 0, fmt.Errorf("seed: append %s event: %w", kind, err)
 	}
-	return id, nil
+	if err := hwchain.Seal(ctx, s.q, prev, event); err != nil {
+		return 0, fmt.Errorf("seed: %w", err)
+	}
+	return event.ID, nil
 }
 
 // createUser makes a demo user with the given (login) username and a random
@@ -7,7 +7,10 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/Alarion239/my239/backend/internal/hwchain"
+	"github.com/Alarion239/my239/backend/internal/hwcheck"
 	"github.com/Alarion239/my239/backend/internal/seed"
+	"github.com/Alarion239/my239/backend/internal/store"
 )
 
 // TestSeedRun_Integration runs the real seeder against a Postgres pointed to by
@@ -85,5 +88,25 @@ func TestSeedRun_Integration(t *testing.T) {
 				groupCount,
 			)
 		}
+
+		// The seeder writes events and thread caches directly; they must
+		// replay cleanly, the same check `cmd/hwcheck` runs in CI.
+		report, err := hwcheck.New(pool).Check(ctx, hwcheck.Scope{}, false)
+		if err != nil {
+			t.Fatalf("run %d: hwcheck: %v", run, err)
+		}
+		for _, f := range report.Findings {
+			t.Errorf("run %d: thread %d inconsistent: illegal=%v drift=%v stray claim=%t",
+				run, f.ThreadID, f.Illegal, f.Drift, f.StrayClaim)
+		}
+		// Every seeded event is sealed, so every chain must verify.
+		err = hwchain.Verify(ctx, store.New(pool), hwchain.Scope{}, func(r hwchain.ThreadResult) {
+			if !r.OK {
+				t.Errorf("run %d: thread %d chain broken: %v", run, r.ThreadID, r.Breaks)
+			}
+		})
+		if err != nil {
+			t.Fatalf("run %d: verify chains: %v", run, err)
+		}
 	}
 }
@@ -0,0 +1,338 @@
+package store
+
+// Query surface for the iCalendar feeds (migration 000044). Hand-written
+// like problem_bank.go. Every event query is scoped by one of the CTEs below
+// to the non-archived terms the user belongs to.
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type CalendarFeed struct {
+	UserID    int64
+	CreatedAt time.Time
+}
+
+const upsertCalendarFeedSQL = `
+INSERT INTO calendar_feeds (user_id, token_hash)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash,
+                                    created_at = NOW()
+RETURNING user_id, created_at`
+
+// UpsertCalendarFeed stores a new token hash for the user, replacing any
+// previous one.
+func (q *Queries) UpsertCalendarFeed(ctx context.Context, userID int64, tokenHash []byte) (CalendarFeed, error) {
+	var f CalendarFeed
+	err := q.db.QueryRow(ctx, upsertCalendarFeedSQL, userID, tokenHash).Scan(&f.UserID, &f.CreatedAt)
+	return f, err
+}
+
+const getCalendarFeedSQL = `SELECT user_id, created_at FROM calendar_feeds WHERE user_id = $1`
+
+func (q *Queries) GetCalendarFeed(ctx context.Context, userID int64) (CalendarFeed, error) {
+	var f CalendarFeed
+	err := q.db.QueryRow(ctx, getCalendarFeedSQL, userID).Scan(&f.UserID, &f.CreatedAt)
+	return f, err
+}
+
+const getCalendarFeedByTokenSQL = `SELECT user_id, created_at FROM calendar_feeds WHERE token_hash = $1`
+
+func (q *Queries) GetCalendarFeedByToken(ctx context.Context, tokenHash []byte) (CalendarFeed, error) {
+	var f CalendarFeed
+	err := q.db.QueryRow(ctx, getCalendarFeedByTokenSQL, tokenHash).Scan(&f.UserID, &f.CreatedAt)
+	return f, err
+}
+
+const deleteCalendarFeedSQL = `DELETE FROM calendar_feeds WHERE user_id = $1`
+
+func (q *Queries) DeleteCalendarFeed(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, deleteCalendarFeedSQL, userID)
+	return err
+}
+
+// calendarMemberScope lists the user's terms: as a student with their group,
+// as a teacher with every group (group_id NULL).
+const calendarMemberScope = `
+WITH scope AS (SELECT s.term_id, s.group_id
+               FROM math_center_students s
+                        JOIN math_center_terms t ON t.id = s.term_id
+               WHERE s.user_id = $1
+                 AND t.archived_at IS NULL
+               UNION
+               SELECT t.id, NULL::bigint
+               FROM math_center_teachers mt
+                        JOIN math_center_terms t ON t.math_center_id = mt.math_center_id
+               WHERE mt.user_id = $1
+                 AND t.archived_at IS NULL)`
+
+// calendarTeachingScope lists the terms of the centers the user teaches.
+const calendarTeachingScope = `
+WITH scope AS (SELECT t.id AS term_id, NULL::bigint AS group_id
+               FROM math_center_teachers mt
+                        JOIN math_center_terms t ON t.math_center_id = mt.math_center_id
+               WHERE mt.user_id = $1
+                 AND t.archived_at IS NULL)`
+
+// CalendarTerm labels an event with the term it belongs to.
+type CalendarTerm struct {
+	TermKind  string
+	TermGrade *int32
+}
+
+type CalendarSeriesRow struct {
+	SeriesID  int64
+	Number    int32
+	Name      string
+	DueAt     time.Time
+	Published bool
+	// SLAHours is the center's grading SLA; nil means the default.
+	SLAHours *int32
+	CalendarTerm
+}
+
+const calendarSeriesColumns = `
+SELECT se.id, se.number, se.name, se.due_at, se.published_at IS NOT NULL, sla.hours, t.kind, t.grade
+FROM math_center_series se
+         JOIN math_center_terms t ON t.id = se.term_id
+         LEFT JOIN homework_sla_config sla ON sla.math_center_id = se.math_center_id
+WHERE se.term_id IN (SELECT term_id FROM scope)`
+
+const listCalendarSeriesSQL = calendarMemberScope + calendarSeriesColumns + `
+  AND se.published_at IS NOT NULL
+ORDER BY se.due_at, se.id`
+
+const listTeachingCalendarSeriesSQL = calendarTeachingScope + calendarSeriesColumns + `
+ORDER BY se.due_at, se.id`
+
+// ListCalendarSeries returns the published series of the user's terms.
+func (q *Queries) ListCalendarSeries(ctx context.Context, userID int64) ([]CalendarSeriesRow, error) {
+	return q.listCalendarSeries(ctx, listCalendarSeriesSQL, userID)
+}
+
+// ListTeachingCalendarSeries returns every series, drafts included, of the
+// centers the user teaches.
+func (q *Queries) ListTeachingCalendarSeries(ctx context.Context, userID int64) ([]CalendarSeriesRow, error) {
+	return q.listCalendarSeries(ctx, listTeachingCalendarSeriesSQL, userID)
+}
+
+func (q *Queries) listCalendarSeries(ctx context.Context, sql string, userID int64) ([]CalendarSeriesRow, error) {
+	rows, err := q.db.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []CalendarSeriesRow{}
+	for rows.Next() {
+		var r CalendarSeriesRow
+		if err := rows.Scan(&r.SeriesID, &r.Number, &r.Name, &r.DueAt, &r.Published, &r.SLAHours,
+			&r.TermKind, &r.TermGrade); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// CalendarCoffinReleaseRow is one release moment of a series' coffins: the
+// subproblems whose разбор went out together.
+type CalendarCoffinReleaseRow struct {
+	SeriesID       int64
+	SeriesNumber   int32
+	SeriesName     string
+	ReleasedAt     time.Time
+	ProblemNumbers []int32
+	Labels         []string
+	CalendarTerm
+}
+
+const listCalendarCoffinReleasesSQL = calendarMemberScope + `
+SELECT se.id, se.number, se.name, ss.released_at,
+       array_agg(p.number ORDER BY p.number, sp.label),
+       array_agg(sp.label ORDER BY p.number, sp.label),
+       t.kind, t.grade
+FROM math_center_subproblem_solutions ss
+         JOIN math_center_subproblems sp ON sp.id = ss.subproblem_id
+         JOIN math_center_problems p ON p.id = sp.problem_id
+         JOIN math_center_series se ON se.id = p.series_id
+         JOIN math_center_terms t ON t.id = se.term_id
+WHERE ss.is_coffin
+  AND ss.released_at IS NOT NULL
+  AND se.published_at IS NOT NULL
+  AND se.term_id IN (SELECT term_id FROM scope)
+GROUP BY se.id, se.number, se.name, ss.released_at, t.kind, t.grade
+ORDER BY ss.released_at, se.id`
+
+// ListCalendarCoffinReleases returns released coffins of published series
+// in the user's terms, grouped by series and release time.
+func (q *Queries) ListCalendarCoffinReleases(ctx context.Context, userID int64) ([]CalendarCoffinReleaseRow, error) {
+	rows, err := q.db.Query(ctx, listCalendarCoffinReleasesSQL, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []CalendarCoffinReleaseRow{}
+	for rows.Next() {
+		var r CalendarCoffinReleaseRow
+		if err := rows.Scan(&r.SeriesID, &r.SeriesNumber, &r.SeriesName, &r.ReleasedAt, &r.ProblemNumbers, &r.Labels,
+			&r.TermKind, &r.TermGrade); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// CalendarPublicationRow is a pending publication schedule. PublishAt is
+// resolved: for at_series_due it is the series' current due_at.
+type CalendarPublicationRow struct {
+	ScheduleID   int64
+	TargetKind   string
+	PublishAt    time.Time
+	SeriesNumber *int32
+	SeriesName   *string
+	LikbezNumber *int32
+	LikbezTitle  *string
+	// ReleasesCoffins is set when the разбор covers a still-open coffin.
+	ReleasesCoffins bool
+	CalendarTerm
+}
+
+const calendarPublicationColumns = `
+SELECT ps.id, ps.target_kind, COALESCE(ps.publish_at, se.due_at),
+       se.number, se.name, l.number, l.title,
+       EXISTS (SELECT 1
+               FROM math_center_subproblem_solutions ss
+               WHERE ss.subproblem_id = ANY (ps.subproblem_ids)
+                 AND ss.is_coffin
+                 AND ss.released_at IS NULL),
+       t.kind, t.grade
+FROM math_center_publication_schedule ps
+         LEFT JOIN math_center_series se ON se.id = ps.series_id
+         LEFT JOIN math_center_likbez l ON l.id = ps.likbez_id
+         JOIN math_center_terms t ON t.id = COALESCE(se.term_id, l.term_id)
+WHERE ps.status = 'pending'
+  AND t.id IN (SELECT term_id FROM scope)`
+
+const listCalendarPublicationsSQL = calendarMemberScope + calendarPublicationColumns + `
+  AND ps.target_kind = 'solutions'
+  AND se.published_at IS NOT NULL
+ORDER BY 3, ps.id`
+
+const listTeachingCalendarPublicationsSQL = calendarTeachingScope + calendarPublicationColumns + `
+  AND ps.target_kind IN ('series', 'likbez')
+ORDER BY 3, ps.id`
+
+// ListCalendarPublications returns the pending разбор publications of
+// published series in the user's terms.
+func (q *Queries) ListCalendarPublications(ctx context.Context, userID int64) ([]CalendarPublicationRow, error) {
+	return q.listCalendarPublications(ctx, listCalendarPublicationsSQL, userID)
+}
+
+// ListTeachingCalendarPublications returns the pending series and likbez
+// publications of the centers the user teaches — the ones students do not
+// see coming.
+func (q *Queries) ListTeachingCalendarPublications(ctx context.Context, userID int64) ([]CalendarPublicationRow, error) {
+	return q.listCalendarPublications(ctx, listTeachingCalendarPublicationsSQL, userID)
+}
+
+func (q *Queries) listCalendarPublications(ctx context.Context, sql string, userID int64) ([]CalendarPublicationRow, error) {
+	rows, err := q.db.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []CalendarPublicationRow{}
+	for rows.Next() {
+		var r CalendarPublicationRow
+		if err := rows.Scan(&r.ScheduleID, &r.TargetKind, &r.PublishAt, &r.SeriesNumber, &r.SeriesName,
+			&r.LikbezNumber, &r.LikbezTitle, &r.ReleasesCoffins, &r.TermKind, &r.TermGrade); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+type CalendarLikbezRow struct {
+	LikbezID int64
+	Number   int32
+	Title    string
+	HeldOn   pgtype.Date
+	CalendarTerm
+}
+
+const listCalendarLikbezSQL = calendarMemberScope + `
+SELECT l.id, l.number, l.title, l.held_on, t.kind, t.grade
+FROM math_center_likbez l
+         JOIN math_center_terms t ON t.id = l.term_id
+WHERE l.published_at IS NOT NULL
+  AND l.term_id IN (SELECT term_id FROM scope)
+ORDER BY l.held_on, l.id`
+
+// ListCalendarLikbez returns the published likbez held in the user's terms.
+func (q *Queries) ListCalendarLikbez(ctx context.Context, userID int64) ([]CalendarLikbezRow, error) {
+	rows, err := q.db.Query(ctx, listCalendarLikbezSQL, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []CalendarLikbezRow{}
+	for rows.Next() {
+		var r CalendarLikbezRow
+		if err := rows.Scan(&r.LikbezID, &r.Number, &r.Title, &r.HeldOn, &r.TermKind, &r.TermGrade); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+type CalendarLessonRow struct {
+	LessonID   int64
+	HeldOn     pgtype.Date
+	StartsAt   pgtype.Time
+	EndsAt     pgtype.Time
+	Topic      string
+	GroupNames []string
+	CalendarTerm
+}
+
+const listCalendarLessonsSQL = calendarMemberScope + `
+SELECT l.id, l.held_on, l.starts_at, l.ends_at, l.topic,
+       ARRAY(SELECT g.name FROM math_center_lesson_groups lg JOIN math_center_groups g ON g.id = lg.group_id
+             WHERE lg.lesson_id = l.id ORDER BY g.name, g.id),
+       t.kind, t.grade
+FROM math_center_lessons l
+         JOIN math_center_terms t ON t.id = l.term_id
+WHERE EXISTS (SELECT 1
+              FROM scope s
+              WHERE s.term_id = l.term_id
+                AND (s.group_id IS NULL OR EXISTS (SELECT 1
+                                                   FROM math_center_lesson_groups lg
+                                                   WHERE lg.lesson_id = l.id
+                                                     AND lg.group_id = s.group_id)))
+ORDER BY l.held_on, l.starts_at, l.id`
+
+// ListCalendarLessons returns the lessons of the user's groups; a teacher
+// gets every lesson of the center's terms.
+func (q *Queries) ListCalendarLessons(ctx context.Context, userID int64) ([]CalendarLessonRow, error) {
+	rows, err := q.db.Query(ctx, listCalendarLessonsSQL, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []CalendarLessonRow{}
+	for rows.Next() {
+		var r CalendarLessonRow
+		if err := rows.Scan(&r.LessonID, &r.HeldOn, &r.StartsAt, &r.EndsAt, &r.Topic, &r.GroupNames,
+			&r.TermKind, &r.TermGrade); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
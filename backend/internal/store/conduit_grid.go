@@ -72,7 +72,7 @@ JOIN users u ON u.id = mcs.user_id
 WHERE g.math_center_id = $1
   AND g.term_id = $2
   AND mcs.term_id = $2
-ORDER BY g.name ASC, u.last_name ASC, u.first_name ASC, mcs.user_id ASC;
+ORDER BY g.name ASC, g.id ASC, u.last_name ASC, u.first_name ASC, mcs.user_id ASC;
 `
 
 const teacherCenterGridColumnsForTermSQL = `
@@ -265,3 +265,78 @@ func (q *Queries) teacherCenterGridCells(ctx context.Context, query string, firs
 	}
 	return items, nil
 }
+
+// GradebookCellRow is a thread cell of the exported gradebook. AcceptedAt is
+// the time of the grade event behind an accepted cell.
+type GradebookCellRow struct {
+	GroupID          int64
+	StudentUserID    int64
+	SubproblemID     int64
+	CurrentStatus    string
+	LastGraderUserID *int64
+	LastGraderName   string
+	GraderFirstName  *string
+	GraderLastName   *string
+	AcceptedAt       *time.Time
+}
+
+// Rows come out in the roster's order (teacherCenterGridRosterForTermSQL), so
+// the export can merge them with the roster one student at a time.
+const gradebookCellsForTermSQL = `
+SELECT g.id,
+       t.student_user_id,
+       t.subproblem_id,
+       t.current_status,
+       t.last_grader_user_id,
+       COALESCE(t.last_grader_name, ''),
+       gu.first_name,
+       gu.last_name,
+       CASE WHEN t.current_status = 'accepted' THEN ge.created_at END
+FROM homework_thread t
+JOIN math_center_series s
+  ON s.id = t.series_id
+ AND s.math_center_id = $1
+ AND s.term_id = $2
+JOIN math_center_students mcs
+  ON mcs.user_id = t.student_user_id
+ AND mcs.term_id = s.term_id
+JOIN math_center_groups g
+  ON g.id = mcs.group_id
+ AND g.math_center_id = s.math_center_id
+JOIN users u ON u.id = t.student_user_id
+LEFT JOIN users gu ON gu.id = t.last_grader_user_id
+LEFT JOIN homework_thread_event ge ON ge.id = t.current_grade_event_id
+WHERE t.math_center_id = $1
+  AND t.current_status <> 'ungraded'
+ORDER BY g.name ASC, g.id ASC, u.last_name ASC, u.first_name ASC, t.student_user_id ASC;
+`
+
+// ForEachGradebookCell streams the term's non-empty cells to fn without
+// collecting them; a non-nil error from fn stops the scan and is returned.
+func (q *Queries) ForEachGradebookCell(ctx context.Context, arg CenterGridTermParams, fn func(GradebookCellRow) error) error {
+	rows, err := q.db.Query(ctx, gradebookCellsForTermSQL, arg.MathCenterID, arg.TermID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item GradebookCellRow
+		if err := rows.Scan(
+			&item.GroupID,
+			&item.StudentUserID,
+			&item.SubproblemID,
+			&item.CurrentStatus,
+			&item.LastGraderUserID,
+			&item.LastGraderName,
+			&item.GraderFirstName,
+			&item.GraderLastName,
+			&item.AcceptedAt,
+		); err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
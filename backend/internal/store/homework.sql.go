@@ -246,7 +246,9 @@ SELECT sp.id            AS subproblem_id,
        -- Coffin state, so the submit handler can keep a coffin open past the
        -- series deadline until its solution is released. Per-subproblem now.
        COALESCE(ss.is_coffin, false)::boolean AS is_coffin,
-       ss.released_at                         AS coffin_released_at
+       ss.released_at                         AS coffin_released_at,
+       -- When the разбор went out; a late resubmission closes for good then.
+       ss.published_at                        AS solution_published_at
 FROM math_center_subproblems sp
          JOIN math_center_problems p ON p.id = sp.problem_id
          JOIN math_center_series   s ON s.id = p.series_id
@@ -255,16 +257,17 @@ WHERE sp.id = $1
 `
 
 type GetSubproblemContextRow struct {
-	SubproblemID      int64      `json:"subproblem_id"`
-	SubproblemLabel   string     `json:"subproblem_label"`
-	ProblemID         int64      `json:"problem_id"`
-	ProblemNumber     int32      `json:"problem_number"`
-	SeriesID          int64      `json:"series_id"`
-	MathCenterID      int64      `json:"math_center_id"`
-	SeriesDueAt       time.Time  `json:"series_due_at"`
-	SeriesPublishedAt *time.Time `json:"series_published_at"`
-	IsCoffin          bool       `json:"is_coffin"`
-	CoffinReleasedAt  *time.Time `json:"coffin_released_at"`
+	SubproblemID        int64      `json:"subproblem_id"`
+	SubproblemLabel     string     `json:"subproblem_label"`
+	ProblemID           int64      `json:"problem_id"`
+	ProblemNumber       int32      `json:"problem_number"`
+	SeriesID            int64      `json:"series_id"`
+	MathCenterID        int64      `json:"math_center_id"`
+	SeriesDueAt         time.Time  `json:"series_due_at"`
+	SeriesPublishedAt   *time.Time `json:"series_published_at"`
+	IsCoffin            bool       `json:"is_coffin"`
+	CoffinReleasedAt    *time.Time `json:"coffin_released_at"`
+	SolutionPublishedAt *time.Time `json:"solution_published_at"`
 }
 
 // One-shot fetch of "what center/series/problem does this subproblem belong
@@ -284,6 +287,7 @@ func (q *Queries) GetSubproblemContext(ctx context.Context, id int64) (GetSubpro
 		&i.SeriesPublishedAt,
 		&i.IsCoffin,
 		&i.CoffinReleasedAt,
+		&i.SolutionPublishedAt,
 	)
 	return i, err
 }
@@ -367,11 +371,14 @@ SELECT
     )::bigint AS my_appeals_count
 FROM homework_thread
 WHERE math_center_id = $1
+  AND ($3::bigint IS NULL
+       OR series_id IN (SELECT id FROM math_center_series WHERE term_id = $3::bigint))
 `
 
 type GraderStatsForCenterParams struct {
-	MathCenterID int64 `json:"math_center_id"`
-	CallerUserID int64 `json:"caller_user_id"`
+	MathCenterID int64  `json:"math_center_id"`
+	CallerUserID int64  `json:"caller_user_id"`
+	TermID       *int64 `json:"term_id"`
 }
 
 type GraderStatsForCenterRow struct {
@@ -380,9 +387,10 @@ type GraderStatsForCenterRow struct {
 	MyAppealsCount int64 `json:"my_appeals_count"`
 }
 
-// {pending, my_claimed, my_appeals} for the grader dashboard.
+// {pending, my_claimed, my_appeals} for the grader dashboard. A non-null
+// term_id counts only that term's series.
 func (q *Queries) GraderStatsForCenter(ctx context.Context, arg GraderStatsForCenterParams) (GraderStatsForCenterRow, error) {
-	row := q.db.QueryRow(ctx, graderStatsForCenter, arg.MathCenterID, arg.CallerUserID)
+	row := q.db.QueryRow(ctx, graderStatsForCenter, arg.MathCenterID, arg.CallerUserID, arg.TermID)
 	var i GraderStatsForCenterRow
 	err := row.Scan(&i.PendingCount, &i.MyClaimedCount, &i.MyAppealsCount)
 	return i, err
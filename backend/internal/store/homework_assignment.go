@@ -0,0 +1,430 @@
+package store
+
+// Query surface for automatic grader assignment (migration 000030).
+// Hand-written alongside the generated homework queries, like
+// homework_photo_processing.go.
+
+import (
+	"context"
+	"time"
+)
+
+// HomeworkSeriesGrading is a series' assignment configuration. A series with
+// no row behaves as mode 'off'.
+type HomeworkSeriesGrading struct {
+	SeriesID          int64
+	Mode              string
+	ReassignOnAbsence bool
+	UpdatedAt         time.Time
+}
+
+const getSeriesGradingSQL = `
+SELECT series_id, mode, reassign_on_absence, updated_at
+FROM homework_series_grading
+WHERE series_id = $1
+`
+
+func (q *Queries) GetSeriesGrading(ctx context.Context, seriesID int64) (HomeworkSeriesGrading, error) {
+	var row HomeworkSeriesGrading
+	err := q.db.QueryRow(ctx, getSeriesGradingSQL, seriesID).
+		Scan(&row.SeriesID, &row.Mode, &row.ReassignOnAbsence, &row.UpdatedAt)
+	return row, err
+}
+
+const upsertSeriesGradingSQL = `
+INSERT INTO homework_series_grading (series_id, mode, reassign_on_absence)
+VALUES ($1, $2, $3)
+ON CONFLICT (series_id) DO UPDATE
+SET mode                = EXCLUDED.mode,
+    reassign_on_absence = EXCLUDED.reassign_on_absence,
+    updated_at          = NOW()
+RETURNING series_id, mode, reassign_on_absence, updated_at
+`
+
+type UpsertSeriesGradingParams struct {
+	SeriesID          int64
+	Mode              string
+	ReassignOnAbsence bool
+}
+
+func (q *Queries) UpsertSeriesGrading(ctx context.Context, arg UpsertSeriesGradingParams) (HomeworkSeriesGrading, error) {
+	var row HomeworkSeriesGrading
+	err := q.db.QueryRow(ctx, upsertSeriesGradingSQL, arg.SeriesID, arg.Mode, arg.ReassignOnAbsence).
+		Scan(&row.SeriesID, &row.Mode, &row.ReassignOnAbsence, &row.UpdatedAt)
+	return row, err
+}
+
+const listSeriesGradersSQL = `
+SELECT grader_user_id
+FROM homework_series_grader
+WHERE series_id = $1
+ORDER BY grader_user_id ASC
+`
+
+// ListSeriesGraders returns the round-robin pool of a series.
+func (q *Queries) ListSeriesGraders(ctx context.Context, seriesID int64) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listSeriesGradersSQL, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Members who stay keep their last_assigned_at, so editing the pool doesn't
+// restart the rotation.
+const replaceSeriesGradersSQL = `
+WITH removed AS (
+    DELETE FROM homework_series_grader
+    WHERE series_id = $1
+      AND NOT (grader_user_id = ANY ($2::bigint[]))
+)
+INSERT INTO homework_series_grader (series_id, grader_user_id)
+SELECT $1, unnest($2::bigint[])
+ON CONFLICT (series_id, grader_user_id) DO NOTHING
+`
+
+func (q *Queries) ReplaceSeriesGraders(ctx context.Context, seriesID int64, graderUserIDs []int64) error {
+	_, err := q.db.Exec(ctx, replaceSeriesGradersSQL, seriesID, graderUserIDs)
+	return err
+}
+
+// ProblemGrader is one (problem, owner) pair of by_problem mode.
+type ProblemGrader struct {
+	ProblemID    int64
+	GraderUserID int64
+}
+
+const listProblemGradersForSeriesSQL = `
+SELECT pg.problem_id, pg.grader_user_id
+FROM homework_problem_grader pg
+         JOIN math_center_problems p ON p.id = pg.problem_id
+WHERE p.series_id = $1
+ORDER BY p.number ASC, pg.grader_user_id ASC
+`
+
+func (q *Queries) ListProblemGradersForSeries(ctx context.Context, seriesID int64) ([]ProblemGrader, error) {
+	rows, err := q.db.Query(ctx, listProblemGradersForSeriesSQL, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ProblemGrader{}
+	for rows.Next() {
+		var row ProblemGrader
+		if err := rows.Scan(&row.ProblemID, &row.GraderUserID); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Pairs are passed as two parallel arrays. Pairs that survive keep their
+// last_assigned_at, same as the pool.
+const replaceProblemGradersSQL = `
+WITH wanted AS (
+    SELECT unnest($2::bigint[]) AS problem_id, unnest($3::bigint[]) AS grader_user_id
+),
+removed AS (
+    DELETE FROM homework_problem_grader pg
+    USING math_center_problems p
+    WHERE p.id = pg.problem_id
+      AND p.series_id = $1
+      AND NOT EXISTS (SELECT 1 FROM wanted w
+                      WHERE w.problem_id = pg.problem_id AND w.grader_user_id = pg.grader_user_id)
+)
+INSERT INTO homework_problem_grader (problem_id, grader_user_id)
+SELECT problem_id, grader_user_id FROM wanted
+ON CONFLICT (problem_id, grader_user_id) DO NOTHING
+`
+
+// ReplaceProblemGraders sets the problem owners of a series. The caller must
+// have checked that every problem belongs to seriesID.
+func (q *Queries) ReplaceProblemGraders(ctx context.Context, seriesID int64, owners []ProblemGrader) error {
+	problemIDs := make([]int64, len(owners))
+	graderIDs := make([]int64, len(owners))
+	for i, o := range owners {
+		problemIDs[i], graderIDs[i] = o.ProblemID, o.GraderUserID
+	}
+	_, err := q.db.Exec(ctx, replaceProblemGradersSQL, seriesID, problemIDs, graderIDs)
+	return err
+}
+
+// Candidates are the problem owners (tier 0, by_problem mode only) and the
+// series pool (tier 1), restricted to current teachers of the center and, with
+// reassign_on_absence, to those not away. The current assignee wins if still
+// a candidate, so a resubmission after a rejection returns to the same
+// grader. Otherwise: lowest tier, then fewest threads waiting on them in this
+// series, then longest since their last assignment. No candidates (mode
+// 'off', empty pool, everyone away) leaves the thread unassigned, which the
+// queue shows to everybody.
+//
+// Two submissions landing at the same instant may both pick the same grader;
+// the load term evens that out on the next one.
+const assignThreadGraderSQL = `
+WITH t AS (
+    SELECT th.id, th.series_id, th.math_center_id, th.assigned_grader_user_id, sp.problem_id
+    FROM homework_thread th
+             JOIN math_center_subproblems sp ON sp.id = th.subproblem_id
+    WHERE th.id = $1
+),
+g AS (
+    SELECT s.mode, s.reassign_on_absence
+    FROM homework_series_grading s
+             JOIN t ON t.series_id = s.series_id
+    WHERE s.mode <> 'off'
+),
+raw AS (
+    SELECT pg.grader_user_id, pg.last_assigned_at, 0 AS tier
+    FROM homework_problem_grader pg
+             JOIN t ON t.problem_id = pg.problem_id
+             CROSS JOIN g
+    WHERE g.mode = 'by_problem'
+    UNION ALL
+    SELECT sg.grader_user_id, sg.last_assigned_at, 1 AS tier
+    FROM homework_series_grader sg
+             JOIN t ON t.series_id = sg.series_id
+             CROSS JOIN g
+),
+candidates AS (
+    SELECT c.grader_user_id, c.last_assigned_at, c.tier,
+           (SELECT COUNT(*)
+            FROM homework_thread x
+            WHERE x.series_id = t.series_id
+              AND x.current_status = 'submitted'
+              AND x.assigned_grader_user_id = c.grader_user_id
+              AND x.id <> t.id) AS load
+    FROM raw c
+             CROSS JOIN t
+             CROSS JOIN g
+             JOIN math_center_teachers mct
+                  ON mct.user_id = c.grader_user_id AND mct.math_center_id = t.math_center_id
+    WHERE NOT (g.reassign_on_absence AND mct.away_until IS NOT NULL AND mct.away_until > NOW())
+),
+pick AS (
+    SELECT c.grader_user_id, c.tier
+    FROM candidates c
+             CROSS JOIN t
+    ORDER BY (c.grader_user_id IS NOT DISTINCT FROM t.assigned_grader_user_id) DESC,
+             c.tier ASC,
+             c.load ASC,
+             c.last_assigned_at ASC NULLS FIRST,
+             c.grader_user_id ASC
+    LIMIT 1
+),
+bump_owner AS (
+    UPDATE homework_problem_grader pg
+    SET last_assigned_at = NOW()
+    FROM pick, t
+    WHERE pick.tier = 0
+      AND pg.problem_id = t.problem_id
+      AND pg.grader_user_id = pick.grader_user_id
+),
+bump_pool AS (
+    UPDATE homework_series_grader sg
+    SET last_assigned_at = NOW()
+    FROM pick, t
+    WHERE pick.tier = 1
+      AND sg.series_id = t.series_id
+      AND sg.grader_user_id = pick.grader_user_id
+)
+UPDATE homework_thread th
+SET assigned_grader_user_id = (SELECT grader_user_id FROM pick)
+WHERE th.id = $1
+RETURNING th.assigned_grader_user_id
+`
+
+// AssignThreadGrader (re)computes the assignee of one thread and stores it.
+// Returns the new assignee, nil when the thread is left unassigned.
+func (q *Queries) AssignThreadGrader(ctx context.Context, threadID int64) (*int64, error) {
+	var assignee *int64
+	err := q.db.QueryRow(ctx, assignThreadGraderSQL, threadID).Scan(&assignee)
+	return assignee, err
+}
+
+const listSubmittedThreadIDsForSeriesSQL = `
+SELECT id
+FROM homework_thread
+WHERE series_id = $1
+  AND current_status = 'submitted'
+ORDER BY updated_at ASC
+`
+
+// ListSubmittedThreadIDsForSeries feeds a rebalance after the series'
+// assignment configuration changes. Oldest first, so the longest-waiting
+// submissions get first pick of the least-loaded graders.
+func (q *Queries) ListSubmittedThreadIDsForSeries(ctx context.Context, seriesID int64) ([]int64, error) {
+	return q.scanIDs(ctx, listSubmittedThreadIDsForSeriesSQL, seriesID)
+}
+
+const listAbsenceReassignableThreadIDsSQL = `
+SELECT t.id
+FROM homework_thread t
+         JOIN homework_series_grading s ON s.series_id = t.series_id
+WHERE t.math_center_id = $1
+  AND t.assigned_grader_user_id = $2
+  AND t.current_status = 'submitted'
+  AND s.mode <> 'off'
+  AND s.reassign_on_absence
+ORDER BY t.updated_at ASC
+`
+
+// ListAbsenceReassignableThreadIDs returns the pending threads assigned to a
+// grader in series of the center that opted into reassign-on-absence.
+func (q *Queries) ListAbsenceReassignableThreadIDs(ctx context.Context, centerID, graderUserID int64) ([]int64, error) {
+	return q.scanIDs(ctx, listAbsenceReassignableThreadIDsSQL, centerID, graderUserID)
+}
+
+func (q *Queries) scanIDs(ctx context.Context, sql string, args ...any) ([]int64, error) {
+	rows, err := q.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const setTeacherAwayUntilSQL = `
+UPDATE math_center_teachers
+SET away_until = $3
+WHERE math_center_id = $1
+  AND user_id = $2
+`
+
+// SetTeacherAwayUntil marks a teacher away until the given time, or present
+// again when awayUntil is nil. Returns the affected-row count (0 when the
+// user is not a teacher of the center).
+func (q *Queries) SetTeacherAwayUntil(ctx context.Context, centerID, userID int64, awayUntil *time.Time) (int64, error) {
+	tag, err := q.db.Exec(ctx, setTeacherAwayUntilSQL, centerID, userID, awayUntil)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const listTeacherAbsencesSQL = `
+SELECT user_id, away_until
+FROM math_center_teachers
+WHERE math_center_id = $1
+  AND away_until > NOW()
+ORDER BY user_id ASC
+`
+
+// TeacherAbsence is a teacher currently marked away.
+type TeacherAbsence struct {
+	UserID    int64
+	AwayUntil time.Time
+}
+
+func (q *Queries) ListTeacherAbsences(ctx context.Context, centerID int64) ([]TeacherAbsence, error) {
+	rows, err := q.db.Query(ctx, listTeacherAbsencesSQL, centerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TeacherAbsence{}
+	for rows.Next() {
+		var row TeacherAbsence
+		if err := rows.Scan(&row.UserID, &row.AwayUntil); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Same shape and claim rules as ListGraderQueueForSeries, for series with an
+// assignment mode: submitted threads show only to their assignee (or to
+// everyone while unassigned), appealed threads only to the last grader.
+// Live claims of the caller are always shown so a grader who took a thread
+// off someone else's list can still find it.
+const listAssignedGraderQueueForSeriesSQL = `
+SELECT t.id, t.student_user_id, t.subproblem_id, t.series_id, t.math_center_id,
+       t.current_status, t.last_grader_user_id, t.claim_holder_user_id,
+       t.claim_expires_at, t.updated_at,
+       u.first_name, u.middle_name, u.last_name,
+       sp.label, p.number,
+       t.assigned_grader_user_id
+FROM homework_thread t
+         JOIN users u                    ON u.id  = t.student_user_id
+         JOIN math_center_subproblems sp ON sp.id = t.subproblem_id
+         JOIN math_center_problems p     ON p.id  = sp.problem_id
+WHERE t.series_id = $1
+  AND t.current_status IN ('submitted', 'appealed')
+  AND (t.claim_holder_user_id IS NULL
+       OR t.claim_expires_at < NOW()
+       OR t.claim_holder_user_id = $2::bigint)
+  AND ((t.claim_holder_user_id = $2::bigint AND t.claim_expires_at > NOW())
+       OR (t.current_status = 'submitted'
+           AND (t.assigned_grader_user_id = $2::bigint
+                OR (NOT $3::bool AND t.assigned_grader_user_id IS NULL)))
+       OR (t.current_status = 'appealed'
+           AND (t.last_grader_user_id = $2::bigint
+                OR (NOT $3::bool AND t.last_grader_user_id IS NULL))))
+ORDER BY t.current_status ASC,
+         t.updated_at ASC
+`
+
+// AssignedGraderQueueRow extends the generated queue row with the assignee.
+type AssignedGraderQueueRow struct {
+	ListGraderQueueForSeriesRow
+	AssignedGraderUserID *int64
+}
+
+// ListAssignedGraderQueueForSeries takes the same params as
+// ListGraderQueueForSeries; MineOnly additionally hides unassigned threads.
+func (q *Queries) ListAssignedGraderQueueForSeries(ctx context.Context, arg ListGraderQueueForSeriesParams) ([]AssignedGraderQueueRow, error) {
+	rows, err := q.db.Query(ctx, listAssignedGraderQueueForSeriesSQL, arg.SeriesID, arg.CallerUserID, arg.MineOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []AssignedGraderQueueRow{}
+	for rows.Next() {
+		var i AssignedGraderQueueRow
+		if err := rows.Scan(
+			&i.ID, &i.StudentUserID, &i.SubproblemID, &i.SeriesID, &i.MathCenterID,
+			&i.CurrentStatus, &i.LastGraderUserID, &i.ClaimHolderUserID,
+			&i.ClaimExpiresAt, &i.UpdatedAt,
+			&i.StudentFirstName, &i.StudentMiddleName, &i.StudentLastName,
+			&i.SubproblemLabel, &i.ProblemNumber,
+			&i.AssignedGraderUserID,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,331 @@
+package store
+
+// Query surface for double-blind calibration grading (migration 000032).
+// Hand-written alongside the generated homework queries, like
+// homework_assignment.go.
+
+import (
+	"context"
+	"time"
+)
+
+// HomeworkCalibrationConfig is a center's sampling rate. A center with no row
+// samples nothing.
+type HomeworkCalibrationConfig struct {
+	MathCenterID int64
+	Percent      int32
+	UpdatedAt    time.Time
+}
+
+const getCalibrationConfigSQL = `
+SELECT math_center_id, percent, updated_at
+FROM homework_calibration_config
+WHERE math_center_id = $1
+`
+
+func (q *Queries) GetCalibrationConfig(ctx context.Context, mathCenterID int64) (HomeworkCalibrationConfig, error) {
+	var row HomeworkCalibrationConfig
+	err := q.db.QueryRow(ctx, getCalibrationConfigSQL, mathCenterID).
+		Scan(&row.MathCenterID, &row.Percent, &row.UpdatedAt)
+	return row, err
+}
+
+const upsertCalibrationConfigSQL = `
+INSERT INTO homework_calibration_config (math_center_id, percent)
+VALUES ($1, $2)
+ON CONFLICT (math_center_id) DO UPDATE
+SET percent    = EXCLUDED.percent,
+    updated_at = NOW()
+RETURNING math_center_id, percent, updated_at
+`
+
+func (q *Queries) UpsertCalibrationConfig(ctx context.Context, mathCenterID int64, percent int32) (HomeworkCalibrationConfig, error) {
+	var row HomeworkCalibrationConfig
+	err := q.db.QueryRow(ctx, upsertCalibrationConfigSQL, mathCenterID, percent).
+		Scan(&row.MathCenterID, &row.Percent, &row.UpdatedAt)
+	return row, err
+}
+
+// The bucket (0–99) is stored on the thread when it is created, so the draw
+// is neither chosen nor retried by the client; the row is only written when
+// it falls under the center's percent. A center without a config row never
+// samples.
+const sampleCalibrationSQL = `
+INSERT INTO homework_calibration (thread_id, graded_event_id, first_grader_user_id, first_verdict)
+SELECT $1, $2, $3, $4
+FROM homework_calibration_config c
+JOIN homework_thread t ON t.id = $1
+WHERE c.math_center_id = $5
+  AND t.calibration_bucket < c.percent
+ON CONFLICT (graded_event_id) DO NOTHING
+`
+
+type SampleCalibrationParams struct {
+	ThreadID          int64
+	GradedEventID     int64
+	FirstGraderUserID int64
+	FirstVerdict      string
+	MathCenterID      int64
+}
+
+// SampleCalibration queues the graded event for a blind second grading when
+// it is drawn. Returns the number of rows written (0 or 1).
+func (q *Queries) SampleCalibration(ctx context.Context, arg SampleCalibrationParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, sampleCalibrationSQL,
+		arg.ThreadID, arg.GradedEventID, arg.FirstGraderUserID, arg.FirstVerdict, arg.MathCenterID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// HomeworkCalibration is one sampled verdict with the thread coordinates the
+// handlers need for authorization and display.
+type HomeworkCalibration struct {
+	ID                 int64
+	ThreadID           int64
+	GradedEventID      int64
+	FirstGraderUserID  int64
+	FirstVerdict       string
+	SecondGraderUserID *int64
+	SecondVerdict      *string
+	SecondBody         string
+	CreatedAt          time.Time
+	CompletedAt        *time.Time
+	ResolvedByUserID   *int64
+	ResolvedAt         *time.Time
+	MathCenterID       int64
+	SeriesID           int64
+	SubproblemID       int64
+	StudentUserID      int64
+}
+
+const calibrationColumns = `
+    c.id, c.thread_id, c.graded_event_id, c.first_grader_user_id, c.first_verdict,
+    c.second_grader_user_id, c.second_verdict, c.second_body, c.created_at, c.completed_at,
+    c.resolved_by_user_id, c.resolved_at,
+    t.math_center_id, t.series_id, t.subproblem_id, t.student_user_id`
+
+func scanCalibration(row interface{ Scan(...any) error }) (HomeworkCalibration, error) {
+	var c HomeworkCalibration
+	err := row.Scan(&c.ID, &c.ThreadID, &c.GradedEventID, &c.FirstGraderUserID, &c.FirstVerdict,
+		&c.SecondGraderUserID, &c.SecondVerdict, &c.SecondBody, &c.CreatedAt, &c.CompletedAt,
+		&c.ResolvedByUserID, &c.ResolvedAt,
+		&c.MathCenterID, &c.SeriesID, &c.SubproblemID, &c.StudentUserID)
+	return c, err
+}
+
+func (q *Queries) listCalibrations(ctx context.Context, sql string, args ...any) ([]HomeworkCalibration, error) {
+	rows, err := q.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []HomeworkCalibration{}
+	for rows.Next() {
+		c, err := scanCalibration(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const getCalibrationSQL = `
+SELECT` + calibrationColumns + `
+FROM homework_calibration c
+         JOIN homework_thread t ON t.id = c.thread_id
+WHERE c.id = $1
+`
+
+func (q *Queries) GetCalibration(ctx context.Context, id int64) (HomeworkCalibration, error) {
+	return scanCalibration(q.db.QueryRow(ctx, getCalibrationSQL, id))
+}
+
+// A first verdict that was later retracted is no longer anything to agree or
+// disagree with, so it drops out of the queue and of every report.
+const calibrationNotRetracted = `
+NOT EXISTS (SELECT 1
+            FROM homework_thread_event r
+            WHERE r.kind = 'retracted'
+              AND r.refers_to_event_id = c.graded_event_id)`
+
+const listPendingCalibrationsSQL = `
+SELECT` + calibrationColumns + `
+FROM homework_calibration c
+         JOIN homework_thread t ON t.id = c.thread_id
+WHERE t.math_center_id = $1
+  AND c.completed_at IS NULL
+  AND c.first_grader_user_id <> $2
+  AND ($3::bigint IS NULL
+       OR t.series_id IN (SELECT id FROM math_center_series WHERE term_id = $3))
+  AND` + calibrationNotRetracted + `
+ORDER BY c.created_at ASC, c.id ASC
+`
+
+// ListPendingCalibrations is the second-grading queue of a center, minus the
+// caller's own first verdicts. A non-nil termID keeps that term's series.
+func (q *Queries) ListPendingCalibrations(ctx context.Context, mathCenterID, callerUserID int64, termID *int64) ([]HomeworkCalibration, error) {
+	return q.listCalibrations(ctx, listPendingCalibrationsSQL, mathCenterID, callerUserID, termID)
+}
+
+const pendingCalibrationOnThreadSQL = `
+SELECT c.graded_event_id
+FROM homework_calibration c
+WHERE c.thread_id = $1
+  AND c.completed_at IS NULL
+  AND c.first_grader_user_id <> $2
+  AND` + calibrationNotRetracted + `
+ORDER BY c.graded_event_id ASC
+LIMIT 1
+`
+
+// PendingCalibrationOnThread returns the earliest sampled verdict on the
+// thread the caller could still second-grade. pgx.ErrNoRows means there is
+// none, so the caller may see the thread unblinded.
+func (q *Queries) PendingCalibrationOnThread(ctx context.Context, threadID, callerUserID int64) (int64, error) {
+	var gradedEventID int64
+	err := q.db.QueryRow(ctx, pendingCalibrationOnThreadSQL, threadID, callerUserID).Scan(&gradedEventID)
+	return gradedEventID, err
+}
+
+// The guard makes the first second-verdict win and keeps the first grader
+// from calibrating themselves.
+const completeCalibrationSQL = `
+WITH c AS (
+    UPDATE homework_calibration
+    SET second_grader_user_id = $2,
+        second_verdict        = $3,
+        second_body           = $4,
+        completed_at          = NOW()
+    WHERE id = $1
+      AND completed_at IS NULL
+      AND first_grader_user_id <> $2
+    RETURNING *
+)
+SELECT` + calibrationColumns + `
+FROM c
+         JOIN homework_thread t ON t.id = c.thread_id
+`
+
+type CompleteCalibrationParams struct {
+	ID                 int64
+	SecondGraderUserID int64
+	SecondVerdict      string
+	SecondBody         string
+}
+
+// CompleteCalibration records the blind second verdict. pgx.ErrNoRows means
+// somebody else got there first (or the caller is the first grader).
+func (q *Queries) CompleteCalibration(ctx context.Context, arg CompleteCalibrationParams) (HomeworkCalibration, error) {
+	return scanCalibration(q.db.QueryRow(ctx, completeCalibrationSQL,
+		arg.ID, arg.SecondGraderUserID, arg.SecondVerdict, arg.SecondBody))
+}
+
+const listCalibrationDisagreementsSQL = `
+SELECT` + calibrationColumns + `
+FROM homework_calibration c
+         JOIN homework_thread t ON t.id = c.thread_id
+WHERE t.math_center_id = $1
+  AND c.completed_at IS NOT NULL
+  AND c.second_verdict <> c.first_verdict
+  AND ($2::bool OR c.resolved_at IS NULL)
+  AND` + calibrationNotRetracted + `
+ORDER BY c.completed_at DESC, c.id DESC
+`
+
+// ListCalibrationDisagreements returns completed pairs whose verdicts differ,
+// newest first; includeResolved also returns those already reviewed.
+func (q *Queries) ListCalibrationDisagreements(ctx context.Context, mathCenterID int64, includeResolved bool) ([]HomeworkCalibration, error) {
+	return q.listCalibrations(ctx, listCalibrationDisagreementsSQL, mathCenterID, includeResolved)
+}
+
+const resolveCalibrationSQL = `
+UPDATE homework_calibration c
+SET resolved_by_user_id = $3,
+    resolved_at         = NOW()
+FROM homework_thread t
+WHERE c.id = $1
+  AND t.id = c.thread_id
+  AND t.math_center_id = $2
+  AND c.completed_at IS NOT NULL
+  AND c.resolved_at IS NULL
+`
+
+// ResolveCalibration marks a disagreement as reviewed. Zero rows means the
+// calibration is not in the center, not completed, or already resolved.
+func (q *Queries) ResolveCalibration(ctx context.Context, id, mathCenterID, userID int64) (int64, error) {
+	tag, err := q.db.Exec(ctx, resolveCalibrationSQL, id, mathCenterID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CalibrationAgreementRow is one grader's tally over completed calibrations,
+// split by which side of the pair they were on.
+type CalibrationAgreementRow struct {
+	GraderUserID    int64
+	GraderFirstName string
+	GraderLastName  string
+	AsFirstTotal    int64
+	AsFirstAgreed   int64
+	AsSecondTotal   int64
+	AsSecondAgreed  int64
+}
+
+const calibrationAgreementForTermSQL = `
+WITH done AS (
+    SELECT c.first_grader_user_id, c.second_grader_user_id,
+           (c.first_verdict = c.second_verdict) AS agreed
+    FROM homework_calibration c
+             JOIN homework_thread t ON t.id = c.thread_id
+             JOIN math_center_series s ON s.id = t.series_id
+    WHERE t.math_center_id = $1
+      AND s.term_id = $2
+      AND c.completed_at IS NOT NULL
+      AND c.second_grader_user_id IS NOT NULL
+      AND` + calibrationNotRetracted + `
+),
+sides AS (
+    SELECT first_grader_user_id AS grader_user_id, 1 AS first_n, agreed::int AS first_ok, 0 AS second_n, 0 AS second_ok
+    FROM done
+    UNION ALL
+    SELECT second_grader_user_id, 0, 0, 1, agreed::int
+    FROM done
+)
+SELECT s.grader_user_id, u.first_name, u.last_name,
+       SUM(s.first_n)::bigint, SUM(s.first_ok)::bigint,
+       SUM(s.second_n)::bigint, SUM(s.second_ok)::bigint
+FROM sides s
+         JOIN users u ON u.id = s.grader_user_id
+GROUP BY s.grader_user_id, u.last_name, u.first_name
+ORDER BY u.last_name ASC, u.first_name ASC, s.grader_user_id ASC
+`
+
+// CalibrationAgreementForTerm tallies agreement per grader over the
+// calibrations of series in one term.
+func (q *Queries) CalibrationAgreementForTerm(ctx context.Context, mathCenterID, termID int64) ([]CalibrationAgreementRow, error) {
+	rows, err := q.db.Query(ctx, calibrationAgreementForTermSQL, mathCenterID, termID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []CalibrationAgreementRow{}
+	for rows.Next() {
+		var r CalibrationAgreementRow
+		if err := rows.Scan(&r.GraderUserID, &r.GraderFirstName, &r.GraderLastName,
+			&r.AsFirstTotal, &r.AsFirstAgreed, &r.AsSecondTotal, &r.AsSecondAgreed); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,149 @@
+package store
+
+// Query surface for the homework event hash chain (migration 000033).
+// Hand-written alongside the generated homework queries, like
+// homework_consistency.go. The hashes themselves are computed in Go
+// (homework.EventHash); these queries only lock, store and read them.
+
+import "context"
+
+// Under READ COMMITTED a FOR UPDATE that waited re-reads the row it locked,
+// so the head returned is the one the previous appender committed.
+const lockEventChainSQL = `
+SELECT event_chain_head
+FROM homework_thread
+WHERE id = $1
+FOR UPDATE
+`
+
+// LockEventChain takes the thread's row lock and returns the hash of its
+// newest sealed event (nil when nothing is sealed yet). Call it before
+// appending so concurrent appends to one thread chain up instead of forking.
+func (q *Queries) LockEventChain(ctx context.Context, threadID int64) ([]byte, error) {
+	var head []byte
+	err := q.db.QueryRow(ctx, lockEventChainSQL, threadID).Scan(&head)
+	return head, err
+}
+
+const sealEventSQL = `
+WITH e AS (
+    UPDATE homework_thread_event
+    SET prev_hash = $3,
+        hash      = $4
+    WHERE id = $1
+      AND thread_id = $2
+      AND hash IS NULL
+    RETURNING thread_id
+)
+UPDATE homework_thread t
+SET event_chain_head = $4
+FROM e
+WHERE t.id = e.thread_id
+`
+
+type SealEventParams struct {
+	EventID  int64
+	ThreadID int64
+	PrevHash []byte
+	Hash     []byte
+}
+
+// SealEvent stores an event's link and advances the thread's head. An event
+// is sealed once; zero rows means it was already sealed or is not on the
+// thread.
+func (q *Queries) SealEvent(ctx context.Context, arg SealEventParams) (int64, error) {
+	prev := arg.PrevHash
+	if prev == nil {
+		// The first link stores an empty prev_hash; NULL means "unsealed".
+		prev = []byte{}
+	}
+	tag, err := q.db.Exec(ctx, sealEventSQL, arg.EventID, arg.ThreadID, prev, arg.Hash)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ChainThreadRow is a thread as the chain verifier pages through them.
+type ChainThreadRow struct {
+	ID             int64
+	MathCenterID   int64
+	SeriesID       int64
+	EventChainHead []byte
+}
+
+const listChainThreadsSQL = `
+SELECT id, math_center_id, series_id, event_chain_head
+FROM homework_thread
+WHERE ($1::bigint = 0 OR math_center_id = $1)
+  AND ($2::bigint = 0 OR id = $2)
+  AND id > $3
+ORDER BY id ASC
+LIMIT $4
+`
+
+type ListChainThreadsParams struct {
+	MathCenterID int64 // 0 = every center
+	ThreadID     int64 // 0 = every thread
+	AfterID      int64
+	Limit        int32
+}
+
+func (q *Queries) ListChainThreads(ctx context.Context, arg ListChainThreadsParams) ([]ChainThreadRow, error) {
+	rows, err := q.db.Query(ctx, listChainThreadsSQL, arg.MathCenterID, arg.ThreadID, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ChainThreadRow{}
+	for rows.Next() {
+		var r ChainThreadRow
+		if err := rows.Scan(&r.ID, &r.MathCenterID, &r.SeriesID, &r.EventChainHead); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ChainEventRow is an event with the columns the hash covers and its link.
+type ChainEventRow struct {
+	HomeworkThreadEvent
+	PrevHash []byte
+	Hash     []byte
+}
+
+const listChainEventsForThreadsSQL = `
+SELECT id, thread_id, event_uuid, kind, actor_user_id, body, verdict, refers_to_event_id,
+       created_at, is_offline, credited_grader_user_id, credited_grader_name, prev_hash, hash
+FROM homework_thread_event
+WHERE thread_id = ANY ($1::bigint[])
+ORDER BY thread_id ASC, id ASC
+`
+
+// ListChainEventsForThreads returns the logs of several threads at once, in
+// append order within each thread.
+func (q *Queries) ListChainEventsForThreads(ctx context.Context, threadIDs []int64) ([]ChainEventRow, error) {
+	rows, err := q.db.Query(ctx, listChainEventsForThreadsSQL, threadIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ChainEventRow{}
+	for rows.Next() {
+		var r ChainEventRow
+		if err := rows.Scan(&r.ID, &r.ThreadID, &r.EventUuid, &r.Kind, &r.ActorUserID, &r.Body, &r.Verdict,
+			&r.RefersToEventID, &r.CreatedAt, &r.IsOffline, &r.CreditedGraderUserID, &r.CreditedGraderName,
+			&r.PrevHash, &r.Hash); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,154 @@
+package store
+
+// Query surface for the thread cache consistency checker (cmd/hwcheck).
+// Hand-written alongside the generated homework queries, like
+// homework_housekeeping.go.
+
+import "context"
+
+const homeworkThreadColumns = `id, student_user_id, subproblem_id, series_id, math_center_id, current_status,
+       current_attempt_event_id, current_grade_event_id, last_grader_user_id, claim_holder_user_id,
+       claim_expires_at, created_at, updated_at, last_grader_name`
+
+func scanHomeworkThread(row interface{ Scan(...any) error }) (HomeworkThread, error) {
+	var i HomeworkThread
+	err := row.Scan(
+		&i.ID,
+		&i.StudentUserID,
+		&i.SubproblemID,
+		&i.SeriesID,
+		&i.MathCenterID,
+		&i.CurrentStatus,
+		&i.CurrentAttemptEventID,
+		&i.CurrentGradeEventID,
+		&i.LastGraderUserID,
+		&i.ClaimHolderUserID,
+		&i.ClaimExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastGraderName,
+	)
+	return i, err
+}
+
+// Keyset-paginated on id so a full-database check streams in pages.
+const listThreadsForCheckSQL = `
+SELECT ` + homeworkThreadColumns + `
+FROM homework_thread
+WHERE ($1::bigint = 0 OR math_center_id = $1)
+  AND ($2::bigint = 0 OR series_id = $2)
+  AND id > $3
+ORDER BY id ASC
+LIMIT $4
+`
+
+type ListThreadsForCheckParams struct {
+	MathCenterID int64 // 0 = every center
+	SeriesID     int64 // 0 = every series
+	AfterID      int64
+	Limit        int32
+}
+
+func (q *Queries) ListThreadsForCheck(ctx context.Context, arg ListThreadsForCheckParams) ([]HomeworkThread, error) {
+	rows, err := q.db.Query(ctx, listThreadsForCheckSQL, arg.MathCenterID, arg.SeriesID, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []HomeworkThread{}
+	for rows.Next() {
+		t, err := scanHomeworkThread(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReplayEventRow is the cache-relevant projection of an event.
+type ReplayEventRow struct {
+	ThreadID             int64
+	ID                   int64
+	Kind                 string
+	ActorUserID          int64
+	Verdict              *string
+	CreditedGraderUserID *int64
+	CreditedGraderName   string
+}
+
+const listReplayEventsForThreadsSQL = `
+SELECT thread_id, id, kind, actor_user_id, verdict, credited_grader_user_id, credited_grader_name
+FROM homework_thread_event
+WHERE thread_id = ANY ($1::bigint[])
+ORDER BY thread_id ASC, id ASC
+`
+
+// ListReplayEventsForThreads returns the logs of several threads at once, in
+// append order within each thread.
+func (q *Queries) ListReplayEventsForThreads(ctx context.Context, threadIDs []int64) ([]ReplayEventRow, error) {
+	rows, err := q.db.Query(ctx, listReplayEventsForThreadsSQL, threadIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ReplayEventRow{}
+	for rows.Next() {
+		var r ReplayEventRow
+		if err := rows.Scan(&r.ThreadID, &r.ID, &r.Kind, &r.ActorUserID, &r.Verdict,
+			&r.CreditedGraderUserID, &r.CreditedGraderName); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const lockThreadSQL = `
+SELECT ` + homeworkThreadColumns + `
+FROM homework_thread
+WHERE id = $1
+FOR UPDATE
+`
+
+// LockThread reads a thread under a row lock, so a repair cannot interleave
+// with a handler appending to the same thread.
+func (q *Queries) LockThread(ctx context.Context, id int64) (HomeworkThread, error) {
+	return scanHomeworkThread(q.db.QueryRow(ctx, lockThreadSQL, id))
+}
+
+// A claim only means something on a gradable thread, so the rebuild drops
+// any claim left on a thread whose status is not submitted/appealed.
+const rebuildThreadCacheSQL = `
+UPDATE homework_thread
+SET current_status           = $2::text,
+    current_attempt_event_id = $3,
+    current_grade_event_id   = $4,
+    last_grader_user_id      = $5,
+    last_grader_name         = $6,
+    claim_holder_user_id     = CASE WHEN $2::text IN ('submitted', 'appealed') THEN claim_holder_user_id END,
+    claim_expires_at         = CASE WHEN $2::text IN ('submitted', 'appealed') THEN claim_expires_at END,
+    updated_at               = NOW()
+WHERE id = $1
+`
+
+type RebuildThreadCacheParams struct {
+	ID                    int64
+	CurrentStatus         string
+	CurrentAttemptEventID *int64
+	CurrentGradeEventID   *int64
+	LastGraderUserID      *int64
+	LastGraderName        string
+}
+
+func (q *Queries) RebuildThreadCache(ctx context.Context, arg RebuildThreadCacheParams) error {
+	_, err := q.db.Exec(ctx, rebuildThreadCacheSQL, arg.ID, arg.CurrentStatus, arg.CurrentAttemptEventID,
+		arg.CurrentGradeEventID, arg.LastGraderUserID, arg.LastGraderName)
+	return err
+}
@@ -0,0 +1,122 @@
+package store
+
+// Query surface for server-side submission drafts. Hand-written like the
+// rest of the post-sqlc homework files; drafts never touch
+// homework_thread_event.
+
+import (
+	"context"
+	"time"
+)
+
+// HomeworkAttemptDraft is a student's unsent attempt on one thread.
+type HomeworkAttemptDraft struct {
+	ThreadID   int64
+	EventUUID  string
+	Body       string
+	ObjectKeys []string
+	UpdatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+const getAttemptDraftSQL = `
+SELECT d.thread_id, d.event_uuid, d.body, d.object_keys, d.updated_at, d.expires_at
+FROM homework_attempt_draft d
+         JOIN homework_thread t ON t.id = d.thread_id
+WHERE t.student_user_id = $1
+  AND t.subproblem_id = $2
+  AND d.expires_at > $3
+`
+
+// GetAttemptDraft returns the student's draft for the subproblem unless it
+// expired before now.
+func (q *Queries) GetAttemptDraft(ctx context.Context, studentUserID, subproblemID int64, now time.Time) (HomeworkAttemptDraft, error) {
+	var d HomeworkAttemptDraft
+	err := q.db.QueryRow(ctx, getAttemptDraftSQL, studentUserID, subproblemID, now).
+		Scan(&d.ThreadID, &d.EventUUID, &d.Body, &d.ObjectKeys, &d.UpdatedAt, &d.ExpiresAt)
+	return d, err
+}
+
+type UpsertAttemptDraftParams struct {
+	ThreadID   int64
+	EventUUID  string
+	Body       string
+	ObjectKeys []string
+	ExpiresAt  time.Time
+}
+
+const upsertAttemptDraftSQL = `
+INSERT INTO homework_attempt_draft (thread_id, event_uuid, body, object_keys, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (thread_id) DO UPDATE
+    SET event_uuid  = EXCLUDED.event_uuid,
+        body        = EXCLUDED.body,
+        object_keys = EXCLUDED.object_keys,
+        updated_at  = NOW(),
+        expires_at  = EXCLUDED.expires_at
+RETURNING thread_id, event_uuid, body, object_keys, updated_at, expires_at
+`
+
+// UpsertAttemptDraft replaces the thread's draft wholesale.
+func (q *Queries) UpsertAttemptDraft(ctx context.Context, arg UpsertAttemptDraftParams) (HomeworkAttemptDraft, error) {
+	var d HomeworkAttemptDraft
+	err := q.db.QueryRow(ctx, upsertAttemptDraftSQL, arg.ThreadID, arg.EventUUID, arg.Body, arg.ObjectKeys, arg.ExpiresAt).
+		Scan(&d.ThreadID, &d.EventUUID, &d.Body, &d.ObjectKeys, &d.UpdatedAt, &d.ExpiresAt)
+	return d, err
+}
+
+const deleteAttemptDraftSQL = `
+DELETE FROM homework_attempt_draft d
+    USING homework_thread t
+WHERE t.id = d.thread_id
+  AND t.student_user_id = $1
+  AND t.subproblem_id = $2
+RETURNING d.thread_id, d.event_uuid, d.object_keys
+`
+
+// DeleteAttemptDraft removes the student's draft for the subproblem and
+// returns what it held so the caller can clean up the photos. pgx.ErrNoRows
+// when there was none.
+func (q *Queries) DeleteAttemptDraft(ctx context.Context, studentUserID, subproblemID int64) (HomeworkAttemptDraft, error) {
+	var d HomeworkAttemptDraft
+	err := q.db.QueryRow(ctx, deleteAttemptDraftSQL, studentUserID, subproblemID).
+		Scan(&d.ThreadID, &d.EventUUID, &d.ObjectKeys)
+	return d, err
+}
+
+const clearAttemptDraftSQL = `DELETE FROM homework_attempt_draft WHERE thread_id = $1`
+
+// ClearAttemptDraft drops the thread's draft, if any. A submitted attempt
+// supersedes it.
+func (q *Queries) ClearAttemptDraft(ctx context.Context, threadID int64) error {
+	_, err := q.db.Exec(ctx, clearAttemptDraftSQL, threadID)
+	return err
+}
+
+const deleteExpiredAttemptDraftsSQL = `
+DELETE FROM homework_attempt_draft
+WHERE expires_at <= $1
+RETURNING thread_id, event_uuid, object_keys
+`
+
+// DeleteExpiredAttemptDrafts removes every draft expired by now and returns
+// them for photo cleanup.
+func (q *Queries) DeleteExpiredAttemptDrafts(ctx context.Context, now time.Time) ([]HomeworkAttemptDraft, error) {
+	rows, err := q.db.Query(ctx, deleteExpiredAttemptDraftsSQL, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []HomeworkAttemptDraft{}
+	for rows.Next() {
+		var d HomeworkAttemptDraft
+		if err := rows.Scan(&d.ThreadID, &d.EventUUID, &d.ObjectKeys); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
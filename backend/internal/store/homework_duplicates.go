@@ -0,0 +1,223 @@
+package store
+
+// Query surface for copied-solution detection. Flags are written by the photo
+// pipeline (photo hashes) and by the attempt transaction (text digests), and
+// read back by the teacher-only thread view and series report.
+
+import (
+	"context"
+	"time"
+)
+
+type RecordPhotoHashParams struct {
+	EventID     int64
+	Idx         int32
+	Hash        int64
+	MaxDistance int
+}
+
+// Stores the hash on the photo row and flags every hashed photo of another
+// student's attempt on the same subproblem within MaxDistance bits. Only
+// attempts (submitted / appealed) take part; grader photos are ignored.
+const recordPhotoHashSQL = `
+WITH ph AS (
+    UPDATE homework_thread_event_photo
+    SET phash = $3
+    WHERE event_id = $1
+      AND idx = $2
+    RETURNING event_id, idx
+)
+INSERT INTO homework_duplicate_flag
+    (kind, series_id, subproblem_id, event_id, photo_idx, other_event_id, other_photo_idx, distance)
+SELECT 'photo', t.series_id, t.subproblem_id, ph.event_id, ph.idx, op.event_id, op.idx,
+       bit_count(($3::bigint # op.phash)::bit(64))
+FROM ph
+         JOIN homework_thread_event e ON e.id = ph.event_id
+         JOIN homework_thread t ON t.id = e.thread_id
+         JOIN homework_thread ot
+              ON ot.subproblem_id = t.subproblem_id
+             AND ot.student_user_id <> t.student_user_id
+         JOIN homework_thread_event oe
+              ON oe.thread_id = ot.id
+             AND oe.kind IN ('submitted', 'appealed')
+         JOIN homework_thread_event_photo op
+              ON op.event_id = oe.id
+             AND op.phash IS NOT NULL
+WHERE e.kind IN ('submitted', 'appealed')
+  AND bit_count(($3::bigint # op.phash)::bit(64)) <= $4
+ON CONFLICT DO NOTHING
+`
+
+// RecordPhotoHash returns how many new flags the photo raised.
+func (q *Queries) RecordPhotoHash(ctx context.Context, arg RecordPhotoHashParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, recordPhotoHashSQL, arg.EventID, arg.Idx, arg.Hash, arg.MaxDistance)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Stores the attempt's body digest and flags every attempt of another
+// student on the same subproblem with the same digest.
+const recordBodyDigestSQL = `
+WITH d AS (
+    INSERT INTO homework_body_digest (event_id, digest)
+    VALUES ($1, $2)
+    ON CONFLICT (event_id) DO NOTHING
+    RETURNING event_id, digest
+)
+INSERT INTO homework_duplicate_flag (kind, series_id, subproblem_id, event_id, other_event_id)
+SELECT 'text', t.series_id, t.subproblem_id, d.event_id, od.event_id
+FROM d
+         JOIN homework_thread_event e ON e.id = d.event_id
+         JOIN homework_thread t ON t.id = e.thread_id
+         JOIN homework_body_digest od ON od.digest = d.digest
+         JOIN homework_thread_event oe ON oe.id = od.event_id
+         JOIN homework_thread ot
+              ON ot.id = oe.thread_id
+             AND ot.subproblem_id = t.subproblem_id
+             AND ot.student_user_id <> t.student_user_id
+ON CONFLICT DO NOTHING
+`
+
+// RecordBodyDigest returns how many new flags the text raised.
+func (q *Queries) RecordBodyDigest(ctx context.Context, eventID int64, digest []byte) (int64, error) {
+	tag, err := q.db.Exec(ctx, recordBodyDigestSQL, eventID, digest)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ThreadDuplicateFlag is one flag seen from a given thread: EventID and
+// PhotoIdx are on that thread, the Other* fields on the matching one.
+type ThreadDuplicateFlag struct {
+	ID                    int64
+	Kind                  string
+	Distance              int32
+	CreatedAt             time.Time
+	EventID               int64
+	PhotoIdx              int32
+	OtherThreadID         int64
+	OtherEventID          int64
+	OtherPhotoIdx         int32
+	OtherStudentUserID    int64
+	OtherStudentFirstName string
+	OtherStudentLastName  string
+}
+
+// A flag names the later attempt first; the second half of the UNION turns
+// flags raised by a later attempt on another thread around.
+const listThreadDuplicateFlagsSQL = `
+SELECT f.id, f.kind, f.distance, f.created_at,
+       f.event_id, f.photo_idx,
+       ot.id, f.other_event_id, f.other_photo_idx,
+       ot.student_user_id, u.first_name, u.last_name
+FROM homework_duplicate_flag f
+         JOIN homework_thread_event e ON e.id = f.event_id
+         JOIN homework_thread_event oe ON oe.id = f.other_event_id
+         JOIN homework_thread ot ON ot.id = oe.thread_id
+         JOIN users u ON u.id = ot.student_user_id
+WHERE e.thread_id = $1
+UNION ALL
+SELECT f.id, f.kind, f.distance, f.created_at,
+       f.other_event_id, f.other_photo_idx,
+       ot.id, f.event_id, f.photo_idx,
+       ot.student_user_id, u.first_name, u.last_name
+FROM homework_duplicate_flag f
+         JOIN homework_thread_event e ON e.id = f.event_id
+         JOIN homework_thread ot ON ot.id = e.thread_id
+         JOIN homework_thread_event oe ON oe.id = f.other_event_id
+         JOIN users u ON u.id = ot.student_user_id
+WHERE oe.thread_id = $1
+ORDER BY 4, 1
+`
+
+func (q *Queries) ListThreadDuplicateFlags(ctx context.Context, threadID int64) ([]ThreadDuplicateFlag, error) {
+	rows, err := q.db.Query(ctx, listThreadDuplicateFlagsSQL, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ThreadDuplicateFlag{}
+	for rows.Next() {
+		var r ThreadDuplicateFlag
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Distance, &r.CreatedAt,
+			&r.EventID, &r.PhotoIdx,
+			&r.OtherThreadID, &r.OtherEventID, &r.OtherPhotoIdx,
+			&r.OtherStudentUserID, &r.OtherStudentFirstName, &r.OtherStudentLastName); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SeriesDuplicateFlag is one flagged pair in a series: the later attempt
+// (ThreadID / EventID) and the earlier one it matched.
+type SeriesDuplicateFlag struct {
+	ID                    int64
+	Kind                  string
+	Distance              int32
+	CreatedAt             time.Time
+	SubproblemID          int64
+	ProblemNumber         int32
+	SubproblemLabel       string
+	ThreadID              int64
+	EventID               int64
+	PhotoIdx              int32
+	StudentUserID         int64
+	StudentFirstName      string
+	StudentLastName       string
+	OtherThreadID         int64
+	OtherEventID          int64
+	OtherPhotoIdx         int32
+	OtherStudentUserID    int64
+	OtherStudentFirstName string
+	OtherStudentLastName  string
+}
+
+const listSeriesDuplicateFlagsSQL = `
+SELECT f.id, f.kind, f.distance, f.created_at,
+       sp.id, p.number, sp.label,
+       t.id, f.event_id, f.photo_idx, t.student_user_id, u.first_name, u.last_name,
+       ot.id, f.other_event_id, f.other_photo_idx, ot.student_user_id, ou.first_name, ou.last_name
+FROM homework_duplicate_flag f
+         JOIN math_center_subproblems sp ON sp.id = f.subproblem_id
+         JOIN math_center_problems p ON p.id = sp.problem_id
+         JOIN homework_thread_event e ON e.id = f.event_id
+         JOIN homework_thread t ON t.id = e.thread_id
+         JOIN users u ON u.id = t.student_user_id
+         JOIN homework_thread_event oe ON oe.id = f.other_event_id
+         JOIN homework_thread ot ON ot.id = oe.thread_id
+         JOIN users ou ON ou.id = ot.student_user_id
+WHERE f.series_id = $1
+ORDER BY p.number ASC, sp.label ASC, f.created_at ASC, f.id ASC
+`
+
+func (q *Queries) ListSeriesDuplicateFlags(ctx context.Context, seriesID int64) ([]SeriesDuplicateFlag, error) {
+	rows, err := q.db.Query(ctx, listSeriesDuplicateFlagsSQL, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SeriesDuplicateFlag{}
+	for rows.Next() {
+		var r SeriesDuplicateFlag
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Distance, &r.CreatedAt,
+			&r.SubproblemID, &r.ProblemNumber, &r.SubproblemLabel,
+			&r.ThreadID, &r.EventID, &r.PhotoIdx, &r.StudentUserID, &r.StudentFirstName, &r.StudentLastName,
+			&r.OtherThreadID, &r.OtherEventID, &r.OtherPhotoIdx, &r.OtherStudentUserID,
+			&r.OtherStudentFirstName, &r.OtherStudentLastName); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
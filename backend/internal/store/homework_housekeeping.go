@@ -0,0 +1,105 @@
+package store
+
+// Query surface for the homework housekeeping sweeper: lease expiry and
+// orphaned-upload garbage collection. Hand-written alongside the generated
+// homework queries, like homework_photo_processing.go.
+
+import "context"
+
+// ExpiredClaim identifies a thread whose stale claim was just cleared, with
+// enough scope to publish a live refresh for its center and series.
+type ExpiredClaim struct {
+	ThreadID     int64
+	MathCenterID int64
+	SeriesID     int64
+}
+
+// The claim columns are re-checked under the row lock, so a heartbeat that
+// lands between the scan and the update keeps its claim, and two replicas
+// sweeping at once never both report the same thread.
+const expireStaleClaimsSQL = `
+UPDATE homework_thread
+SET claim_holder_user_id = NULL,
+    claim_expires_at     = NULL,
+    updated_at           = NOW()
+WHERE claim_holder_user_id IS NOT NULL
+  AND claim_expires_at < NOW()
+RETURNING id, math_center_id, series_id
+`
+
+// ExpireStaleClaims clears every claim whose soft TTL has passed. Reads
+// already treat such claims as free; this only tidies the row so queues and
+// the "in review" badge stop showing a grader who walked away.
+func (q *Queries) ExpireStaleClaims(ctx context.Context) ([]ExpiredClaim, error) {
+	rows, err := q.db.Query(ctx, expireStaleClaimsSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ExpiredClaim{}
+	for rows.Next() {
+		var row ExpiredClaim
+		if err := rows.Scan(&row.ThreadID, &row.MathCenterID, &row.SeriesID); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Events are reached by UUID rather than by key so the lookup rides the
+// UNIQUE (event_uuid) index and the photo event_id index; there is no index
+// on the key columns themselves.
+const listReferencedObjectKeysSQL = `
+SELECT k.object_key
+FROM homework_thread_event e
+         JOIN homework_thread_event_photo p ON p.event_id = e.id
+         CROSS JOIN LATERAL (VALUES (p.object_key),
+                                    (p.preview_object_key),
+                                    (p.thumb_object_key)) AS k (object_key)
+WHERE e.event_uuid = ANY ($1::text[])
+  AND k.object_key IS NOT NULL
+UNION
+SELECT unnest(object_keys)
+FROM homework_attempt_draft
+WHERE event_uuid = ANY ($1::text[])
+`
+
+// ListReferencedObjectKeys returns every object key that an event photo
+// (upload or rendition) or a live submission draft under one of uuids still
+// points at. Anything else under those prefixes is garbage: a retried upload
+// slot that was never finalized, or a raw upload the photo pipeline failed to
+// delete.
+func (q *Queries) ListReferencedObjectKeys(ctx context.Context, uuids []string) ([]string, error) {
+	rows, err := q.db.Query(ctx, listReferencedObjectKeysSQL, uuids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		out = append(out, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const tryAdvisoryXactLockSQL = `SELECT pg_try_advisory_xact_lock($1::bigint)`
+
+// TryAdvisoryXactLock takes a transaction-scoped advisory lock without
+// waiting. Only meaningful on a Queries bound to a transaction: the lock is
+// released at commit/rollback.
+func (q *Queries) TryAdvisoryXactLock(ctx context.Context, key int64) (bool, error) {
+	var locked bool
+	err := q.db.QueryRow(ctx, tryAdvisoryXactLockSQL, key).Scan(&locked)
+	return locked, err
+}
@@ -0,0 +1,182 @@
+package store
+
+// Query surface for the background photo pipeline. Kept out of the generated
+// homework.sql.go because the photo row now doubles as a leased work queue,
+// and the existing generated photo queries stay valid for older callers.
+
+import (
+	"context"
+	"time"
+)
+
+// HomeworkEventPhotoRendition is a photo row together with its pipeline
+// output. PreviewObjectKey/ThumbObjectKey are nil until processing succeeds.
+type HomeworkEventPhotoRendition struct {
+	EventID          int64
+	Idx              int32
+	ObjectKey        string
+	SizeBytes        int64
+	ContentType      string
+	CreatedAt        time.Time
+	ProcessingStatus string
+	PreviewObjectKey *string
+	ThumbObjectKey   *string
+}
+
+const listEventPhotoRenditionsForEventsSQL = `
+SELECT event_id, idx, object_key, size_bytes, content_type, created_at,
+       processing_status, preview_object_key, thumb_object_key
+FROM homework_thread_event_photo
+WHERE event_id = ANY ($1::bigint[])
+ORDER BY event_id ASC, idx ASC
+`
+
+func (q *Queries) ListEventPhotoRenditionsForEvents(ctx context.Context, eventIDs []int64) ([]HomeworkEventPhotoRendition, error) {
+	rows, err := q.db.Query(ctx, listEventPhotoRenditionsForEventsSQL, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []HomeworkEventPhotoRendition{}
+	for rows.Next() {
+		var row HomeworkEventPhotoRendition
+		if err := rows.Scan(
+			&row.EventID, &row.Idx, &row.ObjectKey, &row.SizeBytes, &row.ContentType, &row.CreatedAt,
+			&row.ProcessingStatus, &row.PreviewObjectKey, &row.ThumbObjectKey,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const getEventPhotoRenditionSQL = `
+SELECT event_id, idx, object_key, size_bytes, content_type, created_at,
+       processing_status, preview_object_key, thumb_object_key
+FROM homework_thread_event_photo
+WHERE event_id = $1
+  AND idx = $2
+`
+
+func (q *Queries) GetEventPhotoRendition(ctx context.Context, eventID int64, idx int32) (HomeworkEventPhotoRendition, error) {
+	row := q.db.QueryRow(ctx, getEventPhotoRenditionSQL, eventID, idx)
+	var out HomeworkEventPhotoRendition
+	err := row.Scan(
+		&out.EventID, &out.Idx, &out.ObjectKey, &out.SizeBytes, &out.ContentType, &out.CreatedAt,
+		&out.ProcessingStatus, &out.PreviewObjectKey, &out.ThumbObjectKey,
+	)
+	return out, err
+}
+
+// PendingEventPhoto is one leased unit of pipeline work.
+type PendingEventPhoto struct {
+	EventID     int64
+	Idx         int32
+	ObjectKey   string
+	ContentType string
+	Attempts    int32
+}
+
+// The inner SELECT takes row locks with SKIP LOCKED so concurrent workers on
+// other replicas pick disjoint batches; the outer UPDATE turns the lock into a
+// lease that survives the statement. A lease older than the stale interval is
+// considered abandoned (the worker crashed mid-photo) and may be re-taken.
+const leasePendingEventPhotosSQL = `
+UPDATE homework_thread_event_photo p
+SET processing_locked_at = NOW(),
+    processing_attempts  = p.processing_attempts + 1
+FROM (
+    SELECT event_id, idx
+    FROM homework_thread_event_photo
+    WHERE processing_status = 'pending'
+      AND (processing_locked_at IS NULL OR processing_locked_at < NOW() - $2::interval)
+    ORDER BY created_at ASC, event_id ASC, idx ASC
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+) picked
+WHERE p.event_id = picked.event_id
+  AND p.idx = picked.idx
+RETURNING p.event_id, p.idx, p.object_key, p.content_type, p.processing_attempts
+`
+
+func (q *Queries) LeasePendingEventPhotos(ctx context.Context, limit int32, stale time.Duration) ([]PendingEventPhoto, error) {
+	rows, err := q.db.Query(ctx, leasePendingEventPhotosSQL, limit, stale)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PendingEventPhoto{}
+	for rows.Next() {
+		var row PendingEventPhoto
+		if err := rows.Scan(&row.EventID, &row.Idx, &row.ObjectKey, &row.ContentType, &row.Attempts); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type CompleteEventPhotoProcessingParams struct {
+	EventID          int64
+	Idx              int32
+	PreviousKey      string
+	ObjectKey        string
+	SizeBytes        int64
+	ContentType      string
+	PreviewObjectKey string
+	ThumbObjectKey   string
+}
+
+// The object_key guard makes completion idempotent: a second worker that
+// re-processed a stale lease finds the key already rewritten and affects
+// zero rows, so it knows not to delete anything.
+const completeEventPhotoProcessingSQL = `
+UPDATE homework_thread_event_photo
+SET object_key           = $4,
+    size_bytes           = $5,
+    content_type         = $6,
+    preview_object_key   = $7,
+    thumb_object_key     = $8,
+    processing_status    = 'done',
+    processing_locked_at = NULL,
+    processing_error     = '',
+    processed_at         = NOW()
+WHERE event_id = $1
+  AND idx = $2
+  AND object_key = $3
+`
+
+func (q *Queries) CompleteEventPhotoProcessing(ctx context.Context, arg CompleteEventPhotoProcessingParams) (int64, error) {
+	result, err := q.db.Exec(ctx, completeEventPhotoProcessingSQL,
+		arg.EventID, arg.Idx, arg.PreviousKey, arg.ObjectKey, arg.SizeBytes, arg.ContentType,
+		arg.PreviewObjectKey, arg.ThumbObjectKey,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// SetEventPhotoProcessingStatus records a terminal ('failed', 'unsupported')
+// or retryable ('pending') outcome and releases the lease.
+const setEventPhotoProcessingStatusSQL = `
+UPDATE homework_thread_event_photo
+SET processing_status    = $3,
+    processing_error     = $4,
+    processing_locked_at = NULL,
+    processed_at         = CASE WHEN $3 = 'pending' THEN NULL ELSE NOW() END
+WHERE event_id = $1
+  AND idx = $2
+`
+
+func (q *Queries) SetEventPhotoProcessingStatus(ctx context.Context, eventID int64, idx int32, status, errMsg string) error {
+	_, err := q.db.Exec(ctx, setEventPhotoProcessingStatusSQL, eventID, idx, status, errMsg)
+	return err
+}
@@ -0,0 +1,263 @@
+package store
+
+// Query surface for the rejection / retraction reason taxonomy (migration
+// 000037). Hand-written like the other post-sqlc homework files. Reasons sit
+// in homework_event_reason next to the event they annotate, outside the
+// hwchain hash chain, so unlike the event itself they are not tamper-evident.
+
+import (
+	"context"
+	"time"
+)
+
+// HomeworkReasonCode is one entry of a center's taxonomy.
+type HomeworkReasonCode struct {
+	ID              int64
+	MathCenterID    int64
+	Code            string
+	Label           string
+	AppliesTo       string
+	CountsAsAttempt bool
+	Position        int32
+	ArchivedAt      *time.Time
+}
+
+const reasonCodeColumns = `id, math_center_id, code, label, applies_to, counts_as_attempt, position, archived_at`
+
+func scanReasonCode(row interface{ Scan(...any) error }) (HomeworkReasonCode, error) {
+	var c HomeworkReasonCode
+	err := row.Scan(&c.ID, &c.MathCenterID, &c.Code, &c.Label, &c.AppliesTo, &c.CountsAsAttempt, &c.Position, &c.ArchivedAt)
+	return c, err
+}
+
+// Active codes first in the head teacher's order, archived ones after.
+const listReasonCodesSQL = `
+SELECT ` + reasonCodeColumns + `
+FROM homework_reason_code
+WHERE math_center_id = $1
+ORDER BY archived_at IS NOT NULL, position, id
+`
+
+func (q *Queries) ListReasonCodes(ctx context.Context, mathCenterID int64) ([]HomeworkReasonCode, error) {
+	rows, err := q.db.Query(ctx, listReasonCodesSQL, mathCenterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []HomeworkReasonCode{}
+	for rows.Next() {
+		c, err := scanReasonCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const getActiveReasonCodeSQL = `
+SELECT ` + reasonCodeColumns + `
+FROM homework_reason_code
+WHERE math_center_id = $1
+  AND code = $2
+  AND archived_at IS NULL
+`
+
+// GetActiveReasonCode resolves a code a grader picked; pgx.ErrNoRows when the
+// center has no such live code.
+func (q *Queries) GetActiveReasonCode(ctx context.Context, mathCenterID int64, code string) (HomeworkReasonCode, error) {
+	return scanReasonCode(q.db.QueryRow(ctx, getActiveReasonCodeSQL, mathCenterID, code))
+}
+
+type UpsertReasonCodeParams struct {
+	MathCenterID    int64
+	Code            string
+	Label           string
+	AppliesTo       string
+	CountsAsAttempt bool
+	Position        int32
+}
+
+const upsertReasonCodeSQL = `
+INSERT INTO homework_reason_code (math_center_id, code, label, applies_to, counts_as_attempt, position)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (math_center_id, code) DO UPDATE
+    SET label             = EXCLUDED.label,
+        applies_to        = EXCLUDED.applies_to,
+        counts_as_attempt = EXCLUDED.counts_as_attempt,
+        position          = EXCLUDED.position,
+        archived_at       = NULL,
+        updated_at        = NOW()
+RETURNING ` + reasonCodeColumns
+
+// UpsertReasonCode creates the code or revives and rewrites an existing one.
+func (q *Queries) UpsertReasonCode(ctx context.Context, arg UpsertReasonCodeParams) (HomeworkReasonCode, error) {
+	return scanReasonCode(q.db.QueryRow(ctx, upsertReasonCodeSQL,
+		arg.MathCenterID, arg.Code, arg.Label, arg.AppliesTo, arg.CountsAsAttempt, arg.Position))
+}
+
+const archiveReasonCodesExceptSQL = `
+UPDATE homework_reason_code
+SET archived_at = NOW(),
+    updated_at  = NOW()
+WHERE math_center_id = $1
+  AND archived_at IS NULL
+  AND NOT (code = ANY ($2::text[]))
+`
+
+// ArchiveReasonCodesExcept retires every live code of the center not in
+// keep. Archived codes stay readable on the events that carry them.
+func (q *Queries) ArchiveReasonCodesExcept(ctx context.Context, mathCenterID int64, keep []string) error {
+	_, err := q.db.Exec(ctx, archiveReasonCodesExceptSQL, mathCenterID, keep)
+	return err
+}
+
+const setEventReasonSQL = `
+INSERT INTO homework_event_reason (event_id, reason_code_id)
+VALUES ($1, $2)
+`
+
+func (q *Queries) SetEventReason(ctx context.Context, eventID, reasonCodeID int64) error {
+	_, err := q.db.Exec(ctx, setEventReasonSQL, eventID, reasonCodeID)
+	return err
+}
+
+// EventReason is the reason attached to one event.
+type EventReason struct {
+	EventID         int64
+	Code            string
+	Label           string
+	CountsAsAttempt bool
+}
+
+const listEventReasonsSQL = `
+SELECT er.event_id, rc.code, rc.label, rc.counts_as_attempt
+FROM homework_event_reason er
+         JOIN homework_reason_code rc ON rc.id = er.reason_code_id
+WHERE er.event_id = ANY ($1::bigint[])
+`
+
+func (q *Queries) ListEventReasons(ctx context.Context, eventIDs []int64) ([]EventReason, error) {
+	rows, err := q.db.Query(ctx, listEventReasonsSQL, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []EventReason{}
+	for rows.Next() {
+		var r EventReason
+		if err := rows.Scan(&r.EventID, &r.Code, &r.Label, &r.CountsAsAttempt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SeriesReasonCount tallies one (subproblem, event kind, reason) cell. Code
+// is empty for events given without a reason; CountsAsAttempt is true for
+// them.
+type SeriesReasonCount struct {
+	SubproblemID    int64
+	Kind            string
+	Code            string
+	CountsAsAttempt bool
+	Count           int64
+}
+
+// Rejections and retractions of the series. A rejection that was later
+// retracted no longer stands and is left out.
+const listSeriesReasonCountsSQL = `
+SELECT t.subproblem_id, e.kind, COALESCE(rc.code, ''), COALESCE(rc.counts_as_attempt, TRUE), COUNT(*)::bigint
+FROM homework_thread_event e
+         JOIN homework_thread t ON t.id = e.thread_id
+         LEFT JOIN homework_event_reason er ON er.event_id = e.id
+         LEFT JOIN homework_reason_code rc ON rc.id = er.reason_code_id
+WHERE t.series_id = $1
+  AND ((e.kind = 'graded' AND e.verdict = 'rejected') OR e.kind = 'retracted')
+  AND NOT EXISTS (SELECT 1
+                  FROM homework_thread_event x
+                  WHERE x.thread_id = e.thread_id
+                    AND x.kind = 'retracted'
+                    AND x.refers_to_event_id = e.id)
+GROUP BY 1, 2, 3, 4
+`
+
+func (q *Queries) ListSeriesReasonCounts(ctx context.Context, seriesID int64) ([]SeriesReasonCount, error) {
+	rows, err := q.db.Query(ctx, listSeriesReasonCountsSQL, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SeriesReasonCount{}
+	for rows.Next() {
+		var r SeriesReasonCount
+		if err := rows.Scan(&r.SubproblemID, &r.Kind, &r.Code, &r.CountsAsAttempt, &r.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// A resubmitted or appealed thread still points at the verdict it follows,
+// so current_grade_event_id carries the reason of the last rejection.
+const listPendingThreadsByReasonSQL = `
+SELECT t.id
+FROM homework_thread t
+         JOIN homework_event_reason er ON er.event_id = t.current_grade_event_id
+         JOIN homework_reason_code rc ON rc.id = er.reason_code_id
+WHERE t.series_id = $1
+  AND t.current_status IN ('submitted', 'appealed')
+  AND rc.code = $2
+`
+
+// ListPendingThreadsByReason returns the series's threads waiting for a
+// grader whose previous verdict carried the given code.
+func (q *Queries) ListPendingThreadsByReason(ctx context.Context, seriesID int64, code string) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listPendingThreadsByReasonSQL, seriesID, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const rejectedWithoutAttemptSQL = `
+SELECT EXISTS (SELECT 1
+               FROM homework_thread t
+                        JOIN homework_event_reason er ON er.event_id = t.current_grade_event_id
+                        JOIN homework_reason_code rc ON rc.id = er.reason_code_id
+               WHERE t.student_user_id = $1
+                 AND t.subproblem_id = $2
+                 AND t.current_status = 'rejected'
+                 AND NOT rc.counts_as_attempt)
+`
+
+// RejectedWithoutAttempt reports whether the student's thread stands rejected
+// for a reason that does not count as an attempt (an unreadable photo).
+func (q *Queries) RejectedWithoutAttempt(ctx context.Context, studentUserID, subproblemID int64) (bool, error) {
+	var ok bool
+	err := q.db.QueryRow(ctx, rejectedWithoutAttemptSQL, studentUserID, subproblemID).Scan(&ok)
+	return ok, err
+}
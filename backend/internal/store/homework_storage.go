@@ -0,0 +1,227 @@
+package store
+
+// Query surface for photo storage usage and the per-student quota (migration
+// 000038). Hand-written like the other post-sqlc homework files. Usage is
+// always summed from homework_thread_event_photo.size_bytes; a photo is the
+// student's when the event carrying it was authored by the thread's student,
+// otherwise it is a grader attachment and never counts against a quota.
+
+import (
+	"context"
+	"time"
+)
+
+// HomeworkStorageQuota is a center's per-student cap. A center with no row
+// uses homework.DefaultStudentQuotaBytes.
+type HomeworkStorageQuota struct {
+	MathCenterID    int64
+	BytesPerStudent int64
+	UpdatedAt       time.Time
+}
+
+const getStorageQuotaSQL = `
+SELECT math_center_id, bytes_per_student, updated_at
+FROM homework_storage_quota
+WHERE math_center_id = $1
+`
+
+func (q *Queries) GetStorageQuota(ctx context.Context, mathCenterID int64) (HomeworkStorageQuota, error) {
+	var row HomeworkStorageQuota
+	err := q.db.QueryRow(ctx, getStorageQuotaSQL, mathCenterID).
+		Scan(&row.MathCenterID, &row.BytesPerStudent, &row.UpdatedAt)
+	return row, err
+}
+
+const upsertStorageQuotaSQL = `
+INSERT INTO homework_storage_quota (math_center_id, bytes_per_student)
+VALUES ($1, $2)
+ON CONFLICT (math_center_id) DO UPDATE
+SET bytes_per_student = EXCLUDED.bytes_per_student,
+    updated_at        = NOW()
+RETURNING math_center_id, bytes_per_student, updated_at
+`
+
+func (q *Queries) UpsertStorageQuota(ctx context.Context, mathCenterID, bytesPerStudent int64) (HomeworkStorageQuota, error) {
+	var row HomeworkStorageQuota
+	err := q.db.QueryRow(ctx, upsertStorageQuotaSQL, mathCenterID, bytesPerStudent).
+		Scan(&row.MathCenterID, &row.BytesPerStudent, &row.UpdatedAt)
+	return row, err
+}
+
+const studentStorageBytesSQL = `
+SELECT COALESCE(SUM(p.size_bytes), 0)::bigint
+FROM homework_thread t
+         JOIN homework_thread_event e ON e.thread_id = t.id AND e.actor_user_id = t.student_user_id
+         JOIN homework_thread_event_photo p ON p.event_id = e.id
+WHERE t.student_user_id = $1
+  AND t.math_center_id = $2
+`
+
+// StudentStorageBytes is what the student's own photos in the center take up.
+func (q *Queries) StudentStorageBytes(ctx context.Context, studentUserID, mathCenterID int64) (int64, error) {
+	var n int64
+	err := q.db.QueryRow(ctx, studentStorageBytesSQL, studentUserID, mathCenterID).Scan(&n)
+	return n, err
+}
+
+// StorageUsage splits a set of photos by who uploaded them.
+type StorageUsage struct {
+	StudentBytes int64
+	GraderBytes  int64
+	Photos       int64
+}
+
+const storageUsageColumns = `
+       COALESCE(SUM(p.size_bytes) FILTER (WHERE e.actor_user_id = t.student_user_id), 0)::bigint,
+       COALESCE(SUM(p.size_bytes) FILTER (WHERE e.actor_user_id <> t.student_user_id), 0)::bigint,
+       COUNT(*)::bigint`
+
+// CenterStorageUsage is one center's line of the admin overview.
+// BytesPerStudent is nil when the center uses the default quota.
+type CenterStorageUsage struct {
+	MathCenterID    int64
+	GraduationYear  int32
+	BytesPerStudent *int64
+	StorageUsage
+}
+
+// Every center is listed, including ones without a single photo.
+const listCenterStorageUsageSQL = `
+WITH usage (math_center_id, student_bytes, grader_bytes, photos) AS (SELECT t.math_center_id,` + storageUsageColumns + `
+               FROM homework_thread_event_photo p
+                        JOIN homework_thread_event e ON e.id = p.event_id
+                        JOIN homework_thread t ON t.id = e.thread_id
+               GROUP BY t.math_center_id)
+SELECT c.id, c.graduation_year, sq.bytes_per_student,
+       COALESCE(u.student_bytes, 0), COALESCE(u.grader_bytes, 0), COALESCE(u.photos, 0)
+FROM math_centers c
+         LEFT JOIN usage u ON u.math_center_id = c.id
+         LEFT JOIN homework_storage_quota sq ON sq.math_center_id = c.id
+ORDER BY c.graduation_year DESC, c.id
+`
+
+func (q *Queries) ListCenterStorageUsage(ctx context.Context) ([]CenterStorageUsage, error) {
+	rows, err := q.db.Query(ctx, listCenterStorageUsageSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []CenterStorageUsage{}
+	for rows.Next() {
+		var c CenterStorageUsage
+		if err := rows.Scan(&c.MathCenterID, &c.GraduationYear, &c.BytesPerStudent,
+			&c.StudentBytes, &c.GraderBytes, &c.Photos); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SeriesStorageUsage is one series of a center, with the term it belongs to.
+type SeriesStorageUsage struct {
+	TermID       int64
+	TermKind     string
+	TermGrade    *int32
+	SeriesID     int64
+	SeriesNumber int32
+	StorageUsage
+}
+
+// Only series with at least one photo are listed.
+const listSeriesStorageUsageSQL = `
+SELECT tm.id, tm.kind, tm.grade, s.id, s.number,` + storageUsageColumns + `
+FROM homework_thread_event_photo p
+         JOIN homework_thread_event e ON e.id = p.event_id
+         JOIN homework_thread t ON t.id = e.thread_id
+         JOIN math_center_series s ON s.id = t.series_id
+         JOIN math_center_terms tm ON tm.id = s.term_id
+WHERE t.math_center_id = $1
+GROUP BY tm.id, tm.kind, tm.grade, s.id, s.number
+ORDER BY tm.grade DESC NULLS LAST, tm.id DESC, s.number
+`
+
+func (q *Queries) ListSeriesStorageUsage(ctx context.Context, mathCenterID int64) ([]SeriesStorageUsage, error) {
+	rows, err := q.db.Query(ctx, listSeriesStorageUsageSQL, mathCenterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SeriesStorageUsage{}
+	for rows.Next() {
+		var s SeriesStorageUsage
+		if err := rows.Scan(&s.TermID, &s.TermKind, &s.TermGrade, &s.SeriesID, &s.SeriesNumber,
+			&s.StudentBytes, &s.GraderBytes, &s.Photos); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StudentStorageUsage is one student's own photos in a center.
+type StudentStorageUsage struct {
+	UserID    int64
+	FirstName string
+	LastName  string
+	Bytes     int64
+	Photos    int64
+}
+
+// Heaviest students first.
+const listStudentStorageUsageSQL = `
+SELECT u.id, u.first_name, u.last_name, SUM(p.size_bytes)::bigint, COUNT(*)::bigint
+FROM homework_thread_event_photo p
+         JOIN homework_thread_event e ON e.id = p.event_id
+         JOIN homework_thread t ON t.id = e.thread_id AND e.actor_user_id = t.student_user_id
+         JOIN users u ON u.id = t.student_user_id
+WHERE t.math_center_id = $1
+GROUP BY u.id, u.first_name, u.last_name
+ORDER BY 4 DESC, u.id
+LIMIT $2
+`
+
+func (q *Queries) ListStudentStorageUsage(ctx context.Context, mathCenterID int64, limit int32) ([]StudentStorageUsage, error) {
+	rows, err := q.db.Query(ctx, listStudentStorageUsageSQL, mathCenterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []StudentStorageUsage{}
+	for rows.Next() {
+		var s StudentStorageUsage
+		if err := rows.Scan(&s.UserID, &s.FirstName, &s.LastName, &s.Bytes, &s.Photos); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const countStudentsOverQuotaSQL = `
+SELECT COUNT(*)::bigint
+FROM (SELECT t.student_user_id
+      FROM homework_thread_event_photo p
+               JOIN homework_thread_event e ON e.id = p.event_id
+               JOIN homework_thread t ON t.id = e.thread_id AND e.actor_user_id = t.student_user_id
+      WHERE t.math_center_id = $1
+      GROUP BY t.student_user_id
+      HAVING SUM(p.size_bytes) >= $2) over_quota
+`
+
+// CountStudentsOverQuota counts the center's students who can no longer
+// start an upload under the given quota.
+func (q *Queries) CountStudentsOverQuota(ctx context.Context, mathCenterID, bytesPerStudent int64) (int64, error) {
+	var n int64
+	err := q.db.QueryRow(ctx, countStudentsOverQuotaSQL, mathCenterID, bytesPerStudent).Scan(&n)
+	return n, err
+}
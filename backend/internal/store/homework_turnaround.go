@@ -0,0 +1,350 @@
+package store
+
+// Query surface for grading turnaround and the SLA (migration 000034).
+// Hand-written alongside the generated homework queries, like
+// homework_calibration.go. Durations come back in seconds.
+
+import (
+	"context"
+	"time"
+)
+
+// AttemptClaim is the first claim on an attempt, as RecordAttemptClaim
+// stored it.
+type AttemptClaim struct {
+	ClaimedAt   time.Time
+	SubmittedAt time.Time
+}
+
+// The first claim wins; a re-claim returns no row.
+const recordAttemptClaimSQL = `
+INSERT INTO homework_attempt_claim (attempt_event_id, thread_id, grader_user_id)
+SELECT a.id, a.thread_id, $2
+FROM homework_thread_event a
+WHERE a.id = $1
+ON CONFLICT (attempt_event_id) DO NOTHING
+RETURNING claimed_at, (SELECT created_at FROM homework_thread_event WHERE id = $1)
+`
+
+// RecordAttemptClaim notes that graderUserID picked up the attempt. It
+// returns pgx.ErrNoRows when the attempt was already claimed before.
+func (q *Queries) RecordAttemptClaim(ctx context.Context, attemptEventID, graderUserID int64) (AttemptClaim, error) {
+	var row AttemptClaim
+	err := q.db.QueryRow(ctx, recordAttemptClaimSQL, attemptEventID, graderUserID).
+		Scan(&row.ClaimedAt, &row.SubmittedAt)
+	return row, err
+}
+
+const getAttemptSubmittedAtSQL = `
+SELECT created_at
+FROM homework_thread_event
+WHERE id = $1
+`
+
+// GetAttemptSubmittedAt returns when an attempt (submitted or appealed
+// event) was made.
+func (q *Queries) GetAttemptSubmittedAt(ctx context.Context, attemptEventID int64) (time.Time, error) {
+	var at time.Time
+	err := q.db.QueryRow(ctx, getAttemptSubmittedAtSQL, attemptEventID).Scan(&at)
+	return at, err
+}
+
+// HomeworkSLAConfig is a center's grading SLA. A center with no row uses
+// homework.DefaultSLAHours.
+type HomeworkSLAConfig struct {
+	MathCenterID int64
+	Hours        int32
+	UpdatedAt    time.Time
+}
+
+const getSLAConfigSQL = `
+SELECT math_center_id, hours, updated_at
+FROM homework_sla_config
+WHERE math_center_id = $1
+`
+
+func (q *Queries) GetSLAConfig(ctx context.Context, mathCenterID int64) (HomeworkSLAConfig, error) {
+	var row HomeworkSLAConfig
+	err := q.db.QueryRow(ctx, getSLAConfigSQL, mathCenterID).
+		Scan(&row.MathCenterID, &row.Hours, &row.UpdatedAt)
+	return row, err
+}
+
+const upsertSLAConfigSQL = `
+INSERT INTO homework_sla_config (math_center_id, hours)
+VALUES ($1, $2)
+ON CONFLICT (math_center_id) DO UPDATE
+SET hours      = EXCLUDED.hours,
+    updated_at = NOW()
+RETURNING math_center_id, hours, updated_at
+`
+
+func (q *Queries) UpsertSLAConfig(ctx context.Context, mathCenterID int64, hours int32) (HomeworkSLAConfig, error) {
+	var row HomeworkSLAConfig
+	err := q.db.QueryRow(ctx, upsertSLAConfigSQL, mathCenterID, hours).
+		Scan(&row.MathCenterID, &row.Hours, &row.UpdatedAt)
+	return row, err
+}
+
+// DurationStats summarizes one duration over a set of verdicts. The averages
+// and percentiles are nil when no verdict has the duration (claim waits are
+// only known for attempts claimed after migration 000034).
+type DurationStats struct {
+	Count int64
+	Avg   *float64
+	P50   *float64
+	P90   *float64
+}
+
+// Each online verdict of the term with the attempt it judged and that
+// attempt's first claim. Offline acceptances have no attempt to wait on.
+const turnaroundVerdictsCTE = `
+WITH verdicts AS (
+    SELECT th.series_id,
+           g.actor_user_id AS grader_user_id,
+           EXTRACT(EPOCH FROM fc.claimed_at - a.created_at)::float8 AS claim_wait,
+           EXTRACT(EPOCH FROM g.created_at - a.created_at)::float8  AS turnaround
+    FROM homework_thread_event g
+             JOIN homework_thread th ON th.id = g.thread_id
+             JOIN math_center_series se ON se.id = th.series_id
+             JOIN homework_thread_event a ON a.id = g.refers_to_event_id
+             LEFT JOIN homework_attempt_claim fc ON fc.attempt_event_id = a.id
+    WHERE th.math_center_id = $1
+      AND se.term_id = $2
+      AND g.kind = 'graded'
+)
+`
+
+const turnaroundStatsColumns = `
+       COUNT(v.claim_wait)::bigint,
+       AVG(v.claim_wait)::float8,
+       percentile_cont(0.5) WITHIN GROUP (ORDER BY v.claim_wait)::float8,
+       percentile_cont(0.9) WITHIN GROUP (ORDER BY v.claim_wait)::float8,
+       COUNT(v.turnaround)::bigint,
+       AVG(v.turnaround)::float8,
+       percentile_cont(0.5) WITHIN GROUP (ORDER BY v.turnaround)::float8,
+       percentile_cont(0.9) WITHIN GROUP (ORDER BY v.turnaround)::float8
+`
+
+type TurnaroundParams struct {
+	MathCenterID int64
+	TermID       int64
+}
+
+type SeriesTurnaroundRow struct {
+	SeriesID     int64
+	SeriesNumber int32
+	SeriesName   string
+	ClaimWait    DurationStats
+	Turnaround   DurationStats
+}
+
+const seriesTurnaroundSQL = turnaroundVerdictsCTE + `
+SELECT se.id, se.number, se.name,` + turnaroundStatsColumns + `
+FROM verdicts v
+         JOIN math_center_series se ON se.id = v.series_id
+GROUP BY se.id
+ORDER BY se.due_at ASC, se.number ASC, se.id ASC
+`
+
+// SeriesTurnaround aggregates the term's verdicts per series.
+func (q *Queries) SeriesTurnaround(ctx context.Context, arg TurnaroundParams) ([]SeriesTurnaroundRow, error) {
+	rows, err := q.db.Query(ctx, seriesTurnaroundSQL, arg.MathCenterID, arg.TermID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SeriesTurnaroundRow{}
+	for rows.Next() {
+		var r SeriesTurnaroundRow
+		if err := rows.Scan(&r.SeriesID, &r.SeriesNumber, &r.SeriesName,
+			&r.ClaimWait.Count, &r.ClaimWait.Avg, &r.ClaimWait.P50, &r.ClaimWait.P90,
+			&r.Turnaround.Count, &r.Turnaround.Avg, &r.Turnaround.P50, &r.Turnaround.P90); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type GraderTurnaroundRow struct {
+	GraderUserID    int64
+	GraderFirstName string
+	GraderLastName  string
+	ClaimWait       DurationStats
+	Turnaround      DurationStats
+}
+
+const graderTurnaroundSQL = turnaroundVerdictsCTE + `
+SELECT u.id, u.first_name, u.last_name,` + turnaroundStatsColumns + `
+FROM verdicts v
+         JOIN users u ON u.id = v.grader_user_id
+GROUP BY u.id
+ORDER BY u.last_name ASC, u.first_name ASC, u.id ASC
+`
+
+// GraderTurnaround aggregates the term's verdicts per grader who gave them.
+func (q *Queries) GraderTurnaround(ctx context.Context, arg TurnaroundParams) ([]GraderTurnaroundRow, error) {
+	rows, err := q.db.Query(ctx, graderTurnaroundSQL, arg.MathCenterID, arg.TermID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []GraderTurnaroundRow{}
+	for rows.Next() {
+		var r GraderTurnaroundRow
+		if err := rows.Scan(&r.GraderUserID, &r.GraderFirstName, &r.GraderLastName,
+			&r.ClaimWait.Count, &r.ClaimWait.Avg, &r.ClaimWait.P50, &r.ClaimWait.P90,
+			&r.Turnaround.Count, &r.Turnaround.Avg, &r.Turnaround.P50, &r.Turnaround.P90); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type ListOverdueThreadsParams struct {
+	MathCenterID int64
+	// SubmittedBefore is now minus the SLA.
+	SubmittedBefore time.Time
+	Limit           int32
+}
+
+type OverdueThreadRow struct {
+	ThreadID          int64
+	SeriesID          int64
+	SeriesNumber      int32
+	SeriesName        string
+	ProblemNumber     int32
+	SubproblemLabel   string
+	StudentUserID     int64
+	StudentFirstName  string
+	StudentLastName   string
+	CurrentStatus     string
+	SubmittedAt       time.Time
+	ClaimedAt         *time.Time
+	ClaimHolderUserID *int64
+	ClaimExpiresAt    *time.Time
+}
+
+const listOverdueThreadsSQL = `
+SELECT th.id,
+       se.id,
+       se.number,
+       se.name,
+       p.number,
+       sp.label,
+       u.id,
+       u.first_name,
+       u.last_name,
+       th.current_status,
+       a.created_at,
+       fc.claimed_at,
+       th.claim_holder_user_id,
+       th.claim_expires_at
+FROM homework_thread th
+         JOIN homework_thread_event a ON a.id = th.current_attempt_event_id
+         JOIN math_center_subproblems sp ON sp.id = th.subproblem_id
+         JOIN math_center_problems p ON p.id = sp.problem_id
+         JOIN math_center_series se ON se.id = th.series_id
+         JOIN users u ON u.id = th.student_user_id
+         LEFT JOIN homework_attempt_claim fc ON fc.attempt_event_id = a.id
+WHERE th.math_center_id = $1
+  AND th.current_status IN ('submitted', 'appealed')
+  AND a.created_at < $2
+ORDER BY a.created_at ASC, th.id ASC
+LIMIT $3
+`
+
+// ListOverdueThreads returns threads waiting for a verdict since before the
+// cutoff, oldest first.
+func (q *Queries) ListOverdueThreads(ctx context.Context, arg ListOverdueThreadsParams) ([]OverdueThreadRow, error) {
+	rows, err := q.db.Query(ctx, listOverdueThreadsSQL, arg.MathCenterID, arg.SubmittedBefore, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []OverdueThreadRow{}
+	for rows.Next() {
+		var r OverdueThreadRow
+		if err := rows.Scan(&r.ThreadID, &r.SeriesID, &r.SeriesNumber, &r.SeriesName,
+			&r.ProblemNumber, &r.SubproblemLabel, &r.StudentUserID, &r.StudentFirstName,
+			&r.StudentLastName, &r.CurrentStatus, &r.SubmittedAt, &r.ClaimedAt,
+			&r.ClaimHolderUserID, &r.ClaimExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type OverdueCenterRow struct {
+	MathCenterID   int64
+	GraduationYear int32
+	SLAHours       int32
+	Overdue        int64
+	OldestAt       time.Time
+}
+
+const countOverdueByCenterSQL = `
+SELECT mc.id,
+       mc.graduation_year,
+       COALESCE(cfg.hours, $2)::int,
+       COUNT(*)::bigint,
+       MIN(a.created_at)
+FROM homework_thread th
+         JOIN homework_thread_event a ON a.id = th.current_attempt_event_id
+         JOIN math_centers mc ON mc.id = th.math_center_id
+         LEFT JOIN homework_sla_config cfg ON cfg.math_center_id = th.math_center_id
+WHERE th.current_status IN ('submitted', 'appealed')
+  AND a.created_at < $1::timestamptz - make_interval(hours => COALESCE(cfg.hours, $2))
+GROUP BY mc.id, cfg.hours
+ORDER BY mc.graduation_year ASC
+`
+
+// CountOverdueByCenter counts overdue threads in every center against its
+// own SLA (defaultHours where unset), for the daily digest.
+func (q *Queries) CountOverdueByCenter(ctx context.Context, now time.Time, defaultHours int32) ([]OverdueCenterRow, error) {
+	rows, err := q.db.Query(ctx, countOverdueByCenterSQL, now, defaultHours)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []OverdueCenterRow{}
+	for rows.Next() {
+		var r OverdueCenterRow
+		if err := rows.Scan(&r.MathCenterID, &r.GraduationYear, &r.SLAHours, &r.Overdue, &r.OldestAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const claimSLADigestSQL = `
+INSERT INTO homework_sla_digest (day)
+VALUES ($1::date)
+ON CONFLICT (day) DO NOTHING
+`
+
+// ClaimSLADigest reserves the digest for a day. It reports false when
+// another replica already sent it.
+func (q *Queries) ClaimSLADigest(ctx context.Context, day time.Time) (bool, error) {
+	tag, err := q.db.Exec(ctx, claimSLADigestSQL, day)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,384 @@
+package store
+
+// Query surface for lessons and attendance (migration 000043). Hand-written
+// like problem_bank.go. Dates and times are center-local wall-clock values,
+// scanned as pgtype like likbez held_on.
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Lesson struct {
+	ID              int64
+	MathCenterID    int64
+	TermID          int64
+	HeldOn          pgtype.Date
+	StartsAt        pgtype.Time
+	EndsAt          pgtype.Time
+	Topic           string
+	SeriesID        *int64
+	LikbezID        *int64
+	CreatedByUserID *int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	// GroupIDs and GroupNames are the lesson's groups, by name.
+	GroupIDs   []int64
+	GroupNames []string
+}
+
+const lessonColumns = `l.id, l.math_center_id, l.term_id, l.held_on, l.starts_at, l.ends_at, l.topic, l.series_id, l.likbez_id,
+       l.created_by_user_id, l.created_at, l.updated_at,
+       ARRAY(SELECT g.id FROM math_center_lesson_groups lg JOIN math_center_groups g ON g.id = lg.group_id
+             WHERE lg.lesson_id = l.id ORDER BY g.name, g.id) AS group_ids,
+       ARRAY(SELECT g.name FROM math_center_lesson_groups lg JOIN math_center_groups g ON g.id = lg.group_id
+             WHERE lg.lesson_id = l.id ORDER BY g.name, g.id) AS group_names`
+
+func scanLesson(row interface{ Scan(...any) error }) (Lesson, error) {
+	var l Lesson
+	err := row.Scan(&l.ID, &l.MathCenterID, &l.TermID, &l.HeldOn, &l.StartsAt, &l.EndsAt, &l.Topic, &l.SeriesID, &l.LikbezID,
+		&l.CreatedByUserID, &l.CreatedAt, &l.UpdatedAt, &l.GroupIDs, &l.GroupNames)
+	return l, err
+}
+
+type LessonParams struct {
+	HeldOn   pgtype.Date
+	StartsAt pgtype.Time
+	EndsAt   pgtype.Time
+	Topic    string
+	SeriesID *int64
+	LikbezID *int64
+}
+
+type CreateLessonParams struct {
+	MathCenterID    int64
+	TermID          int64
+	CreatedByUserID int64
+	LessonParams
+}
+
+const createLessonSQL = `
+INSERT INTO math_center_lessons
+    (math_center_id, term_id, held_on, starts_at, ends_at, topic, series_id, likbez_id, created_by_user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id`
+
+// CreateLesson inserts a lesson without groups and returns its id; follow
+// with SetLessonGroups in the same transaction.
+func (q *Queries) CreateLesson(ctx context.Context, arg CreateLessonParams) (int64, error) {
+	var id int64
+	err := q.db.QueryRow(ctx, createLessonSQL, arg.MathCenterID, arg.TermID, arg.HeldOn, arg.StartsAt, arg.EndsAt,
+		arg.Topic, arg.SeriesID, arg.LikbezID, arg.CreatedByUserID).Scan(&id)
+	return id, err
+}
+
+const updateLessonSQL = `
+UPDATE math_center_lessons
+SET held_on    = $2,
+    starts_at  = $3,
+    ends_at    = $4,
+    topic      = $5,
+    series_id  = $6,
+    likbez_id  = $7,
+    updated_at = NOW()
+WHERE id = $1`
+
+func (q *Queries) UpdateLesson(ctx context.Context, id int64, arg LessonParams) error {
+	_, err := q.db.Exec(ctx, updateLessonSQL, id, arg.HeldOn, arg.StartsAt, arg.EndsAt, arg.Topic, arg.SeriesID, arg.LikbezID)
+	return err
+}
+
+const deleteLessonGroupsSQL = `DELETE FROM math_center_lesson_groups WHERE lesson_id = $1`
+
+const insertLessonGroupsSQL = `
+INSERT INTO math_center_lesson_groups (lesson_id, group_id, term_id)
+SELECT $1, g.id, g.term_id
+FROM math_center_groups g
+WHERE g.id = ANY ($3::bigint[])
+  AND g.term_id = $2`
+
+// SetLessonGroups replaces the lesson's groups and returns how many were
+// linked; a group outside termID is skipped, so a count short of
+// len(groupIDs) means the request named a foreign group.
+func (q *Queries) SetLessonGroups(ctx context.Context, lessonID, termID int64, groupIDs []int64) (int64, error) {
+	if _, err := q.db.Exec(ctx, deleteLessonGroupsSQL, lessonID); err != nil {
+		return 0, err
+	}
+	tag, err := q.db.Exec(ctx, insertLessonGroupsSQL, lessonID, termID, groupIDs)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const getLessonSQL = `
+SELECT ` + lessonColumns + `
+FROM math_center_lessons l
+WHERE l.id = $1`
+
+func (q *Queries) GetLesson(ctx context.Context, id int64) (Lesson, error) {
+	return scanLesson(q.db.QueryRow(ctx, getLessonSQL, id))
+}
+
+const deleteLessonSQL = `DELETE FROM math_center_lessons WHERE id = $1`
+
+func (q *Queries) DeleteLesson(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, deleteLessonSQL, id)
+	return err
+}
+
+const seriesInTermSQL = `
+SELECT EXISTS (SELECT 1 FROM math_center_series WHERE id = $1 AND term_id = $2)`
+
+// SeriesInTerm reports whether the series belongs to the term; a lesson may
+// only link a series of its own term.
+func (q *Queries) SeriesInTerm(ctx context.Context, seriesID, termID int64) (bool, error) {
+	var ok bool
+	err := q.db.QueryRow(ctx, seriesInTermSQL, seriesID, termID).Scan(&ok)
+	return ok, err
+}
+
+type ListLessonsParams struct {
+	MathCenterID int64
+	TermID       int64
+	// GroupID, when set, keeps lessons of that group.
+	GroupID *int64
+	// From and To, when valid, bound held_on inclusively.
+	From pgtype.Date
+	To   pgtype.Date
+}
+
+const listLessonsSQL = `
+SELECT ` + lessonColumns + `
+FROM math_center_lessons l
+WHERE l.math_center_id = $1
+  AND l.term_id = $2
+  AND ($3::bigint IS NULL OR EXISTS (SELECT 1
+                                     FROM math_center_lesson_groups lg
+                                     WHERE lg.lesson_id = l.id
+                                       AND lg.group_id = $3))
+  AND ($4::date IS NULL OR l.held_on >= $4)
+  AND ($5::date IS NULL OR l.held_on <= $5)
+ORDER BY l.held_on, l.starts_at, l.id`
+
+// ListLessons returns the term's lessons in timetable order.
+func (q *Queries) ListLessons(ctx context.Context, arg ListLessonsParams) ([]Lesson, error) {
+	rows, err := q.db.Query(ctx, listLessonsSQL, arg.MathCenterID, arg.TermID, arg.GroupID, arg.From, arg.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Lesson{}
+	for rows.Next() {
+		l, err := scanLesson(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// LessonAttendanceRow is one student of the lesson's groups with their
+// mark; Status is nil while unmarked.
+type LessonAttendanceRow struct {
+	StudentUserID   int64
+	GroupID         int64
+	GroupName       string
+	FirstName       string
+	LastName        string
+	Status          *string
+	MarkedByUserID  *int64
+	GoogleSheetCell string
+	MarkedAt        *time.Time
+}
+
+const listLessonAttendanceSQL = `
+SELECT s.user_id,
+       g.id,
+       g.name,
+       u.first_name,
+       u.last_name,
+       a.status,
+       a.marked_by_user_id,
+       COALESCE(a.google_sheet_cell, ''),
+       a.marked_at
+FROM math_center_lessons l
+         JOIN math_center_lesson_groups lg ON lg.lesson_id = l.id
+         JOIN math_center_groups g ON g.id = lg.group_id
+         JOIN math_center_students s ON s.group_id = lg.group_id AND s.term_id = l.term_id
+         JOIN users u ON u.id = s.user_id
+         LEFT JOIN math_center_lesson_attendance a ON a.lesson_id = l.id AND a.student_user_id = s.user_id
+WHERE l.id = $1
+ORDER BY g.name, g.id, u.last_name, u.first_name, s.user_id`
+
+// ListLessonAttendance returns the lesson's current roster with marks.
+func (q *Queries) ListLessonAttendance(ctx context.Context, lessonID int64) ([]LessonAttendanceRow, error) {
+	rows, err := q.db.Query(ctx, listLessonAttendanceSQL, lessonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []LessonAttendanceRow{}
+	for rows.Next() {
+		var a LessonAttendanceRow
+		if err := rows.Scan(&a.StudentUserID, &a.GroupID, &a.GroupName, &a.FirstName, &a.LastName, &a.Status,
+			&a.MarkedByUserID, &a.GoogleSheetCell, &a.MarkedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+const upsertAttendanceSQL = `
+INSERT INTO math_center_lesson_attendance (lesson_id, student_user_id, status, marked_by_user_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (lesson_id, student_user_id) DO UPDATE
+    SET status               = EXCLUDED.status,
+        marked_by_user_id    = EXCLUDED.marked_by_user_id,
+        google_sheet_link_id = NULL,
+        google_sheet_cell    = '',
+        marked_at            = NOW()`
+
+// UpsertAttendance records a teacher's mark, replacing an imported one.
+func (q *Queries) UpsertAttendance(ctx context.Context, lessonID, studentUserID int64, status string, markedByUserID int64) error {
+	_, err := q.db.Exec(ctx, upsertAttendanceSQL, lessonID, studentUserID, status, markedByUserID)
+	return err
+}
+
+const deleteAttendanceSQL = `
+DELETE
+FROM math_center_lesson_attendance
+WHERE lesson_id = $1
+  AND student_user_id = $2`
+
+func (q *Queries) DeleteAttendance(ctx context.Context, lessonID, studentUserID int64) error {
+	_, err := q.db.Exec(ctx, deleteAttendanceSQL, lessonID, studentUserID)
+	return err
+}
+
+type InsertImportedAttendanceParams struct {
+	LessonID          int64
+	StudentUserID     int64
+	Status            string
+	MarkedByUserID    int64
+	GoogleSheetLinkID int64
+	GoogleSheetCell   string
+}
+
+const insertImportedAttendanceSQL = `
+INSERT INTO math_center_lesson_attendance
+    (lesson_id, student_user_id, status, marked_by_user_id, google_sheet_link_id, google_sheet_cell)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (lesson_id, student_user_id) DO NOTHING`
+
+// InsertImportedAttendance records a mark read from a conduit tab unless the
+// student already has one for the lesson, and reports whether it did.
+func (q *Queries) InsertImportedAttendance(ctx context.Context, arg InsertImportedAttendanceParams) (bool, error) {
+	tag, err := q.db.Exec(ctx, insertImportedAttendanceSQL, arg.LessonID, arg.StudentUserID, arg.Status,
+		arg.MarkedByUserID, arg.GoogleSheetLinkID, arg.GoogleSheetCell)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// AttendanceCounts counts lessons held up to a day and the marks on them.
+type AttendanceCounts struct {
+	Lessons int64
+	Present int64
+	Late    int64
+	Absent  int64
+	Excused int64
+}
+
+// A student's lessons in a term are those of their group plus any other
+// lesson they hold a mark for (say, from before a move between groups).
+const attendanceCountColumns = `COUNT(*),
+       COUNT(*) FILTER (WHERE a.status = 'present'),
+       COUNT(*) FILTER (WHERE a.status = 'late'),
+       COUNT(*) FILTER (WHERE a.status = 'absent'),
+       COUNT(*) FILTER (WHERE a.status = 'excused')`
+
+const studentLessonsJoin = `
+         JOIN math_center_lessons l ON l.term_id = s.term_id AND l.held_on <= $3::date
+         LEFT JOIN math_center_lesson_attendance a ON a.lesson_id = l.id AND a.student_user_id = s.user_id`
+
+const studentLessonsWhere = `
+  AND (a.lesson_id IS NOT NULL OR EXISTS (SELECT 1
+                                          FROM math_center_lesson_groups lg
+                                          WHERE lg.lesson_id = l.id
+                                            AND lg.group_id = s.group_id))`
+
+type StudentAttendanceTermRow struct {
+	TermID       int64
+	TermKind     string
+	TermGrade    *int32
+	TermIsActive bool
+	AttendanceCounts
+}
+
+const listStudentAttendanceByTermSQL = `
+SELECT t.id, t.kind, t.grade, t.is_active,
+       ` + attendanceCountColumns + `
+FROM math_center_students s
+         JOIN math_center_terms t ON t.id = s.term_id` + studentLessonsJoin + `
+WHERE t.math_center_id = $1
+  AND s.user_id = $2` + studentLessonsWhere + `
+GROUP BY t.id, t.kind, t.grade, t.is_active
+ORDER BY MIN(l.held_on), t.id`
+
+// ListStudentAttendanceByTerm counts the student's lessons held on or
+// before today in every term of the center, in the order the terms began.
+func (q *Queries) ListStudentAttendanceByTerm(ctx context.Context, centerID, studentUserID int64, today time.Time) ([]StudentAttendanceTermRow, error) {
+	rows, err := q.db.Query(ctx, listStudentAttendanceByTermSQL, centerID, studentUserID, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []StudentAttendanceTermRow{}
+	for rows.Next() {
+		var r StudentAttendanceTermRow
+		if err := rows.Scan(&r.TermID, &r.TermKind, &r.TermGrade, &r.TermIsActive,
+			&r.Lessons, &r.Present, &r.Late, &r.Absent, &r.Excused); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+type TermAttendanceRow struct {
+	StudentUserID int64
+	AttendanceCounts
+}
+
+const listTermAttendanceSQL = `
+SELECT s.user_id,
+       ` + attendanceCountColumns + `
+FROM math_center_students s` + studentLessonsJoin + `
+WHERE l.math_center_id = $1
+  AND s.term_id = $2` + studentLessonsWhere + `
+GROUP BY s.user_id`
+
+// ListTermAttendance counts lessons held on or before today for every
+// student of the term who has had one.
+func (q *Queries) ListTermAttendance(ctx context.Context, centerID, termID int64, today time.Time) ([]TermAttendanceRow, error) {
+	rows, err := q.db.Query(ctx, listTermAttendanceSQL, centerID, termID, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TermAttendanceRow{}
+	for rows.Next() {
+		var r TermAttendanceRow
+		if err := rows.Scan(&r.StudentUserID, &r.Lessons, &r.Present, &r.Late, &r.Absent, &r.Excused); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -356,6 +356,8 @@ SELECT EXISTS (
     FROM math_center_teachers
     WHERE user_id = $1
       AND math_center_id = $2
+      AND (term_id IS NULL
+           OR term_id IN (SELECT id FROM math_center_terms WHERE is_active))
 ) AS is_teacher
 `
 
@@ -364,6 +366,7 @@ type IsTeacherInCenterParams struct {
 	MathCenterID int64 `json:"math_center_id"`
 }
 
+// Term-scoped assistants teach only while their term is active.
 func (q *Queries) IsTeacherInCenter(ctx context.Context, arg IsTeacherInCenterParams) (bool, error) {
 	row := q.db.QueryRow(ctx, isTeacherInCenter, arg.UserID, arg.MathCenterID)
 	var is_teacher bool
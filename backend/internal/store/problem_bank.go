@@ -0,0 +1,281 @@
+package store
+
+// Query surface for the problem bank (migration 000040). Hand-written like
+// publication_schedule.go. A center sees its own bank problems plus the
+// school-wide ones (math_center_id IS NULL).
+
+import (
+	"context"
+	"time"
+)
+
+type BankProblem struct {
+	ID              int64
+	MathCenterID    *int64
+	Title           string
+	StatementTex    string
+	SolutionTex     *string
+	Tags            []string
+	Difficulty      *int16
+	Source          string
+	SubproblemCount int32
+	CreatedByUserID *int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	ArchivedAt      *time.Time
+}
+
+const bankProblemColumns = `id, math_center_id, title, statement_tex, solution_tex, tags, difficulty, source,
+       subproblem_count, created_by_user_id, created_at, updated_at, archived_at`
+
+func scanBankProblem(row interface{ Scan(...any) error }) (BankProblem, error) {
+	var b BankProblem
+	err := row.Scan(&b.ID, &b.MathCenterID, &b.Title, &b.StatementTex, &b.SolutionTex, &b.Tags, &b.Difficulty, &b.Source,
+		&b.SubproblemCount, &b.CreatedByUserID, &b.CreatedAt, &b.UpdatedAt, &b.ArchivedAt)
+	return b, err
+}
+
+type CreateBankProblemParams struct {
+	MathCenterID    *int64
+	Title           string
+	StatementTex    string
+	SolutionTex     *string
+	Tags            []string
+	Difficulty      *int16
+	Source          string
+	SubproblemCount int32
+	CreatedByUserID int64
+}
+
+const createBankProblemSQL = `
+INSERT INTO math_center_bank_problems
+    (math_center_id, title, statement_tex, solution_tex, tags, difficulty, source, subproblem_count, created_by_user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING ` + bankProblemColumns
+
+func (q *Queries) CreateBankProblem(ctx context.Context, arg CreateBankProblemParams) (BankProblem, error) {
+	return scanBankProblem(q.db.QueryRow(ctx, createBankProblemSQL,
+		arg.MathCenterID, arg.Title, arg.StatementTex, arg.SolutionTex, arg.Tags, arg.Difficulty, arg.Source,
+		arg.SubproblemCount, arg.CreatedByUserID))
+}
+
+const getBankProblemSQL = `
+SELECT ` + bankProblemColumns + `
+FROM math_center_bank_problems
+WHERE id = $1
+`
+
+func (q *Queries) GetBankProblem(ctx context.Context, id int64) (BankProblem, error) {
+	return scanBankProblem(q.db.QueryRow(ctx, getBankProblemSQL, id))
+}
+
+type ListBankProblemsParams struct {
+	MathCenterID int64
+	// Tag, when non-empty, keeps problems carrying that exact tag.
+	Tag string
+	// Difficulty, when set, keeps problems rated exactly that.
+	Difficulty *int16
+	// Search, when non-empty, matches title or source case-insensitively.
+	Search string
+	Limit  int32
+}
+
+const listBankProblemsSQL = `
+SELECT ` + bankProblemColumns + `
+FROM math_center_bank_problems
+WHERE (math_center_id = $1 OR math_center_id IS NULL)
+  AND archived_at IS NULL
+  AND ($2 = '' OR $2 = ANY (tags))
+  AND ($3::smallint IS NULL OR difficulty = $3)
+  AND ($4 = '' OR title ILIKE '%' || $4 || '%' OR source ILIKE '%' || $4 || '%')
+ORDER BY updated_at DESC, id DESC
+LIMIT $5
+`
+
+// ListBankProblems returns the unarchived bank problems the center can see,
+// most recently edited first.
+func (q *Queries) ListBankProblems(ctx context.Context, arg ListBankProblemsParams) ([]BankProblem, error) {
+	rows, err := q.db.Query(ctx, listBankProblemsSQL, arg.MathCenterID, arg.Tag, arg.Difficulty, arg.Search, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []BankProblem{}
+	for rows.Next() {
+		b, err := scanBankProblem(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type UpdateBankProblemParams struct {
+	ID              int64
+	Title           string
+	StatementTex    string
+	SolutionTex     *string
+	Tags            []string
+	Difficulty      *int16
+	Source          string
+	SubproblemCount int32
+}
+
+const updateBankProblemSQL = `
+UPDATE math_center_bank_problems
+SET title            = $2,
+    statement_tex    = $3,
+    solution_tex     = $4,
+    tags             = $5,
+    difficulty       = $6,
+    source           = $7,
+    subproblem_count = $8,
+    updated_at       = NOW()
+WHERE id = $1
+  AND archived_at IS NULL
+RETURNING ` + bankProblemColumns
+
+// UpdateBankProblem rewrites the editable fields; pgx.ErrNoRows when the
+// problem is archived or gone. Series already referencing it keep their own
+// problem and subproblem rows.
+func (q *Queries) UpdateBankProblem(ctx context.Context, arg UpdateBankProblemParams) (BankProblem, error) {
+	return scanBankProblem(q.db.QueryRow(ctx, updateBankProblemSQL,
+		arg.ID, arg.Title, arg.StatementTex, arg.SolutionTex, arg.Tags, arg.Difficulty, arg.Source, arg.SubproblemCount))
+}
+
+const archiveBankProblemSQL = `
+UPDATE math_center_bank_problems
+SET archived_at = NOW()
+WHERE id = $1
+  AND archived_at IS NULL
+`
+
+func (q *Queries) ArchiveBankProblem(ctx context.Context, id int64) (int64, error) {
+	tag, err := q.db.Exec(ctx, archiveBankProblemSQL, id)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// BankProblemStat is the history of one bank problem across series. Uses
+// counts every series problem placed from it; the rest only count series
+// that are published and past due, so a series in progress does not drag
+// the solve rate down. Assigned is (subproblem × roster student) pairs, the
+// same spine SeriesProblemStats uses; Attempted is pairs with any
+// submission; Accepted is pairs currently accepted.
+type BankProblemStat struct {
+	BankProblemID int64
+	Uses          int64
+	FinishedUses  int64
+	Assigned      int64
+	Attempted     int64
+	Accepted      int64
+}
+
+const bankProblemStatsSQL = `
+WITH links AS (SELECT p.id, p.bank_problem_id, s.term_id,
+                      (s.published_at IS NOT NULL AND s.due_at < NOW()) AS finished
+               FROM math_center_problems p
+                        JOIN math_center_series s ON s.id = p.series_id
+               WHERE p.bank_problem_id = ANY ($1::bigint[])),
+     tally AS (SELECT l.bank_problem_id,
+                      COUNT(*)                                                        AS assigned,
+                      COUNT(*) FILTER (WHERE t.current_status <> 'ungraded')          AS attempted,
+                      COUNT(*) FILTER (WHERE t.current_status = 'accepted')           AS accepted
+               FROM links l
+                        JOIN math_center_subproblems sp ON sp.problem_id = l.id
+                        JOIN math_center_groups g ON g.term_id = l.term_id
+                        JOIN math_center_students mcs ON mcs.group_id = g.id
+                        LEFT JOIN homework_thread t
+                                  ON t.student_user_id = mcs.user_id
+                                      AND t.subproblem_id = sp.id
+               WHERE l.finished
+               GROUP BY l.bank_problem_id)
+SELECT l.bank_problem_id,
+       COUNT(*)                          AS uses,
+       COUNT(*) FILTER (WHERE l.finished) AS finished_uses,
+       COALESCE(MAX(tally.assigned), 0)  AS assigned,
+       COALESCE(MAX(tally.attempted), 0) AS attempted,
+       COALESCE(MAX(tally.accepted), 0)  AS accepted
+FROM links l
+         LEFT JOIN tally ON tally.bank_problem_id = l.bank_problem_id
+GROUP BY l.bank_problem_id
+`
+
+// BankProblemStats aggregates the history of the given bank problems. Bank
+// problems never placed in a series are absent from the result.
+func (q *Queries) BankProblemStats(ctx context.Context, bankProblemIDs []int64) ([]BankProblemStat, error) {
+	rows, err := q.db.Query(ctx, bankProblemStatsSQL, bankProblemIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []BankProblemStat{}
+	for rows.Next() {
+		var s BankProblemStat
+		if err := rows.Scan(&s.BankProblemID, &s.Uses, &s.FinishedUses, &s.Assigned, &s.Attempted, &s.Accepted); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const createProblemFromBankSQL = `
+INSERT INTO math_center_problems (series_id, number, bank_problem_id)
+VALUES ($1, $2, $3)
+RETURNING id, series_id, number, created_at
+`
+
+// CreateProblemFromBank places a bank problem into a series as a new
+// numbered problem; the caller adds its subproblems.
+func (q *Queries) CreateProblemFromBank(ctx context.Context, seriesID int64, number int32, bankProblemID int64) (MathCenterProblem, error) {
+	var p MathCenterProblem
+	err := q.db.QueryRow(ctx, createProblemFromBankSQL, seriesID, number, bankProblemID).
+		Scan(&p.ID, &p.SeriesID, &p.Number, &p.CreatedAt)
+	return p, err
+}
+
+// SeriesBankLink is one series problem that references the bank.
+type SeriesBankLink struct {
+	ProblemID     int64
+	ProblemNumber int32
+	BankProblemID int64
+	Title         string
+}
+
+const listSeriesBankLinksSQL = `
+SELECT p.id, p.number, b.id, b.title
+FROM math_center_problems p
+         JOIN math_center_bank_problems b ON b.id = p.bank_problem_id
+WHERE p.series_id = $1
+ORDER BY p.number
+`
+
+func (q *Queries) ListSeriesBankLinks(ctx context.Context, seriesID int64) ([]SeriesBankLink, error) {
+	rows, err := q.db.Query(ctx, listSeriesBankLinksSQL, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SeriesBankLink{}
+	for rows.Next() {
+		var l SeriesBankLink
+		if err := rows.Scan(&l.ProblemID, &l.ProblemNumber, &l.BankProblemID, &l.Title); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,254 @@
+package store
+
+// Query surface for scheduled publication (migration 000039). Hand-written
+// like subproblem_solution_publication.go. Every read resolves RunAt, the
+// moment the schedule is due: publish_at, or the series due_at for the
+// symbolic "at series due" option, so moving a deadline moves the разбор.
+
+import (
+	"context"
+	"time"
+)
+
+// Schedule target kinds, mirroring the CHECK on target_kind.
+const (
+	PublicationTargetSeries    = "series"
+	PublicationTargetSolutions = "solutions"
+	PublicationTargetLikbez    = "likbez"
+)
+
+// Schedule statuses, mirroring the CHECK on status.
+const (
+	PublicationPending   = "pending"
+	PublicationDone      = "done"
+	PublicationFailed    = "failed"
+	PublicationCancelled = "cancelled"
+)
+
+type PublicationSchedule struct {
+	ID              int64
+	MathCenterID    int64
+	TargetKind      string
+	SeriesID        *int64
+	LikbezID        *int64
+	SubproblemIDs   []int64
+	PublishAt       *time.Time
+	AtSeriesDue     bool
+	RunAt           time.Time
+	Status          string
+	Attempts        int32
+	LastError       string
+	CreatedByUserID *int64
+	CreatedAt       time.Time
+	FinishedAt      *time.Time
+}
+
+const publicationScheduleColumns = `ps.id, ps.math_center_id, ps.target_kind, ps.series_id, ps.likbez_id, ps.subproblem_ids,
+       ps.publish_at, ps.at_series_due, CASE WHEN ps.at_series_due THEN s.due_at ELSE ps.publish_at END,
+       ps.status, ps.attempts, ps.last_error, ps.created_by_user_id, ps.created_at, ps.finished_at`
+
+func scanPublicationSchedule(row interface{ Scan(...any) error }) (PublicationSchedule, error) {
+	var p PublicationSchedule
+	err := row.Scan(&p.ID, &p.MathCenterID, &p.TargetKind, &p.SeriesID, &p.LikbezID, &p.SubproblemIDs,
+		&p.PublishAt, &p.AtSeriesDue, &p.RunAt,
+		&p.Status, &p.Attempts, &p.LastError, &p.CreatedByUserID, &p.CreatedAt, &p.FinishedAt)
+	return p, err
+}
+
+type CreatePublicationScheduleParams struct {
+	MathCenterID    int64
+	TargetKind      string
+	SeriesID        *int64
+	LikbezID        *int64
+	SubproblemIDs   []int64
+	PublishAt       *time.Time
+	AtSeriesDue     bool
+	CreatedByUserID int64
+}
+
+const createPublicationScheduleSQL = `
+WITH ps AS (
+    INSERT INTO math_center_publication_schedule
+        (math_center_id, target_kind, series_id, likbez_id, subproblem_ids, publish_at, at_series_due, created_by_user_id)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    RETURNING *)
+SELECT ` + publicationScheduleColumns + `
+FROM ps
+         LEFT JOIN math_center_series s ON s.id = ps.series_id
+`
+
+func (q *Queries) CreatePublicationSchedule(ctx context.Context, arg CreatePublicationScheduleParams) (PublicationSchedule, error) {
+	ids := arg.SubproblemIDs
+	if ids == nil {
+		ids = []int64{}
+	}
+	return scanPublicationSchedule(q.db.QueryRow(ctx, createPublicationScheduleSQL,
+		arg.MathCenterID, arg.TargetKind, arg.SeriesID, arg.LikbezID, ids,
+		arg.PublishAt, arg.AtSeriesDue, arg.CreatedByUserID))
+}
+
+const getPublicationScheduleSQL = `
+SELECT ` + publicationScheduleColumns + `
+FROM math_center_publication_schedule ps
+         LEFT JOIN math_center_series s ON s.id = ps.series_id
+WHERE ps.id = $1
+`
+
+func (q *Queries) GetPublicationSchedule(ctx context.Context, id int64) (PublicationSchedule, error) {
+	return scanPublicationSchedule(q.db.QueryRow(ctx, getPublicationScheduleSQL, id))
+}
+
+// Pending schedules in the order they will run, then the ones that ran,
+// failed or were cancelled in the last two weeks, newest first. A non-null
+// $2 keeps the schedules of that term's series and likbez.
+const listPublicationSchedulesSQL = `
+SELECT ` + publicationScheduleColumns + `
+FROM math_center_publication_schedule ps
+         LEFT JOIN math_center_series s ON s.id = ps.series_id
+WHERE ps.math_center_id = $1
+  AND ($2::bigint IS NULL
+       OR s.term_id = $2
+       OR ps.likbez_id IN (SELECT id FROM math_center_likbez WHERE term_id = $2))
+  AND (ps.status = 'pending' OR ps.finished_at > NOW() - INTERVAL '14 days')
+ORDER BY ps.status <> 'pending',
+         CASE WHEN ps.status = 'pending' THEN CASE WHEN ps.at_series_due THEN s.due_at ELSE ps.publish_at END END,
+         ps.finished_at DESC,
+         ps.id
+`
+
+func (q *Queries) ListPublicationSchedules(ctx context.Context, mathCenterID int64, termID *int64) ([]PublicationSchedule, error) {
+	rows, err := q.db.Query(ctx, listPublicationSchedulesSQL, mathCenterID, termID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PublicationSchedule{}
+	for rows.Next() {
+		p, err := scanPublicationSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const cancelPendingSeriesScheduleSQL = `
+UPDATE math_center_publication_schedule
+SET status      = 'cancelled',
+    finished_at = NOW()
+WHERE series_id = $1
+  AND target_kind = 'series'
+  AND status = 'pending'
+`
+
+// CancelPendingSeriesSchedule drops the series statement's pending schedule,
+// if any, so a new one can replace it. Разбор schedules are left alone.
+func (q *Queries) CancelPendingSeriesSchedule(ctx context.Context, seriesID int64) error {
+	_, err := q.db.Exec(ctx, cancelPendingSeriesScheduleSQL, seriesID)
+	return err
+}
+
+const cancelPendingLikbezScheduleSQL = `
+UPDATE math_center_publication_schedule
+SET status      = 'cancelled',
+    finished_at = NOW()
+WHERE likbez_id = $1
+  AND status = 'pending'
+`
+
+func (q *Queries) CancelPendingLikbezSchedule(ctx context.Context, likbezID int64) error {
+	_, err := q.db.Exec(ctx, cancelPendingLikbezScheduleSQL, likbezID)
+	return err
+}
+
+const cancelPublicationScheduleSQL = `
+UPDATE math_center_publication_schedule
+SET status      = 'cancelled',
+    finished_at = NOW()
+WHERE id = $1
+  AND status = 'pending'
+`
+
+// CancelPublicationSchedule returns how many rows it cancelled: 0 when the
+// schedule already ran, failed or was cancelled. A worker publishing the row
+// right now holds its lock, so the cancel waits and then finds it done.
+func (q *Queries) CancelPublicationSchedule(ctx context.Context, id int64) (int64, error) {
+	tag, err := q.db.Exec(ctx, cancelPublicationScheduleSQL, id)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const pendingSolutionScheduleOverlapsSQL = `
+SELECT EXISTS (SELECT 1
+               FROM math_center_publication_schedule
+               WHERE target_kind = 'solutions'
+                 AND status = 'pending'
+                 AND subproblem_ids && $1::bigint[])
+`
+
+// PendingSolutionScheduleOverlaps reports whether any of the subproblems is
+// already in a pending разбор schedule.
+func (q *Queries) PendingSolutionScheduleOverlaps(ctx context.Context, subproblemIDs []int64) (bool, error) {
+	var ok bool
+	err := q.db.QueryRow(ctx, pendingSolutionScheduleOverlapsSQL, subproblemIDs).Scan(&ok)
+	return ok, err
+}
+
+// The row lock is held until the caller's transaction ends; SKIP LOCKED lets
+// workers on other replicas take the next due row instead of waiting.
+const claimDuePublicationSQL = `
+SELECT ` + publicationScheduleColumns + `
+FROM math_center_publication_schedule ps
+         LEFT JOIN math_center_series s ON s.id = ps.series_id
+WHERE ps.status = 'pending'
+  AND CASE WHEN ps.at_series_due THEN s.due_at ELSE ps.publish_at END <= $1
+ORDER BY CASE WHEN ps.at_series_due THEN s.due_at ELSE ps.publish_at END, ps.id
+LIMIT 1
+FOR UPDATE OF ps SKIP LOCKED
+`
+
+// ClaimDuePublication locks the oldest due pending schedule; pgx.ErrNoRows
+// when nothing is due or every due row is taken. Call it inside a
+// transaction.
+func (q *Queries) ClaimDuePublication(ctx context.Context, now time.Time) (PublicationSchedule, error) {
+	return scanPublicationSchedule(q.db.QueryRow(ctx, claimDuePublicationSQL, now))
+}
+
+const finishPublicationScheduleSQL = `
+UPDATE math_center_publication_schedule
+SET status      = $2,
+    last_error  = $3,
+    attempts    = attempts + 1,
+    finished_at = NOW()
+WHERE id = $1
+`
+
+// FinishPublicationSchedule records the outcome of a claimed schedule: done,
+// or failed with the reason (the target was not ready to publish).
+func (q *Queries) FinishPublicationSchedule(ctx context.Context, id int64, status, lastError string) error {
+	_, err := q.db.Exec(ctx, finishPublicationScheduleSQL, id, status, lastError)
+	return err
+}
+
+const recordPublicationErrorSQL = `
+UPDATE math_center_publication_schedule
+SET attempts    = attempts + 1,
+    last_error  = $2,
+    status      = CASE WHEN attempts + 1 >= $3 THEN 'failed' ELSE status END,
+    finished_at = CASE WHEN attempts + 1 >= $3 THEN NOW() END
+WHERE id = $1
+  AND status = 'pending'
+`
+
+// RecordPublicationError notes a transient failure outside the rolled-back
+// publication transaction. After maxAttempts the schedule is parked as failed.
+func (q *Queries) RecordPublicationError(ctx context.Context, id int64, lastError string, maxAttempts int32) error {
+	_, err := q.db.Exec(ctx, recordPublicationErrorSQL, id, lastError, maxAttempts)
+	return err
+}
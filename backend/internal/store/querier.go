@@ -138,6 +138,7 @@ type Querier interface {
 	InsertEventPhoto(ctx context.Context, arg InsertEventPhotoParams) error
 	IsHeadTeacherInCenter(ctx context.Context, arg IsHeadTeacherInCenterParams) (bool, error)
 	IsStudentInCenter(ctx context.Context, arg IsStudentInCenterParams) (bool, error)
+	// Term-scoped assistants teach only while their term is active.
 	IsTeacherInCenter(ctx context.Context, arg IsTeacherInCenterParams) (bool, error)
 	IsTermActive(ctx context.Context, id int64) (bool, error)
 	// Persistent Telegram alert destinations and one-use group enrollment state.
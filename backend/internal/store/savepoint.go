@@ -0,0 +1,28 @@
+package store
+
+// Savepoints inside a caller's transaction, for batch writes that undo one
+// item without giving up the rest. Hand-written; sqlc has no statement kind
+// for them.
+
+import "context"
+
+// Re-declaring the same name shadows the older savepoint, so a loop can set
+// one per item and ROLLBACK TO always returns to the current item's start.
+const (
+	savepointSQL           = `SAVEPOINT batch_item`
+	rollbackToSavepointSQL = `ROLLBACK TO SAVEPOINT batch_item`
+)
+
+// Savepoint marks the start of one item of a batch. Only meaningful on a
+// Queries bound to a transaction.
+func (q *Queries) Savepoint(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, savepointSQL)
+	return err
+}
+
+// RollbackToSavepoint undoes everything since the latest Savepoint; the
+// transaction stays usable.
+func (q *Queries) RollbackToSavepoint(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, rollbackToSavepointSQL)
+	return err
+}
@@ -0,0 +1,113 @@
+package store
+
+// Query surface for cloning a series into another term. Hand-written like
+// subproblem_solution_publication.go. The clone is matched to its source by
+// problem number and subproblem label, the stable identities within a
+// series, so these run in order inside one transaction after the target
+// series row exists.
+
+import "context"
+
+// A bank link survives only if the bank problem is school-wide or belongs to
+// the destination center: another center's private problem must not be
+// linked from, or counted in the solve rates of, a foreign center.
+const cloneSeriesProblemsSQL = `
+INSERT INTO math_center_problems (series_id, number, bank_problem_id)
+SELECT $2,
+       p.number,
+       CASE
+           WHEN b.math_center_id IS NULL OR b.math_center_id = dst.math_center_id THEN p.bank_problem_id
+           END
+FROM math_center_problems p
+         JOIN math_center_series dst ON dst.id = $2
+         LEFT JOIN math_center_bank_problems b ON b.id = p.bank_problem_id
+WHERE p.series_id = $1
+ORDER BY p.number
+`
+
+// CloneSeriesProblems copies the source series' problems into dst, keeping
+// the problem bank links the destination center may use.
+func (q *Queries) CloneSeriesProblems(ctx context.Context, srcSeriesID, dstSeriesID int64) error {
+	_, err := q.db.Exec(ctx, cloneSeriesProblemsSQL, srcSeriesID, dstSeriesID)
+	return err
+}
+
+const cloneSeriesSubproblemsSQL = `
+INSERT INTO math_center_subproblems (problem_id, label)
+SELECT dp.id, sp.label
+FROM math_center_subproblems sp
+         JOIN math_center_problems p ON p.id = sp.problem_id AND p.series_id = $1
+         JOIN math_center_problems dp ON dp.series_id = $2 AND dp.number = p.number
+ORDER BY p.number, sp.label
+`
+
+// CloneSeriesSubproblems copies the subproblem structure; run it after
+// CloneSeriesProblems.
+func (q *Queries) CloneSeriesSubproblems(ctx context.Context, srcSeriesID, dstSeriesID int64) error {
+	_, err := q.db.Exec(ctx, cloneSeriesSubproblemsSQL, srcSeriesID, dstSeriesID)
+	return err
+}
+
+// The copy is a draft: publication, coffin release and the shared разбор
+// group belong to the source term and are not carried over. The PDF key is
+// left NULL because the object has to be copied under the new subproblem's
+// key first; the source keys come back so the caller can do that.
+const cloneSeriesSolutionsSQL = `
+WITH src AS (SELECT dsp.id AS dst_subproblem_id, sol.*
+             FROM math_center_subproblem_solutions sol
+                      JOIN math_center_subproblems sp ON sp.id = sol.subproblem_id
+                      JOIN math_center_problems p ON p.id = sp.problem_id AND p.series_id = $1
+                      JOIN math_center_problems dp ON dp.series_id = $2 AND dp.number = p.number
+                      JOIN math_center_subproblems dsp ON dsp.problem_id = dp.id AND dsp.label = sp.label),
+     ins AS (
+         INSERT INTO math_center_subproblem_solutions (subproblem_id, is_coffin, solution_tex_source, solution_link)
+             SELECT dst_subproblem_id, is_coffin, solution_tex_source, solution_link
+             FROM src
+             RETURNING subproblem_id)
+SELECT dst_subproblem_id, solution_pdf_object_key
+FROM src
+WHERE solution_pdf_object_key IS NOT NULL
+ORDER BY dst_subproblem_id
+`
+
+// ClonedSolutionPDF is a cloned разбор whose PDF still has to be copied.
+type ClonedSolutionPDF struct {
+	SubproblemID int64
+	SourceKey    string
+}
+
+// CloneSeriesSolutions copies the per-subproblem разбор material and coffin
+// flags; run it after CloneSeriesSubproblems. It returns the cloned rows
+// that had a PDF, with the source object key.
+func (q *Queries) CloneSeriesSolutions(ctx context.Context, srcSeriesID, dstSeriesID int64) ([]ClonedSolutionPDF, error) {
+	rows, err := q.db.Query(ctx, cloneSeriesSolutionsSQL, srcSeriesID, dstSeriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ClonedSolutionPDF{}
+	for rows.Next() {
+		var c ClonedSolutionPDF
+		if err := rows.Scan(&c.SubproblemID, &c.SourceKey); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const setClonedSolutionPDFSQL = `
+UPDATE math_center_subproblem_solutions
+SET solution_pdf_object_key = $2,
+    updated_at              = NOW()
+WHERE subproblem_id = $1
+`
+
+// SetClonedSolutionPDF points a cloned разбор at its copied PDF.
+func (q *Queries) SetClonedSolutionPDF(ctx context.Context, subproblemID int64, key string) error {
+	_, err := q.db.Exec(ctx, setClonedSolutionPDFSQL, subproblemID, key)
+	return err
+}
@@ -0,0 +1,161 @@
+package store
+
+// Query surface for the cross-term student progress dashboard. Hand-written
+// like conduit_grid.go; the aggregation into terms and streaks happens in
+// internal/mathcenter.
+
+import (
+	"context"
+	"time"
+)
+
+type StudentProgressParams struct {
+	MathCenterID  int64
+	StudentUserID int64
+	// Now decides which coffins are still open: a coffin closes once its
+	// разбор is published and released_at has passed, the same rule the
+	// student-facing Гробы tab applies.
+	Now time.Time
+}
+
+// StudentProgressSeriesRow is one published series the student can see,
+// with their counts over its subproblems.
+type StudentProgressSeriesRow struct {
+	SeriesID      int64
+	SeriesNumber  int32
+	SeriesName    string
+	SeriesDueAt   time.Time
+	TermID        int64
+	TermKind      string
+	TermGrade     *int32
+	TermIsActive  bool
+	Subproblems   int64
+	Accepted      int64
+	Rejected      int64
+	Pending       int64
+	Coffins       int64
+	CoffinsSolved int64
+	CoffinsOpen   int64
+}
+
+// A series belongs to the student's history when they were enrolled in its
+// term, or when they have a thread on it (a coffin carried into a later term
+// the student joined). Drafts never appear.
+const studentProgressSeriesSQL = `
+SELECT se.id,
+       se.number,
+       se.name,
+       se.due_at,
+       t.id,
+       t.kind,
+       t.grade,
+       t.is_active,
+       COUNT(sp.id)::bigint,
+       COUNT(sp.id) FILTER (WHERE th.current_status = 'accepted')::bigint,
+       COUNT(sp.id) FILTER (WHERE th.current_status = 'rejected')::bigint,
+       COUNT(sp.id) FILTER (
+           WHERE COALESCE(th.current_status, 'ungraded') IN ('ungraded', 'submitted', 'appealed')
+       )::bigint,
+       COUNT(sp.id) FILTER (WHERE sol.is_coffin)::bigint,
+       COUNT(sp.id) FILTER (WHERE sol.is_coffin AND th.current_status = 'accepted')::bigint,
+       COUNT(sp.id) FILTER (
+           WHERE sol.is_coffin
+             AND th.current_status IS DISTINCT FROM 'accepted'
+             AND NOT (sol.published_at IS NOT NULL
+                      AND sol.released_at IS NOT NULL
+                      AND sol.released_at <= $3)
+       )::bigint
+FROM math_center_series se
+         JOIN math_center_terms t ON t.id = se.term_id
+         LEFT JOIN math_center_problems p ON p.series_id = se.id
+         LEFT JOIN math_center_subproblems sp ON sp.problem_id = p.id
+         LEFT JOIN math_center_subproblem_solutions sol ON sol.subproblem_id = sp.id
+         LEFT JOIN homework_thread th
+                   ON th.subproblem_id = sp.id
+                  AND th.student_user_id = $2
+WHERE se.math_center_id = $1
+  AND se.published_at IS NOT NULL
+  AND (
+      EXISTS (SELECT 1
+              FROM math_center_students mcs
+              WHERE mcs.term_id = se.term_id
+                AND mcs.user_id = $2)
+      OR EXISTS (SELECT 1
+                 FROM homework_thread h
+                 WHERE h.series_id = se.id
+                   AND h.student_user_id = $2)
+  )
+GROUP BY se.id, t.id
+ORDER BY se.due_at ASC, se.number ASC, se.id ASC
+`
+
+// ListStudentProgressSeries returns the student's series across every term
+// of the center, oldest deadline first.
+func (q *Queries) ListStudentProgressSeries(ctx context.Context, arg StudentProgressParams) ([]StudentProgressSeriesRow, error) {
+	rows, err := q.db.Query(ctx, studentProgressSeriesSQL, arg.MathCenterID, arg.StudentUserID, arg.Now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []StudentProgressSeriesRow{}
+	for rows.Next() {
+		var r StudentProgressSeriesRow
+		if err := rows.Scan(&r.SeriesID, &r.SeriesNumber, &r.SeriesName, &r.SeriesDueAt,
+			&r.TermID, &r.TermKind, &r.TermGrade, &r.TermIsActive,
+			&r.Subproblems, &r.Accepted, &r.Rejected, &r.Pending,
+			&r.Coffins, &r.CoffinsSolved, &r.CoffinsOpen); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StudentTurnaroundRow sums, per series, the time from each of the student's
+// attempts to the verdict on it.
+type StudentTurnaroundRow struct {
+	SeriesID     int64
+	Graded       int64
+	TotalSeconds float64
+}
+
+// Every online verdict refers to the attempt it judged; offline acceptances
+// have no attempt and do not count. Calibration grades live elsewhere.
+const studentTurnaroundSQL = `
+SELECT th.series_id,
+       COUNT(*)::bigint,
+       COALESCE(SUM(EXTRACT(EPOCH FROM g.created_at - a.created_at)), 0)::float8
+FROM homework_thread th
+         JOIN math_center_series se ON se.id = th.series_id
+         JOIN homework_thread_event g
+              ON g.thread_id = th.id
+             AND g.kind = 'graded'
+         JOIN homework_thread_event a ON a.id = g.refers_to_event_id
+WHERE th.math_center_id = $1
+  AND th.student_user_id = $2
+  AND se.published_at IS NOT NULL
+GROUP BY th.series_id
+`
+
+func (q *Queries) ListStudentTurnaround(ctx context.Context, arg StudentProgressParams) ([]StudentTurnaroundRow, error) {
+	rows, err := q.db.Query(ctx, studentTurnaroundSQL, arg.MathCenterID, arg.StudentUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []StudentTurnaroundRow{}
+	for rows.Next() {
+		var r StudentTurnaroundRow
+		if err := rows.Scan(&r.SeriesID, &r.Graded, &r.TotalSeconds); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
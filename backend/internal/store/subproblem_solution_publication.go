@@ -117,6 +117,7 @@ type SolutionPublicationTarget struct {
 	IsCoffin     bool
 	HasMaterial  bool
 	GroupID      *int64
+	TexSource    *string
 }
 
 const lockSolutionPublicationTargetsSQL = `
@@ -127,7 +128,8 @@ SELECT ss.subproblem_id,
        (ss.solution_tex_source IS NOT NULL
          OR ss.solution_pdf_object_key IS NOT NULL
          OR ss.solution_link IS NOT NULL)::boolean AS has_material,
-       ss.solution_group_id
+       ss.solution_group_id,
+       ss.solution_tex_source
 FROM math_center_subproblem_solutions ss
 JOIN math_center_subproblems sp ON sp.id = ss.subproblem_id
 JOIN math_center_problems p ON p.id = sp.problem_id
@@ -145,7 +147,7 @@ func (q *Queries) LockSolutionPublicationTargets(ctx context.Context, ids []int6
 	out := make([]SolutionPublicationTarget, 0, len(ids))
 	for rows.Next() {
 		var row SolutionPublicationTarget
-		if err := rows.Scan(&row.SubproblemID, &row.MathCenterID, &row.SeriesID, &row.IsCoffin, &row.HasMaterial, &row.GroupID); err != nil {
+		if err := rows.Scan(&row.SubproblemID, &row.MathCenterID, &row.SeriesID, &row.IsCoffin, &row.HasMaterial, &row.GroupID, &row.TexSource); err != nil {
 			return nil, err
 		}
 		out = append(out, row)
@@ -325,12 +325,15 @@ WHERE t.math_center_id = $1
   AND (t.claim_holder_user_id IS NULL
        OR t.claim_expires_at < NOW()
        OR t.claim_holder_user_id = $2::bigint)
+  AND ($3::bigint IS NULL
+       OR t.series_id IN (SELECT id FROM math_center_series WHERE term_id = $3::bigint))
 ORDER BY t.current_status ASC, t.updated_at ASC
 `
 
 type ListCoffinQueueForCenterParams struct {
-	MathCenterID int64 `json:"math_center_id"`
-	CallerUserID int64 `json:"caller_user_id"`
+	MathCenterID int64  `json:"math_center_id"`
+	CallerUserID int64  `json:"caller_user_id"`
+	TermID       *int64 `json:"term_id"`
 }
 
 type ListCoffinQueueForCenterRow struct {
@@ -354,7 +357,7 @@ type ListCoffinQueueForCenterRow struct {
 // subproblems that aren't locked by another grader. Mirrors the per-series
 // grader queue but spans every series and is filtered to coffins.
 func (q *Queries) ListCoffinQueueForCenter(ctx context.Context, arg ListCoffinQueueForCenterParams) ([]ListCoffinQueueForCenterRow, error) {
-	rows, err := q.db.Query(ctx, listCoffinQueueForCenter, arg.MathCenterID, arg.CallerUserID)
+	rows, err := q.db.Query(ctx, listCoffinQueueForCenter, arg.MathCenterID, arg.CallerUserID, arg.TermID)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,124 @@
+package store
+
+// Query surface for teacher roles (migration 000045). Hand-written like
+// lessons.go; membership itself still goes through the sqlc queries
+// (IsTeacherInCenter, AddTeacherToCenter, SetTeacherHead).
+
+import "context"
+
+// TeacherAccess is what a teacher may do in one center. InScope is false for
+// a term-scoped assistant whose term is no longer active.
+type TeacherAccess struct {
+	TeacherID     int64
+	IsHeadTeacher bool
+	Role          string
+	TermID        *int64
+	InScope       bool
+}
+
+const getTeacherAccess = `
+SELECT t.id,
+       t.is_head_teacher,
+       t.role,
+       t.term_id,
+       t.term_id IS NULL OR COALESCE(term.is_active, FALSE) AS in_scope
+FROM math_center_teachers t
+LEFT JOIN math_center_terms term ON term.id = t.term_id
+WHERE t.user_id = $1
+  AND t.math_center_id = $2
+`
+
+// GetTeacherAccess returns pgx.ErrNoRows when userID does not teach the
+// center.
+func (q *Queries) GetTeacherAccess(ctx context.Context, userID, centerID int64) (TeacherAccess, error) {
+	var a TeacherAccess
+	err := q.db.QueryRow(ctx, getTeacherAccess, userID, centerID).
+		Scan(&a.TeacherID, &a.IsHeadTeacher, &a.Role, &a.TermID, &a.InScope)
+	return a, err
+}
+
+const setTeacherRole = `
+UPDATE math_center_teachers
+SET is_head_teacher = $2,
+    role = $3,
+    term_id = $4
+WHERE id = $1
+`
+
+// SetTeacherRole sets the head flag, role and term scope of a teacher row in
+// one statement, so the head/term check sees the final row. Returns the
+// number of rows updated.
+func (q *Queries) SetTeacherRole(ctx context.Context, teacherID int64, isHeadTeacher bool, role string, termID *int64) (int64, error) {
+	tag, err := q.db.Exec(ctx, setTeacherRole, teacherID, isHeadTeacher, role, termID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// TeacherRoleRow is the role of one teacher of a center.
+type TeacherRoleRow struct {
+	TeacherID int64
+	UserID    int64
+	Role      string
+	TermID    *int64
+}
+
+const listTeacherRolesForCenter = `
+SELECT id, user_id, role, term_id
+FROM math_center_teachers
+WHERE math_center_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListTeacherRolesForCenter(ctx context.Context, centerID int64) ([]TeacherRoleRow, error) {
+	rows, err := q.db.Query(ctx, listTeacherRolesForCenter, centerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TeacherRoleRow{}
+	for rows.Next() {
+		var r TeacherRoleRow
+		if err := rows.Scan(&r.TeacherID, &r.UserID, &r.Role, &r.TermID); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// RolloverTeacherRow is a teacher of a center with the term they are scoped
+// to, if any.
+type RolloverTeacherRow struct {
+	UserID        int64
+	IsHeadTeacher bool
+	TermID        *int64
+	FirstName     string
+	LastName      string
+}
+
+const listRolloverTeachers = `
+SELECT t.user_id, t.is_head_teacher, t.term_id, u.first_name, u.last_name
+FROM math_center_teachers t
+         JOIN users u ON u.id = t.user_id
+WHERE t.math_center_id = $1
+ORDER BY t.is_head_teacher DESC, u.last_name ASC, u.first_name ASC
+`
+
+func (q *Queries) ListRolloverTeachers(ctx context.Context, centerID int64) ([]RolloverTeacherRow, error) {
+	rows, err := q.db.Query(ctx, listRolloverTeachers, centerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []RolloverTeacherRow{}
+	for rows.Next() {
+		var r RolloverTeacherRow
+		if err := rows.Scan(&r.UserID, &r.IsHeadTeacher, &r.TermID, &r.FirstName, &r.LastName); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,64 @@
+package store
+
+// Query surface for the term rollover. Hand-written like lessons.go: unlike
+// CopyGroupsToTerm and CopyStudentsToUnassignedGroup, which carry a whole
+// term, these copy one group or one enrollment so a head teacher can pick
+// what follows the cohort.
+
+import "context"
+
+const copyGroupToTerm = `
+INSERT INTO math_center_groups (
+    math_center_id,
+    term_id,
+    name,
+    razbor_default_video,
+    razbor_default_pdf_tex
+)
+SELECT g.math_center_id,
+       $2,
+       $3,
+       g.razbor_default_video,
+       g.razbor_default_pdf_tex
+FROM math_center_groups g
+WHERE g.id = $1
+RETURNING id
+`
+
+// CopyGroupToTerm creates a group in termID under name, inheriting the
+// razbor defaults of sourceGroupID. Returns the new group id.
+func (q *Queries) CopyGroupToTerm(ctx context.Context, sourceGroupID, termID int64, name string) (int64, error) {
+	var id int64
+	err := q.db.QueryRow(ctx, copyGroupToTerm, sourceGroupID, termID, name).Scan(&id)
+	return id, err
+}
+
+const carryStudentToTerm = `
+INSERT INTO math_center_students (
+    user_id,
+    group_id,
+    term_id,
+    razbor_default_video,
+    razbor_default_pdf_tex
+)
+SELECT student.user_id,
+       target_group.id,
+       target_group.term_id,
+       student.razbor_default_video,
+       student.razbor_default_pdf_tex
+FROM math_center_students student
+JOIN math_center_groups target_group ON target_group.id = $3
+WHERE student.term_id = $1
+  AND student.user_id = $2
+ON CONFLICT (user_id, term_id) DO NOTHING
+`
+
+// CarryStudentToTerm enrolls userID of sourceTermID into groupID, keeping
+// the student's own razbor defaults. Reports whether a row was inserted.
+func (q *Queries) CarryStudentToTerm(ctx context.Context, sourceTermID, userID, groupID int64) (bool, error) {
+	tag, err := q.db.Exec(ctx, carryStudentToTerm, sourceTermID, userID, groupID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
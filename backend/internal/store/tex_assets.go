@@ -0,0 +1,217 @@
+package store
+
+// Query surface for TeX document assets (migration 000042). Hand-written
+// like tex_revisions.go. An owner is one series statement, one likbez or
+// one subproblem разбор; each asset is addressed within it by name.
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	TexAssetSeries   = "series"
+	TexAssetLikbez   = "likbez"
+	TexAssetSolution = "solution"
+)
+
+// TexAssetOwner addresses the document an asset belongs to. Exactly one of
+// SeriesID, LikbezID and SubproblemID is set, matching Kind.
+type TexAssetOwner struct {
+	MathCenterID int64
+	Kind         string
+	SeriesID     *int64
+	LikbezID     *int64
+	SubproblemID *int64
+}
+
+type TexAsset struct {
+	ID               int64
+	Name             string
+	ObjectKey        string
+	ContentType      string
+	SizeBytes        int64
+	UploadedByUserID *int64
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// The owner predicate shared by the per-owner queries; $1..$5 are the
+// TexAssetOwner fields in order.
+const texAssetOwnerWhere = `math_center_id = $1
+  AND target_kind = $2
+  AND series_id IS NOT DISTINCT FROM $3::bigint
+  AND likbez_id IS NOT DISTINCT FROM $4::bigint
+  AND subproblem_id IS NOT DISTINCT FROM $5::bigint`
+
+const texAssetColumns = `id, name, object_key, content_type, size_bytes, uploaded_by_user_id, created_at, updated_at`
+
+func scanTexAsset(row interface{ Scan(...any) error }) (TexAsset, error) {
+	var a TexAsset
+	err := row.Scan(&a.ID, &a.Name, &a.ObjectKey, &a.ContentType, &a.SizeBytes, &a.UploadedByUserID, &a.CreatedAt, &a.UpdatedAt)
+	return a, err
+}
+
+func ownerArgs(o TexAssetOwner) []any {
+	return []any{o.MathCenterID, o.Kind, o.SeriesID, o.LikbezID, o.SubproblemID}
+}
+
+type UpsertTexAssetParams struct {
+	Owner            TexAssetOwner
+	Name             string
+	ObjectKey        string
+	ContentType      string
+	SizeBytes        int64
+	UploadedByUserID *int64
+}
+
+const upsertTexAssetSQL = `
+INSERT INTO math_center_tex_assets
+    (math_center_id, target_kind, series_id, likbez_id, subproblem_id, name, object_key, content_type,
+     size_bytes, uploaded_by_user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (object_key) DO UPDATE
+    SET content_type        = EXCLUDED.content_type,
+        size_bytes          = EXCLUDED.size_bytes,
+        uploaded_by_user_id = EXCLUDED.uploaded_by_user_id,
+        updated_at          = NOW()
+RETURNING ` + texAssetColumns
+
+// UpsertTexAsset records an uploaded asset. The object key is derived from
+// the owner and name, so re-uploading a name updates its row.
+func (q *Queries) UpsertTexAsset(ctx context.Context, arg UpsertTexAssetParams) (TexAsset, error) {
+	args := append(ownerArgs(arg.Owner), arg.Name, arg.ObjectKey, arg.ContentType, arg.SizeBytes, arg.UploadedByUserID)
+	return scanTexAsset(q.db.QueryRow(ctx, upsertTexAssetSQL, args...))
+}
+
+const listTexAssetsSQL = `
+SELECT ` + texAssetColumns + `
+FROM math_center_tex_assets
+WHERE ` + texAssetOwnerWhere + `
+ORDER BY name`
+
+// ListTexAssets returns the owner's assets by name.
+func (q *Queries) ListTexAssets(ctx context.Context, owner TexAssetOwner) ([]TexAsset, error) {
+	rows, err := q.db.Query(ctx, listTexAssetsSQL, ownerArgs(owner)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TexAsset{}
+	for rows.Next() {
+		a, err := scanTexAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+const countTexAssetsSQL = `
+SELECT COUNT(*)
+FROM math_center_tex_assets
+WHERE ` + texAssetOwnerWhere
+
+func (q *Queries) CountTexAssets(ctx context.Context, owner TexAssetOwner) (int64, error) {
+	var n int64
+	err := q.db.QueryRow(ctx, countTexAssetsSQL, ownerArgs(owner)...).Scan(&n)
+	return n, err
+}
+
+const deleteTexAssetSQL = `
+DELETE
+FROM math_center_tex_assets
+WHERE ` + texAssetOwnerWhere + `
+  AND name = $6
+RETURNING ` + texAssetColumns
+
+// DeleteTexAsset removes one asset row and returns it, so the caller can
+// delete the object; pgx.ErrNoRows when the owner has no such name.
+func (q *Queries) DeleteTexAsset(ctx context.Context, owner TexAssetOwner, name string) (TexAsset, error) {
+	return scanTexAsset(q.db.QueryRow(ctx, deleteTexAssetSQL, append(ownerArgs(owner), name)...))
+}
+
+const listSeriesTexAssetKeysSQL = `
+SELECT a.object_key
+FROM math_center_tex_assets a
+WHERE a.series_id = $1
+   OR a.subproblem_id IN (SELECT sp.id
+                          FROM math_center_subproblems sp
+                                   JOIN math_center_problems p ON p.id = sp.problem_id
+                          WHERE p.series_id = $1)
+ORDER BY a.object_key`
+
+// ListSeriesTexAssetKeys returns the object keys of the series statement's
+// assets and of its разборы' assets. Rows go with the series by cascade;
+// the objects have to be deleted by the caller.
+func (q *Queries) ListSeriesTexAssetKeys(ctx context.Context, seriesID int64) ([]string, error) {
+	rows, err := q.db.Query(ctx, listSeriesTexAssetKeysSQL, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		out = append(out, key)
+	}
+	return out, rows.Err()
+}
+
+// The clone's subproblems are matched to the source's by problem number
+// and label, like cloneSeriesSolutionsSQL.
+const listTexAssetsForCloneSQL = `
+SELECT d.math_center_id, a.target_kind, dsp.id, a.name, a.object_key, a.content_type, a.size_bytes
+FROM math_center_tex_assets a
+         JOIN math_center_series d ON d.id = $2
+         LEFT JOIN math_center_subproblems sp ON sp.id = a.subproblem_id
+         LEFT JOIN math_center_problems p ON p.id = sp.problem_id
+         LEFT JOIN math_center_problems dp ON dp.series_id = $2 AND dp.number = p.number
+         LEFT JOIN math_center_subproblems dsp ON dsp.problem_id = dp.id AND dsp.label = sp.label
+WHERE a.series_id = $1
+   OR (p.series_id = $1 AND dsp.id IS NOT NULL)
+ORDER BY a.target_kind, dsp.id, a.name`
+
+// ClonedTexAsset is a source asset to copy to a clone: Owner is the
+// clone's document, SourceKey the object to copy.
+type ClonedTexAsset struct {
+	Owner       TexAssetOwner
+	Name        string
+	SourceKey   string
+	ContentType string
+	SizeBytes   int64
+}
+
+// ListTexAssetsForClone lists the assets of src's statement and разборы
+// with the matching documents of dst; run it after CloneSeriesSubproblems.
+// Nothing is written: the caller copies each object and records it with
+// UpsertTexAsset.
+func (q *Queries) ListTexAssetsForClone(ctx context.Context, srcSeriesID, dstSeriesID int64) ([]ClonedTexAsset, error) {
+	rows, err := q.db.Query(ctx, listTexAssetsForCloneSQL, srcSeriesID, dstSeriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ClonedTexAsset{}
+	for rows.Next() {
+		var (
+			c            ClonedTexAsset
+			subproblemID *int64
+		)
+		if err := rows.Scan(&c.Owner.MathCenterID, &c.Owner.Kind, &subproblemID, &c.Name, &c.SourceKey, &c.ContentType, &c.SizeBytes); err != nil {
+			return nil, err
+		}
+		if c.Owner.Kind == TexAssetSeries {
+			dst := dstSeriesID
+			c.Owner.SeriesID = &dst
+		} else {
+			c.Owner.SubproblemID = subproblemID
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
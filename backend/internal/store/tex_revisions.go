@@ -0,0 +1,198 @@
+package store
+
+// Query surface for TeX revision history (migration 000041). Hand-written
+// like problem_bank.go. A target is one series statement, one subproblem
+// разбор or one center preamble; the writers append a revision in the same
+// transaction that updates the live column.
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	TexTargetSeries   = "series"
+	TexTargetSolution = "solution"
+	TexTargetPreamble = "preamble"
+)
+
+// TexTarget addresses one revisioned source. Exactly one of SeriesID and
+// SubproblemID is set for the series and solution kinds; neither for the
+// preamble.
+type TexTarget struct {
+	MathCenterID int64
+	Kind         string
+	SeriesID     *int64
+	SubproblemID *int64
+}
+
+type TexRevision struct {
+	ID                   int64
+	Revision             int32
+	Body                 *string
+	AuthorUserID         *int64
+	RestoredFromRevision *int32
+	CreatedAt            time.Time
+	PublishedAt          *time.Time
+}
+
+// The target predicate shared by every query; $1..$4 are the TexTarget
+// fields in order.
+const texTargetWhere = `math_center_id = $1
+  AND target_kind = $2
+  AND series_id IS NOT DISTINCT FROM $3::bigint
+  AND subproblem_id IS NOT DISTINCT FROM $4::bigint`
+
+const texRevisionColumns = `id, revision, body, author_user_id, restored_from_revision, created_at, published_at`
+
+func scanTexRevision(row interface{ Scan(...any) error }) (TexRevision, error) {
+	var r TexRevision
+	err := row.Scan(&r.ID, &r.Revision, &r.Body, &r.AuthorUserID, &r.RestoredFromRevision, &r.CreatedAt, &r.PublishedAt)
+	return r, err
+}
+
+type RecordTexRevisionParams struct {
+	Target       TexTarget
+	Body         *string
+	AuthorUserID int64
+	// RestoredFrom is the revision number this one copies, when restoring.
+	RestoredFrom *int32
+	// Published stamps the revision as shown to students at once, for
+	// targets that are already visible when saved.
+	Published bool
+}
+
+const recordTexRevisionSQL = `
+WITH latest AS (SELECT revision, body
+                FROM math_center_tex_revisions
+                WHERE ` + texTargetWhere + `
+                ORDER BY revision DESC
+                LIMIT 1)
+INSERT INTO math_center_tex_revisions
+    (math_center_id, target_kind, series_id, subproblem_id, revision, body, author_user_id,
+     restored_from_revision, published_at)
+SELECT $1, $2, $3, $4, COALESCE((SELECT revision FROM latest), 0) + 1, $5::text, $6::bigint, $7::integer,
+       CASE WHEN $8::boolean THEN NOW() END
+WHERE NOT EXISTS (SELECT 1 FROM latest WHERE body IS NOT DISTINCT FROM $5::text)
+RETURNING ` + texRevisionColumns
+
+// RecordTexRevision appends the next revision of a target. A save that
+// leaves the body unchanged is not a revision: pgx.ErrNoRows then. Two
+// concurrent saves of one target race on the revision number and the loser
+// gets a unique violation.
+func (q *Queries) RecordTexRevision(ctx context.Context, arg RecordTexRevisionParams) (TexRevision, error) {
+	t := arg.Target
+	return scanTexRevision(q.db.QueryRow(ctx, recordTexRevisionSQL,
+		t.MathCenterID, t.Kind, t.SeriesID, t.SubproblemID, arg.Body, arg.AuthorUserID, arg.RestoredFrom, arg.Published))
+}
+
+const listTexRevisionsSQL = `
+SELECT ` + texRevisionColumns + `
+FROM math_center_tex_revisions
+WHERE ` + texTargetWhere + `
+ORDER BY revision DESC
+`
+
+// ListTexRevisions returns a target's history, newest first.
+func (q *Queries) ListTexRevisions(ctx context.Context, t TexTarget) ([]TexRevision, error) {
+	rows, err := q.db.Query(ctx, listTexRevisionsSQL, t.MathCenterID, t.Kind, t.SeriesID, t.SubproblemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TexRevision{}
+	for rows.Next() {
+		r, err := scanTexRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+const getTexRevisionSQL = `
+SELECT ` + texRevisionColumns + `
+FROM math_center_tex_revisions
+WHERE ` + texTargetWhere + `
+  AND revision = $5
+`
+
+func (q *Queries) GetTexRevision(ctx context.Context, t TexTarget, revision int32) (TexRevision, error) {
+	return scanTexRevision(q.db.QueryRow(ctx, getTexRevisionSQL,
+		t.MathCenterID, t.Kind, t.SeriesID, t.SubproblemID, revision))
+}
+
+const markSeriesTexRevisionPublishedSQL = `
+UPDATE math_center_tex_revisions
+SET published_at = $2
+WHERE id = (SELECT id
+            FROM math_center_tex_revisions
+            WHERE target_kind = 'series'
+              AND series_id = $1
+            ORDER BY revision DESC
+            LIMIT 1)
+  AND published_at IS NULL
+`
+
+// MarkSeriesTexRevisionPublished records that the series' latest statement
+// revision was shown to students at publishedAt. Run it with the
+// publication; repeat calls keep the first stamp.
+func (q *Queries) MarkSeriesTexRevisionPublished(ctx context.Context, seriesID int64, publishedAt time.Time) error {
+	_, err := q.db.Exec(ctx, markSeriesTexRevisionPublishedSQL, seriesID, publishedAt)
+	return err
+}
+
+const markSolutionTexRevisionsPublishedSQL = `
+UPDATE math_center_tex_revisions
+SET published_at = $2
+WHERE id IN (SELECT DISTINCT ON (subproblem_id) id
+             FROM math_center_tex_revisions
+             WHERE target_kind = 'solution'
+               AND subproblem_id = ANY ($1::bigint[])
+             ORDER BY subproblem_id, revision DESC)
+  AND published_at IS NULL
+`
+
+// MarkSolutionTexRevisionsPublished is MarkSeriesTexRevisionPublished for a
+// published разбор group.
+func (q *Queries) MarkSolutionTexRevisionsPublished(ctx context.Context, subproblemIDs []int64, publishedAt time.Time) error {
+	_, err := q.db.Exec(ctx, markSolutionTexRevisionsPublishedSQL, subproblemIDs, publishedAt)
+	return err
+}
+
+const recordClonedSolutionRevisionsSQL = `
+INSERT INTO math_center_tex_revisions (math_center_id, target_kind, subproblem_id, revision, body, author_user_id)
+SELECT s.math_center_id, 'solution', sol.subproblem_id, 1, sol.solution_tex_source, $2
+FROM math_center_subproblem_solutions sol
+         JOIN math_center_subproblems sp ON sp.id = sol.subproblem_id
+         JOIN math_center_problems p ON p.id = sp.problem_id
+         JOIN math_center_series s ON s.id = p.series_id
+WHERE p.series_id = $1
+  AND sol.solution_tex_source IS NOT NULL
+`
+
+// RecordClonedSolutionRevisions starts the history of every разбор TeX in a
+// freshly cloned series at revision 1; run it after CloneSeriesSolutions.
+func (q *Queries) RecordClonedSolutionRevisions(ctx context.Context, seriesID, authorUserID int64) error {
+	_, err := q.db.Exec(ctx, recordClonedSolutionRevisionsSQL, seriesID, authorUserID)
+	return err
+}
+
+const upsertLatexPreambleSQL = `
+INSERT INTO math_center_latex_settings (math_center_id, preamble)
+VALUES ($1, $2)
+ON CONFLICT (math_center_id) DO UPDATE
+    SET preamble   = EXCLUDED.preamble,
+        updated_at = NOW()
+`
+
+// UpsertLatexPreamble sets the center preamble. It lives here so the write
+// can share a transaction with its revision.
+func (q *Queries) UpsertLatexPreamble(ctx context.Context, centerID int64, preamble string) error {
+	_, err := q.db.Exec(ctx, upsertLatexPreambleSQL, centerID, preamble)
+	return err
+}
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_homework_event_photo_pending;
+
+ALTER TABLE homework_thread_event_photo
+    DROP COLUMN IF EXISTS thumb_object_key,
+    DROP COLUMN IF EXISTS preview_object_key,
+    DROP COLUMN IF EXISTS processed_at,
+    DROP COLUMN IF EXISTS processing_error,
+    DROP COLUMN IF EXISTS processing_locked_at,
+    DROP COLUMN IF EXISTS processing_attempts,
+    DROP COLUMN IF EXISTS processing_status;
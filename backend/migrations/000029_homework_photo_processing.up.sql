@@ -0,0 +1,25 @@
+-- Server-side photo pipeline. Uploads land in the bucket exactly as the phone
+-- produced them: GPS EXIF included, HEIC that many browsers cannot display. A
+-- background worker re-encodes every photo row: the upload is replaced by a
+-- metadata-free JPEG ("full", long edge capped), and two smaller renditions
+-- are written next to it. object_key / size_bytes / content_type are rewritten
+-- to the full rendition once it exists, so everything that already reads those
+-- columns keeps working and never sees the raw upload again.
+--
+-- The row doubles as the work queue (same shape as the Google Sheets outbox):
+-- workers lease rows with SKIP LOCKED and a processing_locked_at timestamp, so
+-- several server replicas can run the pipeline without double-processing.
+-- Existing rows start 'pending' too, which strips metadata from the backlog.
+ALTER TABLE homework_thread_event_photo
+    ADD COLUMN processing_status    TEXT        NOT NULL DEFAULT 'pending'
+        CHECK (processing_status IN ('pending', 'done', 'failed', 'unsupported')),
+    ADD COLUMN processing_attempts  INTEGER     NOT NULL DEFAULT 0,
+    ADD COLUMN processing_locked_at TIMESTAMPTZ,
+    ADD COLUMN processing_error     TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN processed_at         TIMESTAMPTZ,
+    ADD COLUMN preview_object_key   TEXT,
+    ADD COLUMN thumb_object_key     TEXT;
+
+CREATE INDEX idx_homework_event_photo_pending
+    ON homework_thread_event_photo (created_at)
+    WHERE processing_status = 'pending';
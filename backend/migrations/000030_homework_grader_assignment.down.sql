@@ -0,0 +1,6 @@
+ALTER TABLE math_center_teachers DROP COLUMN IF EXISTS away_until;
+DROP INDEX IF EXISTS idx_homework_thread_assigned;
+ALTER TABLE homework_thread DROP COLUMN IF EXISTS assigned_grader_user_id;
+DROP TABLE IF EXISTS homework_problem_grader;
+DROP TABLE IF EXISTS homework_series_grader;
+DROP TABLE IF EXISTS homework_series_grading;
@@ -0,0 +1,51 @@
+-- Automatic grader assignment. By default every teacher sees every pending
+-- thread of a series and graders race to claim them. A series can instead opt
+-- into an assignment mode:
+--   round_robin — each new submission goes to the least-loaded grader of the
+--                 series pool, ties broken by who was assigned longest ago;
+--   by_problem  — submissions go to the owners of the problem ("Аня grades
+--                 problems 1–3"), falling back to the pool for unowned ones.
+-- The queue is then filtered to the caller's assignments. Appeals are not
+-- assigned: they keep sticking to last_grader_user_id.
+CREATE TABLE homework_series_grading
+(
+    series_id           BIGINT      PRIMARY KEY REFERENCES math_center_series (id) ON DELETE CASCADE,
+    mode                TEXT        NOT NULL DEFAULT 'off'
+        CHECK (mode IN ('off', 'round_robin', 'by_problem')),
+    -- When set, graders marked away (math_center_teachers.away_until) are
+    -- skipped for new submissions and their pending threads are handed on.
+    reassign_on_absence BOOLEAN     NOT NULL DEFAULT false,
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The round-robin pool. last_assigned_at drives the rotation.
+CREATE TABLE homework_series_grader
+(
+    series_id        BIGINT NOT NULL REFERENCES math_center_series (id) ON DELETE CASCADE,
+    grader_user_id   BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    last_assigned_at TIMESTAMPTZ,
+    PRIMARY KEY (series_id, grader_user_id)
+);
+
+-- Problem owners for by_problem mode. Several owners of one problem share it
+-- round-robin, same as the pool.
+CREATE TABLE homework_problem_grader
+(
+    problem_id       BIGINT NOT NULL REFERENCES math_center_problems (id) ON DELETE CASCADE,
+    grader_user_id   BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    last_assigned_at TIMESTAMPTZ,
+    PRIMARY KEY (problem_id, grader_user_id)
+);
+CREATE INDEX idx_homework_problem_grader_user ON homework_problem_grader (grader_user_id);
+
+ALTER TABLE homework_thread
+    ADD COLUMN assigned_grader_user_id BIGINT REFERENCES users (id) ON DELETE SET NULL;
+
+-- Load counting and the "my assignments" queue filter.
+CREATE INDEX idx_homework_thread_assigned
+    ON homework_thread (series_id, assigned_grader_user_id)
+    WHERE current_status = 'submitted';
+
+-- A teacher on leave. NULL or past means present.
+ALTER TABLE math_center_teachers
+    ADD COLUMN away_until TIMESTAMPTZ;
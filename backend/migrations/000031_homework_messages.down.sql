@@ -0,0 +1,7 @@
+DELETE FROM homework_thread_event WHERE kind = 'message';
+
+ALTER TABLE homework_thread_event
+    DROP CONSTRAINT homework_thread_event_kind_check,
+    ADD CONSTRAINT homework_thread_event_kind_check
+        CHECK (kind IN ('submitted', 'claimed', 'released', 'graded', 'retracted',
+                        'appealed', 'accepted_offline', 'offline_retracted'));
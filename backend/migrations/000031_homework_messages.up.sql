@@ -0,0 +1,10 @@
+-- Clarification messages between a student and the graders on a thread
+-- ("what do you mean in line 3?"). A message is an ordinary timeline event,
+-- optionally with photos, that never touches homework_thread.current_status
+-- and is never the current attempt or grade — so every status-based count
+-- (queues, grids, stats) ignores it by construction.
+ALTER TABLE homework_thread_event
+    DROP CONSTRAINT homework_thread_event_kind_check,
+    ADD CONSTRAINT homework_thread_event_kind_check
+        CHECK (kind IN ('submitted', 'claimed', 'released', 'graded', 'retracted',
+                        'appealed', 'accepted_offline', 'offline_retracted', 'message'));
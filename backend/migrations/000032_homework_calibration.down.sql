@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS homework_calibration;
+DROP TABLE IF EXISTS homework_calibration_config;
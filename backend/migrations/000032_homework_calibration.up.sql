@@ -0,0 +1,35 @@
+-- Double-blind calibration. A center can ask for a fraction of its graded
+-- threads to be re-graded independently by a second teacher who is shown the
+-- attempt but not the first verdict. Disagreements are surfaced to the head
+-- teachers, and per-grader agreement rates over a term come out of the same
+-- table.
+CREATE TABLE homework_calibration_config
+(
+    math_center_id BIGINT      PRIMARY KEY REFERENCES math_centers (id) ON DELETE CASCADE,
+    -- Share of graded events sampled, in percent. 0 disables calibration.
+    percent        SMALLINT    NOT NULL DEFAULT 0 CHECK (percent BETWEEN 0 AND 100),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE homework_calibration
+(
+    id                    BIGSERIAL PRIMARY KEY,
+    thread_id             BIGINT      NOT NULL REFERENCES homework_thread (id) ON DELETE CASCADE,
+    -- The sampled first verdict. One calibration per graded event.
+    graded_event_id       BIGINT      NOT NULL UNIQUE REFERENCES homework_thread_event (id) ON DELETE CASCADE,
+    first_grader_user_id  BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    first_verdict         TEXT        NOT NULL CHECK (first_verdict IN ('accepted', 'rejected')),
+    second_grader_user_id BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    second_verdict        TEXT CHECK (second_verdict IS NULL OR second_verdict IN ('accepted', 'rejected')),
+    second_body           TEXT        NOT NULL DEFAULT '',
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at          TIMESTAMPTZ,
+    -- A head teacher has looked at the disagreement.
+    resolved_by_user_id   BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    resolved_at           TIMESTAMPTZ,
+    CHECK ((second_verdict IS NULL) = (completed_at IS NULL))
+);
+CREATE INDEX idx_homework_calibration_thread ON homework_calibration (thread_id);
+CREATE INDEX idx_homework_calibration_pending
+    ON homework_calibration (created_at)
+    WHERE completed_at IS NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE homework_thread
+    DROP COLUMN IF EXISTS event_chain_head;
+
+DROP INDEX IF EXISTS homework_thread_event_chain_idx;
+
+ALTER TABLE homework_thread_event
+    DROP CONSTRAINT IF EXISTS homework_thread_event_chain_check,
+    DROP COLUMN IF EXISTS hash,
+    DROP COLUMN IF EXISTS prev_hash;
@@ -0,0 +1,19 @@
+-- Tamper-evident history. Every event appended from now on stores a SHA-256
+-- over its content and the previous event's hash (computed in Go, see
+-- homework.EventHash), and the thread remembers the hash of its newest event
+-- so a deleted tail is visible too. Events that predate this migration stay
+-- unsealed (both columns NULL); a thread's chain starts at its first sealed
+-- event with an empty prev_hash.
+ALTER TABLE homework_thread_event
+    ADD COLUMN prev_hash BYTEA,
+    ADD COLUMN hash      BYTEA,
+    ADD CONSTRAINT homework_thread_event_chain_check CHECK ((prev_hash IS NULL) = (hash IS NULL));
+
+-- Two sealed events can never follow the same predecessor: a fork would mean
+-- appends raced past the thread lock.
+CREATE UNIQUE INDEX homework_thread_event_chain_idx
+    ON homework_thread_event (thread_id, prev_hash)
+    WHERE hash IS NOT NULL;
+
+ALTER TABLE homework_thread
+    ADD COLUMN event_chain_head BYTEA;
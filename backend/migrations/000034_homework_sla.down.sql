@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_homework_thread_waiting;
+DROP TABLE IF EXISTS homework_sla_digest;
+DROP TABLE IF EXISTS homework_sla_config;
+DROP TABLE IF EXISTS homework_attempt_claim;
@@ -0,0 +1,35 @@
+-- Grading turnaround. Claims are a soft lock on homework_thread, not events,
+-- so the first claim on each attempt is recorded here to measure "submitted →
+-- picked up". Only the first claim per attempt is kept; later re-claims after
+-- an expired lease do not move it.
+CREATE TABLE homework_attempt_claim
+(
+    attempt_event_id BIGINT      PRIMARY KEY REFERENCES homework_thread_event (id) ON DELETE CASCADE,
+    thread_id        BIGINT      NOT NULL REFERENCES homework_thread (id) ON DELETE CASCADE,
+    grader_user_id   BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    claimed_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_homework_attempt_claim_thread ON homework_attempt_claim (thread_id);
+
+-- Per-center grading SLA: a submission or appeal waiting longer than this is
+-- overdue. A center with no row uses the default from internal/homework.
+CREATE TABLE homework_sla_config
+(
+    math_center_id BIGINT      PRIMARY KEY REFERENCES math_centers (id) ON DELETE CASCADE,
+    hours          INTEGER     NOT NULL CHECK (hours BETWEEN 1 AND 720),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per day the overdue digest went out, so that of several replicas
+-- only the one that inserts the row sends it.
+CREATE TABLE homework_sla_digest
+(
+    day     DATE        PRIMARY KEY,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The overdue scan starts from threads waiting for a grader.
+CREATE INDEX idx_homework_thread_waiting
+    ON homework_thread (math_center_id, current_attempt_event_id)
+    WHERE current_status IN ('submitted', 'appealed');
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS homework_duplicate_flag;
+DROP TABLE IF EXISTS homework_body_digest;
+ALTER TABLE homework_thread_event_photo DROP COLUMN IF EXISTS phash;
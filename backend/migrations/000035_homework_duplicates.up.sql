@@ -0,0 +1,38 @@
+-- Copied-solution detection. Photos of student attempts get a 64-bit
+-- perceptual hash when the photo pipeline decodes them; long enough attempt
+-- texts get a digest of their normalized body. A near-identical photo or an
+-- identical text from a different student on the same subproblem is recorded
+-- as a flag for teachers. Flags are hints only: they never change a thread's
+-- status and are never shown to students.
+ALTER TABLE homework_thread_event_photo
+    ADD COLUMN phash BIGINT;
+
+CREATE TABLE homework_body_digest
+(
+    event_id BIGINT PRIMARY KEY REFERENCES homework_thread_event (id) ON DELETE CASCADE,
+    digest   BYTEA  NOT NULL
+);
+
+CREATE INDEX idx_homework_body_digest_digest ON homework_body_digest (digest);
+
+-- event_id is the later attempt, other_event_id the earlier one it matched.
+-- photo_idx / other_photo_idx are 0 for text flags; distance is the Hamming
+-- distance between the photo hashes (0 for text).
+CREATE TABLE homework_duplicate_flag
+(
+    id              BIGSERIAL   PRIMARY KEY,
+    kind            TEXT        NOT NULL CHECK (kind IN ('photo', 'text')),
+    series_id       BIGINT      NOT NULL REFERENCES math_center_series (id) ON DELETE CASCADE,
+    subproblem_id   BIGINT      NOT NULL REFERENCES math_center_subproblems (id) ON DELETE CASCADE,
+    event_id        BIGINT      NOT NULL REFERENCES homework_thread_event (id) ON DELETE CASCADE,
+    photo_idx       INTEGER     NOT NULL DEFAULT 0,
+    other_event_id  BIGINT      NOT NULL REFERENCES homework_thread_event (id) ON DELETE CASCADE,
+    other_photo_idx INTEGER     NOT NULL DEFAULT 0,
+    distance        INTEGER     NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (kind, event_id, photo_idx, other_event_id, other_photo_idx)
+);
+
+CREATE INDEX idx_homework_duplicate_flag_series ON homework_duplicate_flag (series_id);
+CREATE INDEX idx_homework_duplicate_flag_event ON homework_duplicate_flag (event_id);
+CREATE INDEX idx_homework_duplicate_flag_other ON homework_duplicate_flag (other_event_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS homework_attempt_draft;
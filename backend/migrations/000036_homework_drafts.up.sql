@@ -0,0 +1,21 @@
+-- Server-side drafts of a submission: the photos a student already uploaded
+-- and the text typed so far, so a reloaded phone app can pick up where it
+-- left off. A draft is not an event: it is outside the hash chain, the
+-- grader queue and every report, and becomes a real 'submitted' event only
+-- when the student sends it. One draft per thread; it expires untouched
+-- after a while and the housekeeping sweeper deletes it with its photos.
+CREATE TABLE homework_attempt_draft
+(
+    thread_id   BIGINT      PRIMARY KEY REFERENCES homework_thread (id) ON DELETE CASCADE,
+    -- The upload prefix the draft's photos live under; reused as the
+    -- submitted event's UUID when the draft is sent.
+    event_uuid  TEXT        NOT NULL CHECK (length(event_uuid) BETWEEN 1 AND 64),
+    body        TEXT        NOT NULL DEFAULT '',
+    object_keys TEXT[]      NOT NULL DEFAULT '{}',
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+-- The orphan-upload sweep looks drafts up by UUID; the draft sweep by age.
+CREATE INDEX idx_homework_attempt_draft_uuid ON homework_attempt_draft (event_uuid);
+CREATE INDEX idx_homework_attempt_draft_expires ON homework_attempt_draft (expires_at);
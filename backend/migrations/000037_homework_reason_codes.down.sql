@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS homework_event_reason;
+DROP TABLE IF EXISTS homework_reason_code;
@@ -0,0 +1,42 @@
+-- Reason codes. Each center keeps a small taxonomy of why an attempt was
+-- rejected or a verdict retracted ("wrong answer", "incomplete proof",
+-- "illegible photo"), so the free-text comment is no longer the only record.
+-- Codes are archived rather than deleted once events point at them.
+CREATE TABLE homework_reason_code
+(
+    id                BIGSERIAL PRIMARY KEY,
+    math_center_id    BIGINT      NOT NULL REFERENCES math_centers (id) ON DELETE CASCADE,
+    code              TEXT        NOT NULL CHECK (code ~ '^[a-z][a-z0-9_]{0,31}$'),
+    label             TEXT        NOT NULL CHECK (char_length(label) BETWEEN 1 AND 100),
+    -- Which events may carry it: rejections, retractions or both.
+    applies_to        TEXT        NOT NULL CHECK (applies_to IN ('rejection', 'retraction', 'both')),
+    -- FALSE for rejections that say nothing about the solution (an
+    -- unreadable photo): the student may resubmit past the deadline and the
+    -- rejection is not counted as a failed attempt.
+    counts_as_attempt BOOLEAN     NOT NULL DEFAULT TRUE,
+    position          INTEGER     NOT NULL DEFAULT 0,
+    archived_at       TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (math_center_id, code)
+);
+
+-- The reason given on a graded or retracted event. Kept beside the event
+-- rather than on it, like the body digest, and written in the same
+-- transaction right after the event is sealed. The hash chain does not
+-- cover it: a reason can be changed here without breaking verification.
+CREATE TABLE homework_event_reason
+(
+    event_id       BIGINT PRIMARY KEY REFERENCES homework_thread_event (id) ON DELETE CASCADE,
+    reason_code_id BIGINT NOT NULL REFERENCES homework_reason_code (id) ON DELETE RESTRICT
+);
+CREATE INDEX idx_homework_event_reason_code ON homework_event_reason (reason_code_id);
+
+-- Existing centers start from the common three.
+INSERT INTO homework_reason_code (math_center_id, code, label, applies_to, counts_as_attempt, position)
+SELECT c.id, d.code, d.label, d.applies_to, d.counts_as_attempt, d.position
+FROM math_centers c
+         CROSS JOIN (VALUES ('wrong_answer', 'Неверный ответ', 'both', TRUE, 1),
+                            ('incomplete_proof', 'Неполное доказательство', 'both', TRUE, 2),
+                            ('illegible_photo', 'Нечитаемое фото', 'rejection', FALSE, 3))
+    AS d (code, label, applies_to, counts_as_attempt, position);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_homework_thread_student_center;
+DROP TABLE IF EXISTS homework_storage_quota;
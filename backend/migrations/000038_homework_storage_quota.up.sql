@@ -0,0 +1,13 @@
+-- Per-center cap on the photo bytes one student keeps in object storage.
+-- Usage is not stored: it is summed from homework_thread_event_photo over
+-- the events the student authored, so grader attachments never count. A
+-- center with no row uses the default from internal/homework.
+CREATE TABLE homework_storage_quota
+(
+    math_center_id    BIGINT      PRIMARY KEY REFERENCES math_centers (id) ON DELETE CASCADE,
+    bytes_per_student BIGINT      NOT NULL CHECK (bytes_per_student BETWEEN 52428800 AND 107374182400),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The quota check sums one student's photos within a center.
+CREATE INDEX idx_homework_thread_student_center ON homework_thread (student_user_id, math_center_id);
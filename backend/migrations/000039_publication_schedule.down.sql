@@ -0,0 +1 @@
+DROP TABLE IF EXISTS math_center_publication_schedule;
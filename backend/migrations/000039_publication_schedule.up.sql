@@ -0,0 +1,47 @@
+-- Scheduled publication of series statements, разбор groups and likbez
+-- entries. A background worker on every replica picks due rows with
+-- SKIP LOCKED and runs the same transition as the manual publish button in
+-- the same transaction that marks the row done, so a crash mid-way leaves
+-- the row pending for the next round.
+CREATE TABLE math_center_publication_schedule
+(
+    id                 BIGSERIAL PRIMARY KEY,
+    math_center_id     BIGINT      NOT NULL REFERENCES math_centers (id) ON DELETE CASCADE,
+    target_kind        TEXT        NOT NULL CHECK (target_kind IN ('series', 'solutions', 'likbez')),
+    -- The series itself for 'series'; the series the subproblems belong to
+    -- for 'solutions', so that at_series_due follows later due_at edits.
+    series_id          BIGINT      REFERENCES math_center_series (id) ON DELETE CASCADE,
+    likbez_id          BIGINT      REFERENCES math_center_likbez (id) ON DELETE CASCADE,
+    -- One shared разбор: published together, like one manual publish call.
+    subproblem_ids     BIGINT[]    NOT NULL DEFAULT '{}',
+    -- Exactly one of a fixed time or "when the series is due" (solutions only).
+    publish_at         TIMESTAMPTZ,
+    at_series_due      BOOLEAN     NOT NULL DEFAULT FALSE,
+    status             TEXT        NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'done', 'failed', 'cancelled')),
+    attempts           INTEGER     NOT NULL DEFAULT 0,
+    last_error         TEXT        NOT NULL DEFAULT '',
+    created_by_user_id BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at        TIMESTAMPTZ,
+    CHECK ((publish_at IS NULL) = at_series_due),
+    CHECK (NOT at_series_due OR target_kind = 'solutions'),
+    CHECK (
+        (target_kind = 'series' AND series_id IS NOT NULL AND likbez_id IS NULL AND cardinality(subproblem_ids) = 0)
+        OR (target_kind = 'solutions' AND series_id IS NOT NULL AND likbez_id IS NULL AND cardinality(subproblem_ids) > 0)
+        OR (target_kind = 'likbez' AND likbez_id IS NOT NULL AND series_id IS NULL AND cardinality(subproblem_ids) = 0)
+    )
+);
+
+-- At most one pending schedule per series statement and per likbez entry.
+CREATE UNIQUE INDEX idx_publication_schedule_series_pending
+    ON math_center_publication_schedule (series_id)
+    WHERE status = 'pending' AND target_kind = 'series';
+CREATE UNIQUE INDEX idx_publication_schedule_likbez_pending
+    ON math_center_publication_schedule (likbez_id)
+    WHERE status = 'pending';
+CREATE INDEX idx_publication_schedule_pending
+    ON math_center_publication_schedule (publish_at)
+    WHERE status = 'pending';
+CREATE INDEX idx_publication_schedule_center
+    ON math_center_publication_schedule (math_center_id, created_at DESC);
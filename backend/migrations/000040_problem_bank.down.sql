@@ -0,0 +1,2 @@
+ALTER TABLE math_center_problems DROP COLUMN IF EXISTS bank_problem_id;
+DROP TABLE IF EXISTS math_center_bank_problems;
@@ -0,0 +1,33 @@
+-- Reusable problem bank. A bank problem carries what a series row never had:
+-- the TeX statement and solution, topic tags, a teacher-assigned difficulty
+-- and the source it came from. math_center_id scopes it to one center; NULL
+-- makes it school-wide, visible to the teachers of every center.
+CREATE TABLE math_center_bank_problems
+(
+    id                 BIGSERIAL PRIMARY KEY,
+    math_center_id     BIGINT      REFERENCES math_centers (id) ON DELETE CASCADE,
+    title              TEXT        NOT NULL,
+    statement_tex      TEXT        NOT NULL,
+    solution_tex       TEXT,
+    tags               TEXT[]      NOT NULL DEFAULT '{}',
+    -- 1 (warm-up) .. 5 (olympiad final); NULL when nobody rated it yet.
+    difficulty         SMALLINT    CHECK (difficulty BETWEEN 1 AND 5),
+    source             TEXT        NOT NULL DEFAULT '',
+    -- Parts a series gets when the problem is placed into it; 0 = one part.
+    subproblem_count   INTEGER     NOT NULL DEFAULT 0 CHECK (subproblem_count BETWEEN 0 AND 26),
+    created_by_user_id BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- Archived problems leave the bank listing but keep their series links,
+    -- so the solve-rate history of past series stays attached.
+    archived_at        TIMESTAMPTZ
+);
+CREATE INDEX idx_mc_bank_problems_center ON math_center_bank_problems (math_center_id);
+CREATE INDEX idx_mc_bank_problems_tags ON math_center_bank_problems USING GIN (tags);
+
+-- A series problem placed from the bank remembers where it came from; the
+-- bank's solve rate is aggregated over these links.
+ALTER TABLE math_center_problems
+    ADD COLUMN bank_problem_id BIGINT REFERENCES math_center_bank_problems (id) ON DELETE SET NULL;
+CREATE INDEX idx_math_center_problems_bank ON math_center_problems (bank_problem_id)
+    WHERE bank_problem_id IS NOT NULL;
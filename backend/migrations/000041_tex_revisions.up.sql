@@ -0,0 +1,50 @@
+-- Revision history for the TeX sources that used to be overwritten in place:
+-- a series statement, a subproblem's разбор and the center LaTeX preamble.
+-- Every save appends a row; restoring an old revision appends a copy of it.
+-- body NULL records that the source was cleared.
+CREATE TABLE math_center_tex_revisions
+(
+    id                     BIGSERIAL PRIMARY KEY,
+    math_center_id         BIGINT      NOT NULL REFERENCES math_centers (id) ON DELETE CASCADE,
+    target_kind            TEXT        NOT NULL CHECK (target_kind IN ('series', 'solution', 'preamble')),
+    series_id              BIGINT      REFERENCES math_center_series (id) ON DELETE CASCADE,
+    subproblem_id          BIGINT      REFERENCES math_center_subproblems (id) ON DELETE CASCADE,
+    -- 1, 2, … per target; what the history and diff endpoints address.
+    revision               INTEGER     NOT NULL,
+    body                   TEXT,
+    author_user_id         BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    restored_from_revision INTEGER,
+    created_at             TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- When students were first shown this revision: set at publication, or
+    -- at save time when the target was already published.
+    published_at           TIMESTAMPTZ,
+    CHECK (
+        (target_kind = 'series' AND series_id IS NOT NULL AND subproblem_id IS NULL)
+            OR (target_kind = 'solution' AND subproblem_id IS NOT NULL AND series_id IS NULL)
+            OR (target_kind = 'preamble' AND series_id IS NULL AND subproblem_id IS NULL)
+        )
+);
+CREATE UNIQUE INDEX uq_mc_tex_revisions_series
+    ON math_center_tex_revisions (series_id, revision) WHERE target_kind = 'series';
+CREATE UNIQUE INDEX uq_mc_tex_revisions_solution
+    ON math_center_tex_revisions (subproblem_id, revision) WHERE target_kind = 'solution';
+CREATE UNIQUE INDEX uq_mc_tex_revisions_preamble
+    ON math_center_tex_revisions (math_center_id, revision) WHERE target_kind = 'preamble';
+
+-- The current sources become revision 1, with no author.
+INSERT INTO math_center_tex_revisions (math_center_id, target_kind, series_id, revision, body, created_at, published_at)
+SELECT math_center_id, 'series', id, 1, tex_source, created_at, published_at
+FROM math_center_series
+WHERE tex_source IS NOT NULL;
+
+INSERT INTO math_center_tex_revisions (math_center_id, target_kind, subproblem_id, revision, body, created_at, published_at)
+SELECT s.math_center_id, 'solution', sol.subproblem_id, 1, sol.solution_tex_source, sol.updated_at, sol.published_at
+FROM math_center_subproblem_solutions sol
+         JOIN math_center_subproblems sp ON sp.id = sol.subproblem_id
+         JOIN math_center_problems p ON p.id = sp.problem_id
+         JOIN math_center_series s ON s.id = p.series_id
+WHERE sol.solution_tex_source IS NOT NULL;
+
+INSERT INTO math_center_tex_revisions (math_center_id, target_kind, revision, body, created_at, published_at)
+SELECT math_center_id, 'preamble', 1, preamble, updated_at, updated_at
+FROM math_center_latex_settings;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS math_center_tex_assets;
@@ -0,0 +1,31 @@
+-- Images a TeX source references with \includegraphics{name}: one set per
+-- series statement, likbez or subproblem разбор. The bytes live in object
+-- storage under a key derived from the owner and the name, so re-uploading a
+-- name replaces the picture without touching the source.
+CREATE TABLE math_center_tex_assets
+(
+    id                  BIGSERIAL PRIMARY KEY,
+    math_center_id      BIGINT      NOT NULL REFERENCES math_centers (id) ON DELETE CASCADE,
+    target_kind         TEXT        NOT NULL CHECK (target_kind IN ('series', 'likbez', 'solution')),
+    series_id           BIGINT      REFERENCES math_center_series (id) ON DELETE CASCADE,
+    likbez_id           BIGINT      REFERENCES math_center_likbez (id) ON DELETE CASCADE,
+    subproblem_id       BIGINT      REFERENCES math_center_subproblems (id) ON DELETE CASCADE,
+    name                TEXT        NOT NULL CHECK (name ~ '^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$'),
+    object_key          TEXT        NOT NULL UNIQUE,
+    content_type        TEXT        NOT NULL CHECK (content_type IN ('image/png', 'image/jpeg', 'image/svg+xml')),
+    size_bytes          BIGINT      NOT NULL CHECK (size_bytes > 0),
+    uploaded_by_user_id BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (
+        (target_kind = 'series' AND series_id IS NOT NULL AND likbez_id IS NULL AND subproblem_id IS NULL)
+            OR (target_kind = 'likbez' AND likbez_id IS NOT NULL AND series_id IS NULL AND subproblem_id IS NULL)
+            OR (target_kind = 'solution' AND subproblem_id IS NOT NULL AND series_id IS NULL AND likbez_id IS NULL)
+        )
+);
+CREATE UNIQUE INDEX uq_mc_tex_assets_series
+    ON math_center_tex_assets (series_id, name) WHERE target_kind = 'series';
+CREATE UNIQUE INDEX uq_mc_tex_assets_likbez
+    ON math_center_tex_assets (likbez_id, name) WHERE target_kind = 'likbez';
+CREATE UNIQUE INDEX uq_mc_tex_assets_solution
+    ON math_center_tex_assets (subproblem_id, name) WHERE target_kind = 'solution';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS math_center_lesson_attendance;
+DROP TABLE IF EXISTS math_center_lesson_groups;
+DROP TABLE IF EXISTS math_center_lessons;
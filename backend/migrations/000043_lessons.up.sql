@@ -0,0 +1,54 @@
+-- Lessons are the center's timetable: a date and wall-clock time (center
+-- local, like likbez held_on), one or more groups of the same term, a topic
+-- and optionally the series or likbez the lesson is about.
+CREATE TABLE math_center_lessons
+(
+    id                 BIGSERIAL PRIMARY KEY,
+    math_center_id     BIGINT      NOT NULL,
+    term_id            BIGINT      NOT NULL,
+    held_on            DATE        NOT NULL,
+    starts_at          TIME        NOT NULL,
+    ends_at            TIME        NOT NULL,
+    topic              TEXT        NOT NULL DEFAULT '',
+    series_id          BIGINT      REFERENCES math_center_series (id) ON DELETE SET NULL,
+    likbez_id          BIGINT      REFERENCES math_center_likbez (id) ON DELETE SET NULL,
+    created_by_user_id BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_math_center_lessons_term_center
+        FOREIGN KEY (term_id, math_center_id)
+            REFERENCES math_center_terms (id, math_center_id)
+            ON DELETE CASCADE,
+    CHECK (ends_at > starts_at)
+);
+CREATE INDEX idx_math_center_lessons_term ON math_center_lessons (term_id, held_on, starts_at);
+
+-- The composite key keeps every group of a lesson inside the lesson's term.
+CREATE TABLE math_center_lesson_groups
+(
+    lesson_id BIGINT NOT NULL REFERENCES math_center_lessons (id) ON DELETE CASCADE,
+    group_id  BIGINT NOT NULL,
+    term_id   BIGINT NOT NULL,
+    PRIMARY KEY (lesson_id, group_id),
+    CONSTRAINT fk_math_center_lesson_groups_group_term
+        FOREIGN KEY (group_id, term_id)
+            REFERENCES math_center_groups (id, term_id)
+            ON DELETE CASCADE
+);
+CREATE INDEX idx_math_center_lesson_groups_group ON math_center_lesson_groups (group_id);
+
+-- One mark per student and lesson. A row imported from a conduit tab keeps
+-- its cell, like imported homework events; a teacher's mark always wins and
+-- an import never overwrites an existing row.
+CREATE TABLE math_center_lesson_attendance
+(
+    lesson_id            BIGINT      NOT NULL REFERENCES math_center_lessons (id) ON DELETE CASCADE,
+    student_user_id      BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status               TEXT        NOT NULL CHECK (status IN ('present', 'late', 'absent', 'excused')),
+    marked_by_user_id    BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    google_sheet_link_id BIGINT      REFERENCES math_center_google_sheet_links (id) ON DELETE SET NULL,
+    google_sheet_cell    TEXT        NOT NULL DEFAULT '',
+    marked_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (lesson_id, student_user_id)
+);
+CREATE INDEX idx_math_center_lesson_attendance_student ON math_center_lesson_attendance (student_user_id);
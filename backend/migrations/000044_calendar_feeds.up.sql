@@ -0,0 +1,10 @@
+-- Secret-URL iCalendar feeds. Calendar clients poll the feed without a
+-- session, so the URL itself is the credential: only its SHA-256 is kept,
+-- like refresh tokens, and rotating replaces the row (the old URL stops
+-- working). One feed per user; the teacher feed shares its token.
+CREATE TABLE calendar_feeds
+(
+    user_id    BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    token_hash BYTEA UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
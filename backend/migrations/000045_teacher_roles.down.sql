@@ -0,0 +1,9 @@
+-- Without term_id a term-scoped teacher would become a teacher of every
+-- term, so their memberships are removed rather than widened.
+DELETE FROM math_center_teachers WHERE term_id IS NOT NULL;
+
+ALTER TABLE math_center_teachers
+    DROP CONSTRAINT IF EXISTS chk_math_center_teachers_head_unscoped,
+    DROP CONSTRAINT IF EXISTS fk_math_center_teachers_term_center,
+    DROP COLUMN IF EXISTS term_id,
+    DROP COLUMN IF EXISTS role;
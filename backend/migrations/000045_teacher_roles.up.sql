@@ -0,0 +1,24 @@
+-- Teacher roles below head teacher. is_head_teacher stays the head flag (a
+-- head can do everything); role narrows what any other teacher may do:
+--   teacher  — grade, edit content and manage the roster (the old default);
+--   grader   — the grading queue and threads only;
+--   editor   — series, likbez and solutions only;
+--   observer — read-only.
+-- term_id scopes a teacher (typically a camp-only one) to a single term:
+-- they count as a teacher only while that term is active, and only for its
+-- series.
+--
+-- Deleting a term does not touch these memberships: the foreign key has no
+-- ON DELETE action, so the delete fails while a teacher is still scoped to
+-- the term. Neither cascading (silently drops the membership) nor SET NULL
+-- (silently widens it to every term) is right; a head removes or rescopes
+-- those teachers first.
+ALTER TABLE math_center_teachers
+    ADD COLUMN role    TEXT   NOT NULL DEFAULT 'teacher'
+        CHECK (role IN ('teacher', 'grader', 'editor', 'observer')),
+    ADD COLUMN term_id BIGINT,
+    ADD CONSTRAINT fk_math_center_teachers_term_center
+        FOREIGN KEY (term_id, math_center_id)
+            REFERENCES math_center_terms (id, math_center_id),
+    ADD CONSTRAINT chk_math_center_teachers_head_unscoped
+        CHECK (NOT (is_head_teacher AND term_id IS NOT NULL));
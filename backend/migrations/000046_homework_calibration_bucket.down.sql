@@ -0,0 +1 @@
+ALTER TABLE homework_thread DROP COLUMN IF EXISTS calibration_bucket;
@@ -0,0 +1,8 @@
+-- The calibration draw used to hash the client-chosen event UUID, so a grader
+-- could retry UUIDs until a verdict fell outside the sample. The bucket is now
+-- minted by the database once per thread and stored; a retract and regrade
+-- draws the same bucket again.
+ALTER TABLE homework_thread
+    ADD COLUMN calibration_bucket SMALLINT NOT NULL
+        DEFAULT floor(random() * 100)::smallint
+        CHECK (calibration_bucket BETWEEN 0 AND 99);
@@ -5,6 +5,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -24,6 +26,7 @@ type MemoryStore struct {
 type memoryObject struct {
 	body        []byte
 	contentType string
+	modified    time.Time
 }
 
 var _ Store = (*MemoryStore)(nil)
@@ -42,7 +45,7 @@ func (m *MemoryStore) Put(_ context.Context, key string, body io.Reader, _ int64
 		return fmt.Errorf("memory put: %w", err)
 	}
 	m.mu.Lock()
-	m.objects[key] = memoryObject{body: buf, contentType: contentType}
+	m.objects[key] = memoryObject{body: buf, contentType: contentType, modified: time.Now()}
 	m.mu.Unlock()
 	return nil
 }
@@ -95,6 +98,49 @@ func (m *MemoryStore) Exists(_ context.Context, key string) (bool, error) {
 	return ok, nil
 }
 
+// Open returns a reader over a copy of the stored body, so a caller that
+// holds the reader open never observes a concurrent Put.
+func (m *MemoryStore) Open(_ context.Context, key string) (io.ReadCloser, string, error) {
+	m.mu.RLock()
+	obj, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, "", ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(bytes.Clone(obj.body))), obj.contentType, nil
+}
+
+// List walks a snapshot of the matching keys, so fn may call Put or Delete on
+// the same store without deadlocking.
+func (m *MemoryStore) List(_ context.Context, prefix string, fn func(ObjectInfo) error) error {
+	m.mu.RLock()
+	infos := make([]ObjectInfo, 0)
+	for key, obj := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, ObjectInfo{Key: key, Size: int64(len(obj.body)), LastModified: obj.modified})
+		}
+	}
+	m.mu.RUnlock()
+	slices.SortFunc(infos, func(a, b ObjectInfo) int { return strings.Compare(a.Key, b.Key) })
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetModTime is a test convenience that backdates an object so age-based
+// sweeps can be exercised without sleeping. No-op for a missing key.
+func (m *MemoryStore) SetModTime(key string, at time.Time) {
+	m.mu.Lock()
+	if obj, ok := m.objects[key]; ok {
+		obj.modified = at
+		m.objects[key] = obj
+	}
+	m.mu.Unlock()
+}
+
 // Get is a test convenience that returns the stored body. Not part of the
 // Store interface because production code never reads through the server.
 func (m *MemoryStore) Get(key string) (io.Reader, string, bool) {
@@ -139,3 +139,94 @@ func TestMemoryStore_StatMissing(t *testing.T) {
 		t.Errorf("Stat missing: got %v, want ErrNotFound", err)
 	}
 }
+
+func TestMemoryStore_Open(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := objectstore.NewMemory()
+	if err := store.Put(ctx, "k", strings.NewReader("pixels"), 6, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	rc, ct, err := store.Open(ctx, "k")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = rc.Close() }()
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(got) != "pixels" || ct != "image/jpeg" {
+		t.Errorf("Open: got (%q, %q), want (pixels, image/jpeg)", got, ct)
+	}
+
+	if _, _, err := store.Open(ctx, "missing"); !errors.Is(err, objectstore.ErrNotFound) {
+		t.Errorf("Open missing: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStore_List(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := objectstore.NewMemory()
+	for _, k := range []string{"a/2", "a/1", "b/1"} {
+		if err := store.Put(ctx, k, strings.NewReader("xy"), 2, "text/plain"); err != nil {
+			t.Fatalf("Put %s: %v", k, err)
+		}
+	}
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.SetModTime("a/1", old)
+
+	var got []objectstore.ObjectInfo
+	err := store.List(ctx, "a/", func(info objectstore.ObjectInfo) error {
+		got = append(got, info)
+		// Deleting from inside the callback must not deadlock.
+		return store.Delete(ctx, info.Key)
+	})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != 2 || got[0].Key != "a/1" || got[1].Key != "a/2" {
+		t.Fatalf("List: got %+v, want a/1, a/2 in order", got)
+	}
+	if got[0].Size != 2 || !got[0].LastModified.Equal(old) {
+		t.Errorf("List: got %+v, want size 2 modified %v", got[0], old)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = store.List(ctx, "", func(objectstore.ObjectInfo) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("List: got (%v, %d calls), want stop after 1 call", err, calls)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := objectstore.NewMemory()
+	if err := store.Put(ctx, "src.pdf", strings.NewReader("%PDF"), 4, "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := objectstore.Copy(ctx, store, "src.pdf", "dst.pdf"); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	r, ct, ok := store.Get("dst.pdf")
+	if !ok {
+		t.Fatal("Get: want the copy")
+	}
+	got, _ := io.ReadAll(r)
+	if string(got) != "%PDF" || ct != "application/pdf" {
+		t.Errorf("copy: got (%q, %q), want (%%PDF, application/pdf)", got, ct)
+	}
+	if _, _, ok := store.Get("src.pdf"); !ok {
+		t.Error("Copy must keep the source")
+	}
+
+	if err := objectstore.Copy(ctx, store, "missing", "dst2"); !errors.Is(err, objectstore.ErrNotFound) {
+		t.Errorf("Copy missing: got %v, want ErrNotFound", err)
+	}
+}
@@ -54,4 +54,41 @@ type Store interface {
 
 	// Exists reports whether the key is present.
 	Exists(ctx context.Context, key string) (bool, error)
+
+	// Open streams an existing object's bytes back to the server along with
+	// its Content-Type, or returns ErrNotFound. Only background processors
+	// read through the server (the photo pipeline re-encodes uploads);
+	// request handlers keep redirecting clients to presigned URLs.
+	Open(ctx context.Context, key string) (io.ReadCloser, string, error)
+
+	// List calls fn for every object whose key starts with prefix, in key
+	// order. Listing stops at the first error fn returns, which List passes
+	// back unchanged. Used by maintenance sweeps that garbage-collect objects
+	// no database row points at; keep prefixes narrow, a bucket can be big.
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+}
+
+// ObjectInfo is what List reports per object.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Copy duplicates the object at srcKey under dstKey, keeping its
+// Content-Type. It streams through the server rather than asking the bucket
+// for a server-side copy, so it works on every Store; use it for objects of
+// bounded size (series and разбор PDFs), not for bulk moves. Returns
+// ErrNotFound if srcKey is absent.
+func Copy(ctx context.Context, s Store, srcKey, dstKey string) error {
+	size, _, err := s.Stat(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	body, contentType, err := s.Open(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = body.Close() }()
+	return s.Put(ctx, dstKey, body, size, contentType)
 }
@@ -190,6 +190,50 @@ func (s *S3Store) Exists(ctx context.Context, key string) (bool, error) {
 	return false, fmt.Errorf("s3 head %q: %w", key, err)
 }
 
+// Open GETs the object body. The caller owns the returned reader and must
+// Close it so the underlying HTTP connection returns to the pool.
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", fmt.Errorf("s3 get %q: %w", key, err)
+	}
+	var ct string
+	if out.ContentType != nil {
+		ct = *out.ContentType
+	}
+	return out.Body, ct, nil
+}
+
+// List pages through ListObjectsV2 (1000 keys per request, the S3 maximum).
+func (s *S3Store) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("s3 list %q: %w", prefix, err)
+		}
+		for _, obj := range page.Contents {
+			info := ObjectInfo{Key: aws.ToString(obj.Key), Size: aws.ToInt64(obj.Size)}
+			if obj.LastModified != nil {
+				info.LastModified = *obj.LastModified
+			}
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // isNotFound recognises both the typed NoSuchKey response and the generic
 // 404 that HeadObject returns (it doesn't surface NoSuchKey by design).
 func isNotFound(err error) bool {
@@ -0,0 +1,203 @@
+// Package xlsx writes a single-sheet Office Open XML workbook as a stream.
+// Rows go straight into the zip entry for the sheet, so memory stays flat no
+// matter how many rows are written. Cells are inline strings or numbers —
+// there is no shared-strings table, which would need every string up front.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Style indices into the fixed styles.xml below.
+const (
+	styleDefault = 0
+	styleBold    = 1
+)
+
+// Writer streams rows into one worksheet. Call Close to finish the archive;
+// the output is not a valid workbook before that.
+type Writer struct {
+	zw     *zip.Writer
+	sheet  *bufio.Writer
+	row    int
+	closed bool
+}
+
+// NewWriter starts a workbook whose only sheet is called sheetName. The
+// first frozenRows rows stay in view when scrolling, and so do the first
+// frozenCols columns.
+func NewWriter(w io.Writer, sheetName string, frozenRows, frozenCols int) (*Writer, error) {
+	zw := zip.NewWriter(w)
+	for _, f := range []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, escape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+	} {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return nil, err
+		}
+	}
+	fw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &Writer{zw: zw, sheet: bufio.NewWriter(fw)}
+	x.put(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	x.put(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if frozenRows > 0 || frozenCols > 0 {
+		x.put(`<sheetViews><sheetView workbookViewId="0"><pane`)
+		if frozenCols > 0 {
+			x.put(` xSplit="` + strconv.Itoa(frozenCols) + `"`)
+		}
+		if frozenRows > 0 {
+			x.put(` ySplit="` + strconv.Itoa(frozenRows) + `"`)
+		}
+		x.put(` topLeftCell="` + CellRef(frozenRows, frozenCols) + `" state="frozen"/></sheetView></sheetViews>`)
+	}
+	x.put(`<sheetData>`)
+	return x, x.err()
+}
+
+// WriteRow appends one row. Values may be string (empty strings leave the
+// cell blank), any integer type, or float64.
+func (x *Writer) WriteRow(values ...any) error {
+	return x.writeRow(styleDefault, values)
+}
+
+// WriteHeader appends one row in bold.
+func (x *Writer) WriteHeader(values ...any) error {
+	return x.writeRow(styleBold, values)
+}
+
+func (x *Writer) writeRow(style int, values []any) error {
+	if x.closed {
+		return errors.New("xlsx: write after close")
+	}
+	// The row is built whole first so a rejected value leaves the sheet
+	// well-formed.
+	r := x.row
+	var b strings.Builder
+	b.WriteString(`<row r="` + strconv.Itoa(r+1) + `">`)
+	s := ""
+	if style != styleDefault {
+		s = ` s="` + strconv.Itoa(style) + `"`
+	}
+	for c, v := range values {
+		ref := CellRef(r, c)
+		switch v := v.(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+			b.WriteString(`<c r="` + ref + `"` + s + ` t="inlineStr"><is><t xml:space="preserve">` + escape(v) + `</t></is></c>`)
+		case int:
+			b.WriteString(`<c r="` + ref + `"` + s + `><v>` + strconv.Itoa(v) + `</v></c>`)
+		case int32:
+			b.WriteString(`<c r="` + ref + `"` + s + `><v>` + strconv.FormatInt(int64(v), 10) + `</v></c>`)
+		case int64:
+			b.WriteString(`<c r="` + ref + `"` + s + `><v>` + strconv.FormatInt(v, 10) + `</v></c>`)
+		case float64:
+			b.WriteString(`<c r="` + ref + `"` + s + `><v>` + strconv.FormatFloat(v, 'g', -1, 64) + `</v></c>`)
+		default:
+			return fmt.Errorf("xlsx: unsupported cell type %T", v)
+		}
+	}
+	b.WriteString(`</row>`)
+	x.row++
+	x.put(b.String())
+	return x.err()
+}
+
+// Close finishes the sheet and the archive. It does not close the
+// underlying writer.
+func (x *Writer) Close() error {
+	if x.closed {
+		return nil
+	}
+	x.closed = true
+	x.put(`</sheetData></worksheet>`)
+	if err := x.err(); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// CellRef renders a zero-based (row, col) as an A1 reference.
+func CellRef(row, col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name + strconv.Itoa(row+1)
+}
+
+// put buffers s; the first write error sticks in the bufio.Writer and is
+// reported by err.
+func (x *Writer) put(s string) {
+	_, _ = x.sheet.WriteString(s)
+}
+
+func (x *Writer) err() error {
+	// A zero-length write surfaces a sticky error without writing anything.
+	_, err := x.sheet.Write(nil)
+	return err
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+const rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+// Two cell formats: 0 is the default, 1 is bold (headers).
+const stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
@@ -0,0 +1,94 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/Alarion239/my239/backend/pkg/xlsx"
+)
+
+func TestCellRef(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		row, col int
+		want     string
+	}{
+		{0, 0, "A1"}, {1, 25, "Z2"}, {2, 26, "AA3"}, {9, 701, "ZZ10"}, {0, 702, "AAA1"},
+	} {
+		if got := xlsx.CellRef(tc.row, tc.col); got != tc.want {
+			t.Errorf("CellRef(%d, %d) = %q, want %q", tc.row, tc.col, got, tc.want)
+		}
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	w, err := xlsx.NewWriter(&buf, "Лист <1>", 1, 1)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteHeader("Имя", "Σ"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow("a & <b>", 3, "", int64(4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow(struct{}{}); err == nil {
+		t.Error("unsupported cell type was accepted")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		_ = rc.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Style  int    `xml:"s,attr"`
+				Inline string `xml:"is>t"`
+				Value  string `xml:"v"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("sheet xml: %v", err)
+	}
+	if len(sheet.Rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(sheet.Rows))
+	}
+	h := sheet.Rows[0].Cells
+	if len(h) != 2 || h[0].Inline != "Имя" || h[0].Style != 1 {
+		t.Errorf("header = %+v", h)
+	}
+	c := sheet.Rows[1].Cells
+	if len(c) != 3 || c[0].Inline != "a & <b>" || c[1].Value != "3" || c[2].Ref != "D2" || c[2].Value != "4" {
+		t.Errorf("row 2 = %+v", c)
+	}
+	if !bytes.Contains(files["xl/workbook.xml"], []byte(`name="Лист &lt;1&gt;"`)) {
+		t.Errorf("sheet name not escaped: %s", files["xl/workbook.xml"])
+	}
+}
@@ -207,7 +207,8 @@ ORDER BY t.current_status ASC,
          t.updated_at ASC;
 
 -- name: GraderStatsForCenter :one
--- {pending, my_claimed, my_appeals} for the grader dashboard.
+-- {pending, my_claimed, my_appeals} for the grader dashboard. A non-null
+-- term_id counts only that term's series.
 SELECT
     COUNT(*) FILTER (
         WHERE current_status IN ('submitted','appealed')
@@ -223,7 +224,9 @@ SELECT
           AND last_grader_user_id = @caller_user_id::bigint
     )::bigint AS my_appeals_count
 FROM homework_thread
-WHERE math_center_id = $1;
+WHERE math_center_id = $1
+  AND (sqlc.narg('term_id')::bigint IS NULL
+       OR series_id IN (SELECT id FROM math_center_series WHERE term_id = sqlc.narg('term_id')::bigint));
 
 -- name: StudentSeriesRollup :many
 -- Per-subproblem status grid for one student in one series. The LEFT JOIN
@@ -431,7 +434,9 @@ SELECT sp.id            AS subproblem_id,
        -- Coffin state, so the submit handler can keep a coffin open past the
        -- series deadline until its solution is released. Per-subproblem now.
        COALESCE(ss.is_coffin, false)::boolean AS is_coffin,
-       ss.released_at                         AS coffin_released_at
+       ss.released_at                         AS coffin_released_at,
+       -- When the разбор went out; a late resubmission closes for good then.
+       ss.published_at                        AS solution_published_at
 FROM math_center_subproblems sp
          JOIN math_center_problems p ON p.id = sp.problem_id
          JOIN math_center_series   s ON s.id = p.series_id
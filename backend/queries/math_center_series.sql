@@ -174,11 +174,14 @@ WHERE p.series_id = $1
 ORDER BY p.number ASC, s.label ASC;
 
 -- name: IsTeacherInCenter :one
+-- Term-scoped assistants teach only while their term is active.
 SELECT EXISTS (
     SELECT 1
     FROM math_center_teachers
     WHERE user_id = $1
       AND math_center_id = $2
+      AND (term_id IS NULL
+           OR term_id IN (SELECT id FROM math_center_terms WHERE is_active))
 ) AS is_teacher;
 
 -- name: IsStudentInCenter :one
@@ -214,7 +214,8 @@ GROUP BY ss.subproblem_id;
 -- name: ListCoffinQueueForCenter :many
 -- Center-wide grading queue for coffins: submissions/appeals on coffin
 -- subproblems that aren't locked by another grader. Mirrors the per-series
--- grader queue but spans every series and is filtered to coffins.
+-- grader queue but spans every series and is filtered to coffins. A non-null
+-- term_id keeps only that term's series.
 SELECT t.id                   AS thread_id,
        t.student_user_id      AS student_user_id,
        t.subproblem_id        AS subproblem_id,
@@ -239,6 +240,8 @@ WHERE t.math_center_id = $1
   AND (t.claim_holder_user_id IS NULL
        OR t.claim_expires_at < NOW()
        OR t.claim_holder_user_id = @caller_user_id::bigint)
+  AND (sqlc.narg('term_id')::bigint IS NULL
+       OR t.series_id IN (SELECT id FROM math_center_series WHERE term_id = sqlc.narg('term_id')::bigint))
 ORDER BY t.current_status ASC, t.updated_at ASC;
 
 -- name: CreateSolutionGroup :one
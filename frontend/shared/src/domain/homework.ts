@@ -219,6 +219,8 @@ export function eventKindLabel(
       return 'Принято очно'
     case 'offline_retracted':
       return 'Очный зачёт отменён'
+    case 'message':
+      return 'Сообщение'
   }
 }
 
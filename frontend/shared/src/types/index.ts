@@ -635,6 +635,8 @@ export type EventKind =
   // In-person «кондуит» grading: accepted offline / that accept undone.
   | 'accepted_offline'
   | 'offline_retracted'
+  // Clarification between student and grader; never changes status.
+  | 'message'
 
 // PhotoView is one image attached to an event. `url` is a short-TTL presigned
 // GET; `object_key` is exposed too so the UI can match images back to events.
@@ -644,6 +646,9 @@ export interface PhotoView {
   url: string
   content_type: string
   size_bytes: number
+  // Small rendition for lists; equals url until the photo pipeline has run.
+  thumb_url: string
+  processing_status: 'pending' | 'done' | 'failed' | 'unsupported'
 }
 
 // EventView mirrors one homework_thread_event row plus its photos.
@@ -809,6 +814,68 @@ export interface QueueItem {
   claim_expires_at?: string | null
   updated_at: string
 	background_hex?: string | null
+  // Set only in series with an assignment mode.
+  assigned_grader_user_id?: number | null
+}
+
+// GradingAssignmentMode selects how new submissions of a series are routed
+// to graders. 'off' keeps the shared queue.
+export type GradingAssignmentMode = 'off' | 'round_robin' | 'by_problem'
+
+// SeriesGradingConfig is GET/PUT /homework/series/{id}/grading.
+export interface SeriesGradingConfig {
+  mode: GradingAssignmentMode
+  reassign_on_absence: boolean
+  pool: number[]
+  problem_owners: { problem_id: number; grader_user_id: number }[]
+}
+
+// CalibrationItem is one entry of the blind second-grading queue
+// (GET /homework/centers/{id}/calibration/queue). No first verdict here.
+export interface CalibrationItem {
+  id: number
+  thread_id: number
+  series_id: number
+  subproblem_id: number
+  student_user_id: number
+  created_at: string
+}
+
+// CalibrationResult is a completed pair: the grade response and the head
+// teachers' disagreements list.
+export interface CalibrationResult extends CalibrationItem {
+  first_grader_user_id: number
+  first_verdict: Verdict
+  second_grader_user_id?: number
+  second_verdict?: Verdict
+  second_body: string
+  agreed: boolean
+  completed_at?: string
+  resolved_by_user_id?: number
+  resolved_at?: string
+}
+
+// BlindCalibrationView is GET /homework/calibration/{id}: the thread as it
+// stood before the sampled verdict.
+export interface BlindCalibrationView {
+  calibration_id: number
+  completed: boolean
+  thread: ThreadView
+}
+
+// CalibrationReport is GET /homework/centers/{id}/calibration/report.
+export interface CalibrationReport {
+  term_id: number
+  graders: {
+    grader_user_id: number
+    grader_first_name: string
+    grader_last_name: string
+    as_first_total: number
+    as_first_agreed: number
+    as_second_total: number
+    as_second_agreed: number
+    agreement_rate: number | null
+  }[]
 }
 
 // GraderStats are the at-a-glance workload counters for a center